@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGeneratedClientsAreUpToDate fails when a route or a gateway type has
+// changed without the clients being generated again
+func TestGeneratedClientsAreUpToDate(t *testing.T) {
+	files, err := generate()
+	require.NoError(t, err)
+
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join("..", "..", name))
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(got), "%s is out of date; run go run ./clients/gen", name)
+	}
+}
+
+func TestTSTypeOf(t *testing.T) {
+	for _, tt := range []struct {
+		goType reflect.Type
+		want   string
+	}{
+		{reflect.TypeFor[string](), "string"},
+		{reflect.TypeFor[int64](), "number"},
+		{reflect.TypeFor[*float64](), "number"},
+		{reflect.TypeFor[[]byte](), "string"},
+		{reflect.TypeFor[[]models.Frame](), "Frame[]"},
+		{reflect.TypeFor[map[string][]string](), "Record<string, string[]>"},
+		{reflect.TypeFor[any](), "unknown"},
+	} {
+		assert.Equal(t, tt.want, tsTypeOf(tt.goType), tt.goType.String())
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"go/format"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// generateGo writes the Go client's aliases of the gateway types and a
+// method per endpoint; the rest of the client is hand-written in client.go
+func generateGo(types []reflect.Type) ([]byte, error) {
+	var body bytes.Buffer
+	imports := map[string]bool{"context": true}
+
+	body.WriteString("// The gateway's request and response types\ntype (\n")
+	for _, t := range types {
+		imports[t.PkgPath()] = true
+		fmt.Fprintf(&body, "\t%s = %s.%s\n", clientName(t), path.Base(t.PkgPath()), t.Name())
+	}
+	body.WriteString(")\n")
+
+	for _, e := range endpoints {
+		writeGoParams(&body, e)
+		writeGoMethod(&body, e, imports)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by clients/gen from the gateway contract; DO NOT EDIT.\n\n")
+	out.WriteString("package gatewayclient\n\nimport (\n")
+	paths := make([]string, 0, len(imports))
+	for importPath := range imports {
+		paths = append(paths, importPath)
+	}
+	// The standard library first, as goimports groups them
+	slices.SortFunc(paths, func(a, b string) int {
+		return cmp.Or(cmp.Compare(isThirdParty(a), isThirdParty(b)), cmp.Compare(a, b))
+	})
+	for i, importPath := range paths {
+		if i > 0 && isThirdParty(importPath) != isThirdParty(paths[i-1]) {
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(&out, "\t%q\n", importPath)
+	}
+	out.WriteString(")\n\n")
+	out.Write(body.Bytes())
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting the Go client: %w\n%s", err, out.Bytes())
+	}
+	return formatted, nil
+}
+
+func isThirdParty(importPath string) int {
+	if first, _, _ := strings.Cut(importPath, "/"); strings.Contains(first, ".") {
+		return 1
+	}
+	return 0
+}
+
+// writeGoParams writes the struct of an endpoint's query parameters
+func writeGoParams(body *bytes.Buffer, e endpoint) {
+	if len(e.Query) == 0 {
+		return
+	}
+	fmt.Fprintf(body, "\n// %sParams are the optional parameters of %s\ntype %sParams struct {\n", e.Name, e.Name, e.Name)
+	for _, q := range e.Query {
+		fmt.Fprintf(body, "\t// %s %s\n\t%s %s\n", q.Field, q.Doc, q.Field, q.Kind)
+	}
+	body.WriteString("}\n")
+}
+
+func writeGoMethod(body *bytes.Buffer, e endpoint, imports map[string]bool) {
+	args := []string{"ctx context.Context"}
+	params := pathParams(e.Path)
+	for _, param := range params {
+		args = append(args, param+" string")
+	}
+	if len(e.Query) > 0 {
+		args = append(args, "params "+e.Name+"Params")
+	}
+	if e.Request != nil {
+		args = append(args, "req "+clientName(e.Request))
+	}
+
+	// The path with its parameters escaped into it
+	route := fmt.Sprintf("%q", e.Path)
+	for _, param := range params {
+		imports["net/url"] = true
+		route = strings.Replace(route, "{"+param+"}", `"+url.PathEscape(`+param+`)+"`, 1)
+	}
+	route = strings.TrimSuffix(route, `+""`)
+
+	response := clientName(e.Response)
+	fmt.Fprintf(body, "\n// %s %s\n//\n// %s %s\n", e.Name, e.Doc, e.Method, e.Path)
+	fmt.Fprintf(body, "func (c *Client) %s(%s) (*%s, error) {\n", e.Name, strings.Join(args, ", "), response)
+
+	query := "nil"
+	if len(e.Query) > 0 {
+		imports["net/url"] = true
+		query = "query"
+		body.WriteString("\tquery := url.Values{}\n")
+		for _, q := range e.Query {
+			switch q.Kind {
+			case reflect.Int:
+				imports["strconv"] = true
+				fmt.Fprintf(body, "\tif params.%s != 0 {\n\t\tquery.Set(%q, strconv.Itoa(params.%s))\n\t}\n", q.Field, q.Name, q.Field)
+			default:
+				fmt.Fprintf(body, "\tif params.%s != \"\" {\n\t\tquery.Set(%q, params.%s)\n\t}\n", q.Field, q.Name, q.Field)
+			}
+		}
+	}
+	request := "nil"
+	if e.Request != nil {
+		request = "req"
+	}
+
+	fmt.Fprintf(body, "\tvar resp %s\n", response)
+	fmt.Fprintf(body, "\tif err := c.do(ctx, %q, %s, %s, %s, &resp); err != nil {\n\t\treturn nil, err\n\t}\n", e.Method, route, query, request)
+	body.WriteString("\treturn &resp, nil\n}\n")
+}
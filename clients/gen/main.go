@@ -0,0 +1,161 @@
+// Command gen generates the gateway API clients from the contract in
+// spec.go: the Go client's types and methods, and the TypeScript client.
+// The output is committed; run it after changing a route or a type the
+// routes use, and TestGeneratedClientsAreUpToDate fails until then.
+//
+//	go run ./clients/gen
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// The generated files, relative to the module root
+const (
+	goOutput         = "clients/go/gatewayclient/api_gen.go"
+	typescriptOutput = "clients/typescript/gateway.ts"
+)
+
+func main() {
+	root := flag.String("root", ".", "module root to write the clients under")
+	flag.Parse()
+
+	files, err := generate()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gen:", err)
+		os.Exit(1)
+	}
+	for name, content := range files {
+		path := filepath.Join(*root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			fmt.Fprintln(os.Stderr, "gen:", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, "gen:", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// generate returns the content of every generated file by its path
+func generate() (map[string][]byte, error) {
+	types, err := collectTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	goClient, err := generateGo(types)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+		goOutput:         goClient,
+		typescriptOutput: generateTypeScript(types),
+	}, nil
+}
+
+// collectTypes returns the named struct types the endpoints use, each once,
+// in the order they are first met
+func collectTypes() ([]reflect.Type, error) {
+	var types []reflect.Type
+	seen := make(map[reflect.Type]bool)
+	names := make(map[string]reflect.Type)
+
+	var visit func(t reflect.Type) error
+	visit = func(t reflect.Type) error {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			return visit(t.Elem())
+		case reflect.Struct:
+		default:
+			return nil
+		}
+		if t == timeType || seen[t] {
+			return nil
+		}
+		seen[t] = true
+
+		name := clientName(t)
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("%s and %s are both named %s in the clients", other, t, name)
+		}
+		names[name] = t
+		types = append(types, t)
+
+		for field := range exportedFields(t) {
+			if err := visit(field.Type); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, e := range endpoints {
+		if e.Request != nil {
+			if err := visit(e.Request); err != nil {
+				return nil, err
+			}
+		}
+		if err := visit(e.Response); err != nil {
+			return nil, err
+		}
+	}
+	if err := visit(errorType); err != nil {
+		return nil, err
+	}
+	return types, nil
+}
+
+// clientName is what the clients call a gateway type: the API version is
+// implied by the client, so AnalysisResultV2 is AnalysisResult
+func clientName(t reflect.Type) string {
+	return strings.TrimSuffix(t.Name(), "V2")
+}
+
+// exportedFields yields the fields of struct t that appear in its JSON,
+// embedded ones included as they are
+func exportedFields(t reflect.Type) func(yield func(reflect.StructField) bool) {
+	return func(yield func(reflect.StructField) bool) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if !field.IsExported() || field.Tag.Get("json") == "-" {
+				continue
+			}
+			if !yield(field) {
+				return
+			}
+		}
+	}
+}
+
+// jsonName returns the JSON name of field and whether it may be left out
+func jsonName(field reflect.StructField) (string, bool) {
+	name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		name = field.Name
+	}
+	optional := false
+	for option := range strings.SplitSeq(options, ",") {
+		if option == "omitempty" || option == "omitzero" {
+			optional = true
+		}
+	}
+	return name, optional
+}
+
+// pathParams returns the names of the braced parameters of path
+func pathParams(path string) []string {
+	var params []string
+	for segment := range strings.SplitSeq(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params = append(params, segment[1:len(segment)-1])
+		}
+	}
+	return params
+}
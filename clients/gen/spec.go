@@ -0,0 +1,86 @@
+package main
+
+import (
+	"reflect"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/services/gateway/translate"
+)
+
+// endpoint is one gateway route as the clients call it
+type endpoint struct {
+	// Name is the Go method name; the TypeScript one is its camelCase
+	Name   string
+	Doc    string
+	Method string
+	// Path names its parameters in braces, as the gateway router does;
+	// each becomes a string argument
+	Path  string
+	Query []queryParam
+	// Request is the JSON body, or nil for none
+	Request  reflect.Type
+	Response reflect.Type
+}
+
+// queryParam is an optional query string parameter, left out when zero
+type queryParam struct {
+	Name  string
+	Field string
+	// Kind is reflect.String or reflect.Int
+	Kind reflect.Kind
+	Doc  string
+}
+
+// resultQuery are the query parameters shaping the results of a response
+var resultQuery = []queryParam{
+	{Name: "min_severity", Field: "MinSeverity", Kind: reflect.String, Doc: "keeps only the findings of this severity or above: info, warning or error"},
+	{Name: "fields", Field: "Fields", Kind: reflect.String, Doc: "keeps only these comma-separated result fields, e.g. title,headings,links.total"},
+	{Name: "links_offset", Field: "LinksOffset", Kind: reflect.Int, Doc: "skips this many redirected links"},
+	{Name: "links_limit", Field: "LinksLimit", Kind: reflect.Int, Doc: "caps the number of redirected links"},
+}
+
+// endpoints is the gateway contract the clients are generated from. The
+// types are the gateway's own, so a change to them shows up as a diff in the
+// generated clients.
+var endpoints = []endpoint{
+	{
+		Name:     "Analyze",
+		Doc:      "analyzes one page",
+		Method:   "POST",
+		Path:     "/api/v2/analyze",
+		Query:    resultQuery,
+		Request:  reflect.TypeFor[models.AnalysisRequest](),
+		Response: reflect.TypeFor[translate.AnalysisResultV2](),
+	},
+	{
+		Name:     "BatchAnalyze",
+		Doc:      "analyzes up to 100 pages; each URL gets its own result or error",
+		Method:   "POST",
+		Path:     "/api/v2/batch-analyze",
+		Query:    resultQuery,
+		Request:  reflect.TypeFor[models.BatchAnalysisRequest](),
+		Response: reflect.TypeFor[translate.BatchResultV2](),
+	},
+	{
+		Name:   "ListResults",
+		Doc:    "lists the saved results, newest first",
+		Method: "GET",
+		Path:   "/api/v2/results",
+		Query: append([]queryParam{
+			{Name: "url", Field: "URL", Kind: reflect.String, Doc: "keeps only the results for this URL"},
+			{Name: "limit", Field: "Limit", Kind: reflect.Int, Doc: "caps the number of results"},
+		}, resultQuery...),
+		Response: reflect.TypeFor[translate.ResultListV2](),
+	},
+	{
+		Name:     "GetResult",
+		Doc:      "returns one saved result",
+		Method:   "GET",
+		Path:     "/api/v2/results/{id}",
+		Query:    resultQuery,
+		Response: reflect.TypeFor[translate.StoredResultV2](),
+	},
+}
+
+// errorType is the body of every error response
+var errorType = reflect.TypeFor[models.ErrorResponse]()
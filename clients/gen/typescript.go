@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	timeType     = reflect.TypeFor[time.Time]()
+	durationType = reflect.TypeFor[time.Duration]()
+)
+
+// generateTypeScript writes the TypeScript client: an interface per gateway
+// type and a GatewayClient with a method per endpoint
+func generateTypeScript(types []reflect.Type) []byte {
+	var out bytes.Buffer
+	out.WriteString("// Code generated by clients/gen from the gateway contract; DO NOT EDIT.\n")
+
+	for _, t := range types {
+		writeInterface(&out, t)
+	}
+	for _, e := range endpoints {
+		if len(e.Query) == 0 {
+			continue
+		}
+		fmt.Fprintf(&out, "\n/** The optional parameters of %s */\nexport interface %sParams {\n", lowerFirst(e.Name), e.Name)
+		for _, q := range e.Query {
+			fmt.Fprintf(&out, "  /** %s */\n  %s?: %s;\n", upperFirst(q.Doc), q.Name, tsScalar(q.Kind))
+		}
+		out.WriteString("}\n")
+	}
+
+	out.WriteString(typescriptRuntime)
+	for _, e := range endpoints {
+		writeTypeScriptMethod(&out, e)
+	}
+	out.WriteString("}\n")
+	return out.Bytes()
+}
+
+func writeInterface(out *bytes.Buffer, t reflect.Type) {
+	var extends []string
+	var fields bytes.Buffer
+	for field := range exportedFields(t) {
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			extends = append(extends, clientName(field.Type))
+			continue
+		}
+		name, optional := jsonName(field)
+		tsType := tsTypeOf(field.Type)
+		if field.Type.Kind() == reflect.Pointer && !optional {
+			tsType += " | null"
+		}
+		marker := ""
+		if optional {
+			marker = "?"
+		}
+		fmt.Fprintf(&fields, "  %s%s: %s;\n", name, marker, tsType)
+	}
+
+	fmt.Fprintf(out, "\nexport interface %s", clientName(t))
+	if len(extends) > 0 {
+		fmt.Fprintf(out, " extends %s", strings.Join(extends, ", "))
+	}
+	out.WriteString(" {\n")
+	out.Write(fields.Bytes())
+	out.WriteString("}\n")
+}
+
+// tsTypeOf maps a Go type to the TypeScript type of its JSON
+func tsTypeOf(t reflect.Type) string {
+	switch {
+	case t == timeType:
+		return "string"
+	case t == durationType:
+		// Durations encode as integer nanoseconds
+		return "number"
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return tsTypeOf(t.Elem())
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Base64
+			return "string"
+		}
+		return tsTypeOf(t.Elem()) + "[]"
+	case reflect.Map:
+		return "Record<string, " + tsTypeOf(t.Elem()) + ">"
+	case reflect.Struct:
+		return clientName(t)
+	case reflect.Interface:
+		return "unknown"
+	default:
+		return tsScalar(t.Kind())
+	}
+}
+
+func tsScalar(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "unknown"
+	}
+}
+
+func writeTypeScriptMethod(out *bytes.Buffer, e endpoint) {
+	var args []string
+	route := e.Path
+	for _, param := range pathParams(e.Path) {
+		args = append(args, param+": string")
+		route = strings.Replace(route, "{"+param+"}", "${encodeURIComponent("+param+")}", 1)
+	}
+	query := "undefined"
+	if len(e.Query) > 0 {
+		args = append(args, "params: "+e.Name+"Params = {}")
+		var entries []string
+		for _, q := range e.Query {
+			entries = append(entries, q.Name+": params."+q.Name)
+		}
+		query = "{ " + strings.Join(entries, ", ") + " }"
+	}
+	body := "undefined"
+	if e.Request != nil {
+		args = append(args, "req: "+clientName(e.Request))
+		body = "req"
+	}
+	args = append(args, "signal?: AbortSignal")
+
+	fmt.Fprintf(out, "\n  /** %s: %s %s */\n", upperFirst(e.Doc), e.Method, e.Path)
+	fmt.Fprintf(out, "  %s(%s): Promise<%s> {\n", lowerFirst(e.Name), strings.Join(args, ", "), clientName(e.Response))
+	fmt.Fprintf(out, "    return this.request(%q, `%s`, %s, %s, signal);\n  }\n", e.Method, route, query, body)
+}
+
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
+
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// typescriptRuntime is the hand-written part of the TypeScript client, up to
+// the generated methods
+const typescriptRuntime = `
+export interface ClientOptions {
+  /** The gateway's URL; requests are relative to the page by default */
+  baseUrl?: string;
+  /** Sent with every request, such as an Authorization header */
+  headers?: Record<string, string>;
+  /** Bounds each attempt; unbounded by default */
+  timeoutMs?: number;
+  /** Retries after a network error or a 502, 503 or 504; 2 by default */
+  retries?: number;
+  /** The wait before the first retry, doubled for each next one; 200 by default */
+  retryDelayMs?: number;
+  /** The fetch implementation; the global one by default */
+  fetch?: typeof fetch;
+}
+
+/** APIError is a response other than 2xx, with the gateway's error body */
+export class APIError extends Error {
+  readonly status: number;
+  readonly body: ErrorResponse;
+
+  constructor(status: number, body: ErrorResponse) {
+    super(body.error || ` + "`status ${status}`" + `);
+    this.name = "APIError";
+    this.status = status;
+    this.body = body;
+  }
+}
+
+const retryStatuses = [502, 503, 504];
+
+const sleep = (ms: number) => new Promise((resolve) => setTimeout(resolve, ms));
+
+export class GatewayClient {
+  private readonly baseUrl: string;
+  private readonly headers: Record<string, string>;
+  private readonly timeoutMs?: number;
+  private readonly retries: number;
+  private readonly retryDelayMs: number;
+  private readonly fetchImpl: typeof fetch;
+
+  constructor(options: ClientOptions = {}) {
+    this.baseUrl = (options.baseUrl ?? "").replace(/\/+$/, "");
+    this.headers = options.headers ?? {};
+    this.timeoutMs = options.timeoutMs;
+    this.retries = options.retries ?? 2;
+    this.retryDelayMs = options.retryDelayMs ?? 200;
+    this.fetchImpl = options.fetch ?? globalThis.fetch.bind(globalThis);
+  }
+
+  private async request<T>(
+    method: string,
+    path: string,
+    query: Record<string, string | number | undefined> | undefined,
+    body: unknown,
+    signal?: AbortSignal,
+  ): Promise<T> {
+    let url = this.baseUrl + path;
+    const params = new URLSearchParams();
+    for (const [key, value] of Object.entries(query ?? {})) {
+      if (value !== undefined && value !== "" && value !== 0) {
+        params.set(key, String(value));
+      }
+    }
+    if (params.toString() !== "") {
+      url += "?" + params.toString();
+    }
+
+    const headers: Record<string, string> = { Accept: "application/json", ...this.headers };
+    if (body !== undefined) {
+      headers["Content-Type"] = "application/json";
+    }
+
+    for (let attempt = 0; ; attempt++) {
+      const controller = new AbortController();
+      const abort = () => controller.abort();
+      signal?.addEventListener("abort", abort);
+      const timer = this.timeoutMs ? setTimeout(abort, this.timeoutMs) : undefined;
+      try {
+        const response = await this.fetchImpl(url, {
+          method,
+          headers,
+          body: body === undefined ? undefined : JSON.stringify(body),
+          signal: controller.signal,
+        });
+        if (retryStatuses.includes(response.status) && attempt < this.retries) {
+          await sleep(this.retryDelayMs * 2 ** attempt);
+          continue;
+        }
+        const data = await response.json().catch(() => undefined);
+        if (!response.ok) {
+          throw new APIError(response.status, data ?? ({ error: response.statusText, status_code: response.status } as ErrorResponse));
+        }
+        return data as T;
+      } catch (error) {
+        if (error instanceof APIError || signal?.aborted || attempt >= this.retries) {
+          throw error;
+        }
+        await sleep(this.retryDelayMs * 2 ** attempt);
+      } finally {
+        clearTimeout(timer);
+        signal?.removeEventListener("abort", abort);
+      }
+    }
+  }
+`
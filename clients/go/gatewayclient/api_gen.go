@@ -0,0 +1,230 @@
+// Code generated by clients/gen from the gateway contract; DO NOT EDIT.
+
+package gatewayclient
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/services/gateway/translate"
+)
+
+// The gateway's request and response types
+type (
+	AnalysisRequest      = models.AnalysisRequest
+	AnalysisOptions      = models.AnalysisOptions
+	RuleSelection        = models.RuleSelection
+	CustomCheck          = models.CustomCheck
+	LinkCheckOptions     = models.LinkCheckOptions
+	AnalysisResult       = translate.AnalysisResultV2
+	HeadingCount         = models.HeadingCount
+	LinkSummary          = models.LinkSummary
+	DataURISummary       = models.DataURISummary
+	SlowLink             = models.SlowLink
+	BrokenLinkGroup      = models.BrokenLinkGroup
+	DomainCount          = models.DomainCount
+	Timings              = models.Timings
+	BudgetUsage          = models.BudgetUsage
+	Traffic              = models.Traffic
+	IDNHost              = models.IDNHost
+	AuthRequirement      = models.AuthRequirement
+	AuthChallenge        = models.AuthChallenge
+	Frame                = models.Frame
+	TitleReport          = models.TitleReport
+	HreflangReport       = models.HreflangReport
+	HreflangLink         = models.HreflangLink
+	HreflangFinding      = models.HreflangFinding
+	AMPReport            = models.AMPReport
+	AMPFinding           = models.AMPFinding
+	PaginationReport     = models.PaginationReport
+	PaginationFinding    = models.PaginationFinding
+	AnchorReport         = models.AnchorReport
+	DuplicateID          = models.DuplicateID
+	RedirectedLink       = models.RedirectedLink
+	Page                 = translate.Page
+	LinkFindings         = models.LinkFindings
+	LinkFinding          = models.LinkFinding
+	AccessibilityReport  = models.AccessibilityReport
+	ContentReport        = models.ContentReport
+	TextStats            = models.TextStats
+	ContentFinding       = models.ContentFinding
+	RobotsReport         = models.RobotsReport
+	RobotsDirectives     = models.RobotsDirectives
+	RobotsConflict       = models.RobotsConflict
+	Cacheability         = models.Cacheability
+	TechnologyHints      = models.TechnologyHints
+	DebugTrace           = models.DebugTrace
+	OutboundRequest      = models.OutboundRequest
+	Warning              = models.Warning
+	Finding              = models.Finding
+	FindingEvidence      = models.FindingEvidence
+	FindingSummary       = models.FindingSummary
+	URLNormalization     = models.URLNormalization
+	CheckResult          = models.CheckResult
+	BatchAnalysisRequest = models.BatchAnalysisRequest
+	BatchResult          = translate.BatchResultV2
+	BatchItem            = translate.BatchItemV2
+	ErrorResponse        = models.ErrorResponse
+	CrossPageFindings    = models.CrossPageFindings
+	DuplicateTitle       = models.DuplicateTitle
+	CanonicalTarget      = models.CanonicalTarget
+	CollapsedRedirect    = models.CollapsedRedirect
+	ResultList           = translate.ResultListV2
+	StoredResult         = translate.StoredResultV2
+)
+
+// AnalyzeParams are the optional parameters of Analyze
+type AnalyzeParams struct {
+	// MinSeverity keeps only the findings of this severity or above: info, warning or error
+	MinSeverity string
+	// Fields keeps only these comma-separated result fields, e.g. title,headings,links.total
+	Fields string
+	// LinksOffset skips this many redirected links
+	LinksOffset int
+	// LinksLimit caps the number of redirected links
+	LinksLimit int
+}
+
+// Analyze analyzes one page
+//
+// POST /api/v2/analyze
+func (c *Client) Analyze(ctx context.Context, params AnalyzeParams, req AnalysisRequest) (*AnalysisResult, error) {
+	query := url.Values{}
+	if params.MinSeverity != "" {
+		query.Set("min_severity", params.MinSeverity)
+	}
+	if params.Fields != "" {
+		query.Set("fields", params.Fields)
+	}
+	if params.LinksOffset != 0 {
+		query.Set("links_offset", strconv.Itoa(params.LinksOffset))
+	}
+	if params.LinksLimit != 0 {
+		query.Set("links_limit", strconv.Itoa(params.LinksLimit))
+	}
+	var resp AnalysisResult
+	if err := c.do(ctx, "POST", "/api/v2/analyze", query, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// BatchAnalyzeParams are the optional parameters of BatchAnalyze
+type BatchAnalyzeParams struct {
+	// MinSeverity keeps only the findings of this severity or above: info, warning or error
+	MinSeverity string
+	// Fields keeps only these comma-separated result fields, e.g. title,headings,links.total
+	Fields string
+	// LinksOffset skips this many redirected links
+	LinksOffset int
+	// LinksLimit caps the number of redirected links
+	LinksLimit int
+}
+
+// BatchAnalyze analyzes up to 100 pages; each URL gets its own result or error
+//
+// POST /api/v2/batch-analyze
+func (c *Client) BatchAnalyze(ctx context.Context, params BatchAnalyzeParams, req BatchAnalysisRequest) (*BatchResult, error) {
+	query := url.Values{}
+	if params.MinSeverity != "" {
+		query.Set("min_severity", params.MinSeverity)
+	}
+	if params.Fields != "" {
+		query.Set("fields", params.Fields)
+	}
+	if params.LinksOffset != 0 {
+		query.Set("links_offset", strconv.Itoa(params.LinksOffset))
+	}
+	if params.LinksLimit != 0 {
+		query.Set("links_limit", strconv.Itoa(params.LinksLimit))
+	}
+	var resp BatchResult
+	if err := c.do(ctx, "POST", "/api/v2/batch-analyze", query, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListResultsParams are the optional parameters of ListResults
+type ListResultsParams struct {
+	// URL keeps only the results for this URL
+	URL string
+	// Limit caps the number of results
+	Limit int
+	// MinSeverity keeps only the findings of this severity or above: info, warning or error
+	MinSeverity string
+	// Fields keeps only these comma-separated result fields, e.g. title,headings,links.total
+	Fields string
+	// LinksOffset skips this many redirected links
+	LinksOffset int
+	// LinksLimit caps the number of redirected links
+	LinksLimit int
+}
+
+// ListResults lists the saved results, newest first
+//
+// GET /api/v2/results
+func (c *Client) ListResults(ctx context.Context, params ListResultsParams) (*ResultList, error) {
+	query := url.Values{}
+	if params.URL != "" {
+		query.Set("url", params.URL)
+	}
+	if params.Limit != 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if params.MinSeverity != "" {
+		query.Set("min_severity", params.MinSeverity)
+	}
+	if params.Fields != "" {
+		query.Set("fields", params.Fields)
+	}
+	if params.LinksOffset != 0 {
+		query.Set("links_offset", strconv.Itoa(params.LinksOffset))
+	}
+	if params.LinksLimit != 0 {
+		query.Set("links_limit", strconv.Itoa(params.LinksLimit))
+	}
+	var resp ResultList
+	if err := c.do(ctx, "GET", "/api/v2/results", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetResultParams are the optional parameters of GetResult
+type GetResultParams struct {
+	// MinSeverity keeps only the findings of this severity or above: info, warning or error
+	MinSeverity string
+	// Fields keeps only these comma-separated result fields, e.g. title,headings,links.total
+	Fields string
+	// LinksOffset skips this many redirected links
+	LinksOffset int
+	// LinksLimit caps the number of redirected links
+	LinksLimit int
+}
+
+// GetResult returns one saved result
+//
+// GET /api/v2/results/{id}
+func (c *Client) GetResult(ctx context.Context, id string, params GetResultParams) (*StoredResult, error) {
+	query := url.Values{}
+	if params.MinSeverity != "" {
+		query.Set("min_severity", params.MinSeverity)
+	}
+	if params.Fields != "" {
+		query.Set("fields", params.Fields)
+	}
+	if params.LinksOffset != 0 {
+		query.Set("links_offset", strconv.Itoa(params.LinksOffset))
+	}
+	if params.LinksLimit != 0 {
+		query.Set("links_limit", strconv.Itoa(params.LinksLimit))
+	}
+	var resp StoredResult
+	if err := c.do(ctx, "GET", "/api/v2/results/"+url.PathEscape(id), query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,189 @@
+// Package gatewayclient calls the gateway's v2 API. The request and response
+// types are the gateway's own, aliased in api_gen.go, and the methods there
+// are generated from the same contract as the TypeScript client.
+//
+//	client := gatewayclient.New("https://analyzer.example.com",
+//		gatewayclient.WithAuthHeader("Authorization", "Bearer "+token))
+//	result, err := client.Analyze(ctx, gatewayclient.AnalyzeParams{}, gatewayclient.AnalysisRequest{URL: "https://example.com"})
+package gatewayclient
+
+//go:generate go run ../../gen -root ../../..
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRetries is how many times a failed call is retried
+	DefaultRetries = 2
+	// DefaultRetryBackoff is the wait before the first retry, doubled for
+	// each next one
+	DefaultRetryBackoff = 200 * time.Millisecond
+)
+
+// retryStatuses are the gateway answers worth another attempt: the analyzer
+// behind it was briefly unreachable or overloaded
+var retryStatuses = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// Client calls one gateway. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	header     http.Header
+	timeout    time.Duration
+	retries    int
+	backoff    time.Duration
+}
+
+// Option configures a Client in New
+type Option func(*Client)
+
+// WithHTTPClient sends the requests with client instead of
+// http.DefaultClient
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) { c.httpClient = client }
+}
+
+// WithAuthHeader sends the header name with value on every request, such as
+// Authorization with a bearer token
+func WithAuthHeader(name, value string) Option {
+	return func(c *Client) { c.header.Set(name, value) }
+}
+
+// WithTimeout bounds each attempt of a call; the context bounds the call as
+// a whole, retries included. Zero, the default, leaves attempts to the
+// context and the gateway's own deadlines.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) { c.timeout = timeout }
+}
+
+// WithRetries retries a call up to retries times after a network error or a
+// 502, 503 or 504, waiting backoff and then twice as long each time. Zero
+// retries disables them.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = max(retries, 0)
+		c.backoff = backoff
+	}
+}
+
+// New returns a client of the gateway at baseURL, such as
+// "http://localhost:8080"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		header:     make(http.Header),
+		retries:    DefaultRetries,
+		backoff:    DefaultRetryBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is a gateway answer other than 2xx
+type APIError struct {
+	StatusCode int
+	// Response is the gateway's error body; only Error is set when the
+	// body was not one
+	Response ErrorResponse
+	// RequestID identifies the call in the gateway's logs
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("gateway: %s (status %d)", e.Response.Error, e.StatusCode)
+}
+
+// do sends a call, retrying it as configured, and decodes the response
+// into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("gateway: encoding request: %w", err)
+		}
+	}
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		retry, err := c.attempt(ctx, method, target, payload, out)
+		if err == nil || !retry || attempt >= c.retries {
+			return err
+		}
+
+		timer := time.NewTimer(c.backoff << attempt)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt makes one request and reports, on failure, whether it is worth
+// retrying
+func (c *Client) attempt(ctx context.Context, method, target string, payload []byte, out any) (bool, error) {
+	attemptCtx := ctx
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(attemptCtx, method, target, body)
+	if err != nil {
+		return false, fmt.Errorf("gateway: %w", err)
+	}
+	for name, values := range c.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Another attempt only helps while the call itself has time left
+		return ctx.Err() == nil, fmt.Errorf("gateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, RequestID: resp.Header.Get("X-Request-ID")}
+		if json.NewDecoder(resp.Body).Decode(&apiErr.Response) != nil || apiErr.Response.Error == "" {
+			apiErr.Response = ErrorResponse{Error: http.StatusText(resp.StatusCode), StatusCode: resp.StatusCode}
+		}
+		return slices.Contains(retryStatuses, resp.StatusCode), apiErr
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return ctx.Err() == nil, fmt.Errorf("gateway: reading response: %w", err)
+		}
+		return false, fmt.Errorf("gateway: decoding response: %w", err)
+	}
+	return false, nil
+}
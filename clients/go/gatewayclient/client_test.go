@@ -0,0 +1,213 @@
+package gatewayclient
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/pkg/metrics"
+	"github.com/RuvinSL/webpage-analyzer/pkg/middleware"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/storage"
+	"github.com/RuvinSL/webpage-analyzer/services/gateway/handlers"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAnalyzer answers for the analyzer service behind the gateway
+type fakeAnalyzer struct {
+	delay time.Duration
+	calls atomic.Int32
+}
+
+func (a *fakeAnalyzer) Analyze(ctx context.Context, url string) (*models.AnalysisResult, error) {
+	return a.AnalyzeWithOptions(ctx, url, models.AnalysisOptions{})
+}
+
+func (a *fakeAnalyzer) AnalyzeWithOptions(ctx context.Context, url string, opts models.AnalysisOptions) (*models.AnalysisResult, error) {
+	a.calls.Add(1)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(a.delay):
+	}
+	return &models.AnalysisResult{
+		URL:         url,
+		HTMLVersion: "HTML5",
+		Title:       "Example",
+		Headings:    models.HeadingCount{H1: 1},
+		Links:       models.LinkSummary{Internal: 2, Total: 2},
+		AnalyzedAt:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}, nil
+}
+
+func (a *fakeAnalyzer) CheckHealth(ctx context.Context) error { return nil }
+
+// newGateway serves the gateway's v2 routes with its real handlers; wrap
+// sees every request first
+func newGateway(t *testing.T, analyzer handlers.AnalyzerClient, wrap func(http.Handler) http.Handler) *httptest.Server {
+	log := logger.NewAdapter(slog.New(slog.DiscardHandler))
+	apiHandler := handlers.NewAPIHandler(analyzer, log, metrics.Nop{})
+	store := storage.NewMemory(100)
+	apiHandler.SetResultStore(store)
+	resultsHandler := handlers.NewResultsHandler(store, log)
+
+	router := mux.NewRouter()
+	router.Use(middleware.RequestID)
+	if wrap != nil {
+		router.Use(wrap)
+	}
+	api := router.PathPrefix("/api/v2").Subrouter()
+	api.HandleFunc("/analyze", apiHandler.AnalyzeURLV2).Methods("POST")
+	api.HandleFunc("/batch-analyze", apiHandler.BatchAnalyzeV2).Methods("POST")
+	api.HandleFunc("/results", resultsHandler.List).Methods("GET")
+	api.HandleFunc("/results/{id}", resultsHandler.Get).Methods("GET")
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_AgainstTheGateway(t *testing.T) {
+	gateway := newGateway(t, &fakeAnalyzer{}, nil)
+	client := New(gateway.URL + "/")
+	ctx := context.Background()
+
+	result, err := client.Analyze(ctx, AnalyzeParams{}, AnalysisRequest{URL: "https://example.com/a"})
+	require.NoError(t, err)
+	assert.Equal(t, "Example", result.Title)
+	assert.Equal(t, 1, result.Headings.H1)
+	assert.Equal(t, 2, result.Links.Internal)
+
+	batch, err := client.BatchAnalyze(ctx, BatchAnalyzeParams{}, BatchAnalysisRequest{URLs: []string{"https://example.com/b", "https://example.com/c"}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, batch.Succeeded)
+	require.Len(t, batch.Items, 2)
+	assert.Equal(t, "https://example.com/b", batch.Items[0].URL)
+
+	list, err := client.ListResults(ctx, ListResultsParams{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, list.Results, 2)
+	assert.Equal(t, "https://example.com/c", list.Results[0].Result.URL, "newest first")
+
+	list, err = client.ListResults(ctx, ListResultsParams{URL: "https://example.com/a"})
+	require.NoError(t, err)
+	require.Len(t, list.Results, 1)
+
+	stored, err := client.GetResult(ctx, list.Results[0].ID, GetResultParams{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/a", stored.Result.URL)
+}
+
+func TestClient_APIError(t *testing.T) {
+	gateway := newGateway(t, &fakeAnalyzer{}, nil)
+	client := New(gateway.URL)
+
+	_, err := client.Analyze(context.Background(), AnalyzeParams{}, AnalysisRequest{})
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, "URL is required", apiErr.Response.Error)
+	assert.NotEmpty(t, apiErr.RequestID)
+	assert.Equal(t, "gateway: URL is required (status 400)", err.Error())
+
+	_, err = client.GetResult(context.Background(), "no/such id", GetResultParams{})
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+}
+
+func TestClient_AuthHeader(t *testing.T) {
+	var seen atomic.Value
+	gateway := newGateway(t, &fakeAnalyzer{}, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen.Store(r.Header.Get("Authorization"))
+			next.ServeHTTP(w, r)
+		})
+	})
+	client := New(gateway.URL, WithAuthHeader("Authorization", "Bearer secret"))
+
+	_, err := client.ListResults(context.Background(), ListResultsParams{})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer secret", seen.Load())
+}
+
+func TestClient_RetriesUnavailableGateway(t *testing.T) {
+	var failures atomic.Int32
+	failures.Store(2)
+	analyzer := &fakeAnalyzer{}
+	gateway := newGateway(t, analyzer, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if failures.Add(-1) >= 0 {
+				http.Error(w, "restarting", http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	client := New(gateway.URL, WithRetries(2, time.Millisecond))
+	result, err := client.Analyze(context.Background(), AnalyzeParams{}, AnalysisRequest{URL: "https://example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", result.URL)
+	assert.Equal(t, int32(1), analyzer.calls.Load())
+
+	failures.Store(2)
+	client = New(gateway.URL, WithRetries(1, time.Millisecond))
+	_, err = client.Analyze(context.Background(), AnalyzeParams{}, AnalysisRequest{URL: "https://example.com"})
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Equal(t, "Service Unavailable", apiErr.Response.Error, "a plain-text body is not an ErrorResponse")
+}
+
+func TestClient_ClientErrorsAreNotRetried(t *testing.T) {
+	var requests atomic.Int32
+	gateway := newGateway(t, &fakeAnalyzer{}, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	_, err := New(gateway.URL).Analyze(context.Background(), AnalyzeParams{}, AnalysisRequest{})
+	require.Error(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestClient_Timeout(t *testing.T) {
+	gateway := newGateway(t, &fakeAnalyzer{delay: time.Second}, nil)
+	client := New(gateway.URL, WithTimeout(20*time.Millisecond), WithRetries(1, time.Millisecond))
+
+	start := time.Now()
+	_, err := client.Analyze(context.Background(), AnalyzeParams{}, AnalysisRequest{URL: "https://example.com"})
+
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "both attempts are bounded")
+}
+
+func TestClient_ContextEndsTheRetries(t *testing.T) {
+	gateway := newGateway(t, &fakeAnalyzer{}, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		})
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := New(gateway.URL, WithRetries(5, time.Second)).Analyze(ctx, AnalyzeParams{}, AnalysisRequest{URL: "https://example.com"})
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/gorilla/mux"
+)
+
+// LogLevelRequest is the body accepted by PUT /admin/loglevel
+type LogLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// LogLevelResponse reports the level currently in effect
+type LogLevelResponse struct {
+	Level string `json:"level"`
+}
+
+// RequireToken rejects requests that do not carry the admin token as a
+// bearer token. An empty token disables the admin endpoints entirely so they
+// are never world-writable by accident.
+func RequireToken(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				sendError(w, "Admin endpoints are disabled", http.StatusForbidden)
+				return
+			}
+
+			provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				sendError(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LogLevelHandler reads and changes the runtime log level
+type LogLevelHandler struct {
+	level  *slog.LevelVar
+	logger interfaces.Logger
+}
+
+// NewLogLevelHandler creates a handler backed by the level the service logger was built with
+func NewLogLevelHandler(level *slog.LevelVar, logger interfaces.Logger) *LogLevelHandler {
+	return &LogLevelHandler{
+		level:  level,
+		logger: logger,
+	}
+}
+
+// Get handles GET /admin/loglevel
+func (h *LogLevelHandler) Get(w http.ResponseWriter, r *http.Request) {
+	h.sendLevel(w)
+}
+
+// Set handles PUT /admin/loglevel
+func (h *LogLevelHandler) Set(w http.ResponseWriter, r *http.Request) {
+	var req LogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	level, err := logger.ParseLevel(req.Level)
+	if err != nil {
+		sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	previous := h.level.Level()
+	h.level.Set(level)
+
+	// Logged at warn so the change is visible whatever the new level is
+	h.logger.Warn("Log level changed",
+		"previous_level", logger.LevelName(previous),
+		"level", logger.LevelName(level),
+		"remote_addr", r.RemoteAddr,
+	)
+
+	h.sendLevel(w)
+}
+
+func (h *LogLevelHandler) sendLevel(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(LogLevelResponse{Level: logger.LevelName(h.level.Level())})
+}
+
+func sendError(w http.ResponseWriter, message string, statusCode int) {
+	response := models.ErrorResponse{
+		Error:      message,
+		StatusCode: statusCode,
+		Timestamp:  time.Now(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
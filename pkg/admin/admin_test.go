@@ -0,0 +1,127 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "admin-secret"
+
+func newTestRouter(token string, level *slog.LevelVar, buf *bytes.Buffer) *mux.Router {
+	log := logger.NewAdapter(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: level})))
+	handler := NewLogLevelHandler(level, log)
+
+	router := mux.NewRouter()
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(RequireToken(token))
+	adminRouter.HandleFunc("/loglevel", handler.Get).Methods("GET")
+	adminRouter.HandleFunc("/loglevel", handler.Set).Methods("PUT")
+	return router
+}
+
+func setLevel(t *testing.T, router http.Handler, token, level string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("PUT", "/admin/loglevel", strings.NewReader(`{"level":"`+level+`"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestLogLevelHandler_Toggle(t *testing.T) {
+	var buf bytes.Buffer
+	level := new(slog.LevelVar)
+	router := newTestRouter(testToken, level, &buf)
+	log := logger.NewAdapter(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: level})))
+
+	log.Debug("debug before toggle")
+	assert.NotContains(t, buf.String(), "debug before toggle")
+
+	recorder := setLevel(t, router, testToken, "debug")
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response LogLevelResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	assert.Equal(t, "debug", response.Level)
+
+	log.Debug("debug while enabled")
+	assert.Contains(t, buf.String(), "debug while enabled")
+
+	recorder = setLevel(t, router, testToken, "info")
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	log.Debug("debug after toggle back")
+	assert.NotContains(t, buf.String(), "debug after toggle back")
+	assert.Contains(t, buf.String(), "Log level changed")
+}
+
+func TestLogLevelHandler_Get(t *testing.T) {
+	var buf bytes.Buffer
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelWarn)
+	router := newTestRouter(testToken, level, &buf)
+
+	req := httptest.NewRequest("GET", "/admin/loglevel", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"level":"warn"}`, recorder.Body.String())
+}
+
+func TestLogLevelHandler_InvalidLevel(t *testing.T) {
+	var buf bytes.Buffer
+	level := new(slog.LevelVar)
+	router := newTestRouter(testToken, level, &buf)
+
+	recorder := setLevel(t, router, testToken, "verbose")
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, slog.LevelInfo, level.Level())
+}
+
+func TestRequireToken(t *testing.T) {
+	tests := []struct {
+		name           string
+		configured     string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "valid token", configured: testToken, authorization: "Bearer " + testToken, expectedStatus: http.StatusOK},
+		{name: "wrong token", configured: testToken, authorization: "Bearer nope", expectedStatus: http.StatusUnauthorized},
+		{name: "missing header", configured: testToken, authorization: "", expectedStatus: http.StatusUnauthorized},
+		{name: "token without bearer scheme", configured: testToken, authorization: testToken, expectedStatus: http.StatusUnauthorized},
+		{name: "disabled when no token configured", configured: "", authorization: "Bearer ", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			level := new(slog.LevelVar)
+			router := newTestRouter(tt.configured, level, &buf)
+
+			req := httptest.NewRequest("PUT", "/admin/loglevel", strings.NewReader(`{"level":"debug"}`))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedStatus != http.StatusOK {
+				assert.Equal(t, slog.LevelInfo, level.Level())
+			}
+		})
+	}
+}
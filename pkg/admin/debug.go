@@ -0,0 +1,71 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// debugRoot is where the debug endpoints are served, and debugPrefix the
+// pprof endpoints among them
+const (
+	debugRoot   = "/debug/"
+	debugPrefix = debugRoot + "pprof/"
+)
+
+// DebugOptions says whether and where a service serves its pprof endpoints.
+// Profiles leak memory contents and a CPU profile ties up the process, so
+// they are off unless Enabled, and then either on their own listener at Addr,
+// meant to be internal only, or on the main router behind the admin token.
+// Handlers are served next to them under /debug/, by name.
+type DebugOptions struct {
+	Enabled  bool
+	Addr     string
+	Token    string
+	Handlers map[string]http.Handler
+}
+
+// MountDebug exposes the pprof endpoints as opts says. With Addr set it
+// returns the server to start for them, nil otherwise.
+func MountDebug(router *mux.Router, opts DebugOptions) *http.Server {
+	switch {
+	case !opts.Enabled:
+		return nil
+	case opts.Addr != "":
+		return &http.Server{
+			Addr:              opts.Addr,
+			Handler:           debugHandler(opts.Handlers),
+			ReadHeaderTimeout: 15 * time.Second,
+			IdleTimeout:       60 * time.Second,
+		}
+	default:
+		debugRouter := router.PathPrefix(debugPrefix).Subrouter()
+		debugRouter.Use(RequireToken(opts.Token))
+		debugRouter.PathPrefix("/").Handler(DebugHandler())
+		for name, handler := range opts.Handlers {
+			router.Handle(debugRoot+name, RequireToken(opts.Token)(handler))
+		}
+		return nil
+	}
+}
+
+// DebugHandler serves the pprof endpoints under /debug/pprof/
+func DebugHandler() http.Handler {
+	return debugHandler(nil)
+}
+
+// debugHandler serves the pprof endpoints and handlers under /debug/
+func debugHandler(handlers map[string]http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	for name, handler := range handlers {
+		mux.Handle(debugRoot+name, handler)
+	}
+	mux.HandleFunc(debugPrefix, pprof.Index)
+	mux.HandleFunc(debugPrefix+"cmdline", pprof.Cmdline)
+	mux.HandleFunc(debugPrefix+"profile", pprof.Profile)
+	mux.HandleFunc(debugPrefix+"symbol", pprof.Symbol)
+	mux.HandleFunc(debugPrefix+"trace", pprof.Trace)
+	return mux
+}
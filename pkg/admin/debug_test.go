@@ -0,0 +1,97 @@
+package admin
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getDebug(t *testing.T, router http.Handler, path, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestMountDebug_DisabledByDefault(t *testing.T) {
+	router := mux.NewRouter()
+	server := MountDebug(router, DebugOptions{Token: testToken})
+
+	assert.Nil(t, server)
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/profile"} {
+		assert.Equal(t, http.StatusNotFound, getDebug(t, router, path, testToken).Code, path)
+	}
+}
+
+func TestMountDebug_BehindAdminToken(t *testing.T) {
+	router := mux.NewRouter()
+	server := MountDebug(router, DebugOptions{Enabled: true, Token: testToken})
+	require.Nil(t, server)
+
+	assert.Equal(t, http.StatusUnauthorized, getDebug(t, router, "/debug/pprof/heap", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, getDebug(t, router, "/debug/pprof/heap", "wrong").Code)
+
+	recorder := getDebug(t, router, "/debug/pprof/", testToken)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "goroutine")
+
+	recorder = getDebug(t, router, "/debug/pprof/goroutine?debug=1", testToken)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "goroutine profile")
+}
+
+func TestMountDebug_SeparateListener(t *testing.T) {
+	router := mux.NewRouter()
+	server := MountDebug(router, DebugOptions{Enabled: true, Addr: "127.0.0.1:6060", Token: testToken})
+	require.NotNil(t, server)
+	assert.Equal(t, "127.0.0.1:6060", server.Addr)
+
+	// Nothing on the main router, even with the token
+	assert.Equal(t, http.StatusNotFound, getDebug(t, router, "/debug/pprof/heap", testToken).Code)
+
+	// The internal listener needs no token
+	internal := httptest.NewServer(server.Handler)
+	defer internal.Close()
+	resp, err := http.Get(internal.URL + "/debug/pprof/goroutine?debug=1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "goroutine profile")
+}
+
+func TestMountDebug_Handlers(t *testing.T) {
+	handlers := map[string]http.Handler{
+		"batches": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "[]") }),
+	}
+
+	router := mux.NewRouter()
+	require.Nil(t, MountDebug(router, DebugOptions{Token: testToken, Handlers: handlers}))
+	assert.Equal(t, http.StatusNotFound, getDebug(t, router, "/debug/batches", testToken).Code, "off unless enabled")
+
+	router = mux.NewRouter()
+	require.Nil(t, MountDebug(router, DebugOptions{Enabled: true, Token: testToken, Handlers: handlers}))
+	assert.Equal(t, http.StatusUnauthorized, getDebug(t, router, "/debug/batches", "").Code)
+	recorder := getDebug(t, router, "/debug/batches", testToken)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "[]", recorder.Body.String())
+
+	router = mux.NewRouter()
+	server := MountDebug(router, DebugOptions{Enabled: true, Addr: "127.0.0.1:6060", Token: testToken, Handlers: handlers})
+	require.NotNil(t, server)
+	assert.Equal(t, http.StatusNotFound, getDebug(t, router, "/debug/batches", testToken).Code)
+	recorder = getDebug(t, server.Handler, "/debug/batches", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "[]", recorder.Body.String())
+	assert.Equal(t, http.StatusOK, getDebug(t, server.Handler, "/debug/pprof/", "").Code)
+}
@@ -0,0 +1,177 @@
+// Package analyzer runs the webpage analysis in-process, for Go programs that
+// want it without calling the services over HTTP. New needs no arguments: it
+// logs through slog's default logger, records no metrics and checks the links
+// of a page itself with a pool of workers.
+//
+//	a := analyzer.New(analyzer.WithFetchTimeout(10 * time.Second))
+//	defer a.Close()
+//	result, err := a.Analyze(ctx, "https://example.com")
+//
+// The analyzer service is built on the same constructor, with the link checks
+// handed to the link checker service instead.
+package analyzer
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/config"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/pkg/metrics"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/services/analyzer/core"
+	linkchecker "github.com/RuvinSL/webpage-analyzer/services/link-checker/core"
+)
+
+// Result is the analysis of one page
+type Result = models.AnalysisResult
+
+// AnalyzeOptions are the per-call choices of AnalyzeWithOptions
+type AnalyzeOptions = models.AnalysisOptions
+
+// Analyzer analyzes pages. It is safe for concurrent use; Close releases the
+// link check workers once it is no longer needed.
+type Analyzer struct {
+	engine *core.Analyzer
+	// checker is nil when the links are checked by a caller-provided checker
+	checker *linkchecker.ConcurrentLinkChecker
+	stop    context.CancelFunc
+}
+
+type settings struct {
+	logger  interfaces.Logger
+	metrics interfaces.MetricsCollector
+
+	transport    http.RoundTripper
+	fetchTimeout time.Duration
+	// fixedTimeout is the Timeout of a WithHTTPClient client
+	fixedTimeout time.Duration
+	ipFamily     string
+	sourceIP     net.IP
+	resolver     httpclient.Resolver
+
+	analysisTimeout  time.Duration
+	maxRequests      int64
+	maxBytes         int64
+	parserLimits     core.ParserLimits
+	genericLinkTexts []string
+	titleMinLength   int
+	titleMaxLength   int
+	disabledRules    []string
+
+	linkChecker        interfaces.LinkChecker
+	linkCheckWorkers   int
+	linkCheckTimeout   time.Duration
+	linkCheckHostDelay time.Duration
+}
+
+// defaults are the services' own
+func defaults() settings {
+	service := config.DefaultAnalyzer()
+	checker := config.DefaultLinkChecker()
+	return settings{
+		logger:  logger.NewAdapter(slog.Default()),
+		metrics: metrics.Nop{},
+
+		fetchTimeout: service.FetchTimeout,
+		ipFamily:     service.IPFamily,
+
+		analysisTimeout: service.MaxAnalysisTimeout,
+		maxRequests:     int64(service.MaxRequestsPerAnalysis),
+		maxBytes:        int64(service.MaxBytesPerAnalysis),
+		parserLimits: core.ParserLimits{
+			MaxDepth:          service.ParserMaxDepth,
+			MaxLinks:          service.ParserMaxLinks,
+			MaxTextLength:     service.ParserMaxTextLength,
+			MaxAnchors:        service.ParserMaxAnchors,
+			LargeDataURIBytes: service.LargeDataURIBytes,
+		},
+		genericLinkTexts: service.GenericLinkTexts,
+		titleMinLength:   service.TitleMinLength,
+		titleMaxLength:   service.TitleMaxLength,
+		disabledRules:    service.DisabledRules,
+
+		linkCheckWorkers: checker.WorkerPoolSize,
+		linkCheckTimeout: checker.CheckTimeout,
+	}
+}
+
+// New returns an Analyzer configured by opts, with the services' defaults
+// for everything they leave out
+func New(opts ...Option) *Analyzer {
+	s := defaults()
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	a := &Analyzer{}
+	linkChecker := s.linkChecker
+	if linkChecker == nil {
+		ctx, stop := context.WithCancel(context.Background())
+		a.checker = linkchecker.NewConcurrentLinkChecker(s.newHTTPClient(s.linkCheckTimeout), s.linkCheckWorkers, s.logger, s.metrics)
+		a.checker.SetHostDelay(s.linkCheckHostDelay)
+		a.checker.SetLinkTimeout(s.linkCheckTimeout)
+		a.checker.Start(ctx)
+		a.stop = stop
+		linkChecker = a.checker
+	}
+
+	parser := core.NewHTMLParser(s.logger)
+	parser.SetLimits(s.parserLimits)
+
+	a.engine = core.NewAnalyzer(s.newHTTPClient(s.fetchTimeout), parser, linkChecker, s.logger, s.metrics)
+	a.engine.SetFetchTimeout(s.fetchTimeout)
+	a.engine.SetMaxTimeout(s.analysisTimeout)
+	a.engine.SetBudget(s.maxRequests, s.maxBytes)
+	a.engine.SetGenericLinkTexts(s.genericLinkTexts)
+	a.engine.SetTitleLength(s.titleMinLength, s.titleMaxLength)
+	a.engine.SetDisabledRules(s.disabledRules)
+	return a
+}
+
+// newHTTPClient returns a client that waits at most timeout for response
+// headers; the callers bound each request as a whole
+func (s *settings) newHTTPClient(timeout time.Duration) *httpclient.Client {
+	client := httpclient.New(timeout, s.logger)
+	client.SetFixedTimeout(s.fixedTimeout)
+	client.SetIPFamily(s.ipFamily)
+	client.SetSourceIP(s.sourceIP)
+	if s.resolver != nil {
+		client.SetResolver(s.resolver)
+	}
+	if s.transport != nil {
+		client.SetTransport(s.transport)
+	}
+	return client
+}
+
+// Analyze fetches the page at url and analyzes it, checking its links
+func (a *Analyzer) Analyze(ctx context.Context, url string) (*Result, error) {
+	return a.engine.AnalyzeURL(ctx, url)
+}
+
+// AnalyzeWithOptions is Analyze with per-call options, such as following
+// frames or skipping the cache
+func (a *Analyzer) AnalyzeWithOptions(ctx context.Context, url string, opts AnalyzeOptions) (*Result, error) {
+	return a.engine.AnalyzeURLWithOptions(ctx, url, opts)
+}
+
+// Core returns the analyzer underneath, for the settings the options do not
+// cover such as rendering and result caching; the analyzer service sets them
+// this way
+func (a *Analyzer) Core() *core.Analyzer {
+	return a.engine
+}
+
+// Close stops the link check workers. Analyses must not be started after it.
+func (a *Analyzer) Close() {
+	if a.checker != nil {
+		a.checker.Stop()
+		a.stop()
+	}
+}
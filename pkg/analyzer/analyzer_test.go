@@ -0,0 +1,232 @@
+package analyzer
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/rules"
+	"github.com/RuvinSL/webpage-analyzer/services/analyzer/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var quiet = WithLogger(slog.New(slog.DiscardHandler))
+
+func newSite(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, `<!DOCTYPE html><html><head><title>Home</title></head><body>
+<h1>Home</h1>
+<a href="/about">About</a>
+<a href="/missing">Missing</a>
+<a href="/about#team">Click here</a>
+</body></html>`)
+	})
+	mux.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<title>About</title>`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_ChecksLinksInProcess(t *testing.T) {
+	site := newSite(t)
+	a := New(quiet)
+	defer a.Close()
+
+	result, err := a.Analyze(context.Background(), site.URL)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Home", result.Title)
+	assert.Equal(t, "HTML5", result.HTMLVersion)
+	assert.Equal(t, 1, result.Headings.H1)
+	assert.Equal(t, 3, result.Links.Total)
+	assert.Equal(t, 3, result.Links.Internal)
+	assert.Equal(t, 1, result.Links.Inaccessible)
+	require.NotNil(t, result.Budget, "the services' budget applies by default")
+	require.NotNil(t, result.Accessibility, "and so do their generic link texts")
+	assert.Equal(t, models.LinkTextGeneric, result.Accessibility.LinkText[0].Kind)
+}
+
+func TestAnalyzer_WithLinkChecker(t *testing.T) {
+	site := newSite(t)
+	checker := &recordingChecker{}
+	a := New(quiet, WithLinkChecker(checker), WithGenericLinkTexts(nil))
+	defer a.Close()
+
+	assert.Nil(t, a.checker, "no workers are started for a provided checker")
+
+	result, err := a.Analyze(context.Background(), site.URL)
+	require.NoError(t, err)
+
+	assert.Len(t, checker.checked(), 2, "/about#team is checked as /about")
+	assert.Equal(t, 0, result.Links.Inaccessible, "the provided checker's answers are used")
+	assert.Nil(t, result.Accessibility)
+}
+
+func TestAnalyzer_Options(t *testing.T) {
+	site := newSite(t)
+	checker := &recordingChecker{}
+	a := New(quiet,
+		WithLinkChecker(checker),
+		WithParserLimits(core.ParserLimits{MaxLinks: 1}),
+		WithBudget(0, 0),
+	)
+	defer a.Close()
+
+	result, err := a.Analyze(context.Background(), site.URL)
+	require.NoError(t, err)
+
+	assert.Len(t, checker.checked(), 1)
+	assert.Nil(t, result.Budget)
+}
+
+func TestAnalyzer_WithDisabledRules(t *testing.T) {
+	site := newSite(t)
+	checker := &recordingChecker{}
+	a := New(quiet, WithLinkChecker(checker), WithDisabledRules(rules.Links))
+	defer a.Close()
+
+	result, err := a.Analyze(context.Background(), site.URL)
+	require.NoError(t, err)
+	assert.Empty(t, checker.checked())
+	assert.NotContains(t, result.Rules, rules.Links)
+
+	result, err = a.AnalyzeWithOptions(context.Background(), site.URL, AnalyzeOptions{
+		Rules: models.RuleSelection{Include: []string{rules.Links}},
+	})
+	require.NoError(t, err)
+	assert.Len(t, checker.checked(), 2, "/about#team is checked as /about")
+	assert.Equal(t, []string{rules.Links}, result.Rules)
+}
+
+func TestAnalyzer_WithFetchTimeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	a := New(quiet, WithFetchTimeout(50*time.Millisecond), WithLinkChecker(&recordingChecker{}))
+	defer a.Close()
+
+	start := time.Now()
+	_, err := a.Analyze(context.Background(), slow.URL)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestAnalyzer_WithFetchTimeout_StalledBody(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("<html>"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	a := New(quiet, WithFetchTimeout(50*time.Millisecond), WithLinkChecker(&recordingChecker{}))
+	defer a.Close()
+
+	start := time.Now()
+	_, err := a.Analyze(context.Background(), slow.URL)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "failed to read response")
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestAnalyzer_WithHTTPClient_Timeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	a := New(quiet, WithHTTPClient(&http.Client{Timeout: 50 * time.Millisecond}), WithLinkChecker(&recordingChecker{}))
+	defer a.Close()
+
+	start := time.Now()
+	_, err := a.Analyze(context.Background(), slow.URL)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestAnalyzer_WithHTTPClient(t *testing.T) {
+	site := newSite(t)
+	transport := &countingTransport{next: http.DefaultTransport}
+	a := New(quiet, WithHTTPClient(&http.Client{Transport: transport}))
+	defer a.Close()
+
+	_, err := a.Analyze(context.Background(), site.URL)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1+2, transport.count(), "the page and its two distinct links")
+}
+
+// recordingChecker reports every link accessible and keeps them
+type recordingChecker struct {
+	mu    sync.Mutex
+	links []models.Link
+}
+
+func (c *recordingChecker) CheckLinks(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+	statuses := make([]models.LinkStatus, len(links))
+	for i, link := range links {
+		statuses[i] = c.CheckLink(ctx, link)
+	}
+	return statuses, nil
+}
+
+func (c *recordingChecker) CheckLink(ctx context.Context, link models.Link) models.LinkStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.links = append(c.links, link)
+	return models.LinkStatus{Link: link, Accessible: true, StatusCode: http.StatusOK}
+}
+
+func (c *recordingChecker) checked() []models.Link {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.links
+}
+
+type countingTransport struct {
+	next     http.RoundTripper
+	mu       sync.Mutex
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.requests++
+	t.mu.Unlock()
+	return t.next.RoundTrip(req)
+}
+
+func (t *countingTransport) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.requests
+}
@@ -0,0 +1,64 @@
+package analyzer_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/analyzer"
+)
+
+// site serves a fixed set of pages without a network, standing in for the
+// web in the examples
+type site map[string]string
+
+func (s site) RoundTrip(req *http.Request) (*http.Response, error) {
+	page, ok := s[req.URL.String()]
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+		Body:       io.NopCloser(strings.NewReader(page)),
+		Request:    req,
+	}, nil
+}
+
+var examplePages = site{
+	"https://example.com/": `<!DOCTYPE html>
+<html><head><title>Example</title></head><body>
+<h1>Welcome</h1><h2>News</h2><h2>Docs</h2>
+<a href="/docs">Docs</a>
+<a href="/gone">Old page</a>
+<a href="https://other.example/">Partner</a>
+</body></html>`,
+	"https://example.com/docs": `<title>Docs</title>`,
+	"https://other.example/":   `<title>Partner</title>`,
+}
+
+func Example() {
+	a := analyzer.New(
+		analyzer.WithHTTPClient(&http.Client{Transport: examplePages}),
+		analyzer.WithLogger(slog.New(slog.DiscardHandler)),
+	)
+	defer a.Close()
+
+	result, err := a.Analyze(context.Background(), "https://example.com/")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(result.Title, result.HTMLVersion)
+	fmt.Println("h1:", result.Headings.H1, "h2:", result.Headings.H2)
+	fmt.Println("internal:", result.Links.Internal, "external:", result.Links.External, "inaccessible:", result.Links.Inaccessible)
+	// Output:
+	// Example HTML5
+	// h1: 1 h2: 2
+	// internal: 2 external: 1 inaccessible: 1
+}
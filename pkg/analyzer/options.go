@@ -0,0 +1,138 @@
+package analyzer
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/services/analyzer/core"
+)
+
+// Option configures an Analyzer in New
+type Option func(*settings)
+
+// WithLogger logs through l instead of slog's default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(s *settings) { s.logger = logger.NewAdapter(l) }
+}
+
+// WithServiceLogger logs through the services' logger, see pkg/logger
+func WithServiceLogger(l interfaces.Logger) Option {
+	return func(s *settings) { s.logger = l }
+}
+
+// WithMetrics records the analyses and link checks with m
+func WithMetrics(m interfaces.MetricsCollector) Option {
+	return func(s *settings) { s.metrics = m }
+}
+
+// WithHTTPClient fetches pages and checks links through client's Transport,
+// and within its Timeout when it has one. The redirect policy and outbound
+// budget stay the analyzer's own, so client's CheckRedirect and Jar are not
+// used; nor are WithResolver, WithIPFamily and WithSourceIP, which apply to
+// the built-in transport.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *settings) {
+		s.transport = client.Transport
+		if s.transport == nil {
+			s.transport = http.DefaultTransport
+		}
+		s.fixedTimeout = client.Timeout
+	}
+}
+
+// WithFetchTimeout bounds the fetch of the page itself
+func WithFetchTimeout(timeout time.Duration) Option {
+	return func(s *settings) { s.fetchTimeout = timeout }
+}
+
+// WithResolver looks host names up with resolver, such as a
+// httpclient.DoHResolver, instead of the system resolver
+func WithResolver(resolver httpclient.Resolver) Option {
+	return func(s *settings) { s.resolver = resolver }
+}
+
+// WithIPFamily connects over "ipv4" or "ipv6" only; "dual", the default,
+// allows both
+func WithIPFamily(family string) Option {
+	return func(s *settings) { s.ipFamily = family }
+}
+
+// WithSourceIP makes the page fetches and link checks originate from ip,
+// an address of the host as httpclient.ParseSourceIP checks
+func WithSourceIP(ip net.IP) Option {
+	return func(s *settings) { s.sourceIP = ip }
+}
+
+// WithAnalysisTimeout bounds a whole analysis, link checks included
+func WithAnalysisTimeout(timeout time.Duration) Option {
+	return func(s *settings) { s.analysisTimeout = timeout }
+}
+
+// WithBudget caps the outbound requests and response bytes of one analysis;
+// links left unchecked once it is spent are reported as skipped. Zero
+// maxRequests lifts it.
+func WithBudget(maxRequests, maxBytes int64) Option {
+	return func(s *settings) {
+		s.maxRequests = maxRequests
+		s.maxBytes = maxBytes
+	}
+}
+
+// WithParserLimits bounds what one document can cost the parser, including
+// how many links are extracted and checked; zero fields keep the defaults
+func WithParserLimits(limits core.ParserLimits) Option {
+	return func(s *settings) { s.parserLimits = limits }
+}
+
+// WithGenericLinkTexts sets the link texts reported as generic, such as
+// "click here"
+func WithGenericLinkTexts(texts []string) Option {
+	return func(s *settings) { s.genericLinkTexts = texts }
+}
+
+// WithTitleLength sets the range of title lengths, in characters, not
+// reported as too short or too long; zero keeps a bound's default
+func WithTitleLength(minLength, maxLength int) Option {
+	return func(s *settings) {
+		s.titleMinLength = minLength
+		s.titleMaxLength = maxLength
+	}
+}
+
+// WithDisabledRules turns the named checks of pkg/rules off unless an
+// analysis includes them in its options
+func WithDisabledRules(names ...string) Option {
+	return func(s *settings) { s.disabledRules = names }
+}
+
+// WithLinkChecker checks the links with checker instead of in-process, as the
+// analyzer service does with the link checker service; the WithLinkCheck
+// options then do not apply
+func WithLinkChecker(checker interfaces.LinkChecker) Option {
+	return func(s *settings) { s.linkChecker = checker }
+}
+
+// WithLinkCheckWorkers sets how many links are checked at once
+func WithLinkCheckWorkers(workers int) Option {
+	return func(s *settings) {
+		if workers > 0 {
+			s.linkCheckWorkers = workers
+		}
+	}
+}
+
+// WithLinkCheckTimeout bounds the check of each link
+func WithLinkCheckTimeout(timeout time.Duration) Option {
+	return func(s *settings) { s.linkCheckTimeout = timeout }
+}
+
+// WithLinkCheckHostDelay spaces the checks of links to the same host at
+// least delay apart
+func WithLinkCheckHostDelay(delay time.Duration) Option {
+	return func(s *settings) { s.linkCheckHostDelay = delay }
+}
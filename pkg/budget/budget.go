@@ -0,0 +1,103 @@
+// Package budget caps the outbound traffic of a single analysis. A Budget is
+// carried in the request context, so every HTTP client along the way, in this
+// process or in the link checker, charges the same allowance.
+package budget
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// ErrExhausted is returned for requests refused because the budget is spent
+var ErrExhausted = errors.New("outbound request budget exhausted")
+
+// SkippedError is the LinkStatus error of links not checked for lack of budget
+const SkippedError = "skipped: budget exhausted"
+
+// Budget counts outbound requests, redirects included, and response bytes
+// against fixed limits. It is safe for concurrent use.
+type Budget struct {
+	maxRequests int64
+	maxBytes    int64
+
+	requests  atomic.Int64
+	bytes     atomic.Int64
+	exhausted atomic.Bool
+}
+
+func New(maxRequests, maxBytes int64) *Budget {
+	return &Budget{
+		maxRequests: max(maxRequests, 0),
+		maxBytes:    max(maxBytes, 0),
+	}
+}
+
+// FromLimits creates a budget from limits sent by another service
+func FromLimits(limits models.BudgetLimits) *Budget {
+	return New(limits.MaxRequests, limits.MaxBytes)
+}
+
+// TryRequest reserves one request. It returns false, and marks the budget
+// exhausted, once either the request or the byte allowance is used up.
+func (b *Budget) TryRequest() bool {
+	if b.bytes.Load() >= b.maxBytes {
+		b.exhausted.Store(true)
+		return false
+	}
+	if b.requests.Add(1) > b.maxRequests {
+		b.requests.Add(-1)
+		b.exhausted.Store(true)
+		return false
+	}
+	return true
+}
+
+// AddBytes records n response bytes
+func (b *Budget) AddBytes(n int64) {
+	b.bytes.Add(n)
+}
+
+// Merge records traffic spent on the budget's behalf elsewhere, such as by
+// the link checker service
+func (b *Budget) Merge(used models.BudgetUsage) {
+	b.requests.Add(used.Requests)
+	b.bytes.Add(used.Bytes)
+	if used.Exhausted {
+		b.exhausted.Store(true)
+	}
+}
+
+// Remaining returns what is left of the budget, to hand on to another service
+func (b *Budget) Remaining() models.BudgetLimits {
+	return models.BudgetLimits{
+		MaxRequests: max(b.maxRequests-b.requests.Load(), 0),
+		MaxBytes:    max(b.maxBytes-b.bytes.Load(), 0),
+	}
+}
+
+// Usage reports the traffic so far against the limits
+func (b *Budget) Usage() models.BudgetUsage {
+	return models.BudgetUsage{
+		Requests:    b.requests.Load(),
+		Bytes:       b.bytes.Load(),
+		MaxRequests: b.maxRequests,
+		MaxBytes:    b.maxBytes,
+		Exhausted:   b.exhausted.Load(),
+	}
+}
+
+type contextKey struct{}
+
+// WithBudget returns a context that charges its outbound requests to b
+func WithBudget(ctx context.Context, b *Budget) context.Context {
+	return context.WithValue(ctx, contextKey{}, b)
+}
+
+// FromContext returns the budget carried by ctx, or nil when it has none
+func FromContext(ctx context.Context) *Budget {
+	b, _ := ctx.Value(contextKey{}).(*Budget)
+	return b
+}
@@ -0,0 +1,65 @@
+package budget
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget_TryRequest(t *testing.T) {
+	b := New(50, 1<<20)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	granted := 0
+	for range 200 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if b.TryRequest() {
+				mu.Lock()
+				granted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 50, granted)
+	assert.Equal(t, models.BudgetUsage{Requests: 50, MaxRequests: 50, MaxBytes: 1 << 20, Exhausted: true}, b.Usage())
+}
+
+func TestBudget_BytesExhaustBudget(t *testing.T) {
+	b := New(10, 100)
+
+	assert.True(t, b.TryRequest())
+	b.AddBytes(100)
+	assert.False(t, b.TryRequest())
+	assert.True(t, b.Usage().Exhausted)
+	assert.Equal(t, models.BudgetLimits{MaxRequests: 9, MaxBytes: 0}, b.Remaining())
+}
+
+func TestBudget_MergeAndRemaining(t *testing.T) {
+	b := New(10, 1000)
+	assert.True(t, b.TryRequest())
+	b.AddBytes(200)
+
+	b.Merge(models.BudgetUsage{Requests: 4, Bytes: 300})
+	assert.Equal(t, models.BudgetLimits{MaxRequests: 5, MaxBytes: 500}, b.Remaining())
+	assert.False(t, b.Usage().Exhausted)
+
+	// Spending past the limits elsewhere never leaves a negative remainder
+	b.Merge(models.BudgetUsage{Requests: 20, Bytes: 2000, Exhausted: true})
+	assert.Equal(t, models.BudgetLimits{}, b.Remaining())
+	assert.True(t, b.Usage().Exhausted)
+}
+
+func TestContext(t *testing.T) {
+	assert.Nil(t, FromContext(context.Background()))
+
+	b := New(1, 1)
+	assert.Same(t, b, FromContext(WithBudget(context.Background(), b)))
+}
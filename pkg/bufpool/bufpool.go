@@ -0,0 +1,83 @@
+// Package bufpool recycles byte buffers between requests.
+//
+// A buffer taken with Get or GetAtLeast belongs to the caller until it is
+// handed back with Put. Nothing read from a pooled buffer may outlive the
+// Put: copy results out first (bytes.Clone, or string(buf.Bytes())) so
+// returned models never alias pooled memory.
+package bufpool
+
+import (
+	"bytes"
+	"sync"
+)
+
+// MaxPooledSize is the largest buffer capacity Get hands out from its pool
+const MaxPooledSize = 1 << 20
+
+// MaxClassSize is the largest buffer capacity kept for reuse. Buffers above
+// MaxPooledSize are kept by size class, the powers of two up to it, and only
+// GetAtLeast hands them out, so that a large body reuses a large buffer
+// while small ones keep taking small buffers. Bigger buffers are left to the
+// garbage collector so one huge page does not pin its memory for the life of
+// the process.
+const MaxClassSize = 16 << 20
+
+var pool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// classes pool the buffers of at least 2, 4, 8 and 16 MiB; a buffer goes in
+// the largest class its capacity reaches
+var classes [classCount]sync.Pool
+
+const classCount = 4
+
+// classSize is the least capacity of the buffers of class i
+func classSize(i int) int {
+	return MaxPooledSize << (i + 1)
+}
+
+// Get returns an empty buffer
+func Get() *bytes.Buffer {
+	return pool.Get().(*bytes.Buffer)
+}
+
+// GetAtLeast returns an empty buffer of capacity n or more, from the size
+// class holding n when n is above MaxPooledSize
+func GetAtLeast(n int) *bytes.Buffer {
+	if n <= MaxPooledSize {
+		buf := Get()
+		buf.Grow(n)
+		return buf
+	}
+	for i := range classes {
+		if size := classSize(i); size >= n {
+			if buf, ok := classes[i].Get().(*bytes.Buffer); ok {
+				return buf
+			}
+			return bytes.NewBuffer(make([]byte, 0, size))
+		}
+	}
+	return bytes.NewBuffer(make([]byte, 0, n))
+}
+
+// Put resets buf and returns it to the pool, or to its size class. buf must
+// not be used afterwards.
+func Put(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > MaxClassSize {
+		return
+	}
+	buf.Reset()
+	if buf.Cap() <= MaxPooledSize {
+		pool.Put(buf)
+		return
+	}
+	for i := classCount - 1; i >= 0; i-- {
+		if buf.Cap() >= classSize(i) {
+			classes[i].Put(buf)
+			return
+		}
+	}
+	// Between MaxPooledSize and the first class: too big for Get, too small
+	// for GetAtLeast
+}
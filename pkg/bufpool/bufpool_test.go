@@ -0,0 +1,46 @@
+package bufpool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet_ReturnsEmptyBuffer(t *testing.T) {
+	buf := Get()
+	buf.WriteString("leftover")
+	Put(buf)
+
+	assert.Zero(t, Get().Len())
+}
+
+func TestPut_DropsOversizedBuffers(t *testing.T) {
+	buf := GetAtLeast(MaxClassSize + 1)
+	assert.GreaterOrEqual(t, buf.Cap(), MaxClassSize+1)
+
+	// Must not panic, and the buffer is simply not kept
+	Put(buf)
+	Put(nil)
+
+	assert.LessOrEqual(t, GetAtLeast(MaxClassSize).Cap(), MaxClassSize)
+}
+
+func TestGetAtLeast_SizeClasses(t *testing.T) {
+	small := GetAtLeast(4096)
+	assert.GreaterOrEqual(t, small.Cap(), 4096)
+	assert.LessOrEqual(t, small.Cap(), MaxPooledSize)
+
+	for _, n := range []int{MaxPooledSize + 1, 3 << 20, 8 << 20, 10<<20 + 1} {
+		buf := GetAtLeast(n)
+		assert.GreaterOrEqual(t, buf.Cap(), n)
+		assert.LessOrEqual(t, buf.Cap(), MaxClassSize)
+		buf.WriteString("leftover")
+		Put(buf)
+
+		assert.Zero(t, GetAtLeast(n).Len())
+	}
+
+	// Large buffers stay out of Get's pool
+	Put(GetAtLeast(4 << 20))
+	assert.LessOrEqual(t, Get().Cap(), MaxPooledSize)
+}
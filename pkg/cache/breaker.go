@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker stops calls to a failing backend. After threshold consecutive
+// failures it opens for cooldown; once the cooldown passes calls are let
+// through again, and the first one to fail reopens it straight away.
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	threshold int
+	cooldown  time.Duration
+	openUntil time.Time
+
+	now func() time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow reports whether a call may go to the backend
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.now().Before(b.openUntil)
+}
+
+// record notes the outcome of a call. opened is set when this failure opened
+// the breaker and recovered when this success closed it.
+func (b *breaker) record(err error) (opened, recovered bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		recovered = b.failures >= b.threshold
+		b.failures = 0
+		return false, recovered
+	}
+
+	b.failures++
+	if b.failures < b.threshold {
+		return false, false
+	}
+	b.openUntil = b.now().Add(b.cooldown)
+	// A failed call after the cooldown reopens the breaker within the same
+	// outage, which is only reported once
+	return b.failures == b.threshold, false
+}
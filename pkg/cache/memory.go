@@ -0,0 +1,114 @@
+// Package cache provides interfaces.Cache implementations.
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+)
+
+// ErrMiss is returned by Get when the key is absent or has expired
+var ErrMiss = errors.New("cache miss")
+
+type entry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// Memory is an in-process cache holding up to maxEntries values. When full,
+// the entry closest to expiry is evicted to make room.
+type Memory struct {
+	mu         sync.Mutex
+	items      map[string]entry
+	maxEntries int
+
+	now func() time.Time
+}
+
+func NewMemory(maxEntries int) *Memory {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	return &Memory{
+		items:      make(map[string]entry),
+		maxEntries: maxEntries,
+		now:        time.Now,
+	}
+}
+
+// Get returns a copy of the value stored under key
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.items[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	if !m.now().Before(e.expiresAt) {
+		delete(m.items, key)
+		return nil, ErrMiss
+	}
+	return append([]byte(nil), e.value...), nil
+}
+
+// Set stores a copy of value under key for ttl seconds
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if _, exists := m.items[key]; !exists {
+		m.evictLocked(now)
+		for len(m.items) >= m.maxEntries {
+			m.evictSoonestLocked()
+		}
+	}
+
+	m.items[key] = entry{
+		value:     append([]byte(nil), value...),
+		expiresAt: now.Add(time.Duration(ttl) * time.Second),
+	}
+	return nil
+}
+
+// Delete removes key; deleting a missing key is not an error
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.items, key)
+	return nil
+}
+
+// Len returns the number of stored entries, including expired ones not yet evicted
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.items)
+}
+
+func (m *Memory) evictLocked(now time.Time) {
+	for key, e := range m.items {
+		if !now.Before(e.expiresAt) {
+			delete(m.items, key)
+		}
+	}
+}
+
+func (m *Memory) evictSoonestLocked() {
+	var soonestKey string
+	var soonest time.Time
+	first := true
+	for key, e := range m.items {
+		if first || e.expiresAt.Before(soonest) {
+			soonestKey, soonest, first = key, e.expiresAt, false
+		}
+	}
+	delete(m.items, soonestKey)
+}
+
+// Ensure Memory implements interfaces.Cache
+var _ interfaces.Cache = (*Memory)(nil)
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestMemory returns a cache whose clock only moves when advance is called
+func newTestMemory(maxEntries int) (*Memory, func(time.Duration)) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewMemory(maxEntries)
+	cache.now = func() time.Time { return now }
+	return cache, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestMemory_SetGetDelete(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newTestMemory(10)
+
+	require.NoError(t, cache.Set(ctx, "a", []byte("one"), 60))
+	value, err := cache.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("one"), value)
+
+	// Callers get their own copy
+	value[0] = 'X'
+	value, err = cache.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("one"), value)
+
+	require.NoError(t, cache.Delete(ctx, "a"))
+	_, err = cache.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrMiss)
+	assert.NoError(t, cache.Delete(ctx, "a"))
+}
+
+func TestMemory_TTLExpiry(t *testing.T) {
+	ctx := context.Background()
+	cache, advance := newTestMemory(10)
+
+	require.NoError(t, cache.Set(ctx, "a", []byte("one"), 60))
+	advance(59 * time.Second)
+	_, err := cache.Get(ctx, "a")
+	require.NoError(t, err)
+
+	advance(time.Second)
+	_, err = cache.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrMiss)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestMemory_EvictsSoonestExpiryWhenFull(t *testing.T) {
+	ctx := context.Background()
+	cache, _ := newTestMemory(2)
+
+	require.NoError(t, cache.Set(ctx, "short", []byte("1"), 10))
+	require.NoError(t, cache.Set(ctx, "long", []byte("2"), 100))
+	require.NoError(t, cache.Set(ctx, "new", []byte("3"), 50))
+
+	assert.Equal(t, 2, cache.Len())
+	_, err := cache.Get(ctx, "short")
+	assert.ErrorIs(t, err, ErrMiss)
+	_, err = cache.Get(ctx, "long")
+	assert.NoError(t, err)
+
+	// Overwriting an existing key never evicts another
+	require.NoError(t, cache.Set(ctx, "long", []byte("4"), 100))
+	assert.Equal(t, 2, cache.Len())
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisOptions configures a Redis cache
+type RedisOptions struct {
+	// URL is a redis:// or rediss:// address, e.g. redis://:secret@redis:6379/0
+	URL string
+	// KeyPrefix namespaces the keys so several deployments can share a server
+	KeyPrefix string
+	// Timeout bounds dialing and each command
+	Timeout time.Duration
+	// After BreakerFailures consecutive failed commands Redis is bypassed for
+	// BreakerCooldown
+	BreakerFailures int
+	BreakerCooldown time.Duration
+}
+
+// Redis is a cache shared by every replica pointing at the same server. It
+// fails open: while Redis is unreachable Get reports a miss and Set and
+// Delete do nothing, so callers carry on as if the cache were empty.
+type Redis struct {
+	client  *redis.Client
+	prefix  string
+	timeout time.Duration
+	breaker *breaker
+	logger  interfaces.Logger
+}
+
+func NewRedis(opts RedisOptions, logger interfaces.Logger) (*Redis, error) {
+	clientOpts, err := redis.ParseURL(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	clientOpts.DialTimeout = opts.Timeout
+	clientOpts.ReadTimeout = opts.Timeout
+	clientOpts.WriteTimeout = opts.Timeout
+	// The breaker decides when to try again
+	clientOpts.MaxRetries = -1
+
+	return &Redis{
+		client:  redis.NewClient(clientOpts),
+		prefix:  opts.KeyPrefix,
+		timeout: opts.Timeout,
+		breaker: newBreaker(opts.BreakerFailures, opts.BreakerCooldown),
+		logger:  logger,
+	}, nil
+}
+
+// Get returns the value stored under key. Errors always wrap ErrMiss.
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	if !r.breaker.allow() {
+		return nil, ErrMiss
+	}
+
+	var value []byte
+	err := r.do(ctx, func(ctx context.Context) error {
+		var err error
+		value, err = r.client.Get(ctx, r.prefix+key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMiss, err)
+	}
+	if value == nil {
+		return nil, ErrMiss
+	}
+	return value, nil
+}
+
+// Set stores value under key for ttl seconds. Like Memory, a ttl below one
+// second stores nothing.
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl int) error {
+	if !r.breaker.allow() {
+		return nil
+	}
+	if ttl < 1 {
+		return r.do(ctx, func(ctx context.Context) error {
+			return r.client.Del(ctx, r.prefix+key).Err()
+		})
+	}
+	return r.do(ctx, func(ctx context.Context) error {
+		return r.client.Set(ctx, r.prefix+key, value, time.Duration(ttl)*time.Second).Err()
+	})
+}
+
+// Delete removes key; deleting a missing key is not an error
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	if !r.breaker.allow() {
+		return nil
+	}
+	return r.do(ctx, func(ctx context.Context) error {
+		return r.client.Del(ctx, r.prefix+key).Err()
+	})
+}
+
+// CheckHealth pings Redis
+func (r *Redis) CheckHealth(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	return r.client.Ping(ctx).Err()
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+// do runs one command within the timeout and feeds the outcome to the
+// breaker. Calls abandoned by the caller say nothing about Redis and are not
+// counted.
+func (r *Redis) do(ctx context.Context, command func(context.Context) error) error {
+	cmdCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	err := command(cmdCtx)
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+
+	opened, recovered := r.breaker.record(err)
+	switch {
+	case opened:
+		r.logger.Warn("Redis unavailable, bypassing the shared cache", "cooldown", r.breaker.cooldown, "error", err)
+	case recovered:
+		r.logger.Info("Redis reachable again, shared cache resumed")
+	}
+	return err
+}
+
+// Ensure Redis implements interfaces.Cache
+var _ interfaces.Cache = (*Redis)(nil)
@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	cache, err := NewRedis(RedisOptions{
+		URL:             "redis://" + server.Addr(),
+		KeyPrefix:       "test:",
+		Timeout:         200 * time.Millisecond,
+		BreakerFailures: 2,
+		BreakerCooldown: time.Minute,
+	}, logger.NewAdapter(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	require.NoError(t, err)
+	t.Cleanup(func() { cache.Close() })
+	return cache, server
+}
+
+func TestRedis_SetGetDelete(t *testing.T) {
+	ctx := context.Background()
+	cache, server := newTestRedis(t)
+
+	_, err := cache.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrMiss)
+
+	require.NoError(t, cache.Set(ctx, "a", []byte("one"), 60))
+	value, err := cache.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("one"), value)
+	assert.True(t, server.Exists("test:a"), "keys carry the prefix")
+
+	require.NoError(t, cache.Delete(ctx, "a"))
+	_, err = cache.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrMiss)
+	assert.NoError(t, cache.Delete(ctx, "a"))
+}
+
+func TestRedis_TTL(t *testing.T) {
+	ctx := context.Background()
+	cache, server := newTestRedis(t)
+
+	require.NoError(t, cache.Set(ctx, "a", []byte("one"), 60))
+	assert.Equal(t, 60*time.Second, server.TTL("test:a"))
+
+	server.FastForward(61 * time.Second)
+	_, err := cache.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrMiss)
+
+	// A ttl below one second stores nothing, as in Memory
+	require.NoError(t, cache.Set(ctx, "b", []byte("two"), 60))
+	require.NoError(t, cache.Set(ctx, "b", []byte("two"), 0))
+	assert.False(t, server.Exists("test:b"))
+}
+
+func TestRedis_FailsOpenDuringOutage(t *testing.T) {
+	ctx := context.Background()
+	cache, server := newTestRedis(t)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.breaker.now = func() time.Time { return now }
+
+	require.NoError(t, cache.Set(ctx, "a", []byte("one"), 600))
+	server.Close()
+
+	// Failures surface until the breaker opens; Get reports them as misses
+	_, err := cache.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrMiss)
+	assert.NotEqual(t, ErrMiss, err)
+	assert.Error(t, cache.Set(ctx, "a", []byte("one"), 60))
+	assert.False(t, cache.breaker.allow())
+
+	// While open, Redis is not called at all
+	_, err = cache.Get(ctx, "a")
+	assert.Equal(t, ErrMiss, err)
+	assert.NoError(t, cache.Set(ctx, "a", []byte("two"), 60))
+	assert.NoError(t, cache.Delete(ctx, "a"))
+
+	// After the cooldown the next call goes through and closes the breaker
+	require.NoError(t, server.Restart())
+	now = now.Add(time.Minute)
+	value, err := cache.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("one"), value)
+	assert.Equal(t, 0, cache.breaker.failures)
+}
+
+func TestRedis_CallerCancellationIsNotAFailure(t *testing.T) {
+	cache, _ := newTestRedis(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for range 3 {
+		_, err := cache.Get(ctx, "a")
+		assert.ErrorIs(t, err, ErrMiss)
+	}
+	assert.True(t, cache.breaker.allow())
+}
+
+func TestNewRedis_InvalidURL(t *testing.T) {
+	_, err := NewRedis(RedisOptions{URL: "http://redis:6379"}, nil)
+	assert.Error(t, err)
+}
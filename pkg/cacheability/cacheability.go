@@ -0,0 +1,271 @@
+// Package cacheability evaluates whether, and for how long, browsers and
+// shared caches may store a response, from its Cache-Control, Pragma,
+// Expires, Date, Age, Last-Modified, ETag and Vary headers as RFC 9111
+// reads them.
+package cacheability
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// Where the freshness lifetime of a response comes from
+const (
+	SourceMaxAge    = "max-age"
+	SourceExpires   = "expires"
+	SourceHeuristic = "heuristic"
+)
+
+// maxDeltaSeconds is the largest delta-seconds value caches honour; larger
+// values are read as this one
+const maxDeltaSeconds = math.MaxInt32 + 1
+
+// heuristicFraction is the share of the time since Last-Modified that caches
+// commonly take as the freshness lifetime of a response without one
+const heuristicFraction = 10
+
+// heuristicStatuses can be stored without explicit freshness information
+var heuristicStatuses = []int{
+	http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent, http.StatusPartialContent,
+	http.StatusMultipleChoices, http.StatusMovedPermanently, http.StatusPermanentRedirect,
+	http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusGone, http.StatusRequestURITooLong,
+	http.StatusNotImplemented,
+}
+
+// Evaluate reads the caching headers of a response with statusCode. now is
+// when the response was received, used in place of a missing Date. It
+// returns nil when there are no headers to evaluate.
+func Evaluate(statusCode int, headers http.Header, now time.Time) *models.Cacheability {
+	if headers == nil {
+		return nil
+	}
+
+	result := &models.Cacheability{
+		ETag:         headers.Get("ETag"),
+		LastModified: headers.Get("Last-Modified"),
+		Vary:         varyFields(headers.Values("Vary")),
+	}
+	var notes []string
+
+	cc := parseCacheControl(headers.Values("Cache-Control"))
+	if len(cc) == 0 && hasToken(headers.Values("Pragma"), "no-cache") {
+		cc["no-cache"] = []string{""}
+		notes = append(notes, "Pragma: no-cache without Cache-Control is read as Cache-Control: no-cache")
+	}
+
+	_, result.NoStore = cc["no-store"]
+	_, result.Public = cc["public"]
+	_, result.MustRevalidate = cc["must-revalidate"]
+	_, result.Immutable = cc["immutable"]
+	result.NoCache, notes = unqualified(cc, "no-cache", notes)
+	result.Private, notes = unqualified(cc, "private", notes)
+
+	date := now
+	if parsed, err := http.ParseTime(headers.Get("Date")); err == nil {
+		date = parsed
+	}
+	result.AgeSeconds, _ = deltaSeconds(headers.Get("Age"))
+
+	// Freshness lifetime: max-age, else Expires, else the heuristic
+	explicit := false
+	if values, ok := cc["max-age"]; ok {
+		explicit = true
+		result.FreshnessSource = SourceMaxAge
+		result.MaxAgeSeconds, notes = directiveSeconds("max-age", values, notes)
+		if len(headers.Values("Expires")) > 0 {
+			notes = append(notes, "max-age takes precedence over Expires")
+		}
+	} else if expires := headers.Values("Expires"); len(expires) > 0 {
+		explicit = true
+		result.FreshnessSource = SourceExpires
+		if at, err := http.ParseTime(expires[0]); err == nil {
+			result.MaxAgeSeconds = max(0, int64(at.Sub(date)/time.Second))
+		} else {
+			notes = append(notes, fmt.Sprintf("Expires %q is not a date, so the page is already stale", expires[0]))
+		}
+	}
+	if values, ok := cc["s-maxage"]; ok {
+		explicit = true
+		var seconds int64
+		seconds, notes = directiveSeconds("s-maxage", values, notes)
+		result.SharedMaxAgeSeconds = &seconds
+	}
+
+	heuristic := slices.Contains(heuristicStatuses, statusCode)
+	if !explicit && heuristic && !result.NoCache {
+		if modified, err := http.ParseTime(result.LastModified); err == nil && modified.Before(date) {
+			result.FreshnessSource = SourceHeuristic
+			result.MaxAgeSeconds = int64(date.Sub(modified)/time.Second) / heuristicFraction
+			notes = append(notes, fmt.Sprintf("no explicit freshness, caches commonly reuse the page for %d%% of the time since Last-Modified", 100/heuristicFraction))
+		}
+	}
+
+	// Whether the response may be stored at all, and by which caches
+	switch {
+	case result.NoStore:
+		notes = append(notes, "not cacheable because Cache-Control: no-store forbids storing it")
+	case slices.Contains(result.Vary, "*"):
+		notes = append(notes, "not cacheable because Vary: * makes every later request a miss")
+	case !heuristic && !explicit && !result.Public && cc["private"] == nil:
+		notes = append(notes, fmt.Sprintf("not cacheable because status %d needs explicit freshness such as max-age", statusCode))
+	default:
+		result.Cacheable = true
+		result.SharedCacheable = !result.Private
+	}
+	if result.Cacheable && result.Private {
+		notes = append(notes, "Cache-Control: private keeps the page out of shared caches such as CDNs")
+	}
+	if !result.SharedCacheable {
+		result.SharedMaxAgeSeconds = nil
+	}
+
+	if result.NoCache {
+		if result.MaxAgeSeconds > 0 || result.SharedMaxAgeSeconds != nil && *result.SharedMaxAgeSeconds > 0 {
+			notes = append(notes, "no-cache overrides max-age: the page may be stored but is revalidated before every reuse")
+		} else if result.Cacheable {
+			notes = append(notes, "Cache-Control: no-cache requires revalidation before every reuse")
+		}
+		result.MaxAgeSeconds = 0
+		if result.SharedMaxAgeSeconds != nil {
+			result.SharedMaxAgeSeconds = new(int64)
+		}
+	}
+
+	if result.Cacheable {
+		if result.MaxAgeSeconds == 0 && result.FreshnessSource == "" && !result.NoCache {
+			notes = append(notes, "no freshness information, so the page is stale as soon as it is stored")
+		} else if result.MaxAgeSeconds > 0 && result.AgeSeconds >= result.MaxAgeSeconds {
+			notes = append(notes, fmt.Sprintf("the page was already stale when fetched: Age %ds is past its %ds lifetime", result.AgeSeconds, result.MaxAgeSeconds))
+		}
+		if result.ETag == "" && result.LastModified == "" && (result.MaxAgeSeconds == 0 || result.MustRevalidate) {
+			notes = append(notes, "no ETag or Last-Modified, so stale copies cannot be revalidated and are fetched in full")
+		}
+	}
+
+	result.Notes = notes
+	return result
+}
+
+// parseCacheControl returns the directives of the Cache-Control field lines
+// by lowercased name, with the argument of each occurrence, unquoted
+func parseCacheControl(lines []string) map[string][]string {
+	directives := make(map[string][]string)
+	for _, line := range lines {
+		for _, member := range splitList(line) {
+			name, value, _ := strings.Cut(member, "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if unquoted, err := strconv.Unquote(value); err == nil && strings.HasPrefix(value, `"`) {
+				value = unquoted
+			}
+			directives[name] = append(directives[name], value)
+		}
+	}
+	return directives
+}
+
+// splitList splits a comma-separated field value, leaving commas inside
+// quoted strings, e.g. private="Set-Cookie, X-Token", in place
+func splitList(value string) []string {
+	var members []string
+	quoted, escaped, start := false, false, 0
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case escaped:
+			escaped = false
+		case c == '\\' && quoted:
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			members = append(members, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(members, value[start:])
+}
+
+// unqualified reports whether the no-cache or private directive name applies
+// to the whole response. With field names, e.g. no-cache="Set-Cookie", it
+// only restricts those fields, which is noted.
+func unqualified(cc map[string][]string, name string, notes []string) (bool, []string) {
+	values, ok := cc[name]
+	if !ok {
+		return false, notes
+	}
+	if slices.Contains(values, "") {
+		return true, notes
+	}
+	return false, append(notes, fmt.Sprintf("%s applies only to the %s fields, not the whole page", name, strings.Join(values, ", ")))
+}
+
+// directiveSeconds reads the delta-seconds argument of a directive. An
+// invalid or conflicting argument makes the response stale, as RFC 9111
+// recommends, which is noted.
+func directiveSeconds(name string, values []string, notes []string) (int64, []string) {
+	seconds, ok := deltaSeconds(values[0])
+	if !ok {
+		return 0, append(notes, fmt.Sprintf("%s=%q is not a number of seconds, so the page is treated as stale", name, values[0]))
+	}
+	for _, value := range values[1:] {
+		if other, _ := deltaSeconds(value); other != seconds {
+			return 0, append(notes, fmt.Sprintf("conflicting %s directives, so the page is treated as stale", name))
+		}
+	}
+	return seconds, notes
+}
+
+// deltaSeconds parses a non-negative number of seconds, capping values too
+// large for caches to represent
+func deltaSeconds(value string) (int64, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.Trim(value, "0123456789") != "" {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds > maxDeltaSeconds {
+		return maxDeltaSeconds, true
+	}
+	return seconds, true
+}
+
+// varyFields returns the field names of the Vary lines, canonicalized and
+// each once
+func varyFields(lines []string) []string {
+	var fields []string
+	for _, line := range lines {
+		for _, field := range strings.Split(line, ",") {
+			field = strings.TrimSpace(field)
+			if field != "*" {
+				field = http.CanonicalHeaderKey(field)
+			}
+			if field != "" && !slices.Contains(fields, field) {
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+// hasToken reports whether the comma-separated field lines contain token,
+// ignoring case
+func hasToken(lines []string, token string) bool {
+	for _, line := range lines {
+		for _, member := range splitList(line) {
+			if strings.EqualFold(strings.TrimSpace(member), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
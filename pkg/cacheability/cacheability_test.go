@@ -0,0 +1,288 @@
+package cacheability
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	fetchedAt = time.Date(2025, 3, 14, 15, 0, 0, 0, time.UTC)
+	date      = fetchedAt.Format(http.TimeFormat)
+)
+
+func seconds(n int64) *int64 { return &n }
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		headers  http.Header
+		expected models.Cacheability
+		notes    []string
+	}{
+		{
+			name:     "no caching headers",
+			headers:  http.Header{},
+			expected: models.Cacheability{Cacheable: true, SharedCacheable: true},
+			notes: []string{
+				"no freshness information, so the page is stale as soon as it is stored",
+				"no ETag or Last-Modified, so stale copies cannot be revalidated and are fetched in full",
+			},
+		},
+		{
+			name:    "public max-age with a validator",
+			headers: http.Header{"Cache-Control": {"public, max-age=600"}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: 600, FreshnessSource: SourceMaxAge,
+				Public: true, ETag: `"v1"`,
+			},
+		},
+		{
+			name:    "directives are case-insensitive and span field lines",
+			headers: http.Header{"Cache-Control": {"Max-Age=60", "MUST-REVALIDATE, Immutable"}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: 60, FreshnessSource: SourceMaxAge,
+				MustRevalidate: true, Immutable: true, ETag: `"v1"`,
+			},
+		},
+		{
+			name:    "no-cache with max-age",
+			headers: http.Header{"Cache-Control": {"no-cache, max-age=600"}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, FreshnessSource: SourceMaxAge, NoCache: true, ETag: `"v1"`,
+			},
+			notes: []string{"no-cache overrides max-age: the page may be stored but is revalidated before every reuse"},
+		},
+		{
+			name:    "no-cache alone",
+			headers: http.Header{"Cache-Control": {"no-cache"}, "Last-Modified": {date}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, NoCache: true, LastModified: date,
+			},
+			notes: []string{"Cache-Control: no-cache requires revalidation before every reuse"},
+		},
+		{
+			name:    "no-cache naming fields",
+			headers: http.Header{"Cache-Control": {`no-cache="Set-Cookie", max-age=60`}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: 60, FreshnessSource: SourceMaxAge, ETag: `"v1"`,
+			},
+			notes: []string{"no-cache applies only to the Set-Cookie fields, not the whole page"},
+		},
+		{
+			name:    "private",
+			headers: http.Header{"Cache-Control": {"private, max-age=60"}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, MaxAgeSeconds: 60, FreshnessSource: SourceMaxAge, Private: true, ETag: `"v1"`,
+			},
+			notes: []string{"Cache-Control: private keeps the page out of shared caches such as CDNs"},
+		},
+		{
+			name:    "private naming fields, with a comma inside the quotes",
+			headers: http.Header{"Cache-Control": {`private="Set-Cookie, X-Token", max-age=60`}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: 60, FreshnessSource: SourceMaxAge, ETag: `"v1"`,
+			},
+			notes: []string{"private applies only to the Set-Cookie, X-Token fields, not the whole page"},
+		},
+		{
+			name:    "private drops s-maxage",
+			headers: http.Header{"Cache-Control": {"private, max-age=60, s-maxage=600"}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, MaxAgeSeconds: 60, FreshnessSource: SourceMaxAge, Private: true, ETag: `"v1"`,
+			},
+			notes: []string{"Cache-Control: private keeps the page out of shared caches such as CDNs"},
+		},
+		{
+			name:    "public with s-maxage",
+			headers: http.Header{"Cache-Control": {"public, max-age=60, s-maxage=3600"}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: 60, FreshnessSource: SourceMaxAge,
+				SharedMaxAgeSeconds: seconds(3600), Public: true, ETag: `"v1"`,
+			},
+		},
+		{
+			name:    "no-store wins over everything",
+			headers: http.Header{"Cache-Control": {"public, max-age=600, no-store"}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				MaxAgeSeconds: 600, FreshnessSource: SourceMaxAge, NoStore: true, Public: true, ETag: `"v1"`,
+			},
+			notes: []string{"not cacheable because Cache-Control: no-store forbids storing it"},
+		},
+		{
+			name:    "Vary star",
+			headers: http.Header{"Cache-Control": {"max-age=600"}, "Vary": {"Accept-Encoding, *"}},
+			expected: models.Cacheability{
+				MaxAgeSeconds: 600, FreshnessSource: SourceMaxAge, Vary: []string{"Accept-Encoding", "*"},
+			},
+			notes: []string{"not cacheable because Vary: * makes every later request a miss"},
+		},
+		{
+			name:    "Vary fields are canonicalized and listed once",
+			headers: http.Header{"Cache-Control": {"max-age=600"}, "Etag": {`"v1"`}, "Vary": {"accept-encoding, cookie", "Accept-Encoding"}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: 600, FreshnessSource: SourceMaxAge,
+				ETag: `"v1"`, Vary: []string{"Accept-Encoding", "Cookie"},
+			},
+		},
+		{
+			name:    "Expires against Date",
+			headers: http.Header{"Date": {date}, "Expires": {fetchedAt.Add(time.Hour).Format(http.TimeFormat)}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: 3600, FreshnessSource: SourceExpires, ETag: `"v1"`,
+			},
+		},
+		{
+			name:    "Expires in the past",
+			headers: http.Header{"Date": {date}, "Expires": {fetchedAt.Add(-time.Hour).Format(http.TimeFormat)}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, FreshnessSource: SourceExpires, ETag: `"v1"`,
+			},
+		},
+		{
+			name:    "Expires zero",
+			headers: http.Header{"Expires": {"0"}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, FreshnessSource: SourceExpires, ETag: `"v1"`,
+			},
+			notes: []string{`Expires "0" is not a date, so the page is already stale`},
+		},
+		{
+			name: "max-age over Expires",
+			headers: http.Header{
+				"Cache-Control": {"max-age=60"}, "Date": {date}, "Etag": {`"v1"`},
+				"Expires": {fetchedAt.Add(time.Hour).Format(http.TimeFormat)},
+			},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: 60, FreshnessSource: SourceMaxAge, ETag: `"v1"`,
+			},
+			notes: []string{"max-age takes precedence over Expires"},
+		},
+		{
+			name:    "Pragma no-cache without Cache-Control",
+			headers: http.Header{"Pragma": {"No-Cache"}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, NoCache: true, ETag: `"v1"`,
+			},
+			notes: []string{
+				"Pragma: no-cache without Cache-Control is read as Cache-Control: no-cache",
+				"Cache-Control: no-cache requires revalidation before every reuse",
+			},
+		},
+		{
+			name:    "Pragma no-cache is ignored next to Cache-Control",
+			headers: http.Header{"Pragma": {"no-cache"}, "Cache-Control": {"max-age=60"}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: 60, FreshnessSource: SourceMaxAge, ETag: `"v1"`,
+			},
+		},
+		{
+			name:    "heuristic from Last-Modified",
+			headers: http.Header{"Date": {date}, "Last-Modified": {fetchedAt.Add(-10 * 24 * time.Hour).Format(http.TimeFormat)}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: 24 * 60 * 60, FreshnessSource: SourceHeuristic,
+				LastModified: fetchedAt.Add(-10 * 24 * time.Hour).Format(http.TimeFormat),
+			},
+			notes: []string{"no explicit freshness, caches commonly reuse the page for 10% of the time since Last-Modified"},
+		},
+		{
+			name:     "status that needs explicit freshness",
+			status:   http.StatusInternalServerError,
+			headers:  http.Header{"Etag": {`"v1"`}},
+			expected: models.Cacheability{ETag: `"v1"`},
+			notes:    []string{"not cacheable because status 500 needs explicit freshness such as max-age"},
+		},
+		{
+			name:    "status with explicit freshness",
+			status:  http.StatusFound,
+			headers: http.Header{"Cache-Control": {"max-age=60"}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: 60, FreshnessSource: SourceMaxAge, ETag: `"v1"`,
+			},
+		},
+		{
+			name:    "quoted max-age",
+			headers: http.Header{"Cache-Control": {`max-age="120"`}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: 120, FreshnessSource: SourceMaxAge, ETag: `"v1"`,
+			},
+		},
+		{
+			name:    "invalid max-age",
+			headers: http.Header{"Cache-Control": {"max-age=-5"}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, FreshnessSource: SourceMaxAge, ETag: `"v1"`,
+			},
+			notes: []string{`max-age="-5" is not a number of seconds, so the page is treated as stale`},
+		},
+		{
+			name:    "conflicting max-age",
+			headers: http.Header{"Cache-Control": {"max-age=60", "max-age=3600"}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, FreshnessSource: SourceMaxAge, ETag: `"v1"`,
+			},
+			notes: []string{"conflicting max-age directives, so the page is treated as stale"},
+		},
+		{
+			name:    "repeated max-age",
+			headers: http.Header{"Cache-Control": {"max-age=60, max-age=60"}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: 60, FreshnessSource: SourceMaxAge, ETag: `"v1"`,
+			},
+		},
+		{
+			name:    "huge max-age is capped",
+			headers: http.Header{"Cache-Control": {"max-age=99999999999999999999"}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: maxDeltaSeconds, FreshnessSource: SourceMaxAge, ETag: `"v1"`,
+			},
+		},
+		{
+			name:    "already stale upstream",
+			headers: http.Header{"Cache-Control": {"max-age=60"}, "Age": {"120"}, "Etag": {`"v1"`}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: 60, FreshnessSource: SourceMaxAge,
+				AgeSeconds: 120, ETag: `"v1"`,
+			},
+			notes: []string{"the page was already stale when fetched: Age 120s is past its 60s lifetime"},
+		},
+		{
+			name:    "must-revalidate without validators",
+			headers: http.Header{"Cache-Control": {"max-age=60, must-revalidate"}},
+			expected: models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: 60, FreshnessSource: SourceMaxAge, MustRevalidate: true,
+			},
+			notes: []string{"no ETag or Last-Modified, so stale copies cannot be revalidated and are fetched in full"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := tt.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			result := Evaluate(status, tt.headers, fetchedAt)
+			if !assert.NotNil(t, result) {
+				return
+			}
+			assert.Equal(t, tt.notes, result.Notes)
+			result.Notes = nil
+			assert.Equal(t, tt.expected, *result)
+		})
+	}
+}
+
+func TestEvaluate_NoHeaders(t *testing.T) {
+	assert.Nil(t, Evaluate(http.StatusOK, nil, fetchedAt))
+}
+
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"a", ` b="x, \"y\", z"`, " c"}, splitList(`a, b="x, \"y\", z", c`))
+	assert.Equal(t, []string{""}, splitList(""))
+}
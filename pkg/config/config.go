@@ -0,0 +1,1209 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/faults"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/rules"
+	"github.com/RuvinSL/webpage-analyzer/pkg/urlutil"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnvVar names the environment variable holding an optional JSON or YAML
+// configuration file. Values from the file are applied before the environment,
+// so environment variables always win.
+const FileEnvVar = "CONFIG_FILE"
+
+const redacted = "[REDACTED]"
+
+// Common holds settings shared by every service
+type Common struct {
+	Port           int           `json:"port" env:"PORT"`
+	LogLevel       string        `json:"log_level" env:"LOG_LEVEL"`
+	LogToFile      bool          `json:"log_to_file" env:"LOG_TO_FILE"`
+	LogDir         string        `json:"log_dir" env:"LOG_DIR"`
+	StartupMaxWait time.Duration `json:"startup_max_wait" env:"STARTUP_MAX_WAIT"`
+	StartupMode    string        `json:"startup_mode" env:"STARTUP_MODE"`
+	// AdminToken guards the /admin endpoints; they are disabled when empty
+	AdminToken string `json:"admin_token" env:"ADMIN_TOKEN" secret:"true"`
+	// The pprof endpoints are off unless DebugEndpoints is set; they are then
+	// served on their own DebugAddr listener, or behind ADMIN_TOKEN
+	DebugEndpoints bool   `json:"debug_endpoints" env:"DEBUG_ENDPOINTS"`
+	DebugAddr      string `json:"debug_addr" env:"DEBUG_ADDR"`
+	// The service serves HTTPS itself when TLSCertFile and TLSKeyFile are
+	// set, picking up a renewed certificate every TLSReloadInterval and on
+	// SIGHUP. TLSRedirectAddr optionally redirects plain HTTP to it.
+	TLSCertFile       string        `json:"tls_cert_file" env:"TLS_CERT_FILE"`
+	TLSKeyFile        string        `json:"tls_key_file" env:"TLS_KEY_FILE"`
+	TLSReloadInterval time.Duration `json:"tls_reload_interval" env:"TLS_RELOAD_INTERVAL"`
+	TLSRedirectAddr   string        `json:"tls_redirect_addr" env:"TLS_REDIRECT_ADDR"`
+	// The dependencies reported by /health are checked in the background
+	// every HealthCheckInterval, each check bounded by HealthCheckTimeout
+	HealthCheckInterval time.Duration `json:"health_check_interval" env:"HEALTH_CHECK_INTERVAL"`
+	HealthCheckTimeout  time.Duration `json:"health_check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+	// InternalH2C makes the service-to-service calls use HTTP/2 over
+	// cleartext; set it on every service together
+	InternalH2C bool `json:"internal_h2c" env:"INTERNAL_H2C"`
+	// FaultInject injects latency and errors for resilience testing, see
+	// package faults; never set it in production. FaultInjectSeed seeds the
+	// injection decisions, zero for a random seed.
+	FaultInject     string `json:"fault_inject" env:"FAULT_INJECT"`
+	FaultInjectSeed int64  `json:"fault_inject_seed" env:"FAULT_INJECT_SEED"`
+}
+
+// DNS selects how the services that fetch pages resolve host names and
+// connect to them
+type DNS struct {
+	// DNSResolver is empty or "system" for the system resolver, or
+	// doh:<https URL> for DNS over HTTPS with the system resolver as fallback
+	DNSResolver        string        `json:"dns_resolver" env:"DNS_RESOLVER"`
+	DNSResolverTimeout time.Duration `json:"dns_resolver_timeout" env:"DNS_RESOLVER_TIMEOUT"`
+	// IPFamily is dual, racing IPv4 and IPv6, or ipv4 or ipv6 to use only
+	// that family's addresses
+	IPFamily string `json:"ip_family" env:"IP_FAMILY"`
+	// OutboundSourceIP, when set, is the local address outbound fetches
+	// originate from, for target sites that allowlist it. The services
+	// check at startup that it is an address of the host.
+	OutboundSourceIP string `json:"outbound_source_ip" env:"OUTBOUND_SOURCE_IP"`
+}
+
+// URLNormalization decides when differently spelled URLs are the same one,
+// for link deduplication, the analyzer's result cache and the batch
+// comparisons, see package urlutil; set it on the analyzer and the gateway
+// together
+type URLNormalization struct {
+	// URLFoldTrailingSlash makes /pricing/ the same as /pricing
+	URLFoldTrailingSlash bool `json:"url_fold_trailing_slash" env:"URL_FOLD_TRAILING_SLASH"`
+	// URLStripParams are the query parameters ignored, each a name or a
+	// prefix ending in *
+	URLStripParams []string `json:"url_strip_params" env:"URL_STRIP_PARAMS"`
+	// URLCase is host to lowercase only the scheme and host, or full to
+	// lowercase the path as well
+	URLCase string `json:"url_case" env:"URL_CASE"`
+}
+
+// dohPrefix marks a DNS_RESOLVER value naming a DoH endpoint
+const dohPrefix = "doh:"
+
+// Analyzer is the analyzer service configuration
+type Analyzer struct {
+	Common
+	DNS
+	URLNormalization
+	LinkCheckerURL     string        `json:"link_checker_service_url" env:"LINK_CHECKER_SERVICE_URL"`
+	FetchTimeout       time.Duration `json:"fetch_timeout" env:"FETCH_TIMEOUT"`
+	LinkCheckerTimeout time.Duration `json:"link_checker_timeout" env:"LINK_CHECKER_TIMEOUT"`
+	MaxAnalysisTimeout time.Duration `json:"analysis_max_timeout" env:"ANALYSIS_MAX_TIMEOUT"`
+
+	// Outbound budget of one analysis, shared by the page fetch, its
+	// redirects and the link checks
+	MaxRequestsPerAnalysis int `json:"analysis_max_requests" env:"ANALYSIS_MAX_REQUESTS"`
+	MaxBytesPerAnalysis    int `json:"analysis_max_bytes" env:"ANALYSIS_MAX_BYTES"`
+	// MaxFramesPerAnalysis caps the frame documents followed on request
+	MaxFramesPerAnalysis int `json:"analysis_max_frames" env:"ANALYSIS_MAX_FRAMES"`
+	// MaxPaginationHops caps the next links followed on request
+	MaxPaginationHops int `json:"analysis_max_pagination_hops" env:"ANALYSIS_MAX_PAGINATION_HOPS"`
+	// At most MaxAnalysesPerHost analyses run at once against one target
+	// host; others wait up to HostWaitTimeout, then get 429. Zero leaves
+	// hosts unlimited.
+	MaxAnalysesPerHost int           `json:"analysis_max_per_host" env:"ANALYSIS_MAX_PER_HOST"`
+	HostWaitTimeout    time.Duration `json:"analysis_host_wait_timeout" env:"ANALYSIS_HOST_WAIT_TIMEOUT"`
+
+	// Requests may override the addresses of the hosts matching
+	// HostOverrideHosts, host names or *.domain; empty accepts no
+	// overrides. Overrides to private addresses also need
+	// AllowPrivateTargets.
+	HostOverrideHosts   []string `json:"host_override_hosts" env:"HOST_OVERRIDE_HOSTS"`
+	AllowPrivateTargets bool     `json:"allow_private_targets" env:"ALLOW_PRIVATE_TARGETS"`
+
+	// Parser guards against pathological documents; what they cut is
+	// reported as truncation
+	ParserMaxDepth      int `json:"parser_max_depth" env:"PARSER_MAX_DEPTH"`
+	ParserMaxLinks      int `json:"parser_max_links" env:"PARSER_MAX_LINKS"`
+	ParserMaxTextLength int `json:"parser_max_text_length" env:"PARSER_MAX_TEXT_LENGTH"`
+	ParserMaxAnchors    int `json:"parser_max_anchors" env:"PARSER_MAX_ANCHORS"`
+	ParserMaxHeadings   int `json:"parser_max_headings" env:"PARSER_MAX_HEADINGS"`
+	// LargeDataURIBytes is the size from which a data: URI link is
+	// reported as weighing on the page
+	LargeDataURIBytes int `json:"large_data_uri_bytes" env:"LARGE_DATA_URI_BYTES"`
+	// Results serializing to more than ResultMaxBytes are sent without
+	// their per-link details
+	ResultMaxBytes int `json:"result_max_bytes" env:"RESULT_MAX_BYTES"`
+
+	// Headless rendering is off unless RenderEnabled is set
+	RenderEnabled       bool          `json:"render_enabled" env:"RENDER_ENABLED"`
+	RenderByDefault     bool          `json:"render_by_default" env:"RENDER_BY_DEFAULT"`
+	RenderMaxConcurrent int           `json:"render_max_concurrent" env:"RENDER_MAX_CONCURRENT"`
+	RenderTimeout       time.Duration `json:"render_timeout" env:"RENDER_TIMEOUT"`
+	RenderWaitSelector  string        `json:"render_wait_selector" env:"RENDER_WAIT_SELECTOR"`
+	ChromePath          string        `json:"chrome_path" env:"CHROME_PATH"`
+	ScreenshotMaxBytes  int           `json:"screenshot_max_bytes" env:"SCREENSHOT_MAX_BYTES"`
+
+	// Repeat fetches are revalidated with ETag/Last-Modified only when
+	// ResultCacheEnabled is set
+	ResultCacheEnabled      bool          `json:"result_cache_enabled" env:"RESULT_CACHE_ENABLED"`
+	ResultCacheTTL          time.Duration `json:"result_cache_ttl" env:"RESULT_CACHE_TTL"`
+	ResultCacheMaxEntries   int           `json:"result_cache_max_entries" env:"RESULT_CACHE_MAX_ENTRIES"`
+	RevalidateSkipLinkCheck bool          `json:"revalidate_skip_link_check" env:"REVALIDATE_SKIP_LINK_CHECK"`
+	// With RedisURL set the result cache lives in Redis, shared by every
+	// replica, instead of in process. Redis is bypassed for
+	// RedisBreakerCooldown after RedisBreakerFailures failed commands.
+	RedisURL             string        `json:"redis_url" env:"REDIS_URL" secret:"true"`
+	RedisKeyPrefix       string        `json:"redis_key_prefix" env:"REDIS_KEY_PREFIX"`
+	RedisTimeout         time.Duration `json:"redis_timeout" env:"REDIS_TIMEOUT"`
+	RedisBreakerFailures int           `json:"redis_breaker_failures" env:"REDIS_BREAKER_FAILURES"`
+	RedisBreakerCooldown time.Duration `json:"redis_breaker_cooldown" env:"REDIS_BREAKER_COOLDOWN"`
+
+	// Analyses requesting debug=true list up to DebugTraceMaxEntries of
+	// their outbound requests, only when DebugTraceEnabled is set
+	DebugTraceEnabled    bool `json:"debug_trace_enabled" env:"DEBUG_TRACE_ENABLED"`
+	DebugTraceMaxEntries int  `json:"debug_trace_max_entries" env:"DEBUG_TRACE_MAX_ENTRIES"`
+
+	// GenericLinkTexts are the link texts reported as saying nothing about
+	// where a link leads; empty reports none
+	GenericLinkTexts []string `json:"generic_link_texts" env:"GENERIC_LINK_TEXTS"`
+
+	// Titles shorter than TitleMinLength or longer than TitleMaxLength
+	// characters are reported
+	TitleMinLength int `json:"title_min_length" env:"TITLE_MIN_LENGTH"`
+	TitleMaxLength int `json:"title_max_length" env:"TITLE_MAX_LENGTH"`
+
+	// DisabledRules are the checks of pkg/rules that run only when a
+	// request includes them
+	DisabledRules []string `json:"analysis_rules_disabled" env:"ANALYSIS_RULES_DISABLED"`
+
+	// With WarmupEnabled the analyzer runs one analysis once its
+	// dependencies are reachable: of WarmupURL, or of a built-in page when
+	// it is empty. A failed warm-up stops the service only with
+	// StrictWarmup.
+	WarmupEnabled bool          `json:"warmup_enabled" env:"WARMUP_ENABLED"`
+	WarmupURL     string        `json:"warmup_url" env:"WARMUP_URL"`
+	WarmupTimeout time.Duration `json:"warmup_timeout" env:"WARMUP_TIMEOUT"`
+	StrictWarmup  bool          `json:"strict_warmup" env:"STRICT_WARMUP"`
+
+	// The analyses' SLO: SLOObjective of them succeed within SLOLatency. The
+	// SLI gauges measure the last minutes of analyses against it.
+	SLOObjective float64       `json:"slo_objective" env:"SLO_OBJECTIVE"`
+	SLOLatency   time.Duration `json:"slo_latency" env:"SLO_LATENCY"`
+}
+
+// Gateway is the API gateway configuration
+type Gateway struct {
+	Common
+	URLNormalization
+	AnalyzerURL     string        `json:"analyzer_service_url" env:"ANALYZER_SERVICE_URL"`
+	AnalyzerTimeout time.Duration `json:"analyzer_timeout" env:"ANALYZER_TIMEOUT"`
+	// Each route class has a deadline, answered with 504 when it passes
+	// before the route does
+	RouteTimeoutAnalyze time.Duration `json:"route_timeout_analyze" env:"ROUTE_TIMEOUT_ANALYZE"`
+	RouteTimeoutBatch   time.Duration `json:"route_timeout_batch" env:"ROUTE_TIMEOUT_BATCH"`
+	RouteTimeoutHealth  time.Duration `json:"route_timeout_health" env:"ROUTE_TIMEOUT_HEALTH"`
+	// LinkCheckerURL is only read for the aggregated /stats
+	LinkCheckerURL string `json:"link_checker_service_url" env:"LINK_CHECKER_SERVICE_URL"`
+
+	// With MirrorAnalyzerURL set, MirrorPercent of the single analyses are
+	// sent to that analyzer as well, in the background: at most
+	// MirrorMaxConcurrent at a time, each bounded by MirrorTimeout. Its
+	// results are discarded, or with MirrorDiff compared to the primary's.
+	MirrorAnalyzerURL   string        `json:"mirror_analyzer_url" env:"MIRROR_ANALYZER_URL"`
+	MirrorPercent       float64       `json:"mirror_percent" env:"MIRROR_PERCENT"`
+	MirrorTimeout       time.Duration `json:"mirror_timeout" env:"MIRROR_TIMEOUT"`
+	MirrorMaxConcurrent int           `json:"mirror_max_concurrent" env:"MIRROR_MAX_CONCURRENT"`
+	MirrorDiff          bool          `json:"mirror_diff" env:"MIRROR_DIFF"`
+
+	// Screenshots are held in memory and served under /api/*/artifacts
+	ArtifactTTL      time.Duration `json:"artifact_ttl" env:"ARTIFACT_TTL"`
+	ArtifactMaxItems int           `json:"artifact_max_items" env:"ARTIFACT_MAX_ITEMS"`
+	ArtifactMaxBytes int           `json:"artifact_max_bytes" env:"ARTIFACT_MAX_BYTES"`
+
+	// Admission control for the analysis routes; requests beyond the queue
+	// are answered with 503
+	MaxConcurrentAnalyses int           `json:"max_concurrent_analyses" env:"MAX_CONCURRENT_ANALYSES"`
+	AnalysisQueueSize     int           `json:"analysis_queue_size" env:"ANALYSIS_QUEUE_SIZE"`
+	AnalysisQueueTimeout  time.Duration `json:"analysis_queue_timeout" env:"ANALYSIS_QUEUE_TIMEOUT"`
+
+	// Analysis results are kept by StorageBackend: memory (up to
+	// StorageMaxResults, lost on restart) or postgres
+	StorageBackend      string        `json:"storage_backend" env:"STORAGE_BACKEND"`
+	StorageMaxResults   int           `json:"storage_max_results" env:"STORAGE_MAX_RESULTS"`
+	StorageQueryTimeout time.Duration `json:"storage_query_timeout" env:"STORAGE_QUERY_TIMEOUT"`
+	// Postgres connection pool; PostgresMigrate applies the schema
+	// migrations on startup
+	PostgresDSN             string        `json:"postgres_dsn" env:"POSTGRES_DSN" secret:"true"`
+	PostgresMigrate         bool          `json:"postgres_migrate" env:"POSTGRES_MIGRATE"`
+	PostgresMaxConns        int           `json:"postgres_max_conns" env:"POSTGRES_MAX_CONNS"`
+	PostgresMinConns        int           `json:"postgres_min_conns" env:"POSTGRES_MIN_CONNS"`
+	PostgresMaxConnLifetime time.Duration `json:"postgres_max_conn_lifetime" env:"POSTGRES_MAX_CONN_LIFETIME"`
+
+	// Browsers on CORSAllowedOrigins may call the API from other sites:
+	// exact origins, subdomain wildcards such as https://*.example.com, or *
+	// for any. Empty allows same-origin use only.
+	CORSAllowedOrigins   []string      `json:"cors_allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	CORSAllowedMethods   []string      `json:"cors_allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	CORSAllowedHeaders   []string      `json:"cors_allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	CORSAllowCredentials bool          `json:"cors_allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	CORSMaxAge           time.Duration `json:"cors_max_age" env:"CORS_MAX_AGE"`
+
+	// Responses of at least CompressionMinSize bytes are compressed with
+	// gzip or deflate for clients that accept it, unless Compression is off
+	Compression        bool `json:"compression" env:"COMPRESSION"`
+	CompressionMinSize int  `json:"compression_min_size" env:"COMPRESSION_MIN_SIZE"`
+
+	// Request and response bodies are logged at debug level, redacted and
+	// capped at DebugLogBodiesMaxBytes: for every request with
+	// DebugLogBodies, otherwise for those carrying ADMIN_TOKEN in the
+	// X-Debug-Log-Bodies header
+	DebugLogBodies         bool `json:"debug_log_bodies" env:"DEBUG_LOG_BODIES"`
+	DebugLogBodiesMaxBytes int  `json:"debug_log_bodies_max_bytes" env:"DEBUG_LOG_BODIES_MAX_BYTES"`
+	// LogResultContent logs the titles and whole URLs of the pages the
+	// analyzer returns, at debug level; otherwise only their hosts are
+	LogResultContent bool `json:"log_result_content" env:"LOG_RESULT_CONTENT"`
+
+	// Security headers of the UI pages; the API routes get a fixed minimal
+	// set. SecurityHSTS sends Strict-Transport-Security and is only for a
+	// gateway reached over HTTPS, such as behind a TLS-terminating proxy.
+	SecurityCSP            string        `json:"security_csp" env:"SECURITY_CSP"`
+	SecurityFrameOptions   string        `json:"security_frame_options" env:"SECURITY_FRAME_OPTIONS"`
+	SecurityReferrerPolicy string        `json:"security_referrer_policy" env:"SECURITY_REFERRER_POLICY"`
+	SecurityHSTS           bool          `json:"security_hsts" env:"SECURITY_HSTS"`
+	SecurityHSTSMaxAge     time.Duration `json:"security_hsts_max_age" env:"SECURITY_HSTS_MAX_AGE"`
+	// SecurityHeadersExclude lists the paths served without security
+	// headers, prefixes when they end in /
+	SecurityHeadersExclude []string `json:"security_headers_exclude" env:"SECURITY_HEADERS_EXCLUDE"`
+}
+
+// LinkChecker is the link checker service configuration
+type LinkChecker struct {
+	Common
+	DNS
+	WorkerPoolSize int           `json:"worker_pool_size" env:"WORKER_POOL_SIZE"`
+	CheckTimeout   time.Duration `json:"check_timeout" env:"CHECK_TIMEOUT"`
+	// MaxLinksPerRequest rejects larger /check batches with 413
+	MaxLinksPerRequest int `json:"max_links_per_request" env:"MAX_LINKS_PER_REQUEST"`
+	// HostDelay spaces requests to the same host at least this far apart;
+	// zero leaves them unpaced
+	HostDelay time.Duration `json:"link_check_host_delay" env:"LINK_CHECK_HOST_DELAY"`
+	// PreconnectHosts is how many of a batch's busiest hosts are connected
+	// to before its links are checked; zero turns it off
+	PreconnectHosts int `json:"link_check_preconnect_hosts" env:"LINK_CHECK_PRECONNECT_HOSTS"`
+	// HedgeDelay is how long a link's request may run before a second one
+	// is sent; zero turns hedging off
+	HedgeDelay time.Duration `json:"link_check_hedge_delay" env:"LINK_CHECK_HEDGE_DELAY"`
+}
+
+func defaultCommon(port int) Common {
+	return Common{
+		Port:           port,
+		LogLevel:       "info",
+		LogToFile:      true,
+		LogDir:         "./logs",
+		StartupMaxWait: 30 * time.Second,
+		StartupMode:    "degraded",
+
+		HealthCheckInterval: 10 * time.Second,
+		HealthCheckTimeout:  2 * time.Second,
+
+		TLSReloadInterval: time.Minute,
+	}
+}
+
+func defaultDNS() DNS {
+	return DNS{DNSResolverTimeout: 2 * time.Second, IPFamily: "dual"}
+}
+
+func defaultURLNormalization() URLNormalization {
+	policy := urlutil.DefaultPolicy()
+	return URLNormalization{URLStripParams: policy.StripParams, URLCase: policy.Case}
+}
+
+// DefaultAnalyzer returns the analyzer defaults
+func DefaultAnalyzer() *Analyzer {
+	return &Analyzer{
+		Common:             defaultCommon(8081),
+		DNS:                defaultDNS(),
+		URLNormalization:   defaultURLNormalization(),
+		LinkCheckerURL:     "http://localhost:8082",
+		FetchTimeout:       30 * time.Second,
+		LinkCheckerTimeout: 30 * time.Second,
+		MaxAnalysisTimeout: 60 * time.Second,
+
+		MaxRequestsPerAnalysis: 1000,
+		MaxBytesPerAnalysis:    256 << 20,
+		MaxFramesPerAnalysis:   10,
+		MaxPaginationHops:      10,
+		MaxAnalysesPerHost:     4,
+		HostWaitTimeout:        10 * time.Second,
+
+		ParserMaxDepth:      512,
+		ParserMaxLinks:      10000,
+		ParserMaxTextLength: 512,
+		ParserMaxAnchors:    10000,
+		ParserMaxHeadings:   1000,
+		LargeDataURIBytes:   32 << 10,
+		ResultMaxBytes:      8 << 20,
+
+		RenderMaxConcurrent: 2,
+		RenderTimeout:       20 * time.Second,
+		ScreenshotMaxBytes:  1 << 20,
+
+		ResultCacheTTL:        time.Hour,
+		ResultCacheMaxEntries: 1000,
+
+		RedisKeyPrefix:       "webpage-analyzer:",
+		RedisTimeout:         500 * time.Millisecond,
+		RedisBreakerFailures: 5,
+		RedisBreakerCooldown: 30 * time.Second,
+
+		DebugTraceMaxEntries: 500,
+
+		WarmupTimeout: 30 * time.Second,
+
+		GenericLinkTexts: []string{
+			"click here", "here", "click", "read more", "more", "learn more", "more info",
+			"link", "this link", "go", "continue", "details", "this page",
+		},
+		TitleMinLength: 10,
+		TitleMaxLength: 60,
+
+		SLOObjective: 0.99,
+		SLOLatency:   10 * time.Second,
+	}
+}
+
+// DefaultContentSecurityPolicy only lets the web UI load its own scripts,
+// styles and images, and screenshots as data URLs
+const DefaultContentSecurityPolicy = "default-src 'self'; script-src 'self'; style-src 'self'; img-src 'self' data:; " +
+	"connect-src 'self'; object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+
+// DefaultGateway returns the gateway defaults
+func DefaultGateway() *Gateway {
+	return &Gateway{
+		Common:           defaultCommon(8080),
+		URLNormalization: defaultURLNormalization(),
+		AnalyzerURL:      "http://localhost:8081",
+		AnalyzerTimeout:  30 * time.Second,
+		LinkCheckerURL:   "http://localhost:8082",
+
+		MirrorPercent:       100,
+		MirrorTimeout:       30 * time.Second,
+		MirrorMaxConcurrent: 10,
+
+		RouteTimeoutAnalyze: 60 * time.Second,
+		RouteTimeoutBatch:   300 * time.Second,
+		RouteTimeoutHealth:  2 * time.Second,
+
+		ArtifactTTL:      15 * time.Minute,
+		ArtifactMaxItems: 100,
+		ArtifactMaxBytes: 1 << 20,
+
+		MaxConcurrentAnalyses: 20,
+		AnalysisQueueSize:     50,
+		AnalysisQueueTimeout:  5 * time.Second,
+
+		StorageBackend:      "memory",
+		StorageMaxResults:   1000,
+		StorageQueryTimeout: 5 * time.Second,
+
+		PostgresMaxConns:        10,
+		PostgresMaxConnLifetime: time.Hour,
+
+		CORSAllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		CORSAllowedHeaders: []string{"Content-Type", "Authorization", "X-Request-ID"},
+		CORSMaxAge:         24 * time.Hour,
+
+		Compression:        true,
+		CompressionMinSize: 1024,
+
+		DebugLogBodiesMaxBytes: 4096,
+
+		SecurityCSP:            DefaultContentSecurityPolicy,
+		SecurityFrameOptions:   "DENY",
+		SecurityReferrerPolicy: "no-referrer",
+		SecurityHSTSMaxAge:     365 * 24 * time.Hour,
+		SecurityHeadersExclude: []string{"/debug/pprof/"},
+	}
+}
+
+// maxHostDelay is the link checker's upper bound on its politeness delay
+const maxHostDelay = 10 * time.Second
+
+// maxPreconnectHosts is the link checker's upper bound on the hosts it
+// preconnects per batch
+const maxPreconnectHosts = 64
+
+// DefaultLinkChecker returns the link checker defaults
+func DefaultLinkChecker() *LinkChecker {
+	return &LinkChecker{
+		Common:         defaultCommon(8082),
+		DNS:            defaultDNS(),
+		WorkerPoolSize: 10,
+		CheckTimeout:   5 * time.Second,
+
+		MaxLinksPerRequest: 10000,
+		PreconnectHosts:    8,
+	}
+}
+
+// LoadAnalyzer loads and validates the analyzer configuration
+func LoadAnalyzer() (*Analyzer, error) {
+	cfg := DefaultAnalyzer()
+	if err := load(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// LoadGateway loads and validates the gateway configuration
+func LoadGateway() (*Gateway, error) {
+	cfg := DefaultGateway()
+	if err := load(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// LoadLinkChecker loads and validates the link checker configuration
+func LoadLinkChecker() (*LinkChecker, error) {
+	cfg := DefaultLinkChecker()
+	if err := load(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// SlogLevel converts the configured log level to a slog.Level
+func (c *Common) SlogLevel() slog.Level {
+	switch c.LogLevel {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Validate checks the shared settings
+func (c *Common) Validate() error {
+	var errs []error
+
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT: must be between 1 and 65535, got %d", c.Port))
+	}
+
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_LEVEL: must be one of debug, info, warn, error, got %q", c.LogLevel))
+	}
+
+	if c.LogToFile && c.LogDir == "" {
+		errs = append(errs, errors.New("LOG_DIR: required when LOG_TO_FILE is true"))
+	}
+
+	errs = append(errs, positive("STARTUP_MAX_WAIT", c.StartupMaxWait))
+
+	switch c.StartupMode {
+	case "fail-fast", "degraded":
+	default:
+		errs = append(errs, fmt.Errorf("STARTUP_MODE: must be fail-fast or degraded, got %q", c.StartupMode))
+	}
+
+	switch {
+	case !c.DebugEndpoints && c.DebugAddr != "":
+		errs = append(errs, errors.New("DEBUG_ADDR: requires DEBUG_ENDPOINTS"))
+	case !c.DebugEndpoints:
+	case c.DebugAddr != "":
+		if _, _, err := net.SplitHostPort(c.DebugAddr); err != nil {
+			errs = append(errs, fmt.Errorf("DEBUG_ADDR: must be host:port, got %q", c.DebugAddr))
+		}
+	case c.AdminToken == "":
+		errs = append(errs, errors.New("DEBUG_ENDPOINTS: requires DEBUG_ADDR or ADMIN_TOKEN"))
+	}
+
+	errs = append(errs,
+		positive("HEALTH_CHECK_INTERVAL", c.HealthCheckInterval),
+		positive("HEALTH_CHECK_TIMEOUT", c.HealthCheckTimeout))
+	if c.HealthCheckTimeout > c.HealthCheckInterval {
+		errs = append(errs, fmt.Errorf("HEALTH_CHECK_TIMEOUT: must not exceed HEALTH_CHECK_INTERVAL (%s), got %s", c.HealthCheckInterval, c.HealthCheckTimeout))
+	}
+
+	errs = append(errs, c.validateTLS())
+
+	if _, err := faults.Parse(c.FaultInject); err != nil {
+		errs = append(errs, fmt.Errorf("FAULT_INJECT: %w", err))
+	}
+	if c.FaultInjectSeed < 0 {
+		errs = append(errs, fmt.Errorf("FAULT_INJECT_SEED: must not be negative, got %d", c.FaultInjectSeed))
+	}
+
+	return errors.Join(errs...)
+}
+
+// TLSEnabled reports whether the service serves HTTPS itself
+func (c *Common) TLSEnabled() bool {
+	return c.TLSCertFile != ""
+}
+
+func (c *Common) validateTLS() error {
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("TLS_CERT_FILE: must be set together with TLS_KEY_FILE")
+	}
+	if !c.TLSEnabled() {
+		if c.TLSRedirectAddr != "" {
+			return errors.New("TLS_REDIRECT_ADDR: requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return nil
+	}
+
+	var errs []error
+	errs = append(errs, positive("TLS_RELOAD_INTERVAL", c.TLSReloadInterval))
+	if c.TLSRedirectAddr != "" {
+		if _, _, err := net.SplitHostPort(c.TLSRedirectAddr); err != nil {
+			errs = append(errs, fmt.Errorf("TLS_REDIRECT_ADDR: must be host:port, got %q", c.TLSRedirectAddr))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DoHEndpoint returns the DNS-over-HTTPS endpoint of DNSResolver, or ""
+// when the system resolver is used
+func (c *DNS) DoHEndpoint() string {
+	endpoint, ok := strings.CutPrefix(c.DNSResolver, dohPrefix)
+	if !ok {
+		return ""
+	}
+	return endpoint
+}
+
+// Validate checks the resolver settings
+func (c *DNS) Validate() error {
+	var errs []error
+	switch c.IPFamily {
+	case "dual", "ipv4", "ipv6":
+	default:
+		errs = append(errs, fmt.Errorf("IP_FAMILY: must be dual, ipv4 or ipv6, got %q", c.IPFamily))
+	}
+	if c.OutboundSourceIP != "" {
+		switch ip := net.ParseIP(c.OutboundSourceIP); {
+		case ip == nil:
+			errs = append(errs, fmt.Errorf("OUTBOUND_SOURCE_IP: must be an IP address, got %q", c.OutboundSourceIP))
+		case c.IPFamily == "ipv4" && ip.To4() == nil, c.IPFamily == "ipv6" && ip.To4() != nil:
+			errs = append(errs, fmt.Errorf("OUTBOUND_SOURCE_IP: %s is not an %s address, as IP_FAMILY requires", ip, c.IPFamily))
+		}
+	}
+
+	switch {
+	case c.DNSResolver == "" || c.DNSResolver == "system":
+	case !strings.HasPrefix(c.DNSResolver, dohPrefix):
+		errs = append(errs, fmt.Errorf("DNS_RESOLVER: must be system or doh:<https URL>, got %q", c.DNSResolver))
+	default:
+		if u, err := url.Parse(c.DoHEndpoint()); err != nil || u.Scheme != "https" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("DNS_RESOLVER: must be doh: followed by an absolute https URL, got %q", c.DNSResolver))
+		}
+		errs = append(errs, positive("DNS_RESOLVER_TIMEOUT", c.DNSResolverTimeout))
+	}
+	return errors.Join(errs...)
+}
+
+// Policy returns the URL normalization policy
+func (c *URLNormalization) Policy() models.URLNormalization {
+	return models.URLNormalization{
+		FoldTrailingSlash: c.URLFoldTrailingSlash,
+		StripParams:       slices.Clone(c.URLStripParams),
+		Case:              c.URLCase,
+	}
+}
+
+// Validate checks the URL normalization settings
+func (c *URLNormalization) Validate() error {
+	var errs []error
+	switch c.URLCase {
+	case models.URLCaseHost, models.URLCaseFull:
+	default:
+		errs = append(errs, fmt.Errorf("URL_CASE: must be %s or %s, got %q", models.URLCaseHost, models.URLCaseFull, c.URLCase))
+	}
+	for _, param := range c.URLStripParams {
+		if !urlutil.ValidStripParam(param) {
+			errs = append(errs, fmt.Errorf("URL_STRIP_PARAMS: %q is not a parameter name or a prefix ending in *", param))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the analyzer configuration
+func (c *Analyzer) Validate() error {
+	return errors.Join(
+		c.Common.Validate(),
+		c.DNS.Validate(),
+		c.URLNormalization.Validate(),
+		serviceURL("LINK_CHECKER_SERVICE_URL", c.LinkCheckerURL),
+		positive("FETCH_TIMEOUT", c.FetchTimeout),
+		positive("LINK_CHECKER_TIMEOUT", c.LinkCheckerTimeout),
+		positive("ANALYSIS_MAX_TIMEOUT", c.MaxAnalysisTimeout),
+		c.validateBudget(),
+		c.validateHostOverrides(),
+		c.validateParser(),
+		c.validateRender(),
+		c.validateResultCache(),
+		c.validateDebugTrace(),
+		rules.Validate("ANALYSIS_RULES_DISABLED", c.DisabledRules),
+		c.validateWarmup(),
+		c.validateSLO(),
+	)
+}
+
+func (c *Analyzer) validateBudget() error {
+	var errs []error
+	if c.MaxRequestsPerAnalysis < 1 {
+		errs = append(errs, fmt.Errorf("ANALYSIS_MAX_REQUESTS: must be positive, got %d", c.MaxRequestsPerAnalysis))
+	}
+	if c.MaxBytesPerAnalysis < 1 {
+		errs = append(errs, fmt.Errorf("ANALYSIS_MAX_BYTES: must be positive, got %d", c.MaxBytesPerAnalysis))
+	}
+	if c.MaxFramesPerAnalysis < 1 {
+		errs = append(errs, fmt.Errorf("ANALYSIS_MAX_FRAMES: must be positive, got %d", c.MaxFramesPerAnalysis))
+	}
+	if c.MaxPaginationHops < 1 {
+		errs = append(errs, fmt.Errorf("ANALYSIS_MAX_PAGINATION_HOPS: must be positive, got %d", c.MaxPaginationHops))
+	}
+	if c.MaxAnalysesPerHost < 0 {
+		errs = append(errs, fmt.Errorf("ANALYSIS_MAX_PER_HOST: must not be negative, got %d", c.MaxAnalysesPerHost))
+	}
+	if c.MaxAnalysesPerHost > 0 {
+		errs = append(errs, positive("ANALYSIS_HOST_WAIT_TIMEOUT", c.HostWaitTimeout))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Analyzer) validateHostOverrides() error {
+	for _, pattern := range c.HostOverrideHosts {
+		host := strings.TrimPrefix(strings.TrimSpace(pattern), "*.")
+		if !models.IsHostName(host) {
+			return fmt.Errorf("HOST_OVERRIDE_HOSTS: %q is not a host name or *.domain", pattern)
+		}
+	}
+	return nil
+}
+
+func (c *Analyzer) validateParser() error {
+	var errs []error
+	if c.ParserMaxDepth < 1 {
+		errs = append(errs, fmt.Errorf("PARSER_MAX_DEPTH: must be positive, got %d", c.ParserMaxDepth))
+	}
+	if c.ParserMaxLinks < 1 {
+		errs = append(errs, fmt.Errorf("PARSER_MAX_LINKS: must be positive, got %d", c.ParserMaxLinks))
+	}
+	if c.ParserMaxTextLength < 1 {
+		errs = append(errs, fmt.Errorf("PARSER_MAX_TEXT_LENGTH: must be positive, got %d", c.ParserMaxTextLength))
+	}
+	if c.ParserMaxAnchors < 1 {
+		errs = append(errs, fmt.Errorf("PARSER_MAX_ANCHORS: must be positive, got %d", c.ParserMaxAnchors))
+	}
+	if c.ParserMaxHeadings < 1 {
+		errs = append(errs, fmt.Errorf("PARSER_MAX_HEADINGS: must be positive, got %d", c.ParserMaxHeadings))
+	}
+	if c.LargeDataURIBytes < 1 {
+		errs = append(errs, fmt.Errorf("LARGE_DATA_URI_BYTES: must be positive, got %d", c.LargeDataURIBytes))
+	}
+	if c.ResultMaxBytes < 1 {
+		errs = append(errs, fmt.Errorf("RESULT_MAX_BYTES: must be positive, got %d", c.ResultMaxBytes))
+	}
+	if c.TitleMinLength < 1 {
+		errs = append(errs, fmt.Errorf("TITLE_MIN_LENGTH: must be positive, got %d", c.TitleMinLength))
+	}
+	if c.TitleMaxLength < c.TitleMinLength {
+		errs = append(errs, fmt.Errorf("TITLE_MAX_LENGTH: must be at least TITLE_MIN_LENGTH (%d), got %d", c.TitleMinLength, c.TitleMaxLength))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Analyzer) validateResultCache() error {
+	if !c.ResultCacheEnabled {
+		if c.RevalidateSkipLinkCheck {
+			return errors.New("REVALIDATE_SKIP_LINK_CHECK: requires RESULT_CACHE_ENABLED")
+		}
+		if c.RedisURL != "" {
+			return errors.New("REDIS_URL: requires RESULT_CACHE_ENABLED")
+		}
+		return nil
+	}
+
+	var errs []error
+	if c.ResultCacheMaxEntries < 1 {
+		errs = append(errs, fmt.Errorf("RESULT_CACHE_MAX_ENTRIES: must be positive, got %d", c.ResultCacheMaxEntries))
+	}
+	if c.ResultCacheTTL < time.Second {
+		errs = append(errs, fmt.Errorf("RESULT_CACHE_TTL: must be at least 1s, got %s", c.ResultCacheTTL))
+	}
+	if c.RedisURL != "" {
+		errs = append(errs, c.validateRedis())
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Analyzer) validateRedis() error {
+	var errs []error
+	// The URL may carry a password, so it is never echoed back
+	if u, err := url.Parse(c.RedisURL); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+		errs = append(errs, errors.New("REDIS_URL: must be a redis:// or rediss:// URL"))
+	}
+	if err := positive("REDIS_TIMEOUT", c.RedisTimeout); err != nil {
+		errs = append(errs, err)
+	}
+	if c.RedisBreakerFailures < 1 {
+		errs = append(errs, fmt.Errorf("REDIS_BREAKER_FAILURES: must be positive, got %d", c.RedisBreakerFailures))
+	}
+	if err := positive("REDIS_BREAKER_COOLDOWN", c.RedisBreakerCooldown); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Analyzer) validateDebugTrace() error {
+	if c.DebugTraceEnabled && c.DebugTraceMaxEntries < 1 {
+		return fmt.Errorf("DEBUG_TRACE_MAX_ENTRIES: must be positive, got %d", c.DebugTraceMaxEntries)
+	}
+	return nil
+}
+
+func (c *Analyzer) validateSLO() error {
+	var errs []error
+	if c.SLOObjective <= 0 || c.SLOObjective >= 1 {
+		errs = append(errs, fmt.Errorf("SLO_OBJECTIVE: must be above 0 and below 1, got %g", c.SLOObjective))
+	}
+	errs = append(errs, positive("SLO_LATENCY", c.SLOLatency))
+	return errors.Join(errs...)
+}
+
+func (c *Analyzer) validateWarmup() error {
+	if !c.WarmupEnabled {
+		if c.StrictWarmup {
+			return errors.New("STRICT_WARMUP: requires WARMUP_ENABLED")
+		}
+		return nil
+	}
+
+	var errs []error
+	if c.WarmupURL != "" {
+		errs = append(errs, serviceURL("WARMUP_URL", c.WarmupURL))
+	}
+	errs = append(errs, positive("WARMUP_TIMEOUT", c.WarmupTimeout))
+	return errors.Join(errs...)
+}
+
+func (c *Analyzer) validateRender() error {
+	if !c.RenderEnabled {
+		if c.RenderByDefault {
+			return errors.New("RENDER_BY_DEFAULT: requires RENDER_ENABLED")
+		}
+		return nil
+	}
+
+	var errs []error
+	if c.RenderMaxConcurrent < 1 {
+		errs = append(errs, fmt.Errorf("RENDER_MAX_CONCURRENT: must be positive, got %d", c.RenderMaxConcurrent))
+	}
+	if c.ScreenshotMaxBytes < 1 {
+		errs = append(errs, fmt.Errorf("SCREENSHOT_MAX_BYTES: must be positive, got %d", c.ScreenshotMaxBytes))
+	}
+	errs = append(errs, positive("RENDER_TIMEOUT", c.RenderTimeout))
+	return errors.Join(errs...)
+}
+
+// Validate checks the gateway configuration
+func (c *Gateway) Validate() error {
+	return errors.Join(
+		c.Common.Validate(),
+		c.URLNormalization.Validate(),
+		serviceURL("ANALYZER_SERVICE_URL", c.AnalyzerURL),
+		serviceURL("LINK_CHECKER_SERVICE_URL", c.LinkCheckerURL),
+		positive("ANALYZER_TIMEOUT", c.AnalyzerTimeout),
+		positive("ROUTE_TIMEOUT_ANALYZE", c.RouteTimeoutAnalyze),
+		positive("ROUTE_TIMEOUT_BATCH", c.RouteTimeoutBatch),
+		positive("ROUTE_TIMEOUT_HEALTH", c.RouteTimeoutHealth),
+		positive("ARTIFACT_TTL", c.ArtifactTTL),
+		c.validateArtifacts(),
+		c.validateMirror(),
+		c.validateAdmission(),
+		c.validateStorage(),
+		c.validateCORS(),
+		c.validateSecurityHeaders(),
+		c.validateCompression(),
+		c.validateBodyLogging(),
+	)
+}
+
+// referrerPolicies are the values Referrer-Policy accepts
+var referrerPolicies = []string{
+	"no-referrer", "no-referrer-when-downgrade", "origin", "origin-when-cross-origin",
+	"same-origin", "strict-origin", "strict-origin-when-cross-origin", "unsafe-url",
+}
+
+func (c *Gateway) validateSecurityHeaders() error {
+	var errs []error
+	switch c.SecurityFrameOptions {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		errs = append(errs, fmt.Errorf("SECURITY_FRAME_OPTIONS: must be DENY, SAMEORIGIN or empty, got %q", c.SecurityFrameOptions))
+	}
+	if c.SecurityReferrerPolicy != "" && !slices.Contains(referrerPolicies, c.SecurityReferrerPolicy) {
+		errs = append(errs, fmt.Errorf("SECURITY_REFERRER_POLICY: must be one of %s or empty, got %q", strings.Join(referrerPolicies, ", "), c.SecurityReferrerPolicy))
+	}
+	if c.SecurityHSTS && c.SecurityHSTSMaxAge < time.Second {
+		errs = append(errs, fmt.Errorf("SECURITY_HSTS_MAX_AGE: must be at least 1s, got %s", c.SecurityHSTSMaxAge))
+	}
+	for _, path := range c.SecurityHeadersExclude {
+		if !strings.HasPrefix(path, "/") {
+			errs = append(errs, fmt.Errorf("SECURITY_HEADERS_EXCLUDE: paths must start with /, got %q", path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Gateway) validateCompression() error {
+	if c.CompressionMinSize < 0 {
+		return fmt.Errorf("COMPRESSION_MIN_SIZE: must not be negative, got %d", c.CompressionMinSize)
+	}
+	return nil
+}
+
+func (c *Gateway) validateBodyLogging() error {
+	if c.DebugLogBodiesMaxBytes < 1 {
+		return fmt.Errorf("DEBUG_LOG_BODIES_MAX_BYTES: must be positive, got %d", c.DebugLogBodiesMaxBytes)
+	}
+	return nil
+}
+
+func (c *Gateway) validateCORS() error {
+	var errs []error
+	for _, origin := range c.CORSAllowedOrigins {
+		if origin == "*" {
+			if c.CORSAllowCredentials {
+				errs = append(errs, errors.New("CORS_ALLOW_CREDENTIALS: cannot be used with * in CORS_ALLOWED_ORIGINS, list the origins instead"))
+			}
+			continue
+		}
+		if !validOrigin(origin) {
+			errs = append(errs, fmt.Errorf("CORS_ALLOWED_ORIGINS: must be *, an origin such as https://app.example.com or a wildcard such as https://*.example.com, got %q", origin))
+		}
+	}
+	if len(c.CORSAllowedOrigins) > 0 && len(c.CORSAllowedMethods) == 0 {
+		errs = append(errs, errors.New("CORS_ALLOWED_METHODS: required when CORS_ALLOWED_ORIGINS is set"))
+	}
+	if c.CORSMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("CORS_MAX_AGE: must not be negative, got %s", c.CORSMaxAge))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Gateway) validateMirror() error {
+	if c.MirrorAnalyzerURL == "" {
+		return nil
+	}
+	errs := []error{serviceURL("MIRROR_ANALYZER_URL", c.MirrorAnalyzerURL)}
+	if c.MirrorPercent <= 0 || c.MirrorPercent > 100 {
+		errs = append(errs, fmt.Errorf("MIRROR_PERCENT: must be above 0 and at most 100, got %g", c.MirrorPercent))
+	}
+	if c.MirrorMaxConcurrent < 1 {
+		errs = append(errs, fmt.Errorf("MIRROR_MAX_CONCURRENT: must be positive, got %d", c.MirrorMaxConcurrent))
+	}
+	errs = append(errs, positive("MIRROR_TIMEOUT", c.MirrorTimeout))
+	return errors.Join(errs...)
+}
+
+func (c *Gateway) validateAdmission() error {
+	var errs []error
+	if c.MaxConcurrentAnalyses < 1 {
+		errs = append(errs, fmt.Errorf("MAX_CONCURRENT_ANALYSES: must be positive, got %d", c.MaxConcurrentAnalyses))
+	}
+	if c.AnalysisQueueSize < 0 {
+		errs = append(errs, fmt.Errorf("ANALYSIS_QUEUE_SIZE: must not be negative, got %d", c.AnalysisQueueSize))
+	}
+	errs = append(errs, positive("ANALYSIS_QUEUE_TIMEOUT", c.AnalysisQueueTimeout))
+	return errors.Join(errs...)
+}
+
+func (c *Gateway) validateStorage() error {
+	var errs []error
+	switch c.StorageBackend {
+	case "memory":
+		if c.StorageMaxResults < 1 {
+			errs = append(errs, fmt.Errorf("STORAGE_MAX_RESULTS: must be positive, got %d", c.StorageMaxResults))
+		}
+	case "postgres":
+		if c.PostgresDSN == "" {
+			errs = append(errs, errors.New("POSTGRES_DSN: required when STORAGE_BACKEND is postgres"))
+		}
+		if c.PostgresMaxConns < 1 {
+			errs = append(errs, fmt.Errorf("POSTGRES_MAX_CONNS: must be positive, got %d", c.PostgresMaxConns))
+		}
+		if c.PostgresMinConns < 0 || c.PostgresMinConns > c.PostgresMaxConns {
+			errs = append(errs, fmt.Errorf("POSTGRES_MIN_CONNS: must be between 0 and POSTGRES_MAX_CONNS, got %d", c.PostgresMinConns))
+		}
+		errs = append(errs, positive("POSTGRES_MAX_CONN_LIFETIME", c.PostgresMaxConnLifetime))
+	case "sqlite":
+		errs = append(errs, errors.New("STORAGE_BACKEND: sqlite is not available yet, use memory or postgres"))
+	default:
+		errs = append(errs, fmt.Errorf("STORAGE_BACKEND: must be memory or postgres, got %q", c.StorageBackend))
+	}
+	errs = append(errs, positive("STORAGE_QUERY_TIMEOUT", c.StorageQueryTimeout))
+	return errors.Join(errs...)
+}
+
+func (c *Gateway) validateArtifacts() error {
+	var errs []error
+	if c.ArtifactMaxItems < 1 {
+		errs = append(errs, fmt.Errorf("ARTIFACT_MAX_ITEMS: must be positive, got %d", c.ArtifactMaxItems))
+	}
+	if c.ArtifactMaxBytes < 1 {
+		errs = append(errs, fmt.Errorf("ARTIFACT_MAX_BYTES: must be positive, got %d", c.ArtifactMaxBytes))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the link checker configuration
+func (c *LinkChecker) Validate() error {
+	var errs []error
+	if c.WorkerPoolSize < 1 {
+		errs = append(errs, fmt.Errorf("WORKER_POOL_SIZE: must be positive, got %d", c.WorkerPoolSize))
+	}
+	if c.MaxLinksPerRequest < 1 {
+		errs = append(errs, fmt.Errorf("MAX_LINKS_PER_REQUEST: must be positive, got %d", c.MaxLinksPerRequest))
+	}
+	if c.HostDelay < 0 || c.HostDelay > maxHostDelay {
+		errs = append(errs, fmt.Errorf("LINK_CHECK_HOST_DELAY: must be between 0s and %s, got %s", maxHostDelay, c.HostDelay))
+	}
+	if c.PreconnectHosts < 0 || c.PreconnectHosts > maxPreconnectHosts {
+		errs = append(errs, fmt.Errorf("LINK_CHECK_PRECONNECT_HOSTS: must be between 0 and %d, got %d", maxPreconnectHosts, c.PreconnectHosts))
+	}
+	if c.HedgeDelay < 0 || c.HedgeDelay > 0 && c.HedgeDelay >= c.CheckTimeout {
+		errs = append(errs, fmt.Errorf("LINK_CHECK_HEDGE_DELAY: must be 0s or less than CHECK_TIMEOUT (%s), got %s", c.CheckTimeout, c.HedgeDelay))
+	}
+	return errors.Join(
+		c.Common.Validate(),
+		c.DNS.Validate(),
+		errors.Join(errs...),
+		positive("CHECK_TIMEOUT", c.CheckTimeout),
+	)
+}
+
+// validOrigin reports whether origin is a scheme and host, optionally with a
+// port, whose host may start with *. for any subdomain
+func validOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.User != nil || u.Path != "" || u.RawQuery != "" {
+		return false
+	}
+	host := strings.TrimPrefix(u.Host, "*.")
+	return host != "" && !strings.Contains(host, "*")
+}
+
+func positive(name string, d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("%s: must be a positive duration, got %s", name, d)
+	}
+	return nil
+}
+
+func serviceURL(name, value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s: must be an absolute http(s) URL, got %q", name, value)
+	}
+	return nil
+}
+
+// Fields returns the configuration as key/value pairs for structured logging,
+// with fields tagged secret:"true" redacted
+func Fields(cfg any) []any {
+	var fields []any
+	walk(reflect.ValueOf(cfg).Elem(), func(field reflect.StructField, value reflect.Value) {
+		name := jsonName(field)
+		if field.Tag.Get("secret") == "true" && !value.IsZero() {
+			fields = append(fields, name, redacted)
+			return
+		}
+		if d, ok := value.Interface().(time.Duration); ok {
+			fields = append(fields, name, d.String())
+			return
+		}
+		fields = append(fields, name, value.Interface())
+	})
+	return fields
+}
+
+// Changed returns the env names of the fields whose values differ between
+// two configurations of the same type, in declaration order
+func Changed(old, next any) []string {
+	var changed []string
+	nextValue := reflect.ValueOf(next).Elem()
+	walk(reflect.ValueOf(old).Elem(), func(field reflect.StructField, value reflect.Value) {
+		other := nextValue.FieldByIndex(fieldIndex(nextValue.Type(), field.Name))
+		if reflect.DeepEqual(value.Interface(), other.Interface()) {
+			return
+		}
+		name := field.Tag.Get("env")
+		if name == "" {
+			name = jsonName(field)
+		}
+		changed = append(changed, name)
+	})
+	return changed
+}
+
+// load applies the optional configuration file and then the environment
+func load(cfg any) error {
+	if path := os.Getenv(FileEnvVar); path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return err
+		}
+	}
+	return loadEnv(cfg)
+}
+
+func loadEnv(cfg any) error {
+	var errs []error
+	walk(reflect.ValueOf(cfg).Elem(), func(field reflect.StructField, value reflect.Value) {
+		key := field.Tag.Get("env")
+		if key == "" {
+			return
+		}
+		raw, ok := os.LookupEnv(key)
+		if !ok || raw == "" {
+			return
+		}
+		if err := setField(value, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+func loadFile(cfg any, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		// Numbers are kept as written, since as float64 an integer of a
+		// million or more would print in exponent form
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&values)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("unsupported config file extension %q: use .json, .yaml or .yml", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	var errs []error
+	walk(reflect.ValueOf(cfg).Elem(), func(field reflect.StructField, value reflect.Value) {
+		name := jsonName(field)
+		raw, ok := values[name]
+		if !ok {
+			return
+		}
+		if err := setField(value, fmt.Sprint(raw)); err != nil {
+			errs = append(errs, fmt.Errorf("%s (in %s): %w", name, path, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// walk visits every leaf field of a config struct, descending into embedded structs
+func walk(v reflect.Value, visit func(reflect.StructField, reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			walk(value, visit)
+			continue
+		}
+		visit(field, value)
+	}
+}
+
+func setField(value reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+
+	if value.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		value.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		value.SetBool(b)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", value.Type())
+		}
+		// File lists arrive as "[a b]" via fmt.Sprint, env lists as "a,b"
+		raw = strings.Trim(raw, "[]")
+		items := strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ' ' })
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported config type %s", value.Type())
+	}
+	return nil
+}
+
+// fieldIndex resolves a promoted field name to its index path
+func fieldIndex(t reflect.Type, name string) []int {
+	field, _ := t.FieldByName(name)
+	return field.Index
+}
+
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,667 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/urlutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAnalyzer_Defaults(t *testing.T) {
+	cfg, err := LoadAnalyzer()
+	require.NoError(t, err)
+
+	assert.Equal(t, 8081, cfg.Port)
+	assert.Equal(t, "info", cfg.LogLevel)
+	assert.True(t, cfg.LogToFile)
+	assert.Equal(t, "http://localhost:8082", cfg.LinkCheckerURL)
+	assert.Equal(t, 30*time.Second, cfg.FetchTimeout)
+	assert.Equal(t, 60*time.Second, cfg.MaxAnalysisTimeout)
+	assert.False(t, cfg.RenderEnabled)
+	assert.Equal(t, 2, cfg.RenderMaxConcurrent)
+	assert.Equal(t, urlutil.DefaultPolicy(), cfg.URLNormalization.Policy())
+}
+
+func TestLoadLinkChecker_FromEnv(t *testing.T) {
+	t.Setenv("PORT", "9999")
+	t.Setenv("WORKER_POOL_SIZE", "20")
+	t.Setenv("CHECK_TIMEOUT", "500ms")
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("LOG_TO_FILE", "false")
+
+	cfg, err := LoadLinkChecker()
+	require.NoError(t, err)
+
+	assert.Equal(t, 9999, cfg.Port)
+	assert.Equal(t, 20, cfg.WorkerPoolSize)
+	assert.Equal(t, 500*time.Millisecond, cfg.CheckTimeout)
+	assert.Equal(t, "debug", cfg.LogLevel)
+	assert.False(t, cfg.LogToFile)
+}
+
+func TestLoadGateway_CORSOrigins(t *testing.T) {
+	cfg, err := LoadGateway()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.CORSAllowedOrigins, "same-origin only by default")
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com, https://*.example.org:8443")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	cfg, err = LoadGateway()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://app.example.com", "https://*.example.org:8443"}, cfg.CORSAllowedOrigins)
+	assert.True(t, cfg.CORSAllowCredentials)
+	assert.Equal(t, []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}, cfg.CORSAllowedMethods)
+}
+
+func TestDNS_DoHEndpoint(t *testing.T) {
+	cfg := DefaultLinkChecker()
+	assert.Empty(t, cfg.DoHEndpoint(), "the system resolver by default")
+
+	t.Setenv("DNS_RESOLVER", "doh:https://cloudflare-dns.com/dns-query")
+	cfg, err := LoadLinkChecker()
+	require.NoError(t, err)
+	assert.Equal(t, "https://cloudflare-dns.com/dns-query", cfg.DoHEndpoint())
+	assert.Equal(t, 2*time.Second, cfg.DNSResolverTimeout)
+
+	cfg.DNSResolver = "system"
+	assert.Empty(t, cfg.DoHEndpoint())
+}
+
+func TestLoad_InvalidValuesFailFast(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		load     func() error
+		contains string
+	}{
+		{
+			name:     "unparsable duration",
+			env:      map[string]string{"CHECK_TIMEOUT": "5sec"},
+			load:     func() error { _, err := LoadLinkChecker(); return err },
+			contains: `CHECK_TIMEOUT: invalid duration "5sec"`,
+		},
+		{
+			name:     "zero worker pool",
+			env:      map[string]string{"WORKER_POOL_SIZE": "0"},
+			load:     func() error { _, err := LoadLinkChecker(); return err },
+			contains: "WORKER_POOL_SIZE: must be positive",
+		},
+		{
+			name:     "zero max links per request",
+			env:      map[string]string{"MAX_LINKS_PER_REQUEST": "0"},
+			load:     func() error { _, err := LoadLinkChecker(); return err },
+			contains: "MAX_LINKS_PER_REQUEST: must be positive",
+		},
+		{
+			name:     "host delay above the limit",
+			env:      map[string]string{"LINK_CHECK_HOST_DELAY": "1m"},
+			load:     func() error { _, err := LoadLinkChecker(); return err },
+			contains: "LINK_CHECK_HOST_DELAY: must be between 0s and 10s",
+		},
+		{
+			name:     "too many preconnect hosts",
+			env:      map[string]string{"LINK_CHECK_PRECONNECT_HOSTS": "65"},
+			load:     func() error { _, err := LoadLinkChecker(); return err },
+			contains: "LINK_CHECK_PRECONNECT_HOSTS: must be between 0 and 64",
+		},
+		{
+			name:     "hedge delay not below the check timeout",
+			env:      map[string]string{"LINK_CHECK_HEDGE_DELAY": "5s"},
+			load:     func() error { _, err := LoadLinkChecker(); return err },
+			contains: "LINK_CHECK_HEDGE_DELAY: must be 0s or less than CHECK_TIMEOUT (5s), got 5s",
+		},
+		{
+			name:     "non-numeric worker pool",
+			env:      map[string]string{"WORKER_POOL_SIZE": "ten"},
+			load:     func() error { _, err := LoadLinkChecker(); return err },
+			contains: `WORKER_POOL_SIZE: invalid integer "ten"`,
+		},
+		{
+			name:     "health check timeout above the interval",
+			env:      map[string]string{"HEALTH_CHECK_INTERVAL": "1s", "HEALTH_CHECK_TIMEOUT": "2s"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "HEALTH_CHECK_TIMEOUT: must not exceed HEALTH_CHECK_INTERVAL (1s), got 2s",
+		},
+		{
+			name:     "zero route timeout",
+			env:      map[string]string{"ROUTE_TIMEOUT_BATCH": "0s"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "ROUTE_TIMEOUT_BATCH: must be a positive duration",
+		},
+		{
+			name:     "unknown disabled rule",
+			env:      map[string]string{"ANALYSIS_RULES_DISABLED": "links,spelling"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: `ANALYSIS_RULES_DISABLED: unknown rule "spelling"`,
+		},
+		{
+			name:     "strict warm-up without warm-up",
+			env:      map[string]string{"STRICT_WARMUP": "true"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "STRICT_WARMUP: requires WARMUP_ENABLED",
+		},
+		{
+			name:     "relative warm-up URL",
+			env:      map[string]string{"WARMUP_ENABLED": "true", "WARMUP_URL": "/health"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: `WARMUP_URL: must be an absolute http(s) URL, got "/health"`,
+		},
+		{
+			name:     "port out of range",
+			env:      map[string]string{"PORT": "70000"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "PORT: must be between 1 and 65535",
+		},
+		{
+			name:     "unknown log level",
+			env:      map[string]string{"LOG_LEVEL": "verbose"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "LOG_LEVEL: must be one of",
+		},
+		{
+			name:     "debug endpoints without protection",
+			env:      map[string]string{"DEBUG_ENDPOINTS": "true"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "DEBUG_ENDPOINTS: requires DEBUG_ADDR or ADMIN_TOKEN",
+		},
+		{
+			name:     "debug address without debug endpoints",
+			env:      map[string]string{"DEBUG_ADDR": "127.0.0.1:6060"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "DEBUG_ADDR: requires DEBUG_ENDPOINTS",
+		},
+		{
+			name:     "debug address without a port",
+			env:      map[string]string{"DEBUG_ENDPOINTS": "true", "DEBUG_ADDR": "127.0.0.1"},
+			load:     func() error { _, err := LoadLinkChecker(); return err },
+			contains: `DEBUG_ADDR: must be host:port, got "127.0.0.1"`,
+		},
+		{
+			name:     "TLS certificate without a key",
+			env:      map[string]string{"TLS_CERT_FILE": "/etc/tls/tls.crt"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "TLS_CERT_FILE: must be set together with TLS_KEY_FILE",
+		},
+		{
+			name:     "HTTP redirect without TLS",
+			env:      map[string]string{"TLS_REDIRECT_ADDR": ":8080"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "TLS_REDIRECT_ADDR: requires TLS_CERT_FILE and TLS_KEY_FILE",
+		},
+		{
+			name:     "zero TLS reload interval",
+			env:      map[string]string{"TLS_CERT_FILE": "/etc/tls/tls.crt", "TLS_KEY_FILE": "/etc/tls/tls.key", "TLS_RELOAD_INTERVAL": "0s"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "TLS_RELOAD_INTERVAL: must be a positive duration",
+		},
+		{
+			name:     "service URL without scheme",
+			env:      map[string]string{"ANALYZER_SERVICE_URL": "analyzer:8081"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "ANALYZER_SERVICE_URL: must be an absolute http(s) URL",
+		},
+		{
+			name:     "CORS origin with a path",
+			env:      map[string]string{"CORS_ALLOWED_ORIGINS": "https://app.example.com/ui"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: `CORS_ALLOWED_ORIGINS: must be *, an origin such as https://app.example.com or a wildcard such as https://*.example.com, got "https://app.example.com/ui"`,
+		},
+		{
+			name:     "CORS wildcard inside the host",
+			env:      map[string]string{"CORS_ALLOWED_ORIGINS": "https://app.*.example.com"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "CORS_ALLOWED_ORIGINS: must be *",
+		},
+		{
+			name:     "CORS credentials for any origin",
+			env:      map[string]string{"CORS_ALLOWED_ORIGINS": "*", "CORS_ALLOW_CREDENTIALS": "true"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "CORS_ALLOW_CREDENTIALS: cannot be used with * in CORS_ALLOWED_ORIGINS",
+		},
+		{
+			name:     "unknown frame options",
+			env:      map[string]string{"SECURITY_FRAME_OPTIONS": "ALLOW-FROM https://example.com"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "SECURITY_FRAME_OPTIONS: must be DENY, SAMEORIGIN or empty",
+		},
+		{
+			name:     "unknown referrer policy",
+			env:      map[string]string{"SECURITY_REFERRER_POLICY": "never"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "SECURITY_REFERRER_POLICY: must be one of no-referrer,",
+		},
+		{
+			name:     "HSTS without a max age",
+			env:      map[string]string{"SECURITY_HSTS": "true", "SECURITY_HSTS_MAX_AGE": "0s"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "SECURITY_HSTS_MAX_AGE: must be at least 1s",
+		},
+		{
+			name:     "relative excluded path",
+			env:      map[string]string{"SECURITY_HEADERS_EXCLUDE": "debug/pprof/"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: `SECURITY_HEADERS_EXCLUDE: paths must start with /, got "debug/pprof/"`,
+		},
+		{
+			name:     "zero artifact cap",
+			env:      map[string]string{"ARTIFACT_MAX_BYTES": "0"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "ARTIFACT_MAX_BYTES: must be positive",
+		},
+		{
+			name:     "relative mirror analyzer URL",
+			env:      map[string]string{"MIRROR_ANALYZER_URL": "analyzer-canary:8081"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: `MIRROR_ANALYZER_URL: must be an absolute http(s) URL, got "analyzer-canary:8081"`,
+		},
+		{
+			name:     "mirror percent above 100",
+			env:      map[string]string{"MIRROR_ANALYZER_URL": "http://analyzer-canary:8081", "MIRROR_PERCENT": "150"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "MIRROR_PERCENT: must be above 0 and at most 100, got 150",
+		},
+		{
+			name:     "zero concurrent mirror calls",
+			env:      map[string]string{"MIRROR_ANALYZER_URL": "http://analyzer-canary:8081", "MIRROR_MAX_CONCURRENT": "0"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "MIRROR_MAX_CONCURRENT: must be positive",
+		},
+		{
+			name:     "unknown fault target",
+			env:      map[string]string{"FAULT_INJECT": "database:latency=2s"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: `FAULT_INJECT: rule "database:latency=2s": target must be one of analyzer, link-checker, inbound`,
+		},
+		{
+			name:     "fault rate above 1",
+			env:      map[string]string{"FAULT_INJECT": "link-checker:error=503:rate=5"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: `FAULT_INJECT: rule "link-checker:error=503:rate=5": rate "5": must be above 0 and at most 1`,
+		},
+		{
+			name:     "zero concurrent analyses",
+			env:      map[string]string{"MAX_CONCURRENT_ANALYSES": "0"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "MAX_CONCURRENT_ANALYSES: must be positive",
+		},
+		{
+			name:     "negative analysis queue",
+			env:      map[string]string{"ANALYSIS_QUEUE_SIZE": "-1"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "ANALYSIS_QUEUE_SIZE: must not be negative",
+		},
+		{
+			name:     "negative compression minimum size",
+			env:      map[string]string{"COMPRESSION_MIN_SIZE": "-1"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "COMPRESSION_MIN_SIZE: must not be negative",
+		},
+		{
+			name:     "zero body log cap",
+			env:      map[string]string{"DEBUG_LOG_BODIES_MAX_BYTES": "0"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "DEBUG_LOG_BODIES_MAX_BYTES: must be positive",
+		},
+		{
+			name:     "unknown storage backend",
+			env:      map[string]string{"STORAGE_BACKEND": "files"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: `STORAGE_BACKEND: must be memory or postgres, got "files"`,
+		},
+		{
+			name:     "sqlite storage backend",
+			env:      map[string]string{"STORAGE_BACKEND": "sqlite"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "STORAGE_BACKEND: sqlite is not available yet",
+		},
+		{
+			name:     "postgres without DSN",
+			env:      map[string]string{"STORAGE_BACKEND": "postgres"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "POSTGRES_DSN: required when STORAGE_BACKEND is postgres",
+		},
+		{
+			name:     "more minimum than maximum postgres connections",
+			env:      map[string]string{"STORAGE_BACKEND": "postgres", "POSTGRES_DSN": "postgres://db/analyzer", "POSTGRES_MIN_CONNS": "20"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: "POSTGRES_MIN_CONNS: must be between 0 and POSTGRES_MAX_CONNS",
+		},
+		{
+			name:     "negative timeout",
+			env:      map[string]string{"FETCH_TIMEOUT": "-1s"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "FETCH_TIMEOUT: must be a positive duration",
+		},
+		{
+			name:     "invalid boolean",
+			env:      map[string]string{"LOG_TO_FILE": "yes please"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: `LOG_TO_FILE: invalid boolean "yes please"`,
+		},
+		{
+			name:     "unknown startup mode",
+			env:      map[string]string{"STARTUP_MODE": "hope"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "STARTUP_MODE: must be fail-fast or degraded",
+		},
+		{
+			name:     "render by default without rendering",
+			env:      map[string]string{"RENDER_BY_DEFAULT": "true"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "RENDER_BY_DEFAULT: requires RENDER_ENABLED",
+		},
+		{
+			name:     "zero concurrent renders",
+			env:      map[string]string{"RENDER_ENABLED": "true", "RENDER_MAX_CONCURRENT": "0"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "RENDER_MAX_CONCURRENT: must be positive",
+		},
+		{
+			name:     "zero analysis request budget",
+			env:      map[string]string{"ANALYSIS_MAX_REQUESTS": "0"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "ANALYSIS_MAX_REQUESTS: must be positive",
+		},
+		{
+			name:     "zero frame limit",
+			env:      map[string]string{"ANALYSIS_MAX_FRAMES": "0"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "ANALYSIS_MAX_FRAMES: must be positive",
+		},
+		{
+			name:     "zero pagination hop limit",
+			env:      map[string]string{"ANALYSIS_MAX_PAGINATION_HOPS": "0"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "ANALYSIS_MAX_PAGINATION_HOPS: must be positive",
+		},
+		{
+			name:     "unknown URL case",
+			env:      map[string]string{"URL_CASE": "path"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: `URL_CASE: must be host or full, got "path"`,
+		},
+		{
+			name:     "wildcard inside a stripped parameter",
+			env:      map[string]string{"URL_STRIP_PARAMS": "utm_*,ref_*_id"},
+			load:     func() error { _, err := LoadGateway(); return err },
+			contains: `URL_STRIP_PARAMS: "ref_*_id" is not a parameter name or a prefix ending in *`,
+		},
+		{
+			name:     "negative per-host analysis limit",
+			env:      map[string]string{"ANALYSIS_MAX_PER_HOST": "-1"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "ANALYSIS_MAX_PER_HOST: must not be negative",
+		},
+		{
+			name:     "zero host wait timeout",
+			env:      map[string]string{"ANALYSIS_HOST_WAIT_TIMEOUT": "0s"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "ANALYSIS_HOST_WAIT_TIMEOUT: must be a positive duration",
+		},
+		{
+			name:     "negative analysis byte budget",
+			env:      map[string]string{"ANALYSIS_MAX_BYTES": "-1"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "ANALYSIS_MAX_BYTES: must be positive",
+		},
+		{
+			name:     "zero parser depth",
+			env:      map[string]string{"PARSER_MAX_DEPTH": "0"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "PARSER_MAX_DEPTH: must be positive",
+		},
+		{
+			name:     "zero parser anchors",
+			env:      map[string]string{"PARSER_MAX_ANCHORS": "0"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "PARSER_MAX_ANCHORS: must be positive",
+		},
+		{
+			name:     "zero parser headings",
+			env:      map[string]string{"PARSER_MAX_HEADINGS": "0"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "PARSER_MAX_HEADINGS: must be positive",
+		},
+		{
+			name:     "zero large data URI size",
+			env:      map[string]string{"LARGE_DATA_URI_BYTES": "0"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "LARGE_DATA_URI_BYTES: must be positive",
+		},
+		{
+			name:     "zero result size",
+			env:      map[string]string{"RESULT_MAX_BYTES": "0"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "RESULT_MAX_BYTES: must be positive",
+		},
+		{
+			name:     "SLO objective of 1",
+			env:      map[string]string{"SLO_OBJECTIVE": "1"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "SLO_OBJECTIVE: must be above 0 and below 1, got 1",
+		},
+		{
+			name:     "zero SLO latency",
+			env:      map[string]string{"SLO_LATENCY": "0s"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "SLO_LATENCY: must be a positive duration",
+		},
+		{
+			name:     "host override pattern with a port",
+			env:      map[string]string{"HOST_OVERRIDE_HOSTS": "staging.example.com,*.example.org:8080"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: `HOST_OVERRIDE_HOSTS: "*.example.org:8080" is not a host name or *.domain`,
+		},
+		{
+			name:     "zero title min length",
+			env:      map[string]string{"TITLE_MIN_LENGTH": "0"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "TITLE_MIN_LENGTH: must be positive",
+		},
+		{
+			name:     "title max length below min",
+			env:      map[string]string{"TITLE_MIN_LENGTH": "30", "TITLE_MAX_LENGTH": "20"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "TITLE_MAX_LENGTH: must be at least TITLE_MIN_LENGTH (30), got 20",
+		},
+		{
+			name:     "sub-second result cache TTL",
+			env:      map[string]string{"RESULT_CACHE_ENABLED": "true", "RESULT_CACHE_TTL": "500ms"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "RESULT_CACHE_TTL: must be at least 1s",
+		},
+		{
+			name:     "skip link check without result cache",
+			env:      map[string]string{"REVALIDATE_SKIP_LINK_CHECK": "true"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "REVALIDATE_SKIP_LINK_CHECK: requires RESULT_CACHE_ENABLED",
+		},
+		{
+			name:     "zero debug trace entries",
+			env:      map[string]string{"DEBUG_TRACE_ENABLED": "true", "DEBUG_TRACE_MAX_ENTRIES": "0"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "DEBUG_TRACE_MAX_ENTRIES: must be positive",
+		},
+		{
+			name:     "redis without result cache",
+			env:      map[string]string{"REDIS_URL": "redis://redis:6379"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "REDIS_URL: requires RESULT_CACHE_ENABLED",
+		},
+		{
+			name:     "redis URL with the wrong scheme",
+			env:      map[string]string{"RESULT_CACHE_ENABLED": "true", "REDIS_URL": "http://redis:6379"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "REDIS_URL: must be a redis:// or rediss:// URL",
+		},
+		{
+			name:     "redis breaker without failures",
+			env:      map[string]string{"RESULT_CACHE_ENABLED": "true", "REDIS_URL": "redis://redis:6379", "REDIS_BREAKER_FAILURES": "0"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "REDIS_BREAKER_FAILURES: must be positive",
+		},
+		{
+			name:     "unknown DNS resolver",
+			env:      map[string]string{"DNS_RESOLVER": "8.8.8.8"},
+			load:     func() error { _, err := LoadLinkChecker(); return err },
+			contains: `DNS_RESOLVER: must be system or doh:<https URL>, got "8.8.8.8"`,
+		},
+		{
+			name:     "plain HTTP DoH endpoint",
+			env:      map[string]string{"DNS_RESOLVER": "doh:http://dns.example/dns-query"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "DNS_RESOLVER: must be doh: followed by an absolute https URL",
+		},
+		{
+			name:     "unknown IP family",
+			env:      map[string]string{"IP_FAMILY": "inet6"},
+			load:     func() error { _, err := LoadLinkChecker(); return err },
+			contains: `IP_FAMILY: must be dual, ipv4 or ipv6, got "inet6"`,
+		},
+		{
+			name:     "malformed outbound source IP",
+			env:      map[string]string{"OUTBOUND_SOURCE_IP": "10.0.0"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: `OUTBOUND_SOURCE_IP: must be an IP address, got "10.0.0"`,
+		},
+		{
+			name:     "outbound source IP of the other family",
+			env:      map[string]string{"OUTBOUND_SOURCE_IP": "10.0.0.2", "IP_FAMILY": "ipv6"},
+			load:     func() error { _, err := LoadLinkChecker(); return err },
+			contains: "OUTBOUND_SOURCE_IP: 10.0.0.2 is not an ipv6 address, as IP_FAMILY requires",
+		},
+		{
+			name:     "zero DNS resolver timeout",
+			env:      map[string]string{"DNS_RESOLVER": "doh:https://dns.example/dns-query", "DNS_RESOLVER_TIMEOUT": "0s"},
+			load:     func() error { _, err := LoadAnalyzer(); return err },
+			contains: "DNS_RESOLVER_TIMEOUT: must be a positive duration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			err := tt.load()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.contains)
+		})
+	}
+}
+
+func TestLoad_ReportsAllErrors(t *testing.T) {
+	t.Setenv("PORT", "0")
+	t.Setenv("WORKER_POOL_SIZE", "-3")
+
+	_, err := LoadLinkChecker()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "PORT")
+	assert.Contains(t, err.Error(), "WORKER_POOL_SIZE")
+}
+
+func TestLoad_FromFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("json", func(t *testing.T) {
+		path := filepath.Join(dir, "gateway.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"port": 9090, "analyzer_service_url": "http://analyzer:8081", "analyzer_timeout": "45s"}`), 0o600))
+		t.Setenv(FileEnvVar, path)
+
+		cfg, err := LoadGateway()
+		require.NoError(t, err)
+		assert.Equal(t, 9090, cfg.Port)
+		assert.Equal(t, "http://analyzer:8081", cfg.AnalyzerURL)
+		assert.Equal(t, 45*time.Second, cfg.AnalyzerTimeout)
+	})
+
+	t.Run("json with a large integer", func(t *testing.T) {
+		path := filepath.Join(dir, "link-checker.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"worker_pool_size": 10485760}`), 0o600))
+		t.Setenv(FileEnvVar, path)
+
+		cfg, err := LoadLinkChecker()
+		require.NoError(t, err)
+		assert.Equal(t, 10485760, cfg.WorkerPoolSize)
+	})
+
+	t.Run("yaml with env override", func(t *testing.T) {
+		path := filepath.Join(dir, "link-checker.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("worker_pool_size: 4\ncheck_timeout: 2s\nlog_to_file: false\n"), 0o600))
+		t.Setenv(FileEnvVar, path)
+		t.Setenv("WORKER_POOL_SIZE", "8")
+
+		cfg, err := LoadLinkChecker()
+		require.NoError(t, err)
+		assert.Equal(t, 8, cfg.WorkerPoolSize)
+		assert.Equal(t, 2*time.Second, cfg.CheckTimeout)
+		assert.False(t, cfg.LogToFile)
+	})
+
+	t.Run("invalid value in file", func(t *testing.T) {
+		path := filepath.Join(dir, "bad.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("check_timeout: soon\n"), 0o600))
+		t.Setenv(FileEnvVar, path)
+
+		_, err := LoadLinkChecker()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "check_timeout")
+	})
+
+	t.Run("unsupported extension", func(t *testing.T) {
+		path := filepath.Join(dir, "config.toml")
+		require.NoError(t, os.WriteFile(path, []byte("port = 1"), 0o600))
+		t.Setenv(FileEnvVar, path)
+
+		_, err := LoadGateway()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported config file extension")
+	})
+}
+
+func TestFields_RedactsSecrets(t *testing.T) {
+	cfg := &struct {
+		Common
+		Token string `json:"token" secret:"true"`
+		Empty string `json:"empty" secret:"true"`
+	}{
+		Common: defaultCommon(8080),
+		Token:  "s3cr3t",
+	}
+
+	fields := Fields(cfg)
+	values := make(map[string]any)
+	for i := 0; i < len(fields); i += 2 {
+		values[fields[i].(string)] = fields[i+1]
+	}
+
+	assert.Equal(t, redacted, values["token"])
+	assert.Equal(t, "", values["empty"])
+	assert.Equal(t, 8080, values["port"])
+	assert.Equal(t, "30s", values["startup_max_wait"])
+	assert.NotContains(t, fields, "s3cr3t")
+}
+
+func TestCommon_SlogLevel(t *testing.T) {
+	for level, expected := range map[string]string{"debug": "DEBUG", "info": "INFO", "warn": "WARN", "error": "ERROR"} {
+		c := Common{LogLevel: level}
+		assert.Equal(t, expected, c.SlogLevel().String())
+	}
+}
+
+func TestChanged(t *testing.T) {
+	old := DefaultLinkChecker()
+	next := DefaultLinkChecker()
+	assert.Empty(t, Changed(old, next))
+
+	next.LogLevel = "debug"
+	next.WorkerPoolSize = 20
+	next.Port = 9090
+
+	assert.Equal(t, []string{"PORT", "LOG_LEVEL", "WORKER_POOL_SIZE"}, Changed(old, next))
+}
@@ -0,0 +1,53 @@
+// Package contextkeys holds the keys of the values that cross package
+// boundaries in a context.Context, behind typed helpers. The key types are
+// unexported, so no other package can collide with them.
+package contextkeys
+
+import "context"
+
+// RequestIDHeader is the HTTP header a request ID travels in between services
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// legacyRequestIDKey is the plain string key request IDs were stored under
+// before this package. RequestIDFrom still reads it so values set by code
+// not yet migrated are found; it goes away in the next release.
+const legacyRequestIDKey = "request_id"
+
+// WithRequestID returns a context carrying the request ID id. An empty id
+// leaves ctx as it is.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns the request ID carried by ctx, or "" when it has none
+func RequestIDFrom(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	if id, ok := ctx.Value(legacyRequestIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+type callerKey struct{}
+
+// WithCaller returns a context carrying caller, who the work done for the
+// request is billed to. An empty caller leaves ctx as it is.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	if caller == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFrom returns the caller carried by ctx, or "" when it has none
+func CallerFrom(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
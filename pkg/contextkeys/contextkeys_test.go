@@ -0,0 +1,41 @@
+package contextkeys
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID_RoundTrip(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "req-123")
+	assert.Equal(t, "req-123", RequestIDFrom(ctx))
+}
+
+func TestRequestID_Absent(t *testing.T) {
+	assert.Empty(t, RequestIDFrom(context.Background()))
+
+	// An empty ID is not stored
+	ctx := context.Background()
+	assert.Equal(t, ctx, WithRequestID(ctx, ""))
+}
+
+func TestRequestID_LegacyKey(t *testing.T) {
+	// Values stored under the old string key are still found
+	legacy := context.WithValue(context.Background(), legacyRequestIDKey, "old-456")
+	assert.Equal(t, "old-456", RequestIDFrom(legacy))
+
+	// but the typed key wins when both are set
+	assert.Equal(t, "new-789", RequestIDFrom(WithRequestID(legacy, "new-789")))
+
+	// and a legacy value that is not a string is ignored
+	assert.Empty(t, RequestIDFrom(context.WithValue(context.Background(), legacyRequestIDKey, 123)))
+}
+
+func TestCaller_RoundTrip(t *testing.T) {
+	ctx := WithCaller(context.Background(), "key-0123456789ab")
+	assert.Equal(t, "key-0123456789ab", CallerFrom(ctx))
+
+	assert.Empty(t, CallerFrom(context.Background()))
+	assert.Equal(t, ctx, WithCaller(ctx, ""))
+}
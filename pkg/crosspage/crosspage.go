@@ -0,0 +1,151 @@
+// Package crosspage finds relations between the pages of one batch
+// analysis: shared titles, canonical URLs pointing at other pages of the
+// batch, and inputs that redirect to the same final URL.
+package crosspage
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/urlutil"
+)
+
+// Find computes the cross-page findings of a batch's results, comparing
+// URLs under urls; a nil urls compares them as NormalizeURL does. Nil
+// results, for failed analyses, are ignored. The findings are sorted and do
+// not depend on the order of results; nil is returned when there are none.
+func Find(results []*models.AnalysisResult, urls *urlutil.Normalizer) *models.CrossPageFindings {
+	pages := make([]*models.AnalysisResult, 0, len(results))
+	for _, result := range results {
+		if result != nil {
+			pages = append(pages, result)
+		}
+	}
+
+	findings := &models.CrossPageFindings{
+		DuplicateTitles:    duplicateTitles(pages, urls),
+		CanonicalTargets:   canonicalTargets(pages, urls),
+		CollapsedRedirects: collapsedRedirects(pages, urls),
+	}
+	if len(findings.DuplicateTitles) == 0 && len(findings.CanonicalTargets) == 0 && len(findings.CollapsedRedirects) == 0 {
+		return nil
+	}
+	return findings
+}
+
+// NormalizeTitle trims a title and collapses its inner whitespace
+func NormalizeTitle(title string) string {
+	return strings.Join(strings.Fields(title), " ")
+}
+
+// NormalizeURL makes trivially different spellings of a URL compare equal,
+// see models.NormalizeURL
+func NormalizeURL(rawURL string) string {
+	return models.NormalizeURL(rawURL)
+}
+
+func duplicateTitles(pages []*models.AnalysisResult, normalizer *urlutil.Normalizer) []models.DuplicateTitle {
+	groups := make(map[string][]string)
+	for _, page := range pages {
+		if title := NormalizeTitle(page.Title); title != "" {
+			groups[title] = append(groups[title], page.URL)
+		}
+	}
+
+	var found []models.DuplicateTitle
+	for title, urls := range groups {
+		if urls = distinct(urls, normalizer); len(urls) > 1 {
+			found = append(found, models.DuplicateTitle{Title: title, URLs: urls})
+		}
+	}
+	slices.SortFunc(found, func(a, b models.DuplicateTitle) int { return cmp.Compare(a.Title, b.Title) })
+	return found
+}
+
+func canonicalTargets(pages []*models.AnalysisResult, normalizer *urlutil.Normalizer) []models.CanonicalTarget {
+	// Every address a page of the batch is known by, input and final
+	owners := make(map[string][]string)
+	for _, page := range pages {
+		for _, address := range addresses(page, normalizer) {
+			owners[address] = append(owners[address], page.URL)
+		}
+	}
+
+	seen := make(map[models.CanonicalTarget]bool)
+	var found []models.CanonicalTarget
+	for _, page := range pages {
+		if page.CanonicalURL == "" {
+			continue
+		}
+		canonical := normalizer.Normalize(page.CanonicalURL)
+		if slices.Contains(addresses(page, normalizer), canonical) {
+			continue // self-referencing, the usual case
+		}
+		for _, owner := range owners[canonical] {
+			if normalizer.Normalize(owner) == normalizer.Normalize(page.URL) {
+				continue
+			}
+			target := models.CanonicalTarget{URL: page.URL, CanonicalURL: page.CanonicalURL}
+			if !seen[target] {
+				seen[target] = true
+				found = append(found, target)
+			}
+			break
+		}
+	}
+	slices.SortFunc(found, func(a, b models.CanonicalTarget) int {
+		return cmp.Or(cmp.Compare(a.URL, b.URL), cmp.Compare(a.CanonicalURL, b.CanonicalURL))
+	})
+	return found
+}
+
+func collapsedRedirects(pages []*models.AnalysisResult, normalizer *urlutil.Normalizer) []models.CollapsedRedirect {
+	groups := make(map[string][]string)
+	for _, page := range pages {
+		final := page.FinalURL
+		if final == "" {
+			final = page.URL
+		}
+		final = normalizer.Normalize(final)
+		groups[final] = append(groups[final], page.URL)
+	}
+
+	var found []models.CollapsedRedirect
+	for final, urls := range groups {
+		if urls = distinct(urls, normalizer); len(urls) > 1 {
+			found = append(found, models.CollapsedRedirect{FinalURL: final, URLs: urls})
+		}
+	}
+	slices.SortFunc(found, func(a, b models.CollapsedRedirect) int { return cmp.Compare(a.FinalURL, b.FinalURL) })
+	return found
+}
+
+// addresses returns the normalized input and final URL of a page
+func addresses(page *models.AnalysisResult, normalizer *urlutil.Normalizer) []string {
+	addrs := []string{normalizer.Normalize(page.URL)}
+	if page.FinalURL != "" {
+		if final := normalizer.Normalize(page.FinalURL); final != addrs[0] {
+			addrs = append(addrs, final)
+		}
+	}
+	return addrs
+}
+
+// distinct returns urls sorted, with spellings of the same URL reduced to
+// the first in sort order
+func distinct(urls []string, normalizer *urlutil.Normalizer) []string {
+	sorted := slices.Clone(urls)
+	slices.Sort(sorted)
+
+	seen := make(map[string]bool, len(sorted))
+	kept := sorted[:0]
+	for _, u := range sorted {
+		if key := normalizer.Normalize(u); !seen[key] {
+			seen[key] = true
+			kept = append(kept, u)
+		}
+	}
+	return kept
+}
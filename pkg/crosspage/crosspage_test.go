@@ -0,0 +1,137 @@
+package crosspage
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/urlutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func batchResults() []*models.AnalysisResult {
+	return []*models.AnalysisResult{
+		{URL: "https://example.com/", Title: "Example  Home", CanonicalURL: "https://example.com/"},
+		{URL: "https://example.com/index.html", Title: " Example Home\n", CanonicalURL: "https://example.com/"},
+		{URL: "http://example.com", Title: "Example Home", FinalURL: "https://example.com/"},
+		{URL: "https://example.com/print/about", Title: "About", CanonicalURL: "https://EXAMPLE.com/about#top"},
+		{URL: "https://example.com/about", Title: "About us"},
+		{URL: "https://example.com/elsewhere", Title: "Elsewhere", CanonicalURL: "https://other.example/"},
+		{URL: "https://example.com/old", Title: "Moved", FinalURL: "https://example.com/new"},
+		{URL: "https://example.com/legacy", Title: "Moved too", FinalURL: "https://example.com/new#content"},
+		nil,
+	}
+}
+
+func TestFind(t *testing.T) {
+	want := &models.CrossPageFindings{
+		DuplicateTitles: []models.DuplicateTitle{
+			{Title: "Example Home", URLs: []string{"http://example.com", "https://example.com/", "https://example.com/index.html"}},
+		},
+		CanonicalTargets: []models.CanonicalTarget{
+			{URL: "https://example.com/index.html", CanonicalURL: "https://example.com/"},
+			{URL: "https://example.com/print/about", CanonicalURL: "https://EXAMPLE.com/about#top"},
+		},
+		CollapsedRedirects: []models.CollapsedRedirect{
+			{FinalURL: "https://example.com/", URLs: []string{"http://example.com", "https://example.com/"}},
+			{FinalURL: "https://example.com/new", URLs: []string{"https://example.com/legacy", "https://example.com/old"}},
+		},
+	}
+
+	assert.Equal(t, want, Find(batchResults(), nil))
+}
+
+func TestFind_IndependentOfOrder(t *testing.T) {
+	results := batchResults()
+	want := Find(results, nil)
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 20 {
+		rng.Shuffle(len(results), func(i, j int) { results[i], results[j] = results[j], results[i] })
+		assert.Equal(t, want, Find(results, nil))
+	}
+}
+
+func TestFind_NothingInCommon(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []*models.AnalysisResult
+	}{
+		{name: "empty batch"},
+		{name: "all failed", results: []*models.AnalysisResult{nil, nil}},
+		{
+			name: "distinct pages",
+			results: []*models.AnalysisResult{
+				{URL: "https://example.com/a", Title: "A", CanonicalURL: "https://example.com/a"},
+				{URL: "https://example.com/b", Title: "B", CanonicalURL: "https://example.com/c"},
+				{URL: "https://example.com/d", FinalURL: "https://example.com/e"},
+			},
+		},
+		{
+			name: "same URL twice",
+			results: []*models.AnalysisResult{
+				{URL: "https://example.com/a", Title: "A"},
+				{URL: "https://EXAMPLE.com/a#top", Title: "A"},
+			},
+		},
+		{
+			name: "untitled pages",
+			results: []*models.AnalysisResult{
+				{URL: "https://example.com/a", Title: "  "},
+				{URL: "https://example.com/b"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Nil(t, Find(tt.results, nil))
+		})
+	}
+}
+
+func TestFind_URLNormalization(t *testing.T) {
+	results := []*models.AnalysisResult{
+		{URL: "https://example.com/pricing", Title: "Pricing"},
+		{URL: "https://example.com/pricing/?utm_source=mail", Title: "Pricing"},
+		{URL: "https://example.com/plans", Title: "Plans", CanonicalURL: "https://example.com/pricing/"},
+	}
+
+	// Under the default policy the trailing slash tells the pages apart
+	want := &models.CrossPageFindings{
+		DuplicateTitles: []models.DuplicateTitle{
+			{Title: "Pricing", URLs: []string{"https://example.com/pricing", "https://example.com/pricing/?utm_source=mail"}},
+		},
+		CanonicalTargets: []models.CanonicalTarget{
+			{URL: "https://example.com/plans", CanonicalURL: "https://example.com/pricing/"},
+		},
+	}
+	assert.Equal(t, want, Find(results, urlutil.Default()))
+
+	folded, err := urlutil.New(models.URLNormalization{FoldTrailingSlash: true, StripParams: urlutil.DefaultStripParams})
+	require.NoError(t, err)
+	want = &models.CrossPageFindings{
+		CanonicalTargets: []models.CanonicalTarget{
+			{URL: "https://example.com/plans", CanonicalURL: "https://example.com/pricing/"},
+		},
+	}
+	assert.Equal(t, want, Find(results, folded))
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	assert.Equal(t, "Example Home", NormalizeTitle("\t Example \n\n Home  "))
+	assert.Equal(t, "", NormalizeTitle(" \n "))
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tests := map[string]string{
+		"HTTPS://Example.COM":           "https://example.com/",
+		"https://example.com/Path#frag": "https://example.com/Path",
+		" https://example.com/a?b=1 ":   "https://example.com/a?b=1",
+		"mailto:someone@example.com":    "mailto:someone@example.com",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, NormalizeURL(in), in)
+	}
+}
@@ -0,0 +1,286 @@
+// Package faults injects latency and errors into the services' calls to
+// each other and into the requests they serve, for resilience testing. It is
+// configured with FAULT_INJECT, a comma-separated list of rules such as
+//
+//	link-checker:latency=2s:rate=0.1,analyzer:error=500:rate=0.05
+//
+// A rule's target is a service, for the calls made to it, or inbound, for the
+// API requests the service itself serves. Each matching call gets the rule's
+// latency, its error status or both, at the rule's rate. The decisions come
+// from a generator seeded once, so a run can be repeated. Without rules the
+// wrappers return what they wrap and cost nothing.
+package faults
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// Targets of a rule
+const (
+	Analyzer    = "analyzer"
+	LinkChecker = "link-checker"
+	// Inbound is the API requests the service serves
+	Inbound = "inbound"
+)
+
+var targets = []string{Analyzer, LinkChecker, Inbound}
+
+// ErrorCode is the ErrorResponse code of an injected error
+const ErrorCode = "injected_fault"
+
+// Rule injects Latency, an error with Status or both into a share Rate of
+// the calls to Target
+type Rule struct {
+	Target  string
+	Latency time.Duration
+	// Status is the HTTP status of the injected error, zero for none
+	Status int
+	Rate   float64
+}
+
+// String returns the rule in FAULT_INJECT form
+func (r Rule) String() string {
+	parts := []string{r.Target}
+	if r.Latency > 0 {
+		parts = append(parts, "latency="+r.Latency.String())
+	}
+	if r.Status != 0 {
+		parts = append(parts, "error="+strconv.Itoa(r.Status))
+	}
+	parts = append(parts, "rate="+strconv.FormatFloat(r.Rate, 'g', -1, 64))
+	return strings.Join(parts, ":")
+}
+
+// Parse parses a FAULT_INJECT value. An empty one has no rules. A rule's
+// rate defaults to 1, every call.
+func Parse(spec string) ([]Rule, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	var rules []Rule
+	for _, text := range strings.Split(spec, ",") {
+		rule, err := parseRule(strings.TrimSpace(text))
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseRule(text string) (Rule, error) {
+	fields := strings.Split(text, ":")
+	rule := Rule{Target: fields[0], Rate: 1}
+	if !slices.Contains(targets, rule.Target) {
+		return Rule{}, fmt.Errorf("rule %q: target must be one of %s", text, strings.Join(targets, ", "))
+	}
+
+	for _, field := range fields[1:] {
+		key, value, _ := strings.Cut(field, "=")
+		var err error
+		switch key {
+		case "latency":
+			rule.Latency, err = time.ParseDuration(value)
+			if err == nil && rule.Latency <= 0 {
+				err = errors.New("must be positive")
+			}
+		case "error":
+			rule.Status, err = strconv.Atoi(value)
+			if err == nil && (rule.Status < 400 || rule.Status > 599) {
+				err = errors.New("must be an HTTP status from 400 to 599")
+			}
+		case "rate":
+			rule.Rate, err = strconv.ParseFloat(value, 64)
+			if err == nil && (rule.Rate <= 0 || rule.Rate > 1) {
+				err = errors.New("must be above 0 and at most 1")
+			}
+		default:
+			return Rule{}, fmt.Errorf("rule %q: unknown setting %q, use latency, error or rate", text, key)
+		}
+		if err != nil {
+			return Rule{}, fmt.Errorf("rule %q: %s %q: %w", text, key, value, err)
+		}
+	}
+	if rule.Latency == 0 && rule.Status == 0 {
+		return Rule{}, fmt.Errorf("rule %q: needs a latency, an error or both", text)
+	}
+	return rule, nil
+}
+
+// Injector applies the rules of a FAULT_INJECT value. A nil Injector
+// injects nothing.
+type Injector struct {
+	rules []Rule
+	seed  int64
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// New returns the Injector of spec, nil when it has no rules. A zero seed
+// picks one at random; Seed tells which, to repeat the run with.
+func New(spec string, seed int64) (*Injector, error) {
+	rules, err := Parse(spec)
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+	if seed == 0 {
+		seed = rand.Int64N(math.MaxInt64) + 1
+	}
+	return &Injector{
+		rules: rules,
+		seed:  seed,
+		rng:   rand.New(rand.NewPCG(uint64(seed), 0)),
+	}, nil
+}
+
+// Rules returns the rules in FAULT_INJECT form
+func (i *Injector) Rules() []string {
+	var rules []string
+	for _, rule := range i.rules {
+		rules = append(rules, rule.String())
+	}
+	return rules
+}
+
+// Seed returns the seed of the injection decisions
+func (i *Injector) Seed() int64 {
+	return i.seed
+}
+
+// applies reports whether i has a rule for target
+func (i *Injector) applies(target string) bool {
+	return i != nil && slices.ContainsFunc(i.rules, func(rule Rule) bool { return rule.Target == target })
+}
+
+// draw decides which rules of target hit this call and returns their
+// latency, summed, and the status of the first error, zero for none
+func (i *Injector) draw(target string) (time.Duration, int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var latency time.Duration
+	status := 0
+	for _, rule := range i.rules {
+		if rule.Target != target || i.rng.Float64() >= rule.Rate {
+			continue
+		}
+		latency += rule.Latency
+		if status == 0 {
+			status = rule.Status
+		}
+	}
+	return latency, status
+}
+
+// sleep waits d, or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d == 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// errorResponse is the body of an injected error with status
+func errorResponse(status int) models.ErrorResponse {
+	return models.ErrorResponse{
+		Error:      "Injected fault",
+		StatusCode: status,
+		Code:       ErrorCode,
+		Timestamp:  time.Now(),
+	}
+}
+
+// Transport returns next with the faults of the calls to target injected,
+// next itself when there are none
+func (i *Injector) Transport(target string, next http.RoundTripper) http.RoundTripper {
+	if !i.applies(target) {
+		return next
+	}
+	return &transport{injector: i, target: target, next: next}
+}
+
+type transport struct {
+	injector *Injector
+	target   string
+	next     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	latency, status := t.injector.draw(t.target)
+	if err := sleep(req.Context(), latency); err != nil {
+		closeBody(req)
+		return nil, err
+	}
+	if status == 0 {
+		return t.next.RoundTrip(req)
+	}
+
+	closeBody(req)
+	body, _ := json.Marshal(errorResponse(status))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// closeBody closes the body of a request not sent on, as a RoundTripper
+// must
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// Middleware returns next with the inbound faults injected, next itself
+// when there are none
+func (i *Injector) Middleware(next http.Handler) http.Handler {
+	if !i.applies(Inbound) {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		latency, status := i.draw(Inbound)
+		if err := sleep(r.Context(), latency); err != nil {
+			// The client is gone
+			return
+		}
+		if status == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(errorResponse(status))
+	})
+}
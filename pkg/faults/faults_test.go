@@ -0,0 +1,236 @@
+package faults
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []Rule
+		wantErr string
+	}{
+		{name: "empty", spec: ""},
+		{
+			name: "request example",
+			spec: "link-checker:latency=2s:rate=0.1,analyzer:error=500:rate=0.05",
+			want: []Rule{
+				{Target: LinkChecker, Latency: 2 * time.Second, Rate: 0.1},
+				{Target: Analyzer, Status: 500, Rate: 0.05},
+			},
+		},
+		{
+			name: "rate defaults to every call",
+			spec: " inbound:latency=100ms:error=503 ",
+			want: []Rule{{Target: Inbound, Latency: 100 * time.Millisecond, Status: 503, Rate: 1}},
+		},
+		{name: "unknown target", spec: "redis:latency=1s", wantErr: "target must be one of analyzer, link-checker, inbound"},
+		{name: "no fault", spec: "analyzer:rate=0.5", wantErr: "needs a latency, an error or both"},
+		{name: "unknown setting", spec: "analyzer:jitter=1s", wantErr: `unknown setting "jitter"`},
+		{name: "bad latency", spec: "analyzer:latency=2", wantErr: `latency "2"`},
+		{name: "negative latency", spec: "analyzer:latency=-1s", wantErr: "must be positive"},
+		{name: "not an error status", spec: "analyzer:error=302", wantErr: "must be an HTTP status from 400 to 599"},
+		{name: "zero rate", spec: "analyzer:error=500:rate=0", wantErr: "must be above 0 and at most 1"},
+		{name: "empty rule", spec: "analyzer:error=500,", wantErr: "target must be one of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := Parse(tt.spec)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rules)
+		})
+	}
+}
+
+func TestInjector_Rules(t *testing.T) {
+	injector, err := New("link-checker:rate=0.1:latency=1500ms,inbound:error=503", 7)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"link-checker:latency=1.5s:rate=0.1", "inbound:error=503:rate=1"}, injector.Rules())
+	assert.Equal(t, int64(7), injector.Seed())
+}
+
+// countingTransport answers every request with 200 and counts them
+type countingTransport struct {
+	calls int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.calls++
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+// assertRate checks that hits out of n calls fit rate, within five
+// standard deviations of the binomial distribution
+func assertRate(t *testing.T, hits, n int, rate float64) {
+	t.Helper()
+	deviation := 5 * math.Sqrt(float64(n)*rate*(1-rate))
+	assert.InDelta(t, rate*float64(n), float64(hits), deviation, "%d of %d calls hit at rate %g", hits, n, rate)
+}
+
+func TestTransport_InjectionRate(t *testing.T) {
+	const n = 20000
+
+	for _, rate := range []float64{0.05, 0.1, 0.5} {
+		injector, err := New("analyzer:error=503:rate="+strconv.FormatFloat(rate, 'g', -1, 64), 42)
+		require.NoError(t, err)
+		next := &countingTransport{}
+		rt := injector.Transport(Analyzer, next)
+
+		injected := 0
+		for range n {
+			resp, err := rt.RoundTrip(httptest.NewRequest(http.MethodPost, "http://analyzer:8081/analyze", nil))
+			require.NoError(t, err)
+			if resp.StatusCode == http.StatusServiceUnavailable {
+				injected++
+			}
+		}
+
+		assertRate(t, injected, n, rate)
+		assert.Equal(t, n-injected, next.calls, "injected errors are not sent on")
+	}
+}
+
+func TestMiddleware_InjectionRate(t *testing.T) {
+	const n = 20000
+
+	injector, err := New("inbound:error=500:rate=0.2", 42)
+	require.NoError(t, err)
+	served := 0
+	handler := injector.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+	}))
+
+	injected := 0
+	for range n {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/analyze", nil))
+		if rec.Code == http.StatusInternalServerError {
+			injected++
+		}
+	}
+
+	assertRate(t, injected, n, 0.2)
+	assert.Equal(t, n-injected, served)
+}
+
+func TestInjector_SameSeedSameDecisions(t *testing.T) {
+	decisions := func(seed int64) []int {
+		injector, err := New("analyzer:error=500:rate=0.3", seed)
+		require.NoError(t, err)
+		var statuses []int
+		for range 200 {
+			_, status := injector.draw(Analyzer)
+			statuses = append(statuses, status)
+		}
+		return statuses
+	}
+
+	assert.Equal(t, decisions(1234), decisions(1234))
+	assert.NotEqual(t, decisions(1234), decisions(4321))
+}
+
+func TestInjector_RandomSeedIsReported(t *testing.T) {
+	injector, err := New("analyzer:error=500", 0)
+	require.NoError(t, err)
+	assert.Positive(t, injector.Seed())
+}
+
+func TestTransport_Latency(t *testing.T) {
+	injector, err := New("link-checker:latency=50ms", 1)
+	require.NoError(t, err)
+	next := &countingTransport{}
+	rt := injector.Transport(LinkChecker, next)
+
+	start := time.Now()
+	resp, err := rt.RoundTrip(httptest.NewRequest(http.MethodPost, "http://link-checker:8082/check", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, 1, next.calls)
+
+	// The latency ends with the caller's context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodPost, "http://link-checker:8082/check", nil).WithContext(ctx)
+	_, err = rt.RoundTrip(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, next.calls)
+}
+
+func TestTransport_InjectedErrorResponse(t *testing.T) {
+	injector, err := New("analyzer:error=502", 1)
+	require.NoError(t, err)
+	client := &http.Client{Transport: injector.Transport(Analyzer, &countingTransport{})}
+
+	resp, err := client.Post("http://analyzer:8081/analyze", "application/json", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	var body models.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, ErrorCode, body.Code)
+	assert.Equal(t, http.StatusBadGateway, body.StatusCode)
+}
+
+func TestMiddleware_InjectedErrorResponse(t *testing.T) {
+	injector, err := New("inbound:error=503", 1)
+	require.NoError(t, err)
+	handler := injector.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the request was served")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/check", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var body models.ErrorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, ErrorCode, body.Code)
+}
+
+// passHandler serves nothing; its address tells whether it was wrapped
+type passHandler struct{ name string }
+
+func (*passHandler) ServeHTTP(http.ResponseWriter, *http.Request) {}
+
+// Without rules for them, the client and handlers are left as they are: a
+// disabled injector costs nothing per call
+func TestDisabled_WrapsNothing(t *testing.T) {
+	disabled, err := New("", 1)
+	require.NoError(t, err)
+	assert.Nil(t, disabled)
+
+	// Rules for other targets leave these untouched as well
+	otherTargets, err := New("link-checker:error=500", 1)
+	require.NoError(t, err)
+
+	for name, injector := range map[string]*Injector{"no rules": disabled, "other targets": otherTargets} {
+		t.Run(name, func(t *testing.T) {
+			next := &countingTransport{}
+			assert.Same(t, next, injector.Transport(Analyzer, next))
+
+			handler := &passHandler{}
+			assert.Same(t, handler, injector.Middleware(handler))
+		})
+	}
+}
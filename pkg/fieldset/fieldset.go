@@ -0,0 +1,257 @@
+// Package fieldset implements sparse fieldsets: a client names the fields
+// of a JSON response it wants, such as title,headings,links.total, and gets
+// only those. A Set is parsed against the Go type of the values it projects,
+// so a path naming no field is rejected before any work is done, and
+// projects them through their JSON encoding, so their json tags and
+// marshalers are honored as they are for the full response.
+package fieldset
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ErrUnknownField is wrapped by the error of a path naming no field
+var ErrUnknownField = errors.New("unknown field")
+
+// Each addresses every element of an array in the at path of Project
+const Each = "*"
+
+// Set is a selection of fields. The nil Set selects everything.
+type Set struct {
+	root node
+}
+
+// node maps the JSON names of the selected fields of an object to their
+// own selection; a nil node selects its field whole
+type node map[string]node
+
+// Parse parses spec, a comma-separated list of dot-separated paths of JSON
+// field names, against typ, the type of the values to project. Paths go
+// through pointers, into the elements of slices and arrays and into the
+// values of maps by key; they end at values with their own JSON encoding,
+// such as times. A path selects its field whole, children included. An
+// empty spec gives the nil Set.
+func Parse(typ reflect.Type, spec string) (*Set, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	set := &Set{root: node{}}
+	for _, path := range strings.Split(spec, ",") {
+		path = strings.TrimSpace(path)
+		segments := strings.Split(path, ".")
+		if !exists(typ, segments) {
+			return nil, fmt.Errorf("%w %q", ErrUnknownField, path)
+		}
+		set.root.add(segments)
+	}
+	return set, nil
+}
+
+// Paths returns the selected paths, sorted, or nil for the nil Set
+func (s *Set) Paths() []string {
+	if s == nil {
+		return nil
+	}
+	var paths []string
+	s.root.walk("", func(path string) { paths = append(paths, path) })
+	return paths
+}
+
+// Project returns the fields of v the Set selects, as the values
+// encoding/json decodes into an any, numbers as json.Number. at is where in
+// v the values the Set was parsed for are, as JSON names with Each for
+// every element of an array, such as "items", Each, "result" for the
+// results of a list; the rest of v is kept whole. The nil Set returns v
+// as it is.
+func (s *Set) Project(v any, at ...string) (any, error) {
+	if s == nil {
+		return v, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	return visit(document, at, s.root.project), nil
+}
+
+func (n node) add(segments []string) {
+	for i, segment := range segments {
+		child, seen := n[segment]
+		switch {
+		case seen && child == nil:
+			// Already selected whole
+			return
+		case i == len(segments)-1:
+			n[segment] = nil
+			return
+		case !seen:
+			child = node{}
+			n[segment] = child
+		}
+		n = child
+	}
+}
+
+func (n node) walk(prefix string, f func(path string)) {
+	names := make([]string, 0, len(n))
+	for name := range n {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if n[name] == nil {
+			f(prefix + name)
+			continue
+		}
+		n[name].walk(prefix+name+".", f)
+	}
+}
+
+// project keeps the fields of value n selects. The selection applies to
+// every element of an array, and leaves null and other values as they are.
+func (n node) project(value any) any {
+	if n == nil {
+		return value
+	}
+	switch value := value.(type) {
+	case map[string]any:
+		selected := make(map[string]any, len(n))
+		for name, child := range n {
+			if field, ok := value[name]; ok {
+				selected[name] = child.project(field)
+			}
+		}
+		return selected
+	case []any:
+		for i, element := range value {
+			value[i] = n.project(element)
+		}
+		return value
+	}
+	return value
+}
+
+// visit applies f to the values at path in value
+func visit(value any, path []string, f func(any) any) any {
+	if len(path) == 0 {
+		return f(value)
+	}
+	switch value := value.(type) {
+	case map[string]any:
+		if field, ok := value[path[0]]; ok {
+			value[path[0]] = visit(field, path[1:], f)
+		}
+	case []any:
+		if path[0] == Each {
+			for i, element := range value {
+				value[i] = visit(element, path[1:], f)
+			}
+		}
+	}
+	return value
+}
+
+var (
+	jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+	textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// exists reports whether the path of segments names a field of typ
+func exists(typ reflect.Type, segments []string) bool {
+	for _, segment := range segments {
+		typ = elementType(typ)
+		if segment == "" || encodesItself(typ) {
+			return false
+		}
+		switch typ.Kind() {
+		case reflect.Struct:
+			field, ok := fieldByJSONName(typ, segment)
+			if !ok {
+				return false
+			}
+			typ = field.Type
+		case reflect.Map:
+			typ = typ.Elem()
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// elementType is typ through pointers and the elements of slices and
+// arrays, whose fields paths select in each element. Byte slices are
+// encoded as strings and kept.
+func elementType(typ reflect.Type) reflect.Type {
+	for {
+		switch typ.Kind() {
+		case reflect.Pointer:
+			typ = typ.Elem()
+		case reflect.Slice, reflect.Array:
+			if typ.Elem().Kind() == reflect.Uint8 {
+				return typ
+			}
+			typ = typ.Elem()
+		default:
+			return typ
+		}
+	}
+}
+
+// encodesItself reports whether typ has its own JSON encoding, whose
+// fields are not those of the type
+func encodesItself(typ reflect.Type) bool {
+	pointer := reflect.PointerTo(typ)
+	return typ.Implements(jsonMarshaler) || pointer.Implements(jsonMarshaler) ||
+		typ.Implements(textMarshaler) || pointer.Implements(textMarshaler)
+}
+
+// fieldByJSONName returns the field of struct typ encoded as name, those of
+// embedded structs included, as encoding/json names them
+func fieldByJSONName(typ reflect.Type, name string) (reflect.StructField, bool) {
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		tagName, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && tagName == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if promoted, ok := fieldByJSONName(embedded, name); ok {
+					return promoted, true
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if tagName == "" {
+			tagName = field.Name
+		}
+		if tagName == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
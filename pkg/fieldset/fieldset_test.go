@@ -0,0 +1,215 @@
+package fieldset
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type counts struct {
+	Total    int `json:"total"`
+	Internal int `json:"internal,omitempty"`
+}
+
+type link struct {
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+	Checks []struct {
+		Method string `json:"method"`
+		Code   int    `json:"code"`
+	} `json:"checks,omitempty"`
+}
+
+type Options struct {
+	Depth int `json:"depth"`
+}
+
+type page struct {
+	Options
+	Title    string              `json:"title"`
+	Counts   counts              `json:"counts"`
+	Summary  *counts             `json:"summary,omitempty"`
+	Links    []link              `json:"links,omitempty"`
+	Headers  map[string][]string `json:"headers,omitempty"`
+	Groups   map[string]counts   `json:"groups,omitempty"`
+	At       time.Time           `json:"at"`
+	Raw      []byte              `json:"raw,omitempty"`
+	Extra    any                 `json:"extra,omitempty"`
+	Renamed  string              `json:"-"`
+	Untagged string
+	private  string
+}
+
+var pageType = reflect.TypeFor[page]()
+
+func testPage() page {
+	p := page{
+		Options: Options{Depth: 2},
+		Title:   "Home",
+		Counts:  counts{Total: 3, Internal: 2},
+		Summary: &counts{Total: 9},
+		Links: []link{
+			{URL: "https://example.com/a", Status: 200},
+			{URL: "https://example.com/b", Status: 404},
+		},
+		Headers:  map[string][]string{"Server": {"nginx"}, "Vary": {"Accept"}},
+		Groups:   map[string]counts{"docs": {Total: 4, Internal: 1}},
+		At:       time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC),
+		Raw:      []byte("hi"),
+		Extra:    map[string]any{"k": 1},
+		Untagged: "kept",
+		private:  "hidden",
+	}
+	p.Links[1].Checks = append(p.Links[1].Checks, struct {
+		Method string `json:"method"`
+		Code   int    `json:"code"`
+	}{Method: "HEAD", Code: 404})
+	return p
+}
+
+// project parses spec against page and projects testPage, returning the
+// projection encoded
+func project(t *testing.T, spec string, at ...string) string {
+	t.Helper()
+	set, err := Parse(pageType, spec)
+	require.NoError(t, err)
+	projected, err := set.Project(testPage(), at...)
+	require.NoError(t, err)
+	data, err := json.Marshal(projected)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestProject(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{name: "top-level fields", spec: "title,counts", want: `{"counts":{"internal":2,"total":3},"title":"Home"}`},
+		{name: "nested field", spec: "counts.total", want: `{"counts":{"total":3}}`},
+		{name: "through a pointer", spec: "summary.total", want: `{"summary":{"total":9}}`},
+		{name: "in every array element", spec: "links.url", want: `{"links":[{"url":"https://example.com/a"},{"url":"https://example.com/b"}]}`},
+		{name: "arrays within arrays", spec: "links.checks.code", want: `{"links":[{},{"checks":[{"code":404}]}]}`},
+		{name: "map key", spec: "headers.Server", want: `{"headers":{"Server":["nginx"]}}`},
+		{name: "within a map value", spec: "groups.docs.internal", want: `{"groups":{"docs":{"internal":1}}}`},
+		{name: "missing map key", spec: "headers.Date", want: `{"headers":{}}`},
+		{name: "embedded struct field", spec: "depth", want: `{"depth":2}`},
+		{name: "untagged field", spec: "Untagged", want: `{"Untagged":"kept"}`},
+		{name: "own encodings are kept", spec: "at,raw", want: `{"at":"2026-03-01T12:00:00Z","raw":"aGk="}`},
+		{name: "whole field wins over its children", spec: "counts.total,counts", want: `{"counts":{"internal":2,"total":3}}`},
+		{name: "children after the whole field", spec: "counts,counts.total", want: `{"counts":{"internal":2,"total":3}}`},
+		{name: "siblings merge", spec: " counts.total , counts.internal ", want: `{"counts":{"internal":2,"total":3}}`},
+		{name: "repeated path", spec: "title,title", want: `{"title":"Home"}`},
+		{name: "interface values whole", spec: "extra", want: `{"extra":{"k":1}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.JSONEq(t, tt.want, project(t, tt.spec))
+		})
+	}
+}
+
+func TestProject_OmittedFieldsStayOmitted(t *testing.T) {
+	set, err := Parse(pageType, "summary,links.url,counts.internal")
+	require.NoError(t, err)
+	projected, err := set.Project(page{Counts: counts{Total: 1}})
+	require.NoError(t, err)
+	data, err := json.Marshal(projected)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"counts":{}}`, string(data))
+}
+
+func TestProject_NumbersKeepTheirEncoding(t *testing.T) {
+	type measured struct {
+		Big   int64   `json:"big"`
+		Ratio float64 `json:"ratio"`
+	}
+	set, err := Parse(reflect.TypeFor[measured](), "big,ratio")
+	require.NoError(t, err)
+	projected, err := set.Project(measured{Big: 1<<62 + 1, Ratio: 0.1})
+	require.NoError(t, err)
+	data, err := json.Marshal(projected)
+	require.NoError(t, err)
+	assert.Equal(t, `{"big":4611686018427387905,"ratio":0.1}`, string(data))
+}
+
+func TestProject_At(t *testing.T) {
+	type item struct {
+		URL    string `json:"url"`
+		Result *page  `json:"result,omitempty"`
+	}
+	type list struct {
+		Items []item `json:"items"`
+		Count int    `json:"count"`
+	}
+	first, second := testPage(), testPage()
+	second.Title = "About"
+	value := list{Items: []item{{URL: "a", Result: &first}, {URL: "b"}, {URL: "c", Result: &second}}, Count: 3}
+
+	set, err := Parse(pageType, "title")
+	require.NoError(t, err)
+	projected, err := set.Project(value, "items", Each, "result")
+	require.NoError(t, err)
+	data, err := json.Marshal(projected)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"items":[{"url":"a","result":{"title":"Home"}},{"url":"b"},{"url":"c","result":{"title":"About"}}],"count":3}`, string(data))
+
+	// A path to nothing leaves the value as it is
+	projected, err = set.Project(value, "entries", Each, "result")
+	require.NoError(t, err)
+	data, err = json.Marshal(projected)
+	require.NoError(t, err)
+	full, err := json.Marshal(value)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(full), string(data))
+}
+
+func TestProject_NilSet(t *testing.T) {
+	set, err := Parse(pageType, "  ")
+	require.NoError(t, err)
+	assert.Nil(t, set)
+	assert.Nil(t, set.Paths())
+
+	value := testPage()
+	projected, err := set.Project(value)
+	require.NoError(t, err)
+	assert.Equal(t, value, projected)
+}
+
+func TestParse_UnknownFields(t *testing.T) {
+	for _, spec := range []string{
+		"titel",
+		"title,counts.totl",
+		"Title",
+		"title.length",
+		"at.year",
+		"raw.0",
+		"extra.k",
+		"private",
+		"Renamed",
+		"-",
+		"counts.",
+		"title,,counts",
+		"links.checks.code.value",
+	} {
+		t.Run(spec, func(t *testing.T) {
+			_, err := Parse(pageType, spec)
+			assert.ErrorIs(t, err, ErrUnknownField)
+		})
+	}
+
+	_, err := Parse(pageType, "title,counts.totl")
+	assert.EqualError(t, err, `unknown field "counts.totl"`)
+}
+
+func TestSet_Paths(t *testing.T) {
+	set, err := Parse(pageType, "title,links.url,counts.total,links.status,counts")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"counts", "links.status", "links.url", "title"}, set.Paths())
+}
@@ -0,0 +1,195 @@
+// Package findings registers every kind of problem the analyzer reports in
+// AnalysisResult.Findings: its stable ID, category and severity. Clients
+// key on the IDs, so an ID is never renamed or reused for another problem;
+// a new problem gets a new ID and a definition here.
+package findings
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// Finding IDs
+const (
+	AuthRequired = "AUTH_REQUIRED"
+
+	TitleMissing  = "TITLE_MISSING"
+	TitleEmpty    = "TITLE_EMPTY"
+	TitleMultiple = "TITLE_MULTIPLE"
+	TitleTooShort = "TITLE_TOO_SHORT"
+	TitleTooLong  = "TITLE_TOO_LONG"
+
+	HeadingsMissingH1  = "HEADINGS_MISSING_H1"
+	HeadingsMultipleH1 = "HEADINGS_MULTIPLE_H1"
+
+	LinksBroken          = "LINKS_BROKEN"
+	LinksMissingNoopener = "LINKS_MISSING_NOOPENER"
+	LinksLargeDataURI    = "LINKS_LARGE_DATA_URI"
+
+	LinkTextEmpty     = "LINK_TEXT_EMPTY"
+	LinkTextGeneric   = "LINK_TEXT_GENERIC"
+	LinkTextAmbiguous = "LINK_TEXT_AMBIGUOUS"
+
+	AnchorsDuplicateID = "ANCHORS_DUPLICATE_ID"
+	AnchorsDangling    = "ANCHORS_DANGLING"
+
+	LoginFormPresent  = "LOGIN_FORM_PRESENT"
+	LoginFormInsecure = "LOGIN_FORM_INSECURE"
+
+	ContentLanguageMismatch = "CONTENT_LANGUAGE_MISMATCH"
+
+	RobotsNoIndex  = "ROBOTS_NOINDEX"
+	RobotsNoFollow = "ROBOTS_NOFOLLOW"
+	RobotsConflict = "ROBOTS_CONFLICT"
+
+	HreflangInvalidCode     = "HREFLANG_INVALID_CODE"
+	HreflangUnreachable     = "HREFLANG_UNREACHABLE"
+	HreflangMissingXDefault = "HREFLANG_MISSING_X_DEFAULT"
+	HreflangNotReciprocal   = "HREFLANG_NOT_RECIPROCAL"
+
+	AMPMissingCanonical     = "AMP_MISSING_CANONICAL"
+	AMPUnreachableAMPHTML   = "AMP_UNREACHABLE_AMPHTML"
+	AMPUnreachableCanonical = "AMP_UNREACHABLE_CANONICAL"
+
+	PaginationUnreachableNext = "PAGINATION_UNREACHABLE_NEXT"
+	PaginationUnreachablePrev = "PAGINATION_UNREACHABLE_PREV"
+	PaginationSelfReference   = "PAGINATION_SELF_REFERENCE"
+	PaginationChainLoop       = "PAGINATION_CHAIN_LOOP"
+	PaginationChainBroken     = "PAGINATION_CHAIN_BROKEN"
+)
+
+// Definition is one kind of finding
+type Definition struct {
+	ID       string `json:"id"`
+	Category string `json:"category"`
+	Severity string `json:"severity"`
+	// Description says what the finding means for any page
+	Description string `json:"description"`
+}
+
+// definitions are all the kinds of findings, by section
+var definitions = []Definition{
+	{AuthRequired, models.CategoryContent, models.SeverityWarning, "The page asks for authentication, so its content could not be analyzed"},
+
+	{TitleMissing, models.CategorySEO, models.SeverityError, "The page has no title"},
+	{TitleEmpty, models.CategorySEO, models.SeverityError, "The page's title element is empty"},
+	{TitleMultiple, models.CategorySEO, models.SeverityWarning, "The page has more than one title element; browsers and search engines take the first"},
+	{TitleTooShort, models.CategorySEO, models.SeverityWarning, "The title is too short to describe the page"},
+	{TitleTooLong, models.CategorySEO, models.SeverityWarning, "The title is too long to be shown in full in search results"},
+
+	{HeadingsMissingH1, models.CategorySEO, models.SeverityWarning, "The page has no h1 heading"},
+	{HeadingsMultipleH1, models.CategorySEO, models.SeverityInfo, "The page has more than one h1 heading"},
+
+	{LinksBroken, models.CategoryContent, models.SeverityWarning, "Links of the page could not be reached"},
+	{LinksMissingNoopener, models.CategorySecurity, models.SeverityWarning,
+		`Links open a new tab without rel="noopener" or "noreferrer", handing the opened page a window.opener handle`},
+	{LinksLargeDataURI, models.CategoryPerformance, models.SeverityWarning,
+		"Links carry large data: URIs, which every visitor downloads as part of the page"},
+
+	{LinkTextEmpty, models.CategoryAccessibility, models.SeverityError, "Links have no accessible name"},
+	{LinkTextGeneric, models.CategoryAccessibility, models.SeverityWarning, `Link texts such as "click here" say nothing about where they lead`},
+	{LinkTextAmbiguous, models.CategoryAccessibility, models.SeverityInfo, "Links to different URLs share the same text"},
+
+	{AnchorsDuplicateID, models.CategoryContent, models.SeverityWarning, "Elements of the page share an id, which breaks links to it"},
+	{AnchorsDangling, models.CategoryContent, models.SeverityWarning, "In-page links point at ids the page does not have"},
+
+	{LoginFormPresent, models.CategorySecurity, models.SeverityInfo, "The page has a login form"},
+	{LoginFormInsecure, models.CategorySecurity, models.SeverityError, "The page has a login form but is served over plain HTTP"},
+
+	{ContentLanguageMismatch, models.CategoryContent, models.SeverityWarning, "The text of the page is in another language than it declares"},
+
+	{RobotsNoIndex, models.CategorySEO, models.SeverityWarning, "Search engines are told not to index the page"},
+	{RobotsNoFollow, models.CategorySEO, models.SeverityInfo, "Search engines are told not to follow the links of the page"},
+	{RobotsConflict, models.CategorySEO, models.SeverityWarning, "The robots meta tags and the X-Robots-Tag header disagree"},
+
+	{HreflangInvalidCode, models.CategorySEO, models.SeverityError, "An hreflang alternate has an invalid language code"},
+	{HreflangUnreachable, models.CategorySEO, models.SeverityWarning, "An hreflang alternate could not be reached"},
+	{HreflangMissingXDefault, models.CategorySEO, models.SeverityInfo, "The hreflang alternates have no x-default"},
+	{HreflangNotReciprocal, models.CategorySEO, models.SeverityWarning, "An hreflang alternate does not point back to the page"},
+
+	{AMPMissingCanonical, models.CategorySEO, models.SeverityError, "The AMP page has no canonical link"},
+	{AMPUnreachableAMPHTML, models.CategorySEO, models.SeverityWarning, "The AMP variant of the page could not be reached"},
+	{AMPUnreachableCanonical, models.CategorySEO, models.SeverityWarning, "The canonical page of the AMP page could not be reached"},
+
+	{PaginationUnreachableNext, models.CategorySEO, models.SeverityWarning, "The next page of the series could not be reached"},
+	{PaginationUnreachablePrev, models.CategorySEO, models.SeverityWarning, "The previous page of the series could not be reached"},
+	{PaginationSelfReference, models.CategorySEO, models.SeverityWarning, "The page names itself as the next or previous page of its series"},
+	{PaginationChainLoop, models.CategorySEO, models.SeverityWarning, "Following the next pages of the series leads back to a page already in it"},
+	{PaginationChainBroken, models.CategorySEO, models.SeverityWarning, "A page further along the series could not be fetched"},
+}
+
+var byID = func() map[string]Definition {
+	byID := make(map[string]Definition, len(definitions))
+	for _, definition := range definitions {
+		byID[definition.ID] = definition
+	}
+	return byID
+}()
+
+// Definitions returns every kind of finding
+func Definitions() []Definition {
+	return slices.Clone(definitions)
+}
+
+// Lookup returns the definition of id
+func Lookup(id string) (Definition, bool) {
+	definition, ok := byID[id]
+	return definition, ok
+}
+
+// New returns a finding of the kind id, explained by message. An id without
+// a definition is a bug and panics, which the tests of every evaluator
+// catch.
+func New(id, message string, evidence models.FindingEvidence) models.Finding {
+	definition, ok := byID[id]
+	if !ok {
+		panic(fmt.Sprintf("findings: %s is not registered", id))
+	}
+	return models.Finding{
+		ID:       id,
+		Category: definition.Category,
+		Severity: definition.Severity,
+		Message:  message,
+		Evidence: evidence,
+	}
+}
+
+// severities ranks the severities from the least severe
+var severities = []string{models.SeverityInfo, models.SeverityWarning, models.SeverityError}
+
+// ValidSeverity reports whether severity is info, warning or error
+func ValidSeverity(severity string) bool {
+	return slices.Contains(severities, severity)
+}
+
+// AtLeast returns the findings of list at minSeverity or above, all of them
+// when minSeverity is empty
+func AtLeast(list []models.Finding, minSeverity string) []models.Finding {
+	if minSeverity == "" {
+		return list
+	}
+	rank := slices.Index(severities, minSeverity)
+	var kept []models.Finding
+	for _, finding := range list {
+		if slices.Index(severities, finding.Severity) >= rank {
+			kept = append(kept, finding)
+		}
+	}
+	return kept
+}
+
+// Summarize counts list by category and severity
+func Summarize(list []models.Finding) *models.FindingSummary {
+	summary := &models.FindingSummary{
+		Total:      len(list),
+		ByCategory: make(map[string]int),
+		BySeverity: make(map[string]int),
+	}
+	for _, finding := range list {
+		summary.ByCategory[finding.Category]++
+		summary.BySeverity[finding.Severity]++
+	}
+	return summary
+}
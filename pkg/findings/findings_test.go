@@ -0,0 +1,120 @@
+package findings
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var idPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)+$`)
+
+func TestDefinitions_AreWellFormed(t *testing.T) {
+	categories := []string{models.CategorySEO, models.CategoryAccessibility, models.CategorySecurity, models.CategoryContent,
+		models.CategoryPerformance}
+	seen := make(map[string]bool)
+
+	for _, definition := range Definitions() {
+		assert.False(t, seen[definition.ID], "%s is defined twice", definition.ID)
+		seen[definition.ID] = true
+
+		assert.Regexp(t, idPattern, definition.ID)
+		assert.Contains(t, categories, definition.Category, definition.ID)
+		assert.True(t, ValidSeverity(definition.Severity), definition.ID)
+		assert.NotEmpty(t, definition.Description, definition.ID)
+	}
+	assert.Len(t, byID, len(definitions))
+}
+
+// TestDefinitions_CoverEveryID guards against an ID constant added without
+// its definition
+func TestDefinitions_CoverEveryID(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "findings.go", nil, 0)
+	require.NoError(t, err)
+
+	var ids []string
+	ast.Inspect(file, func(node ast.Node) bool {
+		literal, ok := node.(*ast.BasicLit)
+		if !ok || literal.Kind != token.STRING {
+			return true
+		}
+		if value, err := strconv.Unquote(literal.Value); err == nil && idPattern.MatchString(value) {
+			ids = append(ids, value)
+		}
+		return true
+	})
+
+	require.NotEmpty(t, ids)
+	for _, id := range ids {
+		_, ok := Lookup(id)
+		assert.True(t, ok, "%s has no definition", id)
+	}
+}
+
+func TestNew(t *testing.T) {
+	finding := New(HeadingsMultipleH1, "The page has 3 h1 headings", models.FindingEvidence{Count: 3})
+
+	assert.Equal(t, models.Finding{
+		ID:       HeadingsMultipleH1,
+		Category: models.CategorySEO,
+		Severity: models.SeverityInfo,
+		Message:  "The page has 3 h1 headings",
+		Evidence: models.FindingEvidence{Count: 3},
+	}, finding)
+
+	assert.PanicsWithValue(t, "findings: NOT_REGISTERED is not registered", func() {
+		New("NOT_REGISTERED", "", models.FindingEvidence{})
+	})
+}
+
+func TestAtLeast(t *testing.T) {
+	list := []models.Finding{
+		New(HeadingsMultipleH1, "", models.FindingEvidence{}),
+		New(HeadingsMissingH1, "", models.FindingEvidence{}),
+		New(TitleMissing, "", models.FindingEvidence{}),
+	}
+	ids := func(list []models.Finding) []string {
+		var ids []string
+		for _, finding := range list {
+			ids = append(ids, finding.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, ids(list), ids(AtLeast(list, "")))
+	assert.Equal(t, ids(list), ids(AtLeast(list, models.SeverityInfo)))
+	assert.Equal(t, []string{HeadingsMissingH1, TitleMissing}, ids(AtLeast(list, models.SeverityWarning)))
+	assert.Equal(t, []string{TitleMissing}, ids(AtLeast(list, models.SeverityError)))
+	assert.Empty(t, AtLeast(list[:1], models.SeverityError))
+}
+
+func TestSummarize(t *testing.T) {
+	summary := Summarize([]models.Finding{
+		New(HeadingsMultipleH1, "", models.FindingEvidence{}),
+		New(TitleMissing, "", models.FindingEvidence{}),
+		New(LinkTextEmpty, "", models.FindingEvidence{}),
+	})
+
+	assert.Equal(t, 3, summary.Total)
+	assert.Equal(t, map[string]int{models.CategorySEO: 2, models.CategoryAccessibility: 1}, summary.ByCategory)
+	assert.Equal(t, map[string]int{models.SeverityInfo: 1, models.SeverityError: 2}, summary.BySeverity)
+
+	empty := Summarize(nil)
+	assert.Zero(t, empty.Total)
+	assert.NotNil(t, empty.ByCategory)
+}
+
+func TestValidSeverity(t *testing.T) {
+	for _, severity := range []string{"info", "warning", "error"} {
+		assert.True(t, ValidSeverity(severity), severity)
+	}
+	for _, severity := range []string{"", "Error", "critical"} {
+		assert.False(t, ValidSeverity(severity), severity)
+	}
+}
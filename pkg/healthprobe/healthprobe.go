@@ -0,0 +1,130 @@
+// Package healthprobe checks a downstream service in the background, so the
+// health endpoints answer from the latest result instead of calling the
+// service on every request. Probes from several replicas would otherwise
+// multiply into constant load on the service, and a slow answer would make
+// the endpoints time out.
+package healthprobe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// StaleAfter is how many intervals a result is trusted for; an older one
+// means checks have stopped completing and is reported unhealthy
+const StaleAfter = 3
+
+var errNotChecked = errors.New("not checked yet")
+
+// Prober checks one dependency every interval, give or take a tenth so the
+// replicas of a service drift apart, and keeps the latest result
+type Prober struct {
+	name     string
+	checker  interfaces.HealthChecker
+	interval time.Duration
+	timeout  time.Duration
+	logger   interfaces.Logger
+	now      func() time.Time
+
+	mu   sync.RWMutex
+	last result
+}
+
+type result struct {
+	err       error
+	latency   time.Duration
+	checkedAt time.Time
+}
+
+func New(name string, checker interfaces.HealthChecker, interval, timeout time.Duration, logger interfaces.Logger) *Prober {
+	return &Prober{
+		name:     name,
+		checker:  checker,
+		interval: interval,
+		timeout:  timeout,
+		logger:   logger,
+		now:      time.Now,
+		last:     result{err: errNotChecked},
+	}
+}
+
+// Run checks the dependency right away and then on every interval until ctx
+// is done
+func (p *Prober) Run(ctx context.Context) {
+	for {
+		p.Check(ctx)
+
+		timer := time.NewTimer(p.nextWait())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *Prober) nextWait() time.Duration {
+	spread := p.interval / 5
+	if spread <= 0 {
+		return p.interval
+	}
+	return p.interval - spread/2 + rand.N(spread)
+}
+
+// Check probes the dependency once and keeps the result
+func (p *Prober) Check(ctx context.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	start := p.now()
+	err := p.checker.CheckHealth(checkCtx)
+	checkedAt := p.now()
+
+	p.mu.Lock()
+	previous := p.last.err
+	p.last = result{err: err, latency: checkedAt.Sub(start), checkedAt: checkedAt}
+	p.mu.Unlock()
+
+	switch {
+	case err != nil && previous == nil:
+		p.logger.Warn("Dependency became unhealthy", "dependency", p.name, "error", err)
+	case err == nil && previous != nil && previous != errNotChecked:
+		p.logger.Info("Dependency recovered", "dependency", p.name)
+	}
+}
+
+// CheckHealth reports the latest result without calling the dependency: nil
+// when it was healthy, and an error when it was not, has not been checked
+// yet, or the result is stale
+func (p *Prober) CheckHealth(ctx context.Context) error {
+	p.mu.RLock()
+	last := p.last
+	p.mu.RUnlock()
+
+	if last.err != nil {
+		return last.err
+	}
+	if age := p.now().Sub(last.checkedAt); age > StaleAfter*p.interval {
+		return fmt.Errorf("last check is stale, %s old", age.Round(time.Second))
+	}
+	return nil
+}
+
+// Result describes the latest check for the health endpoints
+func (p *Prober) Result() models.ProbeResult {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return models.ProbeResult{
+		LatencyMs: float64(p.last.latency.Microseconds()) / 1000,
+		CheckedAt: p.last.checkedAt,
+	}
+}
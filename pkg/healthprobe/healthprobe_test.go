@@ -0,0 +1,166 @@
+package healthprobe
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDependency is healthy until failing is set, and counts its checks
+type fakeDependency struct {
+	failing atomic.Bool
+	checks  atomic.Int32
+	// took is how long a check appears to take on the fake clock
+	took    time.Duration
+	advance func(time.Duration)
+}
+
+func (d *fakeDependency) CheckHealth(ctx context.Context) error {
+	d.checks.Add(1)
+	if d.advance != nil {
+		d.advance(d.took)
+	}
+	if d.failing.Load() {
+		return errors.New("status 503")
+	}
+	return nil
+}
+
+// fakeClock returns a prober whose time is moved by advance
+func fakeClock(p *Prober) (advance func(time.Duration)) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	p.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	return func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+}
+
+func newTestProber(dep *fakeDependency) (*Prober, func(time.Duration)) {
+	p := New("analyzer_service", dep, 10*time.Second, time.Second, logger.New("test", slog.LevelError))
+	advance := fakeClock(p)
+	dep.advance = advance
+	return p, advance
+}
+
+func TestProber_NotCheckedYet(t *testing.T) {
+	p, _ := newTestProber(&fakeDependency{})
+
+	err := p.CheckHealth(context.Background())
+
+	require.Error(t, err)
+	assert.Equal(t, "not checked yet", err.Error())
+}
+
+func TestProber_AnswersFromTheLatestCheck(t *testing.T) {
+	dep := &fakeDependency{took: 25 * time.Millisecond}
+	p, _ := newTestProber(dep)
+
+	p.Check(context.Background())
+	for range 5 {
+		assert.NoError(t, p.CheckHealth(context.Background()))
+	}
+
+	assert.Equal(t, int32(1), dep.checks.Load(), "reads must not call the dependency")
+	result := p.Result()
+	assert.Equal(t, 25.0, result.LatencyMs)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 25*int(time.Millisecond), time.UTC), result.CheckedAt)
+}
+
+func TestProber_DependencyStartsFailing(t *testing.T) {
+	dep := &fakeDependency{}
+	p, advance := newTestProber(dep)
+
+	p.Check(context.Background())
+	require.NoError(t, p.CheckHealth(context.Background()))
+
+	dep.failing.Store(true)
+	advance(10 * time.Second)
+	// Still the cached healthy result until the next check
+	assert.NoError(t, p.CheckHealth(context.Background()))
+
+	p.Check(context.Background())
+	err := p.CheckHealth(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "status 503", err.Error())
+
+	dep.failing.Store(false)
+	advance(10 * time.Second)
+	p.Check(context.Background())
+	assert.NoError(t, p.CheckHealth(context.Background()))
+}
+
+func TestProber_StaleResultIsUnhealthy(t *testing.T) {
+	p, advance := newTestProber(&fakeDependency{})
+
+	p.Check(context.Background())
+	advance(StaleAfter * 10 * time.Second)
+	assert.NoError(t, p.CheckHealth(context.Background()), "exactly at the limit is still fresh")
+
+	advance(time.Second)
+	err := p.CheckHealth(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "last check is stale, 31s old", err.Error())
+}
+
+func TestProber_CheckIsBoundedByTheTimeout(t *testing.T) {
+	blocked := checkerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	p := New("analyzer_service", blocked, time.Second, 20*time.Millisecond, logger.New("test", slog.LevelError))
+
+	start := time.Now()
+	p.Check(context.Background())
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.ErrorIs(t, p.CheckHealth(context.Background()), context.DeadlineExceeded)
+}
+
+func TestProber_RunChecksUntilCancelled(t *testing.T) {
+	dep := &fakeDependency{}
+	p := New("analyzer_service", dep, 5*time.Millisecond, time.Millisecond, logger.New("test", slog.LevelError))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return dep.checks.Load() >= 3 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+	assert.NoError(t, p.CheckHealth(context.Background()))
+}
+
+func TestProber_NextWaitIsJittered(t *testing.T) {
+	p := New("analyzer_service", &fakeDependency{}, 10*time.Second, time.Second, logger.New("test", slog.LevelError))
+
+	seen := make(map[time.Duration]bool)
+	for range 50 {
+		wait := p.nextWait()
+		assert.GreaterOrEqual(t, wait, 9*time.Second)
+		assert.Less(t, wait, 11*time.Second)
+		seen[wait] = true
+	}
+	assert.Greater(t, len(seen), 1)
+}
+
+type checkerFunc func(ctx context.Context) error
+
+func (f checkerFunc) CheckHealth(ctx context.Context) error { return f(ctx) }
@@ -0,0 +1,205 @@
+// Package httpauth reads the challenges of WWW-Authenticate and
+// Proxy-Authenticate headers (RFC 9110, section 11), so an analysis of a
+// page behind authentication can report the scheme and realm it asks for.
+// It only reads them; the analyzer never answers one.
+package httpauth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// schemes spells the well-known schemes as registered, by lower-case name
+var schemes = map[string]string{
+	"basic":            "Basic",
+	"bearer":           "Bearer",
+	"digest":           "Digest",
+	"negotiate":        "Negotiate",
+	"ntlm":             "NTLM",
+	"hoba":             "HOBA",
+	"mutual":           "Mutual",
+	"aws4-hmac-sha256": "AWS4-HMAC-SHA256",
+}
+
+// Requirement returns the authentication a 401 or 407 response with the
+// given challenge headers asks for, or nil for any other status
+func Requirement(statusCode int, challenges []string) *models.AuthRequirement {
+	if statusCode != http.StatusUnauthorized && statusCode != http.StatusProxyAuthRequired {
+		return nil
+	}
+	requirement := &models.AuthRequirement{
+		StatusCode: statusCode,
+		Proxy:      statusCode == http.StatusProxyAuthRequired,
+		Challenges: Parse(challenges),
+	}
+	if len(requirement.Challenges) > 0 {
+		requirement.Scheme = requirement.Challenges[0].Scheme
+		requirement.Realm = requirement.Challenges[0].Realm
+	}
+	return requirement
+}
+
+// Parse returns the challenges of the header values, in order. A value may
+// hold several challenges separated by commas, each a scheme followed by
+// either a token68, such as a Negotiate token, or name=value parameters of
+// which the realm is kept. Whatever does not parse is skipped.
+func Parse(values []string) []models.AuthChallenge {
+	var challenges []models.AuthChallenge
+	for _, value := range values {
+		p := parser{s: value}
+		for {
+			p.skip(" \t,")
+			if p.done() {
+				break
+			}
+			scheme := p.token()
+			if scheme == "" {
+				// Not a challenge; skip to the next one
+				p.skipUntil(',')
+				continue
+			}
+			challenge := models.AuthChallenge{Scheme: canonicalScheme(scheme)}
+			p.params(&challenge)
+			challenges = append(challenges, challenge)
+		}
+	}
+	return challenges
+}
+
+func canonicalScheme(scheme string) string {
+	if registered, ok := schemes[strings.ToLower(scheme)]; ok {
+		return registered
+	}
+	return scheme
+}
+
+// parser reads one header value
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) done() bool { return p.pos >= len(p.s) }
+
+func (p *parser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *parser) skip(chars string) {
+	for !p.done() && strings.IndexByte(chars, p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *parser) skipUntil(c byte) {
+	for !p.done() && p.s[p.pos] != c {
+		p.pos++
+	}
+}
+
+// token reads a token, "" when none starts at the position
+func (p *parser) token() string {
+	start := p.pos
+	for !p.done() && isTokenChar(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// params reads what follows the scheme of challenge, keeping the realm, up
+// to the scheme of the next challenge
+func (p *parser) params(challenge *models.AuthChallenge) {
+	p.skip(" \t")
+	if p.token68() {
+		return
+	}
+	for {
+		start := p.pos
+		name := p.token()
+		p.skip(" \t")
+		if name == "" || p.peek() != '=' {
+			// The scheme of the next challenge
+			p.pos = start
+			return
+		}
+		p.pos++
+		p.skip(" \t")
+		value := p.value()
+		if strings.EqualFold(name, "realm") && challenge.Realm == "" {
+			challenge.Realm = value
+		}
+		p.skip(" \t")
+		if p.peek() != ',' {
+			return
+		}
+		p.skip(" \t,")
+	}
+}
+
+// token68 reads a token68, such as the token of a Negotiate challenge, and
+// reports whether there was one: characters of a token68 with any = padding,
+// ending the challenge
+func (p *parser) token68() bool {
+	start := p.pos
+	for !p.done() && isToken68Char(p.s[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		return false
+	}
+	p.skip("=")
+	p.skip(" \t")
+	if p.done() || p.peek() == ',' {
+		return true
+	}
+	p.pos = start
+	return false
+}
+
+// value reads a parameter value, a token or a quoted string
+func (p *parser) value() string {
+	if p.peek() != '"' {
+		return p.token()
+	}
+	p.pos++
+	var b strings.Builder
+	for !p.done() {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == '"':
+			return b.String()
+		case c == '\\' && !p.done():
+			b.WriteByte(p.s[p.pos])
+			p.pos++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// isTokenChar reports whether c may appear in a token (RFC 9110, section
+// 5.6.2)
+func isTokenChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
+
+// isToken68Char reports whether c may appear in a token68 before its
+// padding (RFC 9110, section 11.2)
+func isToken68Char(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("-._~+/", c) >= 0
+}
@@ -0,0 +1,90 @@
+package httpauth
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []models.AuthChallenge
+	}{
+		{"basic", []string{`Basic realm="Staging"`}, []models.AuthChallenge{{Scheme: "Basic", Realm: "Staging"}}},
+		{"scheme case", []string{`bASIC REALM="Staging"`}, []models.AuthChallenge{{Scheme: "Basic", Realm: "Staging"}}},
+		{"charset after the realm", []string{`Basic realm="Staging", charset="UTF-8"`}, []models.AuthChallenge{{Scheme: "Basic", Realm: "Staging"}}},
+		{"token realm", []string{`Basic realm=intranet`}, []models.AuthChallenge{{Scheme: "Basic", Realm: "intranet"}}},
+		{"escaped quotes", []string{`Basic realm="the \"inner\" area, 2nd floor"`}, []models.AuthChallenge{{Scheme: "Basic", Realm: `the "inner" area, 2nd floor`}}},
+		{"no parameters", []string{`Basic`}, []models.AuthChallenge{{Scheme: "Basic"}}},
+		{
+			name:   "bearer error",
+			values: []string{`Bearer realm="api", error="invalid_token", error_description="The access token expired"`},
+			want:   []models.AuthChallenge{{Scheme: "Bearer", Realm: "api"}},
+		},
+		{
+			name:   "digest",
+			values: []string{`Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=SHA-256, nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`},
+			want:   []models.AuthChallenge{{Scheme: "Digest", Realm: "http-auth@example.org"}},
+		},
+		{
+			name:   "several in one header",
+			values: []string{`Newauth realm="apps", type=1, title="Login to \"apps\"", Basic realm="simple"`},
+			want:   []models.AuthChallenge{{Scheme: "Newauth", Realm: "apps"}, {Scheme: "Basic", Realm: "simple"}},
+		},
+		{
+			name:   "several headers",
+			values: []string{`Negotiate`, `NTLM`, `Basic realm="Windows"`},
+			want:   []models.AuthChallenge{{Scheme: "Negotiate"}, {Scheme: "NTLM"}, {Scheme: "Basic", Realm: "Windows"}},
+		},
+		{
+			name:   "schemes without parameters",
+			values: []string{`Negotiate, Basic realm="x"`},
+			want:   []models.AuthChallenge{{Scheme: "Negotiate"}, {Scheme: "Basic", Realm: "x"}},
+		},
+		{
+			name:   "token68",
+			values: []string{`Negotiate a87421000492aa874209af8bc028==, Basic realm="fallback"`},
+			want:   []models.AuthChallenge{{Scheme: "Negotiate"}, {Scheme: "Basic", Realm: "fallback"}},
+		},
+		{"token68 with slashes", []string{`Negotiate YIIB/wYGKwYBBQUCoIIB8zCCAe+g=`}, []models.AuthChallenge{{Scheme: "Negotiate"}}},
+		{"first realm kept", []string{`Basic realm="a", realm="b"`}, []models.AuthChallenge{{Scheme: "Basic", Realm: "a"}}},
+		{"unterminated quote", []string{`Basic realm="open`}, []models.AuthChallenge{{Scheme: "Basic", Realm: "open"}}},
+		{"surrounding space and commas", []string{` , Basic realm="x" ,, `}, []models.AuthChallenge{{Scheme: "Basic", Realm: "x"}}},
+		{"garbage skipped", []string{`"quoted", Basic realm="x"`}, []models.AuthChallenge{{Scheme: "Basic", Realm: "x"}}},
+		{"empty", []string{""}, nil},
+		{"none", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Parse(tt.values))
+		})
+	}
+}
+
+func TestRequirement(t *testing.T) {
+	assert.Nil(t, Requirement(http.StatusForbidden, []string{`Basic realm="x"`}))
+	assert.Nil(t, Requirement(http.StatusOK, nil))
+
+	assert.Equal(t, &models.AuthRequirement{
+		StatusCode: http.StatusUnauthorized,
+		Scheme:     "Bearer",
+		Realm:      "api",
+		Challenges: []models.AuthChallenge{{Scheme: "Bearer", Realm: "api"}, {Scheme: "Basic", Realm: "legacy"}},
+	}, Requirement(http.StatusUnauthorized, []string{`Bearer realm="api"`, `basic realm="legacy"`}))
+
+	assert.Equal(t, &models.AuthRequirement{
+		StatusCode: http.StatusProxyAuthRequired,
+		Proxy:      true,
+		Scheme:     "Basic",
+		Realm:      "corporate proxy",
+		Challenges: []models.AuthChallenge{{Scheme: "Basic", Realm: "corporate proxy"}},
+	}, Requirement(http.StatusProxyAuthRequired, []string{`Basic realm="corporate proxy"`}))
+
+	assert.Equal(t, &models.AuthRequirement{StatusCode: http.StatusUnauthorized}, Requirement(http.StatusUnauthorized, nil),
+		"a 401 without a challenge still requires authentication")
+}
@@ -1,50 +1,198 @@
 package httpclient
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/budget"
+	"github.com/RuvinSL/webpage-analyzer/pkg/bufpool"
+	"github.com/RuvinSL/webpage-analyzer/pkg/idn"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/trace"
+	"github.com/RuvinSL/webpage-analyzer/pkg/traffic"
 )
 
 // Client implements the HTTPClient interface
 type Client struct {
-	client  *http.Client
-	logger  interfaces.Logger
-	timeout time.Duration
+	client *http.Client
+	// transport is the client's own transport, see SetTransport
+	transport *http.Transport
+	// routes sends the requests to overridden hosts apart from transport,
+	// see WithHostOverrides
+	routes    *overrideRoutes
+	dialer    *dialer
+	warm      *warmPool
+	tlsConfig *tls.Config
+	logger    interfaces.Logger
+	timeout   time.Duration
 }
 
+// New returns a client whose requests end with the deadline of their
+// context. timeout is only a safety net for callers that set none: it bounds
+// the wait for the response headers, not the body read; see SetFixedTimeout
+// for a bound on the whole request.
 func New(timeout time.Duration, logger interfaces.Logger) *Client {
-	return &Client{
-		client: &http.Client{
-			Timeout: timeout, // overall request deadline (includes headers + body)
-			Transport: &http.Transport{
-				DialContext: (&net.Dialer{
-					Timeout:   2 * time.Second,  // TCP connect timeout
-					KeepAlive: 30 * time.Second, // keep-alive
-				}).DialContext,
-				MaxIdleConns:          100,
-				MaxIdleConnsPerHost:   70,
-				IdleConnTimeout:       60 * time.Second,
-				DisableCompression:    false,
-				TLSHandshakeTimeout:   5 * time.Second,
-				ExpectContinueTimeout: 1 * time.Second,
-			},
-		},
+	c := &Client{
+		dialer:  newDialer(),
+		warm:    newWarmPool(),
 		logger:  logger,
 		timeout: timeout,
 	}
+	// The client dials TLS itself so that Preconnect can hand the
+	// transport connections that are already past the handshake
+	c.transport = &http.Transport{
+		DialContext:           c.dialHTTP,
+		DialTLSContext:        c.dialTLS,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   70,
+		IdleConnTimeout:       60 * time.Second,
+		DisableCompression:    false,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: timeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	direct := c.transport.Clone()
+	direct.DisableKeepAlives = true
+	c.routes = &overrideRoutes{pooled: c.transport, direct: direct}
+	c.client = &http.Client{
+		CheckRedirect: checkRedirect,
+		Transport:     c.routes,
+	}
+	return c
+}
+
+// SetResolver makes the client look host names up with resolver, such as a
+// DoHResolver, instead of the system resolver
+func (c *Client) SetResolver(resolver Resolver) {
+	c.dialer.resolver = resolver
+}
+
+// SetTransport sends the requests through transport instead of the
+// client's own, which then no longer applies SetResolver, SetIPFamily,
+// SetSourceIP, host overrides or the response header timeout of New; the
+// fixed timeout, redirect policy and outbound budget stay in force
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	c.client.Transport = transport
+}
+
+// SetFixedTimeout bounds every request, redirects and body read included, by
+// timeout on top of the deadline of its context. Zero, the default, leaves
+// the deadline to the context.
+func (c *Client) SetFixedTimeout(timeout time.Duration) {
+	c.client.Timeout = max(timeout, 0)
+}
+
+// SetIPFamily limits connections to one address family,
+// models.AddressFamilyIPv4 or models.AddressFamilyIPv6; any other value, such
+// as "dual", allows both
+func (c *Client) SetIPFamily(family string) {
+	switch family {
+	case models.AddressFamilyIPv4, models.AddressFamilyIPv6:
+		c.dialer.family = family
+	default:
+		c.dialer.family = ""
+	}
+}
+
+// SetSourceIP makes every connection, redirects and Head requests
+// included, originate from ip, which ParseSourceIP checks belongs to the
+// host. Only addresses of ip's family are then dialed. Nil, the default,
+// leaves the choice to the system.
+func (c *Client) SetSourceIP(ip net.IP) {
+	c.dialer.setSource(ip)
+}
+
+// maxRedirects matches the net/http default policy
+const maxRedirects = 10
+
+// ErrRedirectLoop is returned when a redirect leads back to a URL already
+// visited by the same request
+var ErrRedirectLoop = errors.New("redirect loop")
+
+type maxRedirectsKey struct{}
+
+// WithMaxRedirects returns a context whose requests follow at most n
+// redirects, n no more than 10
+func WithMaxRedirects(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, maxRedirectsKey{}, min(max(n, 0), maxRedirects))
+}
+
+// checkRedirect stops redirect loops and charges every redirect hop to the
+// request's budget, if any
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if limit, ok := req.Context().Value(maxRedirectsKey{}).(int); ok && len(via) > limit {
+		return fmt.Errorf("stopped after %d redirects", limit)
+	}
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	target := req.URL.String()
+	for _, previous := range via {
+		if previous.URL.String() == target {
+			return fmt.Errorf("%w after %d redirects", ErrRedirectLoop, len(via))
+		}
+	}
+	if b := budget.FromContext(req.Context()); b != nil && !b.TryRequest() {
+		return budget.ErrExhausted
+	}
+	return nil
+}
+
+// reserve charges one request to the budget in ctx, if any
+func reserve(ctx context.Context) error {
+	if b := budget.FromContext(ctx); b != nil && !b.TryRequest() {
+		return fmt.Errorf("request failed: %w", budget.ErrExhausted)
+	}
+	return nil
+}
+
+// traceRequest records a finished request in the debug trace of ctx, if any
+func traceRequest(ctx context.Context, method, url string, start time.Time, statusCode int, size int64, err error) {
+	t := trace.FromContext(ctx)
+	if t == nil {
+		return
+	}
+	request := models.OutboundRequest{
+		Method:     method,
+		URL:        url,
+		StatusCode: statusCode,
+		DurationMs: float64(time.Since(start)) / float64(time.Millisecond),
+		Bytes:      size,
+	}
+	if err != nil {
+		request.Error = err.Error()
+	}
+	t.Record(request)
 }
 
 // Get performs an HTTP GET request
 func (c *Client) Get(ctx context.Context, url string) (*models.HTTPResponse, error) {
-	// Create request with context
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	return c.GetConditional(ctx, url, models.Validators{})
+}
+
+// GetConditional performs an HTTP GET that the server may answer with 304
+// Not Modified when validators still match. Zero validators make it a plain
+// GET. With a budget in ctx, the request, its redirects and the body read
+// are charged to it; with a traffic meter, the body bytes read are counted.
+func (c *Client) GetConditional(ctx context.Context, url string, validators models.Validators) (*models.HTTPResponse, error) {
+	if err := reserve(ctx); err != nil {
+		return nil, err
+	}
+
+	// Create request with context; its trace records the connections made.
+	// An internationalized host is requested in its ASCII form, so the
+	// final URL has that form too.
+	attempts := &attemptLog{}
+	req, err := http.NewRequestWithContext(attempts.trace(ctx), http.MethodGet, idn.ASCIIURL(url), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -52,13 +200,20 @@ func (c *Client) Get(ctx context.Context, url string) (*models.HTTPResponse, err
 	// Set headers
 	req.Header.Set("User-Agent", "WebPageAnalyzer/1.0")
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	req.Header.Set("Accept-Language", models.DefaultAcceptLanguage)
 	req.Header.Set("Accept-Encoding", "gzip, deflate") // Enable gzip compression - Ruvin
+	setContextHeaders(ctx, req)
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
 
 	// Log request
 	c.logger.Debug("Making HTTP request",
 		"method", req.Method,
-		"url", url,
+		"url", logger.RedactURL(url),
 	)
 
 	// Perform request
@@ -66,29 +221,35 @@ func (c *Client) Get(ctx context.Context, url string) (*models.HTTPResponse, err
 	resp, err := c.client.Do(req)
 	if err != nil {
 		c.logger.Error("HTTP request failed",
-			"url", url,
-			"error", err,
+			"url", logger.RedactURL(url),
+			"error", logger.RedactText(err.Error()),
 			"duration", time.Since(start),
 		)
-		return nil, fmt.Errorf("request failed: %w", err)
+		err = attempts.wrap(fmt.Errorf("request failed: %w", err))
+		traceRequest(ctx, http.MethodGet, url, start, 0, 0, err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	// Read response body with size limit (10MB)
-	const maxBodySize = 10 * 1024 * 1024
-	limitedReader := io.LimitReader(resp.Body, maxBodySize)
-	body, err := io.ReadAll(limitedReader)
+	body, read, truncated, err := readBody(resp)
+	if b := budget.FromContext(ctx); b != nil {
+		b.AddBytes(int64(len(body)))
+	}
+	traffic.FromContext(ctx).Add(read)
 	if err != nil {
 		c.logger.Error("Failed to read response body",
-			"url", url,
+			"url", logger.RedactURL(url),
 			"error", err,
 		)
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		err = fmt.Errorf("failed to read response: %w", err)
+		traceRequest(ctx, http.MethodGet, url, start, resp.StatusCode, int64(len(body)), err)
+		return nil, err
 	}
+	traceRequest(ctx, http.MethodGet, url, start, resp.StatusCode, int64(len(body)), nil)
 
 	// Log response
 	c.logger.Debug("HTTP response received",
-		"url", url,
+		"url", logger.RedactURL(url),
 		"status_code", resp.StatusCode,
 		"content_length", len(body),
 		"duration", time.Since(start),
@@ -99,43 +260,170 @@ func (c *Client) Get(ctx context.Context, url string) (*models.HTTPResponse, err
 		StatusCode: resp.StatusCode,
 		Body:       body,
 		Headers:    resp.Header,
+		FinalURL:   resp.Request.URL.String(),
+		Redirects:  redirectHops(resp),
+		Truncated:  truncated,
+		Protocol:   resp.Proto,
+	}
+	if truncated {
+		c.logger.Warn("Response body truncated",
+			"url", logger.RedactURL(url),
+			"limit", maxBodySize,
+		)
 	}
 
 	return response, nil
 }
 
+// redirectHops counts the redirects followed to get resp. Each request made
+// for a redirect carries the response that caused it.
+func redirectHops(resp *http.Response) int {
+	hops := 0
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		hops++
+	}
+	return hops
+}
+
+// maxBodySize caps how much of a response body is read
+const maxBodySize = 10 * 1024 * 1024
+
+// readBody reads the body, up to maxBodySize, and returns it, the bytes
+// read, and whether the body went on past the limit. The bytes read include
+// those of a read that failed or went past the limit.
+func readBody(resp *http.Response) ([]byte, int64, bool, error) {
+	if resp.ContentLength > 0 {
+		return readSized(resp.Body, min(resp.ContentLength, maxBodySize))
+	}
+	return readUnsized(resp.Body)
+}
+
+// readSized reads a body announced as size bytes straight into a slice of
+// that size, with no pooled buffer and no copy, then tries one byte more to
+// tell a body that goes on past it
+func readSized(r io.Reader, size int64) ([]byte, int64, bool, error) {
+	body := make([]byte, size)
+	n, err := readFull(r, body)
+	if err != nil {
+		return nil, int64(n), false, err
+	}
+	if n < len(body) {
+		return body[:n], int64(n), false, nil
+	}
+
+	var probe [1]byte
+	m, err := readFull(r, probe[:])
+	if err != nil {
+		return nil, int64(n + m), false, err
+	}
+	switch {
+	case m == 0:
+		return body, int64(n), false, nil
+	case size == maxBodySize:
+		return body, int64(n + m), true, nil
+	}
+	// Longer than announced, which net/http does not let through; read on
+	// as for a body of unknown length
+	return readUnsized(io.MultiReader(bytes.NewReader(body), bytes.NewReader(probe[:m]), r))
+}
+
+// readFull reads into buf until it is full or r ends, which unlike
+// io.ReadFull is not an error
+func readFull(r io.Reader, buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		m, err := r.Read(buf[n:])
+		n += m
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// readUnsized reads a body of unknown length through a pooled buffer and
+// returns an exact-size copy. The buffer moves up a size class when full
+// instead of growing by itself, so large buffers are pooled too; it never
+// leaves this function.
+func readUnsized(r io.Reader) ([]byte, int64, bool, error) {
+	buf := bufpool.Get()
+	defer func() { bufpool.Put(buf) }()
+
+	// The byte past the limit, if any, tells a cut body from one that fits
+	limited := io.LimitReader(r, maxBodySize+1)
+	var read int64
+	for {
+		if buf.Available() == 0 {
+			bigger := bufpool.GetAtLeast(2 * max(buf.Cap(), bytes.MinRead))
+			bigger.Write(buf.Bytes())
+			bufpool.Put(buf)
+			buf = bigger
+		}
+		free := buf.AvailableBuffer()[:buf.Available()]
+		m, err := limited.Read(free)
+		buf.Write(free[:m])
+		read += int64(m)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, read, false, err
+		}
+	}
+	body := buf.Bytes()
+	truncated := len(body) > maxBodySize
+	return bytes.Clone(body[:min(len(body), maxBodySize)]), read, truncated, nil
+}
+
 func (c *Client) Head(ctx context.Context, url string) (*models.HTTPResponse, error) {
-	// Create request with context
-	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err := reserve(ctx); err != nil {
+		return nil, err
+	}
+
+	// Create request with context; its trace records the connections made
+	attempts := &attemptLog{}
+	req, err := http.NewRequestWithContext(attempts.trace(ctx), http.MethodHead, idn.ASCIIURL(url), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
 	req.Header.Set("User-Agent", "WebPageAnalyzer/1.0")
+	setContextHeaders(ctx, req)
 
 	// Perform request
 	start := time.Now()
 	resp, err := c.client.Do(req)
 	if err != nil {
 		c.logger.Debug("HEAD request failed",
-			"url", url,
-			"error", err,
+			"url", logger.RedactURL(url),
+			"error", logger.RedactText(err.Error()),
 			"duration", time.Since(start),
 		)
-		return nil, fmt.Errorf("request failed: %w", err)
+		err = attempts.wrap(fmt.Errorf("request failed: %w", err))
+		traceRequest(ctx, http.MethodHead, url, start, 0, 0, err)
+		return nil, err
 	}
 	defer resp.Body.Close()
+	traceRequest(ctx, http.MethodHead, url, start, resp.StatusCode, 0, nil)
 
 	// Build response (no body for HEAD requests)
 	response := &models.HTTPResponse{
 		StatusCode: resp.StatusCode,
 		Body:       nil,
 		Headers:    resp.Header,
+		Protocol:   resp.Proto,
 	}
 
 	return response, nil
 }
 
-// Ensure Client implements interfaces.HTTPClient
-var _ interfaces.HTTPClient = (*Client)(nil)
+// Ensure Client implements interfaces.ConditionalHTTPClient and
+// interfaces.PreconnectingHTTPClient
+var (
+	_ interfaces.ConditionalHTTPClient   = (*Client)(nil)
+	_ interfaces.PreconnectingHTTPClient = (*Client)(nil)
+)
@@ -1,15 +1,26 @@
 package httpclient
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/budget"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
 	"github.com/RuvinSL/webpage-analyzer/pkg/mocks"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/trace"
+	"github.com/RuvinSL/webpage-analyzer/pkg/traffic"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -28,10 +39,11 @@ func TestNew(t *testing.T) {
 	assert.NotNil(t, client.client)
 	assert.Equal(t, mockLogger, client.logger)
 	assert.Equal(t, timeout, client.timeout)
-	assert.Equal(t, timeout, client.client.Timeout)
+	assert.Zero(t, client.client.Timeout, "requests end with their context")
 
 	// Verify interface implementation
 	var _ interfaces.HTTPClient = client
+	var _ interfaces.ConditionalHTTPClient = client
 }
 
 func TestClientGetSuccess(t *testing.T) {
@@ -194,6 +206,7 @@ func TestClientGetLargeResponse(t *testing.T) {
 
 	mockLogger.EXPECT().Debug("Making HTTP request", "method", "GET", "url", server.URL).Times(1)
 	mockLogger.EXPECT().Debug("HTTP response received", "url", server.URL, "status_code", 200, "content_length", 10*1024*1024, "duration", gomock.Any()).Times(1)
+	mockLogger.EXPECT().Warn("Response body truncated", "url", server.URL, "limit", 10*1024*1024).Times(1)
 
 	client := New(30*time.Second, mockLogger)
 	ctx := context.Background()
@@ -204,6 +217,106 @@ func TestClientGetLargeResponse(t *testing.T) {
 	assert.Equal(t, http.StatusOK, response.StatusCode)
 	// Should be limited to 10MB
 	assert.Equal(t, 10*1024*1024, len(response.Body))
+	assert.True(t, response.Truncated)
+}
+
+func TestClientGetResponseAtLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+
+	// Exactly 10MB fits, nothing is cut
+	content := strings.Repeat("A", 10*1024*1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	response, err := New(30*time.Second, mockLogger).Get(context.Background(), server.URL)
+
+	require.NoError(t, err)
+	assert.Len(t, response.Body, len(content))
+	assert.False(t, response.Truncated)
+}
+
+func TestClientGetBodyIsNotPooled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+
+	// Alternate between a sized and a chunked response
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body := strings.Repeat(strconv.Itoa(calls), 4096)
+		if calls%2 == 1 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client := New(30*time.Second, mockLogger)
+	ctx := context.Background()
+
+	first, err := client.Get(ctx, server.URL)
+	require.NoError(t, err)
+	second, err := client.Get(ctx, server.URL)
+	require.NoError(t, err)
+
+	// Reading the second body must not have reused the first one's memory
+	assert.Equal(t, strings.Repeat("1", 4096), string(first.Body))
+	assert.Equal(t, strings.Repeat("2", 4096), string(second.Body))
+}
+
+func TestClientGetConditional(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Wed, 21 Oct 2015 07:28:00 GMT"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		if r.Header.Get("If-None-Match") == etag || r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("<html>fresh</html>"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		validators models.Validators
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "no validators", wantStatus: http.StatusOK, wantBody: "<html>fresh</html>"},
+		{name: "matching etag", validators: models.Validators{ETag: etag}, wantStatus: http.StatusNotModified},
+		{name: "stale etag", validators: models.Validators{ETag: `"v0"`}, wantStatus: http.StatusOK, wantBody: "<html>fresh</html>"},
+		{name: "matching last modified", validators: models.Validators{LastModified: lastModified}, wantStatus: http.StatusNotModified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockLogger := mocks.NewMockLogger(ctrl)
+			mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+
+			client := New(30*time.Second, mockLogger)
+			response, err := client.GetConditional(context.Background(), server.URL, tt.validators)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, response.StatusCode)
+			assert.Equal(t, tt.wantBody, string(response.Body))
+			assert.Equal(t, models.Validators{ETag: etag, LastModified: lastModified}, models.ValidatorsFrom(response.Headers))
+		})
+	}
 }
 
 func TestClientGetNetworkError(t *testing.T) {
@@ -391,6 +504,72 @@ func BenchmarkClientHead(b *testing.B) {
 	}
 }
 
+func TestReadBody(t *testing.T) {
+	const limit = 10 * 1024 * 1024
+	tests := []struct {
+		name          string
+		size          int
+		contentLength int64
+		want          int
+		read          int64
+		truncated     bool
+	}{
+		{name: "known length", size: 3000, contentLength: 3000, want: 3000, read: 3000},
+		{name: "unknown length", size: 3000, contentLength: -1, want: 3000, read: 3000},
+		{name: "unknown length over a size class", size: 3 << 20, contentLength: -1, want: 3 << 20, read: 3 << 20},
+		{name: "known length past the limit", size: limit + 10, contentLength: limit + 10, want: limit, read: limit + 1, truncated: true},
+		{name: "unknown length past the limit", size: limit + 10, contentLength: -1, want: limit, read: limit + 1, truncated: true},
+		{name: "shorter than announced", size: 100, contentLength: 3000, want: 100, read: 100},
+		{name: "longer than announced", size: 5000, contentLength: 3000, want: 5000, read: 5000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := bytes.Repeat([]byte("0123456789"), tt.size/10+1)[:tt.size]
+			resp := &http.Response{Body: io.NopCloser(bytes.NewReader(data)), ContentLength: tt.contentLength}
+
+			body, read, truncated, err := readBody(resp)
+			require.NoError(t, err)
+			assert.Equal(t, data[:tt.want], body)
+			assert.Equal(t, tt.read, read)
+			assert.Equal(t, tt.truncated, truncated)
+		})
+	}
+}
+
+// BenchmarkReadBody reads bodies of known and unknown length, below and
+// above the pooled size, as readBody gets them from the transport
+func BenchmarkReadBody(b *testing.B) {
+	for _, bc := range []struct {
+		name  string
+		size  int
+		known bool
+	}{
+		{"64KiB", 64 << 10, true},
+		{"64KiB_unknown_length", 64 << 10, false},
+		{"4MiB", 4 << 20, true},
+		{"4MiB_unknown_length", 4 << 20, false},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			data := bytes.Repeat([]byte("a"), bc.size)
+			length := int64(-1)
+			if bc.known {
+				length = int64(bc.size)
+			}
+
+			b.ReportAllocs()
+			b.SetBytes(int64(bc.size))
+			for i := 0; i < b.N; i++ {
+				resp := &http.Response{Body: io.NopCloser(bytes.NewReader(data)), ContentLength: length}
+				body, _, _, err := readBody(resp)
+				if err != nil || len(body) != bc.size {
+					b.Fatalf("read %d bytes: %v", len(body), err)
+				}
+			}
+		})
+	}
+}
+
 // Table-driven tests for different HTTP status codes
 func TestClientGetStatusCodes(t *testing.T) {
 	tests := []struct {
@@ -442,17 +621,120 @@ func TestClientTimeoutConfiguration(t *testing.T) {
 	client := New(timeout, mockLogger)
 
 	assert.Equal(t, timeout, client.timeout)
-	assert.Equal(t, timeout, client.client.Timeout)
+	assert.Zero(t, client.client.Timeout)
 
 	// Verify transport configuration
-	transport, ok := client.client.Transport.(*http.Transport)
-	require.True(t, ok)
+	transport := client.transport
 	assert.NotNil(t, transport.DialContext)
 	assert.Equal(t, 5*time.Second, transport.TLSHandshakeTimeout)
+	assert.Equal(t, timeout, transport.ResponseHeaderTimeout)
 	assert.Equal(t, 100, transport.MaxIdleConns)
 	assert.Equal(t, 70, transport.MaxIdleConnsPerHost)
 }
 
+// stallingServer answers after holding the response back at phase,
+// "headers" or "body", for stall or until the client goes away
+func stallingServer(t *testing.T, phase string, stall time.Duration) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wait := func() {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(stall):
+			}
+		}
+		if phase == "headers" {
+			wait()
+		}
+		w.Header().Set("Content-Length", "10")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("hello"))
+		w.(http.Flusher).Flush()
+		if phase == "body" {
+			wait()
+		}
+		w.Write([]byte("world"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClientGet_StallTimeouts(t *testing.T) {
+	tests := []struct {
+		name          string
+		phase         string
+		headerTimeout time.Duration
+		fixedTimeout  time.Duration
+		deadline      time.Duration
+		wantErr       string
+	}{
+		{
+			name:          "context deadline before headers",
+			phase:         "headers",
+			headerTimeout: 5 * time.Second,
+			deadline:      50 * time.Millisecond,
+			wantErr:       "request failed",
+		},
+		{
+			name:          "context deadline during body",
+			phase:         "body",
+			headerTimeout: 5 * time.Second,
+			deadline:      50 * time.Millisecond,
+			wantErr:       "failed to read response",
+		},
+		{
+			name:          "header timeout without a deadline",
+			phase:         "headers",
+			headerTimeout: 50 * time.Millisecond,
+			wantErr:       "timeout awaiting response headers",
+		},
+		{
+			name:          "header timeout leaves the body alone",
+			phase:         "body",
+			headerTimeout: 50 * time.Millisecond,
+		},
+		{
+			name:          "fixed timeout during body",
+			phase:         "body",
+			headerTimeout: 5 * time.Second,
+			fixedTimeout:  50 * time.Millisecond,
+			wantErr:       "failed to read response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockLogger := mocks.NewMockLogger(ctrl)
+			mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+			mockLogger.EXPECT().Error(gomock.Any(), gomock.Any()).AnyTimes()
+
+			server := stallingServer(t, tt.phase, 200*time.Millisecond)
+			client := New(tt.headerTimeout, mockLogger)
+			client.SetFixedTimeout(tt.fixedTimeout)
+
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			response, err := client.Get(ctx, server.URL)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				assert.Equal(t, "helloworld", string(response.Body))
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			var netErr net.Error
+			assert.True(t, errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout(),
+				"a timeout in either phase classifies as one: %v", err)
+		})
+	}
+}
+
 // Test with gzipped response
 func TestClientGetGzippedResponse(t *testing.T) {
 	ctrl := gomock.NewController(t)
@@ -483,6 +765,290 @@ func TestClientGetGzippedResponse(t *testing.T) {
 	assert.Equal(t, expectedContent, string(response.Body))
 }
 
+func TestClientGet_FinalURLFollowsRedirects(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/new" {
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+			return
+		}
+		w.Write([]byte("moved here"))
+	}))
+	defer server.Close()
+
+	client := New(30*time.Second, mockLogger)
+
+	response, err := client.Get(context.Background(), server.URL+"/old")
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/new", response.FinalURL)
+}
+
+func TestClientGet_CountsRedirectHops(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Error(gomock.Any(), gomock.Any()).AnyTimes()
+
+	// /hop/N redirects to /hop/N-1 and /hop/0 answers; /loop/a and /loop/b
+	// redirect to each other
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hop/0":
+			w.Write([]byte("arrived"))
+		case "/hop/1":
+			http.Redirect(w, r, "/hop/0", http.StatusMovedPermanently)
+		case "/hop/2":
+			http.Redirect(w, r, "/hop/1", http.StatusFound)
+		case "/hop/3":
+			http.Redirect(w, r, "/hop/2", http.StatusFound)
+		case "/loop/a":
+			http.Redirect(w, r, "/loop/b", http.StatusFound)
+		case "/loop/b":
+			http.Redirect(w, r, "/loop/a", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := New(30*time.Second, mockLogger)
+
+	for path, hops := range map[string]int{"/hop/0": 0, "/hop/1": 1, "/hop/3": 3} {
+		response, err := client.Get(context.Background(), server.URL+path)
+		require.NoError(t, err, path)
+		assert.Equal(t, hops, response.Redirects, path)
+		assert.Equal(t, server.URL+"/hop/0", response.FinalURL, path)
+	}
+
+	_, err := client.Get(context.Background(), server.URL+"/loop/a")
+	require.ErrorIs(t, err, ErrRedirectLoop)
+}
+
+func TestClientGet_WithMaxRedirects(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Error(gomock.Any(), gomock.Any()).AnyTimes()
+
+	// /hop/N redirects to /hop/N-1 and /hop/0 answers
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+		if err != nil || n == 0 {
+			w.Write([]byte("arrived"))
+			return
+		}
+		http.Redirect(w, r, "/hop/"+strconv.Itoa(n-1), http.StatusFound)
+	}))
+	defer server.Close()
+
+	client := New(30*time.Second, mockLogger)
+	ctx := WithMaxRedirects(context.Background(), 2)
+
+	response, err := client.Get(ctx, server.URL+"/hop/2")
+	require.NoError(t, err)
+	assert.Equal(t, 2, response.Redirects)
+
+	_, err = client.Get(ctx, server.URL+"/hop/3")
+	assert.ErrorContains(t, err, "stopped after 2 redirects")
+
+	// The default limit is a ceiling
+	_, err = client.Get(WithMaxRedirects(context.Background(), 50), server.URL+"/hop/10")
+	assert.ErrorContains(t, err, "stopped after 10 redirects")
+}
+
+func TestClientGet_BudgetStopsRedirects(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Error(gomock.Any(), gomock.Any()).AnyTimes()
+
+	// Every hop redirects to the next one, forever
+	var hits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		http.Redirect(w, r, "/hop/"+strconv.FormatInt(n, 10), http.StatusFound)
+	}))
+	defer server.Close()
+
+	client := New(30*time.Second, mockLogger)
+	b := budget.New(4, 1<<20)
+	ctx := budget.WithBudget(context.Background(), b)
+
+	_, err := client.Get(ctx, server.URL)
+	require.ErrorIs(t, err, budget.ErrExhausted)
+	assert.Equal(t, int64(4), hits.Load())
+
+	// Nothing is sent once the budget is spent
+	_, err = client.Get(ctx, server.URL)
+	require.ErrorIs(t, err, budget.ErrExhausted)
+	_, err = client.Head(ctx, server.URL)
+	require.ErrorIs(t, err, budget.ErrExhausted)
+	assert.Equal(t, int64(4), hits.Load())
+
+	usage := b.Usage()
+	assert.Equal(t, int64(4), usage.Requests)
+	assert.True(t, usage.Exhausted)
+}
+
+func TestClientGet_BudgetCountsBytes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+
+	body := strings.Repeat("x", 600)
+	var hits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client := New(30*time.Second, mockLogger)
+	b := budget.New(100, 1000)
+	ctx := budget.WithBudget(context.Background(), b)
+
+	for range 2 {
+		_, err := client.Get(ctx, server.URL)
+		require.NoError(t, err)
+	}
+
+	// The byte allowance is spent, so the third fetch is refused
+	_, err := client.Get(ctx, server.URL)
+	require.ErrorIs(t, err, budget.ErrExhausted)
+	assert.Equal(t, int64(2), hits.Load())
+	assert.Equal(t, int64(1200), b.Usage().Bytes)
+}
+
+func TestClientGet_MeterCountsBytesRead(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Warn(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Error(gomock.Any(), gomock.Any()).AnyTimes()
+
+	page := strings.Repeat("<p>analysis</p>", 200)
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write([]byte(page))
+	gz.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 600)))
+	})
+	mux.HandleFunc("/gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(gzipped.Bytes())
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("A", 11*1024*1024)))
+	})
+	mux.HandleFunc("/cut", func(w http.ResponseWriter, r *http.Request) {
+		// The connection closes 300 bytes into a 1000-byte body
+		w.Header().Set("Content-Length", "1000")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(strings.Repeat("y", 300)))
+		w.(http.Flusher).Flush()
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		path    string
+		want    int64
+		wantErr bool
+	}{
+		{path: "/plain", want: 600},
+		// Compressed bodies count as sent, not as decompressed
+		{path: "/gzip", want: int64(gzipped.Len())},
+		// A truncated body counts what was read: the limit and the byte
+		// that showed it was passed
+		{path: "/large", want: 10*1024*1024 + 1},
+		{path: "/cut", want: 300, wantErr: true},
+	}
+
+	client := New(30*time.Second, mockLogger)
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			meter := &traffic.Meter{}
+			ctx := traffic.WithMeter(context.Background(), meter)
+
+			_, err := client.Get(ctx, server.URL+tt.path)
+
+			assert.Equal(t, tt.wantErr, err != nil, "error: %v", err)
+			assert.Equal(t, tt.want, meter.Bytes())
+		})
+	}
+}
+
+func TestClient_RecordsTrace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Error(gomock.Any(), gomock.Any()).AnyTimes()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	defer server.Close()
+
+	client := New(5*time.Second, mockLogger)
+	collector := trace.New(10)
+	ctx := trace.WithCollector(context.Background(), collector)
+
+	_, err := client.Get(ctx, server.URL+"/page?token=secret")
+	require.NoError(t, err)
+	_, err = client.Head(ctx, server.URL+"/missing")
+	require.NoError(t, err)
+	_, err = client.Get(ctx, closed.URL)
+	require.Error(t, err)
+
+	// Requests made without a collector are not recorded anywhere
+	_, err = client.Get(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	requests := collector.Report().Requests
+	require.Len(t, requests, 3)
+
+	assert.Equal(t, http.MethodGet, requests[0].Method)
+	assert.Equal(t, server.URL+"/page?token=[REDACTED]", requests[0].URL)
+	assert.Equal(t, http.StatusOK, requests[0].StatusCode)
+	assert.Equal(t, int64(5), requests[0].Bytes)
+	assert.Positive(t, requests[0].DurationMs)
+	assert.Empty(t, requests[0].Error)
+
+	assert.Equal(t, models.OutboundRequest{Method: http.MethodHead, URL: server.URL + "/missing", StatusCode: http.StatusNotFound, DurationMs: requests[1].DurationMs}, requests[1])
+
+	assert.Equal(t, closed.URL, requests[2].URL)
+	assert.Zero(t, requests[2].StatusCode)
+	assert.Contains(t, requests[2].Error, "request failed")
+}
+
 // Test interface compliance
 func TestInterfaceCompliance(t *testing.T) {
 	ctrl := gomock.NewController(t)
@@ -493,5 +1059,6 @@ func TestInterfaceCompliance(t *testing.T) {
 
 	// Verify that Client implements HTTPClient interface
 	var _ interfaces.HTTPClient = client
+	var _ interfaces.ConditionalHTTPClient = client
 	assert.NotNil(t, client)
 }
@@ -0,0 +1,279 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// fallbackDelay is how long a dial waits on the first address family before
+// racing the other one, as in RFC 8305 Happy Eyeballs; it is the net.Dialer
+// default
+const fallbackDelay = 300 * time.Millisecond
+
+// dialer connects to the addresses its resolver returns for a host, limited
+// to one family when family is set. With both families it tries the family
+// of the first address and, after fallbackDelay or once that family has
+// failed, the other one alongside; the first connection wins. Connections
+// from a source address are limited to its family.
+type dialer struct {
+	net           *net.Dialer
+	resolver      Resolver
+	family        string
+	source        net.IP
+	fallbackDelay time.Duration
+}
+
+func newDialer() *dialer {
+	return &dialer{
+		net: &net.Dialer{
+			Timeout:   2 * time.Second,  // TCP connect timeout, per address
+			KeepAlive: 30 * time.Second, // keep-alive
+		},
+		resolver:      net.DefaultResolver,
+		fallbackDelay: fallbackDelay,
+	}
+}
+
+// DialContext is the Transport's DialContext. A host overridden in ctx is
+// dialed at its override without being looked up.
+func (d *dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []net.IPAddr
+	if ip, ok := hostOverride(ctx, host); ok {
+		addrs, err = overrideAddrs(host, ip)
+	} else {
+		addrs, err = d.resolver.LookupIPAddr(ctx, host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	primaries, fallbacks := d.partition(network, addrs)
+	if len(primaries) == 0 {
+		family := d.family
+		if d.source != nil {
+			family = addressFamily(d.source)
+		}
+		if family == "" {
+			family = "suitable"
+		}
+		return nil, &net.DNSError{Err: fmt.Sprintf("no %s address found", family), Name: host, IsNotFound: true}
+	}
+	if len(fallbacks) == 0 {
+		return d.serial(ctx, network, port, primaries)
+	}
+	return d.race(ctx, network, port, primaries, fallbacks)
+}
+
+// partition keeps the addresses allowed by network, d.family and d.source,
+// split into those of the first address's family and the others
+func (d *dialer) partition(network string, addrs []net.IPAddr) (primaries, fallbacks []net.IPAddr) {
+	for _, addr := range addrs {
+		family := addressFamily(addr.IP)
+		if d.family != "" && family != d.family ||
+			d.source != nil && family != addressFamily(d.source) ||
+			network == "tcp4" && family != models.AddressFamilyIPv4 ||
+			network == "tcp6" && family != models.AddressFamilyIPv6 {
+			continue
+		}
+		if len(primaries) == 0 || addressFamily(primaries[0].IP) == family {
+			primaries = append(primaries, addr)
+		} else {
+			fallbacks = append(fallbacks, addr)
+		}
+	}
+	return primaries, fallbacks
+}
+
+// race dials primaries and fallbacks as two concurrent series, the
+// fallbacks starting late
+func (d *dialer) race(ctx context.Context, network, port string, primaries, fallbacks []net.IPAddr) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan result, 2)
+	dial := func(addrs []net.IPAddr) {
+		go func() {
+			conn, err := d.serial(ctx, network, port, addrs)
+			results <- result{conn, err}
+		}()
+	}
+
+	dial(primaries)
+	pending, fallbackStarted := 1, false
+	startFallback := func() {
+		if !fallbackStarted {
+			fallbackStarted = true
+			pending++
+			dial(fallbacks)
+		}
+	}
+
+	timer := time.NewTimer(d.fallbackDelay)
+	defer timer.Stop()
+
+	var errs []error
+	for {
+		select {
+		case <-timer.C:
+			startFallback()
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				if pending > 0 {
+					// The loser may connect before it sees the cancellation
+					go func() {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}()
+				}
+				return res.conn, nil
+			}
+			errs = append(errs, res.err)
+			startFallback()
+			if pending == 0 {
+				return nil, errors.Join(errs...)
+			}
+		}
+	}
+}
+
+// serial dials addrs in turn until one connects
+func (d *dialer) serial(ctx context.Context, network, port string, addrs []net.IPAddr) (net.Conn, error) {
+	var errs []error
+	for _, addr := range addrs {
+		conn, err := d.net.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// setSource makes connections originate from ip, or from the address the
+// system picks when ip is nil
+func (d *dialer) setSource(ip net.IP) {
+	d.source = ip
+	d.net.LocalAddr = nil
+	if ip != nil {
+		d.net.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+}
+
+// ParseSourceIP parses s as an outbound source address, which must be an
+// address of one of the host's interfaces: one a socket can bind to. An
+// empty s is no source address, nil.
+func ParseSourceIP(s string) (net.IP, error) {
+	if s == "" {
+		return nil, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("%q is not an IP address", s)
+	}
+	listener, err := net.ListenPacket("udp", net.JoinHostPort(ip.String(), "0"))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not an address of this host: %w", ip, err)
+	}
+	listener.Close()
+	return ip, nil
+}
+
+func addressFamily(ip net.IP) string {
+	if ip.To4() != nil {
+		return models.AddressFamilyIPv4
+	}
+	return models.AddressFamilyIPv6
+}
+
+// AttemptError is a failed request with the connections it attempted
+type AttemptError struct {
+	Attempts []models.DialAttempt
+	Err      error
+}
+
+func (e *AttemptError) Error() string { return e.Err.Error() }
+
+func (e *AttemptError) Unwrap() error { return e.Err }
+
+// attemptLog records the connections of one request from its trace; the
+// dials of a race report concurrently
+type attemptLog struct {
+	mu       sync.Mutex
+	attempts []models.DialAttempt
+}
+
+func (l *attemptLog) trace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		ConnectStart: func(network, addr string) {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.attempts = append(l.attempts, models.DialAttempt{Address: addr, Family: hostFamily(addr)})
+		},
+		ConnectDone: func(network, addr string, err error) {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			for i := len(l.attempts) - 1; i >= 0; i-- {
+				attempt := &l.attempts[i]
+				if attempt.Address != addr || attempt.Connected || attempt.Error != "" {
+					continue
+				}
+				if err != nil {
+					attempt.Error = err.Error()
+				} else {
+					attempt.Connected = true
+				}
+				return
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if !info.Reused {
+				return
+			}
+			addr := info.Conn.RemoteAddr().String()
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.attempts = append(l.attempts, models.DialAttempt{Address: addr, Family: hostFamily(addr), Connected: true})
+		},
+	})
+}
+
+// wrap attaches the recorded attempts to err, if there are any
+func (l *attemptLog) wrap(err error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.attempts) == 0 {
+		return err
+	}
+	return &AttemptError{Attempts: append([]models.DialAttempt(nil), l.attempts...), Err: err}
+}
+
+// hostFamily is the address family of an "ip:port" address
+func hostFamily(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	host, _, _ = strings.Cut(host, "%") // IPv6 zone
+	return addressFamily(net.ParseIP(host))
+}
@@ -0,0 +1,205 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/mocks"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unroutableIPv6 is in the discard-only prefix 100::/64, so connecting to it
+// either fails at once or hangs until the dial timeout
+const unroutableIPv6 = "100::1"
+
+func newDualStackClient(t *testing.T, addrs ...string) *Client {
+	ctrl := gomock.NewController(t)
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Error(gomock.Any(), gomock.Any()).AnyTimes()
+
+	resolver := &fakeResolver{}
+	for _, addr := range addrs {
+		resolver.addrs = append(resolver.addrs, net.IPAddr{IP: net.ParseIP(addr)})
+	}
+	client := New(5*time.Second, mockLogger)
+	client.SetResolver(resolver)
+	client.dialer.net.Timeout = time.Second
+	return client
+}
+
+func TestClient_HappyEyeballs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	client := newDualStackClient(t, unroutableIPv6, "127.0.0.1")
+
+	start := time.Now()
+	resp, err := client.Get(context.Background(), "http://dual.example:"+port+"/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Less(t, time.Since(start), client.dialer.net.Timeout, "IPv4 is tried without waiting out the IPv6 attempt")
+}
+
+func TestClient_ReportsDialAttempts(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+
+	client := newDualStackClient(t, unroutableIPv6, "127.0.0.1")
+
+	_, err = client.Get(context.Background(), "http://dual.example:"+port+"/")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "request failed")
+
+	var attemptErr *AttemptError
+	require.True(t, errors.As(err, &attemptErr))
+	require.Len(t, attemptErr.Attempts, 2)
+	families := map[string]models.DialAttempt{}
+	for _, attempt := range attemptErr.Attempts {
+		families[attempt.Family] = attempt
+		assert.False(t, attempt.Connected)
+	}
+	assert.Equal(t, "["+unroutableIPv6+"]:"+port, families[models.AddressFamilyIPv6].Address)
+	assert.Equal(t, "127.0.0.1:"+port, families[models.AddressFamilyIPv4].Address)
+	assert.Contains(t, families[models.AddressFamilyIPv4].Error, "refused")
+}
+
+func TestClient_SetIPFamily(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	client := newDualStackClient(t, unroutableIPv6, "127.0.0.1")
+	client.SetIPFamily(models.AddressFamilyIPv4)
+	client.dialer.fallbackDelay = time.Hour
+
+	_, err = client.Get(context.Background(), "http://dual.example:"+port+"/")
+	require.NoError(t, err, "IPv4 is dialed first, without the IPv6 address")
+
+	client.SetIPFamily(models.AddressFamilyIPv6)
+	client.dialer.resolver = &fakeResolver{addrs: []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}}
+	_, err = client.Get(context.Background(), "http://v4only.example:"+port+"/")
+	var dnsErr *net.DNSError
+	require.ErrorAs(t, err, &dnsErr)
+	assert.Equal(t, "no ipv6 address found", dnsErr.Err)
+}
+
+func TestDialer_Partition(t *testing.T) {
+	addrs := []net.IPAddr{
+		{IP: net.ParseIP("2001:db8::1")},
+		{IP: net.ParseIP("192.0.2.1")},
+		{IP: net.ParseIP("2001:db8::2")},
+		{IP: net.ParseIP("192.0.2.2")},
+	}
+
+	tests := []struct {
+		name      string
+		family    string
+		network   string
+		primaries []string
+		fallbacks []string
+	}{
+		{name: "dual stack", network: "tcp", primaries: []string{"2001:db8::1", "2001:db8::2"}, fallbacks: []string{"192.0.2.1", "192.0.2.2"}},
+		{name: "IPv4 only", family: models.AddressFamilyIPv4, network: "tcp", primaries: []string{"192.0.2.1", "192.0.2.2"}},
+		{name: "IPv6 only", family: models.AddressFamilyIPv6, network: "tcp", primaries: []string{"2001:db8::1", "2001:db8::2"}},
+		{name: "tcp4 network", network: "tcp4", primaries: []string{"192.0.2.1", "192.0.2.2"}},
+		{name: "conflicting family and network", family: models.AddressFamilyIPv6, network: "tcp4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &dialer{family: tt.family}
+			primaries, fallbacks := d.partition(tt.network, addrs)
+			assert.Equal(t, tt.primaries, ips(primaries))
+			assert.Equal(t, tt.fallbacks, ips(fallbacks))
+		})
+	}
+}
+
+func TestClient_SetSourceIP(t *testing.T) {
+	if _, err := ParseSourceIP("127.0.0.2"); err != nil {
+		t.Skip("127.0.0.2 is not a local address on this host:", err)
+	}
+
+	var remotes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		remotes = append(remotes, r.Method+" "+r.URL.Path+" from "+host)
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/final", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		source net.IP
+		host   string
+	}{
+		{name: "system default", host: "127.0.0.1"},
+		{name: "bound source", source: net.ParseIP("127.0.0.2"), host: "127.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remotes = nil
+			client := newDualStackClient(t, "127.0.0.1")
+			client.SetSourceIP(tt.source)
+
+			_, err := client.Get(context.Background(), "http://local.example:"+port+"/moved")
+			require.NoError(t, err)
+			_, err = client.Head(context.Background(), "http://local.example:"+port+"/page")
+			require.NoError(t, err)
+
+			assert.Equal(t, []string{
+				"GET /moved from " + tt.host,
+				"GET /final from " + tt.host,
+				"HEAD /page from " + tt.host,
+			}, remotes)
+		})
+	}
+}
+
+func TestClient_SetSourceIP_OtherFamily(t *testing.T) {
+	client := newDualStackClient(t, "127.0.0.1")
+	client.SetSourceIP(net.ParseIP("::1"))
+
+	_, err := client.Get(context.Background(), "http://v4only.example/")
+	var dnsErr *net.DNSError
+	require.ErrorAs(t, err, &dnsErr)
+	assert.Equal(t, "no ipv6 address found", dnsErr.Err)
+}
+
+func TestParseSourceIP(t *testing.T) {
+	ip, err := ParseSourceIP("127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", ip.String())
+
+	ip, err = ParseSourceIP("")
+	require.NoError(t, err)
+	assert.Nil(t, ip)
+
+	_, err = ParseSourceIP("127.0.0")
+	assert.ErrorContains(t, err, "not an IP address")
+
+	// TEST-NET-1 is never assigned to a host
+	_, err = ParseSourceIP("192.0.2.1")
+	assert.ErrorContains(t, err, "192.0.2.1 is not an address of this host")
+}
@@ -0,0 +1,278 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/sync/singleflight"
+)
+
+// Resolver looks up the addresses of a host; *net.Resolver is one
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// DoH answers are cached for their TTL within these bounds; NXDOMAIN is
+// cached for dohNegativeTTL
+const (
+	dohMinTTL       = 5 * time.Second
+	dohMaxTTL       = 5 * time.Minute
+	dohNegativeTTL  = 30 * time.Second
+	dohMaxEntries   = 10000
+	dohMaxBodyBytes = 64 << 10
+)
+
+const dnsMessageType = "application/dns-message"
+
+// errNoAnswer is returned for a name with no A or AAAA records
+var errNoAnswer = errors.New("no such host")
+
+// DoHResolver resolves host names with DNS over HTTPS (RFC 8484), for
+// environments whose local DNS cannot be relied on. Answers are cached for
+// their TTL. When the DoH server fails, the lookup falls back to the system
+// resolver; a name the server reports as nonexistent does not. Single-label
+// names such as localhost or container service names are never sent to the
+// DoH server.
+type DoHResolver struct {
+	endpoint string
+	client   *http.Client
+	fallback Resolver
+	logger   interfaces.Logger
+	now      func() time.Time
+
+	lookups singleflight.Group
+	mu      sync.Mutex
+	cache   map[string]dohEntry
+}
+
+type dohEntry struct {
+	addrs   []net.IPAddr
+	err     error
+	expires time.Time
+}
+
+// NewDoHResolver creates a resolver that queries endpoint, e.g.
+// https://cloudflare-dns.com/dns-query, giving up on it after timeout
+func NewDoHResolver(endpoint string, timeout time.Duration, logger interfaces.Logger) *DoHResolver {
+	return &DoHResolver{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: timeout},
+		fallback: net.DefaultResolver,
+		logger:   logger,
+		now:      time.Now,
+		cache:    make(map[string]dohEntry),
+	}
+}
+
+// LookupIPAddr returns the IPv4 and then the IPv6 addresses of host
+func (r *DoHResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	name := strings.TrimSuffix(strings.ToLower(host), ".")
+	if ip := net.ParseIP(name); ip != nil {
+		return []net.IPAddr{{IP: ip}}, nil
+	}
+	if !strings.Contains(name, ".") {
+		return r.fallback.LookupIPAddr(ctx, host)
+	}
+	if addrs, err, ok := r.cached(name); ok {
+		return addrs, err
+	}
+
+	// Concurrent lookups of one name share a query, run apart from the
+	// caller's context so that neither the first caller giving up nor its
+	// request trace reaches it; the client timeout bounds it
+	result, err, _ := r.lookups.Do(name, func() (any, error) {
+		return r.lookup(context.Background(), name)
+	})
+	if err == nil {
+		return result.([]net.IPAddr), nil
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	r.logger.Warn("DoH lookup failed, falling back to the system resolver", "host", name, "error", err)
+	return r.fallback.LookupIPAddr(ctx, host)
+}
+
+// lookup queries A and AAAA records together and caches the answer
+func (r *DoHResolver) lookup(ctx context.Context, name string) ([]net.IPAddr, error) {
+	type answer struct {
+		addrs []net.IPAddr
+		ttl   time.Duration
+		err   error
+	}
+	var answers [2]answer
+	var wg sync.WaitGroup
+	for i, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			answers[i].addrs, answers[i].ttl, answers[i].err = r.exchange(ctx, name, qtype)
+		}()
+	}
+	wg.Wait()
+
+	var addrs []net.IPAddr
+	ttl := dohMaxTTL
+	var errs []error
+	for _, a := range answers {
+		if a.err != nil {
+			errs = append(errs, a.err)
+			continue
+		}
+		addrs = append(addrs, a.addrs...)
+		if len(a.addrs) > 0 {
+			ttl = min(ttl, a.ttl)
+		}
+	}
+
+	switch {
+	case len(addrs) > 0:
+		// One address family is enough to connect
+		r.store(name, dohEntry{addrs: addrs, expires: r.now().Add(max(ttl, dohMinTTL))})
+		return addrs, nil
+	case allNotFound(errs):
+		err := &net.DNSError{Err: errNoAnswer.Error(), Name: name, Server: r.endpoint, IsNotFound: true}
+		r.store(name, dohEntry{err: err, expires: r.now().Add(dohNegativeTTL)})
+		return nil, err
+	default:
+		return nil, errors.Join(errs...)
+	}
+}
+
+// allNotFound reports whether every query found the name has no addresses
+func allNotFound(errs []error) bool {
+	for _, err := range errs {
+		if !errors.Is(err, errNoAnswer) {
+			return false
+		}
+	}
+	return true
+}
+
+// exchange sends one query and returns the addresses of its answer and
+// their smallest TTL. A nonexistent name is reported as errNoAnswer.
+func (r *DoHResolver) exchange(ctx context.Context, name string, qtype dnsmessage.Type) ([]net.IPAddr, time.Duration, error) {
+	query, err := buildQuery(name, qtype)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(query))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create DoH request: %w", err)
+	}
+	req.Header.Set("Content-Type", dnsMessageType)
+	req.Header.Set("Accept", dnsMessageType)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("DoH request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("DoH server returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dohMaxBodyBytes))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read DoH response: %w", err)
+	}
+
+	return parseAnswer(body, qtype)
+}
+
+func buildQuery(name string, qtype dnsmessage.Type) ([]byte, error) {
+	qname, err := dnsmessage.NewName(name + ".")
+	if err != nil {
+		return nil, fmt.Errorf("invalid host name %q: %w", name, err)
+	}
+	// RFC 8484 asks for ID 0, so that answers are cacheable
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{RecursionDesired: true})
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(dnsmessage.Question{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	return builder.Finish()
+}
+
+// parseAnswer reads the records of type qtype from a DoH answer, following
+// any CNAME chain the server resolved along the way
+func parseAnswer(body []byte, qtype dnsmessage.Type) ([]net.IPAddr, time.Duration, error) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(body); err != nil {
+		return nil, 0, fmt.Errorf("malformed DoH response: %w", err)
+	}
+	switch {
+	case !msg.Header.Response:
+		return nil, 0, errors.New("malformed DoH response: not a response")
+	case msg.Header.RCode == dnsmessage.RCodeNameError:
+		return nil, 0, errNoAnswer
+	case msg.Header.RCode != dnsmessage.RCodeSuccess:
+		return nil, 0, fmt.Errorf("DoH server answered %s", msg.Header.RCode)
+	}
+
+	var addrs []net.IPAddr
+	ttl := dohMaxTTL
+	for _, answer := range msg.Answers {
+		if answer.Header.Type != qtype {
+			continue
+		}
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			addrs = append(addrs, net.IPAddr{IP: net.IP(body.A[:])})
+		case *dnsmessage.AAAAResource:
+			addrs = append(addrs, net.IPAddr{IP: net.IP(body.AAAA[:])})
+		default:
+			continue
+		}
+		ttl = min(ttl, time.Duration(answer.Header.TTL)*time.Second)
+	}
+	if len(addrs) == 0 {
+		// The name exists without records of this type
+		return nil, 0, errNoAnswer
+	}
+	return addrs, ttl, nil
+}
+
+func (r *DoHResolver) cached(name string) ([]net.IPAddr, error, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.cache[name]
+	if !ok || !r.now().Before(entry.expires) {
+		return nil, nil, false
+	}
+	return entry.addrs, entry.err, true
+}
+
+func (r *DoHResolver) store(name string, entry dohEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.cache) >= dohMaxEntries {
+		now := r.now()
+		for key, old := range r.cache {
+			if !now.Before(old.expires) {
+				delete(r.cache, key)
+			}
+		}
+		if len(r.cache) >= dohMaxEntries {
+			clear(r.cache)
+		}
+	}
+	r.cache[name] = entry
+}
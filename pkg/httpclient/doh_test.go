@@ -0,0 +1,288 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dohStub is a DNS-over-HTTPS server answering from records, with NXDOMAIN
+// for any other name
+type dohStub struct {
+	records map[string][]string
+	ttl     uint32
+	queries atomic.Int32
+}
+
+func (s *dohStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.queries.Add(1)
+	body, _ := io.ReadAll(r.Body)
+	var query dnsmessage.Message
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != dnsMessageType || query.Unpack(body) != nil || len(query.Questions) != 1 {
+		http.Error(w, "bad query", http.StatusBadRequest)
+		return
+	}
+	question := query.Questions[0]
+
+	answer := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: query.ID, Response: true, RecursionAvailable: true},
+		Questions: query.Questions,
+	}
+	name := strings.TrimSuffix(question.Name.String(), ".")
+	records, ok := s.records[name]
+	if !ok {
+		answer.Header.RCode = dnsmessage.RCodeNameError
+	}
+	for _, record := range records {
+		addr := netip.MustParseAddr(record)
+		header := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: s.ttl}
+		switch {
+		case addr.Is4() && question.Type == dnsmessage.TypeA:
+			header.Type = dnsmessage.TypeA
+			answer.Answers = append(answer.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.AResource{A: addr.As4()}})
+		case addr.Is6() && question.Type == dnsmessage.TypeAAAA:
+			header.Type = dnsmessage.TypeAAAA
+			answer.Answers = append(answer.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.AAAAResource{AAAA: addr.As16()}})
+		}
+	}
+
+	packed, err := answer.Pack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", dnsMessageType)
+	w.Write(packed)
+}
+
+// fakeResolver stands in for the system resolver
+type fakeResolver struct {
+	addrs []net.IPAddr
+	calls atomic.Int32
+}
+
+func (f *fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	f.calls.Add(1)
+	return f.addrs, nil
+}
+
+func newTestDoHResolver(t *testing.T, endpoint string) (*DoHResolver, *fakeResolver) {
+	ctrl := gomock.NewController(t)
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Warn(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+
+	fallback := &fakeResolver{addrs: []net.IPAddr{{IP: net.ParseIP("192.0.2.99")}}}
+	resolver := NewDoHResolver(endpoint, time.Second, mockLogger)
+	resolver.fallback = fallback
+	return resolver, fallback
+}
+
+func ips(addrs []net.IPAddr) []string {
+	var out []string
+	for _, addr := range addrs {
+		out = append(out, addr.IP.String())
+	}
+	return out
+}
+
+func TestDoHResolver_LookupIPAddr(t *testing.T) {
+	stub := &dohStub{
+		ttl: 60,
+		records: map[string][]string{
+			"dual.example":  {"192.0.2.1", "2001:db8::1"},
+			"v4.example":    {"192.0.2.2", "192.0.2.3"},
+			"v6.example":    {"2001:db8::2"},
+			"empty.example": nil,
+		},
+	}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		host     string
+		expected []string
+		notFound bool
+	}{
+		{name: "A and AAAA", host: "dual.example", expected: []string{"192.0.2.1", "2001:db8::1"}},
+		{name: "A only", host: "v4.example", expected: []string{"192.0.2.2", "192.0.2.3"}},
+		{name: "AAAA only", host: "v6.example", expected: []string{"2001:db8::2"}},
+		{name: "case and trailing dot", host: "Dual.Example.", expected: []string{"192.0.2.1", "2001:db8::1"}},
+		{name: "NXDOMAIN", host: "missing.example", notFound: true},
+		{name: "no addresses", host: "empty.example", notFound: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, fallback := newTestDoHResolver(t, server.URL)
+
+			addrs, err := resolver.LookupIPAddr(context.Background(), tt.host)
+			if tt.notFound {
+				var dnsErr *net.DNSError
+				require.ErrorAs(t, err, &dnsErr)
+				assert.True(t, dnsErr.IsNotFound)
+				assert.Zero(t, fallback.calls.Load(), "a nonexistent name is not retried with the system resolver")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ips(addrs))
+			assert.Zero(t, fallback.calls.Load())
+		})
+	}
+}
+
+func TestDoHResolver_CachesForTheTTL(t *testing.T) {
+	stub := &dohStub{ttl: 60, records: map[string][]string{"cached.example": {"192.0.2.1"}}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	resolver, _ := newTestDoHResolver(t, server.URL)
+	now := time.Now()
+	resolver.now = func() time.Time { return now }
+
+	lookup := func() {
+		t.Helper()
+		addrs, err := resolver.LookupIPAddr(context.Background(), "cached.example")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"192.0.2.1"}, ips(addrs))
+	}
+
+	lookup()
+	assert.Equal(t, int32(2), stub.queries.Load(), "one A and one AAAA query")
+
+	now = now.Add(59 * time.Second)
+	lookup()
+	assert.Equal(t, int32(2), stub.queries.Load(), "answered from the cache within the TTL")
+
+	now = now.Add(2 * time.Second)
+	lookup()
+	assert.Equal(t, int32(4), stub.queries.Load(), "queried again once the TTL has passed")
+
+	_, err := resolver.LookupIPAddr(context.Background(), "missing.example")
+	require.Error(t, err)
+	_, err = resolver.LookupIPAddr(context.Background(), "missing.example")
+	require.Error(t, err)
+	assert.Equal(t, int32(6), stub.queries.Load(), "NXDOMAIN is cached too")
+}
+
+func TestDoHResolver_FallsBackToTheSystemResolver(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	servfail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		answer := dnsmessage.Message{Header: dnsmessage.Header{Response: true, RCode: dnsmessage.RCodeServerFailure}}
+		packed, _ := answer.Pack()
+		w.Header().Set("Content-Type", dnsMessageType)
+		w.Write(packed)
+	}))
+	defer servfail.Close()
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	tests := []struct {
+		name     string
+		endpoint string
+	}{
+		{name: "error status", endpoint: failing.URL},
+		{name: "server failure", endpoint: servfail.URL},
+		{name: "unreachable", endpoint: unreachable.URL},
+		{name: "timeout", endpoint: slow.URL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, fallback := newTestDoHResolver(t, tt.endpoint)
+			resolver.client.Timeout = 100 * time.Millisecond
+
+			addrs, err := resolver.LookupIPAddr(context.Background(), "www.example.com")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"192.0.2.99"}, ips(addrs))
+			assert.Equal(t, int32(1), fallback.calls.Load())
+		})
+	}
+}
+
+func TestDoHResolver_BypassesTheServer(t *testing.T) {
+	stub := &dohStub{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	resolver, fallback := newTestDoHResolver(t, server.URL)
+
+	addrs, err := resolver.LookupIPAddr(context.Background(), "203.0.113.7")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"203.0.113.7"}, ips(addrs), "IP literals are not looked up")
+
+	addrs, err = resolver.LookupIPAddr(context.Background(), "link-checker")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"192.0.2.99"}, ips(addrs), "single-label names go to the system resolver")
+	assert.Equal(t, int32(1), fallback.calls.Load())
+
+	assert.Zero(t, stub.queries.Load())
+}
+
+func TestDoHResolver_CanceledLookup(t *testing.T) {
+	resolver, fallback := newTestDoHResolver(t, "http://127.0.0.1:1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := resolver.LookupIPAddr(ctx, "www.example.com")
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Zero(t, fallback.calls.Load())
+}
+
+func TestClient_SetResolver(t *testing.T) {
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("resolved over DoH"))
+	}))
+	defer page.Close()
+	_, port, err := net.SplitHostPort(page.Listener.Addr().String())
+	require.NoError(t, err)
+
+	stub := &dohStub{ttl: 60, records: map[string][]string{"page.example": {"127.0.0.1"}}}
+	dns := httptest.NewServer(stub)
+	defer dns.Close()
+
+	resolver, fallback := newTestDoHResolver(t, dns.URL)
+	ctrl := gomock.NewController(t)
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Error(gomock.Any(), gomock.Any()).AnyTimes()
+	client := New(5*time.Second, mockLogger)
+	client.SetResolver(resolver)
+
+	resp, err := client.Get(context.Background(), "http://page.example:"+port+"/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "resolved over DoH", string(resp.Body))
+	assert.Positive(t, stub.queries.Load())
+	assert.Zero(t, fallback.calls.Load())
+
+	_, err = client.Get(context.Background(), "http://missing.example:"+port+"/")
+	require.Error(t, err)
+}
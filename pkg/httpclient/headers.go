@@ -0,0 +1,36 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+)
+
+type headersKey struct{}
+
+// WithHeaders returns a context whose requests carry header, on top of the
+// client's own headers and replacing those of the same name, such as the
+// Accept-Language of one analysis
+func WithHeaders(ctx context.Context, header http.Header) context.Context {
+	merged := HeadersFromContext(ctx).Clone()
+	if merged == nil {
+		merged = make(http.Header, len(header))
+	}
+	for name, values := range header {
+		merged[http.CanonicalHeaderKey(name)] = values
+	}
+	return context.WithValue(ctx, headersKey{}, merged)
+}
+
+// HeadersFromContext returns the headers carried by ctx, or nil when it has
+// none; the result must not be modified
+func HeadersFromContext(ctx context.Context) http.Header {
+	header, _ := ctx.Value(headersKey{}).(http.Header)
+	return header
+}
+
+// setContextHeaders sets the headers ctx carries on req
+func setContextHeaders(ctx context.Context, req *http.Request) {
+	for name, values := range HeadersFromContext(ctx) {
+		req.Header[name] = values
+	}
+}
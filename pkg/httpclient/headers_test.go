@@ -0,0 +1,49 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHeaders_Merges(t *testing.T) {
+	ctx := WithHeaders(context.Background(), http.Header{"accept-language": {"fr"}, "X-One": {"1"}})
+	ctx = WithHeaders(ctx, http.Header{"Accept-Language": {"de"}})
+
+	header := HeadersFromContext(ctx)
+	assert.Equal(t, "de", header.Get("Accept-Language"))
+	assert.Equal(t, "1", header.Get("X-One"))
+	assert.Nil(t, HeadersFromContext(context.Background()))
+}
+
+func TestClient_SendsContextHeaders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+
+	// The page answers in the language asked for
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+	defer server.Close()
+
+	client := New(30*time.Second, mockLogger)
+
+	response, err := client.Get(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "en-US,en;q=0.9", string(response.Body), "the default")
+
+	ctx := WithHeaders(context.Background(), http.Header{"Accept-Language": {"de-DE,de;q=0.8"}})
+	response, err = client.Get(ctx, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "de-DE,de;q=0.8", string(response.Body))
+}
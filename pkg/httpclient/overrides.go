@@ -0,0 +1,79 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/idn"
+)
+
+type hostOverridesKey struct{}
+
+// WithHostOverrides returns a context whose requests connect to the IP
+// address overrides maps their host name to instead of the addresses the
+// host resolves to, like an /etc/hosts entry of one analysis, for a site
+// behind a DNS mapping that is not public. The Host header and the TLS
+// server name stay those of the URL. Connections to an overridden host are
+// never pooled, so no request without the override reuses one. Host names
+// are matched without regard to case, and in either form of an
+// internationalized domain name.
+func WithHostOverrides(ctx context.Context, overrides map[string]string) context.Context {
+	if len(overrides) == 0 {
+		return ctx
+	}
+	lowered := make(map[string]string, len(overrides))
+	for host, ip := range overrides {
+		lowered[idn.ASCIIHost(host)] = ip
+	}
+	return context.WithValue(ctx, hostOverridesKey{}, lowered)
+}
+
+// HostOverridesFromContext returns the host overrides carried by ctx, keyed
+// by lower-case host name in ASCII form, or nil when it has none; the result
+// must not be modified
+func HostOverridesFromContext(ctx context.Context) map[string]string {
+	overrides, _ := ctx.Value(hostOverridesKey{}).(map[string]string)
+	return overrides
+}
+
+// hostOverride returns the address ctx overrides host with, if any
+func hostOverride(ctx context.Context, host string) (string, bool) {
+	ip, ok := HostOverridesFromContext(ctx)[idn.ASCIIHost(host)]
+	return ip, ok
+}
+
+// overriddenAddr reports whether ctx overrides the host of addr, a
+// host:port
+func overriddenAddr(ctx context.Context, addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	_, ok := hostOverride(ctx, host)
+	return ok
+}
+
+// overrideAddrs is the address host is dialed at under the override ip
+func overrideAddrs(host, ip string) ([]net.IPAddr, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil, fmt.Errorf("host override of %s: %q is not an IP address", host, ip)
+	}
+	return []net.IPAddr{{IP: parsed}}, nil
+}
+
+// overrideRoutes sends the requests to hosts overridden in their context
+// through direct, which closes its connections after each request, and the
+// others through pooled
+type overrideRoutes struct {
+	pooled, direct http.RoundTripper
+}
+
+func (r *overrideRoutes) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, ok := hostOverride(req.Context(), req.URL.Hostname()); ok {
+		return r.direct.RoundTrip(req)
+	}
+	return r.pooled.RoundTrip(req)
+}
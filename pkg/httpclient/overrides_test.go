@@ -0,0 +1,113 @@
+package httpclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_HostOverrides(t *testing.T) {
+	for _, useTLS := range []bool{false, true} {
+		var conns atomic.Int32
+		var host, serverName atomic.Value
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host.Store(r.Host)
+			if r.TLS != nil {
+				serverName.Store(r.TLS.ServerName)
+			}
+			w.Write([]byte("ok"))
+		}))
+		server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				conns.Add(1)
+			}
+		}
+		if useTLS {
+			server.StartTLS()
+		} else {
+			server.Start()
+		}
+		defer server.Close()
+
+		// The test certificate is valid for example.com, which the resolver
+		// knows no address of
+		client := newPreconnectClient(t, server.Certificate())
+		resolver := &fakeResolver{}
+		client.SetResolver(resolver)
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+		target := u.Scheme + "://example.com:" + u.Port() + "/page"
+
+		_, err = client.Get(context.Background(), target)
+		require.Error(t, err)
+		assert.Equal(t, int32(1), resolver.calls.Load())
+
+		ctx := WithHostOverrides(context.Background(), map[string]string{"EXAMPLE.com": "127.0.0.1"})
+		resp, err := client.Get(ctx, target)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", string(resp.Body))
+		assert.Equal(t, "example.com:"+u.Port(), host.Load(), "the Host header names the URL's host")
+		if useTLS {
+			assert.Equal(t, "example.com", serverName.Load(), "the TLS server name is the URL's host")
+		}
+		assert.Equal(t, int32(1), resolver.calls.Load(), "an overridden host is not looked up")
+
+		// Connections to an overridden host are not kept for later requests
+		_, err = client.Head(ctx, target)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), conns.Load())
+		require.NoError(t, client.Preconnect(ctx, target))
+		assert.Equal(t, int32(2), conns.Load())
+		_, err = client.Get(context.Background(), target)
+		require.Error(t, err)
+	}
+}
+
+func TestClient_HostOverrideOfAnotherHost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	client := newDualStackClient(t, "127.0.0.1")
+	ctx := WithHostOverrides(context.Background(), map[string]string{"staging.example.com": "192.0.2.1"})
+	resp, err := client.Get(ctx, "http://www.example.com:"+port+"/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	ctx = WithHostOverrides(context.Background(), map[string]string{"www.example.com": "not an IP"})
+	_, err = client.Get(ctx, "http://www.example.com:"+port+"/")
+	assert.ErrorContains(t, err, `host override of www.example.com: "not an IP" is not an IP address`)
+}
+
+func TestClient_HostOverrideOfIDNHost(t *testing.T) {
+	var host atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host.Store(r.Host)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	// The resolver knows no address, so only the override reaches the server
+	client := newDualStackClient(t)
+	for _, key := range []string{"bücher.example", "XN--BCHER-KVA.example"} {
+		ctx := WithHostOverrides(context.Background(), map[string]string{key: "127.0.0.1"})
+		for _, target := range []string{"http://Bücher.example:" + port + "/neu", "http://xn--bcher-kva.example:" + port + "/neu"} {
+			resp, err := client.Get(ctx, target)
+			require.NoError(t, err, "%s for %s", key, target)
+			assert.Equal(t, "xn--bcher-kva.example:"+port, host.Load(), "the Host header has the ASCII form")
+			assert.Equal(t, "http://xn--bcher-kva.example:"+port+"/neu", resp.FinalURL)
+		}
+	}
+}
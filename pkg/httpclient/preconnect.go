@@ -0,0 +1,224 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// warmConnTTL is how long a connection made by Preconnect waits for a
+// request before it is closed, and how long after a connection to an
+// address Preconnect considers the transport's pool warm for it
+const warmConnTTL = 30 * time.Second
+
+// maxWarmAddresses is how many addresses the warm pool remembers before it
+// forgets those not connected to within warmConnTTL
+const maxWarmAddresses = 1024
+
+// warmConn is a connection made ahead of its first request
+type warmConn struct {
+	conn net.Conn
+	at   time.Time
+}
+
+// warmPool holds the connections Preconnect made, by scheme and address,
+// until a request of the Transport takes them. It is safe for concurrent
+// use.
+type warmPool struct {
+	mu    sync.Mutex
+	conns map[string]warmConn
+	// connected is when each address was last connected to, warm or not
+	connected map[string]time.Time
+}
+
+func newWarmPool() *warmPool {
+	return &warmPool{conns: make(map[string]warmConn), connected: make(map[string]time.Time)}
+}
+
+// take returns the unexpired warm connection to key, if there is one
+func (p *warmPool) take(key string) net.Conn {
+	p.mu.Lock()
+	warm, ok := p.conns[key]
+	delete(p.conns, key)
+	p.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	if time.Since(warm.at) > warmConnTTL {
+		warm.conn.Close()
+		return nil
+	}
+	return warm.conn
+}
+
+// reserve reports whether key has been connected to within warmConnTTL, and
+// marks it connected if not, so concurrent Preconnects make one connection
+func (p *warmPool) reserve(key string) bool {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if last, ok := p.connected[key]; ok && now.Sub(last) < warmConnTTL {
+		return true
+	}
+	p.markLocked(key, now)
+	return false
+}
+
+// mark records a connection to key
+func (p *warmPool) mark(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.markLocked(key, time.Now())
+}
+
+func (p *warmPool) markLocked(key string, now time.Time) {
+	if len(p.connected) >= maxWarmAddresses {
+		for k, last := range p.connected {
+			if now.Sub(last) > warmConnTTL {
+				delete(p.connected, k)
+			}
+		}
+	}
+	p.connected[key] = now
+}
+
+// forget undoes the reservation of a Preconnect that failed
+func (p *warmPool) forget(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.connected, key)
+}
+
+// put keeps conn for the next request to key, closing one it replaces
+func (p *warmPool) put(key string, conn net.Conn) {
+	p.mu.Lock()
+	previous, ok := p.conns[key]
+	p.conns[key] = warmConn{conn: conn, at: time.Now()}
+	p.mu.Unlock()
+
+	if ok {
+		previous.conn.Close()
+	}
+}
+
+// Preconnect resolves the host of rawURL and opens a connection to it,
+// completing the TLS handshake for https, without sending a request. The
+// next request to that host uses the connection instead of dialing, so the
+// lookups and handshakes of many hosts can be done at once before their
+// requests are made. Hosts connected to recently are skipped, their
+// connections most likely still pooled, and so is every host once
+// SetTransport has replaced the client's own transport. Hosts overridden
+// in ctx are skipped too, their connections never being reused.
+func (c *Client) Preconnect(ctx context.Context, rawURL string) error {
+	if c.client.Transport != c.routes {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if _, ok := hostOverride(ctx, u.Hostname()); ok {
+		return nil
+	}
+	port := u.Port()
+	switch {
+	case u.Scheme != "http" && u.Scheme != "https":
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	case port == "" && u.Scheme == "https":
+		port = "443"
+	case port == "":
+		port = "80"
+	}
+	addr := net.JoinHostPort(u.Hostname(), port)
+	key := u.Scheme + "|" + addr
+	if c.warm.reserve(key) {
+		return nil
+	}
+
+	conn, err := c.dialer.DialContext(ctx, "tcp", addr)
+	if err == nil && u.Scheme == "https" {
+		conn, err = c.handshake(ctx, conn, addr)
+	}
+	if err != nil {
+		c.warm.forget(key)
+		return err
+	}
+	c.warm.put(key, conn)
+	return nil
+}
+
+// dialHTTP is the Transport's DialContext: a warm connection, if there is
+// one, or a new one. Warm connections are never those of an overridden
+// host.
+func (c *Client) dialHTTP(ctx context.Context, network, addr string) (net.Conn, error) {
+	overridden := overriddenAddr(ctx, addr)
+	key := "http|" + addr
+	if !overridden {
+		if conn := c.warm.take(key); conn != nil {
+			return conn, nil
+		}
+	}
+	conn, err := c.dialer.DialContext(ctx, network, addr)
+	if err == nil && !overridden {
+		c.warm.mark(key)
+	}
+	return conn, err
+}
+
+// dialTLS is the Transport's DialTLSContext, dialHTTP for https
+func (c *Client) dialTLS(ctx context.Context, network, addr string) (net.Conn, error) {
+	overridden := overriddenAddr(ctx, addr)
+	key := "https|" + addr
+	if !overridden {
+		if conn := c.warm.take(key); conn != nil {
+			return conn, nil
+		}
+	}
+	conn, err := c.dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err = c.handshake(ctx, conn, addr)
+	if err == nil && !overridden {
+		c.warm.mark(key)
+	}
+	return conn, err
+}
+
+// handshake runs the TLS handshake over conn with the server at addr,
+// bounded by the transport's TLSHandshakeTimeout, closing conn when it
+// fails
+func (c *Client) handshake(ctx context.Context, conn net.Conn, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	config := &tls.Config{}
+	if c.tlsConfig != nil {
+		config = c.tlsConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.transport.TLSHandshakeTimeout)
+	defer cancel()
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// SetTLSConfig verifies servers with config, such as one that trusts a
+// private certificate authority, instead of the system defaults
+func (c *Client) SetTLSConfig(config *tls.Config) {
+	c.tlsConfig = config
+}
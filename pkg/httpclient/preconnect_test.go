@@ -0,0 +1,116 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCountingServer starts a server, over TLS when useTLS is set, that
+// counts the connections made to it
+func newCountingServer(t *testing.T, useTLS bool, conns *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	// Failed handshakes are expected
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	if useTLS {
+		server.StartTLS()
+	} else {
+		server.Start()
+	}
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newPreconnectClient creates a client that trusts cert, when it is set
+func newPreconnectClient(t *testing.T, cert *x509.Certificate) *Client {
+	ctrl := gomock.NewController(t)
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Error(gomock.Any(), gomock.Any()).AnyTimes()
+
+	client := New(5*time.Second, mockLogger)
+	if cert != nil {
+		roots := x509.NewCertPool()
+		roots.AddCert(cert)
+		client.SetTLSConfig(&tls.Config{RootCAs: roots})
+	}
+	return client
+}
+
+func TestClient_PreconnectIsUsedByTheNextRequest(t *testing.T) {
+	for _, useTLS := range []bool{false, true} {
+		var conns atomic.Int32
+		server := newCountingServer(t, useTLS, &conns)
+		client := newPreconnectClient(t, server.Certificate())
+
+		require.NoError(t, client.Preconnect(context.Background(), server.URL+"/page"))
+		require.Eventually(t, func() bool { return conns.Load() == 1 }, time.Second, time.Millisecond)
+
+		// A second preconnect finds the host warm
+		require.NoError(t, client.Preconnect(context.Background(), server.URL))
+
+		resp, err := client.Get(context.Background(), server.URL+"/page")
+		require.NoError(t, err)
+		assert.Equal(t, "ok", string(resp.Body))
+		resp, err = client.Get(context.Background(), server.URL+"/other")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Equal(t, int32(1), conns.Load(), "tls=%t: the requests used the preconnected connection", useTLS)
+	}
+}
+
+func TestClient_PreconnectSkipsHostsAlreadyConnected(t *testing.T) {
+	var conns atomic.Int32
+	server := newCountingServer(t, true, &conns)
+	client := newPreconnectClient(t, server.Certificate())
+
+	_, err := client.Get(context.Background(), server.URL)
+	require.NoError(t, err)
+	require.NoError(t, client.Preconnect(context.Background(), server.URL))
+
+	assert.Equal(t, int32(1), conns.Load())
+}
+
+func TestClient_PreconnectFailures(t *testing.T) {
+	var conns atomic.Int32
+	server := newCountingServer(t, true, &conns)
+
+	// Without the server's certificate the handshake fails, and a later
+	// request dials again
+	client := newPreconnectClient(t, nil)
+	err := client.Preconnect(context.Background(), server.URL)
+	assert.ErrorContains(t, err, "certificate")
+	client.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+	_, err = client.Get(context.Background(), server.URL)
+	assert.NoError(t, err)
+
+	assert.ErrorContains(t, client.Preconnect(context.Background(), "ftp://example.com/"), "unsupported scheme")
+
+	// With a transport of its own the client has nowhere to put connections
+	client.SetTransport(http.DefaultTransport)
+	before := conns.Load()
+	assert.NoError(t, client.Preconnect(context.Background(), server.URL+"/fresh"))
+	assert.Equal(t, before, conns.Load())
+}
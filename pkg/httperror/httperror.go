@@ -0,0 +1,98 @@
+// Package httperror writes error responses in the representation the client
+// asks for: the JSON models.ErrorResponse the API has always sent, or, for a
+// browser whose Accept header prefers text/html, a small HTML page with the
+// same status, message, code and request ID.
+package httperror
+
+import (
+	"embed"
+	"encoding/json"
+	"html/template"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/contextkeys"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+//go:embed error.html
+var files embed.FS
+
+var page = template.Must(template.ParseFS(files, "error.html"))
+
+// New returns the error response with message and statusCode
+func New(message string, statusCode int) models.ErrorResponse {
+	return models.ErrorResponse{
+		Error:      message,
+		StatusCode: statusCode,
+		Timestamp:  time.Now(),
+	}
+}
+
+// Write sends response to the client of r with its status code, and
+// Retry-After when it says when to retry. The request ID of r is filled in
+// when response has none.
+func Write(w http.ResponseWriter, r *http.Request, response models.ErrorResponse) error {
+	if response.RequestID == "" {
+		response.RequestID = contextkeys.RequestIDFrom(r.Context())
+	}
+	if response.RetryAfterSeconds > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(response.RetryAfterSeconds))
+	}
+	w.Header().Add("Vary", "Accept")
+
+	if PrefersHTML(r) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(response.StatusCode)
+		return page.Execute(w, pageData{ErrorResponse: response, StatusText: http.StatusText(response.StatusCode)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.StatusCode)
+	return json.NewEncoder(w).Encode(response)
+}
+
+// pageData is what error.html renders
+type pageData struct {
+	models.ErrorResponse
+	StatusText string
+}
+
+// PrefersHTML reports whether the Accept header of r rates text/html above
+// application/json, as browsers' navigation requests do. A missing header,
+// */* alone, or a tie answers with JSON.
+func PrefersHTML(r *http.Request) bool {
+	htmlQ, jsonQ := 0.0, 0.0
+	for _, value := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if raw, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(raw, 64); err != nil {
+					continue
+				}
+			}
+			if matches(mediaType, "text/html") {
+				htmlQ = max(htmlQ, q)
+			}
+			if matches(mediaType, "application/json") {
+				jsonQ = max(jsonQ, q)
+			}
+		}
+	}
+	return htmlQ > jsonQ
+}
+
+// matches reports whether the Accept media range covers mediaType
+func matches(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(mediaRange, "/*")
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
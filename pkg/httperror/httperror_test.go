@@ -0,0 +1,97 @@
+package httperror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/contextkeys"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefersHTML(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept []string
+		want   bool
+	}{
+		{name: "no header"},
+		{name: "API client", accept: []string{"application/json"}},
+		{name: "anything", accept: []string{"*/*"}},
+		{
+			name:   "browser navigation",
+			accept: []string{"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"},
+			want:   true,
+		},
+		{name: "html only", accept: []string{"text/html"}, want: true},
+		{name: "text range", accept: []string{"text/*, application/json;q=0.5"}, want: true},
+		{name: "json preferred", accept: []string{"text/html;q=0.5, application/json"}},
+		{name: "tie", accept: []string{"text/html, application/json"}},
+		{name: "split over headers", accept: []string{"application/json;q=0.1", "text/html"}, want: true},
+		{name: "html refused", accept: []string{"text/html;q=0, */*;q=0.1"}},
+		{name: "malformed quality", accept: []string{"text/html;q=high, application/json;q=0.2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v2/results/x", nil)
+			for _, value := range tt.accept {
+				r.Header.Add("Accept", value)
+			}
+			assert.Equal(t, tt.want, PrefersHTML(r))
+		})
+	}
+}
+
+// errorRequest is a request carrying a request ID, accepting accept
+func errorRequest(accept string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/analyze", nil)
+	r.Header.Set("Accept", accept)
+	return r.WithContext(contextkeys.WithRequestID(r.Context(), "req-1234"))
+}
+
+func TestWrite_SameErrorInBothRepresentations(t *testing.T) {
+	response := New("The page <b>is</b> not HTML", http.StatusUnprocessableEntity)
+	response.Code = models.ErrorCodeUnsupportedContentType
+	response.RetryAfterSeconds = 30
+
+	jsonRec := httptest.NewRecorder()
+	require.NoError(t, Write(jsonRec, errorRequest("application/json"), response))
+	htmlRec := httptest.NewRecorder()
+	require.NoError(t, Write(htmlRec, errorRequest("text/html"), response))
+
+	for _, rec := range []*httptest.ResponseRecorder{jsonRec, htmlRec} {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+		assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+	}
+
+	assert.Equal(t, "application/json", jsonRec.Header().Get("Content-Type"))
+	var body models.ErrorResponse
+	require.NoError(t, json.NewDecoder(jsonRec.Body).Decode(&body))
+	assert.Equal(t, models.ErrorCodeUnsupportedContentType, body.Code)
+	assert.Equal(t, "req-1234", body.RequestID)
+
+	assert.Equal(t, "text/html; charset=utf-8", htmlRec.Header().Get("Content-Type"))
+	page := htmlRec.Body.String()
+	assert.Contains(t, page, "<h1>422 Unprocessable Entity</h1>")
+	assert.Contains(t, page, "<code>"+models.ErrorCodeUnsupportedContentType+"</code>")
+	assert.Contains(t, page, "<code>req-1234</code>")
+	assert.Contains(t, page, "The page &lt;b&gt;is&lt;/b&gt; not HTML", "the message is escaped")
+}
+
+func TestWrite_KeepsItsOwnRequestID(t *testing.T) {
+	response := New("Analysis failed", http.StatusBadGateway)
+	response.RequestID = "upstream-id"
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, Write(rec, errorRequest("application/json"), response))
+
+	var body models.ErrorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, "upstream-id", body.RequestID)
+	assert.Empty(t, rec.Header().Get("Retry-After"))
+}
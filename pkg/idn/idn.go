@@ -0,0 +1,114 @@
+// Package idn converts the hosts of URLs between the Unicode form of an
+// internationalized domain name, bücher.example, and its ASCII (punycode)
+// form, xn--bcher-kva.example. URLs are compared, keyed and fetched in the
+// ASCII form, which every spelling of a host shares, and shown in the
+// Unicode one.
+package idn
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// acePrefix starts the ASCII form of a label that is not plain ASCII
+const acePrefix = "xn--"
+
+// ASCIIHost returns host, which may carry a port, in lower-case ASCII form.
+// Hosts that are not valid domain names, IP addresses among them, are only
+// lowercased.
+func ASCIIHost(host string) string {
+	name, port := splitPort(host)
+	if isASCII(name) || isIP(name) {
+		return strings.ToLower(host)
+	}
+	ascii, err := idna.Lookup.ToASCII(name)
+	if err != nil {
+		return strings.ToLower(host)
+	}
+	return ascii + port
+}
+
+// UnicodeHost returns host, which may carry a port, in Unicode form for
+// display. Hosts without a punycode label, and those whose punycode does not
+// decode, are returned as they are.
+func UnicodeHost(host string) string {
+	name, port := splitPort(host)
+	if !IsIDN(name) {
+		return host
+	}
+	unicode, err := idna.Display.ToUnicode(strings.ToLower(name))
+	if err != nil {
+		return host
+	}
+	return unicode + port
+}
+
+// IsIDN reports whether host, in either form, is an internationalized
+// domain name
+func IsIDN(host string) bool {
+	if !isASCII(host) {
+		return true
+	}
+	for label := range strings.SplitSeq(host, ".") {
+		if len(label) >= len(acePrefix) && strings.EqualFold(label[:len(acePrefix)], acePrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ASCIIURL returns rawURL with its host in ASCII form. Unparseable URLs and
+// URLs without a host are returned unchanged.
+func ASCIIURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || !IsIDN(u.Host) {
+		return rawURL
+	}
+	u.Host = ASCIIHost(u.Host)
+	return u.String()
+}
+
+// UnicodeURL returns rawURL with its host in Unicode form for display. The
+// host is written as is, where url.URL would percent-encode it, so the
+// result is for people rather than for url.Parse. Unparseable URLs and URLs
+// without an internationalized host are returned unchanged.
+func UnicodeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || !IsIDN(u.Host) {
+		return rawURL
+	}
+	u.Host = ASCIIHost(u.Host)
+	authority, unicode := u.Host, UnicodeHost(u.Host)
+	if u.User != nil {
+		authority = u.User.String() + "@" + authority
+		unicode = u.User.String() + "@" + unicode
+	}
+	return strings.Replace(u.String(), "//"+authority, "//"+unicode, 1)
+}
+
+// splitPort splits host into the name and the port, with its colon, if it
+// has one
+func splitPort(host string) (name, port string) {
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.Contains(host[i:], "]") {
+		return host[:i], host[i:]
+	}
+	return host, ""
+}
+
+// isIP reports whether name is an IP address, IPv6 in brackets
+func isIP(name string) bool {
+	return strings.HasPrefix(name, "[") || net.ParseIP(name) != nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
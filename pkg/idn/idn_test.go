@@ -0,0 +1,92 @@
+package idn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestASCIIHost(t *testing.T) {
+	tests := map[string]string{
+		"bücher.example":        "xn--bcher-kva.example",
+		"BÜCHER.Example:8443":   "xn--bcher-kva.example:8443",
+		"xn--bcher-kva.example": "xn--bcher-kva.example",
+		"XN--BCHER-KVA.Example": "xn--bcher-kva.example",
+		"例え.テスト":                "xn--r8jz45g.xn--zckzah",
+		"ｅｘａｍｐｌｅ.com":           "example.com",
+		"Example.COM":           "example.com",
+		"my_host.example":       "my_host.example",
+		"127.0.0.1:8080":        "127.0.0.1:8080",
+		"[::1]:80":              "[::1]:80",
+		"":                      "",
+	}
+	for host, want := range tests {
+		assert.Equal(t, want, ASCIIHost(host), host)
+		assert.Equal(t, want, ASCIIHost(want), "converting %s twice changes nothing", host)
+	}
+}
+
+func TestUnicodeHost(t *testing.T) {
+	tests := map[string]string{
+		"xn--bcher-kva.example":      "bücher.example",
+		"XN--BCHER-KVA.example:8443": "bücher.example:8443",
+		"xn--r8jz45g.xn--zckzah":     "例え.テスト",
+		"bücher.example":             "bücher.example",
+		"example.com":                "example.com",
+		"xn--zz.example":             "xn--zz.example", // not valid punycode
+		"[::1]:80":                   "[::1]:80",
+	}
+	for host, want := range tests {
+		assert.Equal(t, want, UnicodeHost(host), host)
+	}
+}
+
+func TestIsIDN(t *testing.T) {
+	assert.True(t, IsIDN("bücher.example"))
+	assert.True(t, IsIDN("www.xn--bcher-kva.example"))
+	assert.True(t, IsIDN("XN--BCHER-KVA.example"))
+	assert.False(t, IsIDN("example.com"))
+	assert.False(t, IsIDN("xn-bcher.example"))
+	assert.False(t, IsIDN(""))
+}
+
+func TestASCIIURL(t *testing.T) {
+	tests := map[string]string{
+		"https://bücher.example/katalog?q=1#top": "https://xn--bcher-kva.example/katalog?q=1#top",
+		"https://b%C3%BCcher.example/":           "https://xn--bcher-kva.example/",
+		"https://user@BÜCHER.example:8443/":      "https://user@xn--bcher-kva.example:8443/",
+		"https://xn--bcher-kva.example/":         "https://xn--bcher-kva.example/",
+		"https://Example.com/Path":               "https://Example.com/Path",
+		"/relative/path":                         "/relative/path",
+		"mailto:someone@bücher.example":          "mailto:someone@bücher.example",
+		"http://[::1":                            "http://[::1",
+	}
+	for rawURL, want := range tests {
+		assert.Equal(t, want, ASCIIURL(rawURL), rawURL)
+	}
+}
+
+func TestUnicodeURL(t *testing.T) {
+	tests := map[string]string{
+		"https://xn--bcher-kva.example/katalog?q=1": "https://bücher.example/katalog?q=1",
+		"https://bücher.example/":                   "https://bücher.example/",
+		"https://b%C3%BCcher.example/":              "https://bücher.example/",
+		"https://user:pw@xn--bcher-kva.example:80/": "https://user:pw@bücher.example:80/",
+		"https://example.com/xn--bcher-kva":         "https://example.com/xn--bcher-kva",
+		"/relative/path":                            "/relative/path",
+	}
+	for rawURL, want := range tests {
+		assert.Equal(t, want, UnicodeURL(rawURL), rawURL)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, rawURL := range []string{
+		"https://bücher.example/katalog",
+		"https://例え.テスト/",
+		"https://xn--bcher-kva.example:8443/a?b=c",
+	} {
+		ascii := ASCIIURL(rawURL)
+		assert.Equal(t, ascii, ASCIIURL(UnicodeURL(ascii)), rawURL)
+	}
+}
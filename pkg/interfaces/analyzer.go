@@ -8,12 +8,16 @@ import (
 
 type Analyzer interface {
 	AnalyzeURL(ctx context.Context, url string) (*models.AnalysisResult, error)
+	AnalyzeURLWithOptions(ctx context.Context, url string, opts models.AnalysisOptions) (*models.AnalysisResult, error)
+}
+
+// FetcherStrategy retrieves the HTML of a page for analysis
+type FetcherStrategy interface {
+	Fetch(ctx context.Context, url string) (*models.HTTPResponse, error)
 }
 
 type HTMLParser interface {
 	ParseHTML(ctx context.Context, content []byte, baseURL string) (*models.ParsedHTML, error)
-	DetectHTMLVersion(content []byte) string
-	ExtractTitle(content []byte) string
 }
 
 type LinkChecker interface {
@@ -21,11 +25,32 @@ type LinkChecker interface {
 	CheckLink(ctx context.Context, link models.Link) models.LinkStatus
 }
 
+// StreamingLinkChecker reports each link status as soon as it is known
+// instead of once the whole batch is done
+type StreamingLinkChecker interface {
+	LinkChecker
+	CheckLinksStream(ctx context.Context, links []models.Link, emit func(models.LinkStatus)) error
+}
+
 type HTTPClient interface {
 	Get(ctx context.Context, url string) (*models.HTTPResponse, error)
 	Head(ctx context.Context, url string) (*models.HTTPResponse, error)
 }
 
+// ConditionalHTTPClient can revalidate a previous fetch. A 304 Not Modified
+// is returned as a response with that status and an empty body, not an error.
+type ConditionalHTTPClient interface {
+	HTTPClient
+	GetConditional(ctx context.Context, url string, validators models.Validators) (*models.HTTPResponse, error)
+}
+
+// PreconnectingHTTPClient can connect to a host ahead of its requests, so
+// that they skip the lookup and handshakes
+type PreconnectingHTTPClient interface {
+	HTTPClient
+	Preconnect(ctx context.Context, url string) error
+}
+
 type Logger interface {
 	Debug(msg string, args ...any)
 	Info(msg string, args ...any)
@@ -35,9 +60,51 @@ type Logger interface {
 }
 
 type MetricsCollector interface {
-	RecordRequest(method, path string, statusCode int, duration float64)
+	// RecordRequest records a served request; requestID, when set, is
+	// attached to the duration observation as an exemplar
+	RecordRequest(method, path string, statusCode int, duration float64, requestID string)
 	RecordAnalysis(success bool, duration float64)
+	// RecordAnalysisFailure records the cause of a failed analysis, one of
+	// models.FailureCauses
+	RecordAnalysisFailure(cause string)
 	RecordLinkCheck(success bool, duration float64)
+	RecordCoalescedAnalysis()
+	RecordScreenshot(success bool, duration float64)
+	RecordStage(name string, seconds float64)
+	// RecordCacheLookup records whether a repeat analysis found its page in
+	// the result cache
+	RecordCacheLookup(hit bool)
+	// RecordUpstreamRetry records a retried call to another service, with
+	// why it was retried
+	RecordUpstreamRetry(upstream, reason string)
+	// RecordLinkCheckHedge records a link check that sent a hedged request,
+	// and whether the hedge's answer was the one used
+	RecordLinkCheckHedge(won bool)
+	// RecordMirror records the outcome of an analysis mirrored to another
+	// analyzer: success, mismatch, failure or dropped
+	RecordMirror(outcome string)
+	// RecordDownloadedBytes counts the response body bytes an analysis
+	// downloaded, its link checks included
+	RecordDownloadedBytes(bytes int64)
+	// RecordCallerBytes counts the response body bytes an analysis
+	// downloaded for caller, the API key the gateway bills it to
+	RecordCallerBytes(caller string, bytes int64)
+	// The Add methods move load gauges by delta: analyses running, link
+	// checks being made and link checks waiting for a worker
+	AddAnalysesInFlight(delta int)
+	AddLinkChecksActive(delta int)
+	AddLinkChecksQueued(delta int)
+}
+
+// FailureTracker keeps the failed analyses of each host, with the cause
+// of each failure
+type FailureTracker interface {
+	RecordHostFailure(host, cause string)
+}
+
+// ScreenshotCapturer captures a PNG thumbnail of a rendered page
+type ScreenshotCapturer interface {
+	CaptureScreenshot(ctx context.Context, url string) ([]byte, error)
 }
 
 type Cache interface {
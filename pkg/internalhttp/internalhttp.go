@@ -0,0 +1,44 @@
+// Package internalhttp carries the calls between the services. With h2c
+// enabled they speak HTTP/2 over cleartext, multiplexing concurrent calls on
+// one connection. It is never used for fetching pages from the internet.
+package internalhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// H2CHandler serves handler over HTTP/2 cleartext as well as HTTP/1.1
+func H2CHandler(handler http.Handler) http.Handler {
+	return h2c.NewHandler(handler, &http2.Server{IdleTimeout: 60 * time.Second})
+}
+
+// Transport returns the transport for calls to baseURL: HTTP/2 with prior
+// knowledge when h2c is enabled and baseURL is plain http, and fallback
+// otherwise. An https baseURL already negotiates HTTP/2 through TLS.
+func Transport(baseURL string, h2cEnabled bool, fallback *http.Transport) http.RoundTripper {
+	if !h2cEnabled {
+		return fallback
+	}
+	if u, err := url.Parse(baseURL); err != nil || u.Scheme != "http" {
+		return fallback
+	}
+	return &http2.Transport{
+		AllowHTTP: true,
+		// AllowHTTP only permits http:// URLs; the connection itself has to
+		// be dialled without TLS
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, addr)
+		},
+		ReadIdleTimeout: 30 * time.Second,
+		PingTimeout:     15 * time.Second,
+	}
+}
@@ -0,0 +1,72 @@
+package internalhttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+)
+
+func TestTransport_UsesH2COnlyForPlainHTTP(t *testing.T) {
+	fallback := &http.Transport{}
+
+	tests := []struct {
+		name    string
+		baseURL string
+		enabled bool
+		wantH2C bool
+	}{
+		{name: "disabled", baseURL: "http://analyzer:8081", enabled: false, wantH2C: false},
+		{name: "plain http", baseURL: "http://analyzer:8081", enabled: true, wantH2C: true},
+		{name: "https negotiates itself", baseURL: "https://analyzer:8081", enabled: true, wantH2C: false},
+		{name: "unparsable", baseURL: "http://%zz", enabled: true, wantH2C: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := Transport(tt.baseURL, tt.enabled, fallback)
+			if tt.wantH2C {
+				assert.IsType(t, &http2.Transport{}, rt)
+			} else {
+				assert.Same(t, fallback, rt)
+			}
+		})
+	}
+}
+
+func TestH2CHandler_RoundTrip(t *testing.T) {
+	server := httptest.NewServer(H2CHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		enabled bool
+		want    string
+	}{
+		{name: "h2c", enabled: true, want: "HTTP/2.0"},
+		{name: "http/1.1 still served", enabled: false, want: "HTTP/1.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{
+				Timeout:   5 * time.Second,
+				Transport: Transport(server.URL, tt.enabled, &http.Transport{}),
+			}
+			resp, err := client.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(body))
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Package keyedsem bounds concurrent work per key, such as per target host,
+// with one semaphore for each key in use. A key is forgotten as soon as
+// nobody holds or waits for it, so memory follows the keys in use rather
+// than every key ever seen.
+package keyedsem
+
+import (
+	"context"
+	"sync"
+)
+
+// Semaphore admits up to limit holders per key. It is safe for concurrent
+// use.
+type Semaphore struct {
+	limit int
+
+	mu   sync.Mutex
+	keys map[string]*entry
+}
+
+// entry is the semaphore of one key; refs counts its holders and waiters
+type entry struct {
+	slots chan struct{}
+	refs  int
+}
+
+func New(limit int) *Semaphore {
+	return &Semaphore{
+		limit: max(limit, 1),
+		keys:  make(map[string]*entry),
+	}
+}
+
+// Acquire waits for a slot of key until ctx is done. The returned release
+// frees the slot; calling it more than once has no further effect.
+func (s *Semaphore) Acquire(ctx context.Context, key string) (release func(), err error) {
+	s.mu.Lock()
+	e, ok := s.keys[key]
+	if !ok {
+		e = &entry{slots: make(chan struct{}, s.limit)}
+		s.keys[key] = e
+	}
+	e.refs++
+	s.mu.Unlock()
+
+	// A free slot wins over a context that is already done
+	select {
+	case e.slots <- struct{}{}:
+	default:
+		select {
+		case e.slots <- struct{}{}:
+		case <-ctx.Done():
+			s.unref(key, e)
+			return nil, ctx.Err()
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-e.slots
+			s.unref(key, e)
+		})
+	}, nil
+}
+
+// unref drops a holder or waiter of key, forgetting the key with the last
+func (s *Semaphore) unref(key string, e *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.refs--
+	if e.refs == 0 {
+		delete(s.keys, key)
+	}
+}
+
+// InUse returns how many slots of key are held
+func (s *Semaphore) InUse(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.keys[key]; ok {
+		return len(e.slots)
+	}
+	return 0
+}
+
+// Keys returns how many keys are held or waited for
+func (s *Semaphore) Keys() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.keys)
+}
@@ -0,0 +1,123 @@
+package keyedsem
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemaphore_LimitsEachKey(t *testing.T) {
+	s := New(2)
+
+	var running, peak atomic.Int64
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := s.Acquire(context.Background(), "a.example")
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer release()
+
+			now := running.Add(1)
+			for {
+				old := peak.Load()
+				if now <= old || peak.CompareAndSwap(old, now) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(2), peak.Load())
+	assert.Zero(t, s.Keys())
+}
+
+func TestSemaphore_KeysAreIndependent(t *testing.T) {
+	s := New(1)
+
+	releaseA, err := s.Acquire(context.Background(), "a.example")
+	require.NoError(t, err)
+	defer releaseA()
+
+	releaseB, err := s.Acquire(context.Background(), "b.example")
+	require.NoError(t, err)
+	defer releaseB()
+
+	assert.Equal(t, 1, s.InUse("a.example"))
+	assert.Equal(t, 1, s.InUse("b.example"))
+	assert.Equal(t, 2, s.Keys())
+}
+
+func TestSemaphore_WaitEndsWithContext(t *testing.T) {
+	s := New(1)
+
+	release, err := s.Acquire(context.Background(), "a.example")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = s.Acquire(ctx, "a.example")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The waiter that gave up no longer holds on to the key
+	release()
+	assert.Zero(t, s.Keys())
+}
+
+func TestSemaphore_ReleaseIsIdempotent(t *testing.T) {
+	s := New(1)
+
+	release, err := s.Acquire(context.Background(), "a.example")
+	require.NoError(t, err)
+	held, err := s.Acquire(context.Background(), "b.example")
+	require.NoError(t, err)
+	defer held()
+
+	release()
+	release()
+	assert.Zero(t, s.InUse("a.example"))
+	assert.Equal(t, 1, s.Keys())
+
+	// The freed slot is available again
+	again, err := s.Acquire(context.Background(), "a.example")
+	require.NoError(t, err)
+	again()
+}
+
+func TestSemaphore_WaiterGetsReleasedSlot(t *testing.T) {
+	s := New(1)
+
+	release, err := s.Acquire(context.Background(), "a.example")
+	require.NoError(t, err)
+
+	acquired := make(chan func())
+	go func() {
+		next, err := s.Acquire(context.Background(), "a.example")
+		if assert.NoError(t, err) {
+			acquired <- next
+		}
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second holder admitted while the slot was taken")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release()
+	next := <-acquired
+	assert.Equal(t, 1, s.Keys())
+	next()
+	assert.Zero(t, s.Keys())
+}
@@ -0,0 +1,278 @@
+// Package langdetect guesses the language of a text: from its script for
+// languages written in a script of their own, and otherwise by comparing
+// the ranks of its letter trigrams with those of sample texts, Cavnar and
+// Trenkle's out-of-place measure. It is meant to cross-check the declared
+// language of a page with a paragraph or more of its text.
+package langdetect
+
+import (
+	"cmp"
+	"embed"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// MinLetters is the least text Detect guesses a language for
+const MinLetters = 20
+
+// profileSize is how many of its most frequent trigrams describe a text
+const profileSize = 300
+
+//go:embed samples/*.txt
+var samples embed.FS
+
+// profile ranks the most frequent trigrams of a text, 0 for the most
+// frequent
+type profile map[string]int
+
+// trigramLanguage is a language told apart by its trigrams from the others
+// in its script
+type trigramLanguage struct {
+	code    string
+	script  *unicode.RangeTable
+	profile profile
+}
+
+// loadProfiles builds the trigram profiles of the sample texts once
+var loadProfiles = sync.OnceValue(func() []trigramLanguage {
+	entries, err := samples.ReadDir("samples")
+	if err != nil {
+		panic(err)
+	}
+	var languages []trigramLanguage
+	for _, entry := range entries {
+		text, err := samples.ReadFile(path.Join("samples", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		script := unicode.Latin
+		if dominantScript(string(text)) == unicode.Cyrillic {
+			script = unicode.Cyrillic
+		}
+		languages = append(languages, trigramLanguage{
+			code:    strings.TrimSuffix(entry.Name(), ".txt"),
+			script:  script,
+			profile: newProfile(string(text)),
+		})
+	}
+	return languages
+})
+
+// scriptLanguages are the languages recognized by their script alone
+var scriptLanguages = []struct {
+	script *unicode.RangeTable
+	code   string
+}{
+	{unicode.Arabic, "ar"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Greek, "el"},
+	{unicode.Han, "zh"},
+	{unicode.Hangul, "ko"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+}
+
+// scripts are the scripts counted; Japanese mixes kana with Han
+var scripts = []*unicode.RangeTable{
+	unicode.Latin, unicode.Cyrillic, unicode.Arabic, unicode.Devanagari, unicode.Greek,
+	unicode.Han, unicode.Hangul, unicode.Hebrew, unicode.Thai, unicode.Hiragana, unicode.Katakana,
+}
+
+// Detect returns the ISO 639-1 code of the language of text and a
+// confidence between 0 and 1. It returns "" and 0 for text shorter than
+// MinLetters letters or in no language it knows.
+func Detect(text string) (string, float64) {
+	counts := make(map[*unicode.RangeTable]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, script := range scripts {
+			if unicode.Is(script, r) {
+				counts[script]++
+				break
+			}
+		}
+	}
+	if letters < MinLetters {
+		return "", 0
+	}
+
+	kana := counts[unicode.Hiragana] + counts[unicode.Katakana]
+	if kana > 0 && kana+counts[unicode.Han] >= letters/2 {
+		return "ja", float64(kana+counts[unicode.Han]) / float64(letters)
+	}
+	script := mostUsed(counts)
+	share := float64(counts[script]) / float64(letters)
+	for _, known := range scriptLanguages {
+		if known.script == script {
+			return known.code, share
+		}
+	}
+
+	return closestProfile(newProfile(text), script, share)
+}
+
+// Knows reports whether Detect can return code, a language it has a
+// trigram profile or a script of its own for
+func Knows(code string) bool {
+	return code == "ja" || scriptLanguage(code) || slices.ContainsFunc(loadProfiles(), func(l trigramLanguage) bool {
+		return l.code == code
+	})
+}
+
+// Decisive reports whether Detect returning code rules out the other
+// languages of the text's script: code is known by a script of its own, or
+// was chosen by its trigrams among two profiles or more of its script.
+// Russian, the only Cyrillic profile, is not: Ukrainian or Bulgarian text
+// reads as Russian too, measured against no match at all.
+func Decisive(code string) bool {
+	if code == "ja" || scriptLanguage(code) {
+		return true
+	}
+	var script *unicode.RangeTable
+	for _, language := range loadProfiles() {
+		if language.code == code {
+			script = language.script
+		}
+	}
+	if script == nil {
+		return false
+	}
+	candidates := 0
+	for _, language := range loadProfiles() {
+		if language.script == script {
+			candidates++
+		}
+	}
+	return candidates >= 2
+}
+
+func scriptLanguage(code string) bool {
+	for _, known := range scriptLanguages {
+		if known.code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// closestProfile compares profile with those of the sample languages in
+// script. The confidence is how much closer the best is than the runner-up,
+// or than no match at all when the script has one language.
+func closestProfile(text profile, script *unicode.RangeTable, share float64) (string, float64) {
+	worst := len(text) * profileSize
+	best, runnerUp := "", worst
+	bestDistance := worst
+	for _, language := range loadProfiles() {
+		if language.script != script {
+			continue
+		}
+		d := distance(text, language.profile)
+		switch {
+		case d < bestDistance:
+			best, runnerUp, bestDistance = language.code, bestDistance, d
+		case d < runnerUp:
+			runnerUp = d
+		}
+	}
+	if best == "" || runnerUp == 0 {
+		return "", 0
+	}
+	margin := float64(runnerUp-bestDistance) / float64(runnerUp)
+	return best, min(1, share*margin/marginScale)
+}
+
+// marginScale is the margin between the two closest profiles read as
+// certain; texts of a paragraph or more usually reach it
+const marginScale = 0.25
+
+// distance is the out-of-place measure of text against language: how far
+// apart each trigram of text is ranked in the two, the profile size for
+// trigrams language lacks
+func distance(text, language profile) int {
+	total := 0
+	for trigram, rank := range text {
+		other, ok := language[trigram]
+		if !ok {
+			total += profileSize
+			continue
+		}
+		total += max(rank-other, other-rank)
+	}
+	return total
+}
+
+// newProfile ranks the profileSize most frequent trigrams of the words of
+// text, each padded with a space on both sides
+func newProfile(text string) profile {
+	counts := make(map[string]int)
+	var word []rune
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		padded := append(append([]rune{' '}, word...), ' ')
+		for i := 0; i+3 <= len(padded); i++ {
+			counts[string(padded[i:i+3])]++
+		}
+		word = word[:0]
+	}
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			word = append(word, unicode.ToLower(r))
+			continue
+		}
+		flush()
+	}
+	flush()
+
+	trigrams := make([]string, 0, len(counts))
+	for trigram := range counts {
+		trigrams = append(trigrams, trigram)
+	}
+	// Ties are broken alphabetically so that profiles are deterministic
+	slices.SortFunc(trigrams, func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), strings.Compare(a, b))
+	})
+	if len(trigrams) > profileSize {
+		trigrams = trigrams[:profileSize]
+	}
+
+	ranks := make(profile, len(trigrams))
+	for i, trigram := range trigrams {
+		ranks[trigram] = i
+	}
+	return ranks
+}
+
+// dominantScript returns the script most letters of text are in
+func dominantScript(text string) *unicode.RangeTable {
+	counts := make(map[*unicode.RangeTable]int)
+	for _, r := range text {
+		for _, script := range scripts {
+			if unicode.Is(script, r) {
+				counts[script]++
+				break
+			}
+		}
+	}
+	return mostUsed(counts)
+}
+
+// mostUsed returns the script with the highest count, in the order of
+// scripts on a tie
+func mostUsed(counts map[*unicode.RangeTable]int) *unicode.RangeTable {
+	var most *unicode.RangeTable
+	for _, script := range scripts {
+		if most == nil || counts[script] > counts[most] {
+			most = script
+		}
+	}
+	return most
+}
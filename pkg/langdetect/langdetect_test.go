@@ -0,0 +1,85 @@
+package langdetect
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"The museum stays open late on Fridays, and the guided tours start every hour from the main entrance.", "en"},
+		{"Das Museum ist freitags länger geöffnet, und die Führungen beginnen jede Stunde am Haupteingang.", "de"},
+		{"Le musée reste ouvert tard le vendredi et les visites guidées partent toutes les heures de l'entrée principale.", "fr"},
+		{"El museo abre hasta tarde los viernes y las visitas guiadas salen cada hora desde la entrada principal.", "es"},
+		{"Il museo resta aperto fino a tardi il venerdì e le visite guidate partono ogni ora dall'ingresso principale.", "it"},
+		{"O museu fica aberto até tarde às sextas-feiras e as visitas guiadas partem a cada hora da entrada principal.", "pt"},
+		{"Het museum is op vrijdag tot laat open en de rondleidingen vertrekken elk uur bij de hoofdingang.", "nl"},
+		{"По пятницам музей работает допоздна, а экскурсии начинаются каждый час от главного входа.", "ru"},
+		{"博物館は金曜日に遅くまで開いていて、ガイドツアーは毎時正面入口から出発します。", "ja"},
+		{"博物馆周五开放到很晚，导览每小时从正门出发，欢迎大家前来参观学习。", "zh"},
+		{"박물관은 금요일에 늦게까지 문을 열고 가이드 투어는 매시간 정문에서 출발합니다.", "ko"},
+		{"يفتح المتحف أبوابه حتى وقت متأخر أيام الجمعة وتبدأ الجولات كل ساعة", "ar"},
+		{"Το μουσείο μένει ανοιχτό ως αργά την Παρασκευή και οι ξεναγήσεις ξεκινούν κάθε ώρα.", "el"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			language, confidence := Detect(tt.text)
+			assert.Equal(t, tt.expected, language)
+			assert.Greater(t, confidence, 0.0)
+			assert.LessOrEqual(t, confidence, 1.0)
+		})
+	}
+}
+
+func TestDetect_TooShort(t *testing.T) {
+	language, confidence := Detect("Home · About · Contact")
+	assert.Empty(t, language)
+	assert.Zero(t, confidence)
+
+	language, _ = Detect(strings.Repeat("1234 ", 100))
+	assert.Empty(t, language, "digits are not letters")
+}
+
+func TestDetect_LongerTextIsMoreCertain(t *testing.T) {
+	sentence := "Das Museum ist freitags länger geöffnet, und die Führungen beginnen jede Stunde am Haupteingang. "
+	_, short := Detect(sentence)
+	_, long := Detect(sentence + "Kinder unter zwölf Jahren zahlen keinen Eintritt, und für Gruppen gibt es einen Rabatt, wenn sie sich vorher anmelden.")
+	assert.Greater(t, long, short)
+}
+
+func TestKnows(t *testing.T) {
+	for _, code := range []string{"en", "de", "ru", "ja", "zh", "ar"} {
+		assert.True(t, Knows(code), code)
+	}
+	for _, code := range []string{"uk", "pl", "sv", ""} {
+		assert.False(t, Knows(code), code)
+	}
+}
+
+func TestDecisive(t *testing.T) {
+	for _, code := range []string{"en", "fr", "nl", "ja", "zh", "el"} {
+		assert.True(t, Decisive(code), code)
+	}
+	assert.False(t, Decisive("ru"), "the only Cyrillic profile")
+	assert.False(t, Decisive("uk"), "no profile")
+}
+
+func BenchmarkDetect(b *testing.B) {
+	text, err := samples.ReadFile("samples/fr.txt")
+	if err != nil {
+		b.Fatal(err)
+	}
+	loadProfiles()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Detect(string(text))
+	}
+}
@@ -0,0 +1,23 @@
+// Package linkcheck carries the LinkCheckOptions of a batch of links in the
+// request context, so the link checker applies them whether it runs in the
+// same process or behind its HTTP API.
+package linkcheck
+
+import (
+	"context"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+type contextKey struct{}
+
+// WithOptions returns a context whose link checks use opts
+func WithOptions(ctx context.Context, opts models.LinkCheckOptions) context.Context {
+	return context.WithValue(ctx, contextKey{}, opts)
+}
+
+// FromContext returns the options carried by ctx, and whether it has any
+func FromContext(ctx context.Context) (models.LinkCheckOptions, bool) {
+	opts, ok := ctx.Value(contextKey{}).(models.LinkCheckOptions)
+	return opts, ok
+}
@@ -0,0 +1,19 @@
+package linkcheck
+
+import (
+	"context"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionsContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	want := models.LinkCheckOptions{Method: models.LinkCheckHead, Retries: 2}
+	got, ok := FromContext(WithOptions(context.Background(), want))
+	assert.True(t, ok)
+	assert.Equal(t, want, got)
+}
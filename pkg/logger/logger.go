@@ -2,31 +2,23 @@ package logger
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/contextkeys"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
 )
 
-func New(service string, level slog.Level) interfaces.Logger {
-	opts := &slog.HandlerOptions{
-		Level: level,
-		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-			if a.Key == slog.TimeKey {
-				return slog.Attr{
-					Key:   a.Key,
-					Value: slog.StringValue(a.Value.Time().Format(time.RFC3339)),
-				}
-			}
-			return a
-		},
-	}
-
-	handler := slog.NewJSONHandler(os.Stdout, opts)
+// New creates a JSON logger on stdout. Passing a *slog.LevelVar as level lets
+// callers change the minimum level at runtime without rebuilding the logger
+func New(service string, level slog.Leveler) interfaces.Logger {
+	handler := slog.NewJSONHandler(os.Stdout, handlerOptions(level))
 
 	baseLogger := slog.New(handler).With(
 		slog.String("service", service),
@@ -38,7 +30,7 @@ func New(service string, level slog.Level) interfaces.Logger {
 }
 
 // NewWithFiles creates a logger that writes to both stdout and files
-func NewWithFiles(service string, level slog.Level, logDir string) interfaces.Logger {
+func NewWithFiles(service string, level slog.Leveler, logDir string) interfaces.Logger {
 	// Create log directory if it doesn't exist
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		// Fallback to stdout only if we can't create log directory
@@ -56,7 +48,40 @@ func NewWithFiles(service string, level slog.Level, logDir string) interfaces.Lo
 	// Create multi writer (both stdout and file)
 	multiWriter := io.MultiWriter(os.Stdout, file)
 
-	opts := &slog.HandlerOptions{
+	handler := slog.NewJSONHandler(multiWriter, handlerOptions(level))
+
+	baseLogger := slog.New(handler).With(
+		slog.String("service", service),
+		slog.Int("pid", os.Getpid()),
+		slog.String("go_version", runtime.Version()),
+	)
+
+	return NewAdapter(baseLogger)
+}
+
+// ParseLevel converts a level name (debug, info, warn, error) to a slog.Level
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q: must be one of debug, info, warn, error", name)
+	}
+}
+
+// LevelName returns the lowercase name accepted by ParseLevel
+func LevelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
+
+func handlerOptions(level slog.Leveler) *slog.HandlerOptions {
+	return &slog.HandlerOptions{
 		Level: level,
 		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
 			if a.Key == slog.TimeKey {
@@ -68,20 +93,10 @@ func NewWithFiles(service string, level slog.Level, logDir string) interfaces.Lo
 			return a
 		},
 	}
-
-	handler := slog.NewJSONHandler(multiWriter, opts)
-
-	baseLogger := slog.New(handler).With(
-		slog.String("service", service),
-		slog.Int("pid", os.Getpid()),
-		slog.String("go_version", runtime.Version()),
-	)
-
-	return NewAdapter(baseLogger)
 }
 
 func WithContext(ctx context.Context, logger interfaces.Logger) interfaces.Logger {
-	if requestID, ok := ctx.Value("request_id").(string); ok {
+	if requestID := contextkeys.RequestIDFrom(ctx); requestID != "" {
 		return logger.With(slog.String("request_id", requestID))
 	}
 	return logger
@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// MaxLoggedBodyBytes caps how much of a request or response body is logged
+const MaxLoggedBodyBytes = 512
+
+const redactedValue = "[REDACTED]"
+
+// sensitiveParams matches query parameter and form field names that usually carry credentials
+var sensitiveParams = regexp.MustCompile(`(?i)(token|secret|password|passwd|credential)|(^|[_-])(key|apikey|pwd|signature|sig|auth|session|sessionid)$`)
+
+// inlineSecrets matches name=value pairs with sensitive names inside free text
+var inlineSecrets = regexp.MustCompile(`(?i)([\w-]*(?:token|secret|password|passwd|credential|api[_-]?key|signature)[\w-]*=)[^&\s"',;]+`)
+
+// jsonSecrets matches JSON string members with sensitive names
+var jsonSecrets = regexp.MustCompile(`(?i)("[\w-]*(?:token|secret|password|passwd|credential|api[_-]?key|signature)[\w-]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// bearerTokens matches credentials following an authorization scheme
+var bearerTokens = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`)
+
+// sensitiveHeaders are always masked when headers are logged
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+	"X-Admin-Token":       true,
+	"X-Debug-Log-Bodies":  true,
+}
+
+// RedactURL masks credentials in a URL before it is logged: the userinfo
+// password and the values of query parameters with sensitive names.
+// Unparseable input falls back to RedactText.
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return RedactText(raw)
+	}
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redactedValue)
+		}
+	}
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for name, values := range query {
+			if sensitiveParams.MatchString(name) {
+				for i := range values {
+					values[i] = redactedValue
+				}
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	// Encode escapes the brackets; keep the marker readable in logs
+	return strings.ReplaceAll(u.String(), url.QueryEscape(redactedValue), redactedValue)
+}
+
+// RedactPageURL is RedactURL when whole is set, and otherwise reduces the
+// URL to its host, for logs that must not tell which page was analyzed.
+// Unparseable input is then dropped.
+func RedactPageURL(raw string, whole bool) string {
+	if whole {
+		return RedactURL(raw)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// RedactBody truncates a body to MaxLoggedBodyBytes and masks inline secrets.
+// It is the only supported way to put a request or response body in a log line.
+func RedactBody(body []byte) string {
+	return RedactBodyLimit(body, MaxLoggedBodyBytes)
+}
+
+// RedactBodyLimit is RedactBody with its own cap, for the debug logging of
+// whole bodies. JSON members with sensitive names are masked as well.
+func RedactBodyLimit(body []byte, limit int) string {
+	truncated := len(body) > limit
+	if truncated {
+		body = body[:limit]
+	}
+
+	text := jsonSecrets.ReplaceAllString(string(body), `${1}"`+redactedValue+`"`)
+	text = RedactText(text)
+	if truncated {
+		text += "...(truncated)"
+	}
+	return text
+}
+
+// RedactText masks name=value secrets and bearer/basic credentials in free
+// text such as error messages, which often embed the failing URL
+func RedactText(text string) string {
+	text = inlineSecrets.ReplaceAllString(text, "${1}"+redactedValue)
+	return bearerTokens.ReplaceAllString(text, "${1} "+redactedValue)
+}
+
+// RedactHeaders flattens headers for logging with credentials masked
+func RedactHeaders(headers http.Header) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for name, values := range headers {
+		canonical := http.CanonicalHeaderKey(name)
+		if sensitiveHeaders[canonical] {
+			redacted[canonical] = redactedValue
+			continue
+		}
+		redacted[canonical] = strings.Join(values, ", ")
+	}
+	return redacted
+}
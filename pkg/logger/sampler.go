@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+)
+
+// Sampler limits how often a repeated log message is emitted. Within each
+// interval the first occurrences of a message key are allowed, after which
+// only every Nth occurrence gets through. It is safe for concurrent use.
+type Sampler struct {
+	first      int
+	thereafter int
+	interval   time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]int
+	dropped     atomic.Uint64
+
+	now func() time.Time
+}
+
+// NewSampler creates a sampler that allows the first occurrences of each key
+// per interval and then every thereafter-th one. A thereafter of zero drops
+// everything past the first occurrences.
+func NewSampler(first, thereafter int, interval time.Duration) *Sampler {
+	return &Sampler{
+		first:      first,
+		thereafter: thereafter,
+		interval:   interval,
+		counts:     make(map[string]int),
+		now:        time.Now,
+	}
+}
+
+// Allow reports whether this occurrence of key should be logged
+func (s *Sampler) Allow(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.windowStart) >= s.interval {
+		s.windowStart = now
+		clear(s.counts)
+	}
+
+	s.counts[key]++
+	n := s.counts[key]
+	if n <= s.first || (s.thereafter > 0 && (n-s.first)%s.thereafter == 0) {
+		return true
+	}
+
+	s.dropped.Add(1)
+	return false
+}
+
+// Dropped returns how many occurrences have been suppressed since the last reset
+func (s *Sampler) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Reset clears all counters and starts a new interval
+func (s *Sampler) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.windowStart = time.Time{}
+	clear(s.counts)
+	s.dropped.Store(0)
+}
+
+// SampledLogger applies a Sampler to debug messages, keyed by message text.
+// Info, Warn and Error always pass through.
+type SampledLogger struct {
+	logger  interfaces.Logger
+	sampler *Sampler
+}
+
+// NewSampledLogger wraps a logger so high-volume debug lines are sampled
+func NewSampledLogger(logger interfaces.Logger, sampler *Sampler) *SampledLogger {
+	return &SampledLogger{
+		logger:  logger,
+		sampler: sampler,
+	}
+}
+
+func (l *SampledLogger) Debug(msg string, args ...any) {
+	if l.sampler.Allow(msg) {
+		l.logger.Debug(msg, args...)
+	}
+}
+
+func (l *SampledLogger) Info(msg string, args ...any) {
+	l.logger.Info(msg, args...)
+}
+
+func (l *SampledLogger) Warn(msg string, args ...any) {
+	l.logger.Warn(msg, args...)
+}
+
+func (l *SampledLogger) Error(msg string, args ...any) {
+	l.logger.Error(msg, args...)
+}
+
+// With returns a child logger that shares the same sampler
+func (l *SampledLogger) With(args ...any) interfaces.Logger {
+	return &SampledLogger{
+		logger:  l.logger.With(args...),
+		sampler: l.sampler,
+	}
+}
+
+// Sampler returns the sampler shared by this logger and its children
+func (l *SampledLogger) Sampler() *Sampler {
+	return l.sampler
+}
+
+// Ensure SampledLogger implements interfaces.Logger
+var _ interfaces.Logger = (*SampledLogger)(nil)
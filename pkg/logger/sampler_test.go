@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampler_FirstThenEveryNth(t *testing.T) {
+	sampler := NewSampler(3, 5, time.Minute)
+
+	var allowed []int
+	for i := 1; i <= 20; i++ {
+		if sampler.Allow("Checking link") {
+			allowed = append(allowed, i)
+		}
+	}
+
+	assert.Equal(t, []int{1, 2, 3, 8, 13, 18}, allowed)
+	assert.Equal(t, uint64(14), sampler.Dropped())
+}
+
+func TestSampler_KeysAreIndependent(t *testing.T) {
+	sampler := NewSampler(1, 0, time.Minute)
+
+	assert.True(t, sampler.Allow("a"))
+	assert.True(t, sampler.Allow("b"))
+	assert.False(t, sampler.Allow("a"))
+	assert.False(t, sampler.Allow("b"))
+}
+
+func TestSampler_IntervalAndReset(t *testing.T) {
+	now := time.Now()
+	sampler := NewSampler(1, 0, time.Second)
+	sampler.now = func() time.Time { return now }
+
+	assert.True(t, sampler.Allow("key"))
+	assert.False(t, sampler.Allow("key"))
+
+	// A new interval starts counting again
+	now = now.Add(time.Second)
+	assert.True(t, sampler.Allow("key"))
+	assert.False(t, sampler.Allow("key"))
+
+	sampler.Reset()
+	assert.Equal(t, uint64(0), sampler.Dropped())
+	assert.True(t, sampler.Allow("key"))
+}
+
+func TestSampler_Concurrent(t *testing.T) {
+	sampler := NewSampler(10, 10, time.Minute)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if sampler.Allow("key") {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 10 up front, then every 10th of the remaining 4990
+	assert.Equal(t, 10+499, allowed)
+	assert.Equal(t, uint64(5000-allowed), sampler.Dropped())
+}
+
+func TestSampledLogger(t *testing.T) {
+	var buf bytes.Buffer
+	base := NewAdapter(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	log := NewSampledLogger(base, NewSampler(2, 0, time.Minute))
+
+	for i := 0; i < 5; i++ {
+		log.Debug("sampled line")
+		log.With("worker", i).Debug("sampled line")
+		log.Error("error line")
+	}
+
+	output := buf.String()
+	assert.Equal(t, 2, strings.Count(output, "sampled line"))
+	assert.Equal(t, 5, strings.Count(output, "error line"))
+	assert.Equal(t, uint64(8), log.Sampler().Dropped())
+}
@@ -0,0 +1,22 @@
+package metrics
+
+// Nop discards every observation, for code that runs without a metrics
+// backend such as the in-process analyzer library
+type Nop struct{}
+
+func (Nop) RecordRequest(method, path string, statusCode int, duration float64, requestID string) {}
+func (Nop) RecordAnalysis(success bool, duration float64)                                         {}
+func (Nop) RecordAnalysisFailure(cause string)                                                    {}
+func (Nop) RecordLinkCheck(success bool, duration float64)                                        {}
+func (Nop) RecordCoalescedAnalysis()                                                              {}
+func (Nop) RecordScreenshot(success bool, duration float64)                                       {}
+func (Nop) RecordStage(name string, seconds float64)                                              {}
+func (Nop) RecordCacheLookup(hit bool)                                                            {}
+func (Nop) RecordUpstreamRetry(upstream, reason string)                                           {}
+func (Nop) RecordLinkCheckHedge(won bool)                                                         {}
+func (Nop) RecordMirror(outcome string)                                                           {}
+func (Nop) RecordDownloadedBytes(bytes int64)                                                     {}
+func (Nop) RecordCallerBytes(caller string, bytes int64)                                          {}
+func (Nop) AddAnalysesInFlight(delta int)                                                         {}
+func (Nop) AddLinkChecksActive(delta int)                                                         {}
+func (Nop) AddLinkChecksQueued(delta int)                                                         {}
@@ -1,10 +1,32 @@
 package metrics
 
 import (
+	"context"
+	"net/http"
+	"runtime"
+	"slices"
+	"time"
+	"unicode/utf8"
+
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/version"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// The native histograms of request and analysis durations grow their
+// buckets by a tenth each, up to nativeMaxBuckets, past which the resolution
+// is halved; they are reset at most once per nativeMinReset
+const (
+	nativeBucketFactor = 1.1
+	nativeMaxBuckets   = 100
+	nativeMinReset     = time.Hour
 )
 
+// exemplarLabel names the request ID in request duration exemplars
+const exemplarLabel = "request_id"
+
 // PrometheusCollector implements metrics collection using Prometheus
 type PrometheusCollector struct {
 	serviceName string
@@ -15,15 +37,38 @@ type PrometheusCollector struct {
 	httpRequestsInFlight prometheus.Gauge
 
 	// Business metrics
-	analysisTotal     *prometheus.CounterVec
-	analysisDuration  *prometheus.HistogramVec
-	linkChecksTotal   *prometheus.CounterVec
-	linkCheckDuration *prometheus.HistogramVec
+	analysisTotal      *prometheus.CounterVec
+	analysisDuration   *prometheus.HistogramVec
+	analysisFailures   *prometheus.CounterVec
+	linkChecksTotal    *prometheus.CounterVec
+	linkCheckDuration  *prometheus.HistogramVec
+	analysisCoalesced  prometheus.Counter
+	screenshotsTotal   *prometheus.CounterVec
+	screenshotDuration *prometheus.HistogramVec
+	stageDuration      *prometheus.HistogramVec
+	cacheLookupsTotal  *prometheus.CounterVec
+	upstreamRetries    *prometheus.CounterVec
+	linkCheckHedges    prometheus.Counter
+	linkCheckHedgesWon prometheus.Counter
+	mirrorRequests     *prometheus.CounterVec
+	downloadedBytes    prometheus.Counter
+	callerBytes        *prometheus.CounterVec
+
+	// Load metrics
+	analysesInFlight prometheus.Gauge
+	linkChecksActive prometheus.Gauge
+	linkChecksQueued prometheus.Gauge
+	goroutines       prometheus.Gauge
+
+	// Build metrics
+	buildInfo prometheus.Gauge
 }
 
 // NewPrometheusCollector creates a new Prometheus metrics collector
 func NewPrometheusCollector(serviceName string) *PrometheusCollector {
-	return &PrometheusCollector{
+	build := version.Get()
+
+	p := &PrometheusCollector{
 		serviceName: serviceName,
 
 		httpRequestsTotal: prometheus.NewCounterVec(
@@ -45,6 +90,10 @@ func NewPrometheusCollector(serviceName string) *PrometheusCollector {
 					"service": serviceName,
 				},
 				Buckets: prometheus.DefBuckets,
+				// Also a native histogram, for scrapers that ask for one
+				NativeHistogramBucketFactor:     nativeBucketFactor,
+				NativeHistogramMaxBucketNumber:  nativeMaxBuckets,
+				NativeHistogramMinResetDuration: nativeMinReset,
 			},
 			[]string{"method", "path", "status"},
 		),
@@ -77,11 +126,25 @@ func NewPrometheusCollector(serviceName string) *PrometheusCollector {
 				ConstLabels: prometheus.Labels{
 					"service": serviceName,
 				},
-				Buckets: []float64{0.1, 0.5, 1, 2.5, 5, 10, 30},
+				Buckets:                         []float64{0.1, 0.5, 1, 2.5, 5, 10, 30},
+				NativeHistogramBucketFactor:     nativeBucketFactor,
+				NativeHistogramMaxBucketNumber:  nativeMaxBuckets,
+				NativeHistogramMinResetDuration: nativeMinReset,
 			},
 			[]string{"status"},
 		),
 
+		analysisFailures: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "webpage_analysis_failures_total",
+				Help: "Total number of failed webpage analyses by cause",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+			[]string{"cause"},
+		),
+
 		linkChecksTotal: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "link_checks_total",
@@ -104,7 +167,181 @@ func NewPrometheusCollector(serviceName string) *PrometheusCollector {
 			},
 			[]string{"status"},
 		),
+
+		analysisCoalesced: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "webpage_analysis_coalesced_total",
+				Help: "Total number of analyses served by sharing a concurrent identical analysis",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+		),
+
+		screenshotsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "webpage_screenshots_total",
+				Help: "Total number of page screenshots captured",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+			[]string{"status"},
+		),
+
+		screenshotDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name: "webpage_screenshot_duration_seconds",
+				Help: "Page screenshot capture duration in seconds",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+				Buckets: []float64{0.5, 1, 2.5, 5, 10, 20},
+			},
+			[]string{"status"},
+		),
+
+		stageDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name: "webpage_analysis_stage_duration_seconds",
+				Help: "Duration of each webpage analysis stage in seconds",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+				Buckets: []float64{0.001, 0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 30},
+			},
+			[]string{"stage"},
+		),
+
+		cacheLookupsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "webpage_analysis_cache_lookups_total",
+				Help: "Total number of result cache lookups by repeat analyses",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+			[]string{"result"},
+		),
+
+		upstreamRetries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "upstream_retries_total",
+				Help: "Total number of retried calls to other services",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+			[]string{"upstream", "reason"},
+		),
+
+		linkCheckHedges: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "link_check_hedges_total",
+				Help: "Total number of link checks that sent a hedged request",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+		),
+
+		linkCheckHedgesWon: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "link_check_hedges_won_total",
+				Help: "Total number of hedged link checks answered by the hedge",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+		),
+
+		mirrorRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "analyzer_mirror_requests_total",
+				Help: "Total number of analyses mirrored to another analyzer, by outcome",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+			[]string{"outcome"},
+		),
+
+		downloadedBytes: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "analysis_downloaded_bytes_total",
+				Help: "Total response body bytes downloaded by analyses, link checks included",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+		),
+
+		callerBytes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "analysis_caller_downloaded_bytes_total",
+				Help: "Total response body bytes downloaded by analyses, by the caller they are billed to",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+			[]string{"caller"},
+		),
+
+		analysesInFlight: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "webpage_analyses_in_flight",
+				Help: "Number of webpage analyses currently running",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+		),
+
+		linkChecksActive: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "link_checks_active",
+				Help: "Number of link checks currently being made",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+		),
+
+		linkChecksQueued: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "link_checks_queued",
+				Help: "Number of link checks waiting for a worker",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+		),
+
+		goroutines: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "service_goroutines",
+				Help: "Number of goroutines of the service, sampled periodically",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+				},
+			},
+		),
+
+		buildInfo: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "build_info",
+				Help: "Build information of the running service, always 1",
+				ConstLabels: prometheus.Labels{
+					"service": serviceName,
+					"version": build.Version,
+					"commit":  build.Commit,
+				},
+			},
+		),
 	}
+
+	p.buildInfo.Set(1)
+	return p
 }
 
 // GetCollectors returns all Prometheus collectors for registration
@@ -115,17 +352,57 @@ func (p *PrometheusCollector) GetCollectors() []prometheus.Collector {
 		p.httpRequestsInFlight,
 		p.analysisTotal,
 		p.analysisDuration,
+		p.analysisFailures,
 		p.linkChecksTotal,
 		p.linkCheckDuration,
+		p.analysisCoalesced,
+		p.screenshotsTotal,
+		p.screenshotDuration,
+		p.stageDuration,
+		p.cacheLookupsTotal,
+		p.upstreamRetries,
+		p.linkCheckHedges,
+		p.linkCheckHedgesWon,
+		p.mirrorRequests,
+		p.downloadedBytes,
+		p.callerBytes,
+		p.analysesInFlight,
+		p.linkChecksActive,
+		p.linkChecksQueued,
+		p.goroutines,
+		p.buildInfo,
 	}
 }
 
-// RecordRequest records HTTP request metrics
-func (p *PrometheusCollector) RecordRequest(method, path string, statusCode int, duration float64) {
+// Handler serves the metrics of the default registry. It speaks OpenMetrics
+// to scrapers that ask for it, the only text format that carries exemplars.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
+
+// RecordRequest records HTTP request metrics, with requestID as the
+// exemplar of the duration when it is set and fits one
+func (p *PrometheusCollector) RecordRequest(method, path string, statusCode int, duration float64, requestID string) {
 	status := statusCodeToString(statusCode)
 
 	p.httpRequestsTotal.WithLabelValues(method, path, status).Inc()
-	p.httpRequestDuration.WithLabelValues(method, path, status).Observe(duration)
+	observer := p.httpRequestDuration.WithLabelValues(method, path, status)
+	if exemplar := requestExemplar(requestID); exemplar != nil {
+		observer.(prometheus.ExemplarObserver).ObserveWithExemplar(duration, exemplar)
+		return
+	}
+	observer.Observe(duration)
+}
+
+// requestExemplar returns the exemplar labels of requestID, nil when it is
+// empty or is not one the client library accepts: request IDs may come
+// from callers, and an invalid exemplar panics
+func requestExemplar(requestID string) prometheus.Labels {
+	if requestID == "" || !utf8.ValidString(requestID) ||
+		utf8.RuneCountInString(exemplarLabel)+utf8.RuneCountInString(requestID) > prometheus.ExemplarMaxRunes {
+		return nil
+	}
+	return prometheus.Labels{exemplarLabel: requestID}
 }
 
 // RecordAnalysis records webpage analysis metrics
@@ -139,6 +416,15 @@ func (p *PrometheusCollector) RecordAnalysis(success bool, duration float64) {
 	p.analysisDuration.WithLabelValues(status).Observe(duration)
 }
 
+// RecordAnalysisFailure counts a failed analysis by cause; causes outside
+// models.FailureCauses are counted as other, so the label stays bounded
+func (p *PrometheusCollector) RecordAnalysisFailure(cause string) {
+	if !slices.Contains(models.FailureCauses, cause) {
+		cause = models.FailureOther
+	}
+	p.analysisFailures.WithLabelValues(cause).Inc()
+}
+
 // RecordLinkCheck records link check metrics
 func (p *PrometheusCollector) RecordLinkCheck(success bool, duration float64) {
 	status := "success"
@@ -150,6 +436,99 @@ func (p *PrometheusCollector) RecordLinkCheck(success bool, duration float64) {
 	p.linkCheckDuration.WithLabelValues(status).Observe(duration)
 }
 
+// RecordCoalescedAnalysis records an analysis that shared another caller's execution
+func (p *PrometheusCollector) RecordCoalescedAnalysis() {
+	p.analysisCoalesced.Inc()
+}
+
+// RecordScreenshot records page screenshot metrics
+func (p *PrometheusCollector) RecordScreenshot(success bool, duration float64) {
+	status := "success"
+	if !success {
+		status = "failure"
+	}
+
+	p.screenshotsTotal.WithLabelValues(status).Inc()
+	p.screenshotDuration.WithLabelValues(status).Observe(duration)
+}
+
+// RecordStage records the duration of one analysis stage
+func (p *PrometheusCollector) RecordStage(name string, seconds float64) {
+	p.stageDuration.WithLabelValues(name).Observe(seconds)
+}
+
+// RecordCacheLookup records a result cache hit or miss
+func (p *PrometheusCollector) RecordCacheLookup(hit bool) {
+	result := "hit"
+	if !hit {
+		result = "miss"
+	}
+	p.cacheLookupsTotal.WithLabelValues(result).Inc()
+}
+
+// RecordUpstreamRetry records a retried call to upstream
+func (p *PrometheusCollector) RecordUpstreamRetry(upstream, reason string) {
+	p.upstreamRetries.WithLabelValues(upstream, reason).Inc()
+}
+
+// RecordLinkCheckHedge records a hedged link check, and whether the hedge
+// won
+func (p *PrometheusCollector) RecordLinkCheckHedge(won bool) {
+	p.linkCheckHedges.Inc()
+	if won {
+		p.linkCheckHedgesWon.Inc()
+	}
+}
+
+// RecordMirror records the outcome of an analysis mirrored to another
+// analyzer
+func (p *PrometheusCollector) RecordMirror(outcome string) {
+	p.mirrorRequests.WithLabelValues(outcome).Inc()
+}
+
+// RecordDownloadedBytes counts the response body bytes an analysis
+// downloaded
+func (p *PrometheusCollector) RecordDownloadedBytes(bytes int64) {
+	p.downloadedBytes.Add(float64(bytes))
+}
+
+// RecordCallerBytes counts the response body bytes an analysis downloaded
+// for caller
+func (p *PrometheusCollector) RecordCallerBytes(caller string, bytes int64) {
+	p.callerBytes.WithLabelValues(caller).Add(float64(bytes))
+}
+
+// AddAnalysesInFlight moves the running analyses gauge by delta
+func (p *PrometheusCollector) AddAnalysesInFlight(delta int) {
+	p.analysesInFlight.Add(float64(delta))
+}
+
+// AddLinkChecksActive moves the active link checks gauge by delta
+func (p *PrometheusCollector) AddLinkChecksActive(delta int) {
+	p.linkChecksActive.Add(float64(delta))
+}
+
+// AddLinkChecksQueued moves the queued link checks gauge by delta
+func (p *PrometheusCollector) AddLinkChecksQueued(delta int) {
+	p.linkChecksQueued.Add(float64(delta))
+}
+
+// WatchGoroutines samples the number of goroutines into the goroutines
+// gauge now and every interval until ctx is done, so that a slow leak shows
+// as a line that keeps rising
+func (p *PrometheusCollector) WatchGoroutines(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.goroutines.Set(float64(runtime.NumGoroutine()))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // IncRequestsInFlight increments the in-flight requests gauge
 func (p *PrometheusCollector) IncRequestsInFlight() {
 	p.httpRequestsInFlight.Inc()
@@ -178,9 +557,17 @@ func statusCodeToString(code int) string {
 
 // Collector interface implementation
 type Collector interface {
-	RecordRequest(method, path string, statusCode int, duration float64)
+	RecordRequest(method, path string, statusCode int, duration float64, requestID string)
 	RecordAnalysis(success bool, duration float64)
+	RecordAnalysisFailure(cause string)
 	RecordLinkCheck(success bool, duration float64)
+	RecordCoalescedAnalysis()
+	RecordScreenshot(success bool, duration float64)
+	RecordStage(name string, seconds float64)
+	RecordCacheLookup(hit bool)
+	AddAnalysesInFlight(delta int)
+	AddLinkChecksActive(delta int)
+	AddLinkChecksQueued(delta int)
 	GetCollectors() []prometheus.Collector
 }
 
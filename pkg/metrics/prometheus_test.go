@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusCollector_BuildInfo(t *testing.T) {
+	collector := NewPrometheusCollector("test-service")
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(collector.buildInfo))
+	assert.Equal(t, 1, testutil.CollectAndCount(collector.buildInfo, "build_info"))
+}
+
+func TestPrometheusCollector_RecordScreenshot(t *testing.T) {
+	collector := NewPrometheusCollector("test-service")
+
+	collector.RecordScreenshot(true, 1.2)
+	collector.RecordScreenshot(false, 0.4)
+	collector.RecordScreenshot(true, 0.8)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(collector.screenshotsTotal.WithLabelValues("success")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(collector.screenshotsTotal.WithLabelValues("failure")))
+	assert.Equal(t, 2, testutil.CollectAndCount(collector.screenshotDuration, "webpage_screenshot_duration_seconds"))
+
+	// Screenshots are metered apart from analyses
+	assert.Equal(t, 0, testutil.CollectAndCount(collector.analysisTotal, "webpage_analysis_total"))
+}
+
+func TestPrometheusCollector_RecordStage(t *testing.T) {
+	collector := NewPrometheusCollector("test-service")
+
+	collector.RecordStage("fetch", 0.3)
+	collector.RecordStage("fetch", 0.2)
+	collector.RecordStage("parse", 0.01)
+
+	// One histogram series per stage
+	assert.Equal(t, 2, testutil.CollectAndCount(collector.stageDuration, "webpage_analysis_stage_duration_seconds"))
+}
+
+func TestPrometheusCollector_Load(t *testing.T) {
+	collector := NewPrometheusCollector("test-service")
+
+	collector.AddAnalysesInFlight(2)
+	collector.AddAnalysesInFlight(-1)
+	collector.AddLinkChecksQueued(10)
+	collector.AddLinkChecksQueued(-4)
+	collector.AddLinkChecksActive(3)
+	collector.RecordCacheLookup(true)
+	collector.RecordCacheLookup(false)
+	collector.RecordCacheLookup(true)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(collector.analysesInFlight))
+	assert.Equal(t, float64(6), testutil.ToFloat64(collector.linkChecksQueued))
+	assert.Equal(t, float64(3), testutil.ToFloat64(collector.linkChecksActive))
+	assert.Equal(t, float64(2), testutil.ToFloat64(collector.cacheLookupsTotal.WithLabelValues("hit")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(collector.cacheLookupsTotal.WithLabelValues("miss")))
+}
+
+func TestPrometheusCollector_WatchGoroutines(t *testing.T) {
+	collector := NewPrometheusCollector("test-service")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		collector.WatchGoroutines(ctx, time.Hour)
+	}()
+
+	// Sampled once straight away, the watcher itself included
+	assert.Eventually(t, func() bool { return testutil.ToFloat64(collector.goroutines) >= 2 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
+
+func TestPrometheusCollector_RecordAnalysisFailure(t *testing.T) {
+	collector := NewPrometheusCollector("test-service")
+
+	collector.RecordAnalysisFailure(models.FailureDNS)
+	collector.RecordAnalysisFailure(models.FailureDNS)
+	collector.RecordAnalysisFailure(models.FailureHTTP4xx)
+	collector.RecordAnalysisFailure("made_up")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(collector.analysisFailures.WithLabelValues(models.FailureDNS)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(collector.analysisFailures.WithLabelValues(models.FailureHTTP4xx)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(collector.analysisFailures.WithLabelValues(models.FailureOther)))
+	assert.Equal(t, 3, testutil.CollectAndCount(collector.analysisFailures), "no series for unknown causes")
+}
+
+func TestPrometheusCollector_RecordCallerBytes(t *testing.T) {
+	collector := NewPrometheusCollector("test-service")
+
+	collector.RecordCallerBytes("key-0123456789ab", 1000)
+	collector.RecordCallerBytes("key-0123456789ab", 24)
+	collector.RecordCallerBytes("anonymous", 512)
+
+	assert.Equal(t, float64(1024), testutil.ToFloat64(collector.callerBytes.WithLabelValues("key-0123456789ab")))
+	assert.Equal(t, float64(512), testutil.ToFloat64(collector.callerBytes.WithLabelValues("anonymous")))
+}
+
+func TestPrometheusCollector_RecordRequestExemplar(t *testing.T) {
+	collector := NewPrometheusCollector("test-service")
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector.httpRequestDuration)
+
+	collector.RecordRequest("POST", "/api/v1/analyze", 200, 0.3, "req-1")
+	collector.RecordRequest("GET", "/health", 200, 0.001, "")
+	// Too long for an exemplar, or not UTF-8, is observed without one
+	collector.RecordRequest("GET", "/version", 200, 0.001, strings.Repeat("x", prometheus.ExemplarMaxRunes))
+	collector.RecordRequest("GET", "/stats", 200, 0.001, "\xff")
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+
+	exemplars := map[string][]string{}
+	for _, metric := range families[0].GetMetric() {
+		var path string
+		for _, label := range metric.GetLabel() {
+			if label.GetName() == "path" {
+				path = label.GetValue()
+			}
+		}
+		for _, bucket := range metric.GetHistogram().GetBucket() {
+			for _, label := range bucket.GetExemplar().GetLabel() {
+				exemplars[path] = append(exemplars[path], label.GetName()+"="+label.GetValue())
+			}
+		}
+	}
+	assert.Equal(t, map[string][]string{"/api/v1/analyze": {"request_id=req-1"}}, exemplars)
+}
@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/contextkeys"
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/gorilla/mux"
+)
+
+// DebugBodiesHeader carries the admin token on a request whose bodies are
+// to be logged
+const DebugBodiesHeader = "X-Debug-Log-Bodies"
+
+// DefaultBodyLogMaxBytes is how much of each body is logged by default
+const DefaultBodyLogMaxBytes = 4096
+
+// BodyLogOptions selects the requests whose bodies LogBodies logs
+type BodyLogOptions struct {
+	// Always logs the bodies of every request
+	Always bool
+	// Token, when set, logs the bodies of the requests whose
+	// DebugBodiesHeader carries it
+	Token string
+	// MaxBytes caps how much of each body is logged
+	MaxBytes int
+}
+
+// LogBodies logs the request and response bodies of the selected requests at
+// debug level, redacted and capped at MaxBytes. The request body is recorded
+// as the handler reads it and the response body as it is written, so
+// streamed requests and responses pass through as they are. With neither
+// Always nor Token set it returns the handler untouched.
+func LogBodies(log interfaces.Logger, opts BodyLogOptions) mux.MiddlewareFunc {
+	if !opts.Always && opts.Token == "" {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	maxBytes := opts.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultBodyLogMaxBytes
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !opts.Always && !debugRequested(r, opts.Token) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// One more byte than logged tells a body that was cut
+			request := &bodyRecorder{limit: maxBytes + 1}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = &teeBody{ReadCloser: r.Body, recorder: request}
+			}
+			response := &bodyLogWriter{ResponseWriter: w, statusCode: http.StatusOK, recorder: bodyRecorder{limit: maxBytes + 1}}
+
+			next.ServeHTTP(response, r)
+
+			log.Debug("Request bodies",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"request_id", contextkeys.RequestIDFrom(r.Context()),
+				"request_headers", logger.RedactHeaders(r.Header),
+				"request_body", logger.RedactBodyLimit(request.loggable(r.Header.Get("Content-Type")), maxBytes),
+				"status", response.statusCode,
+				"response_headers", logger.RedactHeaders(response.Header()),
+				"response_body", logger.RedactBodyLimit(response.recorder.loggable(response.Header().Get("Content-Type")), maxBytes),
+			)
+		})
+	}
+}
+
+// debugRequested reports whether the request carries the admin token in
+// DebugBodiesHeader
+func debugRequested(r *http.Request, token string) bool {
+	provided := r.Header.Get(DebugBodiesHeader)
+	return token != "" && provided != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+// bodyRecorder keeps the first limit bytes of a body and counts the rest
+type bodyRecorder struct {
+	limit int
+	data  []byte
+	total int
+}
+
+func (b *bodyRecorder) record(p []byte) {
+	b.total += len(p)
+	if room := b.limit - len(b.data); room > 0 {
+		b.data = append(b.data, p[:min(room, len(p))]...)
+	}
+}
+
+// loggable returns the recorded body, or a note of its size when its
+// contentType is not text
+func (b *bodyRecorder) loggable(contentType string) []byte {
+	if b.total == 0 || textual(contentType) {
+		return b.data
+	}
+	return fmt.Appendf(nil, "[%d bytes of %s]", b.total, contentType)
+}
+
+// textual reports whether a body of contentType reads as text in a log
+func textual(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	for _, suffix := range []string{"json", "xml", "javascript", "x-www-form-urlencoded"} {
+		if strings.HasSuffix(mediaType, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// teeBody records the request body as the handler reads it
+type teeBody struct {
+	io.ReadCloser
+	recorder *bodyRecorder
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.recorder.record(p[:n])
+	return n, err
+}
+
+// bodyLogWriter records the response body as it is written, passing every
+// write, flush and hijack through
+type bodyLogWriter struct {
+	http.ResponseWriter
+	statusCode int
+	written    bool
+	recorder   bodyRecorder
+}
+
+func (w *bodyLogWriter) WriteHeader(code int) {
+	if w.written {
+		return
+	}
+	// Informational answers come before the final status
+	if code > 199 || code == http.StatusSwitchingProtocols {
+		w.statusCode = code
+		w.written = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *bodyLogWriter) Write(b []byte) (int, error) {
+	w.written = true
+	n, err := w.ResponseWriter.Write(b)
+	w.recorder.record(b[:n])
+	return n, err
+}
+
+// Flush sends any buffered data to the client, if the underlying writer can
+func (w *bodyLogWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack hands the connection over to the handler, as for a WebSocket upgrade
+func (w *bodyLogWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("hijacking %T: %w", w.ResponseWriter, http.ErrNotSupported)
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *bodyLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logArgs returns the key-value arguments of a log call by key
+func logArgs(call LogCall) map[string]any {
+	args := make(map[string]any)
+	for i := 0; i+1 < len(call.Args); i += 2 {
+		args[call.Args[i].(string)] = call.Args[i+1]
+	}
+	return args
+}
+
+// echo answers with the request body it read
+var echo = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = w.Write(body)
+})
+
+func TestLogBodies_HandlerStillReadsTheBody(t *testing.T) {
+	log := &TestLogger{}
+	handler := LogBodies(log, BodyLogOptions{Always: true})(echo)
+
+	body := `{"url":"https://example.com","api_key":"k-123"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/analyze", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, body, rec.Body.String(), "the handler read the whole body")
+
+	require.Len(t, log.DebugCalls, 1)
+	args := logArgs(log.DebugCalls[0])
+	redacted := `{"url":"https://example.com","api_key":"[REDACTED]"}`
+	assert.Equal(t, redacted, args["request_body"])
+	assert.Equal(t, redacted, args["response_body"])
+	assert.Equal(t, http.StatusAccepted, args["status"])
+	assert.Equal(t, "[REDACTED]", args["request_headers"].(map[string]string)["Authorization"])
+}
+
+func TestLogBodies_CapsTheLoggedBodies(t *testing.T) {
+	log := &TestLogger{}
+	handler := LogBodies(log, BodyLogOptions{Always: true, MaxBytes: 16})(echo)
+
+	body := strings.Repeat("x", 100)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	assert.Equal(t, body, rec.Body.String(), "only the log is capped")
+	require.Len(t, log.DebugCalls, 1)
+	args := logArgs(log.DebugCalls[0])
+	assert.Equal(t, strings.Repeat("x", 16)+"...(truncated)", args["request_body"])
+	assert.Equal(t, strings.Repeat("x", 16)+"...(truncated)", args["response_body"])
+}
+
+func TestLogBodies_BinaryBodiesAreSummarized(t *testing.T) {
+	log := &TestLogger{}
+	handler := LogBodies(log, BodyLogOptions{Always: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG\x0D\x0A\x1A\x0A"))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Len(t, log.DebugCalls, 1)
+	args := logArgs(log.DebugCalls[0])
+	assert.Equal(t, "[8 bytes of image/png]", args["response_body"])
+	assert.Equal(t, "", args["request_body"])
+}
+
+func TestLogBodies_AdminHeader(t *testing.T) {
+	log := &TestLogger{}
+	handler := LogBodies(log, BodyLogOptions{Token: "admin-secret"})(echo)
+
+	for _, header := range []string{"", "wrong"} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+		if header != "" {
+			req.Header.Set(DebugBodiesHeader, header)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.Empty(t, log.DebugCalls, "only the admin token turns logging on")
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	req.Header.Set(DebugBodiesHeader, "admin-secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, log.DebugCalls, 1)
+	args := logArgs(log.DebugCalls[0])
+	assert.Equal(t, "{}", args["request_body"])
+	assert.Equal(t, "[REDACTED]", args["request_headers"].(map[string]string)[DebugBodiesHeader])
+}
+
+func TestLogBodies_DisabledLeavesTheHandlerAlone(t *testing.T) {
+	var seen http.ResponseWriter
+	var seenBody io.Reader
+	handler := LogBodies(&TestLogger{}, BodyLogOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, seenBody = w, r.Body
+	}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	body := req.Body
+	handler.ServeHTTP(rec, req)
+
+	assert.Same(t, rec, seen)
+	assert.Equal(t, body, seenBody)
+}
+
+func TestLogBodies_StreamedResponsesAreFlushed(t *testing.T) {
+	log := &TestLogger{}
+	flushed := make(chan struct{})
+	handler := LogBodies(log, BodyLogOptions{Always: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		<-flushed
+		_, _ = io.WriteString(w, "data: second\n\n")
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	first := make([]byte, len("data: first\n\n"))
+	_, err = io.ReadFull(resp.Body, first)
+	require.NoError(t, err)
+	assert.Equal(t, "data: first\n\n", string(first), "read before the handler wrote more")
+	close(flushed)
+
+	rest, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "data: second\n\n", string(rest))
+}
@@ -0,0 +1,350 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+// DefaultCompressMinSize is the smallest response body worth compressing
+const DefaultCompressMinSize = 1024
+
+// CompressOptions configures response compression
+type CompressOptions struct {
+	// MinSize is the smallest body that is compressed; smaller ones are sent
+	// as they are, with a Content-Length. Zero compresses every body.
+	MinSize int
+}
+
+// Compress compresses response bodies with gzip or deflate, whichever the
+// client's Accept-Encoding prefers. Bodies below MinSize, bodies already
+// encoded, partial content, already compressed types such as images and
+// event streams are sent as they are. A handler that flushes starts the
+// compression early and has every flush reach the client.
+//
+// Middleware outside Compress sees the status as the handler set it but the
+// body as sent, compressed; put anything that counts body bytes inside it to
+// count them uncompressed.
+func Compress(opts CompressOptions) mux.MiddlewareFunc {
+	minSize := max(opts.MinSize, 0)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead || acceptsEventStream(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+			next.ServeHTTP(cw, r)
+			// Not deferred: after a panic the recovery middleware answers
+			// on w, and nothing buffered here may follow its error
+			cw.close()
+		})
+	}
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header, or
+// "" when the client takes neither
+func negotiateEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+	weights := make(map[string]float64)
+	for part := range strings.SplitSeq(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		weight := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+		weights[coding] = weight
+	}
+
+	weightOf := func(coding string) float64 {
+		if weight, ok := weights[coding]; ok {
+			return weight
+		}
+		return weights["*"]
+	}
+	gzipWeight, deflateWeight := weightOf("gzip"), weightOf("deflate")
+	switch {
+	case gzipWeight > 0 && gzipWeight >= deflateWeight:
+		return "gzip"
+	case deflateWeight > 0:
+		return "deflate"
+	default:
+		return ""
+	}
+}
+
+// acceptsEventStream reports whether the client asked for an event stream,
+// whose events must reach it as they are written
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// compressedTypes are the content types already compressed, by their type or
+// their prefix ending in /
+var compressedTypes = []string{
+	"image/", "video/", "audio/", "font/woff", "font/woff2",
+	"application/gzip", "application/x-gzip", "application/zip", "application/zstd",
+	"application/x-7z-compressed", "application/x-rar-compressed", "application/pdf",
+	"text/event-stream",
+}
+
+// compressible reports whether a body of contentType gains from compression
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	if mediaType == "image/svg+xml" {
+		return true
+	}
+	for _, compressed := range compressedTypes {
+		if mediaType == compressed || (strings.HasSuffix(compressed, "/") && strings.HasPrefix(mediaType, compressed)) {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	gzipWriters    sync.Pool
+	deflateWriters sync.Pool
+)
+
+func newEncoder(encoding string, w io.Writer) io.WriteCloser {
+	if encoding == "gzip" {
+		if gz, ok := gzipWriters.Get().(*gzip.Writer); ok {
+			gz.Reset(w)
+			return gz
+		}
+		return gzip.NewWriter(w)
+	}
+	if fw, ok := deflateWriters.Get().(*flate.Writer); ok {
+		fw.Reset(w)
+		return fw
+	}
+	// Only an invalid level fails
+	fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+	return fw
+}
+
+func releaseEncoder(encoder io.WriteCloser) {
+	switch encoder := encoder.(type) {
+	case *gzip.Writer:
+		gzipWriters.Put(encoder)
+	case *flate.Writer:
+		deflateWriters.Put(encoder)
+	}
+}
+
+// compressWriter holds the start of the body back until it knows whether to
+// compress it: once it reaches minSize, at a flush, or when the handler
+// returns
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	// status is the status the handler set, zero until it set one
+	status int
+	buf    []byte
+	// started is set once the status is sent; encoder is then set if the
+	// body is compressed
+	started  bool
+	encoder  io.WriteCloser
+	hijacked bool
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.started || cw.status != 0 {
+		return
+	}
+	if code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols {
+		// Informational answers, such as 103 Early Hints, go out as they
+		// come and the final status follows
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.status = code
+	if !cw.eligible() {
+		cw.start(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.started {
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) < cw.minSize {
+			return len(b), nil
+		}
+		cw.start(cw.eligible())
+		if err := cw.writeBuffered(); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if cw.encoder != nil {
+		return cw.encoder.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush sends what was written so far; a body not yet started is compressed
+// from here on if it may be at all, whatever its size
+func (cw *compressWriter) Flush() {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.started {
+		cw.start(cw.eligible())
+		if cw.writeBuffered() != nil {
+			return
+		}
+	}
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		if flusher.Flush() != nil {
+			return
+		}
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack hands the connection over to the handler, as for a WebSocket upgrade
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("hijacking %T: %w", cw.ResponseWriter, http.ErrNotSupported)
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err == nil {
+		cw.hijacked = true
+	}
+	return conn, buf, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// eligible reports whether the response may be compressed, judging by its
+// status and headers
+func (cw *compressWriter) eligible() bool {
+	switch cw.status {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent, http.StatusSwitchingProtocols:
+		return false
+	}
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	if contentType := header.Get("Content-Type"); contentType != "" {
+		return compressible(contentType)
+	}
+	if len(cw.buf) > 0 {
+		return compressible(http.DetectContentType(cw.buf))
+	}
+	return true
+}
+
+// start sends the status with the headers of a compressed body or of one
+// sent as it is
+func (cw *compressWriter) start(compress bool) {
+	cw.started = true
+	if !compress {
+		cw.ResponseWriter.WriteHeader(cw.status)
+		return
+	}
+
+	header := cw.Header()
+	if _, ok := header["Content-Type"]; !ok {
+		// Sniffed from the body as sent it would read as gzip, so it is
+		// sniffed here or, with nothing to go by yet, left out
+		if len(cw.buf) > 0 {
+			header.Set("Content-Type", http.DetectContentType(cw.buf))
+		} else {
+			header["Content-Type"] = nil
+		}
+	}
+	header.Set("Content-Encoding", cw.encoding)
+	header.Del("Content-Length")
+	header.Del("Accept-Ranges")
+	if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+		// The compressed body is not byte for byte the one the tag names
+		header.Set("ETag", "W/"+etag)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	cw.encoder = newEncoder(cw.encoding, cw.ResponseWriter)
+}
+
+func (cw *compressWriter) writeBuffered() error {
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	buf := cw.buf
+	cw.buf = nil
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// close sends what is still held back and ends the compressed body
+func (cw *compressWriter) close() {
+	if cw.hijacked {
+		return
+	}
+	if !cw.started {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			// The handler wrote nothing; the server answers 200 itself
+			return
+		}
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		if cw.Header().Get("Content-Length") == "" && bodyAllowed(cw.status) {
+			cw.Header().Set("Content-Length", strconv.Itoa(len(cw.buf)))
+		}
+		cw.start(false)
+		_ = cw.writeBuffered()
+		return
+	}
+	if cw.encoder != nil {
+		_ = cw.encoder.Close()
+		releaseEncoder(cw.encoder)
+		cw.encoder = nil
+	}
+}
+
+// bodyAllowed reports whether a response of status may have a body
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified
+}
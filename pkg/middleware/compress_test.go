@@ -0,0 +1,302 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decode returns the body of a recorded response, decompressed as its
+// Content-Encoding says
+func decode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body io.Reader = rec.Body
+	switch rec.Header().Get("Content-Encoding") {
+	case "gzip":
+		gz, err := gzip.NewReader(rec.Body)
+		require.NoError(t, err)
+		body = gz
+	case "deflate":
+		body = flate.NewReader(rec.Body)
+	}
+	decoded, err := io.ReadAll(body)
+	require.NoError(t, err)
+	return string(decoded)
+}
+
+func serveCompressed(handler http.HandlerFunc, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/results", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	Compress(CompressOptions{MinSize: DefaultCompressMinSize})(handler).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCompress_DecodedBodiesMatch(t *testing.T) {
+	large := `{"results":[` + strings.Repeat(`{"url":"https://example.com","title":"Example"},`, 100) + `{}]}`
+	tests := []struct {
+		name string
+		body string
+		// writes splits the body over several writes
+		writes int
+	}{
+		{name: "large body", body: large, writes: 1},
+		{name: "large body in small writes", body: large, writes: 50},
+		{name: "tiny body", body: `{"ok":true}`, writes: 1},
+		{name: "empty body", body: "", writes: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				size := (len(tt.body) + tt.writes - 1) / max(tt.writes, 1)
+				for rest := tt.body; rest != ""; {
+					n := min(size, len(rest))
+					_, _ = w.Write([]byte(rest[:n]))
+					rest = rest[n:]
+				}
+			}
+
+			identity := serveCompressed(handler, "")
+			for _, encoding := range []string{"gzip", "deflate"} {
+				compressed := serveCompressed(handler, encoding)
+				assert.Equal(t, http.StatusCreated, compressed.Code)
+				assert.Equal(t, identity.Body.String(), decode(t, compressed), encoding)
+				assert.Equal(t, "Accept-Encoding", compressed.Header().Get("Vary"))
+				assert.Equal(t, "application/json", compressed.Header().Get("Content-Type"))
+
+				if len(tt.body) >= DefaultCompressMinSize {
+					assert.Equal(t, encoding, compressed.Header().Get("Content-Encoding"))
+					assert.Empty(t, compressed.Header().Get("Content-Length"))
+					assert.Less(t, compressed.Body.Len(), len(tt.body))
+				} else {
+					assert.Empty(t, compressed.Header().Get("Content-Encoding"), "below the minimum size")
+					assert.Equal(t, fmt.Sprint(len(tt.body)), compressed.Header().Get("Content-Length"))
+				}
+			}
+			assert.Equal(t, tt.body, identity.Body.String())
+			assert.Empty(t, identity.Header().Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", identity.Header().Get("Vary"))
+		})
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "gzip", want: "gzip"},
+		{header: "gzip, deflate, br", want: "gzip"},
+		{header: "deflate", want: "deflate"},
+		{header: "deflate;q=1, gzip;q=0.5", want: "deflate"},
+		{header: "GZIP;q=0.8", want: "gzip"},
+		{header: "gzip;q=0, deflate;q=0", want: ""},
+		{header: "gzip;q=0, *", want: "deflate"},
+		{header: "*", want: "gzip"},
+		{header: "identity", want: ""},
+		{header: "br, zstd", want: ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, negotiateEncoding(tt.header), tt.header)
+	}
+}
+
+func TestCompress_LeavesSomeResponsesAsTheyAre(t *testing.T) {
+	large := strings.Repeat("a", 4*DefaultCompressMinSize)
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		request func(*http.Request)
+	}{
+		{
+			name: "already encoded",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "br")
+				_, _ = io.WriteString(w, large)
+			},
+		},
+		{
+			name: "image",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				_, _ = io.WriteString(w, large)
+			},
+		},
+		{
+			name: "sniffed image",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, "\x89PNG\x0D\x0A\x1A\x0A"+large)
+			},
+		},
+		{
+			name: "partial content",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Range", "bytes 0-4095/8192")
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = io.WriteString(w, large)
+			},
+		},
+		{
+			name: "head request",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, large)
+			},
+			request: func(r *http.Request) { r.Method = http.MethodHead },
+		},
+		{
+			name: "event stream",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, _ = io.WriteString(w, "data: "+large+"\n\n")
+			},
+		},
+		{
+			name: "event stream requested",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, "data: "+large+"\n\n")
+			},
+			request: func(r *http.Request) { r.Header.Set("Accept", "text/event-stream") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			if tt.request != nil {
+				tt.request(req)
+			}
+			rec := httptest.NewRecorder()
+			Compress(CompressOptions{MinSize: DefaultCompressMinSize})(tt.handler).ServeHTTP(rec, req)
+
+			assert.NotEqual(t, "gzip", rec.Header().Get("Content-Encoding"))
+			assert.Contains(t, rec.Body.String(), large)
+		})
+	}
+}
+
+func TestCompress_NoContent(t *testing.T) {
+	rec := serveCompressed(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}, "gzip")
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Empty(t, rec.Header().Get("Content-Length"))
+	assert.Zero(t, rec.Body.Len())
+}
+
+func TestCompress_WeakensStrongETags(t *testing.T) {
+	rec := serveCompressed(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Length", fmt.Sprint(2*DefaultCompressMinSize))
+		_, _ = io.WriteString(w, strings.Repeat("a", 2*DefaultCompressMinSize))
+	}, "gzip")
+
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, `W/"v1"`, rec.Header().Get("ETag"))
+	assert.Empty(t, rec.Header().Get("Accept-Ranges"))
+	assert.Empty(t, rec.Header().Get("Content-Length"))
+}
+
+func TestCompress_SetsTheSniffedContentType(t *testing.T) {
+	rec := serveCompressed(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "<!DOCTYPE html><html>"+strings.Repeat("<p>text</p>", 200)+"</html>")
+	}, "gzip")
+
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+}
+
+func TestCompress_FlushedChunksReachTheClient(t *testing.T) {
+	lines := make(chan string)
+	handler := Compress(CompressOptions{MinSize: DefaultCompressMinSize})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.(http.Flusher).Flush()
+		for line := range lines {
+			_, _ = io.WriteString(w, line+"\n")
+			w.(http.Flusher).Flush()
+		}
+	}))
+	server := httptest.NewServer(Metrics(&MockMetricsCollector{})(handler))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "gzip", resp.Header.Get("Content-Encoding"), "a flush starts compressing a small body")
+
+	gz, err := gzip.NewReader(resp.Body)
+	require.NoError(t, err)
+	reader := bufio.NewReader(gz)
+
+	// Each line is readable before the next is written
+	for _, line := range []string{`{"n":1}`, `{"n":2}`, `{"n":3}`} {
+		lines <- line
+		got, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, line+"\n", got)
+	}
+	close(lines)
+	rest, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+}
+
+func TestCompress_StatusCaptureSeesTheHandlersStatus(t *testing.T) {
+	collector := &MockMetricsCollector{}
+
+	handler := Metrics(collector)(Compress(CompressOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, strings.Repeat("not found ", 200), http.StatusNotFound)
+	})))
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, strings.Repeat("not found ", 200)+"\n", decode(t, rec))
+	calls := collector.GetRequestCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, http.StatusNotFound, calls[0].StatusCode)
+}
+
+func TestCompress_PanicLeavesTheAnswerToRecovery(t *testing.T) {
+	logger := &TestLogger{}
+	handler := Recovery(logger)(Compress(CompressOptions{MinSize: DefaultCompressMinSize})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "partial")
+		panic("boom")
+	})))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	var body models.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body), "only Recovery's answer is sent")
+	assert.Equal(t, "Internal Server Error", body.Error)
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// CORSOptions configures which browser origins may call the API
+type CORSOptions struct {
+	// AllowedOrigins lists exact origins (https://app.example.com), subdomain
+	// wildcards (https://*.example.com) or * for any origin. Origins not
+	// listed get no CORS headers.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight answer
+	MaxAge time.Duration
+}
+
+// CORS answers preflight requests and adds CORS headers for the allowed
+// origins. The matching origin is reflected rather than answered with *,
+// unless any origin is allowed and credentials are not.
+func CORS(opts CORSOptions) mux.MiddlewareFunc {
+	allowAll := slices.Contains(opts.AllowedOrigins, "*")
+	methods := strings.Join(opts.AllowedMethods, ", ")
+	headers := strings.Join(opts.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(opts.MaxAge.Seconds()))
+
+	// The answer depends on the Origin unless every origin gets *
+	reflectOrigin := !allowAll || opts.AllowCredentials
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if reflectOrigin {
+				w.Header().Add("Vary", "Origin")
+			}
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			if origin != "" && (allowAll || originAllowed(opts.AllowedOrigins, origin)) {
+				if reflectOrigin {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+				} else {
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+				}
+				if opts.AllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+				if preflight {
+					w.Header().Set("Access-Control-Allow-Methods", methods)
+					w.Header().Set("Access-Control-Allow-Headers", allowHeaders(opts.AllowedHeaders, headers, r.Header.Get("Access-Control-Request-Headers")))
+					w.Header().Set("Access-Control-Max-Age", maxAge)
+				}
+			}
+
+			// Preflights never reach the routes, whatever the origin
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allowHeaders echoes the headers a preflight asks for when all of them are
+// allowed, and otherwise lists the allowed ones so the browser refuses
+func allowHeaders(allowed []string, joined, requested string) string {
+	if requested == "" {
+		return joined
+	}
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if !slices.ContainsFunc(allowed, func(a string) bool { return strings.EqualFold(a, header) }) {
+			return joined
+		}
+	}
+	return requested
+}
+
+// originAllowed reports whether origin matches one of patterns. A pattern
+// with a *. host matches any subdomain at any depth, with the same scheme
+// and port, but not the bare domain.
+func originAllowed(patterns []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == origin {
+			return true
+		}
+
+		scheme, host, ok := strings.Cut(pattern, "://*.")
+		if !ok {
+			continue
+		}
+		prefix, suffix := scheme+"://", "."+host
+		if len(origin) <= len(prefix)+len(suffix) || !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+			continue
+		}
+		sub := origin[len(prefix) : len(origin)-len(suffix)]
+		if !strings.ContainsAny(sub, "/:@") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testCORSOptions() CORSOptions {
+	return CORSOptions{
+		AllowedOrigins: []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods: []string{"GET", "POST", "OPTIONS"},
+		AllowedHeaders: []string{"Content-Type", "X-Request-ID"},
+		MaxAge:         time.Hour,
+	}
+}
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    func(*CORSOptions)
+		method  string
+		headers map[string]string
+		// want lists the expected response headers, "" for absent
+		want       map[string]string
+		wantVary   []string
+		wantStatus int
+		wantNext   bool
+	}{
+		{
+			name:       "allowed origin is reflected",
+			method:     "POST",
+			headers:    map[string]string{"Origin": "https://app.example.com"},
+			want:       map[string]string{"Access-Control-Allow-Origin": "https://app.example.com", "Access-Control-Allow-Credentials": "", "Access-Control-Allow-Methods": ""},
+			wantVary:   []string{"Origin"},
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+		},
+		{
+			name:       "disallowed origin gets no CORS headers",
+			method:     "POST",
+			headers:    map[string]string{"Origin": "https://evil.example.com"},
+			want:       map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Credentials": ""},
+			wantVary:   []string{"Origin"},
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+		},
+		{
+			name:       "same-origin request without Origin",
+			method:     "GET",
+			want:       map[string]string{"Access-Control-Allow-Origin": ""},
+			wantVary:   []string{"Origin"},
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+		},
+		{
+			name:       "subdomain wildcard matches nested subdomains",
+			method:     "GET",
+			headers:    map[string]string{"Origin": "https://a.b.example.org"},
+			want:       map[string]string{"Access-Control-Allow-Origin": "https://a.b.example.org"},
+			wantVary:   []string{"Origin"},
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+		},
+		{
+			name:       "subdomain wildcard does not match the bare domain",
+			method:     "GET",
+			headers:    map[string]string{"Origin": "https://example.org"},
+			want:       map[string]string{"Access-Control-Allow-Origin": ""},
+			wantVary:   []string{"Origin"},
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+		},
+		{
+			name:       "subdomain wildcard keeps the scheme",
+			method:     "GET",
+			headers:    map[string]string{"Origin": "http://ui.example.org"},
+			want:       map[string]string{"Access-Control-Allow-Origin": ""},
+			wantVary:   []string{"Origin"},
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+		},
+		{
+			name:       "credentials are allowed",
+			opts:       func(o *CORSOptions) { o.AllowCredentials = true },
+			method:     "POST",
+			headers:    map[string]string{"Origin": "https://app.example.com"},
+			want:       map[string]string{"Access-Control-Allow-Origin": "https://app.example.com", "Access-Control-Allow-Credentials": "true"},
+			wantVary:   []string{"Origin"},
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+		},
+		{
+			name:       "any origin without credentials answers *",
+			opts:       func(o *CORSOptions) { o.AllowedOrigins = []string{"*"} },
+			method:     "GET",
+			headers:    map[string]string{"Origin": "https://anywhere.example"},
+			want:       map[string]string{"Access-Control-Allow-Origin": "*"},
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+		},
+		{
+			name: "any origin with credentials reflects the origin",
+			opts: func(o *CORSOptions) {
+				o.AllowedOrigins = []string{"*"}
+				o.AllowCredentials = true
+			},
+			method:     "GET",
+			headers:    map[string]string{"Origin": "https://anywhere.example"},
+			want:       map[string]string{"Access-Control-Allow-Origin": "https://anywhere.example", "Access-Control-Allow-Credentials": "true"},
+			wantVary:   []string{"Origin"},
+			wantStatus: http.StatusOK,
+			wantNext:   true,
+		},
+		{
+			name:   "preflight from an allowed origin",
+			opts:   func(o *CORSOptions) { o.AllowCredentials = true },
+			method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": "content-type, x-request-id",
+			},
+			want: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET, POST, OPTIONS",
+				"Access-Control-Allow-Headers":     "content-type, x-request-id",
+				"Access-Control-Max-Age":           "3600",
+			},
+			wantVary:   []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:   "preflight asking for a header that is not allowed",
+			method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": "Content-Type, X-Secret",
+			},
+			want: map[string]string{
+				"Access-Control-Allow-Origin":  "https://app.example.com",
+				"Access-Control-Allow-Headers": "Content-Type, X-Request-ID",
+			},
+			wantVary:   []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:   "preflight from a disallowed origin",
+			method: "OPTIONS",
+			headers: map[string]string{
+				"Origin":                        "https://evil.example.com",
+				"Access-Control-Request-Method": "POST",
+			},
+			want: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+				"Access-Control-Allow-Headers": "",
+				"Access-Control-Max-Age":       "",
+			},
+			wantVary:   []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			wantStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testCORSOptions()
+			if tt.opts != nil {
+				tt.opts(&opts)
+			}
+			called := false
+			handler := CORS(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.Write([]byte("OK"))
+			}))
+
+			req := httptest.NewRequest(tt.method, "/api/v2/analyze", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantNext, called)
+			for header, want := range tt.want {
+				assert.Equal(t, want, w.Header().Get(header), header)
+			}
+			assert.Equal(t, tt.wantVary, w.Header().Values("Vary"))
+		})
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	patterns := []string{"https://app.example.com", "https://*.example.org:8443"}
+	tests := map[string]bool{
+		"https://app.example.com":            true,
+		"https://APP.example.com":            true,
+		"https://app.example.com:8443":       false,
+		"http://app.example.com":             false,
+		"https://ui.example.org:8443":        true,
+		"https://ui.example.org":             false,
+		"https://example.org:8443":           false,
+		"https://.example.org:8443":          false,
+		"https://evil.com/.example.org:8443": false,
+		"null":                               false,
+	}
+	for origin, want := range tests {
+		assert.Equal(t, want, originAllowed(patterns, origin), origin)
+	}
+}
@@ -1,35 +1,44 @@
+// Package middleware holds the HTTP middleware every service puts in front
+// of its routes: request IDs, request logging and metrics, panic recovery,
+// CORS and response compression.
 package middleware
 
 import (
-	"context"
+	"bufio"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/contextkeys"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httperror"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
 	"github.com/gorilla/mux"
 )
 
+// RequestID carries the caller's X-Request-ID, or a new one, in the request
+// context and echoes it on the response
 func RequestID(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		requestID := r.Header.Get("X-Request-ID")
+		requestID := r.Header.Get(contextkeys.RequestIDHeader)
 		if requestID == "" {
 			// Generate new request ID
 			requestID = generateRequestID()
 		}
 
 		// Add to context
-		ctx := context.WithValue(r.Context(), "request_id", requestID)
+		ctx := contextkeys.WithRequestID(r.Context(), requestID)
 
 		// Add to response header
-		w.Header().Set("X-Request-ID", requestID)
+		w.Header().Set(contextkeys.RequestIDHeader, requestID)
 
 		// Continue with request
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// Logging logs the start and the outcome of every request
 func Logging(logger interfaces.Logger) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -42,10 +51,7 @@ func Logging(logger interfaces.Logger) mux.MiddlewareFunc {
 			}
 
 			// Get request ID from context
-			requestID := ""
-			if id, ok := r.Context().Value("request_id").(string); ok {
-				requestID = id
-			}
+			requestID := contextkeys.RequestIDFrom(r.Context())
 
 			// Log request start
 			logger.Info("Request started",
@@ -66,12 +72,15 @@ func Logging(logger interfaces.Logger) mux.MiddlewareFunc {
 				"path", r.URL.Path,
 				"status", wrapped.statusCode,
 				"duration", duration,
+				"remote_addr", r.RemoteAddr,
 				"request_id", requestID,
 			)
 		})
 	}
 }
 
+// Metrics records the method, path, status and duration of every request,
+// with the request ID set by RequestID as the duration's exemplar
 func Metrics(collector interfaces.MetricsCollector) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -88,7 +97,7 @@ func Metrics(collector interfaces.MetricsCollector) mux.MiddlewareFunc {
 
 			// Record metrics
 			duration := time.Since(start).Seconds()
-			collector.RecordRequest(r.Method, r.URL.Path, wrapped.statusCode, duration)
+			collector.RecordRequest(r.Method, r.URL.Path, wrapped.statusCode, duration, contextkeys.RequestIDFrom(r.Context()))
 		})
 	}
 }
@@ -107,8 +116,7 @@ func Recovery(logger interfaces.Logger) mux.MiddlewareFunc {
 						"remote_addr", r.RemoteAddr,
 					)
 
-					// Return error response
-					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					httperror.Write(w, r, httperror.New("Internal Server Error", http.StatusInternalServerError))
 				}
 			}()
 
@@ -117,28 +125,9 @@ func Recovery(logger interfaces.Logger) mux.MiddlewareFunc {
 	}
 }
 
-// CORS middleware adds CORS headers
-func CORS() mux.MiddlewareFunc {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Set CORS headers
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
-			w.Header().Set("Access-Control-Max-Age", "86400")
-
-			// Handle preflight requests
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-
-			next.ServeHTTP(w, r)
-		})
-	}
-}
-
-// responseWriter wraps http.ResponseWriter to capture status code
+// responseWriter wraps http.ResponseWriter to capture status code. It passes
+// flushing and hijacking through, so streamed and upgraded responses work
+// behind the middleware.
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
@@ -160,6 +149,36 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return rw.ResponseWriter.Write(b)
 }
 
+// Flush sends any buffered data to the client, if the underlying writer can
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		if !rw.written {
+			rw.WriteHeader(http.StatusOK)
+		}
+		flusher.Flush()
+	}
+}
+
+// Hijack hands the connection over to the handler, as for a WebSocket upgrade
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("hijacking %T: %w", rw.ResponseWriter, http.ErrNotSupported)
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err == nil && !rw.written {
+		// The handler answers on the raw connection from here on
+		rw.statusCode = http.StatusSwitchingProtocols
+		rw.written = true
+	}
+	return conn, buf, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func generateRequestID() string {
 	// In production, use a proper UUID library
 	return fmt.Sprintf("%d-%s", time.Now().Unix(), generateRandomString(8))
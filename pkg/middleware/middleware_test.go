@@ -1,12 +1,17 @@
 package middleware
 
 import (
-	"context"
+	"bufio"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/contextkeys"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -102,9 +107,10 @@ type RequestMetricsCall struct {
 	Path       string
 	StatusCode int
 	Duration   float64
+	RequestID  string
 }
 
-func (m *MockMetricsCollector) RecordRequest(method, path string, statusCode int, duration float64) {
+func (m *MockMetricsCollector) RecordRequest(method, path string, statusCode int, duration float64, requestID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.RecordRequestCalls = append(m.RecordRequestCalls, RequestMetricsCall{
@@ -112,11 +118,25 @@ func (m *MockMetricsCollector) RecordRequest(method, path string, statusCode int
 		Path:       path,
 		StatusCode: statusCode,
 		Duration:   duration,
+		RequestID:  requestID,
 	})
 }
 
-func (m *MockMetricsCollector) RecordAnalysis(success bool, duration float64)  {}
-func (m *MockMetricsCollector) RecordLinkCheck(success bool, duration float64) {}
+func (m *MockMetricsCollector) RecordAnalysis(success bool, duration float64)   {}
+func (m *MockMetricsCollector) RecordLinkCheck(success bool, duration float64)  {}
+func (m *MockMetricsCollector) RecordCoalescedAnalysis()                        {}
+func (m *MockMetricsCollector) RecordScreenshot(success bool, duration float64) {}
+func (m *MockMetricsCollector) RecordStage(name string, seconds float64)        {}
+func (m *MockMetricsCollector) RecordAnalysisFailure(cause string)              {}
+func (m *MockMetricsCollector) RecordCacheLookup(hit bool)                      {}
+func (m *MockMetricsCollector) RecordUpstreamRetry(upstream, reason string)     {}
+func (m *MockMetricsCollector) RecordLinkCheckHedge(won bool)                   {}
+func (m *MockMetricsCollector) RecordMirror(outcome string)                     {}
+func (m *MockMetricsCollector) RecordDownloadedBytes(bytes int64)               {}
+func (m *MockMetricsCollector) RecordCallerBytes(caller string, bytes int64)    {}
+func (m *MockMetricsCollector) AddAnalysesInFlight(delta int)                   {}
+func (m *MockMetricsCollector) AddLinkChecksActive(delta int)                   {}
+func (m *MockMetricsCollector) AddLinkChecksQueued(delta int)                   {}
 
 func (m *MockMetricsCollector) GetRequestCalls() []RequestMetricsCall {
 	m.mu.Lock()
@@ -188,9 +208,7 @@ func TestRequestID_ContextPropagation(t *testing.T) {
 	var capturedRequestID string
 
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if id, ok := r.Context().Value("request_id").(string); ok {
-			capturedRequestID = id
-		}
+		capturedRequestID = contextkeys.RequestIDFrom(r.Context())
 		w.Write([]byte("OK"))
 	})
 
@@ -217,7 +235,7 @@ func TestLogging_RequestAndResponse(t *testing.T) {
 	req.RemoteAddr = "127.0.0.1:12345"
 
 	// Add request ID to context
-	ctx := context.WithValue(req.Context(), "request_id", "log-test-123")
+	ctx := contextkeys.WithRequestID(req.Context(), "log-test-123")
 	req = req.WithContext(ctx)
 
 	w := httptest.NewRecorder()
@@ -280,6 +298,24 @@ func TestMetrics_RecordRequest(t *testing.T) {
 	assert.Equal(t, "/api/missing", call.Path)
 	assert.Equal(t, 404, call.StatusCode)
 	assert.GreaterOrEqual(t, call.Duration, 0.0) // Should be >= 0, not > 0
+	assert.Empty(t, call.RequestID)
+}
+
+func TestMetrics_RecordsRequestID(t *testing.T) {
+	collector := &MockMetricsCollector{}
+	handler := &TestHandler{Body: "OK"}
+
+	middleware := RequestID(Metrics(collector)(handler))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set(contextkeys.RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+
+	middleware.ServeHTTP(w, req)
+
+	calls := collector.GetRequestCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "req-42", calls[0].RequestID)
 }
 
 func TestMetrics_DefaultStatusCode(t *testing.T) {
@@ -358,43 +394,19 @@ func TestRecovery_WithPanicObject(t *testing.T) {
 	assert.Equal(t, 1, logger.GetErrorCount())
 }
 
-func TestCORS_RegularRequest(t *testing.T) {
-	handler := &TestHandler{Body: "OK"}
-	middleware := CORS()(handler)
+func TestRecovery_ErrorPageForBrowsers(t *testing.T) {
+	handler := RequestID(Recovery(&TestLogger{})(&TestHandler{ShouldPanic: true, PanicValue: "boom"}))
 
-	req := httptest.NewRequest("GET", "/api/test", nil)
+	req := httptest.NewRequest("GET", "/api/v2/results", nil)
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set(contextkeys.RequestIDHeader, "req-42")
 	w := httptest.NewRecorder()
 
-	middleware.ServeHTTP(w, req)
-
-	// Check CORS headers
-	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
-	assert.Equal(t, "GET, POST, PUT, DELETE, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
-	assert.Equal(t, "Content-Type, Authorization, X-Request-ID", w.Header().Get("Access-Control-Allow-Headers"))
-	assert.Equal(t, "86400", w.Header().Get("Access-Control-Max-Age"))
-
-	// Should process request normally
-	assert.Equal(t, "OK", w.Body.String())
-}
-
-func TestCORS_PreflightRequest(t *testing.T) {
-	handler := &TestHandler{Body: "Should not be called"}
-	middleware := CORS()(handler)
-
-	req := httptest.NewRequest("OPTIONS", "/api/test", nil)
-	w := httptest.NewRecorder()
-
-	middleware.ServeHTTP(w, req)
-
-	// Check CORS headers are set
-	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
-	assert.Equal(t, "GET, POST, PUT, DELETE, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+	handler.ServeHTTP(w, req)
 
-	// Should return 200 OK for preflight
-	assert.Equal(t, http.StatusOK, w.Code)
-
-	// Should not call next handler
-	assert.Empty(t, w.Body.String())
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "<code>req-42</code>")
 }
 
 func TestResponseWriter_WriteHeader(t *testing.T) {
@@ -429,3 +441,79 @@ func TestResponseWriter_Write(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rw.statusCode)
 	assert.Equal(t, "test data", w.Body.String())
 }
+
+func TestResponseWriter_FlushPassesThrough(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("event: one\n\n"))
+		require.NoError(t, http.NewResponseController(w).Flush())
+	})
+	w := httptest.NewRecorder()
+
+	Logging(&TestLogger{})(handler).ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+
+	assert.True(t, w.Flushed)
+	assert.Equal(t, "event: one\n\n", w.Body.String())
+}
+
+func TestResponseWriter_FlushWritesHeader(t *testing.T) {
+	w := httptest.NewRecorder()
+	rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+	rw.Flush()
+
+	assert.True(t, w.Flushed)
+	assert.True(t, rw.written)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestResponseWriter_HijackPassesThrough(t *testing.T) {
+	metrics := &MockMetricsCollector{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\nhello")
+		buf.Flush()
+	})
+	server := httptest.NewServer(Metrics(metrics)(handler))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET /ws HTTP/1.1\r\nHost: test\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n")
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	rest, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(rest))
+
+	require.Eventually(t, func() bool { return len(metrics.GetRequestCalls()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, http.StatusSwitchingProtocols, metrics.GetRequestCalls()[0].StatusCode)
+}
+
+func TestResponseWriter_HijackNotSupported(t *testing.T) {
+	rw := &responseWriter{ResponseWriter: httptest.NewRecorder(), statusCode: http.StatusOK}
+
+	_, _, err := rw.Hijack()
+
+	assert.ErrorIs(t, err, http.ErrNotSupported)
+	assert.False(t, rw.written)
+}
+
+func BenchmarkLogging(b *testing.B) {
+	handler := Logging(&TestLogger{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest("GET", "/test", nil)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
@@ -51,6 +51,59 @@ func (mr *MockAnalyzerMockRecorder) AnalyzeURL(ctx, url interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnalyzeURL", reflect.TypeOf((*MockAnalyzer)(nil).AnalyzeURL), ctx, url)
 }
 
+// AnalyzeURLWithOptions mocks base method.
+func (m *MockAnalyzer) AnalyzeURLWithOptions(ctx context.Context, url string, opts models.AnalysisOptions) (*models.AnalysisResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnalyzeURLWithOptions", ctx, url, opts)
+	ret0, _ := ret[0].(*models.AnalysisResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AnalyzeURLWithOptions indicates an expected call of AnalyzeURLWithOptions.
+func (mr *MockAnalyzerMockRecorder) AnalyzeURLWithOptions(ctx, url, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnalyzeURLWithOptions", reflect.TypeOf((*MockAnalyzer)(nil).AnalyzeURLWithOptions), ctx, url, opts)
+}
+
+// MockFetcherStrategy is a mock of FetcherStrategy interface.
+type MockFetcherStrategy struct {
+	ctrl     *gomock.Controller
+	recorder *MockFetcherStrategyMockRecorder
+}
+
+// MockFetcherStrategyMockRecorder is the mock recorder for MockFetcherStrategy.
+type MockFetcherStrategyMockRecorder struct {
+	mock *MockFetcherStrategy
+}
+
+// NewMockFetcherStrategy creates a new mock instance.
+func NewMockFetcherStrategy(ctrl *gomock.Controller) *MockFetcherStrategy {
+	mock := &MockFetcherStrategy{ctrl: ctrl}
+	mock.recorder = &MockFetcherStrategyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFetcherStrategy) EXPECT() *MockFetcherStrategyMockRecorder {
+	return m.recorder
+}
+
+// Fetch mocks base method.
+func (m *MockFetcherStrategy) Fetch(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Fetch", ctx, url)
+	ret0, _ := ret[0].(*models.HTTPResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Fetch indicates an expected call of Fetch.
+func (mr *MockFetcherStrategyMockRecorder) Fetch(ctx, url interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fetch", reflect.TypeOf((*MockFetcherStrategy)(nil).Fetch), ctx, url)
+}
+
 // MockHTMLParser is a mock of HTMLParser interface.
 type MockHTMLParser struct {
 	ctrl     *gomock.Controller
@@ -74,34 +127,6 @@ func (m *MockHTMLParser) EXPECT() *MockHTMLParserMockRecorder {
 	return m.recorder
 }
 
-// DetectHTMLVersion mocks base method.
-func (m *MockHTMLParser) DetectHTMLVersion(content []byte) string {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DetectHTMLVersion", content)
-	ret0, _ := ret[0].(string)
-	return ret0
-}
-
-// DetectHTMLVersion indicates an expected call of DetectHTMLVersion.
-func (mr *MockHTMLParserMockRecorder) DetectHTMLVersion(content interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetectHTMLVersion", reflect.TypeOf((*MockHTMLParser)(nil).DetectHTMLVersion), content)
-}
-
-// ExtractTitle mocks base method.
-func (m *MockHTMLParser) ExtractTitle(content []byte) string {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ExtractTitle", content)
-	ret0, _ := ret[0].(string)
-	return ret0
-}
-
-// ExtractTitle indicates an expected call of ExtractTitle.
-func (mr *MockHTMLParserMockRecorder) ExtractTitle(content interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtractTitle", reflect.TypeOf((*MockHTMLParser)(nil).ExtractTitle), content)
-}
-
 // ParseHTML mocks base method.
 func (m *MockHTMLParser) ParseHTML(ctx context.Context, content []byte, baseURL string) (*models.ParsedHTML, error) {
 	m.ctrl.T.Helper()
@@ -169,6 +194,72 @@ func (mr *MockLinkCheckerMockRecorder) CheckLinks(ctx, links interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckLinks", reflect.TypeOf((*MockLinkChecker)(nil).CheckLinks), ctx, links)
 }
 
+// MockStreamingLinkChecker is a mock of StreamingLinkChecker interface.
+type MockStreamingLinkChecker struct {
+	ctrl     *gomock.Controller
+	recorder *MockStreamingLinkCheckerMockRecorder
+}
+
+// MockStreamingLinkCheckerMockRecorder is the mock recorder for MockStreamingLinkChecker.
+type MockStreamingLinkCheckerMockRecorder struct {
+	mock *MockStreamingLinkChecker
+}
+
+// NewMockStreamingLinkChecker creates a new mock instance.
+func NewMockStreamingLinkChecker(ctrl *gomock.Controller) *MockStreamingLinkChecker {
+	mock := &MockStreamingLinkChecker{ctrl: ctrl}
+	mock.recorder = &MockStreamingLinkCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStreamingLinkChecker) EXPECT() *MockStreamingLinkCheckerMockRecorder {
+	return m.recorder
+}
+
+// CheckLink mocks base method.
+func (m *MockStreamingLinkChecker) CheckLink(ctx context.Context, link models.Link) models.LinkStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckLink", ctx, link)
+	ret0, _ := ret[0].(models.LinkStatus)
+	return ret0
+}
+
+// CheckLink indicates an expected call of CheckLink.
+func (mr *MockStreamingLinkCheckerMockRecorder) CheckLink(ctx, link interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckLink", reflect.TypeOf((*MockStreamingLinkChecker)(nil).CheckLink), ctx, link)
+}
+
+// CheckLinks mocks base method.
+func (m *MockStreamingLinkChecker) CheckLinks(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckLinks", ctx, links)
+	ret0, _ := ret[0].([]models.LinkStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckLinks indicates an expected call of CheckLinks.
+func (mr *MockStreamingLinkCheckerMockRecorder) CheckLinks(ctx, links interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckLinks", reflect.TypeOf((*MockStreamingLinkChecker)(nil).CheckLinks), ctx, links)
+}
+
+// CheckLinksStream mocks base method.
+func (m *MockStreamingLinkChecker) CheckLinksStream(ctx context.Context, links []models.Link, emit func(models.LinkStatus)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckLinksStream", ctx, links, emit)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckLinksStream indicates an expected call of CheckLinksStream.
+func (mr *MockStreamingLinkCheckerMockRecorder) CheckLinksStream(ctx, links, emit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckLinksStream", reflect.TypeOf((*MockStreamingLinkChecker)(nil).CheckLinksStream), ctx, links, emit)
+}
+
 // MockHTTPClient is a mock of HTTPClient interface.
 type MockHTTPClient struct {
 	ctrl     *gomock.Controller
@@ -222,6 +313,141 @@ func (mr *MockHTTPClientMockRecorder) Head(ctx, url interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Head", reflect.TypeOf((*MockHTTPClient)(nil).Head), ctx, url)
 }
 
+// MockConditionalHTTPClient is a mock of ConditionalHTTPClient interface.
+type MockConditionalHTTPClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockConditionalHTTPClientMockRecorder
+}
+
+// MockConditionalHTTPClientMockRecorder is the mock recorder for MockConditionalHTTPClient.
+type MockConditionalHTTPClientMockRecorder struct {
+	mock *MockConditionalHTTPClient
+}
+
+// NewMockConditionalHTTPClient creates a new mock instance.
+func NewMockConditionalHTTPClient(ctrl *gomock.Controller) *MockConditionalHTTPClient {
+	mock := &MockConditionalHTTPClient{ctrl: ctrl}
+	mock.recorder = &MockConditionalHTTPClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockConditionalHTTPClient) EXPECT() *MockConditionalHTTPClientMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockConditionalHTTPClient) Get(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, url)
+	ret0, _ := ret[0].(*models.HTTPResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockConditionalHTTPClientMockRecorder) Get(ctx, url interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockConditionalHTTPClient)(nil).Get), ctx, url)
+}
+
+// GetConditional mocks base method.
+func (m *MockConditionalHTTPClient) GetConditional(ctx context.Context, url string, validators models.Validators) (*models.HTTPResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConditional", ctx, url, validators)
+	ret0, _ := ret[0].(*models.HTTPResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConditional indicates an expected call of GetConditional.
+func (mr *MockConditionalHTTPClientMockRecorder) GetConditional(ctx, url, validators interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConditional", reflect.TypeOf((*MockConditionalHTTPClient)(nil).GetConditional), ctx, url, validators)
+}
+
+// Head mocks base method.
+func (m *MockConditionalHTTPClient) Head(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Head", ctx, url)
+	ret0, _ := ret[0].(*models.HTTPResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Head indicates an expected call of Head.
+func (mr *MockConditionalHTTPClientMockRecorder) Head(ctx, url interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Head", reflect.TypeOf((*MockConditionalHTTPClient)(nil).Head), ctx, url)
+}
+
+// MockPreconnectingHTTPClient is a mock of PreconnectingHTTPClient interface.
+type MockPreconnectingHTTPClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockPreconnectingHTTPClientMockRecorder
+}
+
+// MockPreconnectingHTTPClientMockRecorder is the mock recorder for MockPreconnectingHTTPClient.
+type MockPreconnectingHTTPClientMockRecorder struct {
+	mock *MockPreconnectingHTTPClient
+}
+
+// NewMockPreconnectingHTTPClient creates a new mock instance.
+func NewMockPreconnectingHTTPClient(ctrl *gomock.Controller) *MockPreconnectingHTTPClient {
+	mock := &MockPreconnectingHTTPClient{ctrl: ctrl}
+	mock.recorder = &MockPreconnectingHTTPClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPreconnectingHTTPClient) EXPECT() *MockPreconnectingHTTPClientMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockPreconnectingHTTPClient) Get(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, url)
+	ret0, _ := ret[0].(*models.HTTPResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockPreconnectingHTTPClientMockRecorder) Get(ctx, url interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockPreconnectingHTTPClient)(nil).Get), ctx, url)
+}
+
+// Head mocks base method.
+func (m *MockPreconnectingHTTPClient) Head(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Head", ctx, url)
+	ret0, _ := ret[0].(*models.HTTPResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Head indicates an expected call of Head.
+func (mr *MockPreconnectingHTTPClientMockRecorder) Head(ctx, url interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Head", reflect.TypeOf((*MockPreconnectingHTTPClient)(nil).Head), ctx, url)
+}
+
+// Preconnect mocks base method.
+func (m *MockPreconnectingHTTPClient) Preconnect(ctx context.Context, url string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Preconnect", ctx, url)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Preconnect indicates an expected call of Preconnect.
+func (mr *MockPreconnectingHTTPClientMockRecorder) Preconnect(ctx, url interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Preconnect", reflect.TypeOf((*MockPreconnectingHTTPClient)(nil).Preconnect), ctx, url)
+}
+
 // MockLogger is a mock of Logger interface.
 type MockLogger struct {
 	ctrl     *gomock.Controller
@@ -354,6 +580,42 @@ func (m *MockMetricsCollector) EXPECT() *MockMetricsCollectorMockRecorder {
 	return m.recorder
 }
 
+// AddAnalysesInFlight mocks base method.
+func (m *MockMetricsCollector) AddAnalysesInFlight(delta int) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddAnalysesInFlight", delta)
+}
+
+// AddAnalysesInFlight indicates an expected call of AddAnalysesInFlight.
+func (mr *MockMetricsCollectorMockRecorder) AddAnalysesInFlight(delta interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAnalysesInFlight", reflect.TypeOf((*MockMetricsCollector)(nil).AddAnalysesInFlight), delta)
+}
+
+// AddLinkChecksActive mocks base method.
+func (m *MockMetricsCollector) AddLinkChecksActive(delta int) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddLinkChecksActive", delta)
+}
+
+// AddLinkChecksActive indicates an expected call of AddLinkChecksActive.
+func (mr *MockMetricsCollectorMockRecorder) AddLinkChecksActive(delta interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddLinkChecksActive", reflect.TypeOf((*MockMetricsCollector)(nil).AddLinkChecksActive), delta)
+}
+
+// AddLinkChecksQueued mocks base method.
+func (m *MockMetricsCollector) AddLinkChecksQueued(delta int) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddLinkChecksQueued", delta)
+}
+
+// AddLinkChecksQueued indicates an expected call of AddLinkChecksQueued.
+func (mr *MockMetricsCollectorMockRecorder) AddLinkChecksQueued(delta interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddLinkChecksQueued", reflect.TypeOf((*MockMetricsCollector)(nil).AddLinkChecksQueued), delta)
+}
+
 // RecordAnalysis mocks base method.
 func (m *MockMetricsCollector) RecordAnalysis(success bool, duration float64) {
 	m.ctrl.T.Helper()
@@ -366,6 +628,66 @@ func (mr *MockMetricsCollectorMockRecorder) RecordAnalysis(success, duration int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordAnalysis", reflect.TypeOf((*MockMetricsCollector)(nil).RecordAnalysis), success, duration)
 }
 
+// RecordAnalysisFailure mocks base method.
+func (m *MockMetricsCollector) RecordAnalysisFailure(cause string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RecordAnalysisFailure", cause)
+}
+
+// RecordAnalysisFailure indicates an expected call of RecordAnalysisFailure.
+func (mr *MockMetricsCollectorMockRecorder) RecordAnalysisFailure(cause interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordAnalysisFailure", reflect.TypeOf((*MockMetricsCollector)(nil).RecordAnalysisFailure), cause)
+}
+
+// RecordCacheLookup mocks base method.
+func (m *MockMetricsCollector) RecordCacheLookup(hit bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RecordCacheLookup", hit)
+}
+
+// RecordCacheLookup indicates an expected call of RecordCacheLookup.
+func (mr *MockMetricsCollectorMockRecorder) RecordCacheLookup(hit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordCacheLookup", reflect.TypeOf((*MockMetricsCollector)(nil).RecordCacheLookup), hit)
+}
+
+// RecordCallerBytes mocks base method.
+func (m *MockMetricsCollector) RecordCallerBytes(caller string, bytes int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RecordCallerBytes", caller, bytes)
+}
+
+// RecordCallerBytes indicates an expected call of RecordCallerBytes.
+func (mr *MockMetricsCollectorMockRecorder) RecordCallerBytes(caller, bytes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordCallerBytes", reflect.TypeOf((*MockMetricsCollector)(nil).RecordCallerBytes), caller, bytes)
+}
+
+// RecordCoalescedAnalysis mocks base method.
+func (m *MockMetricsCollector) RecordCoalescedAnalysis() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RecordCoalescedAnalysis")
+}
+
+// RecordCoalescedAnalysis indicates an expected call of RecordCoalescedAnalysis.
+func (mr *MockMetricsCollectorMockRecorder) RecordCoalescedAnalysis() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordCoalescedAnalysis", reflect.TypeOf((*MockMetricsCollector)(nil).RecordCoalescedAnalysis))
+}
+
+// RecordDownloadedBytes mocks base method.
+func (m *MockMetricsCollector) RecordDownloadedBytes(bytes int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RecordDownloadedBytes", bytes)
+}
+
+// RecordDownloadedBytes indicates an expected call of RecordDownloadedBytes.
+func (mr *MockMetricsCollectorMockRecorder) RecordDownloadedBytes(bytes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordDownloadedBytes", reflect.TypeOf((*MockMetricsCollector)(nil).RecordDownloadedBytes), bytes)
+}
+
 // RecordLinkCheck mocks base method.
 func (m *MockMetricsCollector) RecordLinkCheck(success bool, duration float64) {
 	m.ctrl.T.Helper()
@@ -378,16 +700,149 @@ func (mr *MockMetricsCollectorMockRecorder) RecordLinkCheck(success, duration in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordLinkCheck", reflect.TypeOf((*MockMetricsCollector)(nil).RecordLinkCheck), success, duration)
 }
 
+// RecordLinkCheckHedge mocks base method.
+func (m *MockMetricsCollector) RecordLinkCheckHedge(won bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RecordLinkCheckHedge", won)
+}
+
+// RecordLinkCheckHedge indicates an expected call of RecordLinkCheckHedge.
+func (mr *MockMetricsCollectorMockRecorder) RecordLinkCheckHedge(won interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordLinkCheckHedge", reflect.TypeOf((*MockMetricsCollector)(nil).RecordLinkCheckHedge), won)
+}
+
+// RecordMirror mocks base method.
+func (m *MockMetricsCollector) RecordMirror(outcome string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RecordMirror", outcome)
+}
+
+// RecordMirror indicates an expected call of RecordMirror.
+func (mr *MockMetricsCollectorMockRecorder) RecordMirror(outcome interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordMirror", reflect.TypeOf((*MockMetricsCollector)(nil).RecordMirror), outcome)
+}
+
 // RecordRequest mocks base method.
-func (m *MockMetricsCollector) RecordRequest(method, path string, statusCode int, duration float64) {
+func (m *MockMetricsCollector) RecordRequest(method, path string, statusCode int, duration float64, requestID string) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "RecordRequest", method, path, statusCode, duration)
+	m.ctrl.Call(m, "RecordRequest", method, path, statusCode, duration, requestID)
 }
 
 // RecordRequest indicates an expected call of RecordRequest.
-func (mr *MockMetricsCollectorMockRecorder) RecordRequest(method, path, statusCode, duration interface{}) *gomock.Call {
+func (mr *MockMetricsCollectorMockRecorder) RecordRequest(method, path, statusCode, duration, requestID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordRequest", reflect.TypeOf((*MockMetricsCollector)(nil).RecordRequest), method, path, statusCode, duration, requestID)
+}
+
+// RecordScreenshot mocks base method.
+func (m *MockMetricsCollector) RecordScreenshot(success bool, duration float64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RecordScreenshot", success, duration)
+}
+
+// RecordScreenshot indicates an expected call of RecordScreenshot.
+func (mr *MockMetricsCollectorMockRecorder) RecordScreenshot(success, duration interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordScreenshot", reflect.TypeOf((*MockMetricsCollector)(nil).RecordScreenshot), success, duration)
+}
+
+// RecordStage mocks base method.
+func (m *MockMetricsCollector) RecordStage(name string, seconds float64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RecordStage", name, seconds)
+}
+
+// RecordStage indicates an expected call of RecordStage.
+func (mr *MockMetricsCollectorMockRecorder) RecordStage(name, seconds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordStage", reflect.TypeOf((*MockMetricsCollector)(nil).RecordStage), name, seconds)
+}
+
+// RecordUpstreamRetry mocks base method.
+func (m *MockMetricsCollector) RecordUpstreamRetry(upstream, reason string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RecordUpstreamRetry", upstream, reason)
+}
+
+// RecordUpstreamRetry indicates an expected call of RecordUpstreamRetry.
+func (mr *MockMetricsCollectorMockRecorder) RecordUpstreamRetry(upstream, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordUpstreamRetry", reflect.TypeOf((*MockMetricsCollector)(nil).RecordUpstreamRetry), upstream, reason)
+}
+
+// MockFailureTracker is a mock of FailureTracker interface.
+type MockFailureTracker struct {
+	ctrl     *gomock.Controller
+	recorder *MockFailureTrackerMockRecorder
+}
+
+// MockFailureTrackerMockRecorder is the mock recorder for MockFailureTracker.
+type MockFailureTrackerMockRecorder struct {
+	mock *MockFailureTracker
+}
+
+// NewMockFailureTracker creates a new mock instance.
+func NewMockFailureTracker(ctrl *gomock.Controller) *MockFailureTracker {
+	mock := &MockFailureTracker{ctrl: ctrl}
+	mock.recorder = &MockFailureTrackerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFailureTracker) EXPECT() *MockFailureTrackerMockRecorder {
+	return m.recorder
+}
+
+// RecordHostFailure mocks base method.
+func (m *MockFailureTracker) RecordHostFailure(host, cause string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RecordHostFailure", host, cause)
+}
+
+// RecordHostFailure indicates an expected call of RecordHostFailure.
+func (mr *MockFailureTrackerMockRecorder) RecordHostFailure(host, cause interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordHostFailure", reflect.TypeOf((*MockFailureTracker)(nil).RecordHostFailure), host, cause)
+}
+
+// MockScreenshotCapturer is a mock of ScreenshotCapturer interface.
+type MockScreenshotCapturer struct {
+	ctrl     *gomock.Controller
+	recorder *MockScreenshotCapturerMockRecorder
+}
+
+// MockScreenshotCapturerMockRecorder is the mock recorder for MockScreenshotCapturer.
+type MockScreenshotCapturerMockRecorder struct {
+	mock *MockScreenshotCapturer
+}
+
+// NewMockScreenshotCapturer creates a new mock instance.
+func NewMockScreenshotCapturer(ctrl *gomock.Controller) *MockScreenshotCapturer {
+	mock := &MockScreenshotCapturer{ctrl: ctrl}
+	mock.recorder = &MockScreenshotCapturerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockScreenshotCapturer) EXPECT() *MockScreenshotCapturerMockRecorder {
+	return m.recorder
+}
+
+// CaptureScreenshot mocks base method.
+func (m *MockScreenshotCapturer) CaptureScreenshot(ctx context.Context, url string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureScreenshot", ctx, url)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureScreenshot indicates an expected call of CaptureScreenshot.
+func (mr *MockScreenshotCapturerMockRecorder) CaptureScreenshot(ctx, url interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordRequest", reflect.TypeOf((*MockMetricsCollector)(nil).RecordRequest), method, path, statusCode, duration)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureScreenshot", reflect.TypeOf((*MockScreenshotCapturer)(nil).CaptureScreenshot), ctx, url)
 }
 
 // MockCache is a mock of Cache interface.
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+var goldenTime = time.Date(2025, 3, 14, 15, 9, 26, 535000000, time.UTC)
+
+// assertGolden compares the JSON encoding of v with testdata/<name>.golden.json
+func assertGolden(t *testing.T, name string, v any) {
+	t.Helper()
+
+	got, err := json.MarshalIndent(v, "", "  ")
+	require.NoError(t, err)
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", name+".golden.json")
+	if *update {
+		require.NoError(t, os.MkdirAll("testdata", 0755))
+		require.NoError(t, os.WriteFile(path, got, 0644))
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "missing golden file, run go test ./pkg/models -update")
+	assert.Equal(t, string(want), string(got))
+}
+
+func TestGolden_AnalysisResult(t *testing.T) {
+	assertGolden(t, "analysis_result", AnalysisResult{
+		URL:          "https://example.com",
+		HTMLVersion:  "HTML5",
+		Title:        "Example Domain",
+		Headings:     HeadingCount{H1: 1, H2: 3},
+		Links:        LinkSummary{Internal: 4, External: 2, Inaccessible: 1, Total: 6},
+		HasLoginForm: true,
+		AnalyzedAt:   goldenTime,
+	})
+}
+
+func TestGolden_AnalysisResultTimings(t *testing.T) {
+	assertGolden(t, "analysis_result_timings", AnalysisResult{
+		URL:         "https://example.com",
+		HTMLVersion: "HTML5",
+		AnalyzedAt:  goldenTime,
+		Timings: &Timings{
+			FetchMs:                412.5,
+			HTMLVersionDetectionMs: 0.02,
+			ParseMs:                3.1,
+			LinkCheckMs:            1830,
+			TotalMs:                2246.4,
+		},
+	})
+}
+
+func TestGolden_AnalysisResultWarnings(t *testing.T) {
+	assertGolden(t, "analysis_result_warnings", AnalysisResult{
+		URL:         "https://example.com/old",
+		HTMLVersion: "HTML5",
+		Title:       "Example Domain",
+		Links:       LinkSummary{Internal: 2, Total: 2},
+		AnalyzedAt:  goldenTime,
+		Warnings: []Warning{
+			{
+				Code:    WarningRedirected,
+				Message: "The URL redirected, https://example.com/new was analyzed instead",
+				Context: map[string]string{"final_url": "https://example.com/new", "redirects": "1"},
+			},
+			{
+				Code:    WarningLinksUnchecked,
+				Message: "1 of 2 links were not checked, the link summary is incomplete",
+				Context: map[string]string{"unchecked": "1", "budget_exhausted": "1", "timed_out": "0", "not_reported": "0"},
+			},
+			{Code: WarningTruncatedBody, Message: "The page is too large, only its first 10485760 bytes were analyzed"},
+		},
+	})
+}
+
+func TestGolden_AnalysisResultLinkDurations(t *testing.T) {
+	assertGolden(t, "analysis_result_link_durations", AnalysisResult{
+		URL:         "https://example.com",
+		HTMLVersion: "HTML5",
+		Links: LinkSummary{
+			Internal: 3,
+			External: 1,
+			Total:    4,
+			SlowestLinks: []SlowLink{
+				{URL: "https://slow.example.net/", DurationMs: 2310.7},
+				{URL: "https://example.com/old", DurationMs: 96.2},
+			},
+			DurationP50Ms: 41.5,
+			DurationP95Ms: 2310.7,
+		},
+		AnalyzedAt: goldenTime,
+		RedirectedLinks: []RedirectedLink{
+			{URL: "https://example.com/old", FinalURL: "https://example.com/new", Redirects: 1, DurationMs: 96.2},
+		},
+	})
+}
+
+func TestGolden_AnalysisResultBrokenGroups(t *testing.T) {
+	assertGolden(t, "analysis_result_broken_groups", AnalysisResult{
+		URL:         "https://example.com",
+		HTMLVersion: "HTML5",
+		Links: LinkSummary{
+			Internal:     4,
+			Inaccessible: 3,
+			Total:        4,
+			BrokenGroups: []BrokenLinkGroup{{
+				Pattern:  "/blog/2021/*",
+				Count:    3,
+				Examples: []string{"https://example.com/blog/2021/a", "https://example.com/blog/2021/b", "https://example.com/blog/2021/c"},
+			}},
+		},
+		AnalyzedAt: goldenTime,
+	})
+}
+
+func TestGolden_AnalysisResultZeroTime(t *testing.T) {
+	assertGolden(t, "analysis_result_zero_time", AnalysisResult{
+		URL:         "https://example.com",
+		HTMLVersion: "HTML5",
+	})
+}
+
+func TestGolden_LinkStatus(t *testing.T) {
+	assertGolden(t, "link_status", LinkStatus{
+		Link:       Link{URL: "https://example.com/about", Text: "About", Type: LinkTypeInternal},
+		Accessible: true,
+		StatusCode: 200,
+		CheckedAt:  goldenTime,
+		DurationMs: 182.4,
+	})
+}
+
+func TestGolden_LinkStatusTransportError(t *testing.T) {
+	assertGolden(t, "link_status_transport_error", LinkStatus{
+		Link:       Link{URL: "https://unreachable.invalid", Type: LinkTypeExternal},
+		Accessible: false,
+		Error:      "request failed: no such host",
+		CheckedAt:  goldenTime,
+	})
+}
+
+func TestGolden_BatchAnalysisResult(t *testing.T) {
+	assertGolden(t, "batch_analysis_result", BatchAnalysisResult{
+		Results: []AnalysisResult{{
+			URL:         "https://example.com",
+			HTMLVersion: "HTML5",
+			Title:       "Example Domain",
+			Headings:    HeadingCount{H1: 1},
+			Links:       LinkSummary{Total: 1, External: 1},
+			AnalyzedAt:  goldenTime,
+		}},
+		Errors: []ErrorResponse{{
+			Error:      "analyzer service error (status 500): Failed to analyze URL",
+			StatusCode: 502,
+			Details:    "Failed to analyze: https://broken.example",
+			Timestamp:  goldenTime,
+		}},
+		TotalTime: 1500 * time.Millisecond,
+	})
+}
+
+func TestGolden_ErrorResponse(t *testing.T) {
+	assertGolden(t, "error_response", ErrorResponse{
+		Error:      "Invalid URL format",
+		StatusCode: 400,
+		Timestamp:  goldenTime,
+	})
+}
+
+func TestTimestampsEncodeAsRFC3339(t *testing.T) {
+	data, err := json.Marshal(ErrorResponse{Error: "x", StatusCode: 500, Timestamp: goldenTime})
+	require.NoError(t, err)
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	parsed, err := time.Parse(time.RFC3339, decoded["timestamp"].(string))
+	require.NoError(t, err)
+	assert.True(t, parsed.Equal(goldenTime))
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// CanonicalJSON is the serialization of r that ResultHash is taken over: r
+// as sent, less what changes from one run of the same analysis to the next.
+// Left out are the timestamps and durations (AnalyzedAt, Timings, the link
+// check durations and the upstream cache age), what the run spent,
+// downloaded or traced (Budget, Traffic, DebugTrace), the Screenshot, which the gateway moves to an
+// artifact, the raw ResponseHeaders, which carry Date, and ResultHash
+// itself. Map keys are sorted and struct fields keep their declared order,
+// so equal results serialize to equal bytes.
+func (r *AnalysisResult) CanonicalJSON() ([]byte, error) {
+	canonical := *r
+	canonical.AnalyzedAt = time.Time{}
+	canonical.Timings = nil
+	canonical.Budget = nil
+	canonical.Traffic = nil
+	canonical.DebugTrace = nil
+	canonical.Screenshot = ""
+	canonical.ResponseHeaders = nil
+	canonical.ResultHash = ""
+
+	canonical.Links.SlowestLinks = nil
+	canonical.Links.DurationP50Ms = 0
+	canonical.Links.DurationP95Ms = 0
+	if r.RedirectedLinks != nil {
+		canonical.RedirectedLinks = slices.Clone(r.RedirectedLinks)
+		for i := range canonical.RedirectedLinks {
+			canonical.RedirectedLinks[i].DurationMs = 0
+		}
+	}
+	if r.Cacheability != nil {
+		cacheability := *r.Cacheability
+		cacheability.AgeSeconds = 0
+		canonical.Cacheability = &cacheability
+	}
+	return json.Marshal(canonical)
+}
+
+// Hash is the hex SHA-256 of r's CanonicalJSON, the ResultHash of r
+func (r *AnalysisResult) Hash() (string, error) {
+	data, err := r.CanonicalJSON()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
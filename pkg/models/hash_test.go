@@ -0,0 +1,216 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hashedResult is a result with a field of every kind CanonicalJSON keeps
+// or leaves out
+func hashedResult() AnalysisResult {
+	return AnalysisResult{
+		URL:         "https://example.com",
+		HTMLVersion: "HTML5",
+		Title:       "Example Domain",
+		Headings:    HeadingCount{H1: 1, H2: 3},
+		Links: LinkSummary{
+			Internal: 4, External: 2, Inaccessible: 1, Total: 6, RedirectedLinks: 1,
+			Skipped:       map[string]int{LinkSkipFragmentOnly: 2, LinkSkipEmptyHref: 1},
+			SlowestLinks:  []SlowLink{{URL: "https://example.com/slow", DurationMs: 912.5}},
+			DurationP50Ms: 120,
+			DurationP95Ms: 912.5,
+		},
+		AnalyzedAt:      goldenTime,
+		Screenshot:      "data:image/png;base64,iVBORw0KGgo=",
+		Timings:         &Timings{FetchMs: 412.5, TotalMs: 2246.4},
+		Budget:          &BudgetUsage{Requests: 7, Bytes: 52311},
+		FinalURL:        "https://example.com/",
+		StatusCode:      200,
+		ResponseHeaders: map[string][]string{"Date": {"Fri, 14 Mar 2025 15:09:26 GMT"}},
+		RedirectedLinks: []RedirectedLink{{URL: "https://example.com/old", FinalURL: "https://example.com/new", Redirects: 1, DurationMs: 88}},
+		Cacheability:    &Cacheability{Cacheable: true, MaxAgeSeconds: 600, FreshnessSource: "max-age", AgeSeconds: 42},
+		Warnings: []Warning{{Code: WarningRedirected, Message: "The URL redirected",
+			Context: map[string]string{"final_url": "https://example.com/", "status": "301"}}},
+		FindingSummary: &FindingSummary{Total: 1,
+			ByCategory: map[string]int{CategorySEO: 1, CategoryContent: 0},
+			BySeverity: map[string]int{SeverityWarning: 1, SeverityError: 0}},
+		DebugTrace: &DebugTrace{},
+		ResultHash: "stale",
+	}
+}
+
+func TestGolden_AnalysisResultCanonical(t *testing.T) {
+	result := hashedResult()
+	canonical, err := result.CanonicalJSON()
+	require.NoError(t, err)
+	assertGolden(t, "analysis_result_canonical", json.RawMessage(canonical))
+
+	// The hash is pinned too: a change of the serialization changes every
+	// stored hash, and has to be deliberate
+	hash, err := result.Hash()
+	require.NoError(t, err)
+	assert.Equal(t, "c8dfd9c32aecf509d2b679973d6b8b2e166a748d6fcb8b402c6412963b167fc7", hash)
+}
+
+// randomResult builds a result from rng, its maps filled in a random order
+func randomResult(rng *rand.Rand) AnalysisResult {
+	pick := func(values ...string) string { return values[rng.IntN(len(values))] }
+	result := AnalysisResult{
+		URL:          fmt.Sprintf("https://%s.example/%d", pick("a", "b", "c"), rng.IntN(100)),
+		HTMLVersion:  pick("HTML5", "HTML 4.01 Strict", "XHTML 1.0 Transitional"),
+		Title:        pick("", "Home", "Über uns", "<script>"),
+		Headings:     HeadingCount{H1: rng.IntN(3), H2: rng.IntN(5), H6: rng.IntN(2)},
+		HasLoginForm: rng.IntN(2) == 0,
+		Links:        LinkSummary{Internal: rng.IntN(50), External: rng.IntN(50), Inaccessible: rng.IntN(5)},
+	}
+	result.Links.Total = result.Links.Internal + result.Links.External
+	reasons := []string{LinkSkipEmptyHref, LinkSkipFragmentOnly, LinkSkipUnsupportedScheme, LinkSkipParseError}
+	rng.Shuffle(len(reasons), func(i, j int) { reasons[i], reasons[j] = reasons[j], reasons[i] })
+	for _, reason := range reasons[:rng.IntN(len(reasons)+1)] {
+		if result.Links.Skipped == nil {
+			result.Links.Skipped = make(map[string]int)
+		}
+		result.Links.Skipped[reason] = 1 + rng.IntN(9)
+	}
+	for range rng.IntN(3) {
+		context := make(map[string]string)
+		for _, key := range []string{"final_url", "reason", "bytes"}[:rng.IntN(4)] {
+			context[key] = pick("x", "y", "z")
+		}
+		result.Warnings = append(result.Warnings, Warning{Code: pick(WarningRedirected, WarningTruncatedBody), Message: pick("m1", "m2"), Context: context})
+	}
+	if rng.IntN(2) == 0 {
+		result.RedirectedLinks = []RedirectedLink{{URL: "https://a.example/old", FinalURL: "https://a.example/new", Redirects: 1 + rng.IntN(3)}}
+	}
+	return result
+}
+
+// reordered copies result with every map rebuilt in another insertion
+// order, and the fields CanonicalJSON leaves out changed
+func reordered(rng *rand.Rand, result AnalysisResult) AnalysisResult {
+	reinsert := func(m map[string]int) map[string]int {
+		if m == nil {
+			return nil
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		rng.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+		out := make(map[string]int, len(m))
+		for _, k := range keys {
+			out[k] = m[k]
+		}
+		return out
+	}
+
+	twin := result
+	twin.Links.Skipped = reinsert(result.Links.Skipped)
+	twin.Warnings = nil
+	for _, warning := range result.Warnings {
+		context := make(map[string]string, len(warning.Context))
+		for k, v := range warning.Context {
+			context[k] = v
+		}
+		warning.Context = context
+		twin.Warnings = append(twin.Warnings, warning)
+	}
+	twin.RedirectedLinks = nil
+	for _, link := range result.RedirectedLinks {
+		link.DurationMs = rng.Float64() * 1000
+		twin.RedirectedLinks = append(twin.RedirectedLinks, link)
+	}
+
+	twin.AnalyzedAt = time.Unix(rng.Int64N(2e9), 0)
+	twin.Timings = &Timings{FetchMs: rng.Float64() * 1000, TotalMs: rng.Float64() * 5000}
+	twin.Budget = &BudgetUsage{Requests: rng.Int64N(100)}
+	twin.Screenshot = fmt.Sprintf("/api/v2/artifacts/%d", rng.IntN(1000))
+	twin.ResponseHeaders = map[string][]string{"Date": {twin.AnalyzedAt.Format(time.RFC1123)}}
+	twin.Links.SlowestLinks = []SlowLink{{URL: result.URL, DurationMs: rng.Float64() * 1000}}
+	twin.Links.DurationP50Ms = rng.Float64() * 100
+	twin.ResultHash = "stale"
+	return twin
+}
+
+func TestAnalysisResult_HashIgnoresRunDetails(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := range 500 {
+		result := randomResult(rng)
+		twin := reordered(rng, result)
+
+		want, err := result.Hash()
+		require.NoError(t, err)
+		got, err := twin.Hash()
+		require.NoError(t, err)
+		require.Equal(t, want, got, "case %d: equal results hash apart", i)
+		require.Len(t, got, 64)
+
+		// Whatever the result says about the page counts
+		twin.Links.Internal++
+		changed, err := twin.Hash()
+		require.NoError(t, err)
+		require.NotEqual(t, want, changed, "case %d: a different result hashes the same", i)
+	}
+}
+
+func TestAnalysisResult_HashCoversThePage(t *testing.T) {
+	base := hashedResult()
+	want, err := base.Hash()
+	require.NoError(t, err)
+
+	changes := map[string]func(*AnalysisResult){
+		"title":          func(r *AnalysisResult) { r.Title = "Other" },
+		"headings":       func(r *AnalysisResult) { r.Headings.H3 = 1 },
+		"skipped links":  func(r *AnalysisResult) { r.Links.Skipped[LinkSkipParseError] = 1 },
+		"final URL":      func(r *AnalysisResult) { r.FinalURL = "https://example.com/home" },
+		"status code":    func(r *AnalysisResult) { r.StatusCode = 203 },
+		"warning":        func(r *AnalysisResult) { r.Warnings[0].Context["status"] = "302" },
+		"redirected":     func(r *AnalysisResult) { r.RedirectedLinks[0].Redirects = 2 },
+		"cacheability":   func(r *AnalysisResult) { r.Cacheability.MaxAgeSeconds = 60 },
+		"finding counts": func(r *AnalysisResult) { r.FindingSummary.BySeverity[SeverityError] = 1 },
+	}
+	for name, change := range changes {
+		t.Run(name, func(t *testing.T) {
+			result := hashedResult()
+			change(&result)
+			got, err := result.Hash()
+			require.NoError(t, err)
+			assert.NotEqual(t, want, got)
+		})
+	}
+
+	// Hashing leaves the result as it was
+	result := hashedResult()
+	_, err = result.Hash()
+	require.NoError(t, err)
+	assert.Equal(t, hashedResult(), result)
+}
+
+func TestGolden_ParsedHTMLHeadings(t *testing.T) {
+	// However the levels were met, the encoding lists them in order
+	levels := []string{"h3", "h1", "h2"}
+	texts := map[string][]string{"h1": {"Title"}, "h2": {"First", "Second"}, "h3": {"Detail"}}
+	var encodings []string
+	for range 3 {
+		parsed := ParsedHTML{HTMLVersion: "HTML5", Headings: make(map[string][]string)}
+		for _, level := range levels {
+			parsed.Headings[level] = texts[level]
+		}
+		data, err := json.Marshal(parsed)
+		require.NoError(t, err)
+		encodings = append(encodings, string(data))
+		levels = append(levels[1:], levels[0])
+
+		if len(encodings) == 1 {
+			assertGolden(t, "parsed_html_headings", parsed)
+		}
+	}
+	assert.Equal(t, encodings[0], encodings[1])
+	assert.Equal(t, encodings[0], encodings[2])
+}
@@ -1,14 +1,152 @@
+// Package models defines the types shared between services. JSON field names
+// are snake_case; time.Time fields encode as RFC 3339 and are omitted when zero.
 package models
 
 import (
+	"fmt"
+	"math"
 	"net/http"
 	"time"
 )
 
 type AnalysisRequest struct {
 	URL string `json:"url" validate:"required,url"`
+	AnalysisOptions
 }
 
+// AnalysisOptions are opt-in, per-request analysis features
+type AnalysisOptions struct {
+	// Render loads the page in a headless browser so JavaScript-built content
+	// is analyzed, when the analyzer has rendering enabled
+	Render bool `json:"render,omitempty"`
+	// Screenshot attaches a thumbnail of the rendered page to the result.
+	// A failed capture never fails the analysis.
+	Screenshot bool `json:"screenshot,omitempty"`
+	// FollowFrames fetches the page's frame and iframe documents, up to the
+	// analyzer's frame limit, and counts their headings and links too
+	FollowFrames bool `json:"follow_frames,omitempty"`
+	// CheckHreflangReciprocal fetches each hreflang alternate and reports
+	// those that do not link back to the page
+	CheckHreflangReciprocal bool `json:"check_hreflang_reciprocal,omitempty"`
+	// FollowPagination follows the page's rel=next links, up to the
+	// analyzer's hop limit, to count the pages of its series
+	FollowPagination bool `json:"follow_pagination,omitempty"`
+	// ReportRedirectedLinks lists the internal links that redirect, so they
+	// can be updated to point at their final URL
+	ReportRedirectedLinks bool `json:"report_redirected_links,omitempty"`
+	// Debug attaches the outbound requests the analysis made, when the
+	// analyzer has debug traces enabled
+	Debug bool `json:"debug,omitempty"`
+	// AcceptLanguage is sent as the Accept-Language of the page fetch, to
+	// analyze one language variant of a localized page; empty sends
+	// DefaultAcceptLanguage. See ValidateAcceptLanguage.
+	AcceptLanguage string `json:"accept_language,omitempty"`
+	// Rules picks the checks that run, by name; unset runs the analyzer's
+	// default set. See pkg/rules.
+	Rules RuleSelection `json:"rules,omitzero"`
+	// Checks are assertions about the page source, each reported as passed
+	// or failed in the result. See ValidateChecks.
+	Checks []CustomCheck `json:"checks,omitempty"`
+	// LinkCheck tunes how the links of this analysis are checked; unset
+	// uses the link checker's defaults. See ValidateLinkCheckOptions.
+	LinkCheck *LinkCheckOptions `json:"link_check,omitempty"`
+	// IncludeHeaders attaches the status code and headers of the page's
+	// final response to the result, with cookie values redacted
+	IncludeHeaders bool `json:"include_headers,omitempty"`
+	// HostOverrides maps host names to the IP addresses the page and its
+	// links are fetched from instead of those the names resolve to, for a
+	// site behind a DNS mapping that is not public, such as a staging
+	// host. The analyzer accepts only the hosts it is configured to. See
+	// ValidateHostOverrides.
+	HostOverrides map[string]string `json:"host_overrides,omitempty"`
+	// StrictAuth fails the analysis of a page that answers 401 or 407 with
+	// an HTTP error, instead of returning a result with RequiresAuth
+	StrictAuth bool `json:"strict_auth,omitempty"`
+}
+
+// RuleSelection picks the checks of an analysis. A non-empty Include runs
+// only those rules, otherwise the analyzer's defaults run; Exclude then
+// drops rules from either.
+type RuleSelection struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// IsZero reports whether s leaves the analyzer's defaults unchanged
+func (s RuleSelection) IsZero() bool {
+	return len(s.Include) == 0 && len(s.Exclude) == 0
+}
+
+// Custom check types
+const (
+	// CheckContains passes when the page contains the pattern
+	CheckContains = "contains"
+	// CheckNotContains passes when the page does not contain the pattern
+	CheckNotContains = "not_contains"
+	// CheckRegex passes when the regular expression matches the page
+	CheckRegex = "regex"
+	// CheckCSSSelectorExists passes when an element matches the CSS
+	// selector. It is reserved for when the analyzer has a selector engine
+	// and is reported as failed with an error until then.
+	CheckCSSSelectorExists = "css_selector_exists"
+)
+
+// CustomCheck is an assertion about the decoded source of the page. A
+// required check that fails, or cannot be evaluated, sets
+// AnalysisResult.ChecksFailed.
+type CustomCheck struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Pattern string `json:"pattern"`
+	// Optional checks are reported but never set ChecksFailed
+	Optional bool `json:"optional,omitempty"`
+}
+
+// CheckResult is the outcome of one CustomCheck
+type CheckResult struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Passed   bool   `json:"passed"`
+	Optional bool   `json:"optional,omitempty"`
+	// Matches counts the occurrences of the pattern in the page, up to
+	// MaxCheckMatches
+	Matches int `json:"matches"`
+	// Error says why the check could not be evaluated, such as an invalid
+	// regular expression; such a check has failed
+	Error string `json:"error,omitempty"`
+}
+
+// Link check methods
+const (
+	// LinkCheckGet requests each link with GET, the default
+	LinkCheckGet = "get"
+	// LinkCheckHead requests each link with HEAD only
+	LinkCheckHead = "head"
+	// LinkCheckHeadThenGet requests each link with HEAD and falls back to
+	// GET when that fails, for servers that do not answer HEAD properly
+	LinkCheckHeadThenGet = "head_then_get"
+)
+
+// LinkCheckOptions tune the checks of one batch of links. Zero fields keep
+// the link checker's defaults.
+type LinkCheckOptions struct {
+	// PerLinkTimeoutMs bounds each attempt at one link
+	PerLinkTimeoutMs int64 `json:"per_link_timeout_ms,omitempty"`
+	// Method is one of the link check methods; empty is LinkCheckGet
+	Method string `json:"method,omitempty"`
+	// Retries is how many more times a link is tried after a network error,
+	// a 429 or a 5xx
+	Retries int `json:"retries,omitempty"`
+	// MaxRedirects is how many redirects a check follows
+	MaxRedirects int `json:"max_redirects,omitempty"`
+	// AcceptableStatusCodes count as accessible, on top of 2xx and 3xx,
+	// such as the 403 of a site that turns crawlers away
+	AcceptableStatusCodes []int `json:"acceptable_status_codes,omitempty"`
+}
+
+// DefaultAcceptLanguage is the Accept-Language of a fetch that names none
+const DefaultAcceptLanguage = "en-US,en;q=0.9"
+
 // AnalysisResult represents the complete analysis result
 type AnalysisResult struct {
 	URL          string       `json:"url"`
@@ -17,7 +155,555 @@ type AnalysisResult struct {
 	Headings     HeadingCount `json:"headings"`
 	Links        LinkSummary  `json:"links"`
 	HasLoginForm bool         `json:"has_login_form"`
-	AnalyzedAt   time.Time    `json:"analyzed_at"`
+	AnalyzedAt   time.Time    `json:"analyzed_at,omitzero"`
+	// Screenshot is a PNG thumbnail, usable directly as an image source: a
+	// data: URI from the analyzer, or an artifact URL once the gateway has
+	// stored it
+	Screenshot string `json:"screenshot,omitempty"`
+	// Timings is how long each analysis stage took
+	Timings *Timings `json:"timings,omitempty"`
+	// Budget is the outbound traffic the analysis spent against its budget
+	Budget *BudgetUsage `json:"budget,omitempty"`
+	// Traffic is what the analysis downloaded, the link checks included
+	Traffic *Traffic `json:"traffic,omitempty"`
+	// FinalURL is the page URL after redirects
+	FinalURL string `json:"final_url,omitempty"`
+	// DisplayURL is URL with its internationalized host in Unicode form,
+	// for showing to people; it is omitted when the host is plain ASCII
+	DisplayURL string `json:"display_url,omitempty"`
+	// IDNHosts are the internationalized hosts of the page and its links in
+	// both forms, sorted by their ASCII form
+	IDNHosts []IDNHost `json:"idn_hosts,omitempty"`
+	// RequiresAuth is set when the page asked for authentication instead of
+	// serving its content, which is then not analyzed: the result has none
+	// of the content sections, only the AUTH_REQUIRED finding
+	RequiresAuth *AuthRequirement `json:"requires_auth,omitempty"`
+	// StatusCode and ResponseHeaders are those of the page's final
+	// response, when AnalysisOptions.IncludeHeaders asks for them. Header
+	// names are canonical and every value is kept, in order; Set-Cookie
+	// values are cut down to the cookie name.
+	StatusCode      int                 `json:"status_code,omitempty"`
+	ResponseHeaders map[string][]string `json:"response_headers,omitempty"`
+	// AcceptLanguage is the Accept-Language the page was fetched with, which
+	// tells the language variant analyzed
+	AcceptLanguage string `json:"accept_language,omitempty"`
+	// CanonicalURL is the URL the page declares canonical, if any
+	CanonicalURL string `json:"canonical_url,omitempty"`
+	// HasFrames is set for pages with frames or iframes, whose content is
+	// only counted when frames are followed
+	HasFrames bool    `json:"has_frames,omitempty"`
+	Frames    []Frame `json:"frames,omitempty"`
+	// Titles describes the page's title elements, for the title rule
+	Titles *TitleReport `json:"titles,omitempty"`
+	// Hreflang reports the page's language alternates and their problems
+	Hreflang *HreflangReport `json:"hreflang,omitempty"`
+	// AMP relates the page to its AMP variant, or an AMP page to its
+	// canonical page; it is omitted when the page has neither
+	AMP *AMPReport `json:"amp,omitempty"`
+	// Pagination places the page in the series it declares with rel=next
+	// and rel=prev links; it is omitted when the page declares none
+	Pagination *PaginationReport `json:"pagination,omitempty"`
+	// Anchors lists the duplicate ids of the page and its in-page links to
+	// ids it does not have; it is omitted when there are neither
+	Anchors *AnchorReport `json:"anchors,omitempty"`
+	// RedirectedLinks are the internal links that redirect, when requested
+	RedirectedLinks []RedirectedLink `json:"redirected_links,omitempty"`
+	// LinkFindings reports the rel and target attributes of the page's
+	// links; it is omitted when no link has one worth reporting
+	LinkFindings *LinkFindings `json:"link_findings,omitempty"`
+	// Accessibility lists the page's accessibility problems; it is omitted
+	// when none are found
+	Accessibility *AccessibilityReport `json:"accessibility,omitempty"`
+	// Content measures the page's visible text
+	Content *ContentReport `json:"content,omitempty"`
+	// Robots is what the page tells search engine crawlers; it is omitted
+	// when it tells them nothing, which leaves it indexable
+	Robots *RobotsReport `json:"robots,omitempty"`
+	// Cacheability summarizes the page's caching headers; it is omitted
+	// when the fetch reported no headers
+	Cacheability *Cacheability `json:"cacheability,omitempty"`
+	// Technology hints at what the site is built with; it is omitted when
+	// the page names nothing
+	Technology *TechnologyHints `json:"technology,omitempty"`
+	// DebugTrace lists the outbound requests of a debug analysis
+	DebugTrace *DebugTrace `json:"debug_trace,omitempty"`
+	// Warnings are the soft issues met during the analysis, in the order
+	// met; the result stands but should be read with them in mind
+	Warnings []Warning `json:"warnings,omitempty"`
+	// Findings are the problems of the page, gathered from the sections
+	// above in one machine-readable list; FindingSummary counts them
+	Findings       []Finding       `json:"findings,omitempty"`
+	FindingSummary *FindingSummary `json:"finding_summary,omitempty"`
+	// Rules are the checks that ran, see AnalysisOptions.Rules; the sections
+	// of the others are left out
+	Rules []string `json:"rules,omitempty"`
+	// URLNormalization is the policy under which differently spelled links
+	// counted as the same URL, for Links.Unique and the link checks
+	URLNormalization *URLNormalization `json:"url_normalization,omitempty"`
+	// Checks are the outcomes of AnalysisOptions.Checks, in their order;
+	// ChecksFailed is set when a required one failed
+	Checks       []CheckResult `json:"checks,omitempty"`
+	ChecksFailed bool          `json:"checks_failed,omitempty"`
+	// ResultHash identifies the outcome of the analysis: results that tell
+	// the same about a page have the same hash, whenever they were made.
+	// See CanonicalJSON for what it covers.
+	ResultHash string `json:"result_hash,omitempty"`
+}
+
+// AuthRequirement is the authentication a page asked for: a 401 from the
+// page's server, or a 407 from a proxy on the way to it
+type AuthRequirement struct {
+	StatusCode int `json:"status_code"`
+	// Proxy is set for a 407, whose challenges are the proxy's
+	Proxy bool `json:"proxy,omitempty"`
+	// Scheme and Realm are those of the first challenge, such as Basic and
+	// the name of the protected area; both are empty when the response
+	// carried no challenge
+	Scheme string `json:"scheme,omitempty"`
+	Realm  string `json:"realm,omitempty"`
+	// Challenges are all the schemes offered, in the order offered
+	Challenges []AuthChallenge `json:"challenges,omitempty"`
+}
+
+// AuthChallenge is one challenge of a WWW-Authenticate or Proxy-Authenticate
+// header. Well-known schemes are spelled as registered, such as Basic,
+// Bearer, Digest, Negotiate and NTLM; others as sent.
+type AuthChallenge struct {
+	Scheme string `json:"scheme"`
+	Realm  string `json:"realm,omitempty"`
+}
+
+// IDNHost is an internationalized domain name in its Unicode form, such as
+// bücher.example, and in the ASCII (punycode) form it is fetched and
+// compared by, such as xn--bcher-kva.example
+type IDNHost struct {
+	Unicode string `json:"unicode"`
+	ASCII   string `json:"ascii"`
+}
+
+// Finding categories
+const (
+	CategorySEO           = "seo"
+	CategoryAccessibility = "accessibility"
+	CategorySecurity      = "security"
+	CategoryContent       = "content"
+	CategoryPerformance   = "performance"
+)
+
+// Finding severities, from the least severe
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// Finding is one problem of a page. ID is stable across releases, such as
+// HEADINGS_MULTIPLE_H1, and pkg/findings registers each with its Category
+// and Severity; Message explains it for this page.
+type Finding struct {
+	ID       string          `json:"id"`
+	Category string          `json:"category"`
+	Severity string          `json:"severity"`
+	Message  string          `json:"message"`
+	Evidence FindingEvidence `json:"evidence,omitzero"`
+}
+
+// FindingEvidence points at what a finding is about: the URLs concerned, up
+// to a cap, CSS selectors of the elements, how many there are, and values
+// such as link texts or language codes
+type FindingEvidence struct {
+	URLs      []string `json:"urls,omitempty"`
+	Selectors []string `json:"selectors,omitempty"`
+	Count     int      `json:"count,omitempty"`
+	Values    []string `json:"values,omitempty"`
+}
+
+// FindingSummary counts the findings of a result by category and severity
+type FindingSummary struct {
+	Total      int            `json:"total"`
+	ByCategory map[string]int `json:"by_category"`
+	BySeverity map[string]int `json:"by_severity"`
+}
+
+// Warning codes. A new kind of soft issue only needs a code here and a
+// place in the analyzer that adds it.
+const (
+	// WarningTruncatedBody: the page was larger than the fetch limit and
+	// only its beginning was analyzed
+	WarningTruncatedBody = "truncated_body"
+	// WarningLinksUnchecked: some links were not checked, for lack of
+	// budget or time, or because the link checker failed
+	WarningLinksUnchecked = "links_unchecked"
+	// WarningCharsetFallback: the page is not in UTF-8, or does not say
+	// what it is in, and was read as UTF-8 regardless
+	WarningCharsetFallback = "charset_fallback"
+	// WarningRedirected: the URL redirected and the page it led to was
+	// analyzed instead
+	WarningRedirected = "redirected"
+	// WarningCollectionTruncated: the page exceeded the parser limits and
+	// some of its links, headings, texts or anchors were left out
+	WarningCollectionTruncated = "collection_truncated"
+	// WarningResultTrimmed: the result was too large to send in full and
+	// its per-link details were left out
+	WarningResultTrimmed = "result_trimmed"
+	// WarningResultSummarized: the result was too large to send even
+	// without its per-link details, and only its summary was sent
+	WarningResultSummarized = "result_summarized"
+)
+
+// Warning is one soft issue of an analysis: Code is one of the Warning*
+// constants, Message explains it to a person, and Context holds the details
+// that depend on the page, such as counts or URLs
+type Warning struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Context map[string]string `json:"context,omitempty"`
+}
+
+// Cacheability is whether, and for how long, caches may store a page
+type Cacheability struct {
+	// Cacheable is set when a browser cache may store the page, and
+	// SharedCacheable when shared caches such as CDNs may as well
+	Cacheable       bool `json:"cacheable"`
+	SharedCacheable bool `json:"shared_cacheable"`
+	// MaxAgeSeconds is how long caches may reuse the page without
+	// revalidating it. FreshnessSource is where that comes from: max-age,
+	// expires or heuristic; it is empty when the page has no lifetime.
+	MaxAgeSeconds   int64  `json:"max_age_seconds"`
+	FreshnessSource string `json:"freshness_source,omitempty"`
+	// SharedMaxAgeSeconds is the s-maxage lifetime shared caches use instead
+	SharedMaxAgeSeconds *int64 `json:"shared_max_age_seconds,omitempty"`
+	// AgeSeconds is how long the page had been cached upstream when fetched
+	AgeSeconds int64 `json:"age_seconds,omitempty"`
+	// Cache-Control directives; NoCache and Private are only set when
+	// they apply to the whole page rather than to named fields
+	NoStore        bool `json:"no_store,omitempty"`
+	NoCache        bool `json:"no_cache,omitempty"`
+	Private        bool `json:"private,omitempty"`
+	Public         bool `json:"public,omitempty"`
+	MustRevalidate bool `json:"must_revalidate,omitempty"`
+	Immutable      bool `json:"immutable,omitempty"`
+	// Validators and Vary fields, as sent
+	ETag         string   `json:"etag,omitempty"`
+	LastModified string   `json:"last_modified,omitempty"`
+	Vary         []string `json:"vary,omitempty"`
+	// Notes explain the verdict, e.g. why the page is not cacheable
+	Notes []string `json:"notes,omitempty"`
+}
+
+// TechnologyHints are what a page says about the software serving it: the
+// HTTP protocol of its response, its Server and X-Powered-By headers and its
+// <meta name="generator">, cut to a bounded length. Products are the known
+// products these name, by major version when they give one, such as
+// "WordPress 6.x" or "nginx". Absent values are omitted.
+type TechnologyHints struct {
+	Protocol  string   `json:"protocol,omitempty"`
+	Server    string   `json:"server,omitempty"`
+	PoweredBy string   `json:"powered_by,omitempty"`
+	Generator string   `json:"generator,omitempty"`
+	Products  []string `json:"products,omitempty"`
+}
+
+// LinkFindings counts the links whose rel and target attributes matter for
+// search engines and security, and lists the problems found with them
+type LinkFindings struct {
+	// External links marked rel="nofollow", "sponsored" or "ugc"
+	NofollowExternal  int `json:"nofollow_external"`
+	SponsoredExternal int `json:"sponsored_external"`
+	UGCExternal       int `json:"ugc_external"`
+	// NewTab counts the links with target="_blank"
+	NewTab   int           `json:"new_tab"`
+	Findings []LinkFinding `json:"findings,omitempty"`
+}
+
+// Link finding kinds
+const (
+	// LinkMissingNoopener flags target="_blank" links without rel="noopener"
+	// or "noreferrer", which hand the opened page a window.opener handle
+	LinkMissingNoopener = "missing_noopener"
+)
+
+// Link text finding kinds, after the links screen reader users cannot tell
+// apart out of context
+const (
+	// LinkTextEmpty flags links without an accessible name, such as
+	// icon-only links without alt text or aria-label
+	LinkTextEmpty = "empty_link_text"
+	// LinkTextGeneric flags links whose text says nothing about where they
+	// lead, such as "click here" or "read more"
+	LinkTextGeneric = "generic_link_text"
+	// LinkTextAmbiguous flags links sharing their text with links to other
+	// URLs
+	LinkTextAmbiguous = "ambiguous_link_text"
+)
+
+// LinkFinding is one problem with a page's links. Count is how many links
+// have it; URLs lists them, each once, up to a cap, and is left out of a
+// result too large to send with them. Texts lists the link texts
+// concerned, for the link text findings that have one.
+type LinkFinding struct {
+	Kind  string   `json:"kind"`
+	Count int      `json:"count"`
+	URLs  []string `json:"urls,omitempty"`
+	Texts []string `json:"texts,omitempty"`
+}
+
+// AccessibilityReport lists the accessibility problems of a page
+type AccessibilityReport struct {
+	// LinkText flags the links whose text does not say where they lead
+	LinkText []LinkFinding `json:"link_text,omitempty"`
+}
+
+// Content finding kinds
+const (
+	// ContentLanguageMismatch flags text detected in another language than
+	// the lang attribute of <html> declares
+	ContentLanguageMismatch = "language_mismatch"
+)
+
+// ContentReport measures the visible text of a page and cross-checks its
+// language with the one declared
+type ContentReport struct {
+	TextStats
+	// DeclaredLanguage is the lang attribute of <html>, as written
+	DeclaredLanguage string           `json:"declared_language,omitempty"`
+	Findings         []ContentFinding `json:"findings,omitempty"`
+}
+
+// ContentFinding is one problem with the text of a page
+type ContentFinding struct {
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Where robots directives come from
+const (
+	// RobotsSourceMeta is <meta name="robots"> or <meta name="googlebot">
+	RobotsSourceMeta = "meta"
+	// RobotsSourceHeader is the X-Robots-Tag response header
+	RobotsSourceHeader = "header"
+)
+
+// RobotsReport is what a page tells search engine crawlers in its robots
+// meta tags and X-Robots-Tag headers
+type RobotsReport struct {
+	// Indexable and Followable are false when a directive for all crawlers
+	// or for Googlebot, from either source, forbids indexing the page or
+	// following its links: the most restrictive directive wins
+	Indexable  bool `json:"indexable"`
+	Followable bool `json:"followable"`
+	// Directives are the normalized directives of each source for each
+	// crawler, meta tags first
+	Directives []RobotsDirectives `json:"directives"`
+	// Conflicts are the directives the meta tags and the header give a
+	// crawler different values for
+	Conflicts []RobotsConflict `json:"conflicts,omitempty"`
+}
+
+// RobotsDirectives are the directives one source gives one crawler
+type RobotsDirectives struct {
+	Source string `json:"source"`
+	// UserAgent is the crawler addressed, such as googlebot; it is empty
+	// for all crawlers
+	UserAgent string `json:"user_agent,omitempty"`
+	// NoIndex and NoFollow are also set by none
+	NoIndex   bool `json:"noindex,omitempty"`
+	NoFollow  bool `json:"nofollow,omitempty"`
+	NoArchive bool `json:"noarchive,omitempty"`
+	// MaxSnippet is the longest text snippet allowed in search results, -1
+	// for no limit
+	MaxSnippet *int `json:"max_snippet,omitempty"`
+	// UnavailableAfter is the date after which the page is not to be shown
+	// in search results, as sent
+	UnavailableAfter string `json:"unavailable_after,omitempty"`
+	// Values are the meta tag contents or header values, as sent
+	Values []string `json:"values"`
+}
+
+// RobotsConflict is a directive the meta tags and the X-Robots-Tag header
+// give one crawler different values for, such as noindex in one and index
+// in the other
+type RobotsConflict struct {
+	UserAgent string `json:"user_agent,omitempty"`
+	// Directive is index, follow, max-snippet or unavailable_after
+	Directive string `json:"directive"`
+	Meta      string `json:"meta"`
+	Header    string `json:"header"`
+}
+
+// RedirectedLink is a link of the page that answered with a redirect
+type RedirectedLink struct {
+	URL       string `json:"url"`
+	FinalURL  string `json:"final_url"`
+	Redirects int    `json:"redirects"`
+	// DurationMs is how long the check took, redirects included
+	DurationMs float64 `json:"duration_ms,omitempty"`
+}
+
+// Hreflang finding kinds
+const (
+	HreflangInvalidCode     = "invalid_code"
+	HreflangUnreachable     = "unreachable_alternate"
+	HreflangMissingXDefault = "missing_x_default"
+	HreflangNotReciprocal   = "not_reciprocal"
+)
+
+// HreflangLink is a <link rel="alternate" hreflang="..."> of a page
+type HreflangLink struct {
+	Lang string `json:"lang"`
+	URL  string `json:"url"`
+}
+
+// HreflangReport lists a page's hreflang alternates, in document order, and
+// the problems found with them
+type HreflangReport struct {
+	Alternates []HreflangLink    `json:"alternates"`
+	Findings   []HreflangFinding `json:"findings,omitempty"`
+}
+
+// HreflangFinding is one problem with a page's hreflang alternates
+type HreflangFinding struct {
+	Kind   string `json:"kind"`
+	Lang   string `json:"lang,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// AMP finding kinds
+const (
+	// AMPMissingCanonical flags an AMP page without a rel=canonical link
+	AMPMissingCanonical = "missing_canonical"
+	// AMPUnreachableAMPHTML and AMPUnreachableCanonical flag a counterpart
+	// the link checker could not reach
+	AMPUnreachableAMPHTML   = "unreachable_amphtml"
+	AMPUnreachableCanonical = "unreachable_canonical"
+)
+
+// TitleReport describes the title elements of a page. Count is how many
+// there are; the first is the page's title, as in browsers. Length is that
+// title's length in characters, judged against the analyzer's MinLength and
+// MaxLength.
+type TitleReport struct {
+	Count     int `json:"count"`
+	Length    int `json:"length"`
+	MinLength int `json:"min_length"`
+	MaxLength int `json:"max_length"`
+}
+
+// AMPReport is the AMP side of a page: whether it is an AMP page itself
+// (<html amp> or <html ⚡>), the AMP variant it points to with
+// <link rel="amphtml">, and its canonical URL
+type AMPReport struct {
+	IsAMP        bool         `json:"is_amp"`
+	AMPHTMLURL   string       `json:"amphtml_url,omitempty"`
+	CanonicalURL string       `json:"canonical_url,omitempty"`
+	Findings     []AMPFinding `json:"findings,omitempty"`
+}
+
+// AMPFinding is one problem with the relationship between a page and its
+// AMP counterpart
+type AMPFinding struct {
+	Kind   string `json:"kind"`
+	URL    string `json:"url,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Positions of a page in its paginated series
+const (
+	PaginationFirst  = "first"
+	PaginationMiddle = "middle"
+	PaginationLast   = "last"
+)
+
+// Pagination finding kinds
+const (
+	// PaginationUnreachableNext and PaginationUnreachablePrev flag a next
+	// or previous page the link checker could not reach
+	PaginationUnreachableNext = "unreachable_next"
+	PaginationUnreachablePrev = "unreachable_prev"
+	// PaginationSelfReference flags a next or previous link to the page
+	// itself
+	PaginationSelfReference = "self_reference"
+	// PaginationChainLoop flags a chain of next links that leads back to a
+	// page already in it, found when the chain is followed
+	PaginationChainLoop = "chain_loop"
+	// PaginationChainBroken flags a page further down the chain of next
+	// links that could not be fetched
+	PaginationChainBroken = "chain_broken"
+)
+
+// PaginationReport places a page in the paginated series it declares with
+// <link rel="next"> and <link rel="prev">: first with only a next page,
+// last with only a previous one, middle with both
+type PaginationReport struct {
+	Position string `json:"position"`
+	NextURL  string `json:"next_url,omitempty"`
+	PrevURL  string `json:"prev_url,omitempty"`
+	// SeriesLength counts the page and the pages its next links lead to,
+	// when the request asked for the chain to be followed; it is the
+	// length of the series for its first page
+	SeriesLength int `json:"series_length,omitempty"`
+	// SeriesTruncated is set when the analyzer's hop limit stopped the
+	// count before the last page
+	SeriesTruncated bool                `json:"series_truncated,omitempty"`
+	Findings        []PaginationFinding `json:"findings,omitempty"`
+}
+
+// PaginationFinding is one problem with the series of a page
+type PaginationFinding struct {
+	Kind   string `json:"kind"`
+	URL    string `json:"url,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// AnchorReport lists what breaks the in-page links of a page: ids that more
+// than one element has, which is invalid HTML and leaves links to them
+// pointing at the first, and links to fragments no element is named by
+type AnchorReport struct {
+	// DuplicateIDs are in the order of their first element
+	DuplicateIDs []DuplicateID `json:"duplicate_ids,omitempty"`
+	// DanglingAnchors are the fragments of same-page links, as written
+	// after the "#", that match no id or <a name>, each once, in page
+	// order
+	DanglingAnchors []string `json:"dangling_anchors,omitempty"`
+}
+
+// DuplicateID is an id and how many elements have it
+type DuplicateID struct {
+	ID    string `json:"id"`
+	Count int    `json:"count"`
+}
+
+// Frame is a frame or iframe document of a page. A followed frame's headings
+// and links are included in the page's counts and broken out here.
+type Frame struct {
+	URL      string        `json:"url"`
+	Followed bool          `json:"followed"`
+	Headings *HeadingCount `json:"headings,omitempty"`
+	Links    int           `json:"links,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Analysis stage names, used by Timings and as the stage metric label
+const (
+	StageFetch     = "fetch"
+	StageParse     = "parse"
+	StageLinkCheck = "link_check"
+	StageTotal     = "total"
+
+	// Deprecated: version detection is part of StageParse and no longer metered
+	StageHTMLVersionDetection = "html_version_detection"
+)
+
+// Timings holds the duration of each analysis stage in milliseconds
+type Timings struct {
+	FetchMs float64 `json:"fetch_ms"`
+	// HTMLVersionDetectionMs is always 0: the version is read during the
+	// parse and counted in ParseMs. It stays for wire compatibility.
+	HTMLVersionDetectionMs float64 `json:"html_version_detection_ms"`
+	ParseMs                float64 `json:"parse_ms"`
+	LinkCheckMs            float64 `json:"link_check_ms"`
+	TotalMs                float64 `json:"total_ms"`
 }
 
 // HeadingCount represents the count of each heading level
@@ -36,20 +722,179 @@ type LinkSummary struct {
 	External     int `json:"external"`
 	Inaccessible int `json:"inaccessible"`
 	Total        int `json:"total"`
+	// RedirectedLinks counts the checked links that answered with a redirect
+	RedirectedLinks int `json:"redirected,omitempty"`
+	// Unique counts the distinct URLs among the links of Total, under the
+	// result's URLNormalization; each is checked once
+	Unique int `json:"unique,omitempty"`
+	// Skipped counts, by reason, the <a> elements the page has that are not
+	// links worth checking and so are not in Total
+	Skipped map[string]int `json:"skipped,omitempty"`
+	// DataURIs describes the skipped data: URI links, if any
+	DataURIs *DataURISummary `json:"data_uris,omitempty"`
+	// SlowestLinks are the MaxSlowestLinks checked links that took longest,
+	// slowest first
+	SlowestLinks []SlowLink `json:"slowest_links,omitempty"`
+	// DurationP50Ms and DurationP95Ms are percentiles of the time the
+	// checked links took
+	DurationP50Ms float64 `json:"duration_p50_ms,omitempty"`
+	DurationP95Ms float64 `json:"duration_p95_ms,omitempty"`
+	// BrokenGroups are the MaxBrokenLinkGroups largest groups of inaccessible
+	// internal links sharing a path prefix, largest first
+	BrokenGroups []BrokenLinkGroup `json:"broken_groups,omitempty"`
+	// ExternalDomains counts the external links by registrable domain, the
+	// MaxExternalDomains most linked first; ExternalDomainsOther counts the
+	// external links to the rest
+	ExternalDomains      []DomainCount `json:"external_domains,omitempty"`
+	ExternalDomainsOther int           `json:"external_domains_other,omitempty"`
+}
+
+// URL case foldings of URLNormalization.Case
+const (
+	// URLCaseHost lowercases the scheme and host, which are case-insensitive
+	URLCaseHost = "host"
+	// URLCaseFull lowercases the path as well, for sites that serve it
+	// without regard to case
+	URLCaseFull = "full"
+)
+
+// URLNormalization is a policy deciding when differently spelled URLs are
+// the same one, see package urlutil. Fragments are always ignored.
+type URLNormalization struct {
+	// FoldTrailingSlash makes /pricing/ the same as /pricing
+	FoldTrailingSlash bool `json:"fold_trailing_slash"`
+	// StripParams are the query parameters ignored, such as tracking ones;
+	// each is a name or a prefix ending in *, as in utm_*
+	StripParams []string `json:"strip_params,omitempty"`
+	// Case is URLCaseHost or URLCaseFull; empty means URLCaseHost
+	Case string `json:"case"`
+}
+
+// MaxExternalDomains is how many domains LinkSummary.ExternalDomains lists
+const MaxExternalDomains = 20
+
+// DomainCount is a registrable domain, such as example.co.uk for
+// cdn.example.co.uk, or an IP address, and how many links lead to it
+type DomainCount struct {
+	Domain string `json:"domain"`
+	Count  int    `json:"count"`
+}
+
+// MaxSlowestLinks is how many links LinkSummary.SlowestLinks lists
+const MaxSlowestLinks = 5
+
+// MaxBrokenLinkGroups is how many groups LinkSummary.BrokenGroups lists
+const MaxBrokenLinkGroups = 5
+
+// BrokenLinkGroup is a set of inaccessible links sharing a path prefix
+type BrokenLinkGroup struct {
+	// Pattern is the shared path followed by "/*", or by "?*" when the links
+	// differ only in their query strings
+	Pattern string `json:"pattern"`
+	Count   int    `json:"count"`
+	// Examples are a few of the links, in page order
+	Examples []string `json:"examples,omitempty"`
+}
+
+// SlowLink is a link and how long its check took
+type SlowLink struct {
+	URL        string  `json:"url"`
+	DurationMs float64 `json:"duration_ms"`
 }
 
 // ParsedHTML represents the parsed HTML content
 type ParsedHTML struct {
-	Title        string
-	Headings     map[string][]string // heading level
-	Links        []Link
-	HasLoginForm bool
+	// Doctype is the DOCTYPE declaration as parsed, empty when there is none
+	Doctype      string              `json:"doctype,omitempty"`
+	HTMLVersion  string              `json:"html_version"`
+	Title        string              `json:"title"`
+	Headings     map[string][]string `json:"headings,omitempty"` // heading level
+	Links        []Link              `json:"links,omitempty"`
+	HasLoginForm bool                `json:"has_login_form"`
+	// CanonicalURL is the absolute href of <link rel="canonical">, if any
+	CanonicalURL string `json:"canonical_url,omitempty"`
+	// Frames are the absolute src URLs of the page's frames and iframes
+	Frames []string `json:"frames,omitempty"`
+	// Hreflangs are the page's language alternates, hrefs made absolute
+	Hreflangs []HreflangLink `json:"hreflangs,omitempty"`
+	// IsAMP is set for AMP pages, marked by an amp or ⚡ attribute on <html>
+	IsAMP bool `json:"is_amp,omitempty"`
+	// AMPHTMLURL is the absolute href of <link rel="amphtml">, if any
+	AMPHTMLURL string `json:"amphtml_url,omitempty"`
+	// NextURL and PrevURL are the absolute hrefs of the first
+	// <link rel="next"> and <link rel="prev">, if any
+	NextURL string `json:"next_url,omitempty"`
+	PrevURL string `json:"prev_url,omitempty"`
+	// Lang is the lang attribute of <html>, as written
+	Lang string `json:"lang,omitempty"`
+	// Text describes the page's visible text
+	Text TextStats `json:"text"`
+	// RobotsMeta are the page's robots and googlebot meta tags
+	RobotsMeta []RobotsMeta `json:"robots_meta,omitempty"`
+	// Truncation is set when the document exceeded the parser's limits
+	Truncation *ParseTruncation `json:"truncation,omitempty"`
+	// TitleCount is the number of title elements, wherever they are; Title
+	// is the first, as browsers take it. Those of inline SVG are not
+	// counted.
+	TitleCount int `json:"title_count,omitempty"`
+	// SkippedLinks counts, by reason, the <a> elements left out of Links
+	SkippedLinks map[string]int `json:"skipped_links,omitempty"`
+	// DataURIs describes the data: URI links among them
+	DataURIs *DataURISummary `json:"data_uris,omitempty"`
+	// Anchors is set when the page has duplicate ids or dangling in-page
+	// links
+	Anchors *AnchorReport `json:"anchors,omitempty"`
+	// Generator is the content of the first <meta name="generator">
+	Generator string `json:"generator,omitempty"`
+}
+
+// TextStats describe the visible text of a page: the text of its body
+// outside script, style, noscript and template elements
+type TextStats struct {
+	// Words are runs of letters and digits, apostrophes and hyphens
+	// included; each Chinese or Japanese character counts as a word
+	Words int `json:"words"`
+	// Characters leaves out whitespace
+	Characters int `json:"characters"`
+	// Language is the ISO 639-1 code of the language the text is detected
+	// in, with a Confidence between 0 and 1; it is empty when the text is
+	// too short or in a language the detector does not know
+	Language   string  `json:"language,omitempty"`
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// RobotsMeta is a <meta name="robots"> or <meta name="googlebot"> tag
+type RobotsMeta struct {
+	// Name is robots or googlebot, lowercased
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// ParseTruncation counts what the parser left out to stay within its limits
+type ParseTruncation struct {
+	// DeepElements are elements nested beyond the maximum depth; their
+	// tags are dropped and their content flattened or not examined
+	DeepElements int `json:"deep_elements,omitempty"`
+	DroppedLinks int `json:"dropped_links,omitempty"`
+	// DroppedHeadings are headings beyond the maximum of their level
+	DroppedHeadings int `json:"dropped_headings,omitempty"`
+	// TruncatedTexts are titles, headings and link texts cut at the maximum
+	// length; the kept text ends in an ellipsis
+	TruncatedTexts int `json:"truncated_texts,omitempty"`
+	// DroppedAnchors are ids, <a name>s and same-page link fragments beyond
+	// the maximum; dangling anchors are not reported once an id or a name
+	// is dropped
+	DroppedAnchors int `json:"dropped_anchors,omitempty"`
 }
 
 type Link struct {
 	URL  string   `json:"url"`
 	Text string   `json:"text"`
 	Type LinkType `json:"type"`
+	// Rel holds the link's rel keywords, lowercased, each once
+	Rel []string `json:"rel,omitempty"`
+	// Target is the browsing context the link opens in, e.g. _blank
+	Target string `json:"target,omitempty"`
 }
 
 type LinkType string
@@ -60,34 +905,359 @@ const (
 	LinkTypeUnknown  LinkType = "unknown"
 )
 
+// Reasons an <a> element is skipped rather than extracted as a link
+const (
+	LinkSkipEmptyHref         = "empty_href"
+	LinkSkipFragmentOnly      = "fragment_only"
+	LinkSkipUnsupportedScheme = "unsupported_scheme"
+	LinkSkipParseError        = "parse_error"
+	// LinkSkipDataURI is a well-formed data: URI, carrying its content
+	// inline; a malformed one is a parse error
+	LinkSkipDataURI = "data_uri"
+	// LinkSkipBlob is a blob: URL, which only exists in the browser that
+	// created it
+	LinkSkipBlob = "blob"
+)
+
+// DataURISummary describes the data: URI links of a page. Their sizes are
+// as written in the page, "data:" included.
+type DataURISummary struct {
+	// MediaTypes counts the links by the media type of their data,
+	// lowercased and without parameters; text/plain when they declare none
+	MediaTypes map[string]int `json:"media_types"`
+	// Large counts the links of LargeBytes or more
+	Large      int `json:"large,omitempty"`
+	LargeBytes int `json:"large_bytes"`
+	// LargestBytes is the size of the largest link
+	LargestBytes int `json:"largest_bytes"`
+}
+
+// LinkNotCheckedError is the error of a link whose check did not happen
+// before the batch timed out
+const LinkNotCheckedError = "Check timeout or not processed"
+
+// LinkUnsupportedSchemeError is the error of a link the link checker was
+// sent but has no way to fetch, such as a data: or blob: URL
+const LinkUnsupportedSchemeError = "Unsupported scheme, only http and https links are checked"
+
+// LinkStatus is the outcome of checking one link. StatusCode is omitted when
+// no HTTP response was received.
 type LinkStatus struct {
 	Link       Link      `json:"link"`
 	Accessible bool      `json:"accessible"`
-	StatusCode int       `json:"status_code"`
+	StatusCode int       `json:"status_code,omitempty"`
 	Error      string    `json:"error,omitempty"`
-	CheckedAt  time.Time `json:"checked_at"`
+	CheckedAt  time.Time `json:"checked_at,omitzero"`
+	// Skipped is set when the link was not checked because the analysis ran
+	// out of outbound budget
+	Skipped bool `json:"skipped,omitempty"`
+	// Redirects is the number of redirect hops the check followed, and
+	// FinalURL where they led; both are empty when the link answered directly
+	Redirects int    `json:"redirects,omitempty"`
+	FinalURL  string `json:"final_url,omitempty"`
+	// DialAttempts lists the addresses a failed check tried to connect to
+	DialAttempts []DialAttempt `json:"dial_attempts,omitempty"`
+	// DurationMs is how long the check took, retries and redirects
+	// included; it is empty for links that were not checked
+	DurationMs float64 `json:"duration_ms,omitempty"`
+}
+
+// Address families of a DialAttempt
+const (
+	AddressFamilyIPv4 = "ipv4"
+	AddressFamilyIPv6 = "ipv6"
+)
+
+// DialAttempt is one connection a request attempted. An attempt that
+// neither connected nor failed is one the request stopped waiting for.
+type DialAttempt struct {
+	Address   string `json:"address"`
+	Family    string `json:"family"`
+	Connected bool   `json:"connected,omitempty"`
+	Error     string `json:"error,omitempty"`
 }
 
+// HTTPResponse is a fetched page; it is internal and never sent on the wire
 type HTTPResponse struct {
-	StatusCode int
-	Body       []byte
-	Headers    http.Header
+	StatusCode int         `json:"status_code"`
+	Body       []byte      `json:"-"`
+	Headers    http.Header `json:"headers,omitempty"`
+	// FinalURL is where the request ended up after redirects, when known
+	FinalURL string `json:"final_url,omitempty"`
+	// Redirects is the number of redirects followed to reach FinalURL
+	Redirects int `json:"redirects,omitempty"`
+	// Truncated is set when the body was longer than the client reads; Body
+	// then holds its beginning
+	Truncated bool `json:"truncated,omitempty"`
+	// Protocol is the HTTP version the response came over, such as HTTP/2.0
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// Validators are the HTTP cache validators of a previous fetch, sent back as
+// If-None-Match and If-Modified-Since to revalidate it
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
 }
 
+// IsZero reports whether there is nothing to revalidate with
+func (v Validators) IsZero() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// ValidatorsFrom returns the validators in response headers
+func ValidatorsFrom(headers http.Header) Validators {
+	return Validators{
+		ETag:         headers.Get("ETag"),
+		LastModified: headers.Get("Last-Modified"),
+	}
+}
+
+// StatusClientClosedRequest is the status, nginx's 499, logged for a request
+// whose client went away before it was answered
+const StatusClientClosedRequest = 499
+
 type ErrorResponse struct {
-	Error      string    `json:"error"`
-	StatusCode int       `json:"status_code"`
-	Details    string    `json:"details,omitempty"`
-	Timestamp  time.Time `json:"timestamp"`
+	Error      string `json:"error"`
+	StatusCode int    `json:"status_code"`
+	// Code identifies errors clients may want to tell apart, such as
+	// ErrorCodeInvalidResult
+	Code    string `json:"code,omitempty"`
+	Details string `json:"details,omitempty"`
+	// ContentType and ContentBytes describe the page of an
+	// ErrorCodeUnsupportedContentType error
+	ContentType  string `json:"content_type,omitempty"`
+	ContentBytes int64  `json:"content_bytes,omitempty"`
+	// Host and RetryAfterSeconds describe the target of an
+	// ErrorCodeTargetBusy error
+	Host              string `json:"host,omitempty"`
+	RetryAfterSeconds int    `json:"retry_after_seconds,omitempty"`
+	// RequestID is the X-Request-ID of the failed request, to quote when
+	// reporting it
+	RequestID string    `json:"request_id,omitempty"`
+	Timestamp time.Time `json:"timestamp,omitzero"`
+}
+
+// HTTPStatusError is a page fetch answered with an HTTP error status
+type HTTPStatusError struct {
+	StatusCode int
+	// Challenges are the WWW-Authenticate values of a 401, or the
+	// Proxy-Authenticate ones of a 407, as sent
+	Challenges []string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("HTTP error: status code %d", e.StatusCode)
+}
+
+// ErrorCodeUnsupportedContentType is the ErrorResponse code of a 422 sent for
+// a page that is not HTML
+const ErrorCodeUnsupportedContentType = "unsupported_content_type"
+
+// UnsupportedContentTypeError is returned for a page whose content is not
+// HTML, such as a PDF, JSON or an image
+type UnsupportedContentTypeError struct {
+	// ContentType is the detected media type, without parameters
+	ContentType string
+	Bytes       int64
+}
+
+func (e *UnsupportedContentTypeError) Error() string {
+	return fmt.Sprintf("unsupported content type %s (%d bytes)", e.ContentType, e.Bytes)
+}
+
+// Response is the 422 error response reporting e
+func (e *UnsupportedContentTypeError) Response() ErrorResponse {
+	return ErrorResponse{
+		Error:        "The page is not HTML: " + e.Error(),
+		StatusCode:   http.StatusUnprocessableEntity,
+		Code:         ErrorCodeUnsupportedContentType,
+		ContentType:  e.ContentType,
+		ContentBytes: e.Bytes,
+		Timestamp:    time.Now(),
+	}
+}
+
+// ErrorCodeTargetBusy is the ErrorResponse code of a 429 sent when the
+// page's host already has as many analyses running as the analyzer allows
+const ErrorCodeTargetBusy = "target_busy"
+
+// TargetBusyError is returned for an analysis that waited too long for its
+// turn on the page's host
+type TargetBusyError struct {
+	Host string
+	// RetryAfter is how long the analysis waited, a fair guess at when a
+	// slot frees up
+	RetryAfter time.Duration
 }
 
+func (e *TargetBusyError) Error() string {
+	return fmt.Sprintf("target host %s is busy", e.Host)
+}
+
+// Response is the 429 error response reporting e
+func (e *TargetBusyError) Response() ErrorResponse {
+	return ErrorResponse{
+		Error:             "Target host is busy, retry later: " + e.Error(),
+		StatusCode:        http.StatusTooManyRequests,
+		Code:              ErrorCodeTargetBusy,
+		Host:              e.Host,
+		RetryAfterSeconds: max(1, int(math.Ceil(e.RetryAfter.Seconds()))),
+		Timestamp:         time.Now(),
+	}
+}
+
+// LimitMaxLinksPerRequest is the HealthStatus.Limits key for the largest
+// batch the link checker accepts on /check
+const LimitMaxLinksPerRequest = "max_links_per_request"
+
 type HealthStatus struct {
 	Status    string            `json:"status"`
 	Service   string            `json:"service"`
-	Version   string            `json:"version"`
-	Uptime    string            `json:"uptime"`
+	Version   string            `json:"version,omitempty"`
+	Uptime    string            `json:"uptime,omitempty"`
 	Checks    map[string]string `json:"checks,omitempty"`
-	Timestamp time.Time         `json:"timestamp"`
+	Limits    map[string]int    `json:"limits,omitempty"`
+	Admission *AdmissionStats   `json:"admission,omitempty"`
+	// Probes tells when each dependency in Checks was last checked and how
+	// long it took to answer
+	Probes    map[string]ProbeResult `json:"probes,omitempty"`
+	Timestamp time.Time              `json:"timestamp,omitzero"`
+}
+
+// ProbeResult is the latest background health check of a dependency
+type ProbeResult struct {
+	LatencyMs float64   `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at,omitzero"`
+}
+
+// BudgetLimits is the outbound allowance granted to a batch of link checks
+type BudgetLimits struct {
+	MaxRequests int64 `json:"max_requests"`
+	MaxBytes    int64 `json:"max_bytes"`
+}
+
+// BudgetUsage is the outbound traffic spent against a budget. Exhausted is
+// set once a request was refused for lack of budget.
+type BudgetUsage struct {
+	Requests     int64 `json:"requests"`
+	Bytes        int64 `json:"bytes"`
+	MaxRequests  int64 `json:"max_requests"`
+	MaxBytes     int64 `json:"max_bytes"`
+	Exhausted    bool  `json:"exhausted"`
+	SkippedLinks int   `json:"skipped_links,omitempty"`
+}
+
+// Traffic is the response body bytes downloaded for an analysis, as they
+// came over the wire: compressed bodies count compressed, and a body cut
+// off at the size limit counts what was read of it
+type Traffic struct {
+	Bytes int64 `json:"bytes"`
+}
+
+// DebugTrace is the outbound requests made for one analysis, in the order
+// they completed. Requests beyond the analyzer's cap are only counted.
+type DebugTrace struct {
+	Requests []OutboundRequest `json:"requests"`
+	Dropped  int               `json:"dropped,omitempty"`
+}
+
+// OutboundRequest is one request of a DebugTrace. Source names the service
+// that made it when that is not the analyzer itself.
+type OutboundRequest struct {
+	Method     string  `json:"method"`
+	URL        string  `json:"url"`
+	StatusCode int     `json:"status_code,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+	Bytes      int64   `json:"bytes"`
+	Error      string  `json:"error,omitempty"`
+	Source     string  `json:"source,omitempty"`
+}
+
+// AdmissionStats is the state of a service's admission control
+type AdmissionStats struct {
+	InFlight    int   `json:"in_flight"`
+	Queued      int   `json:"queued"`
+	Rejected    int64 `json:"rejected"`
+	MaxInFlight int   `json:"max_in_flight"`
+	MaxQueued   int   `json:"max_queued"`
+}
+
+// ServiceStats is a service's /stats snapshot of its current load. Recent
+// figures cover the last WindowSeconds.
+type ServiceStats struct {
+	Service       string          `json:"service"`
+	UptimeSeconds int64           `json:"uptime_seconds"`
+	WindowSeconds int64           `json:"window_seconds,omitempty"`
+	Analyses      *AnalysisStats  `json:"analyses,omitempty"`
+	LinkChecks    *LinkCheckStats `json:"link_checks,omitempty"`
+	Admission     *AdmissionStats `json:"admission,omitempty"`
+	// Downstream holds the gateway's snapshots of the services behind it by
+	// name; one that could not be read carries only Error
+	Downstream map[string]*ServiceStats `json:"downstream,omitempty"`
+	Error      string                   `json:"error,omitempty"`
+}
+
+// AnalysisStats is the analyzer's share of ServiceStats
+type AnalysisStats struct {
+	InFlight       int64   `json:"in_flight"`
+	Recent         int     `json:"recent"`
+	RecentFailed   int     `json:"recent_failed"`
+	AverageSeconds float64 `json:"average_seconds"`
+	Coalesced      int64   `json:"coalesced"`
+	CacheHits      int64   `json:"cache_hits"`
+	CacheMisses    int64   `json:"cache_misses"`
+	// CacheHitRate is hits over lookups, 0 before the first lookup
+	CacheHitRate float64 `json:"cache_hit_rate"`
+	// FailuresByCause counts failed analyses since start by FailureCauses
+	FailuresByCause map[string]int64 `json:"failures_by_cause,omitempty"`
+	// TopFailingHosts are the hosts with the most failed analyses in the
+	// last hour, most failures first
+	TopFailingHosts []FailingHost `json:"top_failing_hosts,omitempty"`
+	// Warmup is the outcome of the startup warm-up, once it ran
+	Warmup *WarmupStats `json:"warmup,omitempty"`
+}
+
+// WarmupStats is the outcome of the analyzer's startup warm-up
+type WarmupStats struct {
+	DurationSeconds float64 `json:"duration_seconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// Analysis failure causes, the cause label of the analysis failure metric
+const (
+	FailureTimeout    = "timeout"
+	FailureDNS        = "dns"
+	FailureConnection = "connection"
+	FailureHTTP4xx    = "http_4xx"
+	FailureHTTP5xx    = "http_5xx"
+	FailureParse      = "parse"
+	FailureTooLarge   = "too_large"
+	FailureCanceled   = "canceled"
+	FailureOther      = "other"
+)
+
+// FailureCauses lists every analysis failure cause
+var FailureCauses = []string{
+	FailureTimeout, FailureDNS, FailureConnection, FailureHTTP4xx,
+	FailureHTTP5xx, FailureParse, FailureTooLarge, FailureCanceled, FailureOther,
+}
+
+// FailingHost counts the failed analyses of pages on one host
+type FailingHost struct {
+	Host     string         `json:"host"`
+	Failures int            `json:"failures"`
+	Causes   map[string]int `json:"causes"`
+}
+
+// LinkCheckStats is the link checker's share of ServiceStats
+type LinkCheckStats struct {
+	ActiveWorkers  int64   `json:"active_workers"`
+	WorkerPoolSize int     `json:"worker_pool_size,omitempty"`
+	Queued         int64   `json:"queued"`
+	Recent         int     `json:"recent"`
+	RecentFailed   int     `json:"recent_failed"`
+	AverageSeconds float64 `json:"average_seconds"`
 }
 
 type MetricsData struct {
@@ -106,10 +1276,41 @@ type ValidationError struct {
 // BatchAnalysisRequest represents a request to analyze multiple URLs
 type BatchAnalysisRequest struct {
 	URLs []string `json:"urls" validate:"required,min=1,max=100,dive,url"`
+	AnalysisOptions
 }
 
+// BatchAnalysisResult is the v1 batch response. TotalTime keeps its legacy
+// encoding as integer nanoseconds for wire compatibility.
 type BatchAnalysisResult struct {
-	Results   []AnalysisResult `json:"results"`
-	Errors    []ErrorResponse  `json:"errors,omitempty"`
-	TotalTime time.Duration    `json:"total_time"`
+	Results           []AnalysisResult   `json:"results"`
+	Errors            []ErrorResponse    `json:"errors,omitempty"`
+	TotalTime         time.Duration      `json:"total_time"`
+	CrossPageFindings *CrossPageFindings `json:"cross_page_findings,omitempty"`
+}
+
+// CrossPageFindings relate the successfully analyzed pages of one batch to
+// each other. Every list is sorted, so the findings do not depend on the
+// order of the batch.
+type CrossPageFindings struct {
+	DuplicateTitles    []DuplicateTitle    `json:"duplicate_titles,omitempty"`
+	CanonicalTargets   []CanonicalTarget   `json:"canonical_targets,omitempty"`
+	CollapsedRedirects []CollapsedRedirect `json:"collapsed_redirects,omitempty"`
+}
+
+// DuplicateTitle is a title, whitespace-normalized, shared by several pages
+type DuplicateTitle struct {
+	Title string   `json:"title"`
+	URLs  []string `json:"urls"`
+}
+
+// CanonicalTarget is a page whose canonical URL is another page of the batch
+type CanonicalTarget struct {
+	URL          string `json:"url"`
+	CanonicalURL string `json:"canonical_url"`
+}
+
+// CollapsedRedirect is a final URL reached from several batch inputs
+type CollapsedRedirect struct {
+	FinalURL string   `json:"final_url"`
+	URLs     []string `json:"urls"`
 }
@@ -0,0 +1,256 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/idn"
+	"github.com/RuvinSL/webpage-analyzer/pkg/safe"
+)
+
+// ErrorCodeInvalidResult is the ErrorResponse code of a 500 sent instead of
+// a result that failed Validate
+const ErrorCodeInvalidResult = "invalid_result"
+
+// ErrInvalidResult is wrapped by every error Validate returns
+var ErrInvalidResult = errors.New("invalid analysis result")
+
+// Validate checks the invariants a result must hold before it is sent to a
+// client: it is for requestedURL, its counts are not negative and add up,
+// and it is timestamped. All violations are reported together.
+func (r *AnalysisResult) Validate(requestedURL string) error {
+	if r == nil {
+		return fmt.Errorf("%w: result is nil", ErrInvalidResult)
+	}
+
+	var errs []error
+	switch {
+	case r.URL == "":
+		errs = append(errs, errors.New("url is empty"))
+	case NormalizeURL(r.URL) != NormalizeURL(requestedURL):
+		errs = append(errs, fmt.Errorf("url %q does not match the requested %q", r.URL, requestedURL))
+	}
+
+	type count struct {
+		name  string
+		value int
+	}
+	counts := []count{
+		{"headings.h1", r.Headings.H1},
+		{"headings.h2", r.Headings.H2},
+		{"headings.h3", r.Headings.H3},
+		{"headings.h4", r.Headings.H4},
+		{"headings.h5", r.Headings.H5},
+		{"headings.h6", r.Headings.H6},
+		{"links.internal", r.Links.Internal},
+		{"links.external", r.Links.External},
+		{"links.inaccessible", r.Links.Inaccessible},
+		{"links.total", r.Links.Total},
+		{"links.redirected", r.Links.RedirectedLinks},
+	}
+	if f := r.LinkFindings; f != nil {
+		counts = append(counts,
+			count{"link_findings.nofollow_external", f.NofollowExternal},
+			count{"link_findings.sponsored_external", f.SponsoredExternal},
+			count{"link_findings.ugc_external", f.UGCExternal},
+			count{"link_findings.new_tab", f.NewTab},
+		)
+		for _, finding := range f.Findings {
+			counts = append(counts, count{"link_findings." + finding.Kind + ".count", finding.Count})
+		}
+	}
+	for _, reason := range slices.Sorted(maps.Keys(r.Links.Skipped)) {
+		counts = append(counts, count{"links.skipped." + reason, r.Links.Skipped[reason]})
+	}
+	for _, c := range counts {
+		if c.value < 0 {
+			errs = append(errs, fmt.Errorf("%s is negative: %d", c.name, c.value))
+		}
+	}
+	if r.Links.Total != r.Links.Internal+r.Links.External {
+		errs = append(errs, fmt.Errorf("links.total %d is not internal %d + external %d", r.Links.Total, r.Links.Internal, r.Links.External))
+	}
+
+	if r.AnalyzedAt.IsZero() {
+		errs = append(errs, errors.New("analyzed_at is not set"))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidResult, errors.Join(errs...))
+}
+
+// NormalizeURL makes trivially different spellings of a URL compare equal:
+// the scheme and host are lowercased, the host of an internationalized
+// domain name is in ASCII form, the fragment is dropped and an empty path
+// becomes "/". Unparseable URLs are returned unchanged.
+func NormalizeURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return rawURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = idn.ASCIIHost(u.Host)
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" && u.Opaque == "" {
+		u.Path = "/"
+	}
+	return u.String()
+}
+
+// maxAcceptLanguage bounds the length of an Accept-Language value; browsers
+// send a few dozen bytes
+const maxAcceptLanguage = 256
+
+// languageRange is a language tag such as en, en-US or zh-Hant-TW, or *
+var languageRange = regexp.MustCompile(`^(\*|[A-Za-z]{1,8}(-[A-Za-z0-9]{1,8})*)$`)
+
+// qualityValue is the weight of a language range, from q=0 to q=1 with up
+// to three decimals
+var qualityValue = regexp.MustCompile(`^q=(0(\.[0-9]{0,3})?|1(\.0{0,3})?)$`)
+
+// ValidateAcceptLanguage checks that value is a plausible Accept-Language:
+// comma-separated language tags, each optionally weighted by a q between 0
+// and 1, as in "fr-CH, fr;q=0.9, en;q=0.8". Empty is valid and means the
+// default.
+func ValidateAcceptLanguage(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > maxAcceptLanguage {
+		return fmt.Errorf("accept_language is longer than %d characters", maxAcceptLanguage)
+	}
+
+	for entry := range strings.SplitSeq(value, ",") {
+		tag, weight, weighted := strings.Cut(trimOWS(entry), ";")
+		tag = trimOWS(tag)
+		if !languageRange.MatchString(tag) {
+			return fmt.Errorf("accept_language: %q is not a language tag", tag)
+		}
+		if !weighted {
+			continue
+		}
+		if !qualityValue.MatchString(trimOWS(weight)) {
+			return fmt.Errorf("accept_language: %q is not a weight between q=0 and q=1", trimOWS(weight))
+		}
+	}
+	return nil
+}
+
+// trimOWS trims the spaces and tabs HTTP allows around list items
+func trimOWS(s string) string {
+	return strings.Trim(s, " \t")
+}
+
+// Limits of the custom checks of one analysis
+const (
+	MaxChecks         = 20
+	MaxCheckNameBytes = 100
+	MaxCheckPattern   = safe.MaxPatternLength
+	// MaxCheckMatches caps the matches counted per check
+	MaxCheckMatches = 1000
+)
+
+// checkTypes are the types of CustomCheck
+var checkTypes = []string{CheckContains, CheckNotContains, CheckRegex, CheckCSSSelectorExists}
+
+// ValidateChecks checks that there are at most MaxChecks checks, each with
+// a name, a known type and a pattern within the limits. Regular expressions
+// are not compiled here; one that does not compile fails its check alone.
+func ValidateChecks(checks []CustomCheck) error {
+	if len(checks) > MaxChecks {
+		return fmt.Errorf("checks: at most %d are allowed, got %d", MaxChecks, len(checks))
+	}
+	for i, check := range checks {
+		switch {
+		case check.Name == "":
+			return fmt.Errorf("checks[%d].name is required", i)
+		case len(check.Name) > MaxCheckNameBytes:
+			return fmt.Errorf("checks[%d].name is longer than %d bytes", i, MaxCheckNameBytes)
+		case !slices.Contains(checkTypes, check.Type):
+			return fmt.Errorf("checks[%d].type: %q is not one of %s", i, check.Type, strings.Join(checkTypes, ", "))
+		case check.Pattern == "":
+			return fmt.Errorf("checks[%d].pattern is required", i)
+		case len(check.Pattern) > MaxCheckPattern:
+			return fmt.Errorf("checks[%d].pattern is longer than %d bytes", i, MaxCheckPattern)
+		}
+	}
+	return nil
+}
+
+// Limits of LinkCheckOptions, which a request cannot raise
+const (
+	MaxLinkCheckTimeoutMs    = 30_000
+	MaxLinkCheckRetries      = 3
+	MaxLinkCheckRedirects    = 10
+	MaxAcceptableStatusCodes = 20
+)
+
+// linkCheckMethods are the methods of LinkCheckOptions
+var linkCheckMethods = []string{LinkCheckGet, LinkCheckHead, LinkCheckHeadThenGet}
+
+// ValidateLinkCheckOptions checks that opts, when set, stay within the
+// limits and name a known method and real status codes
+func ValidateLinkCheckOptions(opts *LinkCheckOptions) error {
+	if opts == nil {
+		return nil
+	}
+	switch {
+	case opts.PerLinkTimeoutMs < 0 || opts.PerLinkTimeoutMs > MaxLinkCheckTimeoutMs:
+		return fmt.Errorf("link_check.per_link_timeout_ms must be between 0 and %d", MaxLinkCheckTimeoutMs)
+	case opts.Method != "" && !slices.Contains(linkCheckMethods, opts.Method):
+		return fmt.Errorf("link_check.method: %q is not one of %s", opts.Method, strings.Join(linkCheckMethods, ", "))
+	case opts.Retries < 0 || opts.Retries > MaxLinkCheckRetries:
+		return fmt.Errorf("link_check.retries must be between 0 and %d", MaxLinkCheckRetries)
+	case opts.MaxRedirects < 0 || opts.MaxRedirects > MaxLinkCheckRedirects:
+		return fmt.Errorf("link_check.max_redirects must be between 0 and %d", MaxLinkCheckRedirects)
+	case len(opts.AcceptableStatusCodes) > MaxAcceptableStatusCodes:
+		return fmt.Errorf("link_check.acceptable_status_codes: at most %d are allowed, got %d", MaxAcceptableStatusCodes, len(opts.AcceptableStatusCodes))
+	}
+	for i, code := range opts.AcceptableStatusCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("link_check.acceptable_status_codes[%d]: %d is not an HTTP status code", i, code)
+		}
+	}
+	return nil
+}
+
+// MaxHostOverrides caps the host overrides of one analysis
+const MaxHostOverrides = 10
+
+// hostName is a DNS host name of letters, digits and hyphens, such as
+// staging.example.com
+var hostName = regexp.MustCompile(`(?i)^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+
+// IsHostName reports whether host is a DNS host name, in Unicode or ASCII
+// form, rather than an IP address, a host:port or a URL
+func IsHostName(host string) bool {
+	host = idn.ASCIIHost(host)
+	return len(host) <= 253 && hostName.MatchString(host) && net.ParseIP(host) == nil
+}
+
+// ValidateHostOverrides checks that there are at most MaxHostOverrides
+// overrides, each mapping a host name, not an IP address, to an IP address.
+// Whether the analyzer allows them is its own policy.
+func ValidateHostOverrides(overrides map[string]string) error {
+	if len(overrides) > MaxHostOverrides {
+		return fmt.Errorf("host_overrides: at most %d are allowed, got %d", MaxHostOverrides, len(overrides))
+	}
+	for _, host := range slices.Sorted(maps.Keys(overrides)) {
+		switch {
+		case !IsHostName(host):
+			return fmt.Errorf("host_overrides: %q is not a host name", host)
+		case net.ParseIP(overrides[host]) == nil:
+			return fmt.Errorf("host_overrides[%q]: %q is not an IP address", host, overrides[host])
+		}
+	}
+	return nil
+}
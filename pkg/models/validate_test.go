@@ -0,0 +1,267 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validResult() *AnalysisResult {
+	return &AnalysisResult{
+		URL:         "https://example.com",
+		HTMLVersion: "HTML5",
+		Headings:    HeadingCount{H1: 1, H2: 3},
+		Links:       LinkSummary{Internal: 4, External: 2, Inaccessible: 1, Total: 6},
+		AnalyzedAt:  time.Date(2025, 3, 14, 15, 9, 26, 0, time.UTC),
+	}
+}
+
+func TestAnalysisResult_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested string
+		mutate    func(r *AnalysisResult)
+		contains  []string
+	}{
+		{name: "valid", requested: "https://example.com"},
+		{name: "requested URL spelled differently", requested: "HTTPS://Example.com/#top"},
+		{name: "no links", requested: "https://example.com", mutate: func(r *AnalysisResult) { r.Links = LinkSummary{} }},
+		{
+			name:      "empty URL",
+			requested: "https://example.com",
+			mutate:    func(r *AnalysisResult) { r.URL = "" },
+			contains:  []string{"url is empty"},
+		},
+		{
+			name:      "other URL",
+			requested: "https://example.org",
+			contains:  []string{`url "https://example.com" does not match the requested "https://example.org"`},
+		},
+		{
+			name:      "negative counts",
+			requested: "https://example.com",
+			mutate: func(r *AnalysisResult) {
+				r.Headings.H4 = -1
+				r.Links.Inaccessible = -2
+			},
+			contains: []string{"headings.h4 is negative: -1", "links.inaccessible is negative: -2"},
+		},
+		{
+			name:      "negative skipped count",
+			requested: "https://example.com",
+			mutate: func(r *AnalysisResult) {
+				r.Links.Skipped = map[string]int{LinkSkipEmptyHref: 2, LinkSkipParseError: -1}
+			},
+			contains: []string{"links.skipped.parse_error is negative: -1"},
+		},
+		{
+			name:      "negative link findings",
+			requested: "https://example.com",
+			mutate: func(r *AnalysisResult) {
+				r.LinkFindings = &LinkFindings{NewTab: -1, Findings: []LinkFinding{{Kind: LinkMissingNoopener, Count: -3}}}
+			},
+			contains: []string{"link_findings.new_tab is negative: -1", "link_findings.missing_noopener.count is negative: -3"},
+		},
+		{
+			name:      "total does not add up",
+			requested: "https://example.com",
+			mutate:    func(r *AnalysisResult) { r.Links.Total = 5 },
+			contains:  []string{"links.total 5 is not internal 4 + external 2"},
+		},
+		{
+			name:      "zero value",
+			requested: "https://example.com",
+			mutate:    func(r *AnalysisResult) { *r = AnalysisResult{} },
+			contains:  []string{"url is empty", "analyzed_at is not set"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validResult()
+			if tt.mutate != nil {
+				tt.mutate(result)
+			}
+
+			err := result.Validate(tt.requested)
+			if len(tt.contains) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrInvalidResult)
+			for _, want := range tt.contains {
+				assert.ErrorContains(t, err, want)
+			}
+		})
+	}
+}
+
+func TestAnalysisResult_Validate_Nil(t *testing.T) {
+	var result *AnalysisResult
+	assert.ErrorIs(t, result.Validate("https://example.com"), ErrInvalidResult)
+}
+
+func FuzzAnalysisResult_Validate(f *testing.F) {
+	f.Add("https://example.com", "https://example.com/", 1, 4, 2, 1, 6, int64(1))
+	f.Add("", "https://example.com", 0, 0, 0, 0, 0, int64(0))
+	f.Add("%zz", "%zz", -1, 1, 1, 3, 2, int64(-5))
+
+	f.Fuzz(func(t *testing.T, resultURL, requested string, h1, internal, external, inaccessible, total int, unix int64) {
+		result := &AnalysisResult{
+			URL:      resultURL,
+			Headings: HeadingCount{H1: h1},
+			Links:    LinkSummary{Internal: internal, External: external, Inaccessible: inaccessible, Total: total},
+		}
+		if unix != 0 {
+			result.AnalyzedAt = time.Unix(unix, 0)
+		}
+
+		err := result.Validate(requested)
+
+		valid := resultURL != "" && NormalizeURL(resultURL) == NormalizeURL(requested) &&
+			h1 >= 0 && internal >= 0 && external >= 0 && inaccessible >= 0 && total >= 0 &&
+			total == internal+external && unix != 0
+		if valid && err != nil {
+			t.Fatalf("valid result rejected: %v", err)
+		}
+		if !valid && err == nil {
+			t.Fatalf("invalid result accepted: %+v for %q", result, requested)
+		}
+	})
+}
+
+func TestValidateAcceptLanguage(t *testing.T) {
+	valid := []string{
+		"",
+		"en",
+		"en-US,en;q=0.9",
+		"fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5",
+		"zh-Hant-TW",
+		"de;q=1.000",
+		"en;q=0",
+	}
+	for _, value := range valid {
+		assert.NoError(t, ValidateAcceptLanguage(value), value)
+	}
+
+	invalid := []string{
+		"en_US",
+		"en,,fr",
+		"en;q=1.5",
+		"en;q=NaN",
+		"en;q=0.12345",
+		"en;level=1",
+		"en\r\nX-Injected: 1",
+		"verylongtag",
+		strings.Repeat("en,", 100) + "en",
+	}
+	for _, value := range invalid {
+		assert.Error(t, ValidateAcceptLanguage(value), value)
+	}
+}
+
+func TestValidateChecks(t *testing.T) {
+	assert.NoError(t, ValidateChecks(nil))
+	assert.NoError(t, ValidateChecks([]CustomCheck{
+		{Name: "analytics", Type: CheckRegex, Pattern: `gtag\(`},
+		{Name: "no lorem", Type: CheckNotContains, Pattern: "lorem ipsum", Optional: true},
+		// A pattern that does not compile fails its check, not the request
+		{Name: "broken", Type: CheckRegex, Pattern: `(`},
+	}))
+
+	tooMany := make([]CustomCheck, MaxChecks+1)
+	for i := range tooMany {
+		tooMany[i] = CustomCheck{Name: "check", Type: CheckContains, Pattern: "x"}
+	}
+
+	tests := []struct {
+		checks  []CustomCheck
+		message string
+	}{
+		{tooMany, "checks: at most 20 are allowed, got 21"},
+		{[]CustomCheck{{Type: CheckContains, Pattern: "x"}}, "checks[0].name is required"},
+		{[]CustomCheck{{Name: strings.Repeat("n", MaxCheckNameBytes+1), Type: CheckContains, Pattern: "x"}}, "checks[0].name is longer than 100 bytes"},
+		{[]CustomCheck{{Name: "ok", Type: CheckContains, Pattern: "x"}, {Name: "xpath", Type: "xpath", Pattern: "//a"}}, `checks[1].type: "xpath" is not one of contains, not_contains, regex, css_selector_exists`},
+		{[]CustomCheck{{Name: "empty", Type: CheckContains}}, "checks[0].pattern is required"},
+		{[]CustomCheck{{Name: "long", Type: CheckRegex, Pattern: strings.Repeat("a", MaxCheckPattern+1)}}, "checks[0].pattern is longer than 1024 bytes"},
+	}
+	for _, tt := range tests {
+		assert.EqualError(t, ValidateChecks(tt.checks), tt.message)
+	}
+}
+
+func TestValidateLinkCheckOptions(t *testing.T) {
+	assert.NoError(t, ValidateLinkCheckOptions(nil))
+	assert.NoError(t, ValidateLinkCheckOptions(&LinkCheckOptions{}))
+	assert.NoError(t, ValidateLinkCheckOptions(&LinkCheckOptions{
+		PerLinkTimeoutMs:      MaxLinkCheckTimeoutMs,
+		Method:                LinkCheckHeadThenGet,
+		Retries:               MaxLinkCheckRetries,
+		MaxRedirects:          MaxLinkCheckRedirects,
+		AcceptableStatusCodes: []int{403, 599},
+	}))
+
+	tests := []struct {
+		opts    LinkCheckOptions
+		message string
+	}{
+		{LinkCheckOptions{PerLinkTimeoutMs: -1}, "link_check.per_link_timeout_ms must be between 0 and 30000"},
+		{LinkCheckOptions{PerLinkTimeoutMs: MaxLinkCheckTimeoutMs + 1}, "link_check.per_link_timeout_ms must be between 0 and 30000"},
+		{LinkCheckOptions{Method: "POST"}, `link_check.method: "POST" is not one of get, head, head_then_get`},
+		{LinkCheckOptions{Retries: 4}, "link_check.retries must be between 0 and 3"},
+		{LinkCheckOptions{MaxRedirects: 11}, "link_check.max_redirects must be between 0 and 10"},
+		{LinkCheckOptions{AcceptableStatusCodes: make([]int, MaxAcceptableStatusCodes+1)}, "link_check.acceptable_status_codes: at most 20 are allowed, got 21"},
+		{LinkCheckOptions{AcceptableStatusCodes: []int{403, 600}}, "link_check.acceptable_status_codes[1]: 600 is not an HTTP status code"},
+	}
+	for _, tt := range tests {
+		assert.EqualError(t, ValidateLinkCheckOptions(&tt.opts), tt.message)
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"HTTPS://Example.COM/Pricing#plans", "https://example.com/Pricing"},
+		{"https://example.com", "https://example.com/"},
+		{"https://bücher.example/neu", "https://xn--bcher-kva.example/neu"},
+		{"https://BÜCHER.example:8443/neu", "https://xn--bcher-kva.example:8443/neu"},
+		{"https://XN--BCHER-KVA.example/neu", "https://xn--bcher-kva.example/neu"},
+		{"http://[::1", "http://[::1"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, NormalizeURL(tt.url), tt.url)
+	}
+}
+
+func TestValidateHostOverrides(t *testing.T) {
+	assert.NoError(t, ValidateHostOverrides(nil))
+	assert.NoError(t, ValidateHostOverrides(map[string]string{"staging.example.com": "10.0.0.5", "Localhost": "::1"}))
+	assert.NoError(t, ValidateHostOverrides(map[string]string{"bücher.example": "10.0.0.5", "xn--strae-oqa.example": "10.0.0.6"}))
+
+	tooMany := map[string]string{}
+	for i := range MaxHostOverrides + 1 {
+		tooMany[fmt.Sprintf("host%d.example.com", i)] = "10.0.0.5"
+	}
+
+	tests := []struct {
+		overrides map[string]string
+		message   string
+	}{
+		{tooMany, "host_overrides: at most 10 are allowed, got 11"},
+		{map[string]string{"staging.example.com:8080": "10.0.0.5"}, `host_overrides: "staging.example.com:8080" is not a host name`},
+		{map[string]string{"https://staging.example.com": "10.0.0.5"}, `host_overrides: "https://staging.example.com" is not a host name`},
+		{map[string]string{"-staging.example.com": "10.0.0.5"}, `host_overrides: "-staging.example.com" is not a host name`},
+		{map[string]string{"10.0.0.1": "10.0.0.5"}, `host_overrides: "10.0.0.1" is not a host name`},
+		{map[string]string{"": "10.0.0.5"}, `host_overrides: "" is not a host name`},
+		{map[string]string{"bü cher.example": "10.0.0.5"}, `host_overrides: "bü cher.example" is not a host name`},
+		{map[string]string{"staging.example.com": "staging.internal"}, `host_overrides["staging.example.com"]: "staging.internal" is not an IP address`},
+	}
+	for _, tt := range tests {
+		assert.EqualError(t, ValidateHostOverrides(tt.overrides), tt.message)
+	}
+}
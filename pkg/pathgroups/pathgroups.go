@@ -0,0 +1,119 @@
+// Package pathgroups clusters URLs by the path they share, so that many
+// broken links under one section of a site read as one pattern, such as
+// "/blog/2021/*", rather than a list of URLs.
+package pathgroups
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// MaxExamples is how many of its URLs a group lists, in input order
+const MaxExamples = 3
+
+// node is a path segment of the trie. urls are the URLs whose path ends at
+// the node.
+type node struct {
+	children map[string]*node
+	urls     []string
+}
+
+// grouper collects the groups of a trie
+type grouper struct {
+	minSize int
+	// order is the position of each URL in the input
+	order  map[string]int
+	groups []models.BrokenLinkGroup
+}
+
+// Find groups urls by the longest path prefix that at least minSize of them
+// share, the deepest such prefix first: a URL belongs to one group at most,
+// and URLs left over from deeper groups count towards shallower ones. The
+// prefix is matched segment by segment, so /blog and /blog-archive never
+// share a group, and the root is not a prefix. Duplicate URLs count once;
+// URLs that do not parse are ignored. Groups are sorted by count, largest
+// first, then by pattern.
+func Find(urls []string, minSize int) []models.BrokenLinkGroup {
+	g := &grouper{minSize: max(minSize, 1), order: make(map[string]int, len(urls))}
+	root := &node{}
+	for _, rawURL := range urls {
+		if _, ok := g.order[rawURL]; ok {
+			continue
+		}
+		segments, ok := pathSegments(rawURL)
+		if !ok {
+			continue
+		}
+		g.order[rawURL] = len(g.order)
+
+		n := root
+		for _, segment := range segments {
+			child, ok := n.children[segment]
+			if !ok {
+				child = &node{}
+				if n.children == nil {
+					n.children = make(map[string]*node)
+				}
+				n.children[segment] = child
+			}
+			n = child
+		}
+		n.urls = append(n.urls, rawURL)
+	}
+
+	for segment, child := range root.children {
+		g.collect(child, "/"+segment)
+	}
+	slices.SortFunc(g.groups, func(a, b models.BrokenLinkGroup) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Pattern, b.Pattern))
+	})
+	return g.groups
+}
+
+// collect groups the URLs under n, whose path is prefix, and returns those
+// left out of a group
+func (g *grouper) collect(n *node, prefix string) []string {
+	var nested []string
+	for segment, child := range n.children {
+		nested = append(nested, g.collect(child, prefix+"/"+segment)...)
+	}
+
+	left := append(slices.Clone(n.urls), nested...)
+	if len(left) < g.minSize {
+		return left
+	}
+
+	pattern := prefix + "/*"
+	if len(nested) == 0 {
+		pattern = prefix + "?*"
+	}
+	slices.SortFunc(left, func(a, b string) int {
+		return cmp.Compare(g.order[a], g.order[b])
+	})
+	g.groups = append(g.groups, models.BrokenLinkGroup{
+		Pattern:  pattern,
+		Count:    len(left),
+		Examples: slices.Clone(left[:min(MaxExamples, len(left))]),
+	})
+	return nil
+}
+
+// pathSegments splits the path of rawURL into its non-empty segments,
+// percent-encoded as in the URL
+func pathSegments(rawURL string) ([]string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, false
+	}
+	var segments []string
+	for segment := range strings.SplitSeq(u.EscapedPath(), "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments, true
+}
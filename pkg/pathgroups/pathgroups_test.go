@@ -0,0 +1,194 @@
+package pathgroups
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name    string
+		urls    []string
+		minSize int
+		want    []models.BrokenLinkGroup
+	}{
+		{
+			name:    "no URLs",
+			minSize: 2,
+		},
+		{
+			name: "longest shared prefix",
+			urls: []string{
+				"https://example.com/blog/2021/a",
+				"https://example.com/blog/2021/b",
+				"https://example.com/blog/2021/c/d",
+			},
+			minSize: 3,
+			want: []models.BrokenLinkGroup{{
+				Pattern:  "/blog/2021/*",
+				Count:    3,
+				Examples: []string{"https://example.com/blog/2021/a", "https://example.com/blog/2021/b", "https://example.com/blog/2021/c/d"},
+			}},
+		},
+		{
+			name: "below the minimum size",
+			urls: []string{
+				"https://example.com/blog/2021/a",
+				"https://example.com/blog/2021/b",
+				"https://example.com/docs/a",
+			},
+			minSize: 3,
+		},
+		{
+			name: "prefixes collide only on whole segments",
+			urls: []string{
+				"https://example.com/blog/a",
+				"https://example.com/blog/b",
+				"https://example.com/blog-archive/a",
+				"https://example.com/blog-archive/b",
+				"https://example.com/blogs",
+			},
+			minSize: 2,
+			want: []models.BrokenLinkGroup{
+				{Pattern: "/blog-archive/*", Count: 2, Examples: []string{"https://example.com/blog-archive/a", "https://example.com/blog-archive/b"}},
+				{Pattern: "/blog/*", Count: 2, Examples: []string{"https://example.com/blog/a", "https://example.com/blog/b"}},
+			},
+		},
+		{
+			name: "query strings only",
+			urls: []string{
+				"https://example.com/search?q=a",
+				"https://example.com/search?q=b",
+				"https://example.com/search/?q=c",
+			},
+			minSize: 3,
+			want: []models.BrokenLinkGroup{{
+				Pattern:  "/search?*",
+				Count:    3,
+				Examples: []string{"https://example.com/search?q=a", "https://example.com/search?q=b", "https://example.com/search/?q=c"},
+			}},
+		},
+		{
+			name: "query strings and a subpath",
+			urls: []string{
+				"https://example.com/search?q=a",
+				"https://example.com/search?q=b",
+				"https://example.com/search/advanced",
+			},
+			minSize: 3,
+			want: []models.BrokenLinkGroup{{
+				Pattern:  "/search/*",
+				Count:    3,
+				Examples: []string{"https://example.com/search?q=a", "https://example.com/search?q=b", "https://example.com/search/advanced"},
+			}},
+		},
+		{
+			name: "leftovers count towards the parent",
+			urls: []string{
+				"https://example.com/blog/2021/a",
+				"https://example.com/blog/2021/b",
+				"https://example.com/blog/2022/a",
+				"https://example.com/blog/2021/c",
+				"https://example.com/blog/2023/a",
+				"https://example.com/blog",
+			},
+			minSize: 3,
+			want: []models.BrokenLinkGroup{
+				{Pattern: "/blog/*", Count: 3, Examples: []string{"https://example.com/blog/2022/a", "https://example.com/blog/2023/a", "https://example.com/blog"}},
+				{Pattern: "/blog/2021/*", Count: 3, Examples: []string{"https://example.com/blog/2021/a", "https://example.com/blog/2021/b", "https://example.com/blog/2021/c"}},
+			},
+		},
+		{
+			name: "duplicates count once and the root is not a prefix",
+			urls: []string{
+				"https://example.com/a",
+				"https://example.com/a",
+				"https://example.com/b",
+				"https://example.com/",
+				"https://example.com",
+			},
+			minSize: 2,
+		},
+		{
+			name: "escaped segments",
+			urls: []string{
+				"https://example.com/a%2Fb/x",
+				"https://example.com/a%2Fb/y",
+				"https://example.com/a/b/z",
+			},
+			minSize: 2,
+			want: []models.BrokenLinkGroup{
+				{Pattern: "/a%2Fb/*", Count: 2, Examples: []string{"https://example.com/a%2Fb/x", "https://example.com/a%2Fb/y"}},
+			},
+		},
+		{
+			name: "examples are capped",
+			urls: []string{
+				"https://example.com/old/e",
+				"https://example.com/old/d",
+				"https://example.com/old/c",
+				"https://example.com/old/b",
+				"https://example.com/old/a",
+				"://not a url",
+			},
+			minSize: 2,
+			want: []models.BrokenLinkGroup{{
+				Pattern:  "/old/*",
+				Count:    5,
+				Examples: []string{"https://example.com/old/e", "https://example.com/old/d", "https://example.com/old/c"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Find(tt.urls, tt.minSize))
+		})
+	}
+}
+
+func TestFind_LargestFirst(t *testing.T) {
+	var urls []string
+	for i := range 4 {
+		urls = append(urls, fmt.Sprintf("https://example.com/small/%d", i))
+	}
+	for i := range 6 {
+		urls = append(urls, fmt.Sprintf("https://example.com/large/%d", i))
+	}
+	for i := range 4 {
+		urls = append(urls, fmt.Sprintf("https://example.com/also-small/%d", i))
+	}
+
+	var patterns []string
+	for _, group := range Find(urls, 3) {
+		patterns = append(patterns, group.Pattern)
+	}
+	assert.Equal(t, []string{"/large/*", "/also-small/*", "/small/*"}, patterns)
+}
+
+func TestFind_Deterministic(t *testing.T) {
+	var urls []string
+	for _, section := range []string{"a", "b", "c"} {
+		for i := range 5 {
+			urls = append(urls, fmt.Sprintf("https://example.com/%s/%d/page", section, i%2))
+			urls = append(urls, fmt.Sprintf("https://example.com/%s/%d?page=%d", section, i%2, i))
+		}
+	}
+	want := Find(urls, 3)
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 20 {
+		shuffled := append([]string(nil), urls...)
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		got := Find(shuffled, 3)
+		// Examples follow the input order; the groups do not
+		for i := range got {
+			got[i].Examples = want[i].Examples
+		}
+		assert.Equal(t, want, got)
+	}
+}
@@ -0,0 +1,176 @@
+package readiness
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// Mode decides what happens when dependencies are still unreachable once the
+// maximum startup wait has elapsed
+type Mode string
+
+const (
+	// ModeFailFast makes Wait return an error so the service can exit
+	ModeFailFast Mode = "fail-fast"
+	// ModeDegraded flips the service to ready anyway and reports it as degraded
+	ModeDegraded Mode = "degraded"
+)
+
+const (
+	defaultInitialBackoff = 200 * time.Millisecond
+	defaultMaxBackoff     = 5 * time.Second
+	defaultCheckTimeout   = 2 * time.Second
+)
+
+// ParseMode converts an environment value into a Mode
+func ParseMode(value string) (Mode, error) {
+	switch Mode(value) {
+	case ModeFailFast:
+		return ModeFailFast, nil
+	case ModeDegraded, "":
+		return ModeDegraded, nil
+	default:
+		return "", fmt.Errorf("invalid startup mode %q: must be %q or %q", value, ModeFailFast, ModeDegraded)
+	}
+}
+
+// Dependency is a downstream service that must be reachable before traffic is accepted
+type Dependency struct {
+	Name    string
+	Checker interfaces.HealthChecker
+}
+
+// Gate tracks whether a service is ready to receive traffic
+type Gate struct {
+	serviceName string
+	deps        []Dependency
+	logger      interfaces.Logger
+
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	CheckTimeout   time.Duration
+
+	ready    atomic.Bool
+	degraded atomic.Bool
+
+	mu     sync.RWMutex
+	checks map[string]string
+}
+
+// NewGate creates a gate that is not ready until Wait succeeds
+func NewGate(serviceName string, logger interfaces.Logger, deps ...Dependency) *Gate {
+	return &Gate{
+		serviceName:    serviceName,
+		deps:           deps,
+		logger:         logger,
+		InitialBackoff: defaultInitialBackoff,
+		MaxBackoff:     defaultMaxBackoff,
+		CheckTimeout:   defaultCheckTimeout,
+		checks:         make(map[string]string),
+	}
+}
+
+// Wait probes all dependencies with exponential backoff until they are all
+// healthy or maxWait elapses, then flips the ready flag. In fail-fast mode an
+// error is returned instead when the dependencies never came up.
+func (g *Gate) Wait(ctx context.Context, maxWait time.Duration, mode Mode) error {
+	waitCtx, cancel := context.WithTimeout(ctx, maxWait)
+	defer cancel()
+
+	backoff := g.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		if g.probe(waitCtx) {
+			g.ready.Store(true)
+			g.logger.Info("Dependencies reachable, service is ready", "attempts", attempt)
+			return nil
+		}
+
+		g.logger.Warn("Dependencies not ready yet", "attempt", attempt, "retry_in", backoff, "checks", g.snapshot())
+
+		select {
+		case <-waitCtx.Done():
+			if mode == ModeFailFast {
+				return fmt.Errorf("dependencies not ready after %s: %v", maxWait, g.snapshot())
+			}
+			g.degraded.Store(true)
+			g.ready.Store(true)
+			g.logger.Warn("Startup wait exceeded, serving in degraded mode", "max_wait", maxWait, "checks", g.snapshot())
+			return nil
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > g.MaxBackoff {
+			backoff = g.MaxBackoff
+		}
+	}
+}
+
+// probe checks every dependency once and reports whether all are healthy
+func (g *Gate) probe(ctx context.Context) bool {
+	healthy := true
+	for _, dep := range g.deps {
+		checkCtx, cancel := context.WithTimeout(ctx, g.CheckTimeout)
+		err := dep.Checker.CheckHealth(checkCtx)
+		cancel()
+
+		g.mu.Lock()
+		if err != nil {
+			g.checks[dep.Name] = "unhealthy: " + err.Error()
+			healthy = false
+		} else {
+			g.checks[dep.Name] = "healthy"
+		}
+		g.mu.Unlock()
+	}
+	return healthy
+}
+
+func (g *Gate) snapshot() map[string]string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	checks := make(map[string]string, len(g.checks))
+	for name, status := range g.checks {
+		checks[name] = status
+	}
+	return checks
+}
+
+// IsReady reports whether the service should receive traffic
+func (g *Gate) IsReady() bool {
+	return g.ready.Load()
+}
+
+// Ready handles the readiness endpoint
+func (g *Gate) Ready(w http.ResponseWriter, r *http.Request) {
+	status := "starting"
+	statusCode := http.StatusServiceUnavailable
+
+	if g.IsReady() {
+		status = "ready"
+		statusCode = http.StatusOK
+		if g.degraded.Load() {
+			status = "degraded"
+		}
+	}
+
+	response := models.HealthStatus{
+		Status:    status,
+		Service:   g.serviceName,
+		Checks:    g.snapshot(),
+		Timestamp: time.Now(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,166 @@
+package readiness
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/mocks"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// delayedChecker is a health checker whose dependency only comes up after a delay
+type delayedChecker struct {
+	url    string
+	client *http.Client
+}
+
+func (c *delayedChecker) CheckHealth(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unhealthy status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// startDelayedDependency starts a server that answers 503 until upAfter has passed
+func startDelayedDependency(t *testing.T, upAfter time.Duration) (*delayedChecker, *int32) {
+	start := time.Now()
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if time.Since(start) < upAfter {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return &delayedChecker{url: server.URL, client: server.Client()}, &hits
+}
+
+type failingChecker struct{}
+
+func (failingChecker) CheckHealth(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+func newTestGate(t *testing.T, deps ...Dependency) *Gate {
+	ctrl := gomock.NewController(t)
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Info(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Warn(gomock.Any(), gomock.Any()).AnyTimes()
+
+	gate := NewGate("test-service", mockLogger, deps...)
+	gate.InitialBackoff = 10 * time.Millisecond
+	gate.MaxBackoff = 40 * time.Millisecond
+	return gate
+}
+
+func readyStatus(t *testing.T, gate *Gate) (int, models.HealthStatus) {
+	w := httptest.NewRecorder()
+	gate.Ready(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+	var status models.HealthStatus
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&status))
+	return w.Code, status
+}
+
+func TestGate_WaitsForDelayedDependency(t *testing.T) {
+	checker, hits := startDelayedDependency(t, 150*time.Millisecond)
+	gate := newTestGate(t, Dependency{Name: "link_checker_service", Checker: checker})
+
+	code, status := readyStatus(t, gate)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "starting", status.Status)
+
+	done := make(chan error, 1)
+	go func() { done <- gate.Wait(context.Background(), 2*time.Second, ModeFailFast) }()
+
+	// Still not ready while the dependency is down
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, gate.IsReady())
+
+	require.NoError(t, <-done)
+	assert.True(t, gate.IsReady())
+	assert.Greater(t, atomic.LoadInt32(hits), int32(1), "dependency should have been retried")
+
+	code, status = readyStatus(t, gate)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", status.Status)
+	assert.Equal(t, "healthy", status.Checks["link_checker_service"])
+}
+
+func TestGate_FailFast(t *testing.T) {
+	gate := newTestGate(t, Dependency{Name: "analyzer_service", Checker: failingChecker{}})
+
+	err := gate.Wait(context.Background(), 100*time.Millisecond, ModeFailFast)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dependencies not ready")
+	assert.False(t, gate.IsReady())
+}
+
+func TestGate_ServeDegraded(t *testing.T) {
+	gate := newTestGate(t, Dependency{Name: "analyzer_service", Checker: failingChecker{}})
+
+	err := gate.Wait(context.Background(), 100*time.Millisecond, ModeDegraded)
+
+	require.NoError(t, err)
+	assert.True(t, gate.IsReady())
+
+	code, status := readyStatus(t, gate)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "degraded", status.Status)
+	assert.Contains(t, status.Checks["analyzer_service"], "unhealthy")
+}
+
+func TestGate_NoDependencies(t *testing.T) {
+	gate := newTestGate(t)
+
+	require.NoError(t, gate.Wait(context.Background(), time.Second, ModeFailFast))
+	assert.True(t, gate.IsReady())
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected Mode
+		wantErr  bool
+	}{
+		{value: "", expected: ModeDegraded},
+		{value: "degraded", expected: ModeDegraded},
+		{value: "fail-fast", expected: ModeFailFast},
+		{value: "crash", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			mode, err := ParseMode(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, mode)
+		})
+	}
+}
@@ -0,0 +1,205 @@
+// Package robots reads the directives a page gives search engine crawlers
+// in its robots meta tags and X-Robots-Tag headers, as Google documents
+// them: comma-separated directives, optionally addressed to one crawler.
+package robots
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// googlebot is the crawler whose directives count towards Indexable and
+// Followable besides those for all crawlers
+const googlebot = "googlebot"
+
+// directives are the names known to crawlers. A header value starting with
+// any other word and a colon addresses the crawler of that name.
+var directives = []string{
+	"all", "none", "index", "noindex", "follow", "nofollow", "noarchive", "nocache", "nosnippet",
+	"notranslate", "noimageindex", "indexifembedded",
+	"max-snippet", "max-image-preview", "max-video-preview", "unavailable_after",
+}
+
+// group is the directives one source gives one crawler, with the index and
+// follow it states explicitly, which only matter for conflicts
+type group struct {
+	models.RobotsDirectives
+	index, follow bool
+}
+
+// Evaluate reads the robots meta tags of a page and the X-Robots-Tag values
+// of headers. It returns nil when neither gives a directive.
+func Evaluate(headers http.Header, meta []models.RobotsMeta) *models.RobotsReport {
+	// Meta tags first, each source in the order its crawlers appear
+	var groups []*group
+	find := func(source, agent string) *group {
+		for _, g := range groups {
+			if g.Source == source && g.UserAgent == agent {
+				return g
+			}
+		}
+		g := &group{RobotsDirectives: models.RobotsDirectives{Source: source, UserAgent: agent}}
+		groups = append(groups, g)
+		return g
+	}
+
+	for _, tag := range meta {
+		agent := ""
+		if tag.Name != "robots" {
+			agent = tag.Name
+		}
+		g := find(models.RobotsSourceMeta, agent)
+		g.Values = append(g.Values, tag.Content)
+		for name, value := range splitDirectives(tag.Content) {
+			g.apply(name, value)
+		}
+	}
+	for _, value := range headers.Values("X-Robots-Tag") {
+		agent, list := headerAgent(value)
+		g := find(models.RobotsSourceHeader, agent)
+		g.Values = append(g.Values, value)
+		for name, value := range splitDirectives(list) {
+			g.apply(name, value)
+		}
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+
+	report := &models.RobotsReport{Indexable: true, Followable: true}
+	for _, g := range groups {
+		report.Directives = append(report.Directives, g.RobotsDirectives)
+		if g.UserAgent == "" || g.UserAgent == googlebot {
+			report.Indexable = report.Indexable && !g.NoIndex
+			report.Followable = report.Followable && !g.NoFollow
+		}
+		if g.Source != models.RobotsSourceMeta {
+			continue
+		}
+		for _, other := range groups {
+			if other.Source == models.RobotsSourceHeader && other.UserAgent == g.UserAgent {
+				report.Conflicts = append(report.Conflicts, conflicts(g, other)...)
+			}
+		}
+	}
+	return report
+}
+
+// headerAgent splits the crawler an X-Robots-Tag value addresses, such as
+// googlebot in "googlebot: noindex", from its directives
+func headerAgent(value string) (agent, list string) {
+	name, rest, ok := strings.Cut(value, ":")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !ok || name == "" || strings.ContainsAny(name, ", \t") || slices.Contains(directives, name) {
+		return "", value
+	}
+	return name, rest
+}
+
+// splitDirectives yields the lowercased name and the value of each
+// directive of a comma-separated list. The date of unavailable_after may
+// itself hold a comma, as in "Sunday, 01-Jan-2026 00:00:00 GMT".
+func splitDirectives(list string) func(yield func(name, value string) bool) {
+	return func(yield func(name, value string) bool) {
+		var pending, pendingValue string
+		for part := range strings.SplitSeq(list, ",") {
+			part = strings.TrimSpace(part)
+			name, value, _ := strings.Cut(part, ":")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if pending == "unavailable_after" && !slices.Contains(directives, name) {
+				pendingValue += ", " + part
+				continue
+			}
+			if pending != "" && !yield(pending, pendingValue) {
+				return
+			}
+			pending, pendingValue = name, strings.TrimSpace(value)
+		}
+		if pending != "" {
+			yield(pending, pendingValue)
+		}
+	}
+}
+
+// apply records one directive; unknown ones are kept only in Values
+func (g *group) apply(name, value string) {
+	switch name {
+	case "noindex":
+		g.NoIndex = true
+	case "index":
+		g.index = true
+	case "nofollow":
+		g.NoFollow = true
+	case "follow":
+		g.follow = true
+	case "none":
+		g.NoIndex, g.NoFollow = true, true
+	case "all":
+		g.index, g.follow = true, true
+	case "noarchive", "nocache":
+		// nocache is Bing's name for noarchive
+		g.NoArchive = true
+	case "max-snippet":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < -1 {
+			return
+		}
+		if g.MaxSnippet == nil || shorter(n, *g.MaxSnippet) {
+			g.MaxSnippet = &n
+		}
+	case "unavailable_after":
+		if g.UnavailableAfter == "" {
+			g.UnavailableAfter = value
+		}
+	}
+}
+
+// shorter reports whether snippet length a is stricter than b; -1 is no
+// limit
+func shorter(a, b int) bool {
+	return a != -1 && (b == -1 || a < b)
+}
+
+// conflicts lists the directives meta and header state differently for
+// their crawler. Leaving a directive out is not a conflict.
+func conflicts(meta, header *group) []models.RobotsConflict {
+	var found []models.RobotsConflict
+	add := func(directive, metaValue, headerValue string) {
+		if metaValue != "" && headerValue != "" && metaValue != headerValue {
+			found = append(found, models.RobotsConflict{
+				UserAgent: meta.UserAgent,
+				Directive: directive,
+				Meta:      metaValue,
+				Header:    headerValue,
+			})
+		}
+	}
+	add("index", stated(meta.NoIndex, meta.index, "index"), stated(header.NoIndex, header.index, "index"))
+	add("follow", stated(meta.NoFollow, meta.follow, "follow"), stated(header.NoFollow, header.follow, "follow"))
+	add("max-snippet", snippet(meta.MaxSnippet), snippet(header.MaxSnippet))
+	add("unavailable_after", meta.UnavailableAfter, header.UnavailableAfter)
+	return found
+}
+
+// stated is the no form of directive when it is given, else the plain form
+// when that is, else ""
+func stated(negated, plain bool, directive string) string {
+	switch {
+	case negated:
+		return "no" + directive
+	case plain:
+		return directive
+	}
+	return ""
+}
+
+func snippet(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
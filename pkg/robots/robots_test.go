@@ -0,0 +1,157 @@
+package robots
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func length(n int) *int { return &n }
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  http.Header
+		meta     []models.RobotsMeta
+		expected *models.RobotsReport
+	}{
+		{
+			name:     "no directives",
+			headers:  http.Header{"Content-Type": {"text/html"}},
+			expected: nil,
+		},
+		{
+			name: "combined meta directives",
+			meta: []models.RobotsMeta{{Name: "robots", Content: "NoIndex, nofollow, noarchive, max-snippet:50"}},
+			expected: &models.RobotsReport{
+				Directives: []models.RobotsDirectives{{
+					Source: models.RobotsSourceMeta, NoIndex: true, NoFollow: true, NoArchive: true,
+					MaxSnippet: length(50), Values: []string{"NoIndex, nofollow, noarchive, max-snippet:50"},
+				}},
+			},
+		},
+		{
+			name: "none is noindex and nofollow",
+			meta: []models.RobotsMeta{{Name: "robots", Content: "none"}},
+			expected: &models.RobotsReport{
+				Directives: []models.RobotsDirectives{
+					{Source: models.RobotsSourceMeta, NoIndex: true, NoFollow: true, Values: []string{"none"}},
+				},
+			},
+		},
+		{
+			name: "all restricts nothing",
+			meta: []models.RobotsMeta{{Name: "robots", Content: "all"}},
+			expected: &models.RobotsReport{
+				Indexable: true, Followable: true,
+				Directives: []models.RobotsDirectives{{Source: models.RobotsSourceMeta, Values: []string{"all"}}},
+			},
+		},
+		{
+			name: "meta tags for one crawler merge, the strictest snippet wins",
+			meta: []models.RobotsMeta{
+				{Name: "googlebot", Content: "max-snippet:-1"},
+				{Name: "googlebot", Content: "max-snippet:20, nocache"},
+			},
+			expected: &models.RobotsReport{
+				Indexable: true, Followable: true,
+				Directives: []models.RobotsDirectives{{
+					Source: models.RobotsSourceMeta, UserAgent: "googlebot", NoArchive: true, MaxSnippet: length(20),
+					Values: []string{"max-snippet:-1", "max-snippet:20, nocache"},
+				}},
+			},
+		},
+		{
+			name:    "header with a date holding a comma",
+			headers: http.Header{"X-Robots-Tag": {"unavailable_after: Sunday, 01-Jan-2026 00:00:00 GMT, noarchive"}},
+			expected: &models.RobotsReport{
+				Indexable: true, Followable: true,
+				Directives: []models.RobotsDirectives{{
+					Source: models.RobotsSourceHeader, NoArchive: true, UnavailableAfter: "Sunday, 01-Jan-2026 00:00:00 GMT",
+					Values: []string{"unavailable_after: Sunday, 01-Jan-2026 00:00:00 GMT, noarchive"},
+				}},
+			},
+		},
+		{
+			name:    "header addressed to crawlers",
+			headers: http.Header{"X-Robots-Tag": {"googlebot: nofollow", "BingBot: noindex", "noarchive"}},
+			expected: &models.RobotsReport{
+				Indexable: true,
+				Directives: []models.RobotsDirectives{
+					{Source: models.RobotsSourceHeader, UserAgent: "googlebot", NoFollow: true, Values: []string{"googlebot: nofollow"}},
+					{Source: models.RobotsSourceHeader, UserAgent: "bingbot", NoIndex: true, Values: []string{"BingBot: noindex"}},
+					{Source: models.RobotsSourceHeader, NoArchive: true, Values: []string{"noarchive"}},
+				},
+			},
+		},
+		{
+			name:    "header and meta agree",
+			headers: http.Header{"X-Robots-Tag": {"noindex"}},
+			meta:    []models.RobotsMeta{{Name: "robots", Content: "noindex"}},
+			expected: &models.RobotsReport{
+				Followable: true,
+				Directives: []models.RobotsDirectives{
+					{Source: models.RobotsSourceMeta, NoIndex: true, Values: []string{"noindex"}},
+					{Source: models.RobotsSourceHeader, NoIndex: true, Values: []string{"noindex"}},
+				},
+			},
+		},
+		{
+			name:    "header noindex against meta index",
+			headers: http.Header{"X-Robots-Tag": {"noindex, max-snippet:10"}},
+			meta:    []models.RobotsMeta{{Name: "robots", Content: "index, follow, max-snippet:-1"}},
+			expected: &models.RobotsReport{
+				Followable: true,
+				Directives: []models.RobotsDirectives{
+					{Source: models.RobotsSourceMeta, MaxSnippet: length(-1), Values: []string{"index, follow, max-snippet:-1"}},
+					{Source: models.RobotsSourceHeader, NoIndex: true, MaxSnippet: length(10), Values: []string{"noindex, max-snippet:10"}},
+				},
+				Conflicts: []models.RobotsConflict{
+					{Directive: "index", Meta: "index", Header: "noindex"},
+					{Directive: "max-snippet", Meta: "-1", Header: "10"},
+				},
+			},
+		},
+		{
+			name:    "conflicts are per crawler",
+			headers: http.Header{"X-Robots-Tag": {"googlebot: all", "unavailable_after: 2026-01-01"}},
+			meta: []models.RobotsMeta{
+				{Name: "googlebot", Content: "none"},
+				{Name: "robots", Content: "unavailable_after: 2025-06-30"},
+			},
+			expected: &models.RobotsReport{
+				Directives: []models.RobotsDirectives{
+					{Source: models.RobotsSourceMeta, UserAgent: "googlebot", NoIndex: true, NoFollow: true, Values: []string{"none"}},
+					{Source: models.RobotsSourceMeta, UnavailableAfter: "2025-06-30", Values: []string{"unavailable_after: 2025-06-30"}},
+					{Source: models.RobotsSourceHeader, UserAgent: "googlebot", Values: []string{"googlebot: all"}},
+					{Source: models.RobotsSourceHeader, UnavailableAfter: "2026-01-01", Values: []string{"unavailable_after: 2026-01-01"}},
+				},
+				Conflicts: []models.RobotsConflict{
+					{UserAgent: "googlebot", Directive: "index", Meta: "noindex", Header: "index"},
+					{UserAgent: "googlebot", Directive: "follow", Meta: "nofollow", Header: "follow"},
+					{Directive: "unavailable_after", Meta: "2025-06-30", Header: "2026-01-01"},
+				},
+			},
+		},
+		{
+			name:    "leaving a directive out is not a conflict",
+			headers: http.Header{"X-Robots-Tag": {"nofollow"}},
+			meta:    []models.RobotsMeta{{Name: "robots", Content: "noarchive"}},
+			expected: &models.RobotsReport{
+				Indexable: true,
+				Directives: []models.RobotsDirectives{
+					{Source: models.RobotsSourceMeta, NoArchive: true, Values: []string{"noarchive"}},
+					{Source: models.RobotsSourceHeader, NoFollow: true, Values: []string{"nofollow"}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Evaluate(tt.headers, tt.meta))
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Package rules names the checks of an analysis, which requests pick through
+// models.AnalysisOptions.Rules. The analyzer implements each rule; the names
+// live here so the gateway and the configuration can reject unknown ones
+// before any work is done.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// Rule names
+const (
+	Title          = "title"
+	Headings       = "headings"
+	Links          = "links"
+	LinkAttributes = "link_attributes"
+	LinkText       = "link_text"
+	Anchors        = "anchors"
+	LoginForm      = "login_form"
+	Content        = "content"
+	Robots         = "robots"
+	Cacheability   = "cacheability"
+	Technology     = "technology"
+	Hreflang       = "hreflang"
+	AMP            = "amp"
+	Pagination     = "pagination"
+)
+
+// ErrUnknown is wrapped by the error of a selection naming no rule
+var ErrUnknown = errors.New("unknown rule")
+
+// Definition is one rule
+type Definition struct {
+	Name string `json:"name"`
+	// Description says what the rule checks and what it costs
+	Description string `json:"description"`
+}
+
+// definitions are all the rules, in the order they are listed in results
+var definitions = []Definition{
+	{Title, "Reports a page without a title"},
+	{Headings, "Counts the headings by level and checks for a single h1"},
+	{Links, "Checks that every link can be reached, one request per link"},
+	{LinkAttributes, "Counts the rel and target attributes of the links and reports missing noopener"},
+	{LinkText, "Reports links with an empty, generic or ambiguous text"},
+	{Anchors, "Reports duplicate element ids and in-page links to ids the page does not have"},
+	{LoginForm, "Detects a login form and whether it is served over HTTPS"},
+	{Content, "Counts the words of the visible text and detects its language"},
+	{Robots, "Reads the robots meta tags and X-Robots-Tag headers"},
+	{Cacheability, "Evaluates the caching headers of the page"},
+	{Technology, "Reports the protocol, server, X-Powered-By and generator hints of the page"},
+	{Hreflang, "Validates the hreflang alternates, fetching each of them"},
+	{AMP, "Checks the AMP and canonical links, fetching each of them"},
+	{Pagination, "Places the page in its rel=next and rel=prev series, fetching the next and previous pages"},
+}
+
+// Definitions returns every rule
+func Definitions() []Definition {
+	return slices.Clone(definitions)
+}
+
+// Names returns the name of every rule
+func Names() []string {
+	names := make([]string, len(definitions))
+	for i, definition := range definitions {
+		names[i] = definition.Name
+	}
+	return names
+}
+
+// Known reports whether name is a rule
+func Known(name string) bool {
+	return slices.ContainsFunc(definitions, func(d Definition) bool { return d.Name == name })
+}
+
+// Validate checks that every name of names is a rule; field names them in
+// the error
+func Validate(field string, names []string) error {
+	for _, name := range names {
+		if !Known(name) {
+			return fmt.Errorf("%s: %w %q", field, ErrUnknown, name)
+		}
+	}
+	return nil
+}
+
+// ValidateSelection checks that selection names only rules
+func ValidateSelection(selection models.RuleSelection) error {
+	return errors.Join(
+		Validate("rules.include", selection.Include),
+		Validate("rules.exclude", selection.Exclude),
+	)
+}
+
+// Select returns the names of the rules that run for selection, in the
+// order of Definitions. Without an include list, every rule runs but those
+// in disabled, the analyzer's defaults.
+func Select(disabled []string, selection models.RuleSelection) []string {
+	var selected []string
+	for _, definition := range definitions {
+		name := definition.Name
+		switch {
+		case len(selection.Include) > 0 && !slices.Contains(selection.Include, name):
+		case len(selection.Include) == 0 && slices.Contains(disabled, name):
+		case slices.Contains(selection.Exclude, name):
+		default:
+			selected = append(selected, name)
+		}
+	}
+	return selected
+}
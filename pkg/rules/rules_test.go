@@ -0,0 +1,82 @@
+package rules
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefinitions_AreWellFormed(t *testing.T) {
+	name := regexp.MustCompile(`^[a-z]+(_[a-z]+)*$`)
+	seen := make(map[string]bool)
+
+	for _, definition := range Definitions() {
+		assert.False(t, seen[definition.Name], "%s is defined twice", definition.Name)
+		seen[definition.Name] = true
+
+		assert.Regexp(t, name, definition.Name)
+		assert.NotEmpty(t, definition.Description, definition.Name)
+	}
+	assert.Equal(t, len(definitions), len(Names()))
+}
+
+func TestSelect(t *testing.T) {
+	all := Names()
+
+	tests := []struct {
+		name      string
+		disabled  []string
+		selection models.RuleSelection
+		want      []string
+	}{
+		{"defaults", nil, models.RuleSelection{}, all},
+		{"disabled by default", []string{Hreflang, AMP, Pagination}, models.RuleSelection{}, all[:len(all)-3]},
+		{
+			"include keeps the registry order",
+			nil, models.RuleSelection{Include: []string{Headings, Links}},
+			[]string{Headings, Links},
+		},
+		{
+			"include overrides the defaults",
+			[]string{Links}, models.RuleSelection{Include: []string{Links, Title}},
+			[]string{Title, Links},
+		},
+		{
+			"exclude applies to the defaults",
+			[]string{AMP, Pagination}, models.RuleSelection{Exclude: []string{Links, Hreflang, Robots}},
+			[]string{Title, Headings, LinkAttributes, LinkText, Anchors, LoginForm, Content, Cacheability, Technology},
+		},
+		{
+			"exclude applies to include",
+			nil, models.RuleSelection{Include: []string{Links, Headings}, Exclude: []string{Links}},
+			[]string{Headings},
+		},
+		{
+			"nothing left",
+			nil, models.RuleSelection{Include: []string{Links}, Exclude: []string{Links}},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Select(tt.disabled, tt.selection))
+		})
+	}
+}
+
+func TestValidateSelection(t *testing.T) {
+	assert.NoError(t, ValidateSelection(models.RuleSelection{}))
+	assert.NoError(t, ValidateSelection(models.RuleSelection{Include: []string{Links}, Exclude: []string{AMP}}))
+
+	err := ValidateSelection(models.RuleSelection{Include: []string{Links, "spelling"}})
+	require.ErrorIs(t, err, ErrUnknown)
+	assert.EqualError(t, err, `rules.include: unknown rule "spelling"`)
+
+	err = ValidateSelection(models.RuleSelection{Exclude: []string{"Links"}})
+	require.ErrorIs(t, err, ErrUnknown)
+	assert.EqualError(t, err, `rules.exclude: unknown rule "Links"`)
+}
@@ -0,0 +1,115 @@
+// Package safe bounds the cost of regular expressions run over untrusted
+// input, whether the pattern comes from a request or the input is a whole
+// page.
+//
+// Go's regexp is RE2, so matching is linear and never backtracks, but a
+// costly pattern over megabytes of HTML is still slow. A Regexp caps the
+// length of its pattern, can be limited to a window at the start of its
+// input, and counts matches under a timeout.
+package safe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// MaxPatternLength is the longest pattern Compile accepts, in bytes
+const MaxPatternLength = 1024
+
+var (
+	// ErrPatternTooLong is returned by Compile for a pattern over
+	// MaxPatternLength
+	ErrPatternTooLong = errors.New("pattern is too long")
+	// ErrTimeout is wrapped by the error of a Count that ran out of time
+	ErrTimeout = errors.New("regular expression timed out")
+)
+
+// Regexp is a compiled pattern and the window of input it looks at
+type Regexp struct {
+	re *regexp.Regexp
+	// window is how many leading bytes of an input are matched; zero is all
+	window int
+}
+
+// Compile compiles pattern, rejecting one over MaxPatternLength
+func Compile(pattern string) (*Regexp, error) {
+	if len(pattern) > MaxPatternLength {
+		return nil, fmt.Errorf("%w: %d bytes, at most %d", ErrPatternTooLong, len(pattern), MaxPatternLength)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return &Regexp{re: re}, nil
+}
+
+// MustCompile is Compile for package-level patterns; it panics on error
+func MustCompile(pattern string) *Regexp {
+	re, err := Compile(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return re
+}
+
+// Window returns a copy of r that matches only the first n bytes of its
+// input
+func (r *Regexp) Window(n int) *Regexp {
+	return &Regexp{re: r.re, window: n}
+}
+
+// String returns the pattern
+func (r *Regexp) String() string {
+	return r.re.String()
+}
+
+// scope returns the part of b that r matches
+func (r *Regexp) scope(b []byte) []byte {
+	if r.window > 0 && len(b) > r.window {
+		return b[:r.window]
+	}
+	return b
+}
+
+// Match reports whether r matches b
+func (r *Regexp) Match(b []byte) bool {
+	return r.re.Match(r.scope(b))
+}
+
+// MatchString reports whether r matches s
+func (r *Regexp) MatchString(s string) bool {
+	if r.window > 0 && len(s) > r.window {
+		s = s[:r.window]
+	}
+	return r.re.MatchString(s)
+}
+
+// FindSubmatch returns the leftmost match of r in b and its submatches,
+// or nil
+func (r *Regexp) FindSubmatch(b []byte) [][]byte {
+	return r.re.FindSubmatch(r.scope(b))
+}
+
+// Count counts the matches of r in b, up to limit, giving up after timeout
+// or when ctx is done. Matching cannot be interrupted, so an abandoned one
+// finishes in the background.
+func (r *Regexp) Count(ctx context.Context, b []byte, limit int, timeout time.Duration) (int, error) {
+	done := make(chan int, 1)
+	go func() {
+		done <- len(r.re.FindAllIndex(r.scope(b), limit))
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case matches := <-done:
+		return matches, nil
+	case <-timer.C:
+		return 0, fmt.Errorf("%w after %s", ErrTimeout, timeout)
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
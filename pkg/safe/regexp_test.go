@@ -0,0 +1,71 @@
+package safe
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	re, err := Compile(`gtag\(`)
+	require.NoError(t, err)
+	assert.Equal(t, `gtag\(`, re.String())
+
+	_, err = Compile(strings.Repeat("a", MaxPatternLength+1))
+	assert.ErrorIs(t, err, ErrPatternTooLong)
+	assert.EqualError(t, err, "pattern is too long: 1025 bytes, at most 1024")
+
+	_, err = Compile(`gtag(`)
+	assert.ErrorContains(t, err, "invalid regular expression")
+
+	assert.Panics(t, func() { MustCompile(`(`) })
+}
+
+func TestRegexp_Window(t *testing.T) {
+	content := []byte(strings.Repeat(" ", 100) + "<meta charset=utf-8>")
+	re := MustCompile(`charset=([a-z0-9-]+)`)
+
+	assert.True(t, re.Match(content))
+	assert.False(t, re.Window(100).Match(content), "the match starts past the window")
+	assert.True(t, re.Window(200).Match(content))
+	assert.False(t, re.Window(100).MatchString(string(content)))
+
+	assert.Nil(t, re.Window(110).FindSubmatch(content))
+	assert.Equal(t, "utf-8", string(re.Window(200).FindSubmatch(content)[1]))
+	assert.True(t, re.Match(content), "Window leaves the original unchanged")
+}
+
+func TestRegexp_Count(t *testing.T) {
+	ctx := context.Background()
+	re := MustCompile(`x`)
+
+	n, err := re.Count(ctx, []byte("axbxcx"), 10, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	n, err = re.Count(ctx, []byte("xxxxx"), 2, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n, "capped at the limit")
+
+	n, err = re.Window(3).Count(ctx, []byte("xxxxx"), 10, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+}
+
+func TestRegexp_CountTimeout(t *testing.T) {
+	content := []byte(strings.Repeat("ab", 1<<20))
+	re := MustCompile(`(a|b)*c`)
+
+	_, err := re.Count(context.Background(), content, 1, time.Nanosecond)
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.EqualError(t, err, "regular expression timed out after 1ns")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = re.Count(ctx, content, 1, time.Minute)
+	assert.ErrorIs(t, err, context.Canceled)
+}
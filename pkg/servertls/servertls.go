@@ -0,0 +1,160 @@
+// Package servertls lets a service serve HTTPS itself. The certificate is
+// read through a CertReloader, so a renewed certificate on disk is picked up
+// without a restart.
+package servertls
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+)
+
+// CertReloader holds the certificate of a TLS server and swaps it for the
+// one on disk when the files change. It is safe for concurrent use.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	logger   interfaces.Logger
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	// certPEM and keyPEM are the file contents cert was loaded from
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// NewCertReloader loads the certificate and key, failing if they cannot be
+// used
+func NewCertReloader(certFile, keyFile string, logger interfaces.Logger) (*CertReloader, error) {
+	c := &CertReloader{certFile: certFile, keyFile: keyFile, logger: logger}
+	if _, err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// TLSConfig returns a server configuration that always presents the current
+// certificate
+func (c *CertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: c.GetCertificate,
+	}
+}
+
+// GetCertificate returns the current certificate, for tls.Config
+func (c *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// Reload reads the files again and switches to their certificate if it
+// changed. A certificate that fails to load is reported and the current one
+// kept.
+func (c *CertReloader) Reload() error {
+	changed, err := c.reload()
+	if err != nil {
+		c.logger.Error("TLS certificate reload failed, keeping the current one", "cert_file", c.certFile, "error", err)
+		return err
+	}
+	if changed {
+		c.logger.Info("TLS certificate reloaded", "cert_file", c.certFile)
+	}
+	return nil
+}
+
+// Watch reloads the certificate every interval until ctx is done
+func (c *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.Reload()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *CertReloader) reload() (bool, error) {
+	certPEM, err := os.ReadFile(c.certFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read TLS certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(c.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read TLS key: %w", err)
+	}
+
+	c.mu.RLock()
+	unchanged := bytes.Equal(certPEM, c.certPEM) && bytes.Equal(keyPEM, c.keyPEM)
+	c.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	// A renewal caught between writing the two files fails here and is
+	// picked up on the next reload
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, fmt.Errorf("invalid TLS certificate or key: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert, c.certPEM, c.keyPEM = &cert, certPEM, keyPEM
+	return true, nil
+}
+
+// ListenAndServe serves srv over TLS when it has a TLS configuration, and
+// over plain HTTP otherwise
+func ListenAndServe(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
+// RedirectServer returns a plain HTTP server on addr that redirects every
+// request to HTTPS on httpsPort, or nil when addr is empty
+func RedirectServer(addr string, httpsPort int) *http.Server {
+	if addr == "" {
+		return nil
+	}
+	return &http.Server{
+		Addr:              addr,
+		Handler:           RedirectHandler(httpsPort),
+		ReadHeaderTimeout: 15 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+}
+
+// RedirectHandler redirects requests to the same host and path over HTTPS on
+// httpsPort
+func RedirectHandler(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		} else if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+			host = "[" + host + "]"
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
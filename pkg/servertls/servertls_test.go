@@ -0,0 +1,156 @@
+package servertls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCert writes a self-signed certificate for commonName and its key into
+// dir, replacing any there
+func writeCert(t *testing.T, dir, commonName string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func newTestReloader(t *testing.T, certFile, keyFile string) *CertReloader {
+	t.Helper()
+	certs, err := NewCertReloader(certFile, keyFile, logger.NewAdapter(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	require.NoError(t, err)
+	return certs
+}
+
+// serveTLS serves an empty handler over TLS with certs and returns its address
+func serveTLS(t *testing.T, certs *CertReloader) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &http.Server{Handler: http.NotFoundHandler(), TLSConfig: certs.TLSConfig()}
+	go srv.ServeTLS(listener, "", "")
+	t.Cleanup(func() { srv.Close() })
+	return listener.Addr().String()
+}
+
+// servedName returns the common name of the certificate served at addr
+func servedName(t *testing.T, addr string) string {
+	t.Helper()
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func TestCertReloader_ReloadSwapsServedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "first")
+	certs := newTestReloader(t, certFile, keyFile)
+	addr := serveTLS(t, certs)
+	require.Equal(t, "first", servedName(t, addr))
+
+	writeCert(t, dir, "renewed")
+	require.NoError(t, certs.Reload())
+
+	assert.Equal(t, "renewed", servedName(t, addr))
+}
+
+func TestCertReloader_WatchPicksUpRenewal(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "first")
+	certs := newTestReloader(t, certFile, keyFile)
+	addr := serveTLS(t, certs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go certs.Watch(ctx, 10*time.Millisecond)
+
+	writeCert(t, dir, "renewed")
+
+	assert.Eventually(t, func() bool { return servedName(t, addr) == "renewed" }, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestCertReloader_KeepsCertificateOnBadRenewal(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "first")
+	certs := newTestReloader(t, certFile, keyFile)
+	addr := serveTLS(t, certs)
+
+	require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0o600))
+	assert.Error(t, certs.Reload())
+
+	assert.Equal(t, "first", servedName(t, addr))
+}
+
+func TestNewCertReloader_MissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewCertReloader(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), logger.NewAdapter(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	assert.ErrorContains(t, err, "failed to read TLS certificate")
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsPort int
+		host      string
+		target    string
+		want      string
+	}{
+		{"default HTTPS port", 443, "example.com:80", "/api/v2/analyze?x=1", "https://example.com/api/v2/analyze?x=1"},
+		{"custom HTTPS port", 8443, "example.com:8080", "/", "https://example.com:8443/"},
+		{"host without port", 8443, "example.com", "/health", "https://example.com:8443/health"},
+		{"IPv6 host", 443, "[::1]:8080", "/", "https://[::1]/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.target, nil)
+			req.Host = tt.host
+			recorder := httptest.NewRecorder()
+
+			RedirectHandler(tt.httpsPort).ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusPermanentRedirect, recorder.Code)
+			assert.Equal(t, tt.want, recorder.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRedirectServer_DisabledWithoutAddr(t *testing.T) {
+	assert.Nil(t, RedirectServer("", 8443))
+	assert.Equal(t, ":8080", RedirectServer(":8080", 8443).Addr)
+}
@@ -0,0 +1,99 @@
+package stats
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// hostBuckets is how many buckets a HostFailures span is split into
+const hostBuckets = 60
+
+// maxHostsPerBucket bounds the hosts one bucket tracks; failures of further
+// hosts in the same bucket are not tracked
+const maxHostsPerBucket = 1000
+
+// HostFailures counts failures by host over a rolling span, in buckets of
+// a sixtieth of it, so memory stays bounded however many failures there
+// are. It is safe for concurrent use.
+type HostFailures struct {
+	width time.Duration
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets [hostBuckets]hostBucket
+}
+
+// hostBucket holds the failures of one period by host and cause
+type hostBucket struct {
+	period int64
+	hosts  map[string]map[string]int
+}
+
+// NewHostFailures creates a tracker over span
+func NewHostFailures(span time.Duration) *HostFailures {
+	return &HostFailures{
+		width: max(span/hostBuckets, time.Millisecond),
+		now:   time.Now,
+	}
+}
+
+// Record counts one failure of host with cause
+func (h *HostFailures) Record(host, cause string) {
+	period := h.now().UnixNano() / int64(h.width)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	bucket := &h.buckets[period%hostBuckets]
+	if bucket.period != period || bucket.hosts == nil {
+		*bucket = hostBucket{period: period, hosts: make(map[string]map[string]int)}
+	}
+	causes, ok := bucket.hosts[host]
+	if !ok {
+		if len(bucket.hosts) >= maxHostsPerBucket {
+			return
+		}
+		causes = make(map[string]int)
+		bucket.hosts[host] = causes
+	}
+	causes[cause]++
+}
+
+// Top returns up to n hosts with the most failures within the span, most
+// failures first and ties by host name
+func (h *HostFailures) Top(n int) []models.FailingHost {
+	oldest := h.now().UnixNano()/int64(h.width) - hostBuckets + 1
+
+	h.mu.Lock()
+	totals := make(map[string]*models.FailingHost)
+	for _, bucket := range h.buckets {
+		if bucket.period < oldest {
+			continue
+		}
+		for host, causes := range bucket.hosts {
+			total, ok := totals[host]
+			if !ok {
+				total = &models.FailingHost{Host: host, Causes: make(map[string]int)}
+				totals[host] = total
+			}
+			for cause, count := range causes {
+				total.Causes[cause] += count
+				total.Failures += count
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	top := make([]models.FailingHost, 0, len(totals))
+	for _, total := range totals {
+		top = append(top, *total)
+	}
+	slices.SortFunc(top, func(a, b models.FailingHost) int {
+		return cmp.Or(cmp.Compare(b.Failures, a.Failures), cmp.Compare(a.Host, b.Host))
+	})
+	return top[:min(n, len(top))]
+}
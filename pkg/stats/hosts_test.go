@@ -0,0 +1,103 @@
+package stats
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// hostClock returns a tracker whose time is moved by advance
+func hostClock(h *HostFailures) (advance func(time.Duration)) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	h.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	return func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+}
+
+func TestHostFailures_Top(t *testing.T) {
+	h := NewHostFailures(time.Hour)
+	advance := hostClock(h)
+
+	h.Record("b.example", models.FailureDNS)
+	h.Record("a.example", models.FailureTimeout)
+	advance(10 * time.Minute)
+	h.Record("c.example", models.FailureHTTP5xx)
+	h.Record("c.example", models.FailureHTTP5xx)
+	h.Record("c.example", models.FailureTimeout)
+
+	assert.Equal(t, []models.FailingHost{
+		{Host: "c.example", Failures: 3, Causes: map[string]int{models.FailureHTTP5xx: 2, models.FailureTimeout: 1}},
+		{Host: "a.example", Failures: 1, Causes: map[string]int{models.FailureTimeout: 1}},
+		{Host: "b.example", Failures: 1, Causes: map[string]int{models.FailureDNS: 1}},
+	}, h.Top(10))
+	assert.Len(t, h.Top(1), 1)
+	assert.Empty(t, NewHostFailures(time.Hour).Top(10))
+}
+
+func TestHostFailures_Expiry(t *testing.T) {
+	h := NewHostFailures(time.Hour)
+	advance := hostClock(h)
+
+	h.Record("old.example", models.FailureDNS)
+	advance(30 * time.Minute)
+	h.Record("new.example", models.FailureParse)
+	advance(31 * time.Minute)
+
+	assert.Equal(t, []models.FailingHost{
+		{Host: "new.example", Failures: 1, Causes: map[string]int{models.FailureParse: 1}},
+	}, h.Top(10), "failures older than the span are dropped")
+
+	// A bucket reused a span later starts over
+	advance(29 * time.Minute)
+	h.Record("new.example", models.FailureParse)
+	assert.Equal(t, 1, h.Top(10)[0].Failures)
+}
+
+func TestHostFailures_BoundsHostsPerBucket(t *testing.T) {
+	h := NewHostFailures(time.Hour)
+	hostClock(h)
+
+	for i := range maxHostsPerBucket + 10 {
+		h.Record(fmt.Sprintf("host%04d.example", i), models.FailureOther)
+	}
+	h.Record("host0000.example", models.FailureOther)
+
+	top := h.Top(maxHostsPerBucket + 10)
+	assert.Len(t, top, maxHostsPerBucket)
+	assert.Equal(t, "host0000.example", top[0].Host, "hosts already tracked still count")
+	assert.Equal(t, 2, top[0].Failures)
+}
+
+func TestHostFailures_ConcurrentUpdates(t *testing.T) {
+	h := NewHostFailures(time.Hour)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				h.Record(fmt.Sprintf("host%d.example", i%2), models.FailureTimeout)
+				h.Top(3)
+			}
+		}()
+	}
+	wg.Wait()
+
+	top := h.Top(10)
+	assert.Len(t, top, 2)
+	assert.Equal(t, 400, top[0].Failures)
+	assert.Equal(t, 400, top[1].Failures)
+}
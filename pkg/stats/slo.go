@@ -0,0 +1,85 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SLO is a target for a window's samples: Objective of them, such as 0.99,
+// succeed within Latency
+type SLO struct {
+	Objective float64
+	Latency   time.Duration
+}
+
+// SLI is how the samples of a window measure up to an SLO. An empty window
+// has met it, with ratios of 1 and no budget burning.
+type SLI struct {
+	Count int
+	// SuccessRatio is the share of samples that succeeded
+	SuccessRatio float64
+	// LatencyCompliance is the share of samples that succeeded within the
+	// SLO's latency, the good ones
+	LatencyCompliance float64
+	// BurnRate is how fast the error budget, the 1 - Objective of samples
+	// allowed to miss, is being spent: 1 spends it exactly over the SLO's
+	// period, 14.4 spends a 30 day budget in two days
+	BurnRate float64
+}
+
+// SLI measures the samples within the span against slo
+func (w *Window) SLI(slo SLO) SLI {
+	threshold := slo.Latency.Seconds()
+	var count, succeeded, good int
+	w.each(func(s sample) {
+		count++
+		if s.failed {
+			return
+		}
+		succeeded++
+		if s.seconds <= threshold {
+			good++
+		}
+	})
+
+	sli := SLI{Count: count, SuccessRatio: 1, LatencyCompliance: 1}
+	if count == 0 {
+		return sli
+	}
+	sli.SuccessRatio = float64(succeeded) / float64(count)
+	sli.LatencyCompliance = float64(good) / float64(count)
+	if budget := 1 - slo.Objective; budget > 0 {
+		sli.BurnRate = (1 - sli.LatencyCompliance) / budget
+	}
+	return sli
+}
+
+// SLOCollectors returns gauges of the analyses' SLI against slo over the
+// last Span, for registration: webpage_analysis_sli_success_ratio,
+// webpage_analysis_sli_latency_compliance, webpage_analysis_slo_burn_rate
+// and webpage_analysis_sli_window_analyses. They let a simple threshold
+// alert on burn rate work without recording rules.
+func (c *Collector) SLOCollectors(serviceName string, slo SLO) []prometheus.Collector {
+	labels := prometheus.Labels{"service": serviceName}
+	gauge := func(name, help string, value func(SLI) float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{Name: name, Help: help, ConstLabels: labels},
+			func() float64 { return value(c.analyses.SLI(slo)) },
+		)
+	}
+	return []prometheus.Collector{
+		gauge("webpage_analysis_sli_success_ratio",
+			"Share of the analyses of the last 5 minutes that succeeded",
+			func(sli SLI) float64 { return sli.SuccessRatio }),
+		gauge("webpage_analysis_sli_latency_compliance",
+			"Share of the analyses of the last 5 minutes that succeeded within the SLO latency",
+			func(sli SLI) float64 { return sli.LatencyCompliance }),
+		gauge("webpage_analysis_slo_burn_rate",
+			"Rate the analyses of the last 5 minutes spend the SLO error budget at, 1 spending it over the SLO period",
+			func(sli SLI) float64 { return sli.BurnRate }),
+		gauge("webpage_analysis_sli_window_analyses",
+			"Analyses of the last 5 minutes the SLI gauges are computed from",
+			func(sli SLI) float64 { return float64(sli.Count) }),
+	}
+}
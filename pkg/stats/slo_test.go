@@ -0,0 +1,103 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+var analysisSLO = SLO{Objective: 0.99, Latency: 10 * time.Second}
+
+func TestWindow_SLIEmpty(t *testing.T) {
+	w := NewWindow(5*time.Minute, 10)
+	assert.Equal(t, SLI{SuccessRatio: 1, LatencyCompliance: 1}, w.SLI(analysisSLO))
+}
+
+func TestWindow_SLI(t *testing.T) {
+	w := NewWindow(5*time.Minute, 100)
+	advance := fakeClock(w)
+
+	// 100 analyses: 95 fast, 3 slow and 2 failed
+	for range 95 {
+		w.Observe(2, true)
+	}
+	w.Observe(10, true) // at the latency is within it
+	w.Observe(12, true)
+	w.Observe(30, true)
+	w.Observe(1, false)
+	w.Observe(45, false)
+
+	sli := w.SLI(analysisSLO)
+	assert.Equal(t, 100, sli.Count)
+	assert.InDelta(t, 0.98, sli.SuccessRatio, 1e-9)
+	assert.InDelta(t, 0.96, sli.LatencyCompliance, 1e-9)
+	assert.InDelta(t, 4, sli.BurnRate, 1e-9, "4% bad against a 1% budget")
+
+	// The bad ones age out with the rest
+	advance(4 * time.Minute)
+	for range 10 {
+		w.Observe(3, true)
+	}
+	advance(time.Minute)
+	assert.Equal(t, SLI{Count: 10, SuccessRatio: 1, LatencyCompliance: 1}, w.SLI(analysisSLO))
+
+	advance(5 * time.Minute)
+	assert.Equal(t, SLI{SuccessRatio: 1, LatencyCompliance: 1}, w.SLI(analysisSLO))
+}
+
+func TestWindow_SLIAllBad(t *testing.T) {
+	w := NewWindow(5*time.Minute, 10)
+	fakeClock(w)
+
+	w.Observe(20, true)
+	w.Observe(1, false)
+
+	sli := w.SLI(SLO{Objective: 0.9, Latency: 10 * time.Second})
+	assert.Equal(t, 0.5, sli.SuccessRatio)
+	assert.Zero(t, sli.LatencyCompliance)
+	assert.InDelta(t, 10, sli.BurnRate, 1e-9, "the whole budget ten times over")
+}
+
+func TestCollector_SLOCollectors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := mocks.NewMockMetricsCollector(ctrl)
+	next.EXPECT().RecordAnalysis(gomock.Any(), gomock.Any()).AnyTimes()
+
+	c := NewCollector(next)
+	advance := fakeClock(c.analyses)
+	collectors := c.SLOCollectors("analyzer", SLO{Objective: 0.9, Latency: 5 * time.Second})
+
+	c.RecordAnalysis(true, 1)
+	c.RecordAnalysis(true, 2)
+	c.RecordAnalysis(true, 8)
+	c.RecordAnalysis(false, 1)
+
+	want := `
+# HELP webpage_analysis_sli_latency_compliance Share of the analyses of the last 5 minutes that succeeded within the SLO latency
+# TYPE webpage_analysis_sli_latency_compliance gauge
+webpage_analysis_sli_latency_compliance{service="analyzer"} 0.5
+# HELP webpage_analysis_sli_success_ratio Share of the analyses of the last 5 minutes that succeeded
+# TYPE webpage_analysis_sli_success_ratio gauge
+webpage_analysis_sli_success_ratio{service="analyzer"} 0.75
+# HELP webpage_analysis_sli_window_analyses Analyses of the last 5 minutes the SLI gauges are computed from
+# TYPE webpage_analysis_sli_window_analyses gauge
+webpage_analysis_sli_window_analyses{service="analyzer"} 4
+`
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collectors...)
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(want),
+		"webpage_analysis_sli_success_ratio", "webpage_analysis_sli_latency_compliance", "webpage_analysis_sli_window_analyses"))
+	assert.InDelta(t, 5, testutil.ToFloat64(collectors[2]), 1e-9)
+
+	// Read at scrape time, so the gauges follow the window
+	advance(Span)
+	assert.Equal(t, 1.0, testutil.ToFloat64(collectors[1]))
+	assert.Zero(t, testutil.ToFloat64(collectors[2]))
+	assert.Zero(t, testutil.ToFloat64(collectors[3]))
+}
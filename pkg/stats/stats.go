@@ -0,0 +1,171 @@
+// Package stats keeps a small in-memory view of a service's load for its
+// /stats endpoint, for operators without Prometheus. A Collector sits in
+// front of the service's MetricsCollector, so the numbers come from the same
+// calls that record the metrics.
+package stats
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// Span is how far back the recent figures of a snapshot reach
+const Span = 5 * time.Minute
+
+// windowCapacity bounds the samples each window keeps within Span
+const windowCapacity = 4096
+
+// FailureSpan is how far back the top failing hosts reach
+const FailureSpan = time.Hour
+
+// topFailingHosts is how many failing hosts a snapshot lists
+const topFailingHosts = 10
+
+// Collector forwards every call to the MetricsCollector it wraps and keeps
+// the figures reported by /stats. It is safe for concurrent use.
+type Collector struct {
+	interfaces.MetricsCollector
+
+	analyses   *Window
+	linkChecks *Window
+	hosts      *HostFailures
+	failures   map[string]*atomic.Int64 // by models.FailureCauses
+
+	analysesInFlight atomic.Int64
+	linkChecksActive atomic.Int64
+	linkChecksQueued atomic.Int64
+	coalesced        atomic.Int64
+	cacheHits        atomic.Int64
+	cacheMisses      atomic.Int64
+	warmup           atomic.Pointer[models.WarmupStats]
+}
+
+// NewCollector wraps next
+func NewCollector(next interfaces.MetricsCollector) *Collector {
+	c := &Collector{
+		MetricsCollector: next,
+		analyses:         NewWindow(Span, windowCapacity),
+		linkChecks:       NewWindow(Span, windowCapacity),
+		hosts:            NewHostFailures(FailureSpan),
+		failures:         make(map[string]*atomic.Int64, len(models.FailureCauses)),
+	}
+	for _, cause := range models.FailureCauses {
+		c.failures[cause] = new(atomic.Int64)
+	}
+	return c
+}
+
+func (c *Collector) RecordAnalysis(success bool, duration float64) {
+	c.analyses.Observe(duration, success)
+	c.MetricsCollector.RecordAnalysis(success, duration)
+}
+
+func (c *Collector) RecordAnalysisFailure(cause string) {
+	c.failures[c.boundCause(cause)].Add(1)
+	c.MetricsCollector.RecordAnalysisFailure(cause)
+}
+
+// RecordHostFailure counts a failed analysis of a page on host towards the
+// top failing hosts
+func (c *Collector) RecordHostFailure(host, cause string) {
+	c.hosts.Record(host, c.boundCause(cause))
+}
+
+// boundCause maps causes outside models.FailureCauses to other
+func (c *Collector) boundCause(cause string) string {
+	if _, ok := c.failures[cause]; ok {
+		return cause
+	}
+	return models.FailureOther
+}
+
+func (c *Collector) RecordLinkCheck(success bool, duration float64) {
+	c.linkChecks.Observe(duration, success)
+	c.MetricsCollector.RecordLinkCheck(success, duration)
+}
+
+func (c *Collector) RecordCoalescedAnalysis() {
+	c.coalesced.Add(1)
+	c.MetricsCollector.RecordCoalescedAnalysis()
+}
+
+func (c *Collector) RecordCacheLookup(hit bool) {
+	if hit {
+		c.cacheHits.Add(1)
+	} else {
+		c.cacheMisses.Add(1)
+	}
+	c.MetricsCollector.RecordCacheLookup(hit)
+}
+
+func (c *Collector) AddAnalysesInFlight(delta int) {
+	c.analysesInFlight.Add(int64(delta))
+	c.MetricsCollector.AddAnalysesInFlight(delta)
+}
+
+func (c *Collector) AddLinkChecksActive(delta int) {
+	c.linkChecksActive.Add(int64(delta))
+	c.MetricsCollector.AddLinkChecksActive(delta)
+}
+
+func (c *Collector) AddLinkChecksQueued(delta int) {
+	c.linkChecksQueued.Add(int64(delta))
+	c.MetricsCollector.AddLinkChecksQueued(delta)
+}
+
+// RecordWarmup keeps the outcome of the analyzer's warm-up
+func (c *Collector) RecordWarmup(took time.Duration, err error) {
+	warmup := &models.WarmupStats{DurationSeconds: took.Seconds()}
+	if err != nil {
+		warmup.Error = err.Error()
+	}
+	c.warmup.Store(warmup)
+}
+
+// Analyses reports the analyzer's load
+func (c *Collector) Analyses() *models.AnalysisStats {
+	recent := c.analyses.Summary()
+	stats := &models.AnalysisStats{
+		InFlight:       c.analysesInFlight.Load(),
+		Recent:         recent.Count,
+		RecentFailed:   recent.Failed,
+		AverageSeconds: recent.AverageSeconds,
+		Coalesced:      c.coalesced.Load(),
+		CacheHits:      c.cacheHits.Load(),
+		CacheMisses:    c.cacheMisses.Load(),
+	}
+	if lookups := stats.CacheHits + stats.CacheMisses; lookups > 0 {
+		stats.CacheHitRate = float64(stats.CacheHits) / float64(lookups)
+	}
+	for cause, count := range c.failures {
+		if n := count.Load(); n > 0 {
+			if stats.FailuresByCause == nil {
+				stats.FailuresByCause = make(map[string]int64)
+			}
+			stats.FailuresByCause[cause] = n
+		}
+	}
+	if top := c.hosts.Top(topFailingHosts); len(top) > 0 {
+		stats.TopFailingHosts = top
+	}
+	if warmup := c.warmup.Load(); warmup != nil {
+		copied := *warmup
+		stats.Warmup = &copied
+	}
+	return stats
+}
+
+// LinkChecks reports the link checker's load
+func (c *Collector) LinkChecks() *models.LinkCheckStats {
+	recent := c.linkChecks.Summary()
+	return &models.LinkCheckStats{
+		ActiveWorkers:  c.linkChecksActive.Load(),
+		Queued:         c.linkChecksQueued.Load(),
+		Recent:         recent.Count,
+		RecentFailed:   recent.Failed,
+		AverageSeconds: recent.AverageSeconds,
+	}
+}
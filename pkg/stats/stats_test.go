@@ -0,0 +1,130 @@
+package stats
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/mocks"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector_ForwardsAndCounts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := mocks.NewMockMetricsCollector(ctrl)
+	next.EXPECT().RecordAnalysis(true, 2.0)
+	next.EXPECT().RecordAnalysis(false, 4.0)
+	next.EXPECT().RecordCoalescedAnalysis()
+	next.EXPECT().RecordCacheLookup(true).Times(3)
+	next.EXPECT().RecordCacheLookup(false)
+	next.EXPECT().AddAnalysesInFlight(1).Times(2)
+	next.EXPECT().AddAnalysesInFlight(-1)
+	next.EXPECT().RecordStage("fetch", 0.5)
+	next.EXPECT().RecordLinkCheck(true, 0.25)
+	next.EXPECT().AddLinkChecksActive(2)
+	next.EXPECT().AddLinkChecksQueued(5)
+
+	c := NewCollector(next)
+	c.AddAnalysesInFlight(1)
+	c.AddAnalysesInFlight(1)
+	c.RecordAnalysis(true, 2)
+	c.AddAnalysesInFlight(-1)
+	c.RecordAnalysis(false, 4)
+	c.RecordCoalescedAnalysis()
+	for _, hit := range []bool{true, false, true, true} {
+		c.RecordCacheLookup(hit)
+	}
+	c.RecordStage("fetch", 0.5)
+	c.RecordLinkCheck(true, 0.25)
+	c.AddLinkChecksActive(2)
+	c.AddLinkChecksQueued(5)
+
+	analyses := c.Analyses()
+	assert.Equal(t, int64(1), analyses.InFlight)
+	assert.Equal(t, 2, analyses.Recent)
+	assert.Equal(t, 1, analyses.RecentFailed)
+	assert.Equal(t, 3.0, analyses.AverageSeconds)
+	assert.Equal(t, int64(1), analyses.Coalesced)
+	assert.Equal(t, int64(3), analyses.CacheHits)
+	assert.Equal(t, int64(1), analyses.CacheMisses)
+	assert.Equal(t, 0.75, analyses.CacheHitRate)
+
+	linkChecks := c.LinkChecks()
+	assert.Equal(t, int64(2), linkChecks.ActiveWorkers)
+	assert.Equal(t, int64(5), linkChecks.Queued)
+	assert.Equal(t, 1, linkChecks.Recent)
+	assert.Equal(t, 0.25, linkChecks.AverageSeconds)
+}
+
+func TestCollector_Failures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := mocks.NewMockMetricsCollector(ctrl)
+	next.EXPECT().RecordAnalysisFailure(models.FailureDNS).Times(2)
+	next.EXPECT().RecordAnalysisFailure("made_up")
+
+	c := NewCollector(next)
+	assert.Nil(t, c.Analyses().FailuresByCause)
+	assert.Nil(t, c.Analyses().TopFailingHosts)
+
+	c.RecordAnalysisFailure(models.FailureDNS)
+	c.RecordAnalysisFailure(models.FailureDNS)
+	c.RecordAnalysisFailure("made_up")
+	c.RecordHostFailure("down.example", models.FailureDNS)
+	c.RecordHostFailure("down.example", "made_up")
+
+	analyses := c.Analyses()
+	assert.Equal(t, map[string]int64{models.FailureDNS: 2, models.FailureOther: 1}, analyses.FailuresByCause)
+	assert.Equal(t, []models.FailingHost{
+		{Host: "down.example", Failures: 2, Causes: map[string]int{models.FailureDNS: 1, models.FailureOther: 1}},
+	}, analyses.TopFailingHosts)
+}
+
+func TestCollector_NoCacheLookups(t *testing.T) {
+	c := NewCollector(mocks.NewMockMetricsCollector(gomock.NewController(t)))
+	assert.Zero(t, c.Analyses().CacheHitRate)
+}
+
+func TestCollector_Warmup(t *testing.T) {
+	c := NewCollector(mocks.NewMockMetricsCollector(gomock.NewController(t)))
+	assert.Nil(t, c.Analyses().Warmup, "not run yet")
+
+	c.RecordWarmup(1500*time.Millisecond, nil)
+	assert.Equal(t, &models.WarmupStats{DurationSeconds: 1.5}, c.Analyses().Warmup)
+
+	c.RecordWarmup(time.Second, errors.New("link checker unreachable"))
+	assert.Equal(t, &models.WarmupStats{DurationSeconds: 1, Error: "link checker unreachable"}, c.Analyses().Warmup)
+}
+
+func TestCollector_ConcurrentGauges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := mocks.NewMockMetricsCollector(ctrl)
+	next.EXPECT().AddLinkChecksActive(gomock.Any()).AnyTimes()
+	next.EXPECT().AddLinkChecksQueued(gomock.Any()).AnyTimes()
+	next.EXPECT().RecordLinkCheck(gomock.Any(), gomock.Any()).AnyTimes()
+
+	c := NewCollector(next)
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				c.AddLinkChecksQueued(1)
+				c.AddLinkChecksQueued(-1)
+				c.AddLinkChecksActive(1)
+				c.RecordLinkCheck(true, 0.1)
+				c.AddLinkChecksActive(-1)
+				c.LinkChecks()
+			}
+		}()
+	}
+	wg.Wait()
+
+	linkChecks := c.LinkChecks()
+	assert.Zero(t, linkChecks.ActiveWorkers)
+	assert.Zero(t, linkChecks.Queued)
+	assert.Equal(t, 2000, linkChecks.Recent)
+}
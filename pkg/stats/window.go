@@ -0,0 +1,87 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// Window keeps the durations observed over a rolling span in a ring buffer of
+// fixed capacity. Past capacity, the newest samples overwrite the oldest, so
+// under heavy load a summary covers the most recent samples rather than the
+// whole span. It is safe for concurrent use.
+type Window struct {
+	span time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	samples []sample
+	next    int
+	size    int
+}
+
+type sample struct {
+	at      time.Time
+	seconds float64
+	failed  bool
+}
+
+// Summary describes the samples within a Window's span
+type Summary struct {
+	Count          int
+	Failed         int
+	AverageSeconds float64
+}
+
+// NewWindow creates a window over span holding at most capacity samples
+func NewWindow(span time.Duration, capacity int) *Window {
+	return &Window{
+		span:    span,
+		now:     time.Now,
+		samples: make([]sample, max(capacity, 1)),
+	}
+}
+
+// Observe records one duration in seconds and whether it succeeded
+func (w *Window) Observe(seconds float64, success bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// Stamped under the lock, so the ring stays in time order
+	w.samples[w.next] = sample{at: w.now(), seconds: seconds, failed: !success}
+	w.next = (w.next + 1) % len(w.samples)
+	w.size = min(w.size+1, len(w.samples))
+}
+
+// Summary summarizes the samples observed within the span
+func (w *Window) Summary() Summary {
+	var summary Summary
+	var total float64
+	w.each(func(s sample) {
+		summary.Count++
+		total += s.seconds
+		if s.failed {
+			summary.Failed++
+		}
+	})
+	if summary.Count > 0 {
+		summary.AverageSeconds = total / float64(summary.Count)
+	}
+	return summary
+}
+
+// each calls fn with the samples observed within the span, newest first
+func (w *Window) each(fn func(s sample)) {
+	cutoff := w.now().Add(-w.span)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Walk from the newest sample back; they are in time order, so the first
+	// one past the cutoff ends the walk
+	for i := range w.size {
+		s := w.samples[(w.next-1-i+len(w.samples))%len(w.samples)]
+		if !s.at.After(cutoff) {
+			break
+		}
+		fn(s)
+	}
+}
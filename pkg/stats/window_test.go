@@ -0,0 +1,81 @@
+package stats
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock returns a window whose time is moved by advance
+func fakeClock(w *Window) (advance func(time.Duration)) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	w.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	return func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+}
+
+func TestWindow_Empty(t *testing.T) {
+	w := NewWindow(time.Minute, 10)
+	assert.Equal(t, Summary{}, w.Summary())
+}
+
+func TestWindow_Expiry(t *testing.T) {
+	w := NewWindow(5*time.Minute, 100)
+	advance := fakeClock(w)
+
+	w.Observe(1, true)
+	w.Observe(3, false)
+	advance(3 * time.Minute)
+	w.Observe(8, true)
+
+	assert.Equal(t, Summary{Count: 3, Failed: 1, AverageSeconds: 4}, w.Summary())
+
+	advance(2 * time.Minute)
+	assert.Equal(t, Summary{Count: 1, AverageSeconds: 8}, w.Summary(), "the first two are exactly one span old")
+
+	advance(3 * time.Minute)
+	assert.Equal(t, Summary{}, w.Summary())
+}
+
+func TestWindow_CapacityKeepsTheNewest(t *testing.T) {
+	w := NewWindow(time.Hour, 3)
+	fakeClock(w)
+
+	for i := 1; i <= 5; i++ {
+		w.Observe(float64(i), i%2 == 0)
+	}
+
+	assert.Equal(t, Summary{Count: 3, Failed: 2, AverageSeconds: 4}, w.Summary(), "3, 4 and 5 remain")
+}
+
+func TestWindow_ConcurrentUpdates(t *testing.T) {
+	const writers, each = 8, 500
+	w := NewWindow(time.Hour, writers*each)
+
+	var wg sync.WaitGroup
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range each {
+				w.Observe(2, i%10 != 0)
+				if i%50 == 0 {
+					w.Summary()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, Summary{Count: writers * each, Failed: writers * each / 10, AverageSeconds: 2}, w.Summary())
+}
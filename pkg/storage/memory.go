@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+type storedResult struct {
+	id      string
+	url     string
+	savedAt time.Time
+	data    []byte
+}
+
+// Memory is an in-process Store holding up to maxResults results; when full
+// the oldest result is dropped. Results are kept as JSON, like the database
+// backends, so callers never share them. Nothing survives a restart.
+type Memory struct {
+	mu         sync.Mutex
+	results    []storedResult // oldest first
+	schedules  map[string]Schedule
+	maxResults int
+
+	now func() time.Time
+}
+
+func NewMemory(maxResults int) *Memory {
+	if maxResults < 1 {
+		maxResults = 1
+	}
+	return &Memory{
+		schedules:  make(map[string]Schedule),
+		maxResults: maxResults,
+		now:        time.Now,
+	}
+}
+
+// SaveResult stores a copy of result
+func (m *Memory) SaveResult(ctx context.Context, result *models.AnalysisResult) (ResultRecord, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return ResultRecord{}, fmt.Errorf("failed to encode result: %w", err)
+	}
+	id, err := NewID()
+	if err != nil {
+		return ResultRecord{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := storedResult{id: id, url: result.URL, savedAt: m.now().UTC(), data: data}
+	if len(m.results) >= m.maxResults {
+		m.results = slices.Delete(m.results, 0, len(m.results)-m.maxResults+1)
+	}
+	m.results = append(m.results, stored)
+	return stored.record()
+}
+
+func (m *Memory) GetResult(ctx context.Context, id string) (ResultRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := slices.IndexFunc(m.results, func(r storedResult) bool { return r.id == id })
+	if i < 0 {
+		return ResultRecord{}, ErrNotFound
+	}
+	return m.results[i].record()
+}
+
+func (m *Memory) ListResults(ctx context.Context, filter ResultFilter) ([]ResultRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	records := []ResultRecord{}
+	for i := len(m.results) - 1; i >= 0 && len(records) < filter.EffectiveLimit(); i-- {
+		if filter.URL != "" && m.results[i].url != filter.URL {
+			continue
+		}
+		record, err := m.results[i].record()
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (m *Memory) SaveSchedule(ctx context.Context, schedule Schedule) (Schedule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if schedule.ID == "" {
+		id, err := NewID()
+		if err != nil {
+			return Schedule{}, err
+		}
+		schedule.ID = id
+		schedule.CreatedAt = m.now().UTC()
+	} else if existing, ok := m.schedules[schedule.ID]; ok {
+		schedule.CreatedAt = existing.CreatedAt
+	} else {
+		return Schedule{}, ErrNotFound
+	}
+
+	m.schedules[schedule.ID] = schedule
+	return schedule, nil
+}
+
+func (m *Memory) GetSchedule(ctx context.Context, id string) (Schedule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedule, ok := m.schedules[id]
+	if !ok {
+		return Schedule{}, ErrNotFound
+	}
+	return schedule, nil
+}
+
+// ListSchedules returns every schedule, the most recently created first
+func (m *Memory) ListSchedules(ctx context.Context) ([]Schedule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedules := make([]Schedule, 0, len(m.schedules))
+	for _, schedule := range m.schedules {
+		schedules = append(schedules, schedule)
+	}
+	slices.SortFunc(schedules, func(a, b Schedule) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return schedules, nil
+}
+
+func (m *Memory) DeleteSchedule(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.schedules[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.schedules, id)
+	return nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+func (r storedResult) record() (ResultRecord, error) {
+	var result models.AnalysisResult
+	if err := json.Unmarshal(r.data, &result); err != nil {
+		return ResultRecord{}, fmt.Errorf("failed to decode result: %w", err)
+	}
+	return ResultRecord{ID: r.id, URL: r.url, SavedAt: r.savedAt, Result: &result}, nil
+}
+
+// Ensure Memory implements Store
+var _ Store = (*Memory)(nil)
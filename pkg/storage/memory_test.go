@@ -0,0 +1,46 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/storage"
+	"github.com/RuvinSL/webpage-analyzer/pkg/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		return storage.NewMemory(100)
+	})
+}
+
+func TestMemory_DropsOldestWhenFull(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemory(3)
+
+	var ids []string
+	for i := range 5 {
+		saved, err := store.SaveResult(ctx, &models.AnalysisResult{URL: fmt.Sprintf("https://example.com/%d", i)})
+		require.NoError(t, err)
+		ids = append(ids, saved.ID)
+	}
+
+	list, err := store.ListResults(ctx, storage.ResultFilter{})
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, ids[4], list[0].ID)
+	assert.Equal(t, ids[2], list[2].ID)
+
+	_, err = store.GetResult(ctx, ids[1])
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestResultFilter_EffectiveLimit(t *testing.T) {
+	assert.Equal(t, storage.DefaultListLimit, storage.ResultFilter{}.EffectiveLimit())
+	assert.Equal(t, 7, storage.ResultFilter{Limit: 7}.EffectiveLimit())
+	assert.Equal(t, storage.MaxListLimit, storage.ResultFilter{Limit: 1 << 20}.EffectiveLimit())
+}
@@ -0,0 +1,20 @@
+CREATE TABLE analysis_results (
+    id       TEXT PRIMARY KEY,
+    url      TEXT NOT NULL,
+    saved_at TIMESTAMPTZ NOT NULL,
+    result   JSONB NOT NULL
+);
+
+CREATE INDEX analysis_results_saved_at_idx ON analysis_results (saved_at DESC, id DESC);
+CREATE INDEX analysis_results_url_saved_at_idx ON analysis_results (url, saved_at DESC, id DESC);
+
+CREATE TABLE schedules (
+    id          TEXT PRIMARY KEY,
+    url         TEXT NOT NULL,
+    interval_ms BIGINT NOT NULL,
+    options     JSONB NOT NULL,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX schedules_next_run_at_idx ON schedules (next_run_at);
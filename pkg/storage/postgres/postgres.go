@@ -0,0 +1,346 @@
+// Package postgres implements storage.Store on PostgreSQL with pgx. The
+// schema is kept in embedded SQL migrations applied by Migrate.
+package postgres
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationLockID is the advisory lock held while migrating, so replicas
+// starting together apply each migration once
+const migrationLockID = 0x77656261 // "weba"
+
+// DefaultQueryTimeout bounds each query when Options leaves it unset
+const DefaultQueryTimeout = 5 * time.Second
+
+// Options configure the connection pool
+type Options struct {
+	DSN             string
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	QueryTimeout    time.Duration
+}
+
+// Store is a storage.Store on a PostgreSQL connection pool
+type Store struct {
+	pool         *pgxpool.Pool
+	queryTimeout time.Duration
+
+	now func() time.Time
+}
+
+// Open connects to the database and checks that it answers. It does not
+// migrate the schema; call Migrate for that.
+func Open(ctx context.Context, opts Options) (*Store, error) {
+	cfg, err := pgxpool.ParseConfig(opts.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid postgres DSN: %w", err)
+	}
+	if opts.MaxConns > 0 {
+		cfg.MaxConns = opts.MaxConns
+	}
+	if opts.MinConns > 0 {
+		cfg.MinConns = opts.MinConns
+	}
+	if opts.MaxConnLifetime > 0 {
+		cfg.MaxConnLifetime = opts.MaxConnLifetime
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create postgres pool: %w", err)
+	}
+
+	s := &Store{pool: pool, queryTimeout: opts.QueryTimeout, now: time.Now}
+	if s.queryTimeout <= 0 {
+		s.queryTimeout = DefaultQueryTimeout
+	}
+
+	pingCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	if err := pool.Ping(pingCtx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to reach postgres: %w", err)
+	}
+	return s, nil
+}
+
+// Migrate applies the embedded migrations not yet recorded in
+// schema_migrations, each in its own transaction, and returns their names
+func (s *Store) Migrate(ctx context.Context) ([]string, error) {
+	names, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(names)
+
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return nil, fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var applied []string
+	for _, name := range names {
+		version := strings.TrimSuffix(strings.TrimPrefix(name, "migrations/"), ".sql")
+
+		var done bool
+		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", version).Scan(&done); err != nil {
+			return applied, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		if done {
+			continue
+		}
+
+		sql, err := migrations.ReadFile(name)
+		if err != nil {
+			return applied, err
+		}
+		err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, string(sql)); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version)
+			return err
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %s failed: %w", version, err)
+		}
+		applied = append(applied, version)
+	}
+	return applied, nil
+}
+
+// SaveResult stores result as JSONB under a new ID
+func (s *Store) SaveResult(ctx context.Context, result *models.AnalysisResult) (storage.ResultRecord, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return storage.ResultRecord{}, fmt.Errorf("failed to encode result: %w", err)
+	}
+	id, err := storage.NewID()
+	if err != nil {
+		return storage.ResultRecord{}, err
+	}
+	record := storage.ResultRecord{ID: id, URL: result.URL, SavedAt: s.timestamp(), Result: result}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err = s.pool.Exec(ctx,
+		"INSERT INTO analysis_results (id, url, saved_at, result) VALUES ($1, $2, $3, $4)",
+		record.ID, record.URL, record.SavedAt, data,
+	)
+	if err != nil {
+		return storage.ResultRecord{}, fmt.Errorf("failed to save result: %w", err)
+	}
+	return record, nil
+}
+
+func (s *Store) GetResult(ctx context.Context, id string) (storage.ResultRecord, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	row := s.pool.QueryRow(ctx, "SELECT id, url, saved_at, result FROM analysis_results WHERE id = $1", id)
+	record, err := scanResult(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.ResultRecord{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return storage.ResultRecord{}, fmt.Errorf("failed to get result: %w", err)
+	}
+	return record, nil
+}
+
+func (s *Store) ListResults(ctx context.Context, filter storage.ResultFilter) ([]storage.ResultRecord, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `SELECT id, url, saved_at, result FROM analysis_results
+		WHERE $1 = '' OR url = $1
+		ORDER BY saved_at DESC, id DESC
+		LIMIT $2`, filter.URL, filter.EffectiveLimit())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list results: %w", err)
+	}
+	records, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (storage.ResultRecord, error) {
+		return scanResult(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list results: %w", err)
+	}
+	if records == nil {
+		records = []storage.ResultRecord{}
+	}
+	return records, nil
+}
+
+// SaveSchedule inserts a schedule without an ID and updates one with an ID,
+// keeping its creation time
+func (s *Store) SaveSchedule(ctx context.Context, schedule storage.Schedule) (storage.Schedule, error) {
+	options, err := json.Marshal(schedule.Options)
+	if err != nil {
+		return storage.Schedule{}, fmt.Errorf("failed to encode schedule options: %w", err)
+	}
+	schedule.NextRunAt = schedule.NextRunAt.UTC().Truncate(time.Microsecond)
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if schedule.ID == "" {
+		if schedule.ID, err = storage.NewID(); err != nil {
+			return storage.Schedule{}, err
+		}
+		schedule.CreatedAt = s.timestamp()
+		_, err = s.pool.Exec(ctx,
+			"INSERT INTO schedules (id, url, interval_ms, options, next_run_at, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
+			schedule.ID, schedule.URL, schedule.Interval.Milliseconds(), options, schedule.NextRunAt, schedule.CreatedAt,
+		)
+		if err != nil {
+			return storage.Schedule{}, fmt.Errorf("failed to save schedule: %w", err)
+		}
+		return schedule, nil
+	}
+
+	err = s.pool.QueryRow(ctx,
+		"UPDATE schedules SET url = $2, interval_ms = $3, options = $4, next_run_at = $5 WHERE id = $1 RETURNING created_at",
+		schedule.ID, schedule.URL, schedule.Interval.Milliseconds(), options, schedule.NextRunAt,
+	).Scan(&schedule.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.Schedule{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return storage.Schedule{}, fmt.Errorf("failed to save schedule: %w", err)
+	}
+	schedule.CreatedAt = schedule.CreatedAt.UTC()
+	return schedule, nil
+}
+
+func (s *Store) GetSchedule(ctx context.Context, id string) (storage.Schedule, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	row := s.pool.QueryRow(ctx, "SELECT id, url, interval_ms, options, next_run_at, created_at FROM schedules WHERE id = $1", id)
+	schedule, err := scanSchedule(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.Schedule{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return storage.Schedule{}, fmt.Errorf("failed to get schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+func (s *Store) ListSchedules(ctx context.Context) ([]storage.Schedule, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, "SELECT id, url, interval_ms, options, next_run_at, created_at FROM schedules ORDER BY created_at DESC, id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	schedules, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (storage.Schedule, error) {
+		return scanSchedule(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	if schedules == nil {
+		schedules = []storage.Schedule{}
+	}
+	return schedules, nil
+}
+
+func (s *Store) DeleteSchedule(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, "DELETE FROM schedules WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// CheckHealth pings the database, for readiness checks
+func (s *Store) CheckHealth(ctx context.Context) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.pool.Ping(ctx)
+}
+
+func (s *Store) Close() error {
+	s.pool.Close()
+	return nil
+}
+
+func (s *Store) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+// timestamp returns the current time at the database's precision
+func (s *Store) timestamp() time.Time {
+	return s.now().UTC().Truncate(time.Microsecond)
+}
+
+func scanResult(row pgx.Row) (storage.ResultRecord, error) {
+	var record storage.ResultRecord
+	var data []byte
+	if err := row.Scan(&record.ID, &record.URL, &record.SavedAt, &data); err != nil {
+		return storage.ResultRecord{}, err
+	}
+	record.SavedAt = record.SavedAt.UTC()
+	if err := json.Unmarshal(data, &record.Result); err != nil {
+		return storage.ResultRecord{}, fmt.Errorf("failed to decode result %s: %w", record.ID, err)
+	}
+	return record, nil
+}
+
+func scanSchedule(row pgx.Row) (storage.Schedule, error) {
+	var schedule storage.Schedule
+	var intervalMs int64
+	var options []byte
+	if err := row.Scan(&schedule.ID, &schedule.URL, &intervalMs, &options, &schedule.NextRunAt, &schedule.CreatedAt); err != nil {
+		return storage.Schedule{}, err
+	}
+	schedule.Interval = time.Duration(intervalMs) * time.Millisecond
+	schedule.NextRunAt = schedule.NextRunAt.UTC()
+	schedule.CreatedAt = schedule.CreatedAt.UTC()
+	if err := json.Unmarshal(options, &schedule.Options); err != nil {
+		return storage.Schedule{}, fmt.Errorf("failed to decode schedule %s: %w", schedule.ID, err)
+	}
+	return schedule, nil
+}
+
+// Ensure Store implements storage.Store
+var _ storage.Store = (*Store)(nil)
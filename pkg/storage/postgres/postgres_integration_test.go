@@ -0,0 +1,72 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/storage"
+	"github.com/RuvinSL/webpage-analyzer/pkg/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+// startPostgres runs a throwaway PostgreSQL container, skipping the test
+// when Docker is not available
+func startPostgres(t *testing.T) string {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctx := context.Background()
+	container, err := tcpostgres.Run(ctx, "postgres:16-alpine",
+		tcpostgres.WithDatabase("analyzer"),
+		tcpostgres.WithUsername("analyzer"),
+		tcpostgres.WithPassword("analyzer"),
+		tcpostgres.BasicWaitStrategies(),
+	)
+	testcontainers.CleanupContainer(t, container)
+	require.NoError(t, err)
+
+	dsn, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	return dsn
+}
+
+func TestStore_Postgres(t *testing.T) {
+	dsn := startPostgres(t)
+	ctx := context.Background()
+
+	admin, err := Open(ctx, Options{DSN: dsn})
+	require.NoError(t, err)
+	defer admin.Close()
+
+	applied, err := admin.Migrate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0001_create_results_and_schedules"}, applied)
+
+	again, err := admin.Migrate(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, again, "migrations are applied once")
+
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		_, err := admin.pool.Exec(ctx, "TRUNCATE analysis_results, schedules")
+		require.NoError(t, err)
+
+		store, err := Open(ctx, Options{DSN: dsn, MaxConns: 4, QueryTimeout: 5 * time.Second})
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+		return store
+	})
+}
+
+func TestOpen_Unreachable(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := Open(ctx, Options{DSN: "postgres://nobody@127.0.0.1:1/none?connect_timeout=1", QueryTimeout: time.Second})
+	assert.Error(t, err)
+}
@@ -0,0 +1,91 @@
+// Package storage persists analysis results and schedules. Store is the
+// contract; Memory implements it in process and the postgres subpackage
+// implements it on PostgreSQL.
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// Backends selectable with STORAGE_BACKEND
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+// ErrNotFound is returned when no record has the requested ID
+var ErrNotFound = errors.New("not found")
+
+// DefaultListLimit applies when a list is requested without a limit
+const DefaultListLimit = 50
+
+// MaxListLimit caps how many records one list call returns
+const MaxListLimit = 500
+
+// Store persists analysis results and schedules. Lists are newest first.
+type Store interface {
+	// SaveResult stores result under a new ID and returns the stored record
+	SaveResult(ctx context.Context, result *models.AnalysisResult) (ResultRecord, error)
+	GetResult(ctx context.Context, id string) (ResultRecord, error)
+	ListResults(ctx context.Context, filter ResultFilter) ([]ResultRecord, error)
+
+	// SaveSchedule creates the schedule when its ID is empty and replaces
+	// the stored one otherwise
+	SaveSchedule(ctx context.Context, schedule Schedule) (Schedule, error)
+	GetSchedule(ctx context.Context, id string) (Schedule, error)
+	ListSchedules(ctx context.Context) ([]Schedule, error)
+	DeleteSchedule(ctx context.Context, id string) error
+
+	Close() error
+}
+
+// ResultRecord is a stored analysis result
+type ResultRecord struct {
+	ID      string                 `json:"id"`
+	URL     string                 `json:"url"`
+	SavedAt time.Time              `json:"saved_at"`
+	Result  *models.AnalysisResult `json:"result"`
+}
+
+// ResultFilter narrows ListResults. An empty URL matches every result.
+type ResultFilter struct {
+	URL   string
+	Limit int
+}
+
+// EffectiveLimit returns the filter's limit, defaulted and capped
+func (f ResultFilter) EffectiveLimit() int {
+	switch {
+	case f.Limit < 1:
+		return DefaultListLimit
+	case f.Limit > MaxListLimit:
+		return MaxListLimit
+	}
+	return f.Limit
+}
+
+// Schedule is a URL to analyze again every Interval
+type Schedule struct {
+	ID        string                 `json:"id"`
+	URL       string                 `json:"url"`
+	Interval  time.Duration          `json:"interval"`
+	Options   models.AnalysisOptions `json:"options"`
+	NextRunAt time.Time              `json:"next_run_at"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// NewID returns a random 32 character hex ID
+func NewID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,158 @@
+// Package storagetest is a conformance suite for storage.Store
+// implementations, run by each backend's tests.
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run checks the storage.Store contract against stores from newStore. Each
+// subtest gets its own empty store.
+func Run(t *testing.T, newStore func(t *testing.T) storage.Store) {
+	t.Run("SaveAndGetResult", func(t *testing.T) { testSaveAndGetResult(t, newStore(t)) })
+	t.Run("ListResults", func(t *testing.T) { testListResults(t, newStore(t)) })
+	t.Run("Schedules", func(t *testing.T) { testSchedules(t, newStore(t)) })
+	t.Run("NotFound", func(t *testing.T) { testNotFound(t, newStore(t)) })
+}
+
+func result(url string) *models.AnalysisResult {
+	return &models.AnalysisResult{
+		URL:         url,
+		HTMLVersion: "HTML5",
+		Title:       "Example Domain",
+		Headings:    models.HeadingCount{H1: 1, H2: 2},
+		Links:       models.LinkSummary{Internal: 3, External: 1, Inaccessible: 1, Total: 4},
+		AnalyzedAt:  time.Date(2025, 3, 14, 15, 9, 26, 0, time.UTC),
+		Timings:     &models.Timings{FetchMs: 12.5, TotalMs: 40},
+	}
+}
+
+func testSaveAndGetResult(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	want := result("https://example.com/")
+
+	saved, err := store.SaveResult(ctx, want)
+	require.NoError(t, err)
+	assert.NotEmpty(t, saved.ID)
+	assert.Equal(t, want.URL, saved.URL)
+	assert.WithinDuration(t, time.Now(), saved.SavedAt, time.Minute)
+
+	got, err := store.GetResult(ctx, saved.ID)
+	require.NoError(t, err)
+	assert.Equal(t, saved.ID, got.ID)
+	assert.Equal(t, want.URL, got.URL)
+	assert.True(t, saved.SavedAt.Equal(got.SavedAt), "saved_at %s, got %s", saved.SavedAt, got.SavedAt)
+	assert.Equal(t, want, got.Result)
+
+	// The stored copy is not shared with the caller
+	got.Result.Title = "changed"
+	again, err := store.GetResult(ctx, saved.ID)
+	require.NoError(t, err)
+	assert.Equal(t, want.Title, again.Result.Title)
+}
+
+func testListResults(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+
+	empty, err := store.ListResults(ctx, storage.ResultFilter{})
+	require.NoError(t, err)
+	assert.NotNil(t, empty)
+	assert.Empty(t, empty)
+
+	var ids []string
+	for i := range 5 {
+		url := fmt.Sprintf("https://example.com/%d", i%2)
+		saved, err := store.SaveResult(ctx, result(url))
+		require.NoError(t, err)
+		ids = append(ids, saved.ID)
+		time.Sleep(2 * time.Millisecond) // distinct saved_at
+	}
+
+	all, err := store.ListResults(ctx, storage.ResultFilter{})
+	require.NoError(t, err)
+	require.Len(t, all, 5)
+	for i, record := range all {
+		assert.Equal(t, ids[len(ids)-1-i], record.ID, "newest first")
+	}
+
+	limited, err := store.ListResults(ctx, storage.ResultFilter{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, limited, 2)
+	assert.Equal(t, ids[4], limited[0].ID)
+
+	byURL, err := store.ListResults(ctx, storage.ResultFilter{URL: "https://example.com/1"})
+	require.NoError(t, err)
+	require.Len(t, byURL, 2)
+	assert.Equal(t, []string{ids[3], ids[1]}, []string{byURL[0].ID, byURL[1].ID})
+}
+
+func testSchedules(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	next := time.Date(2025, 3, 15, 6, 0, 0, 0, time.UTC)
+
+	created, err := store.SaveSchedule(ctx, storage.Schedule{
+		URL:       "https://example.com/",
+		Interval:  6 * time.Hour,
+		Options:   models.AnalysisOptions{FollowFrames: true},
+		NextRunAt: next,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	got, err := store.GetSchedule(ctx, created.ID)
+	require.NoError(t, err)
+	assertSameSchedule(t, created, got)
+
+	created.Interval = time.Hour
+	created.NextRunAt = next.Add(time.Hour)
+	updated, err := store.SaveSchedule(ctx, created)
+	require.NoError(t, err)
+	got, err = store.GetSchedule(ctx, created.ID)
+	require.NoError(t, err)
+	assertSameSchedule(t, updated, got)
+	assert.Equal(t, time.Hour, got.Interval)
+
+	time.Sleep(2 * time.Millisecond)
+	second, err := store.SaveSchedule(ctx, storage.Schedule{URL: "https://example.org/", Interval: time.Hour, NextRunAt: next})
+	require.NoError(t, err)
+
+	list, err := store.ListSchedules(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, second.ID, list[0].ID, "most recently created first")
+
+	require.NoError(t, store.DeleteSchedule(ctx, created.ID))
+	_, err = store.GetSchedule(ctx, created.ID)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func testNotFound(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+
+	_, err := store.GetResult(ctx, "missing")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = store.GetSchedule(ctx, "missing")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = store.SaveSchedule(ctx, storage.Schedule{ID: "missing", URL: "https://example.com/"})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.ErrorIs(t, store.DeleteSchedule(ctx, "missing"), storage.ErrNotFound)
+}
+
+func assertSameSchedule(t *testing.T, want, got storage.Schedule) {
+	t.Helper()
+	assert.Equal(t, want.ID, got.ID)
+	assert.Equal(t, want.URL, got.URL)
+	assert.Equal(t, want.Interval, got.Interval)
+	assert.Equal(t, want.Options, got.Options)
+	assert.True(t, want.NextRunAt.Equal(got.NextRunAt), "next_run_at %s, got %s", want.NextRunAt, got.NextRunAt)
+	assert.True(t, want.CreatedAt.Equal(got.CreatedAt), "created_at %s, got %s", want.CreatedAt, got.CreatedAt)
+}
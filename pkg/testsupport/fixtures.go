@@ -0,0 +1,206 @@
+package testsupport
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// Paths of the canned fixtures NewServer serves
+const (
+	// PathHTML5 is a plain HTML5 page with an internal link, an external one
+	// and a fragment-only link
+	PathHTML5 = "/html5"
+	// PathAbout is the page PathHTML5 links to
+	PathAbout = "/about"
+	// PathXHTML is a legacy XHTML 1.0 Strict page
+	PathXHTML = "/xhtml"
+	// PathBrokenLinks links to BrokenLinkCount pages that answer 404 and
+	// to one that does not
+	PathBrokenLinks = "/broken-links"
+	// PathRedirect reaches PathHTML5 through RedirectHops redirects
+	PathRedirect = "/redirect"
+	// PathSlow answers after SlowDelay
+	PathSlow = "/slow"
+	// PathGzip is served gzip-encoded
+	PathGzip = "/gzip"
+	// PathLoginForm has a login form
+	PathLoginForm = "/login"
+)
+
+// Parameters of the canned fixtures
+const (
+	BrokenLinkCount = 3
+	RedirectHops    = 3
+	SlowDelay       = 300 * time.Millisecond
+)
+
+// Expectation is the part of an analysis a fixture determines; timings,
+// findings and the like vary or follow from it
+type Expectation struct {
+	URL string
+	// FinalURL is only compared when set
+	FinalURL     string
+	HTMLVersion  string
+	Title        string
+	Headings     models.HeadingCount
+	Links        LinkCounts
+	HasLoginForm bool
+}
+
+// LinkCounts are the counts of models.LinkSummary
+type LinkCounts struct {
+	Internal     int
+	External     int
+	Inaccessible int
+	Total        int
+}
+
+// ExpectationOf returns the part of result an Expectation describes
+func ExpectationOf(result *models.AnalysisResult) Expectation {
+	return Expectation{
+		URL:          result.URL,
+		FinalURL:     result.FinalURL,
+		HTMLVersion:  result.HTMLVersion,
+		Title:        result.Title,
+		Headings:     result.Headings,
+		Links:        LinkCounts{result.Links.Internal, result.Links.External, result.Links.Inaccessible, result.Links.Total},
+		HasLoginForm: result.HasLoginForm,
+	}
+}
+
+// AssertMatches reports an error on t unless result is the analysis want
+// describes
+func AssertMatches(t testing.TB, want Expectation, result *models.AnalysisResult) bool {
+	t.Helper()
+	if result == nil {
+		t.Errorf("analysis of %s: no result", want.URL)
+		return false
+	}
+	got := ExpectationOf(result)
+	if want.FinalURL == "" {
+		got.FinalURL = ""
+	}
+	if got != want {
+		t.Errorf("analysis of %s:\n got: %+v\nwant: %+v", want.URL, got, want)
+		return false
+	}
+	return true
+}
+
+// html5Page is the markup of PathHTML5
+const html5Page = `<!DOCTYPE html>
+<html lang="en">
+<head><title>Fixture Home</title></head>
+<body>
+<h1>Fixture Home</h1>
+<h2>Internal</h2>
+<p><a href="` + PathAbout + `">About us</a></p>
+<h2>External</h2>
+<p><a href="` + OtherHostURL + PathAbout + `">The same site under another name</a></p>
+<p><a href="#top">Back to top</a></p>
+</body>
+</html>`
+
+var html5Expectation = Expectation{
+	HTMLVersion: "HTML5",
+	Title:       "Fixture Home",
+	Headings:    models.HeadingCount{H1: 1, H2: 2},
+	Links:       LinkCounts{Internal: 1, External: 1, Total: 2},
+}
+
+// Fixtures returns a site with the canned fixtures and their expectations;
+// further routes can be added to it
+func Fixtures() *Site {
+	site := NewSite().
+		Page(PathHTML5, html5Page).
+		Expect(PathHTML5, html5Expectation).
+		Page(PathAbout, `<!DOCTYPE html><html><head><title>About Us</title></head><body><h1>About Us</h1></body></html>`).
+		Expect(PathAbout, Expectation{
+			HTMLVersion: "HTML5",
+			Title:       "About Us",
+			Headings:    models.HeadingCount{H1: 1},
+		}).
+		Page(PathXHTML, `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="en">
+<head><title>Legacy XHTML Page</title></head>
+<body>
+<h1>Legacy XHTML Page</h1>
+<h3>Details</h3>
+<p><a href="`+PathHTML5+`">Home</a></p>
+</body>
+</html>`).
+		Expect(PathXHTML, Expectation{
+			HTMLVersion: "XHTML 1.0 Strict",
+			Title:       "Legacy XHTML Page",
+			Headings:    models.HeadingCount{H1: 1, H3: 1},
+			Links:       LinkCounts{Internal: 1, Total: 1},
+		}).
+		BrokenLinksPage(PathBrokenLinks, BrokenLinkCount).
+		RedirectChain(PathRedirect, RedirectHops, PathHTML5).
+		SlowPage(PathSlow, SlowDelay, simplePage("Slow Page")).
+		Expect(PathSlow, simpleExpectation("Slow Page")).
+		GzipPage(PathGzip, simplePage("Compressed Page")).
+		Expect(PathGzip, simpleExpectation("Compressed Page")).
+		Page(PathLoginForm, `<!DOCTYPE html>
+<html><head><title>Sign In</title></head>
+<body>
+<h1>Sign In</h1>
+<form action="/session" method="post">
+<input type="text" name="username">
+<input type="password" name="password">
+<button type="submit">Sign in</button>
+</form>
+</body></html>`).
+		Expect(PathLoginForm, Expectation{
+			HTMLVersion:  "HTML5",
+			Title:        "Sign In",
+			Headings:     models.HeadingCount{H1: 1},
+			HasLoginForm: true,
+		})
+
+	redirected := html5Expectation
+	redirected.FinalURL = ServerURL + PathHTML5
+	return site.Expect(PathRedirect, redirected)
+}
+
+// NewServer serves the canned fixtures until the test ends
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	return Fixtures().Start(t)
+}
+
+// BrokenLinksPage serves a page at path linking to n pages under path that
+// answer 404, and to PathAbout, and records its expectation. The site must
+// serve PathAbout for the last link to be accessible.
+func (s *Site) BrokenLinksPage(path string, n int) *Site {
+	var markup strings.Builder
+	markup.WriteString("<!DOCTYPE html>\n<html><head><title>Broken Links</title></head><body>\n<h1>Broken Links</h1>\n")
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&markup, "<p><a href=\"%s/missing/%d\">Missing page %d</a></p>\n", path, i, i)
+	}
+	fmt.Fprintf(&markup, "<p><a href=\"%s\">About us</a></p>\n</body></html>", PathAbout)
+
+	for i := 1; i <= n; i++ {
+		s.Status(fmt.Sprintf("%s/missing/%d", path, i), http.StatusNotFound)
+	}
+	return s.Page(path, markup.String()).Expect(path, Expectation{
+		HTMLVersion: "HTML5",
+		Title:       "Broken Links",
+		Headings:    models.HeadingCount{H1: 1},
+		Links:       LinkCounts{Internal: n + 1, Inaccessible: n, Total: n + 1},
+	})
+}
+
+func simplePage(title string) string {
+	return "<!DOCTYPE html><html><head><title>" + title + "</title></head><body><h1>" + title + "</h1></body></html>"
+}
+
+func simpleExpectation(title string) Expectation {
+	return Expectation{HTMLVersion: "HTML5", Title: title, Headings: models.HeadingCount{H1: 1}}
+}
@@ -0,0 +1,200 @@
+// Package testsupport serves deterministic fixture sites for tests of the
+// analyzer, its services and programs built on pkg/analyzer. NewServer
+// serves the canned fixtures, each with the analysis it is expected to get;
+// NewSite composes custom sites from the same building blocks.
+//
+//	server := testsupport.NewServer(t)
+//	result, err := a.Analyze(ctx, server.PageURL(testsupport.PathHTML5))
+//	testsupport.AssertMatches(t, server.Expected(testsupport.PathHTML5), result)
+//
+// Pages are served from loopback, so analyzers that refuse private addresses
+// must be configured to allow them.
+package testsupport
+
+import (
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Placeholders of page markup, replaced when the page is served: ServerURL
+// with the URL of the server and OtherHostURL with the same server under
+// another host name, so that links to it are external
+const (
+	ServerURL    = "{{server}}"
+	OtherHostURL = "{{other}}"
+)
+
+// Site is a set of routes to serve, built with its methods. Paths that have
+// no route answer 404.
+type Site struct {
+	mu       sync.Mutex
+	routes   map[string]http.Handler
+	expected map[string]Expectation
+}
+
+// NewSite returns an empty site
+func NewSite() *Site {
+	return &Site{
+		routes:   make(map[string]http.Handler),
+		expected: make(map[string]Expectation),
+	}
+}
+
+// Handle serves path with handler
+func (s *Site) Handle(path string, handler http.Handler) *Site {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[path] = handler
+	return s
+}
+
+// Page serves the HTML markup at path
+func (s *Site) Page(path, markup string) *Site {
+	return s.Handle(path, page(markup, false))
+}
+
+// GzipPage serves the HTML markup at path gzip-encoded, to clients that
+// accept it
+func (s *Site) GzipPage(path, markup string) *Site {
+	return s.Handle(path, page(markup, true))
+}
+
+// SlowPage serves the HTML markup at path once delay has passed, or not at
+// all when the client leaves first
+func (s *Site) SlowPage(path string, delay time.Duration, markup string) *Site {
+	inner := page(markup, false)
+	return s.Handle(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			inner.ServeHTTP(w, r)
+		case <-r.Context().Done():
+		}
+	}))
+}
+
+// Status answers path with status and a short page saying so
+func (s *Site) Status(path string, status int) *Site {
+	return s.Handle(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		io.WriteString(w, "<title>"+http.StatusText(status)+"</title>")
+	}))
+}
+
+// Redirect redirects path to target with status, 302 when it is zero.
+// target may be relative to the server.
+func (s *Site) Redirect(path, target string, status int) *Site {
+	if status == 0 {
+		status = http.StatusFound
+	}
+	return s.Handle(path, http.RedirectHandler(target, status))
+}
+
+// RedirectChain redirects path through hops further paths, path/1 to
+// path/hops, and then to target
+func (s *Site) RedirectChain(path string, hops int, target string) *Site {
+	next := path
+	for i := 1; i <= hops; i++ {
+		hop := path + "/" + strconv.Itoa(i)
+		s.Redirect(next, hop, http.StatusFound)
+		next = hop
+	}
+	return s.Redirect(next, target, http.StatusFound)
+}
+
+// Expect records the analysis the page at path is expected to get, for
+// Server.Expected
+func (s *Site) Expect(path string, expectation Expectation) *Site {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expected[path] = expectation
+	return s
+}
+
+// ServeHTTP serves the route of r's path
+func (s *Site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	handler, ok := s.routes[r.URL.Path]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+// page serves markup, its placeholders replaced for the host it was asked
+// for, gzip-encoded when compress is set and the client accepts it
+func page(markup string, compress bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := expand(markup, r.Host)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if !compress || !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			io.WriteString(w, body)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		io.WriteString(gz, body)
+		gz.Close()
+	})
+}
+
+// expand replaces the placeholders of markup for a server reached at host
+func expand(markup, host string) string {
+	return strings.NewReplacer(ServerURL, "http://"+host, OtherHostURL, "http://"+otherHost(host)).Replace(markup)
+}
+
+// otherHost names the loopback server at host by the other of localhost
+// and 127.0.0.1
+func otherHost(host string) string {
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return host
+	}
+	if name == "localhost" {
+		return net.JoinHostPort("127.0.0.1", port)
+	}
+	return net.JoinHostPort("localhost", port)
+}
+
+// Server serves a Site over loopback HTTP
+type Server struct {
+	*httptest.Server
+	site *Site
+}
+
+// Start serves the site until the test ends
+func (s *Site) Start(t testing.TB) *Server {
+	t.Helper()
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return &Server{Server: server, site: s}
+}
+
+// PageURL returns the URL of path on the server
+func (s *Server) PageURL(path string) string {
+	return s.URL + path
+}
+
+// Expected returns the analysis expected of the page at path, its URLs
+// filled in. It panics when the site records none.
+func (s *Server) Expected(path string) Expectation {
+	s.site.mu.Lock()
+	expectation, ok := s.site.expected[path]
+	s.site.mu.Unlock()
+	if !ok {
+		panic("testsupport: no expectation for " + path)
+	}
+	expectation.URL = s.PageURL(path)
+	expectation.FinalURL = strings.ReplaceAll(expectation.FinalURL, ServerURL, s.URL)
+	return expectation
+}
@@ -0,0 +1,143 @@
+package testsupport_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/analyzer"
+	"github.com/RuvinSL/webpage-analyzer/pkg/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAnalyzer(t *testing.T) *analyzer.Analyzer {
+	a := analyzer.New(analyzer.WithLogger(slog.New(slog.DiscardHandler)))
+	t.Cleanup(a.Close)
+	return a
+}
+
+// Every canned fixture gets the analysis it is expected to, through the
+// library facade
+func TestFixtures_MatchTheirExpectations(t *testing.T) {
+	server := testsupport.NewServer(t)
+	a := newAnalyzer(t)
+
+	for _, path := range []string{
+		testsupport.PathHTML5,
+		testsupport.PathAbout,
+		testsupport.PathXHTML,
+		testsupport.PathBrokenLinks,
+		testsupport.PathRedirect,
+		testsupport.PathSlow,
+		testsupport.PathGzip,
+		testsupport.PathLoginForm,
+	} {
+		t.Run(path, func(t *testing.T) {
+			result, err := a.Analyze(context.Background(), server.PageURL(path))
+			require.NoError(t, err)
+			testsupport.AssertMatches(t, server.Expected(path), result)
+		})
+	}
+}
+
+func TestFixtures_Redirect(t *testing.T) {
+	server := testsupport.NewServer(t)
+
+	hops := 0
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		hops++
+		return nil
+	}}
+	resp, err := client.Get(server.PageURL(testsupport.PathRedirect))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, testsupport.RedirectHops+1, hops, "the chain's hops, then the target")
+	assert.Equal(t, server.PageURL(testsupport.PathHTML5), resp.Request.URL.String())
+	assert.Equal(t, server.PageURL(testsupport.PathHTML5), server.Expected(testsupport.PathRedirect).FinalURL)
+}
+
+func TestFixtures_Gzip(t *testing.T) {
+	server := testsupport.NewServer(t)
+
+	req, err := http.NewRequest(http.MethodGet, server.PageURL(testsupport.PathGzip), nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+}
+
+func TestFixtures_SlowPage(t *testing.T) {
+	server := testsupport.NewServer(t)
+
+	start := time.Now()
+	resp, err := http.Get(server.PageURL(testsupport.PathSlow))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.GreaterOrEqual(t, time.Since(start), testsupport.SlowDelay)
+
+	// A client that gives up is not kept waiting
+	client := &http.Client{Timeout: 20 * time.Millisecond}
+	_, err = client.Get(server.PageURL(testsupport.PathSlow))
+	assert.Error(t, err)
+}
+
+func TestSite_Builder(t *testing.T) {
+	site := testsupport.NewSite().
+		Page("/", `<title>Custom</title><a href="/gone">Gone</a><a href="`+testsupport.ServerURL+`/moved">Moved</a><a href="`+testsupport.OtherHostURL+`/">Elsewhere</a>`).
+		Status("/gone", http.StatusGone).
+		Redirect("/moved", "/", http.StatusMovedPermanently).
+		Expect("/", testsupport.Expectation{
+			HTMLVersion: "Unknown/No DOCTYPE",
+			Title:       "Custom",
+			Links:       testsupport.LinkCounts{Internal: 2, External: 1, Inaccessible: 1, Total: 3},
+		})
+	server := site.Start(t)
+
+	resp, err := http.Get(server.PageURL("/"))
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `href="`+server.URL+`/moved"`, "placeholders are expanded")
+	assert.NotContains(t, string(body), testsupport.OtherHostURL)
+
+	resp, err = http.Get(server.PageURL("/unrouted"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	result, err := newAnalyzer(t).Analyze(context.Background(), server.PageURL("/"))
+	require.NoError(t, err)
+	testsupport.AssertMatches(t, server.Expected("/"), result)
+}
+
+// recorder is a testing.TB that records failures instead of failing
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper()                           {}
+func (r *recorder) Errorf(format string, args ...any) { r.failed = true }
+
+func TestAssertMatches_ReportsDifferences(t *testing.T) {
+	server := testsupport.NewServer(t)
+	result, err := newAnalyzer(t).Analyze(context.Background(), server.PageURL(testsupport.PathHTML5))
+	require.NoError(t, err)
+
+	rec := &recorder{TB: t}
+	assert.True(t, testsupport.AssertMatches(rec, server.Expected(testsupport.PathHTML5), result))
+	assert.False(t, rec.failed)
+
+	result.Links.Inaccessible = 1
+	assert.False(t, testsupport.AssertMatches(rec, server.Expected(testsupport.PathHTML5), result))
+	assert.True(t, rec.failed)
+}
@@ -0,0 +1,91 @@
+// Package trace records the outbound requests of a single debug analysis. A
+// Collector is carried in the request context, like a budget, so the HTTP
+// client records every request it makes, in this process or in the link
+// checker. Contexts without one cost a nil check.
+package trace
+
+import (
+	"context"
+	"sync"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// Collector keeps up to a fixed number of requests and counts the rest. It
+// is safe for concurrent use.
+type Collector struct {
+	maxEntries int
+
+	mu       sync.Mutex
+	requests []models.OutboundRequest
+	dropped  int
+}
+
+func New(maxEntries int) *Collector {
+	return &Collector{maxEntries: max(maxEntries, 0)}
+}
+
+// Record adds a finished request, masking credentials in its URL and error
+func (c *Collector) Record(request models.OutboundRequest) {
+	request.URL = logger.RedactURL(request.URL)
+	if request.Error != "" {
+		request.Error = logger.RedactText(request.Error)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.requests) >= c.maxEntries {
+		c.dropped++
+		return
+	}
+	c.requests = append(c.requests, request)
+}
+
+// Merge adds the requests another service made on the collector's behalf,
+// marking those without a source as made by source
+func (c *Collector) Merge(trace models.DebugTrace, source string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, request := range trace.Requests {
+		if len(c.requests) >= c.maxEntries {
+			c.dropped++
+			continue
+		}
+		if request.Source == "" {
+			request.Source = source
+		}
+		c.requests = append(c.requests, request)
+	}
+	c.dropped += trace.Dropped
+}
+
+// Remaining returns how many more requests will be kept, to hand on to
+// another service
+func (c *Collector) Remaining() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.maxEntries - len(c.requests)
+}
+
+// Report returns a copy of the requests recorded so far
+func (c *Collector) Report() *models.DebugTrace {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	requests := make([]models.OutboundRequest, len(c.requests))
+	copy(requests, c.requests)
+	return &models.DebugTrace{Requests: requests, Dropped: c.dropped}
+}
+
+type contextKey struct{}
+
+// WithCollector returns a context whose outbound requests are recorded in c
+func WithCollector(ctx context.Context, c *Collector) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the collector carried by ctx, or nil when it has none
+func FromContext(ctx context.Context) *Collector {
+	c, _ := ctx.Value(contextKey{}).(*Collector)
+	return c
+}
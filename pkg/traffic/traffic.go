@@ -0,0 +1,44 @@
+// Package traffic counts the response bytes downloaded for one analysis, to
+// attribute bandwidth to whoever asked for it. A Meter is carried in the
+// request context, like a budget, so the page fetch and the link checks,
+// in this process or in the link checker, add to the same total.
+package traffic
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Meter counts response body bytes as read from the network, before any
+// decompression. It is safe for concurrent use; a nil Meter counts nothing.
+type Meter struct {
+	bytes atomic.Int64
+}
+
+// Add records n bytes read
+func (m *Meter) Add(n int64) {
+	if m != nil {
+		m.bytes.Add(n)
+	}
+}
+
+// Bytes returns the bytes read so far
+func (m *Meter) Bytes() int64 {
+	if m == nil {
+		return 0
+	}
+	return m.bytes.Load()
+}
+
+type contextKey struct{}
+
+// WithMeter returns a context whose requests add the bytes they read to m
+func WithMeter(ctx context.Context, m *Meter) context.Context {
+	return context.WithValue(ctx, contextKey{}, m)
+}
+
+// FromContext returns the meter carried by ctx, or nil when it has none
+func FromContext(ctx context.Context) *Meter {
+	m, _ := ctx.Value(contextKey{}).(*Meter)
+	return m
+}
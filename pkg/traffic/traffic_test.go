@@ -0,0 +1,38 @@
+package traffic
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeter_Add(t *testing.T) {
+	m := &Meter{}
+
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Add(512)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(51200), m.Bytes())
+}
+
+func TestMeter_Nil(t *testing.T) {
+	var m *Meter
+	m.Add(10)
+	assert.Zero(t, m.Bytes())
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Nil(t, FromContext(context.Background()))
+
+	m := &Meter{}
+	assert.Same(t, m, FromContext(WithMeter(context.Background(), m)))
+}
@@ -0,0 +1,172 @@
+// Package urlutil decides when differently spelled URLs are the same one,
+// under a policy teams can set: whether /pricing/ is /pricing, which
+// tracking parameters such as utm_source are ignored, and whether paths are
+// compared without regard to case. The analyzer dedupes a page's links and
+// keys its result cache with it, and the gateway compares the pages of a
+// batch with it.
+package urlutil
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/idn"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// DefaultStripParams are the tracking parameters ignored by default
+var DefaultStripParams = []string{"utm_*", "gclid", "fbclid"}
+
+// DefaultPolicy keeps trailing slashes and the case of paths, and ignores
+// DefaultStripParams
+func DefaultPolicy() models.URLNormalization {
+	return models.URLNormalization{StripParams: slices.Clone(DefaultStripParams), Case: models.URLCaseHost}
+}
+
+// Validate checks a policy, naming the offending field
+func Validate(policy models.URLNormalization) error {
+	var errs []error
+	switch policy.Case {
+	case "", models.URLCaseHost, models.URLCaseFull:
+	default:
+		errs = append(errs, fmt.Errorf("case: must be %s or %s, got %q", models.URLCaseHost, models.URLCaseFull, policy.Case))
+	}
+	for _, param := range policy.StripParams {
+		if !ValidStripParam(param) {
+			errs = append(errs, fmt.Errorf("strip_params: %q is not a parameter name or a prefix ending in *", param))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidStripParam reports whether param is a query parameter name or a
+// prefix of names ending in *
+func ValidStripParam(param string) bool {
+	name := strings.TrimSuffix(param, "*")
+	return name != "" && !strings.ContainsAny(name, "*&=#")
+}
+
+// Normalizer rewrites URLs to one spelling per URL under a policy; make
+// one with New. A nil Normalizer only lowercases the scheme and host and
+// drops the fragment, like models.NormalizeURL.
+type Normalizer struct {
+	policy models.URLNormalization
+	// names are the parameters stripped by exact name, prefixes those
+	// stripped by prefix, all lowercase
+	names    map[string]bool
+	prefixes []string
+}
+
+// New returns the normalizer of policy, or the error of Validate
+func New(policy models.URLNormalization) (*Normalizer, error) {
+	if err := Validate(policy); err != nil {
+		return nil, err
+	}
+	if policy.Case == "" {
+		policy.Case = models.URLCaseHost
+	}
+	policy.StripParams = slices.Clone(policy.StripParams)
+
+	n := &Normalizer{policy: policy, names: make(map[string]bool)}
+	for _, param := range policy.StripParams {
+		param = strings.ToLower(param)
+		if prefix, ok := strings.CutSuffix(param, "*"); ok {
+			n.prefixes = append(n.prefixes, prefix)
+		} else {
+			n.names[param] = true
+		}
+	}
+	return n, nil
+}
+
+// Default returns the normalizer of DefaultPolicy
+func Default() *Normalizer {
+	n, err := New(DefaultPolicy())
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// Policy returns the policy of n
+func (n *Normalizer) Policy() models.URLNormalization {
+	if n == nil {
+		return models.URLNormalization{Case: models.URLCaseHost}
+	}
+	policy := n.policy
+	policy.StripParams = slices.Clone(policy.StripParams)
+	return policy
+}
+
+// Normalize returns the spelling of rawURL that every spelling of the same
+// URL shares: the scheme and host are lowercased, an internationalized host
+// is put in its ASCII form, the fragment is dropped and an empty path
+// becomes "/", then the policy folds the trailing slash, drops the stripped
+// query parameters, keeping the others in order, and lowercases the path.
+// Unparseable URLs are returned unchanged.
+func (n *Normalizer) Normalize(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return rawURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = idn.ASCIIHost(u.Host)
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" && u.Opaque == "" {
+		u.Path = "/"
+	}
+	if n == nil || u.Opaque != "" {
+		return u.String()
+	}
+
+	if n.policy.Case == models.URLCaseFull {
+		u.Path = strings.ToLower(u.Path)
+		u.RawPath = strings.ToLower(u.RawPath)
+	}
+	if n.policy.FoldTrailingSlash && len(u.Path) > 1 {
+		u.Path = "/" + strings.TrimRight(u.Path[1:], "/")
+		if u.RawPath != "" {
+			u.RawPath = "/" + strings.TrimRight(u.RawPath[1:], "/")
+		}
+	}
+	u.RawQuery = n.stripQuery(u.RawQuery)
+	u.ForceQuery = false
+	return u.String()
+}
+
+// stripQuery drops the stripped parameters from rawQuery, leaving the
+// others as written
+func (n *Normalizer) stripQuery(rawQuery string) string {
+	var kept []string
+	for param := range strings.SplitSeq(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if !n.stripped(strings.ToLower(name)) {
+			kept = append(kept, param)
+		}
+	}
+	return strings.Join(kept, "&")
+}
+
+// stripped reports whether the policy ignores the parameter name, given
+// in lowercase
+func (n *Normalizer) stripped(name string) bool {
+	if n.names[name] {
+		return true
+	}
+	for _, prefix := range n.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
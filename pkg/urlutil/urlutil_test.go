@@ -0,0 +1,145 @@
+package urlutil
+
+import (
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizer_Normalize(t *testing.T) {
+	defaults := DefaultPolicy()
+	keep := models.URLNormalization{}
+	fold := models.URLNormalization{FoldTrailingSlash: true}
+	full := models.URLNormalization{Case: models.URLCaseFull}
+	all := models.URLNormalization{FoldTrailingSlash: true, StripParams: []string{"utm_*", "gclid", "ref"}, Case: models.URLCaseFull}
+
+	tests := []struct {
+		name   string
+		policy models.URLNormalization
+		url    string
+		want   string
+	}{
+		// Always applied
+		{"scheme and host lowercased", keep, "HTTPS://Example.COM/Pricing", "https://example.com/Pricing"},
+		{"fragment dropped", keep, "https://example.com/pricing#plans", "https://example.com/pricing"},
+		{"empty path is the root", keep, "https://example.com", "https://example.com/"},
+		{"surrounding space trimmed", keep, "  https://example.com/pricing ", "https://example.com/pricing"},
+		{"unparseable unchanged", keep, "http://[::1", "http://[::1"},
+		{"opaque unchanged but for case", keep, "MAILTO:Team@Example.com", "mailto:Team@Example.com"},
+		{"unicode host in ASCII form", keep, "https://Bücher.example/Neu", "https://xn--bcher-kva.example/Neu"},
+		{"ASCII form lowercased", keep, "https://XN--BCHER-KVA.example/Neu", "https://xn--bcher-kva.example/Neu"},
+		{"port kept with the ASCII form", keep, "https://bücher.example:8443/", "https://xn--bcher-kva.example:8443/"},
+
+		// Trailing slash
+		{"slash kept by default", defaults, "https://example.com/pricing/", "https://example.com/pricing/"},
+		{"slash folded", fold, "https://example.com/pricing/", "https://example.com/pricing"},
+		{"slashes folded", fold, "https://example.com/pricing//", "https://example.com/pricing"},
+		{"no slash to fold", fold, "https://example.com/pricing", "https://example.com/pricing"},
+		{"root kept", fold, "https://example.com/", "https://example.com/"},
+		{"escaped path folded", fold, "https://example.com/a%2Fb/", "https://example.com/a%2Fb"},
+		{"slash before the query folded", fold, "https://example.com/pricing/?plan=pro", "https://example.com/pricing?plan=pro"},
+
+		// Query parameters
+		{"tracking prefix stripped", defaults, "https://example.com/pricing?utm_source=x&utm_medium=y", "https://example.com/pricing"},
+		{"named parameters stripped", defaults, "https://example.com/pricing?gclid=1&fbclid=2", "https://example.com/pricing"},
+		{"others kept in order", defaults, "https://example.com/p?b=2&utm_source=x&a=1", "https://example.com/p?b=2&a=1"},
+		{"parameter names ignore case", defaults, "https://example.com/p?UTM_Source=x&GCLID=1", "https://example.com/p"},
+		{"escaped names matched", defaults, "https://example.com/p?utm%5Fsource=x&a=1", "https://example.com/p?a=1"},
+		{"prefix needs a match", defaults, "https://example.com/p?utm=1&gclid_extra=2", "https://example.com/p?utm=1&gclid_extra=2"},
+		{"values kept as written", defaults, "https://example.com/p?q=a%20b+c", "https://example.com/p?q=a%20b+c"},
+		{"parameter without a value", defaults, "https://example.com/p?gclid&a", "https://example.com/p?a"},
+		{"empty pairs dropped", defaults, "https://example.com/p?a=1&&b=2&", "https://example.com/p?a=1&b=2"},
+		{"lone question mark dropped", defaults, "https://example.com/p?", "https://example.com/p"},
+		{"nothing stripped without the policy", keep, "https://example.com/p?utm_source=x", "https://example.com/p?utm_source=x"},
+
+		// Case
+		{"path case kept by default", defaults, "https://example.com/Pricing/Plans", "https://example.com/Pricing/Plans"},
+		{"path lowercased", full, "https://example.com/Pricing/Plans", "https://example.com/pricing/plans"},
+		{"escaped path lowercased", full, "https://example.com/A%2FB", "https://example.com/a%2fb"},
+		{"query case kept", full, "https://example.com/P?Q=V", "https://example.com/p?Q=V"},
+
+		// Together
+		{"every rule", all, "HTTPS://Example.com/Pricing/?utm_campaign=x&Plan=Pro&ref=nav#top", "https://example.com/pricing?Plan=Pro"},
+		{"relative reference", all, "/Pricing/?ref=nav", "/pricing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalizer, err := New(tt.policy)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, normalizer.Normalize(tt.url))
+			assert.Equal(t, tt.want, normalizer.Normalize(tt.want), "normalizing twice changes nothing")
+		})
+	}
+}
+
+func TestNormalizer_SameURL(t *testing.T) {
+	normalizer, err := New(models.URLNormalization{FoldTrailingSlash: true, StripParams: DefaultStripParams})
+	require.NoError(t, err)
+
+	pricing := normalizer.Normalize("https://example.com/pricing")
+	for _, spelling := range []string{
+		"https://example.com/pricing/",
+		"https://example.com/pricing?utm_source=x",
+		"https://EXAMPLE.com/pricing/?utm_source=x&gclid=1#faq",
+	} {
+		assert.Equal(t, pricing, normalizer.Normalize(spelling), spelling)
+	}
+	assert.NotEqual(t, pricing, normalizer.Normalize("https://example.com/Pricing"))
+	assert.NotEqual(t, pricing, normalizer.Normalize("https://example.com/pricing?plan=pro"))
+}
+
+func TestNormalizer_Nil(t *testing.T) {
+	var normalizer *Normalizer
+	assert.Equal(t, "https://example.com/Pricing/?utm_source=x", normalizer.Normalize("HTTPS://EXAMPLE.COM/Pricing/?utm_source=x#top"))
+	assert.Equal(t, models.URLNormalization{Case: models.URLCaseHost}, normalizer.Policy())
+	assert.Equal(t, models.NormalizeURL("HTTP://Example.com"), normalizer.Normalize("HTTP://Example.com"))
+}
+
+func TestNormalizer_Policy(t *testing.T) {
+	params := []string{"ref"}
+	normalizer, err := New(models.URLNormalization{StripParams: params})
+	require.NoError(t, err)
+	params[0] = "changed"
+
+	policy := normalizer.Policy()
+	assert.Equal(t, models.URLNormalization{StripParams: []string{"ref"}, Case: models.URLCaseHost}, policy)
+	policy.StripParams[0] = "changed"
+	assert.Equal(t, []string{"ref"}, normalizer.Policy().StripParams)
+
+	assert.Equal(t, DefaultPolicy(), Default().Policy())
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  models.URLNormalization
+		wantErr string
+	}{
+		{name: "zero", policy: models.URLNormalization{}},
+		{name: "default", policy: DefaultPolicy()},
+		{name: "full case", policy: models.URLNormalization{Case: models.URLCaseFull}},
+		{name: "unknown case", policy: models.URLNormalization{Case: "path"}, wantErr: `case: must be host or full, got "path"`},
+		{name: "empty parameter", policy: models.URLNormalization{StripParams: []string{""}}, wantErr: `strip_params: "" is not a parameter name or a prefix ending in *`},
+		{name: "bare wildcard", policy: models.URLNormalization{StripParams: []string{"*"}}, wantErr: `strip_params: "*" is not a parameter name or a prefix ending in *`},
+		{name: "inner wildcard", policy: models.URLNormalization{StripParams: []string{"utm_*_id"}}, wantErr: `strip_params: "utm_*_id" is not a parameter name or a prefix ending in *`},
+		{name: "parameter with a value", policy: models.URLNormalization{StripParams: []string{"ref=nav"}}, wantErr: `strip_params: "ref=nav" is not a parameter name or a prefix ending in *`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.policy)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				_, err = New(tt.policy)
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+			_, err = New(tt.policy)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package version
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// Build information, set at link time:
+//
+//	go build -ldflags "-X github.com/RuvinSL/webpage-analyzer/pkg/version.Version=v1.2.3 \
+//	  -X github.com/RuvinSL/webpage-analyzer/pkg/version.Commit=abc1234 \
+//	  -X github.com/RuvinSL/webpage-analyzer/pkg/version.BuildDate=2025-01-01T00:00:00Z"
+var (
+	Version   string
+	Commit    string
+	BuildDate string
+)
+
+// unset is reported for any build field the linker did not fill in
+const unset = "dev"
+
+var startTime = time.Now()
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Response is the body returned by the /version endpoint
+type Response struct {
+	Info
+	Service string `json:"service"`
+	Uptime  string `json:"uptime"`
+}
+
+// Get returns the build information, defaulting unset fields to "dev"
+func Get() Info {
+	return Info{
+		Version:   orUnset(Version),
+		Commit:    orUnset(Commit),
+		BuildDate: orUnset(BuildDate),
+		GoVersion: runtime.Version(),
+	}
+}
+
+// Uptime returns how long the process has been running
+func Uptime() time.Duration {
+	return time.Since(startTime)
+}
+
+// Handler serves GET /version for the named service
+func Handler(serviceName string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := Response{
+			Info:    Get(),
+			Service: serviceName,
+			Uptime:  Uptime().Round(time.Second).String(),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+func orUnset(value string) string {
+	if value == "" {
+		return unset
+	}
+	return value
+}
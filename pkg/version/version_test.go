@@ -0,0 +1,54 @@
+package version
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet_DefaultsToDev(t *testing.T) {
+	info := Get()
+
+	assert.Equal(t, "dev", info.Version)
+	assert.Equal(t, "dev", info.Commit)
+	assert.Equal(t, "dev", info.BuildDate)
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+}
+
+func TestGet_UsesLinkerValues(t *testing.T) {
+	original := []string{Version, Commit, BuildDate}
+	t.Cleanup(func() { Version, Commit, BuildDate = original[0], original[1], original[2] })
+
+	Version, Commit, BuildDate = "v1.2.3", "abc1234", "2025-01-01T00:00:00Z"
+	info := Get()
+
+	assert.Equal(t, "v1.2.3", info.Version)
+	assert.Equal(t, "abc1234", info.Commit)
+	assert.Equal(t, "2025-01-01T00:00:00Z", info.BuildDate)
+}
+
+func TestHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/version", nil)
+	recorder := httptest.NewRecorder()
+
+	Handler("test-service").ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var body map[string]string
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&body))
+
+	assert.Equal(t, "test-service", body["service"])
+	assert.Equal(t, "dev", body["version"])
+	assert.Equal(t, "dev", body["commit"])
+	assert.Equal(t, "dev", body["build_date"])
+	assert.Equal(t, runtime.Version(), body["go_version"])
+	assert.NotEmpty(t, body["uptime"])
+	assert.Len(t, body, 6)
+}
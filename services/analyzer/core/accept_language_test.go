@@ -0,0 +1,78 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/cache"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLanguageServer serves a German page to clients preferring German and an
+// English one to the rest, each with its own ETag, counting full responses
+func newLanguageServer(t *testing.T, full *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		title := "Welcome"
+		if strings.HasPrefix(r.Header.Get("Accept-Language"), "de") {
+			title = "Willkommen"
+		}
+		etag := fmt.Sprintf("%q", title)
+		w.Header().Set("Vary", "Accept-Language")
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		fmt.Fprintf(w, "<!DOCTYPE html><html><head><title>%s</title></head><body></body></html>", title)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_AnalyzeURLWithOptions_AcceptLanguage(t *testing.T) {
+	var full atomic.Int32
+	server := newLanguageServer(t, &full)
+	analyzer := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+
+	result, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "Welcome", result.Title)
+	assert.Equal(t, models.DefaultAcceptLanguage, result.AcceptLanguage)
+
+	result, err = analyzer.AnalyzeURLWithOptions(context.Background(), server.URL, models.AnalysisOptions{AcceptLanguage: "de-DE,de;q=0.9"})
+	require.NoError(t, err)
+	assert.Equal(t, "Willkommen", result.Title)
+	assert.Equal(t, "de-DE,de;q=0.9", result.AcceptLanguage)
+}
+
+func TestAnalyzer_AnalyzeURLWithOptions_LanguagesAreCachedApart(t *testing.T) {
+	var full atomic.Int32
+	server := newLanguageServer(t, &full)
+	analyzer := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+	analyzer.SetResultCache(cache.NewMemory(10), time.Minute, false)
+	german := models.AnalysisOptions{AcceptLanguage: "de"}
+
+	english, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	first, err := analyzer.AnalyzeURLWithOptions(context.Background(), server.URL, german)
+	require.NoError(t, err)
+	second, err := analyzer.AnalyzeURLWithOptions(context.Background(), server.URL, german)
+	require.NoError(t, err)
+
+	// The German page was not revalidated against the English one's ETag
+	assert.Equal(t, int32(2), full.Load())
+	assert.Equal(t, "Welcome", english.Title)
+	assert.Equal(t, "Willkommen", first.Title)
+	assert.Equal(t, "Willkommen", second.Title)
+	assert.Equal(t, "de", second.AcceptLanguage)
+}
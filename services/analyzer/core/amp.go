@@ -0,0 +1,62 @@
+package core
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/crosspage"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// checkAMP reports how a page relates to its AMP counterpart and checks that
+// the counterpart can be reached: the AMP variant of a page, or the canonical
+// page of an AMP page. An AMP page that is its own canonical is valid
+// standalone AMP and has no counterpart to check.
+func (a *Analyzer) checkAMP(ctx context.Context, pageURL, finalURL string, parsed *models.ParsedHTML) *models.AMPReport {
+	report := &models.AMPReport{
+		IsAMP:        parsed.IsAMP,
+		AMPHTMLURL:   parsed.AMPHTMLURL,
+		CanonicalURL: parsed.CanonicalURL,
+	}
+	if parsed.IsAMP && parsed.CanonicalURL == "" {
+		report.Findings = append(report.Findings, models.AMPFinding{Kind: models.AMPMissingCanonical})
+	}
+
+	page := pageAddresses(pageURL, finalURL)
+	counterparts := map[string]string{} // URL to the finding kind if it is unreachable
+	if parsed.AMPHTMLURL != "" && !slices.Contains(page, crosspage.NormalizeURL(parsed.AMPHTMLURL)) {
+		counterparts[parsed.AMPHTMLURL] = models.AMPUnreachableAMPHTML
+	}
+	if parsed.IsAMP && parsed.CanonicalURL != "" && !slices.Contains(page, crosspage.NormalizeURL(parsed.CanonicalURL)) {
+		counterparts[parsed.CanonicalURL] = models.AMPUnreachableCanonical
+	}
+	if len(counterparts) == 0 {
+		return report
+	}
+
+	pageHost := hostOf(pageURL)
+	var links []models.Link
+	for _, target := range slices.Sorted(maps.Keys(counterparts)) {
+		link := models.Link{URL: target, Text: "amp", Type: models.LinkTypeExternal}
+		if hostOf(target) == pageHost {
+			link.Type = models.LinkTypeInternal
+		}
+		links = append(links, link)
+	}
+
+	statuses, err := a.linkChecker.CheckLinks(ctx, links)
+	if err != nil {
+		a.logger.Warn("Failed to check the AMP counterpart", "error", err)
+	}
+	// Counterparts skipped for lack of budget were not checked and are not
+	// reported
+	for _, status := range statuses {
+		kind, ok := counterparts[status.Link.URL]
+		if !ok || status.Accessible || status.Skipped {
+			continue
+		}
+		report.Findings = append(report.Findings, models.AMPFinding{Kind: kind, URL: status.Link.URL, Detail: status.Error})
+	}
+	return report
+}
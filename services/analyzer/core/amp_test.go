@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAMPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	documents := map[string]string{
+		"/article": `<!DOCTYPE html><html><head><title>Article</title>
+<link rel="amphtml" href="/amp/article"></head><body></body></html>`,
+		"/broken": `<!DOCTYPE html><html><head><title>Broken</title>
+<link rel="amphtml" href="/amp/missing"></head><body></body></html>`,
+		"/amp/article": `<!DOCTYPE html><html ⚡><head><title>Article</title>
+<link rel="canonical" href="/article"></head><body></body></html>`,
+		"/amp/orphan": `<!DOCTYPE html><html amp><head><title>Orphan</title></head><body></body></html>`,
+		"/amp/standalone": `<!DOCTYPE html><html amp><head><title>Standalone</title>
+<link rel="canonical" href="/amp/standalone"></head><body></body></html>`,
+		"/amp/stale": `<!DOCTYPE html><html amp><head><title>Stale</title>
+<link rel="canonical" href="/gone"></head><body></body></html>`,
+		"/plain": `<!DOCTYPE html><html><head><title>Plain</title></head><body></body></html>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		document, ok := documents[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, document)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_AMP(t *testing.T) {
+	server := newAMPServer(t)
+
+	tests := []struct {
+		path     string
+		expected *models.AMPReport
+	}{
+		{
+			path:     "/article",
+			expected: &models.AMPReport{AMPHTMLURL: server.URL + "/amp/article"},
+		},
+		{
+			path: "/broken",
+			expected: &models.AMPReport{
+				AMPHTMLURL: server.URL + "/amp/missing",
+				Findings:   []models.AMPFinding{{Kind: models.AMPUnreachableAMPHTML, URL: server.URL + "/amp/missing", Detail: "HTTP 404"}},
+			},
+		},
+		{
+			path:     "/amp/article",
+			expected: &models.AMPReport{IsAMP: true, CanonicalURL: server.URL + "/article"},
+		},
+		{
+			path: "/amp/orphan",
+			expected: &models.AMPReport{
+				IsAMP:    true,
+				Findings: []models.AMPFinding{{Kind: models.AMPMissingCanonical}},
+			},
+		},
+		{
+			path:     "/amp/standalone",
+			expected: &models.AMPReport{IsAMP: true, CanonicalURL: server.URL + "/amp/standalone"},
+		},
+		{
+			path: "/amp/stale",
+			expected: &models.AMPReport{
+				IsAMP:        true,
+				CanonicalURL: server.URL + "/gone",
+				Findings:     []models.AMPFinding{{Kind: models.AMPUnreachableCanonical, URL: server.URL + "/gone", Detail: "HTTP 404"}},
+			},
+		},
+		{path: "/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result, err := newTestAnalyzer(t, nil, newStatusLinkChecker()).AnalyzeURL(context.Background(), server.URL+tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.AMP)
+			// The counterpart is checked apart from the page's own links
+			assert.Zero(t, result.Links.Total)
+		})
+	}
+}
@@ -1,20 +1,114 @@
 package core
 
 import (
+	"cmp"
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/budget"
+	"github.com/RuvinSL/webpage-analyzer/pkg/cacheability"
+	"github.com/RuvinSL/webpage-analyzer/pkg/findings"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/keyedsem"
+	"github.com/RuvinSL/webpage-analyzer/pkg/linkcheck"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/pathgroups"
+	"github.com/RuvinSL/webpage-analyzer/pkg/robots"
+	"github.com/RuvinSL/webpage-analyzer/pkg/rules"
+	"github.com/RuvinSL/webpage-analyzer/pkg/trace"
+	"github.com/RuvinSL/webpage-analyzer/pkg/traffic"
+	"github.com/RuvinSL/webpage-analyzer/pkg/urlutil"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 )
 
+// DefaultMaxAnalysisTimeout bounds a shared (coalesced) analysis, which is
+// detached from the cancellation of any single caller.
+const DefaultMaxAnalysisTimeout = 60 * time.Second
+
+// ErrRenderingDisabled is returned when rendering is requested but no
+// rendering backend is configured
+var ErrRenderingDisabled = errors.New("javascript rendering is disabled")
+
+// ErrParse is wrapped by the error of a page that could not be parsed
+var ErrParse = errors.New("failed to parse HTML")
+
 type Analyzer struct {
-	httpClient  interfaces.HTTPClient
+	fetcher     interfaces.FetcherStrategy
 	htmlParser  interfaces.HTMLParser
 	linkChecker interfaces.LinkChecker
 	logger      interfaces.Logger
 	metrics     interfaces.MetricsCollector
+
+	// renderer is nil unless rendering is enabled
+	renderer        interfaces.FetcherStrategy
+	renderByDefault bool
+	screenshotter   interfaces.ScreenshotCapturer
+
+	// cache is nil unless revalidation is enabled, see SetResultCache
+	cache        interfaces.Cache
+	cacheTTL     time.Duration
+	recheckLinks bool
+
+	// Outbound budget per analysis; zero maxRequests leaves it off, see SetBudget
+	maxRequests int64
+	maxBytes    int64
+
+	maxFrames int
+	// urls decides which spellings of a URL are the same, see
+	// SetURLNormalization
+	urls *urlutil.Normalizer
+	// paginationHops caps the next links followed, see SetPaginationHops
+	paginationHops int
+
+	// failures is nil unless failing hosts are tracked, see SetFailureTracker
+	failures interfaces.FailureTracker
+
+	// Debug traces are ignored unless traceEntries is set, see SetDebugTrace
+	traceEntries int
+
+	// genericLinkTexts are normalized, see SetGenericLinkTexts
+	genericLinkTexts map[string]bool
+
+	// The title lengths not reported, see SetTitleLength
+	titleMinLength int
+	titleMaxLength int
+
+	// disabledRules run only when a request includes them, see
+	// SetDisabledRules
+	disabledRules []string
+
+	// hosts is nil unless analyses per target host are limited, see
+	// SetHostLimit
+	hosts    *keyedsem.Semaphore
+	hostWait time.Duration
+
+	// Host overrides are refused unless overrideHosts is set, see
+	// SetHostOverrides
+	overrideHosts   []string
+	overridePrivate bool
+
+	group      singleflight.Group
+	maxTimeout time.Duration
+	// runs are the callers of the coalesced analyses in flight, by key
+	runsMu sync.Mutex
+	runs   map[string]*sharedRun
+
+	// warmup is set by the first WarmUp
+	warmupOnce sync.Once
+	warmup     warmup
 }
 
 func NewAnalyzer(
@@ -26,88 +120,520 @@ func NewAnalyzer(
 	metrics interfaces.MetricsCollector,
 ) *Analyzer {
 	return &Analyzer{
-		httpClient:  httpClient,
+		fetcher:     NewHTTPFetcher(httpClient),
 		htmlParser:  htmlParser,
 		linkChecker: linkChecker,
 		logger:      logger,
 		metrics:     metrics,
+		maxTimeout:  DefaultMaxAnalysisTimeout,
+		maxFrames:   DefaultMaxFrames,
+		urls:        urlutil.Default(),
+
+		paginationHops: DefaultPaginationHops,
+
+		titleMinLength: DefaultTitleMinLength,
+		titleMaxLength: DefaultTitleMaxLength,
 	}
 }
 
+// SetMaxTimeout sets the upper bound for a single shared analysis
+func (a *Analyzer) SetMaxTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		a.maxTimeout = timeout
+	}
+}
+
+// SetFetchTimeout bounds each fetch of the built-in HTTP fetcher, the page
+// and its frames, body reads included. A fetch given up on this way fails as
+// a timeout.
+func (a *Analyzer) SetFetchTimeout(timeout time.Duration) {
+	if fetcher, ok := a.fetcher.(*HTTPFetcher); ok {
+		fetcher.SetTimeout(timeout)
+	}
+}
+
+// SetRenderer enables the headless rendering backend. With byDefault set,
+// every analysis is rendered, not only those that request it.
+func (a *Analyzer) SetRenderer(renderer interfaces.FetcherStrategy, byDefault bool) {
+	a.renderer = renderer
+	a.renderByDefault = byDefault && renderer != nil
+}
+
+// SetBudget caps the outbound traffic of each analysis at maxRequests
+// requests, redirects included, and maxBytes response bytes, shared between
+// the page fetch and the link checks. Links left unchecked once the budget
+// is spent are reported as skipped.
+func (a *Analyzer) SetBudget(maxRequests, maxBytes int64) {
+	a.maxRequests = maxRequests
+	a.maxBytes = maxBytes
+}
+
+// SetFailureTracker reports the host and cause of every failed analysis to
+// tracker
+func (a *Analyzer) SetFailureTracker(tracker interfaces.FailureTracker) {
+	a.failures = tracker
+}
+
+// SetDebugTrace lets analyses that ask for it list their outbound requests,
+// up to maxEntries of them. With zero maxEntries the request is ignored.
+func (a *Analyzer) SetDebugTrace(maxEntries int) {
+	a.traceEntries = max(maxEntries, 0)
+}
+
+// SetScreenshotter enables screenshots for analyses that request them
+func (a *Analyzer) SetScreenshotter(screenshotter interfaces.ScreenshotCapturer) {
+	a.screenshotter = screenshotter
+}
+
+// AnalyzeURL analyzes the page at url with the default options
 func (a *Analyzer) AnalyzeURL(ctx context.Context, url string) (*models.AnalysisResult, error) {
+	return a.AnalyzeURLWithOptions(ctx, url, models.AnalysisOptions{})
+}
+
+// AnalyzeURLWithOptions analyzes the page at url. Concurrent calls for the
+// same normalized URL and options share one execution and each caller
+// receives its own copy of the result.
+func (a *Analyzer) AnalyzeURLWithOptions(ctx context.Context, url string, opts models.AnalysisOptions) (*models.AnalysisResult, error) {
+	render := opts.Render || a.renderByDefault
+	if render && a.renderer == nil {
+		return nil, ErrRenderingDisabled
+	}
+	if err := a.checkHostOverrides(opts.HostOverrides, render); err != nil {
+		return nil, err
+	}
+
+	if err := rules.ValidateSelection(opts.Rules); err != nil {
+		return nil, err
+	}
+	selected := a.selectRules(opts.Rules)
+
+	if opts.Screenshot && a.screenshotter == nil {
+		a.logger.Warn("Screenshot requested but screenshots are disabled, continuing without it", "url", logger.RedactURL(url))
+		opts.Screenshot = false
+	}
+
+	if opts.Debug && a.traceEntries == 0 {
+		a.logger.Debug("Debug trace requested but debug traces are disabled, continuing without it", "url", logger.RedactURL(url))
+		opts.Debug = false
+	}
+
+	fetcher := a.fetcher
+	key := a.coalesceKey(url)
+	if render {
+		fetcher = a.renderer
+		key += "|render"
+	}
+	if opts.Screenshot {
+		key += "|screenshot"
+	}
+	if opts.FollowFrames {
+		key += "|frames"
+	}
+	if opts.CheckHreflangReciprocal {
+		key += "|hreflang"
+	}
+	if opts.FollowPagination {
+		key += "|pagination"
+	}
+	if opts.ReportRedirectedLinks {
+		key += "|redirects"
+	}
+	if opts.IncludeHeaders {
+		key += "|headers"
+	}
+	if opts.StrictAuth {
+		key += "|strictauth"
+	}
+	if opts.AcceptLanguage != "" && opts.AcceptLanguage != models.DefaultAcceptLanguage {
+		key += "|lang=" + opts.AcceptLanguage
+	}
+	if !opts.Rules.IsZero() {
+		key += "|rules=" + strings.Join(ruleNames(selected), ",")
+	}
+	if len(opts.Checks) > 0 {
+		checks, err := json.Marshal(opts.Checks)
+		if err != nil {
+			return nil, err
+		}
+		key += "|checks=" + string(checks)
+	}
+	if opts.LinkCheck != nil {
+		linkCheck, err := json.Marshal(opts.LinkCheck)
+		if err != nil {
+			return nil, err
+		}
+		key += "|linkcheck=" + string(linkCheck)
+	}
+	if len(opts.HostOverrides) > 0 {
+		overrides, err := json.Marshal(opts.HostOverrides)
+		if err != nil {
+			return nil, err
+		}
+		key += "|hosts=" + string(overrides)
+	}
+
+	// A trace belongs to the caller that asked for it, so debug analyses are
+	// never shared
+	if opts.Debug {
+		debugCtx, cancel := context.WithTimeout(ctx, a.maxTimeout)
+		defer cancel()
+		return a.run(debugCtx, url, fetcher, opts, selected)
+	}
+
+	for {
+		shared := a.joinRun(key)
+		ch := a.group.DoChan(key, func() (interface{}, error) {
+			// The shared run must survive a caller disconnecting while
+			// others still wait for it, and stops once they have all gone.
+			// It is still bounded by the server max timeout.
+			sharedCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), a.maxTimeout)
+			defer cancel()
+			a.startRun(key, cancel)
+			return a.run(sharedCtx, url, fetcher, opts, selected)
+		})
+
+		select {
+		case res := <-ch:
+			a.leaveRun(key, shared, false)
+			if errors.Is(res.Err, context.Canceled) && ctx.Err() == nil {
+				// Joined as the callers before gave up on the run; start
+				// another
+				continue
+			}
+			if res.Shared {
+				a.metrics.RecordCoalescedAnalysis()
+			}
+			if res.Err != nil {
+				return nil, res.Err
+			}
+			result := cloneResult(res.Val.(*models.AnalysisResult))
+			if result.URL != url {
+				// The shared run was for another spelling of the URL
+				result.URL = url
+				result.DisplayURL = displayURL(url)
+				a.setResultHash(result)
+			}
+			return result, nil
+		case <-ctx.Done():
+			a.leaveRun(key, shared, true)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// run analyzes the page once its host admits another analysis
+func (a *Analyzer) run(ctx context.Context, url string, fetcher interfaces.FetcherStrategy, opts models.AnalysisOptions, selected []Rule) (*models.AnalysisResult, error) {
+	release, err := a.admit(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return a.analyze(ctx, url, fetcher, opts, selected)
+}
+
+func (a *Analyzer) analyze(ctx context.Context, url string, fetcher interfaces.FetcherStrategy, opts models.AnalysisOptions, selected []Rule) (result *models.AnalysisResult, err error) {
 	start := time.Now()
+
+	// Exactly one observation per analysis, flagged by the returned error;
+	// failures are counted by cause as well
+	a.metrics.AddAnalysesInFlight(1)
 	defer func() {
-		duration := time.Since(start).Seconds()
-		a.metrics.RecordAnalysis(true, duration)
+		a.metrics.AddAnalysesInFlight(-1)
+		a.metrics.RecordAnalysis(err == nil, time.Since(start).Seconds())
+		if err != nil {
+			cause := FailureCause(err)
+			a.metrics.RecordAnalysisFailure(cause)
+			// A caller giving up says nothing about the host
+			if a.failures != nil && cause != models.FailureCanceled {
+				a.failures.RecordHostFailure(hostOf(url), cause)
+			}
+		}
 	}()
 
-	a.logger.Info("Starting URL analysis", "url", url)
+	a.logger.Info("Starting URL analysis", "url", logger.RedactURL(url))
 
-	// Fetch the web page
-	response, err := a.fetchWebPage(ctx, url)
+	var spend *budget.Budget
+	if a.maxRequests > 0 {
+		spend = budget.New(a.maxRequests, a.maxBytes)
+		ctx = budget.WithBudget(ctx, spend)
+	}
+
+	meter := &traffic.Meter{}
+	ctx = traffic.WithMeter(ctx, meter)
+
+	var collector *trace.Collector
+	if opts.Debug {
+		collector = trace.New(a.traceEntries)
+		ctx = trace.WithCollector(ctx, collector)
+	}
+
+	// Every fetch of the analysis asks for the same language variant
+	language := cmp.Or(opts.AcceptLanguage, models.DefaultAcceptLanguage)
+	ctx = httpclient.WithHeaders(ctx, http.Header{"Accept-Language": {language}})
+
+	if opts.LinkCheck != nil {
+		ctx = linkcheck.WithOptions(ctx, *opts.LinkCheck)
+	}
+
+	// The page, its frames and its links are all fetched from the
+	// overridden addresses
+	ctx = httpclient.WithHostOverrides(ctx, opts.HostOverrides)
+
+	timings := &models.Timings{}
+
+	// Fetch the web page, revalidating a cached copy when there is one. The
+	// custom checks need the page source, and the headers the page's own
+	// response, neither of which a 304 carries. Pages fetched from
+	// overridden addresses are neither revalidated nor cached, since
+	// another server answers for the same URL without the overrides.
+	stageStart := time.Now()
+	revalidate := len(opts.Checks) == 0 && !opts.IncludeHeaders && len(opts.HostOverrides) == 0
+	response, cached, err := a.fetch(ctx, url, language, fetcher, revalidate)
+	timings.FetchMs = a.recordStage(models.StageFetch, stageStart)
+	// A page asking for authentication is an outcome of its own rather than
+	// a failed analysis, unless the caller wants it to fail
+	if auth := requiresAuth(err); auth != nil && !opts.StrictAuth {
+		a.logger.Info("Page requires authentication", "url", logger.RedactURL(url),
+			"status", auth.StatusCode, "scheme", auth.Scheme, "realm", auth.Realm)
+		result = authResult(url, auth)
+		timings.TotalMs = a.recordStage(models.StageTotal, start)
+		result.Timings = timings
+		result.Traffic = &models.Traffic{Bytes: meter.Bytes()}
+		a.metrics.RecordDownloadedBytes(result.Traffic.Bytes)
+		if collector != nil {
+			result.DebugTrace = collector.Report()
+		}
+		a.setResultHash(result)
+		return result, nil
+	}
 	if err != nil {
-		a.logger.Error("Failed to fetch web page", "url", url, "error", err)
-		a.metrics.RecordAnalysis(false, time.Since(start).Seconds())
+		a.logger.Error("Failed to fetch web page", "url", logger.RedactURL(url), "error", err)
 		return nil, err
 	}
 
-	// Detect HTML version
-	htmlVersion := a.htmlParser.DetectHTMLVersion(response.Body)
+	if err := a.stopped(ctx, url, models.StageFetch); err != nil {
+		return nil, err
+	}
 
-	//fmt.Println("LOG: response.Body =", response.Body)
+	var soft warnings
+	soft.checkRedirect(url, response)
 
-	// Parse HTML content
-	parsed, err := a.htmlParser.ParseHTML(ctx, response.Body, url)
+	validators := models.ValidatorsFrom(response.Headers)
+	var parsed *models.ParsedHTML
+	if cached != nil {
+		parsed = cached.Parsed
+		if validators.IsZero() {
+			validators = cached.Validators
+		}
+		soft.reuse(cached.Result.Warnings)
+	} else {
+		if err := checkContentType(response); err != nil {
+			a.logger.Warn("Page is not HTML", "url", logger.RedactURL(url), "error", err)
+			return nil, err
+		}
+		soft.checkBody(response)
 
-	if err != nil {
-		a.logger.Error("Failed to parse HTML", "url", url, "error", err)
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		// Parse HTML content in a single pass, which also reports the title and HTML version
+		stageStart = time.Now()
+		parsed, err = a.htmlParser.ParseHTML(ctx, response.Body, url)
+		timings.ParseMs = a.recordStage(models.StageParse, stageStart)
+		if err := a.stopped(ctx, url, models.StageParse); err != nil {
+			return nil, err
+		}
+		if err != nil {
+			a.logger.Error("Failed to parse HTML", "url", logger.RedactURL(url), "error", err)
+			return nil, fmt.Errorf("%w: %w", ErrParse, err)
+		}
+		if t := parsed.Truncation; t != nil {
+			a.logger.Warn("Page exceeds the parser limits, analysis is partial", "url", logger.RedactURL(url),
+				"deep_elements", t.DeepElements, "dropped_links", t.DroppedLinks, "dropped_headings", t.DroppedHeadings,
+				"truncated_texts", t.TruncatedTexts, "dropped_anchors", t.DroppedAnchors)
+			soft.checkTruncation(t)
+		}
+	}
+
+	// The cache keeps the page's own parse; frame content is merged into a copy
+	page := parsed
+	var frames []models.Frame
+	if len(parsed.Frames) > 0 {
+		if opts.FollowFrames {
+			page, frames = a.followFrames(ctx, url, fetcher, parsed)
+		} else {
+			frames = listFrames(parsed.Frames)
+		}
 	}
+	framesMerged := page != parsed
 
-	// Count headings
-	headingCount := a.countHeadings(parsed.Headings)
+	// An unchanged page keeps its previous link summary unless links are
+	// rechecked, frames add links the cached summary does not cover, the
+	// redirected links must be listed from fresh statuses, the links are
+	// checked with options of their own, or the links were not checked for
+	// it
+	reuseLinks := cached != nil && !a.recheckLinks && !framesMerged && !opts.ReportRedirectedLinks && opts.LinkCheck == nil &&
+		slices.Contains(cached.Result.Rules, rules.Links)
 
-	// Check links concurrently
-	linkStatuses, err := a.linkChecker.CheckLinks(ctx, parsed.Links)
-	if err != nil {
-		a.logger.Warn("Failed to check some links", "error", err)
-		// Continue with partial results
+	run := &pageRun{
+		analyzer:   a,
+		url:        url,
+		fetcher:    fetcher,
+		opts:       opts,
+		response:   response,
+		parsed:     parsed,
+		page:       page,
+		cached:     cached,
+		reuseLinks: reuseLinks,
+		timings:    timings,
 	}
 
-	// Summarize links
-	linkSummary := a.summarizeLinks(parsed.Links, linkStatuses)
+	// The page facts come from the parse; the rules add the rest
+	result = &models.AnalysisResult{
+		URL:            url,
+		HTMLVersion:    parsed.HTMLVersion,
+		Title:          parsed.Title,
+		Links:          a.summarizeLinks(page.Links, nil),
+		AnalyzedAt:     time.Now(),
+		FinalURL:       response.FinalURL,
+		AcceptLanguage: language,
+		CanonicalURL:   parsed.CanonicalURL,
+		HasFrames:      len(frames) > 0,
+		Frames:         frames,
+		Rules:          ruleNames(selected),
+	}
+	result.DisplayURL = displayURL(url)
+	result.IDNHosts = idnHosts(url, response.FinalURL, page.Links)
+	result.Links.Skipped = maps.Clone(page.SkippedLinks)
+	result.Links.DataURIs = cloneDataURIs(page.DataURIs)
+	if opts.IncludeHeaders {
+		result.StatusCode = response.StatusCode
+		result.ResponseHeaders = responseHeaders(response.Headers)
+	}
+
+	// The rules run alongside ancillary fetches, such as the screenshot; all
+	// of them are bounded by ctx
+	g, gctx := errgroup.WithContext(ctx)
+	for _, rule := range selected {
+		g.Go(func() error {
+			rule.Apply(gctx, run, result)
+			return nil
+		})
+	}
+
+	if len(opts.Checks) > 0 {
+		g.Go(func() error {
+			content, err := decodeBody(response.Body)
+			if err != nil {
+				return err
+			}
+			result.Checks, result.ChecksFailed = evaluateChecks(gctx, content, opts.Checks, checkRegexTimeout)
+			return nil
+		})
+	}
+
+	var shot string
+	if opts.Screenshot {
+		g.Go(func() error {
+			shot = a.captureScreenshot(gctx, url)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	// The rules make do with what they got before ctx was done; the result
+	// is then incomplete and nobody is waiting for it
+	if err := a.stopped(ctx, url, stageRules); err != nil {
+		return nil, err
+	}
+	linkStatuses := run.linkStatuses
+
+	result.Screenshot = shot
+	if slices.Contains(result.Rules, rules.Links) && !reuseLinks {
+		soft.checkLinks(page.Links, linkStatuses)
+	}
+	result.Warnings = soft
+	result.Findings = pageFindings(selected, result)
+	result.FindingSummary = findings.Summarize(result.Findings)
+
+	// Counts that include frame content must not stand in for the page's own
+	if !framesMerged && len(opts.HostOverrides) == 0 {
+		a.storeEntry(ctx, url, language, validators, parsed, result)
+	}
+
+	timings.TotalMs = a.recordStage(models.StageTotal, start)
+	result.Timings = timings
 
-	// Build result
-	result := &models.AnalysisResult{
-		URL:          url,
-		HTMLVersion:  htmlVersion,
-		Title:        parsed.Title,
-		Headings:     headingCount,
-		Links:        linkSummary,
-		HasLoginForm: parsed.HasLoginForm,
-		AnalyzedAt:   time.Now(),
+	if collector != nil {
+		result.DebugTrace = collector.Report()
 	}
 
+	result.Traffic = &models.Traffic{Bytes: meter.Bytes()}
+	a.metrics.RecordDownloadedBytes(result.Traffic.Bytes)
+
+	if spend != nil {
+		usage := spend.Usage()
+		for _, status := range linkStatuses {
+			if status.Skipped {
+				usage.SkippedLinks++
+			}
+		}
+		result.Budget = &usage
+		if usage.Exhausted {
+			a.logger.Warn("Outbound budget exhausted",
+				"url", logger.RedactURL(url),
+				"requests", usage.Requests,
+				"bytes", usage.Bytes,
+				"skipped_links", usage.SkippedLinks,
+			)
+		}
+	}
+
+	a.setResultHash(result)
+
 	a.logger.Info("URL analysis completed",
-		"url", url,
+		"url", logger.RedactURL(url),
 		"duration", time.Since(start),
-		"links_found", len(parsed.Links),
+		"links_found", len(page.Links),
+		"bytes", result.Traffic.Bytes,
 	)
 
 	return result, nil
 }
 
-func (a *Analyzer) fetchWebPage(ctx context.Context, url string) (*models.HTTPResponse, error) {
-	response, err := a.httpClient.Get(ctx, url)
+// stageRules names the rules stage in the logs of a stopped analysis
+const stageRules = "rules"
+
+// stopped returns ctx's error once ctx is done, logging the stage the
+// analysis stopped after, so an analysis whose callers have gone stops
+// between stages instead of running to the end for nobody
+func (a *Analyzer) stopped(ctx context.Context, url, stage string) error {
+	err := ctx.Err()
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+		a.logger.Info("Analysis stopped", "url", logger.RedactURL(url), "stage", stage, "error", err)
 	}
+	return err
+}
 
-	if response.StatusCode >= 400 {
-		return nil, fmt.Errorf("HTTP error: status code %d", response.StatusCode)
-	}
+// recordStage meters the stage that began at start and returns its
+// duration in milliseconds for the result's Timings
+func (a *Analyzer) recordStage(name string, start time.Time) float64 {
+	elapsed := time.Since(start)
+	a.metrics.RecordStage(name, elapsed.Seconds())
+	return float64(elapsed) / float64(time.Millisecond)
+}
+
+// captureScreenshot captures the page as a data: URI. A failed capture is
+// logged and metered, and yields an empty screenshot rather than an error.
+func (a *Analyzer) captureScreenshot(ctx context.Context, url string) string {
+	start := time.Now()
+	png, err := a.screenshotter.CaptureScreenshot(ctx, url)
+	a.metrics.RecordScreenshot(err == nil, time.Since(start).Seconds())
 
-	return response, nil
+	if err != nil {
+		a.logger.Warn("Screenshot failed, continuing without it", "url", logger.RedactURL(url), "error", err)
+		return ""
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
 }
 
 // headings by level
@@ -122,6 +648,10 @@ func (a *Analyzer) countHeadings(headings map[string][]string) models.HeadingCou
 	}
 }
 
+// minBrokenLinkGroup is how many inaccessible internal links must share a
+// path prefix for the summary to group them
+const minBrokenLinkGroup = 3
+
 func (a *Analyzer) summarizeLinks(links []models.Link, statuses []models.LinkStatus) models.LinkSummary {
 	summary := models.LinkSummary{
 		Total: len(links),
@@ -132,7 +662,14 @@ func (a *Analyzer) summarizeLinks(links []models.Link, statuses []models.LinkSta
 		statusMap[status.Link.URL] = status
 	}
 
+	// Each URL counts once towards Unique and the broken groups, under the
+	// first of its spellings
+	unique := make(map[string]bool, len(links))
+	var broken []string
 	for _, link := range links {
+		key := a.urls.Normalize(link.URL)
+		first := !unique[key]
+		unique[key] = true
 		switch link.Type {
 		case models.LinkTypeInternal:
 			summary.Internal++
@@ -140,13 +677,298 @@ func (a *Analyzer) summarizeLinks(links []models.Link, statuses []models.LinkSta
 			summary.External++
 		}
 
-		// Check if link is inaccessible
-		// fmt.Printf("=============== DEBUG ===\n")
-		// fmt.Printf("Service: %s\n", link.URL)
-		if status, exists := statusMap[link.URL]; exists && !status.Accessible {
+		// Check if link is inaccessible; skipped links were never checked
+		status, exists := statusMap[link.URL]
+		if exists && !status.Accessible && !status.Skipped {
 			summary.Inaccessible++
+			if link.Type == models.LinkTypeInternal && first {
+				broken = append(broken, link.URL)
+			}
+		}
+		if exists && status.Redirects > 0 {
+			summary.RedirectedLinks++
 		}
 	}
 
+	summary.Unique = len(unique)
+	summary.SlowestLinks, summary.DurationP50Ms, summary.DurationP95Ms = a.linkDurations(links, statusMap)
+	summary.ExternalDomains, summary.ExternalDomainsOther = externalDomains(links)
+	if groups := pathgroups.Find(broken, minBrokenLinkGroup); len(groups) > 0 {
+		summary.BrokenGroups = groups[:min(models.MaxBrokenLinkGroups, len(groups))]
+	}
 	return summary
 }
+
+// linkDurations lists the slowest of the checked links, once per URL, and
+// the median and 95th percentile of their check durations
+func (a *Analyzer) linkDurations(links []models.Link, statuses map[string]models.LinkStatus) (slowest []models.SlowLink, p50, p95 float64) {
+	seen := make(map[string]bool)
+	var checked []models.SlowLink
+	for _, link := range links {
+		status, ok := statuses[link.URL]
+		key := a.urls.Normalize(link.URL)
+		if !ok || status.DurationMs <= 0 || seen[key] {
+			continue
+		}
+		seen[key] = true
+		checked = append(checked, models.SlowLink{URL: link.URL, DurationMs: status.DurationMs})
+	}
+	if len(checked) == 0 {
+		return nil, 0, 0
+	}
+
+	// Slowest first; links as slow as each other keep their page order
+	slices.SortStableFunc(checked, func(a, b models.SlowLink) int {
+		return cmp.Compare(b.DurationMs, a.DurationMs)
+	})
+	return slices.Clone(checked[:min(models.MaxSlowestLinks, len(checked))]),
+		percentile(checked, 50), percentile(checked, 95)
+}
+
+// percentile returns the nearest-rank p-th percentile of the durations of
+// links sorted slowest first
+func percentile(links []models.SlowLink, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(links))))
+	return links[len(links)-max(rank, 1)].DurationMs
+}
+
+// redirectedLinks lists the internal links whose check followed redirects,
+// once per URL, in page order
+func (a *Analyzer) redirectedLinks(links []models.Link, statuses []models.LinkStatus) []models.RedirectedLink {
+	statusMap := make(map[string]models.LinkStatus, len(statuses))
+	for _, status := range statuses {
+		statusMap[status.Link.URL] = status
+	}
+
+	var found []models.RedirectedLink
+	seen := make(map[string]bool)
+	for _, link := range links {
+		status := statusMap[link.URL]
+		key := a.urls.Normalize(link.URL)
+		if link.Type != models.LinkTypeInternal || status.Redirects == 0 || seen[key] {
+			continue
+		}
+		seen[key] = true
+		found = append(found, models.RedirectedLink{URL: link.URL, FinalURL: status.FinalURL, Redirects: status.Redirects, DurationMs: status.DurationMs})
+	}
+	return found
+}
+
+// pageCacheability evaluates the caching headers of the page fetch. A 304
+// stands for the cached page, and may leave out the validators it confirmed.
+func pageCacheability(response *models.HTTPResponse, cached *revalidationEntry) *models.Cacheability {
+	status, headers := response.StatusCode, response.Headers
+	if cached != nil && headers != nil {
+		status = http.StatusOK
+		headers = headers.Clone()
+		if headers.Get("ETag") == "" && cached.Validators.ETag != "" {
+			headers.Set("ETag", cached.Validators.ETag)
+		}
+		if headers.Get("Last-Modified") == "" && cached.Validators.LastModified != "" {
+			headers.Set("Last-Modified", cached.Validators.LastModified)
+		}
+	}
+	return cacheability.Evaluate(status, headers, time.Now())
+}
+
+// pageRobots reads the robots directives of the page's meta tags and
+// X-Robots-Tag headers. A 304 need not repeat the headers, so without them
+// the cached page's are used.
+func pageRobots(response *models.HTTPResponse, parsed *models.ParsedHTML, cached *revalidationEntry) *models.RobotsReport {
+	headers := response.Headers
+	if cached != nil && len(headers.Values("X-Robots-Tag")) == 0 && cached.Result.Robots != nil {
+		headers = http.Header{}
+		for _, directives := range cached.Result.Robots.Directives {
+			if directives.Source == models.RobotsSourceHeader {
+				for _, value := range directives.Values {
+					headers.Add("X-Robots-Tag", value)
+				}
+			}
+		}
+	}
+	return robots.Evaluate(headers, parsed.RobotsMeta)
+}
+
+// setResultHash sets the ResultHash of result
+func (a *Analyzer) setResultHash(result *models.AnalysisResult) {
+	if hash, err := result.Hash(); err != nil {
+		a.logger.Warn("Failed to hash analysis result", "url", logger.RedactURL(result.URL), "error", err)
+	} else {
+		result.ResultHash = hash
+	}
+}
+
+// coalesceKey normalizes a URL under the analyzer's URL normalization, so
+// that the spellings of the same page it considers equal share one analysis
+func (a *Analyzer) coalesceKey(rawURL string) string {
+	return a.urls.Normalize(rawURL)
+}
+
+// cloneResult returns a copy of result that callers may modify freely
+func cloneResult(result *models.AnalysisResult) *models.AnalysisResult {
+	if result == nil {
+		return nil
+	}
+	clone := *result
+	if result.Timings != nil {
+		timings := *result.Timings
+		clone.Timings = &timings
+	}
+	if result.Titles != nil {
+		titles := *result.Titles
+		clone.Titles = &titles
+	}
+	if result.Budget != nil {
+		usage := *result.Budget
+		clone.Budget = &usage
+	}
+	if result.Traffic != nil {
+		downloaded := *result.Traffic
+		clone.Traffic = &downloaded
+	}
+	if result.Hreflang != nil {
+		report := models.HreflangReport{
+			Alternates: slices.Clone(result.Hreflang.Alternates),
+			Findings:   slices.Clone(result.Hreflang.Findings),
+		}
+		clone.Hreflang = &report
+	}
+	if result.Anchors != nil {
+		report := *result.Anchors
+		report.DuplicateIDs = slices.Clone(result.Anchors.DuplicateIDs)
+		report.DanglingAnchors = slices.Clone(result.Anchors.DanglingAnchors)
+		clone.Anchors = &report
+	}
+	if result.Technology != nil {
+		hints := *result.Technology
+		hints.Products = slices.Clone(result.Technology.Products)
+		clone.Technology = &hints
+	}
+	if result.AMP != nil {
+		report := *result.AMP
+		report.Findings = slices.Clone(result.AMP.Findings)
+		clone.AMP = &report
+	}
+	if result.Pagination != nil {
+		report := *result.Pagination
+		report.Findings = slices.Clone(result.Pagination.Findings)
+		clone.Pagination = &report
+	}
+	clone.IDNHosts = slices.Clone(result.IDNHosts)
+	if result.RequiresAuth != nil {
+		auth := *result.RequiresAuth
+		auth.Challenges = slices.Clone(result.RequiresAuth.Challenges)
+		clone.RequiresAuth = &auth
+	}
+	clone.RedirectedLinks = slices.Clone(result.RedirectedLinks)
+	clone.Rules = slices.Clone(result.Rules)
+	if result.URLNormalization != nil {
+		policy := *result.URLNormalization
+		policy.StripParams = slices.Clone(result.URLNormalization.StripParams)
+		clone.URLNormalization = &policy
+	}
+	clone.Checks = slices.Clone(result.Checks)
+	if result.Warnings != nil {
+		clone.Warnings = make([]models.Warning, len(result.Warnings))
+		for i, warning := range result.Warnings {
+			warning.Context = maps.Clone(warning.Context)
+			clone.Warnings[i] = warning
+		}
+	}
+	if result.DebugTrace != nil {
+		debugTrace := *result.DebugTrace
+		debugTrace.Requests = slices.Clone(result.DebugTrace.Requests)
+		clone.DebugTrace = &debugTrace
+	}
+	if result.ResponseHeaders != nil {
+		clone.ResponseHeaders = make(map[string][]string, len(result.ResponseHeaders))
+		for name, values := range result.ResponseHeaders {
+			clone.ResponseHeaders[name] = slices.Clone(values)
+		}
+	}
+	clone.Links.Skipped = maps.Clone(result.Links.Skipped)
+	clone.Links.DataURIs = cloneDataURIs(result.Links.DataURIs)
+	clone.Links.SlowestLinks = slices.Clone(result.Links.SlowestLinks)
+	clone.Links.ExternalDomains = slices.Clone(result.Links.ExternalDomains)
+	if result.Links.BrokenGroups != nil {
+		clone.Links.BrokenGroups = make([]models.BrokenLinkGroup, len(result.Links.BrokenGroups))
+		for i, group := range result.Links.BrokenGroups {
+			group.Examples = slices.Clone(group.Examples)
+			clone.Links.BrokenGroups[i] = group
+		}
+	}
+	if result.LinkFindings != nil {
+		findings := *result.LinkFindings
+		findings.Findings = make([]models.LinkFinding, len(result.LinkFindings.Findings))
+		for i, finding := range result.LinkFindings.Findings {
+			finding.URLs = slices.Clone(finding.URLs)
+			findings.Findings[i] = finding
+		}
+		clone.LinkFindings = &findings
+	}
+	if result.Accessibility != nil {
+		report := *result.Accessibility
+		report.LinkText = make([]models.LinkFinding, len(result.Accessibility.LinkText))
+		for i, finding := range result.Accessibility.LinkText {
+			finding.URLs = slices.Clone(finding.URLs)
+			finding.Texts = slices.Clone(finding.Texts)
+			report.LinkText[i] = finding
+		}
+		clone.Accessibility = &report
+	}
+	if result.Content != nil {
+		report := *result.Content
+		report.Findings = slices.Clone(result.Content.Findings)
+		clone.Content = &report
+	}
+	if result.Robots != nil {
+		report := *result.Robots
+		report.Directives = make([]models.RobotsDirectives, len(result.Robots.Directives))
+		for i, directives := range result.Robots.Directives {
+			if directives.MaxSnippet != nil {
+				n := *directives.MaxSnippet
+				directives.MaxSnippet = &n
+			}
+			directives.Values = slices.Clone(directives.Values)
+			report.Directives[i] = directives
+		}
+		report.Conflicts = slices.Clone(result.Robots.Conflicts)
+		clone.Robots = &report
+	}
+	if result.Cacheability != nil {
+		verdict := *result.Cacheability
+		if verdict.SharedMaxAgeSeconds != nil {
+			seconds := *verdict.SharedMaxAgeSeconds
+			verdict.SharedMaxAgeSeconds = &seconds
+		}
+		verdict.Vary = slices.Clone(verdict.Vary)
+		verdict.Notes = slices.Clone(verdict.Notes)
+		clone.Cacheability = &verdict
+	}
+	if result.Findings != nil {
+		clone.Findings = make([]models.Finding, len(result.Findings))
+		for i, finding := range result.Findings {
+			finding.Evidence.URLs = slices.Clone(finding.Evidence.URLs)
+			finding.Evidence.Selectors = slices.Clone(finding.Evidence.Selectors)
+			finding.Evidence.Values = slices.Clone(finding.Evidence.Values)
+			clone.Findings[i] = finding
+		}
+	}
+	if result.FindingSummary != nil {
+		summary := *result.FindingSummary
+		summary.ByCategory = maps.Clone(summary.ByCategory)
+		summary.BySeverity = maps.Clone(summary.BySeverity)
+		clone.FindingSummary = &summary
+	}
+	if result.Frames != nil {
+		clone.Frames = make([]models.Frame, len(result.Frames))
+		for i, frame := range result.Frames {
+			if frame.Headings != nil {
+				headings := *frame.Headings
+				frame.Headings = &headings
+			}
+			clone.Frames[i] = frame
+		}
+	}
+	return &clone
+}
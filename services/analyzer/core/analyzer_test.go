@@ -2,12 +2,28 @@ package core
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/budget"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/linkcheck"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
 	"github.com/RuvinSL/webpage-analyzer/pkg/mocks"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/traffic"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -21,6 +37,7 @@ func TestAnalyzer_AnalyzeURL(t *testing.T) {
 		expectedResult *models.AnalysisResult
 		expectedError  bool
 		errorContains  string
+		failureCause   string
 	}{
 		{
 			name: "successful analysis",
@@ -34,16 +51,12 @@ func TestAnalyzer_AnalyzeURL(t *testing.T) {
 						Body:       []byte("<html><head><title>Example</title></head><body><h1>Test</h1></body></html>"),
 					}, nil)
 
-				// Mock HTML version detection
-				htmlParser.EXPECT().
-					DetectHTMLVersion(gomock.Any()).
-					Return("HTML5")
-
 				// Mock HTML parsing
 				htmlParser.EXPECT().
 					ParseHTML(gomock.Any(), gomock.Any(), "https://example.com").
 					Return(&models.ParsedHTML{
-						Title: "Example",
+						HTMLVersion: "HTML5",
+						Title:       "Example",
 						Headings: map[string][]string{
 							"h1": {"Test"},
 						},
@@ -52,6 +65,7 @@ func TestAnalyzer_AnalyzeURL(t *testing.T) {
 							{URL: "https://external.com", Type: models.LinkTypeExternal},
 						},
 						HasLoginForm: false,
+						SkippedLinks: map[string]int{models.LinkSkipFragmentOnly: 2},
 					}, nil)
 
 				// Mock link checking
@@ -87,6 +101,11 @@ func TestAnalyzer_AnalyzeURL(t *testing.T) {
 					External:     1,
 					Inaccessible: 0,
 					Total:        2,
+					Unique:       2,
+					Skipped:      map[string]int{models.LinkSkipFragmentOnly: 2},
+					ExternalDomains: []models.DomainCount{
+						{Domain: "external.com", Count: 1},
+					},
 				},
 				HasLoginForm: false,
 			},
@@ -102,6 +121,7 @@ func TestAnalyzer_AnalyzeURL(t *testing.T) {
 			},
 			expectedError: true,
 			errorContains: "failed to fetch URL",
+			failureCause:  models.FailureOther,
 		},
 		{
 			name: "HTTP error status",
@@ -116,6 +136,23 @@ func TestAnalyzer_AnalyzeURL(t *testing.T) {
 			},
 			expectedError: true,
 			errorContains: "HTTP error: status code 404",
+			failureCause:  models.FailureHTTP4xx,
+		},
+		{
+			name: "PDF is not parsed",
+			url:  "https://example.com/report.pdf",
+			setupMocks: func(httpClient *mocks.MockHTTPClient, htmlParser *mocks.MockHTMLParser, linkChecker *mocks.MockLinkChecker) {
+				httpClient.EXPECT().
+					Get(gomock.Any(), "https://example.com/report.pdf").
+					Return(&models.HTTPResponse{
+						StatusCode: 200,
+						Body:       []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3"),
+						Headers:    http.Header{"Content-Type": {"application/pdf"}},
+					}, nil)
+			},
+			expectedError: true,
+			errorContains: "unsupported content type application/pdf (14 bytes)",
+			failureCause:  models.FailureOther,
 		},
 		{
 			name: "HTML parsing error",
@@ -126,18 +163,82 @@ func TestAnalyzer_AnalyzeURL(t *testing.T) {
 					Return(&models.HTTPResponse{
 						StatusCode: 200,
 						Body:       []byte("invalid html"),
+						Headers:    http.Header{"Content-Type": {"text/html"}},
 					}, nil)
 
-				htmlParser.EXPECT().
-					DetectHTMLVersion(gomock.Any()).
-					Return("Unknown")
-
 				htmlParser.EXPECT().
 					ParseHTML(gomock.Any(), gomock.Any(), "https://example.com").
 					Return(nil, errors.New("invalid HTML structure"))
 			},
 			expectedError: true,
 			errorContains: "failed to parse HTML",
+			failureCause:  models.FailureParse,
+		},
+		{
+			name: "soft issues become warnings",
+			url:  "https://example.com/old",
+			setupMocks: func(httpClient *mocks.MockHTTPClient, htmlParser *mocks.MockHTMLParser, linkChecker *mocks.MockLinkChecker) {
+				httpClient.EXPECT().
+					Get(gomock.Any(), "https://example.com/old").
+					Return(&models.HTTPResponse{
+						StatusCode: 200,
+						Body:       []byte(`<html><head><meta charset="windows-1252"><title>Caf` + "\xe9" + `</title></head><body>`),
+						FinalURL:   "https://example.com/new",
+						Redirects:  1,
+						Truncated:  true,
+					}, nil)
+
+				htmlParser.EXPECT().
+					ParseHTML(gomock.Any(), gomock.Any(), "https://example.com/old").
+					Return(&models.ParsedHTML{
+						HTMLVersion: "HTML5",
+						Title:       "Caf\ufffd",
+						Headings:    map[string][]string{},
+						Links: []models.Link{
+							{URL: "https://example.com/a", Type: models.LinkTypeInternal},
+							{URL: "https://example.com/b", Type: models.LinkTypeInternal},
+							{URL: "https://example.com/c", Type: models.LinkTypeInternal},
+						},
+					}, nil)
+
+				// One link checked, one left out for lack of budget and one
+				// not reported at all
+				linkChecker.EXPECT().
+					CheckLinks(gomock.Any(), gomock.Any()).
+					Return([]models.LinkStatus{
+						{Link: models.Link{URL: "https://example.com/a"}, Accessible: true, StatusCode: 200},
+						{Link: models.Link{URL: "https://example.com/b"}, Skipped: true},
+					}, nil)
+			},
+			expectedResult: &models.AnalysisResult{
+				URL:         "https://example.com/old",
+				HTMLVersion: "HTML5",
+				Title:       "Caf\ufffd",
+				Links:       models.LinkSummary{Internal: 3, Total: 3, Unique: 3},
+				Warnings: []models.Warning{
+					{
+						Code:    models.WarningRedirected,
+						Message: "The URL redirected, https://example.com/new was analyzed instead",
+						Context: map[string]string{"final_url": "https://example.com/new", "redirects": "1"},
+					},
+					{
+						Code:    models.WarningTruncatedBody,
+						Message: "The page is too large, only its first 73 bytes were analyzed",
+						Context: map[string]string{"analyzed_bytes": "73"},
+					},
+					{
+						Code:    models.WarningCharsetFallback,
+						Message: "The page is in windows-1252, which is not supported; it was read as UTF-8 and some text may be garbled",
+						Context: map[string]string{"charset": "windows-1252", "read_as": "utf-8"},
+					},
+					{
+						Code:    models.WarningLinksUnchecked,
+						Message: "2 of 3 links were not checked, the link summary is incomplete",
+						Context: map[string]string{"unchecked": "2", "budget_exhausted": "1", "timed_out": "0", "not_reported": "1"},
+					},
+				},
+			},
+			expectedError: false,
 		},
 		{
 			name: "with login form",
@@ -150,13 +251,10 @@ func TestAnalyzer_AnalyzeURL(t *testing.T) {
 						Body:       []byte("<html><body><form><input type='password'/></form></body></html>"),
 					}, nil)
 
-				htmlParser.EXPECT().
-					DetectHTMLVersion(gomock.Any()).
-					Return("HTML5")
-
 				htmlParser.EXPECT().
 					ParseHTML(gomock.Any(), gomock.Any(), "https://example.com/login").
 					Return(&models.ParsedHTML{
+						HTMLVersion:  "HTML5",
 						Title:        "Login Page",
 						Headings:     map[string][]string{},
 						Links:        []models.Link{},
@@ -209,8 +307,19 @@ func TestAnalyzer_AnalyzeURL(t *testing.T) {
 			mockLogger.EXPECT().Warn(gomock.Any(), gomock.Any()).AnyTimes()
 			mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
 
-			// Set up metrics expectations
-			mockMetrics.EXPECT().RecordAnalysis(gomock.Any(), gomock.Any()).AnyTimes()
+			// Exactly one analysis observation, flagged by the outcome
+			mockMetrics.EXPECT().RecordAnalysis(!tt.expectedError, gomock.Any()).Times(1)
+			mockMetrics.EXPECT().RecordStage(gomock.Any(), gomock.Any()).AnyTimes()
+			// Counted in flight for its duration, whatever the outcome
+			mockMetrics.EXPECT().AddAnalysesInFlight(1).Times(1)
+			mockMetrics.EXPECT().AddAnalysesInFlight(-1).Times(1)
+			// and a failure counted by its cause
+			if tt.expectedError {
+				mockMetrics.EXPECT().RecordAnalysisFailure(tt.failureCause).Times(1)
+			} else {
+				// and the bytes of a completed one
+				mockMetrics.EXPECT().RecordDownloadedBytes(gomock.Any()).Times(1)
+			}
 
 			// Set up test-specific mocks
 			tt.setupMocks(mockHTTPClient, mockHTMLParser, mockLinkChecker)
@@ -239,6 +348,7 @@ func TestAnalyzer_AnalyzeURL(t *testing.T) {
 				assert.Equal(t, tt.expectedResult.Headings, result.Headings)
 				assert.Equal(t, tt.expectedResult.Links, result.Links)
 				assert.Equal(t, tt.expectedResult.HasLoginForm, result.HasLoginForm)
+				assert.Equal(t, tt.expectedResult.Warnings, result.Warnings)
 				assert.WithinDuration(t, time.Now(), result.AnalyzedAt, 1*time.Second)
 			}
 		})
@@ -322,6 +432,86 @@ func TestAnalyzersummarizeLinks(t *testing.T) {
 				External:     2,
 				Inaccessible: 1,
 				Total:        4,
+				Unique:       4,
+				ExternalDomains: []models.DomainCount{
+					{Domain: "broken.com", Count: 1},
+					{Domain: "external.com", Count: 1},
+				},
+			},
+		},
+		{
+			name: "durations",
+			links: []models.Link{
+				{URL: "https://example.com/a", Type: models.LinkTypeInternal},
+				{URL: "https://example.com/b", Type: models.LinkTypeInternal},
+				{URL: "https://example.com/a", Type: models.LinkTypeInternal},
+				{URL: "https://example.com/c", Type: models.LinkTypeInternal},
+				{URL: "https://example.com/d", Type: models.LinkTypeInternal},
+				{URL: "https://example.com/e", Type: models.LinkTypeInternal},
+				{URL: "https://example.com/f", Type: models.LinkTypeInternal},
+				{URL: "https://example.com/skipped", Type: models.LinkTypeInternal},
+			},
+			statuses: []models.LinkStatus{
+				{Link: models.Link{URL: "https://example.com/a"}, Accessible: true, DurationMs: 40},
+				{Link: models.Link{URL: "https://example.com/b"}, Accessible: true, DurationMs: 10},
+				{Link: models.Link{URL: "https://example.com/c"}, Accessible: false, DurationMs: 900},
+				{Link: models.Link{URL: "https://example.com/d"}, Accessible: true, DurationMs: 20},
+				{Link: models.Link{URL: "https://example.com/e"}, Accessible: true, DurationMs: 40},
+				{Link: models.Link{URL: "https://example.com/f"}, Accessible: true, DurationMs: 30},
+				{Link: models.Link{URL: "https://example.com/skipped"}, Skipped: true},
+			},
+			expected: models.LinkSummary{
+				Internal:     8,
+				Inaccessible: 1,
+				Total:        8,
+				Unique:       7,
+				// Each link once, the skipped one left out; a and e are as
+				// slow as each other and keep their page order
+				SlowestLinks: []models.SlowLink{
+					{URL: "https://example.com/c", DurationMs: 900},
+					{URL: "https://example.com/a", DurationMs: 40},
+					{URL: "https://example.com/e", DurationMs: 40},
+					{URL: "https://example.com/f", DurationMs: 30},
+					{URL: "https://example.com/d", DurationMs: 20},
+				},
+				DurationP50Ms: 30,
+				DurationP95Ms: 900,
+			},
+		},
+		{
+			name: "broken groups",
+			links: []models.Link{
+				{URL: "https://example.com/blog/2021/a", Type: models.LinkTypeInternal},
+				{URL: "https://example.com/blog/2021/b", Type: models.LinkTypeInternal},
+				{URL: "https://example.com/blog/2021/c", Type: models.LinkTypeInternal},
+				{URL: "https://example.com/blog/2021/d", Type: models.LinkTypeInternal},
+				{URL: "https://example.com/blog/2022/a", Type: models.LinkTypeInternal},
+				{URL: "https://external.com/blog/2021/e", Type: models.LinkTypeExternal},
+				{URL: "https://example.com/blog/2021/skipped", Type: models.LinkTypeInternal},
+			},
+			statuses: []models.LinkStatus{
+				{Link: models.Link{URL: "https://example.com/blog/2021/a"}, Accessible: false},
+				{Link: models.Link{URL: "https://example.com/blog/2021/b"}, Accessible: false},
+				{Link: models.Link{URL: "https://example.com/blog/2021/c"}, Accessible: false},
+				{Link: models.Link{URL: "https://example.com/blog/2021/d"}, Accessible: true},
+				{Link: models.Link{URL: "https://example.com/blog/2022/a"}, Accessible: false},
+				{Link: models.Link{URL: "https://external.com/blog/2021/e"}, Accessible: false},
+				{Link: models.Link{URL: "https://example.com/blog/2021/skipped"}, Skipped: true},
+			},
+			expected: models.LinkSummary{
+				Internal:        6,
+				External:        1,
+				Inaccessible:    5,
+				Total:           7,
+				Unique:          7,
+				ExternalDomains: []models.DomainCount{{Domain: "external.com", Count: 1}},
+				// Only the inaccessible internal links are grouped; the one
+				// under /blog/2022 is too few for a group of its own
+				BrokenGroups: []models.BrokenLinkGroup{{
+					Pattern:  "/blog/2021/*",
+					Count:    3,
+					Examples: []string{"https://example.com/blog/2021/a", "https://example.com/blog/2021/b", "https://example.com/blog/2021/c"},
+				}},
 			},
 		},
 		{
@@ -344,3 +534,789 @@ func TestAnalyzersummarizeLinks(t *testing.T) {
 		})
 	}
 }
+
+// countingHTTPClient counts upstream fetches and holds each one open long
+// enough for concurrent callers to pile up behind it
+type countingHTTPClient struct {
+	calls int32
+	delay time.Duration
+	body  []byte
+}
+
+func (c *countingHTTPClient) Get(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	atomic.AddInt32(&c.calls, 1)
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &models.HTTPResponse{StatusCode: 200, Body: c.body}, nil
+}
+
+func (c *countingHTTPClient) Head(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	return &models.HTTPResponse{StatusCode: 200}, nil
+}
+
+func newCoalescingTestAnalyzer(t *testing.T, httpClient *countingHTTPClient) (*Analyzer, *mocks.MockMetricsCollector) {
+	ctrl := gomock.NewController(t)
+
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Info(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Error(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Warn(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+
+	mockLinkChecker := mocks.NewMockLinkChecker(ctrl)
+	mockLinkChecker.EXPECT().CheckLinks(gomock.Any(), gomock.Any()).Return([]models.LinkStatus{}, nil).AnyTimes()
+
+	mockMetrics := mocks.NewMockMetricsCollector(ctrl)
+	mockMetrics.EXPECT().RecordAnalysis(gomock.Any(), gomock.Any()).AnyTimes()
+	mockMetrics.EXPECT().RecordAnalysisFailure(gomock.Any()).AnyTimes()
+	mockMetrics.EXPECT().RecordStage(gomock.Any(), gomock.Any()).AnyTimes()
+	mockMetrics.EXPECT().AddAnalysesInFlight(gomock.Any()).AnyTimes()
+	mockMetrics.EXPECT().RecordDownloadedBytes(gomock.Any()).AnyTimes()
+
+	analyzer := NewAnalyzer(httpClient, NewHTMLParser(mockLogger), mockLinkChecker, mockLogger, mockMetrics)
+	return analyzer, mockMetrics
+}
+
+func TestAnalyzer_AnalyzeURL_CoalescesConcurrentRequests(t *testing.T) {
+	httpClient := &countingHTTPClient{
+		delay: 100 * time.Millisecond,
+		body:  []byte("<!DOCTYPE html><html><head><title>Shared</title></head><body><h1>Hi</h1></body></html>"),
+	}
+	analyzer, mockMetrics := newCoalescingTestAnalyzer(t, httpClient)
+	mockMetrics.EXPECT().RecordCoalescedAnalysis().MinTimes(1)
+
+	const callers = 5
+	results := make([]*models.AnalysisResult, callers)
+	errs := make([]error, callers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Spellings differing only in host case and fragment share one run
+			url := "https://example.com/"
+			if i%2 == 1 {
+				url = "https://EXAMPLE.com/#top"
+			}
+			results[i], errs[i] = analyzer.AnalyzeURL(context.Background(), url)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&httpClient.calls))
+	for i := 0; i < callers; i++ {
+		require.NoError(t, errs[i])
+		require.NotNil(t, results[i])
+		assert.Equal(t, "Shared", results[i].Title)
+		assert.Equal(t, 1, results[i].Headings.H1)
+	}
+
+	// Each caller gets its own copy
+	results[0].Title = "modified"
+	assert.Equal(t, "Shared", results[1].Title)
+}
+
+func TestAnalyzer_AnalyzeURL_CallerCancellationDoesNotAbortSharedRun(t *testing.T) {
+	httpClient := &countingHTTPClient{
+		delay: 100 * time.Millisecond,
+		body:  []byte("<html><head><title>Survivor</title></head></html>"),
+	}
+	analyzer, mockMetrics := newCoalescingTestAnalyzer(t, httpClient)
+	mockMetrics.EXPECT().RecordCoalescedAnalysis().AnyTimes()
+
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := analyzer.AnalyzeURL(cancelledCtx, "https://example.com")
+		firstErr <- err
+	}()
+
+	// Let the first caller start the shared run, then disconnect it
+	time.Sleep(20 * time.Millisecond)
+	secondResult := make(chan *models.AnalysisResult, 1)
+	go func() {
+		result, _ := analyzer.AnalyzeURL(context.Background(), "https://example.com")
+		secondResult <- result
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	assert.ErrorIs(t, <-firstErr, context.Canceled)
+
+	result := <-secondResult
+	require.NotNil(t, result)
+	assert.Equal(t, "Survivor", result.Title)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&httpClient.calls))
+}
+
+func TestAnalyzer_AnalyzeURL_SharedRunBoundedByMaxTimeout(t *testing.T) {
+	httpClient := &countingHTTPClient{delay: time.Second}
+	analyzer, mockMetrics := newCoalescingTestAnalyzer(t, httpClient)
+	mockMetrics.EXPECT().RecordCoalescedAnalysis().AnyTimes()
+	analyzer.SetMaxTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+	_, err := analyzer.AnalyzeURL(context.Background(), "https://example.com")
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+// cancellingHTTPClient serves a page with a link, calling cancel first if
+// it is set, or waits for the request to be abandoned if block is set
+type cancellingHTTPClient struct {
+	cancel  context.CancelFunc
+	block   bool
+	stopped chan error
+}
+
+func (c *cancellingHTTPClient) Get(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	if c.block {
+		<-ctx.Done()
+		c.stopped <- ctx.Err()
+		return nil, ctx.Err()
+	}
+	if c.cancel != nil {
+		c.cancel()
+	}
+	return &models.HTTPResponse{StatusCode: 200, Body: []byte(`<html><body><a href="/next">Next</a></body></html>`)}, nil
+}
+
+func (c *cancellingHTTPClient) Head(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	return &models.HTTPResponse{StatusCode: 200}, nil
+}
+
+func TestAnalyzer_AnalyzeURL_SharedRunStopsWhenEveryCallerLeaves(t *testing.T) {
+	httpClient := &cancellingHTTPClient{block: true, stopped: make(chan error, 1)}
+	analyzer := newTestAnalyzer(t, httpClient, &brokenLinkChecker{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err := analyzer.AnalyzeURL(ctx, "https://example.com")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	select {
+	case err := <-httpClient.stopped:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("the shared run went on without callers")
+	}
+}
+
+func TestAnalyzer_Analyze_StopsBetweenStages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	httpClient := &cancellingHTTPClient{cancel: cancel}
+	linkChecker := &brokenLinkChecker{}
+	analyzer := newTestAnalyzer(t, httpClient, linkChecker)
+
+	// The page arrives as the caller gives up
+	result, err := analyzer.analyze(ctx, "https://example.com", analyzer.fetcher, models.AnalysisOptions{}, analyzer.selectRules(models.RuleSelection{}))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, ErrParse)
+	assert.Nil(t, result)
+	assert.Zero(t, linkChecker.calls.Load(), "the links were not checked")
+}
+
+func TestAnalyzer_AnalyzeURL_ResultHashIsStable(t *testing.T) {
+	title := "Stable"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<!DOCTYPE html><html><head><title>"+title+`</title></head><body>
+			<h1>One</h1><h2>Two</h2><a href="/a">A</a><a href="https://broken.example/">B</a><a href="#top">Top</a></body></html>`)
+	}))
+	defer server.Close()
+	analyzer := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+
+	first, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	second, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, first.ResultHash)
+	assert.Equal(t, first.ResultHash, second.ResultHash, "the runs differ in timings only")
+	hash, err := second.Hash()
+	require.NoError(t, err)
+	assert.Equal(t, second.ResultHash, hash)
+
+	title = "Changed"
+	third, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ResultHash, third.ResultHash)
+}
+
+// optionsLinkChecker records the link check options its batches ran with
+type optionsLinkChecker struct {
+	mu      sync.Mutex
+	options []*models.LinkCheckOptions
+}
+
+func (c *optionsLinkChecker) CheckLinks(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+	var recorded *models.LinkCheckOptions
+	if opts, ok := linkcheck.FromContext(ctx); ok {
+		recorded = &opts
+	}
+	c.mu.Lock()
+	c.options = append(c.options, recorded)
+	c.mu.Unlock()
+
+	statuses := make([]models.LinkStatus, len(links))
+	for i, link := range links {
+		statuses[i] = c.CheckLink(ctx, link)
+	}
+	return statuses, nil
+}
+
+func (c *optionsLinkChecker) CheckLink(ctx context.Context, link models.Link) models.LinkStatus {
+	return models.LinkStatus{Link: link, Accessible: true, StatusCode: http.StatusOK}
+}
+
+func TestAnalyzer_AnalyzeURLWithOptions_LinkCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<!DOCTYPE html><html><head><title>Links</title></head><body><a href="/about">About</a></body></html>`)
+	}))
+	defer server.Close()
+
+	linkChecker := &optionsLinkChecker{}
+	analyzer := newTestAnalyzer(t, nil, linkChecker)
+
+	_, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	want := &models.LinkCheckOptions{Method: models.LinkCheckHead, Retries: 1}
+	_, err = analyzer.AnalyzeURLWithOptions(context.Background(), server.URL, models.AnalysisOptions{LinkCheck: want})
+	require.NoError(t, err)
+
+	assert.Equal(t, []*models.LinkCheckOptions{nil, want}, linkChecker.options)
+}
+
+const spaShell = `<!DOCTYPE html><html><head><title>App</title></head><body><div id="app"></div></body></html>`
+const spaRendered = `<!DOCTYPE html><html><head><title>App</title></head><body><div id="app"><h1>Home</h1><h2>News</h2></div></body></html>`
+
+func TestAnalyzer_AnalyzeURLWithOptions_Render(t *testing.T) {
+	tests := []struct {
+		name         string
+		render       bool
+		byDefault    bool
+		expectedH1   int
+		renderCalled bool
+	}{
+		{"plain fetch by default", false, false, 0, false},
+		{"render on request", true, false, 1, true},
+		{"render by default", false, true, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := &countingHTTPClient{body: []byte(spaShell)}
+			analyzer, _ := newCoalescingTestAnalyzer(t, httpClient)
+
+			renderer := mocks.NewMockFetcherStrategy(gomock.NewController(t))
+			if tt.renderCalled {
+				renderer.EXPECT().
+					Fetch(gomock.Any(), "https://app.example.com").
+					Return(&models.HTTPResponse{StatusCode: 200, Body: []byte(spaRendered)}, nil)
+			}
+			analyzer.SetRenderer(renderer, tt.byDefault)
+
+			result, err := analyzer.AnalyzeURLWithOptions(context.Background(), "https://app.example.com", models.AnalysisOptions{Render: tt.render})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedH1, result.Headings.H1)
+			assert.Equal(t, "HTML5", result.HTMLVersion)
+			if tt.renderCalled {
+				assert.Equal(t, int32(0), atomic.LoadInt32(&httpClient.calls))
+			}
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeURLWithOptions_RenderingDisabled(t *testing.T) {
+	httpClient := &countingHTTPClient{body: []byte(spaShell)}
+	analyzer, _ := newCoalescingTestAnalyzer(t, httpClient)
+
+	_, err := analyzer.AnalyzeURLWithOptions(context.Background(), "https://app.example.com", models.AnalysisOptions{Render: true})
+
+	assert.ErrorIs(t, err, ErrRenderingDisabled)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&httpClient.calls))
+
+	// Asking for a default render without a renderer is ignored
+	analyzer.SetRenderer(nil, true)
+	_, err = analyzer.AnalyzeURL(context.Background(), "https://app.example.com")
+	assert.NoError(t, err)
+}
+
+func TestAnalyzer_AnalyzeURLWithOptions_RenderErrorFailsAnalysis(t *testing.T) {
+	analyzer, _ := newCoalescingTestAnalyzer(t, &countingHTTPClient{})
+
+	renderer := mocks.NewMockFetcherStrategy(gomock.NewController(t))
+	renderer.EXPECT().Fetch(gomock.Any(), gomock.Any()).Return(nil, errors.New("HTTP error: status code 404"))
+	analyzer.SetRenderer(renderer, false)
+
+	_, err := analyzer.AnalyzeURLWithOptions(context.Background(), "https://app.example.com", models.AnalysisOptions{Render: true})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP error: status code 404")
+}
+
+func TestAnalyzer_AnalyzeURLWithOptions_RenderedAndPlainAreNotCoalesced(t *testing.T) {
+	httpClient := &countingHTTPClient{delay: 50 * time.Millisecond, body: []byte(spaShell)}
+	analyzer, mockMetrics := newCoalescingTestAnalyzer(t, httpClient)
+	mockMetrics.EXPECT().RecordCoalescedAnalysis().Times(0)
+
+	renderer := mocks.NewMockFetcherStrategy(gomock.NewController(t))
+	renderer.EXPECT().Fetch(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, url string) (*models.HTTPResponse, error) {
+			time.Sleep(50 * time.Millisecond)
+			return &models.HTTPResponse{StatusCode: 200, Body: []byte(spaRendered)}, nil
+		})
+	analyzer.SetRenderer(renderer, false)
+
+	var wg sync.WaitGroup
+	var plain, rendered *models.AnalysisResult
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		plain, _ = analyzer.AnalyzeURL(context.Background(), "https://app.example.com")
+	}()
+	go func() {
+		defer wg.Done()
+		rendered, _ = analyzer.AnalyzeURLWithOptions(context.Background(), "https://app.example.com", models.AnalysisOptions{Render: true})
+	}()
+	wg.Wait()
+
+	require.NotNil(t, plain)
+	require.NotNil(t, rendered)
+	assert.Equal(t, 0, plain.Headings.H1)
+	assert.Equal(t, 1, rendered.Headings.H1)
+}
+
+func TestAnalyzer_AnalyzeURLWithOptions_Screenshot(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nthumbnail")
+
+	tests := []struct {
+		name       string
+		captureErr error
+		expected   string
+	}{
+		{"captured", nil, "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)},
+		{"capture failure does not fail analysis", errors.New("render timeout"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer, mockMetrics := newCoalescingTestAnalyzer(t, &countingHTTPClient{body: []byte(spaShell)})
+			mockMetrics.EXPECT().RecordScreenshot(tt.captureErr == nil, gomock.Any()).Times(1)
+
+			screenshotter := mocks.NewMockScreenshotCapturer(gomock.NewController(t))
+			screenshotter.EXPECT().CaptureScreenshot(gomock.Any(), "https://app.example.com").Return(png, tt.captureErr)
+			analyzer.SetScreenshotter(screenshotter)
+
+			result, err := analyzer.AnalyzeURLWithOptions(context.Background(), "https://app.example.com", models.AnalysisOptions{Screenshot: true})
+
+			require.NoError(t, err)
+			assert.Equal(t, "App", result.Title)
+			assert.Equal(t, tt.expected, result.Screenshot)
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeURLWithOptions_ScreenshotOptIn(t *testing.T) {
+	analyzer, _ := newCoalescingTestAnalyzer(t, &countingHTTPClient{body: []byte(spaShell)})
+
+	// Not requested: the capturer must not be called
+	screenshotter := mocks.NewMockScreenshotCapturer(gomock.NewController(t))
+	analyzer.SetScreenshotter(screenshotter)
+
+	result, err := analyzer.AnalyzeURL(context.Background(), "https://app.example.com")
+	require.NoError(t, err)
+	assert.Empty(t, result.Screenshot)
+
+	// Requested without a capturer: analysis still succeeds
+	analyzer.SetScreenshotter(nil)
+	result, err = analyzer.AnalyzeURLWithOptions(context.Background(), "https://app.example.com", models.AnalysisOptions{Screenshot: true})
+	require.NoError(t, err)
+	assert.Empty(t, result.Screenshot)
+}
+
+// stageRecorder captures RecordStage calls in the order they are made
+type stageRecorder struct {
+	mu      sync.Mutex
+	names   []string
+	seconds map[string]float64
+}
+
+func (r *stageRecorder) record(name string, seconds float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names = append(r.names, name)
+	r.seconds[name] = seconds
+}
+
+func newTimingTestAnalyzer(t *testing.T, httpClient interfaces.HTTPClient, parseDelay, linkCheckDelay time.Duration) (*Analyzer, *stageRecorder) {
+	ctrl := gomock.NewController(t)
+
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Info(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Error(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Warn(gomock.Any(), gomock.Any()).AnyTimes()
+
+	mockParser := mocks.NewMockHTMLParser(ctrl)
+	mockParser.EXPECT().ParseHTML(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, content []byte, baseURL string) (*models.ParsedHTML, error) {
+			time.Sleep(parseDelay)
+			return &models.ParsedHTML{
+				HTMLVersion: "HTML5",
+				Title:       "Timed",
+				Links:       []models.Link{{URL: "https://example.com/a", Type: models.LinkTypeInternal}},
+			}, nil
+		}).AnyTimes()
+
+	mockLinkChecker := mocks.NewMockLinkChecker(ctrl)
+	mockLinkChecker.EXPECT().CheckLinks(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+			time.Sleep(linkCheckDelay)
+			return []models.LinkStatus{{Link: links[0], Accessible: true, StatusCode: 200}}, nil
+		}).AnyTimes()
+
+	recorder := &stageRecorder{seconds: make(map[string]float64)}
+	mockMetrics := mocks.NewMockMetricsCollector(ctrl)
+	mockMetrics.EXPECT().RecordAnalysis(gomock.Any(), gomock.Any()).AnyTimes()
+	mockMetrics.EXPECT().RecordAnalysisFailure(gomock.Any()).AnyTimes()
+	mockMetrics.EXPECT().RecordStage(gomock.Any(), gomock.Any()).Do(recorder.record).AnyTimes()
+	mockMetrics.EXPECT().RecordDownloadedBytes(gomock.Any()).AnyTimes()
+	mockMetrics.EXPECT().AddAnalysesInFlight(gomock.Any()).AnyTimes()
+	mockMetrics.EXPECT().RecordScreenshot(gomock.Any(), gomock.Any()).AnyTimes()
+
+	return NewAnalyzer(httpClient, mockParser, mockLinkChecker, mockLogger, mockMetrics), recorder
+}
+
+func TestAnalyzer_AnalyzeURL_Timings(t *testing.T) {
+	httpClient := &countingHTTPClient{delay: 30 * time.Millisecond, body: []byte("<!DOCTYPE html><html></html>")}
+	analyzer, recorder := newTimingTestAnalyzer(t, httpClient, 10*time.Millisecond, 60*time.Millisecond)
+
+	result, err := analyzer.AnalyzeURL(context.Background(), "https://example.com")
+	require.NoError(t, err)
+	require.NotNil(t, result.Timings)
+	timings := result.Timings
+
+	// Every stage is at least as long as the delay injected into it
+	assert.GreaterOrEqual(t, timings.FetchMs, 30.0)
+	assert.GreaterOrEqual(t, timings.ParseMs, 10.0)
+	assert.GreaterOrEqual(t, timings.LinkCheckMs, 60.0)
+
+	// and the relative ordering follows the delays
+	assert.Greater(t, timings.LinkCheckMs, timings.FetchMs)
+	assert.Greater(t, timings.FetchMs, timings.ParseMs)
+	assert.Zero(t, timings.HTMLVersionDetectionMs)
+	assert.GreaterOrEqual(t, timings.TotalMs, timings.FetchMs+timings.ParseMs+timings.LinkCheckMs)
+
+	// The same values are metered, one observation per stage in stage order
+	assert.Equal(t, []string{
+		models.StageFetch,
+		models.StageParse,
+		models.StageLinkCheck,
+		models.StageTotal,
+	}, recorder.names)
+	assert.InDelta(t, timings.FetchMs/1000, recorder.seconds[models.StageFetch], 1e-9)
+	assert.InDelta(t, timings.TotalMs/1000, recorder.seconds[models.StageTotal], 1e-9)
+}
+
+func TestAnalyzer_AnalyzeURL_TimingsOnFetchFailure(t *testing.T) {
+	httpClient := mocks.NewMockHTTPClient(gomock.NewController(t))
+	httpClient.EXPECT().Get(gomock.Any(), "https://example.com").Return(nil, errors.New("connection refused"))
+	analyzer, recorder := newTimingTestAnalyzer(t, httpClient, 0, 0)
+
+	_, err := analyzer.AnalyzeURL(context.Background(), "https://example.com")
+	require.Error(t, err)
+
+	// Only the stages that ran are metered
+	assert.Equal(t, []string{models.StageFetch}, recorder.names)
+}
+
+func TestAnalyzer_AnalyzeURLWithOptions_ScreenshotRunsAlongsideLinkCheck(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	analyzer, _ := newTimingTestAnalyzer(t, &countingHTTPClient{body: []byte(spaShell)}, 0, 0)
+
+	// Each side waits for the other to start, so this only completes when
+	// link checking and the screenshot run at the same time
+	linkCheckStarted := make(chan struct{})
+	screenshotStarted := make(chan struct{})
+	waitFor := func(ch <-chan struct{}) error {
+		select {
+		case <-ch:
+			return nil
+		case <-time.After(time.Second):
+			return errors.New("ran sequentially")
+		}
+	}
+
+	linkChecker := mocks.NewMockLinkChecker(ctrl)
+	linkChecker.EXPECT().CheckLinks(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+			close(linkCheckStarted)
+			return nil, waitFor(screenshotStarted)
+		})
+	analyzer.linkChecker = linkChecker
+
+	screenshotter := mocks.NewMockScreenshotCapturer(ctrl)
+	screenshotter.EXPECT().CaptureScreenshot(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, url string) ([]byte, error) {
+			close(screenshotStarted)
+			return []byte("png"), waitFor(linkCheckStarted)
+		})
+	analyzer.SetScreenshotter(screenshotter)
+
+	result, err := analyzer.AnalyzeURLWithOptions(context.Background(), "https://app.example.com", models.AnalysisOptions{Screenshot: true})
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.Screenshot)
+}
+
+// staticLinkChecker reports every link as accessible without any I/O
+type staticLinkChecker struct{}
+
+func (staticLinkChecker) CheckLinks(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+	statuses := make([]models.LinkStatus, len(links))
+	for i, link := range links {
+		statuses[i] = models.LinkStatus{Link: link, Accessible: true, StatusCode: 200}
+	}
+	return statuses, nil
+}
+
+func (staticLinkChecker) CheckLink(ctx context.Context, link models.Link) models.LinkStatus {
+	return models.LinkStatus{Link: link, Accessible: true, StatusCode: 200}
+}
+
+// budgetLinkChecker checks links one by one with a real client, the way the
+// link checker service does, so the checks spend the budget in ctx
+type budgetLinkChecker struct {
+	client interfaces.HTTPClient
+}
+
+func (c budgetLinkChecker) CheckLinks(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+	statuses := make([]models.LinkStatus, len(links))
+	for i, link := range links {
+		statuses[i] = c.CheckLink(ctx, link)
+	}
+	return statuses, nil
+}
+
+func (c budgetLinkChecker) CheckLink(ctx context.Context, link models.Link) models.LinkStatus {
+	resp, err := c.client.Get(ctx, link.URL)
+	switch {
+	case errors.Is(err, budget.ErrExhausted):
+		return models.LinkStatus{Link: link, Skipped: true, Error: budget.SkippedError}
+	case err != nil:
+		return models.LinkStatus{Link: link, Error: err.Error()}
+	}
+	return models.LinkStatus{Link: link, Accessible: true, StatusCode: resp.StatusCode}
+}
+
+// newRedirectServer serves a page linking to five paths that each redirect
+// three times before answering, counting every request
+func newRedirectServer(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var hits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/" {
+			io.WriteString(w, `<!DOCTYPE html><html><head><title>Hops</title></head><body>`+
+				`<a href="/a">a</a><a href="/b">b</a><a href="/c">c</a><a href="/d">d</a><a href="/e">e</a></body></html>`)
+			return
+		}
+		if hop := strings.Count(r.URL.Path, "/"); hop < 4 {
+			http.Redirect(w, r, r.URL.Path+"/next", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func TestAnalyzer_AnalyzeURL_CountsDownloadedBytes(t *testing.T) {
+	log := newTestLogger()
+	client := httpclient.New(2*time.Second, log)
+
+	page := `<!DOCTYPE html><html><head><title>Counted</title></head><body>
+<a href="/small">Small</a><a href="/large">Large</a><a href="/missing">Missing</a>
+</body></html>`
+	bodies := map[string]string{
+		"/":        page,
+		"/small":   strings.Repeat("s", 1000),
+		"/large":   strings.Repeat("l", 2500),
+		"/missing": "gone",
+	}
+	pages := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		io.WriteString(w, bodies[r.URL.Path])
+	}))
+	defer pages.Close()
+
+	// GETs the links with the shared client, reporting what it read the
+	// way the link checker service does
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Links []models.Link `json:"links"`
+		}
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&req)) {
+			return
+		}
+		meter := &traffic.Meter{}
+		ctx := traffic.WithMeter(r.Context(), meter)
+		var statuses []models.LinkStatus
+		for _, link := range req.Links {
+			resp, err := client.Get(ctx, link.URL)
+			if !assert.NoError(t, err) {
+				return
+			}
+			statuses = append(statuses, models.LinkStatus{Link: link, StatusCode: resp.StatusCode, Accessible: resp.StatusCode < 400})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"link_statuses": statuses, "bytes_downloaded": meter.Bytes()})
+	}))
+	defer service.Close()
+
+	analyzer := newTestAnalyzer(t, client, NewLinkCheckerClient(service.URL, 2*time.Second, log))
+	result, err := analyzer.AnalyzeURL(context.Background(), pages.URL+"/")
+	require.NoError(t, err)
+
+	require.Equal(t, 3, result.Links.Total)
+	want := 0
+	for _, body := range bodies {
+		want += len(body)
+	}
+	require.NotNil(t, result.Traffic)
+	assert.Equal(t, int64(want), result.Traffic.Bytes)
+}
+
+func TestAnalyzer_AnalyzeURL_BudgetStopsFetches(t *testing.T) {
+	server, hits := newRedirectServer(t)
+	log := newTestLogger()
+	client := httpclient.New(5*time.Second, log)
+
+	analyzer := newTestAnalyzer(t, client, budgetLinkChecker{client: client})
+	analyzer.SetBudget(6, 1<<20)
+
+	result, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	// The page and the first link's four hops fit; the second link is cut
+	// off after its first hop and the rest are never fetched
+	assert.Equal(t, int64(6), hits.Load())
+	require.NotNil(t, result.Budget)
+	assert.Equal(t, int64(6), result.Budget.Requests)
+	assert.Equal(t, int64(6), result.Budget.MaxRequests)
+	assert.True(t, result.Budget.Exhausted)
+	assert.Equal(t, 4, result.Budget.SkippedLinks)
+	assert.Equal(t, 5, result.Links.Total)
+	assert.Zero(t, result.Links.Inaccessible, "skipped links are not inaccessible")
+}
+
+func TestAnalyzer_AnalyzeURL_BudgetStopsRedirectLoop(t *testing.T) {
+	var hits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.Redirect(w, r, r.URL.Path+"x", http.StatusFound)
+	}))
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, nil, staticLinkChecker{})
+	analyzer.SetBudget(3, 1<<20)
+
+	_, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.ErrorIs(t, err, budget.ErrExhausted)
+	assert.Equal(t, int64(3), hits.Load())
+}
+
+func TestAnalyzer_AnalyzeURL_RedirectedLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			io.WriteString(w, `<!DOCTYPE html><html><head><title>Links</title></head><body>`+
+				`<a href="/current">current</a><a href="/old">old</a><a href="/older">older</a><a href="/old">again</a></body></html>`)
+		case "/old":
+			http.Redirect(w, r, "/current", http.StatusMovedPermanently)
+		case "/older":
+			http.Redirect(w, r, "/old", http.StatusMovedPermanently)
+		case "/current":
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	log := newTestLogger()
+	client := httpclient.New(5*time.Second, log)
+	analyzer := newTestAnalyzer(t, client, statusLinkChecker{client: client})
+
+	plain, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, 3, plain.Links.RedirectedLinks)
+	assert.Zero(t, plain.Links.Inaccessible)
+	assert.Nil(t, plain.RedirectedLinks, "listed only on request")
+
+	reported, err := analyzer.AnalyzeURLWithOptions(context.Background(), server.URL, models.AnalysisOptions{ReportRedirectedLinks: true})
+	require.NoError(t, err)
+	assert.Equal(t, []models.RedirectedLink{
+		{URL: server.URL + "/old", FinalURL: server.URL + "/current", Redirects: 1},
+		{URL: server.URL + "/older", FinalURL: server.URL + "/current", Redirects: 2},
+	}, reported.RedirectedLinks)
+}
+
+// nopMetrics discards every observation
+type nopMetrics struct{}
+
+func (nopMetrics) RecordRequest(method, path string, statusCode int, duration float64, requestID string) {
+}
+func (nopMetrics) RecordAnalysis(success bool, duration float64)   {}
+func (nopMetrics) RecordLinkCheck(success bool, duration float64)  {}
+func (nopMetrics) RecordCoalescedAnalysis()                        {}
+func (nopMetrics) RecordScreenshot(success bool, duration float64) {}
+func (nopMetrics) RecordStage(name string, seconds float64)        {}
+func (nopMetrics) RecordAnalysisFailure(cause string)              {}
+func (nopMetrics) RecordCacheLookup(hit bool)                      {}
+func (nopMetrics) RecordUpstreamRetry(upstream, reason string)     {}
+func (nopMetrics) RecordLinkCheckHedge(won bool)                   {}
+func (nopMetrics) RecordMirror(outcome string)                     {}
+func (nopMetrics) RecordDownloadedBytes(bytes int64)               {}
+func (nopMetrics) RecordCallerBytes(caller string, bytes int64)    {}
+func (nopMetrics) AddAnalysesInFlight(delta int)                   {}
+func (nopMetrics) AddLinkChecksActive(delta int)                   {}
+func (nopMetrics) AddLinkChecksQueued(delta int)                   {}
+
+// newTestLogger returns a logger that writes nowhere
+func newTestLogger() interfaces.Logger {
+	return logger.NewAdapter(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// newTestAnalyzer returns an analyzer over client and checker that logs
+// nowhere and records no metrics; a nil client fetches over plain HTTP
+func newTestAnalyzer(t testing.TB, client interfaces.HTTPClient, checker interfaces.LinkChecker) *Analyzer {
+	t.Helper()
+	log := newTestLogger()
+	if client == nil {
+		client = httpclient.New(5*time.Second, log)
+	}
+	return NewAnalyzer(client, NewHTMLParser(log), checker, log, nopMetrics{})
+}
+
+func BenchmarkAnalyzer_AnalyzeURL(b *testing.B) {
+	page, err := os.ReadFile("testdata/representative.html")
+	require.NoError(b, err)
+
+	analyzer := newTestAnalyzer(b, &countingHTTPClient{body: page}, staticLinkChecker{})
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := analyzer.AnalyzeURL(ctx, "https://docs.example.com/page"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/idn"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"golang.org/x/net/html"
+)
+
+// anchorCollector gathers, during the traversal, the ids and <a name>s a
+// fragment can point at and the fragments of the page's same-page links.
+// Each set keeps at most max values; the others are counted as dropped.
+type anchorCollector struct {
+	max int
+	// ids counts the elements with each id, and order lists the ids as
+	// first seen
+	ids   map[string]int
+	order []string
+	names map[string]bool
+	// fragments are the fragments of same-page links, each once
+	fragments []string
+	seen      map[string]bool
+	// droppedTargets are ids and names, droppedFragments fragments
+	droppedTargets   int
+	droppedFragments int
+}
+
+func newAnchorCollector(max int) *anchorCollector {
+	return &anchorCollector{
+		max:   max,
+		ids:   make(map[string]int),
+		names: make(map[string]bool),
+		seen:  make(map[string]bool),
+	}
+}
+
+// visit records the id of an element and, for an <a>, its name and the
+// fragment of its href when it points into the page at baseURL
+func (c *anchorCollector) visit(node *html.Node, baseURL *url.URL) {
+	// An id is case-sensitive and compared as written; an empty one
+	// names nothing
+	if id := attribute(node, "id"); id != "" {
+		switch {
+		case c.ids[id] > 0:
+			c.ids[id]++
+		case len(c.ids) < c.max:
+			c.ids[id] = 1
+			c.order = append(c.order, id)
+		default:
+			c.droppedTargets++
+		}
+	}
+	if node.Data != "a" {
+		return
+	}
+
+	if name := attribute(node, "name"); name != "" && !c.names[name] {
+		if len(c.names) < c.max {
+			c.names[name] = true
+		} else {
+			c.droppedTargets++
+		}
+	}
+	if fragment, ok := sameDocumentFragment(strings.TrimSpace(attribute(node, "href")), baseURL); ok && !c.seen[fragment] {
+		if len(c.fragments) < c.max {
+			c.seen[fragment] = true
+			c.fragments = append(c.fragments, fragment)
+		} else {
+			c.droppedFragments++
+		}
+	}
+}
+
+// result returns the report of the page, nil when it has neither duplicate
+// ids nor dangling anchors, and counts what was dropped in truncation
+func (c *anchorCollector) result(truncation *models.ParseTruncation) *models.AnchorReport {
+	truncation.DroppedAnchors += c.droppedTargets + c.droppedFragments
+
+	var report models.AnchorReport
+	for _, id := range c.order {
+		if count := c.ids[id]; count > 1 {
+			report.DuplicateIDs = append(report.DuplicateIDs, models.DuplicateID{ID: id, Count: count})
+		}
+	}
+	// Without every id and name, a fragment matching none of those kept
+	// may still name an element
+	if c.droppedTargets == 0 {
+		for _, fragment := range c.fragments {
+			if !c.indicates(fragment) {
+				report.DanglingAnchors = append(report.DanglingAnchors, fragment)
+			}
+		}
+	}
+
+	if report.DuplicateIDs == nil && report.DanglingAnchors == nil {
+		return nil
+	}
+	return &report
+}
+
+// indicates reports whether fragment points into the page, following the
+// HTML spec's indicated part of the document: an empty fragment or "top"
+// is the top of the page, otherwise an element must have the fragment,
+// as written or percent-decoded, as its id or, for an <a>, its name
+func (c *anchorCollector) indicates(fragment string) bool {
+	if fragment == "" || c.ids[fragment] > 0 || c.names[fragment] || strings.EqualFold(fragment, "top") {
+		return true
+	}
+	decoded, err := url.PathUnescape(fragment)
+	if err != nil {
+		return false
+	}
+	return c.ids[decoded] > 0 || c.names[decoded] || strings.EqualFold(decoded, "top")
+}
+
+// sameDocumentFragment returns the fragment of href, as written, when href
+// points into the page at baseURL: it is only a fragment, or it resolves to
+// the page's own URL and has one
+func sameDocumentFragment(href string, baseURL *url.URL) (string, bool) {
+	ref, fragment, ok := strings.Cut(href, "#")
+	if !ok {
+		return "", false
+	}
+	if ref == "" {
+		return fragment, true
+	}
+
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return "", false
+	}
+	target := baseURL.ResolveReference(refURL)
+	return fragment, strings.EqualFold(target.Scheme, baseURL.Scheme) &&
+		idn.ASCIIHost(target.Host) == idn.ASCIIHost(baseURL.Host) &&
+		documentPath(target) == documentPath(baseURL) &&
+		target.RawQuery == baseURL.RawQuery
+}
+
+// documentPath returns the escaped path of u, "/" for an empty one
+func documentPath(u *url.URL) string {
+	if path := u.EscapedPath(); path != "" {
+		return path
+	}
+	return "/"
+}
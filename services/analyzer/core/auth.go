@@ -0,0 +1,60 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/findings"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpauth"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// requiresAuth returns the authentication the page fetch failing with err
+// asked for, or nil when it failed otherwise
+func requiresAuth(err error) *models.AuthRequirement {
+	var statusErr *models.HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		return nil
+	}
+	return httpauth.Requirement(statusErr.StatusCode, statusErr.Challenges)
+}
+
+// authResult is the result of a page that asked for authentication
+// instead of serving its content. There is no content to run the rules
+// on, so it only names what was asked for, with its finding.
+func authResult(url string, auth *models.AuthRequirement) *models.AnalysisResult {
+	result := &models.AnalysisResult{
+		URL:          url,
+		AnalyzedAt:   time.Now(),
+		DisplayURL:   displayURL(url),
+		IDNHosts:     idnHosts(url, "", nil),
+		RequiresAuth: auth,
+	}
+	result.Findings = evaluateAuth(result)
+	result.FindingSummary = findings.Summarize(result.Findings)
+	return result
+}
+
+func evaluateAuth(result *models.AnalysisResult) []models.Finding {
+	auth := result.RequiresAuth
+	if auth == nil {
+		return nil
+	}
+	asker := "The page"
+	if auth.Proxy {
+		asker = "A proxy on the way to the page"
+	}
+	message := asker + " asks for authentication"
+	var values []string
+	switch {
+	case auth.Scheme != "" && auth.Realm != "":
+		message = fmt.Sprintf("%s asks for %s authentication to %q", asker, auth.Scheme, auth.Realm)
+		values = []string{auth.Scheme, auth.Realm}
+	case auth.Scheme != "":
+		message = fmt.Sprintf("%s asks for %s authentication", asker, auth.Scheme)
+		values = []string{auth.Scheme}
+	}
+	return []models.Finding{findings.New(findings.AuthRequired, message+"; its content was not analyzed",
+		models.FindingEvidence{URLs: []string{result.URL}, Values: values})}
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/findings"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuthServer answers every request with status and the challenge headers
+func newAuthServer(t *testing.T, status int, header string, challenges ...string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, challenge := range challenges {
+			w.Header().Add(header, challenge)
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(status)
+		w.Write([]byte("<!DOCTYPE html><html><head><title>Sign in</title></head><body><h1>Sign in</h1><a href=\"/help\">Help</a></body></html>"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_RequiresAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     string
+		challenges []string
+		want       models.AuthRequirement
+		message    string
+	}{
+		{
+			name:       "basic",
+			status:     http.StatusUnauthorized,
+			header:     "WWW-Authenticate",
+			challenges: []string{`Basic realm="Staging", charset="UTF-8"`},
+			want: models.AuthRequirement{StatusCode: 401, Scheme: "Basic", Realm: "Staging",
+				Challenges: []models.AuthChallenge{{Scheme: "Basic", Realm: "Staging"}}},
+			message: `The page asks for Basic authentication to "Staging"; its content was not analyzed`,
+		},
+		{
+			name:       "bearer",
+			status:     http.StatusUnauthorized,
+			header:     "WWW-Authenticate",
+			challenges: []string{`Bearer realm="api", error="invalid_token"`},
+			want: models.AuthRequirement{StatusCode: 401, Scheme: "Bearer", Realm: "api",
+				Challenges: []models.AuthChallenge{{Scheme: "Bearer", Realm: "api"}}},
+			message: `The page asks for Bearer authentication to "api"; its content was not analyzed`,
+		},
+		{
+			name:       "digest",
+			status:     http.StatusUnauthorized,
+			header:     "WWW-Authenticate",
+			challenges: []string{`Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=SHA-256, nonce="7ypf/xlj9XXwfDPEoM4URrv"`},
+			want: models.AuthRequirement{StatusCode: 401, Scheme: "Digest", Realm: "http-auth@example.org",
+				Challenges: []models.AuthChallenge{{Scheme: "Digest", Realm: "http-auth@example.org"}}},
+			message: `The page asks for Digest authentication to "http-auth@example.org"; its content was not analyzed`,
+		},
+		{
+			name:       "several schemes",
+			status:     http.StatusUnauthorized,
+			header:     "WWW-Authenticate",
+			challenges: []string{"Negotiate", "NTLM", `Basic realm="intranet"`},
+			want: models.AuthRequirement{StatusCode: 401, Scheme: "Negotiate",
+				Challenges: []models.AuthChallenge{{Scheme: "Negotiate"}, {Scheme: "NTLM"}, {Scheme: "Basic", Realm: "intranet"}}},
+			message: "The page asks for Negotiate authentication; its content was not analyzed",
+		},
+		{
+			name:    "no challenge",
+			status:  http.StatusUnauthorized,
+			want:    models.AuthRequirement{StatusCode: 401},
+			message: "The page asks for authentication; its content was not analyzed",
+		},
+		{
+			name:       "proxy",
+			status:     http.StatusProxyAuthRequired,
+			header:     "Proxy-Authenticate",
+			challenges: []string{`Basic realm="corporate proxy"`},
+			want: models.AuthRequirement{StatusCode: 407, Proxy: true, Scheme: "Basic", Realm: "corporate proxy",
+				Challenges: []models.AuthChallenge{{Scheme: "Basic", Realm: "corporate proxy"}}},
+			message: `A proxy on the way to the page asks for Basic authentication to "corporate proxy"; its content was not analyzed`,
+		},
+		{
+			name:       "challenge of the other header ignored",
+			status:     http.StatusUnauthorized,
+			header:     "Proxy-Authenticate",
+			challenges: []string{`Basic realm="proxy"`},
+			want:       models.AuthRequirement{StatusCode: 401},
+			message:    "The page asks for authentication; its content was not analyzed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newAuthServer(t, tt.status, tt.header, tt.challenges...)
+			pageURL := server.URL + "/admin"
+
+			result, err := newTestAnalyzer(t, nil, newStatusLinkChecker()).AnalyzeURL(context.Background(), pageURL)
+			require.NoError(t, err)
+
+			require.NotNil(t, result.RequiresAuth)
+			assert.Equal(t, tt.want, *result.RequiresAuth)
+			require.NoError(t, result.Validate(pageURL))
+
+			// The body of the 401 is not the page's content
+			assert.Empty(t, result.Title)
+			assert.Empty(t, result.HTMLVersion)
+			assert.Zero(t, result.Headings)
+			assert.Zero(t, result.Links.Total)
+			assert.Empty(t, result.Rules)
+
+			require.Len(t, result.Findings, 1)
+			assert.Equal(t, findings.AuthRequired, result.Findings[0].ID)
+			assert.Equal(t, tt.message, result.Findings[0].Message)
+			assert.Equal(t, 1, result.FindingSummary.Total)
+
+			require.NotNil(t, result.Timings)
+			hash, err := result.Hash()
+			require.NoError(t, err)
+			assert.Equal(t, hash, result.ResultHash)
+		})
+	}
+}
+
+func TestAnalyzer_RequiresAuth_Strict(t *testing.T) {
+	server := newAuthServer(t, http.StatusUnauthorized, "WWW-Authenticate", `Basic realm="Staging"`)
+
+	result, err := newTestAnalyzer(t, nil, newStatusLinkChecker()).AnalyzeURLWithOptions(context.Background(), server.URL, models.AnalysisOptions{StrictAuth: true})
+	require.Error(t, err)
+	assert.Nil(t, result)
+	assert.EqualError(t, err, "HTTP error: status code 401")
+	var statusErr *models.HTTPStatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, []string{`Basic realm="Staging"`}, statusErr.Challenges)
+	assert.Equal(t, models.FailureHTTP4xx, FailureCause(err))
+}
+
+func TestAnalyzer_OtherClientErrorsStillFail(t *testing.T) {
+	server := newAuthServer(t, http.StatusForbidden, "WWW-Authenticate", `Basic realm="Staging"`)
+
+	_, err := newTestAnalyzer(t, nil, newStatusLinkChecker()).AnalyzeURL(context.Background(), server.URL)
+	assert.EqualError(t, err, "HTTP error: status code 403")
+}
+
+func TestEvaluateAuth(t *testing.T) {
+	assert.Nil(t, evaluateAuth(&models.AnalysisResult{URL: "https://example.com/"}))
+
+	list := evaluateAuth(&models.AnalysisResult{URL: "https://example.com/admin",
+		RequiresAuth: &models.AuthRequirement{StatusCode: 401, Scheme: "Basic", Realm: "Staging"}})
+	require.Len(t, list, 1)
+	assert.Equal(t, models.CategoryContent, list[0].Category)
+	assert.Equal(t, models.SeverityWarning, list[0].Severity)
+	assert.Equal(t, models.FindingEvidence{URLs: []string{"https://example.com/admin"}, Values: []string{"Basic", "Staging"}}, list[0].Evidence)
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/safe"
+)
+
+// checkRegexTimeout bounds the matching of one regular expression. Go's
+// regexp runs in linear time, but a large page and a costly pattern can
+// still hold an analysis up.
+const checkRegexTimeout = 2 * time.Second
+
+// evaluateChecks evaluates checks against the decoded source of the page,
+// in their order, and reports whether a required one failed. A regular
+// expression that does not compile or outlasts timeout fails its own check
+// only.
+func evaluateChecks(ctx context.Context, content []byte, checks []models.CustomCheck, timeout time.Duration) ([]models.CheckResult, bool) {
+	results := make([]models.CheckResult, len(checks))
+	failed := false
+	for i, check := range checks {
+		result := models.CheckResult{Name: check.Name, Type: check.Type, Optional: check.Optional}
+		matches, err := countMatches(ctx, content, check, timeout)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Matches = matches
+			if check.Type == models.CheckNotContains {
+				result.Passed = matches == 0
+			} else {
+				result.Passed = matches > 0
+			}
+		}
+		if !result.Passed && !check.Optional {
+			failed = true
+		}
+		results[i] = result
+	}
+	return results, failed
+}
+
+// countMatches counts the occurrences of the pattern of check in content,
+// up to models.MaxCheckMatches
+func countMatches(ctx context.Context, content []byte, check models.CustomCheck, timeout time.Duration) (int, error) {
+	switch check.Type {
+	case models.CheckContains, models.CheckNotContains:
+		return min(bytes.Count(content, []byte(check.Pattern)), models.MaxCheckMatches), nil
+	case models.CheckRegex:
+		re, err := safe.Compile(check.Pattern)
+		if err != nil {
+			return 0, err
+		}
+		return re.Count(ctx, content, models.MaxCheckMatches, timeout)
+	case models.CheckCSSSelectorExists:
+		return 0, fmt.Errorf("%s checks are not supported yet", check.Type)
+	default:
+		return 0, fmt.Errorf("unknown check type %q", check.Type)
+	}
+}
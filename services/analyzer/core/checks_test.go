@@ -0,0 +1,139 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/cache"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const checksPage = `<html><head><title>Shop</title>
+<script>gtag('config', 'G-123'); gtag('event', 'view');</script>
+</head><body><h1>Welcome</h1><p>Free shipping</p></body></html>`
+
+func TestEvaluateChecks(t *testing.T) {
+	checks := []models.CustomCheck{
+		{Name: "analytics", Type: models.CheckRegex, Pattern: `gtag\('[a-z]+'`},
+		{Name: "shipping", Type: models.CheckContains, Pattern: "Free shipping"},
+		{Name: "no lorem", Type: models.CheckNotContains, Pattern: "lorem ipsum"},
+		{Name: "case matters", Type: models.CheckContains, Pattern: "free shipping", Optional: true},
+	}
+
+	results, failed := evaluateChecks(context.Background(), []byte(checksPage), checks, time.Second)
+
+	assert.False(t, failed, "only an optional check failed")
+	assert.Equal(t, []models.CheckResult{
+		{Name: "analytics", Type: models.CheckRegex, Passed: true, Matches: 2},
+		{Name: "shipping", Type: models.CheckContains, Passed: true, Matches: 1},
+		{Name: "no lorem", Type: models.CheckNotContains, Passed: true},
+		{Name: "case matters", Type: models.CheckContains, Optional: true},
+	}, results)
+}
+
+func TestEvaluateChecks_RequiredFailure(t *testing.T) {
+	results, failed := evaluateChecks(context.Background(), []byte(checksPage), []models.CustomCheck{
+		{Name: "no analytics", Type: models.CheckNotContains, Pattern: "gtag"},
+	}, time.Second)
+
+	assert.True(t, failed)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Passed)
+	assert.Equal(t, 2, results[0].Matches)
+	assert.Empty(t, results[0].Error)
+}
+
+func TestEvaluateChecks_ErrorsAreReportedPerCheck(t *testing.T) {
+	results, failed := evaluateChecks(context.Background(), []byte(checksPage), []models.CustomCheck{
+		{Name: "broken", Type: models.CheckRegex, Pattern: `gtag(`, Optional: true},
+		{Name: "selector", Type: models.CheckCSSSelectorExists, Pattern: "h1", Optional: true},
+		{Name: "shipping", Type: models.CheckContains, Pattern: "shipping"},
+	}, time.Second)
+
+	assert.False(t, failed, "the checks that could not be evaluated are optional")
+	require.Len(t, results, 3)
+	assert.False(t, results[0].Passed)
+	assert.Contains(t, results[0].Error, "invalid regular expression")
+	assert.False(t, results[1].Passed)
+	assert.Equal(t, "css_selector_exists checks are not supported yet", results[1].Error)
+	assert.True(t, results[2].Passed, "the other checks are still evaluated")
+}
+
+func TestEvaluateChecks_RegexTimeout(t *testing.T) {
+	content := []byte(strings.Repeat("ab", 1<<20))
+
+	results, failed := evaluateChecks(context.Background(), content, []models.CustomCheck{
+		{Name: "slow", Type: models.CheckRegex, Pattern: `(a|b)*c`},
+	}, time.Nanosecond)
+
+	assert.True(t, failed)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Passed)
+	assert.Equal(t, "regular expression timed out after 1ns", results[0].Error)
+}
+
+func TestEvaluateChecks_MatchesAreCapped(t *testing.T) {
+	content := []byte(strings.Repeat("x", 2*models.MaxCheckMatches))
+
+	results, _ := evaluateChecks(context.Background(), content, []models.CustomCheck{
+		{Name: "contains", Type: models.CheckContains, Pattern: "x"},
+		{Name: "regex", Type: models.CheckRegex, Pattern: "x"},
+	}, time.Second)
+
+	assert.Equal(t, models.MaxCheckMatches, results[0].Matches)
+	assert.Equal(t, models.MaxCheckMatches, results[1].Matches)
+}
+
+func TestAnalyzer_AnalyzeURL_Checks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, checksPage)
+	}))
+	defer server.Close()
+	analyzer := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+
+	result, err := analyzer.AnalyzeURLWithOptions(context.Background(), server.URL, models.AnalysisOptions{
+		Checks: []models.CustomCheck{
+			{Name: "welcome", Type: models.CheckContains, Pattern: "<h1>Welcome</h1>"},
+			{Name: "no pixel", Type: models.CheckNotContains, Pattern: "gtag", Optional: true},
+		},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, result.Checks, 2)
+	assert.True(t, result.Checks[0].Passed)
+	assert.False(t, result.Checks[1].Passed)
+	assert.False(t, result.ChecksFailed, "the failed check is optional")
+}
+
+func TestAnalyzer_AnalyzeURL_ChecksSkipRevalidation(t *testing.T) {
+	server := newETagServer(t)
+	analyzer := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+	analyzer.SetResultCache(cache.NewMemory(10), time.Minute, false)
+
+	_, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	result, err := analyzer.AnalyzeURLWithOptions(context.Background(), server.URL, models.AnalysisOptions{
+		Checks: []models.CustomCheck{{Name: "missing", Type: models.CheckContains, Pattern: "not on the page"}},
+	})
+	require.NoError(t, err)
+
+	assert.Zero(t, server.notModified.Load(), "a 304 has no source to check")
+	require.Len(t, result.Checks, 1)
+	assert.False(t, result.Checks[0].Passed)
+	assert.True(t, result.ChecksFailed)
+
+	// The checks of one analysis are not cached with its result
+	result, err = analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), server.notModified.Load())
+	assert.Nil(t, result.Checks)
+	assert.False(t, result.ChecksFailed)
+}
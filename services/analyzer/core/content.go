@@ -0,0 +1,34 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/langdetect"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// mismatchConfidence is the least confidence in the detected language for
+// it to be held against the declared one
+const mismatchConfidence = 0.5
+
+// contentReport measures the page's visible text and flags a detected
+// language other than the declared one. Only the primary subtags are
+// compared, so text in English matches a declared en-GB. A language the
+// detector does not know is never held against the text, nor is a
+// detection that had no other candidate in its script: Ukrainian text
+// reads as Russian, the only Cyrillic profile.
+func contentReport(parsed *models.ParsedHTML) *models.ContentReport {
+	report := &models.ContentReport{TextStats: parsed.Text, DeclaredLanguage: parsed.Lang}
+
+	declared, _, _ := strings.Cut(strings.ToLower(parsed.Lang), "-")
+	detected := parsed.Text.Language
+	if declared != "" && detected != "" && parsed.Text.Confidence >= mismatchConfidence && declared != detected &&
+		langdetect.Knows(declared) && langdetect.Decisive(detected) {
+		report.Findings = append(report.Findings, models.ContentFinding{
+			Kind:   models.ContentLanguageMismatch,
+			Detail: fmt.Sprintf("the page declares %q but its text reads as %s", parsed.Lang, detected),
+		})
+	}
+	return report
+}
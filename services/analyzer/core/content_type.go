@@ -0,0 +1,65 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// sniffLen is how much of a body http.DetectContentType looks at
+const sniffLen = 512
+
+// detectContentType reports the media type of a page and whether it can be
+// analyzed as HTML. The declared Content-Type is checked against the first
+// bytes of the body: HTML is analyzed whatever the header says, and a header
+// claiming HTML is trusted unless the bytes are recognizably something else,
+// such as a PDF or an image. A gzip body, kept compressed by the client, is
+// sniffed decompressed.
+func detectContentType(header string, body []byte) (mediaType string, isHTML bool) {
+	declared, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		declared = ""
+	}
+
+	head := decodePrefix(body, sniffLen)
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+
+	switch {
+	case sniffed == "text/html":
+		return sniffed, true
+	case sniffed == "text/xml" && bytes.Contains(bytes.ToLower(head), []byte("<html")):
+		// XHTML opening with an XML declaration
+		return "application/xhtml+xml", true
+	case declared == "text/html" || declared == "application/xhtml+xml":
+		if inconclusive(sniffed) {
+			return declared, true
+		}
+		return sniffed, false
+	case declared != "" && inconclusive(sniffed):
+		return declared, false
+	case sniffed == "text/plain" && json.Valid(body):
+		return "application/json", false
+	}
+	return sniffed, false
+}
+
+// inconclusive reports whether a sniffed type says no more than "some text"
+// or "some bytes", leaving the declared type to be believed
+func inconclusive(sniffed string) bool {
+	return sniffed == "text/plain" || sniffed == "text/xml" || sniffed == "application/octet-stream"
+}
+
+// checkContentType fails a fetched page that is not HTML
+func checkContentType(response *models.HTTPResponse) error {
+	mediaType, isHTML := detectContentType(response.Headers.Get("Content-Type"), response.Body)
+	if isHTML {
+		return nil
+	}
+	return &models.UnsupportedContentTypeError{
+		ContentType: mediaType,
+		Bytes:       int64(len(response.Body)),
+	}
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectContentType(t *testing.T) {
+	const page = "<!DOCTYPE html><html><head><title>T</title></head><body></body></html>"
+	const xhtml = `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title>T</title></head><body></body></html>`
+
+	tests := []struct {
+		name      string
+		header    string
+		body      string
+		mediaType string
+		isHTML    bool
+	}{
+		{name: "HTML", header: "text/html; charset=utf-8", body: page, mediaType: "text/html", isHTML: true},
+		{name: "HTML without a header", body: page, mediaType: "text/html", isHTML: true},
+		{name: "HTML labeled as plain text", header: "text/plain", body: page, mediaType: "text/html", isHTML: true},
+		{name: "HTML labeled as binary", header: "application/octet-stream", body: page, mediaType: "text/html", isHTML: true},
+		{name: "XHTML", header: "application/xhtml+xml", body: xhtml, mediaType: "application/xhtml+xml", isHTML: true},
+		{name: "XHTML without a header", body: xhtml, mediaType: "application/xhtml+xml", isHTML: true},
+		{name: "HTML fragment trusted by its header", header: "text/html", body: "<div>partial</div>", mediaType: "text/html", isHTML: true},
+		{name: "empty HTML", header: "TEXT/HTML", mediaType: "text/html", isHTML: true},
+		{name: "PDF", header: "application/pdf", body: "%PDF-1.7\n%\xe2\xe3\xcf\xd3", mediaType: "application/pdf"},
+		{name: "PDF labeled as HTML", header: "text/html", body: "%PDF-1.7\n%\xe2\xe3\xcf\xd3", mediaType: "application/pdf"},
+		{name: "PDF without a header", body: "%PDF-1.4\n", mediaType: "application/pdf"},
+		{name: "PNG labeled as HTML", header: "text/html", body: "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", mediaType: "image/png"},
+		{name: "JSON", header: "application/json", body: `{"name":"value"}`, mediaType: "application/json"},
+		{name: "JSON without a header", body: ` [1, 2, 3] `, mediaType: "application/json"},
+		{name: "plain text", header: "text/plain; charset=utf-8", body: "just some words", mediaType: "text/plain"},
+		{name: "plain text without a header", body: "just some words", mediaType: "text/plain"},
+		{name: "malformed header", header: "text/html;;", body: "just some words", mediaType: "text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mediaType, isHTML := detectContentType(tt.header, []byte(tt.body))
+			assert.Equal(t, tt.mediaType, mediaType)
+			assert.Equal(t, tt.isHTML, isHTML)
+		})
+	}
+}
+
+func TestCheckContentType(t *testing.T) {
+	err := checkContentType(&models.HTTPResponse{
+		Body:    []byte(`{"ok":true}`),
+		Headers: http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+	})
+
+	var unsupported *models.UnsupportedContentTypeError
+	require.True(t, errors.As(err, &unsupported))
+	assert.Equal(t, "application/json", unsupported.ContentType)
+	assert.Equal(t, int64(11), unsupported.Bytes)
+
+	assert.NoError(t, checkContentType(&models.HTTPResponse{Body: []byte("<html><body>hi</body></html>")}))
+}
+
+func TestDetectContentType_Gzip(t *testing.T) {
+	compress := func(body string) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(body))
+		gz.Close()
+		return buf.Bytes()
+	}
+
+	mediaType, isHTML := detectContentType("text/html", compress("<!DOCTYPE html><title>T</title>"))
+	assert.Equal(t, "text/html", mediaType)
+	assert.True(t, isHTML)
+
+	mediaType, isHTML = detectContentType("text/html", compress("%PDF-1.7\n"))
+	assert.Equal(t, "application/pdf", mediaType)
+	assert.False(t, isHTML)
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"encoding/base64"
+	"io"
+	"maps"
+	"mime"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// Schemes of hrefs whose content is in the browser rather than behind a URL
+// to check. They are classified before the href is resolved, which would
+// mangle them.
+const (
+	dataScheme = "data:"
+	blobScheme = "blob:"
+)
+
+// hasScheme reports whether href starts with scheme, ignoring case
+func hasScheme(href, scheme string) bool {
+	return len(href) >= len(scheme) && strings.EqualFold(href[:len(scheme)], scheme)
+}
+
+// parseDataURI returns the media type of a data: URI (RFC 2397),
+// lowercased and without its parameters, and text/plain when it declares
+// none. It is not ok when the URI is malformed: without the comma that ends
+// its header, with an invalid media type, or with data that does not
+// decode.
+func parseDataURI(href string) (string, bool) {
+	header, data, found := strings.Cut(href[len(dataScheme):], ",")
+	if !found {
+		return "", false
+	}
+
+	params := strings.Split(header, ";")
+	isBase64 := false
+	if last := params[len(params)-1]; len(params) > 1 && strings.EqualFold(strings.TrimSpace(last), "base64") {
+		isBase64 = true
+		params = params[:len(params)-1]
+	}
+	mediaType := "text/plain"
+	if strings.TrimSpace(params[0]) != "" {
+		parsed, _, err := mime.ParseMediaType(strings.Join(params, ";"))
+		if err != nil || !strings.Contains(parsed, "/") {
+			return "", false
+		}
+		mediaType = parsed
+	}
+
+	decoded, err := url.PathUnescape(data)
+	if err != nil {
+		return "", false
+	}
+	if isBase64 {
+		// Browsers ignore whitespace in base64 data, and padding is optional
+		decoded = strings.TrimRight(strings.Map(dropSpace, decoded), "=")
+		if _, err := io.Copy(io.Discard, base64.NewDecoder(base64.RawStdEncoding, strings.NewReader(decoded))); err != nil {
+			return "", false
+		}
+	}
+	return mediaType, true
+}
+
+func dropSpace(r rune) rune {
+	if unicode.IsSpace(r) {
+		return -1
+	}
+	return r
+}
+
+// addDataURI counts the data: URI link href of result under its media type,
+// or as a parse error when it is malformed
+func (p *HTMLParser) addDataURI(result *models.ParsedHTML, href string) {
+	href = strings.TrimSpace(href)
+	mediaType, ok := parseDataURI(href)
+	if !ok {
+		countSkipped(result, models.LinkSkipParseError)
+		return
+	}
+	countSkipped(result, models.LinkSkipDataURI)
+
+	if result.DataURIs == nil {
+		result.DataURIs = &models.DataURISummary{
+			MediaTypes: make(map[string]int),
+			LargeBytes: p.limits.LargeDataURIBytes,
+		}
+	}
+	summary := result.DataURIs
+	summary.MediaTypes[mediaType]++
+	if len(href) >= summary.LargeBytes {
+		summary.Large++
+	}
+	summary.LargestBytes = max(summary.LargestBytes, len(href))
+}
+
+// countSkipped counts an <a> of result skipped for reason
+func countSkipped(result *models.ParsedHTML, reason string) {
+	if result.SkippedLinks == nil {
+		result.SkippedLinks = make(map[string]int)
+	}
+	result.SkippedLinks[reason]++
+}
+
+// mergeDataURIs returns the summary of the data: URI links of both into and
+// from, reusing into when it has one
+func mergeDataURIs(into, from *models.DataURISummary) *models.DataURISummary {
+	if from == nil {
+		return into
+	}
+	if into == nil {
+		return cloneDataURIs(from)
+	}
+	for mediaType, n := range from.MediaTypes {
+		into.MediaTypes[mediaType] += n
+	}
+	into.Large += from.Large
+	into.LargestBytes = max(into.LargestBytes, from.LargestBytes)
+	return into
+}
+
+// cloneDataURIs returns a copy of summary that may be modified freely
+func cloneDataURIs(summary *models.DataURISummary) *models.DataURISummary {
+	if summary == nil {
+		return nil
+	}
+	clone := *summary
+	clone.MediaTypes = maps.Clone(summary.MediaTypes)
+	return &clone
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/findings"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDataURI(t *testing.T) {
+	tests := []struct {
+		name      string
+		href      string
+		mediaType string
+		malformed bool
+	}{
+		{name: "base64", href: "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg==", mediaType: "image/png"},
+		{name: "base64 without padding", href: "data:text/plain;base64,aGVsbG8", mediaType: "text/plain"},
+		{name: "base64 with whitespace", href: "data:text/plain;base64,aGVs bG8=\n", mediaType: "text/plain"},
+		{name: "url-encoded", href: "data:text/html;charset=utf-8,%3Ch1%3EHello%3C%2Fh1%3E", mediaType: "text/html"},
+		{name: "media type lowercased", href: "DATA:Image/SVG+XML,%3Csvg%2F%3E", mediaType: "image/svg+xml"},
+		{name: "no media type", href: "data:,Hello%2C%20World", mediaType: "text/plain"},
+		{name: "parameters only", href: "data:;charset=utf-8;base64,aGVsbG8=", mediaType: "text/plain"},
+		{name: "no comma", href: "data:text/plain;base64", malformed: true},
+		{name: "media type without subtype", href: "data:image,abc", malformed: true},
+		{name: "invalid parameter", href: "data:text/plain;charset,abc", malformed: true},
+		{name: "invalid base64", href: "data:image/png;base64,not*base64", malformed: true},
+		{name: "invalid escape", href: "data:text/plain,100%", malformed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mediaType, ok := parseDataURI(tt.href)
+			assert.Equal(t, !tt.malformed, ok)
+			assert.Equal(t, tt.mediaType, mediaType)
+		})
+	}
+}
+
+func TestHTMLParserParseHTML_DataURIsAndBlobs(t *testing.T) {
+	parser := NewHTMLParser(nil)
+	parser.SetLimits(ParserLimits{LargeDataURIBytes: 100})
+
+	large := "data:image/png;base64," + strings.Repeat("AAAA", 30)
+	content := `<!DOCTYPE html><html><body>
+<a href="/about">About</a>
+<a href="data:text/plain;base64,aGVsbG8=">Hello</a>
+<a href=" DATA:text/csv,a%2Cb ">Table</a>
+<a href="` + large + `">Image</a>
+<a href="data:image/png;base64,***">Broken</a>
+<a href="data:no-comma">Broken</a>
+<a href="blob:https://example.com/550e8400-e29b-41d4-a716-446655440000">Export</a>
+<a href="blob:null/1234">Export</a>
+</body></html>`
+	parsed, err := parser.ParseHTML(context.Background(), []byte(content), "https://example.com/")
+	require.NoError(t, err)
+
+	// Only the http link is left to check
+	require.Len(t, parsed.Links, 1)
+	assert.Equal(t, "https://example.com/about", parsed.Links[0].URL)
+	assert.Equal(t, map[string]int{
+		models.LinkSkipDataURI:    3,
+		models.LinkSkipParseError: 2,
+		models.LinkSkipBlob:       2,
+	}, parsed.SkippedLinks)
+	assert.Equal(t, &models.DataURISummary{
+		MediaTypes:   map[string]int{"text/plain": 1, "text/csv": 1, "image/png": 1},
+		Large:        1,
+		LargeBytes:   100,
+		LargestBytes: len(large),
+	}, parsed.DataURIs)
+}
+
+func TestHTMLParserParseHTML_NoDataURIs(t *testing.T) {
+	parser := NewHTMLParser(nil)
+
+	parsed, err := parser.ParseHTML(context.Background(), []byte(`<a href="blob:https://example.com/1">Export</a>`), "https://example.com/")
+	require.NoError(t, err)
+	assert.Nil(t, parsed.DataURIs)
+	assert.Equal(t, map[string]int{models.LinkSkipBlob: 1}, parsed.SkippedLinks)
+}
+
+func TestMergeDataURIs(t *testing.T) {
+	page := &models.DataURISummary{MediaTypes: map[string]int{"image/png": 2}, LargeBytes: 100, LargestBytes: 40}
+	frame := &models.DataURISummary{MediaTypes: map[string]int{"image/png": 1, "text/plain": 1}, Large: 1, LargeBytes: 100, LargestBytes: 300}
+
+	merged := mergeDataURIs(cloneDataURIs(page), frame)
+	assert.Equal(t, &models.DataURISummary{
+		MediaTypes:   map[string]int{"image/png": 3, "text/plain": 1},
+		Large:        1,
+		LargeBytes:   100,
+		LargestBytes: 300,
+	}, merged)
+	assert.Equal(t, map[string]int{"image/png": 2}, page.MediaTypes, "the page's own summary is left alone")
+
+	assert.Equal(t, frame, mergeDataURIs(nil, frame))
+	assert.NotSame(t, frame, mergeDataURIs(nil, frame))
+	assert.Nil(t, mergeDataURIs(nil, nil))
+}
+
+func TestEvaluateDataURIs(t *testing.T) {
+	result := &models.AnalysisResult{}
+	assert.Empty(t, evaluateDataURIs(result))
+
+	result.Links.DataURIs = &models.DataURISummary{MediaTypes: map[string]int{"image/png": 3}, LargeBytes: 32768, LargestBytes: 2000}
+	assert.Empty(t, evaluateDataURIs(result))
+
+	result.Links.DataURIs.Large = 2
+	result.Links.DataURIs.LargestBytes = 90000
+	list := evaluateDataURIs(result)
+	require.Len(t, list, 1)
+	assert.Equal(t, findings.LinksLargeDataURI, list[0].ID)
+	assert.Equal(t, models.CategoryPerformance, list[0].Category)
+	assert.Equal(t, "2 data: URI links are 32768 bytes or larger, the largest 90000 bytes", list[0].Message)
+	assert.Equal(t, 2, list[0].Evidence.Count)
+}
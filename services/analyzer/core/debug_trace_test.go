@@ -0,0 +1,162 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tracedPage = `<!DOCTYPE html><html><head><title>Traced</title></head><body>
+<a href="/about">About</a><a href="/missing">Missing</a><a href="/contact">Contact</a>
+</body></html>`
+
+// newTraceTestAnalyzer analyzes pages from a local server, checking their
+// links through a link checker service that HEADs them with the shared HTTP
+// client. traceLimits receives the trace_limit of each batch, nil when the
+// analyzer asked for no trace.
+func newTraceTestAnalyzer(t *testing.T) (analyzer *Analyzer, pageURL string, traceLimits chan *int) {
+	t.Helper()
+	log := newTestLogger()
+	client := httpclient.New(2*time.Second, log)
+
+	pages := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			io.WriteString(w, tracedPage)
+		case "/missing":
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(pages.Close)
+
+	traceLimits = make(chan *int, 10)
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Links      []models.Link `json:"links"`
+			TraceLimit *int          `json:"trace_limit"`
+		}
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&req)) {
+			return
+		}
+		traceLimits <- req.TraceLimit
+
+		ctx := r.Context()
+		var collector *trace.Collector
+		if req.TraceLimit != nil {
+			collector = trace.New(*req.TraceLimit)
+			ctx = trace.WithCollector(ctx, collector)
+		}
+		response := struct {
+			LinkStatuses []models.LinkStatus `json:"link_statuses"`
+			Trace        *models.DebugTrace  `json:"trace,omitempty"`
+		}{}
+		for _, link := range req.Links {
+			resp, err := client.Head(ctx, link.URL)
+			if !assert.NoError(t, err) {
+				return
+			}
+			response.LinkStatuses = append(response.LinkStatuses, models.LinkStatus{
+				Link: link, StatusCode: resp.StatusCode, Accessible: resp.StatusCode < 400,
+			})
+		}
+		if collector != nil {
+			response.Trace = collector.Report()
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(service.Close)
+
+	linkChecker := NewLinkCheckerClient(service.URL, 2*time.Second, log)
+	return newTestAnalyzer(t, client, linkChecker), pages.URL + "/", traceLimits
+}
+
+func TestAnalyzer_DebugTrace(t *testing.T) {
+	analyzer, pageURL, traceLimits := newTraceTestAnalyzer(t)
+	analyzer.SetDebugTrace(10)
+
+	result, err := analyzer.AnalyzeURLWithOptions(context.Background(), pageURL, models.AnalysisOptions{Debug: true})
+	require.NoError(t, err)
+
+	// The page fetch took one of the ten entries before the links were sent
+	limit := <-traceLimits
+	require.NotNil(t, limit)
+	assert.Equal(t, 9, *limit)
+
+	require.NotNil(t, result.DebugTrace)
+	requests := result.DebugTrace.Requests
+	require.Len(t, requests, 4)
+	assert.Zero(t, result.DebugTrace.Dropped)
+
+	assert.Equal(t, "GET", requests[0].Method)
+	assert.Equal(t, pageURL, requests[0].URL)
+	assert.Equal(t, http.StatusOK, requests[0].StatusCode)
+	assert.Equal(t, int64(len(tracedPage)), requests[0].Bytes)
+	assert.Empty(t, requests[0].Source)
+
+	base := pageURL[:len(pageURL)-1]
+	for i, want := range []struct {
+		url    string
+		status int
+	}{
+		{base + "/about", http.StatusOK},
+		{base + "/missing", http.StatusNotFound},
+		{base + "/contact", http.StatusOK},
+	} {
+		request := requests[i+1]
+		assert.Equal(t, "HEAD", request.Method)
+		assert.Equal(t, want.url, request.URL)
+		assert.Equal(t, want.status, request.StatusCode)
+		assert.Equal(t, "link-checker", request.Source)
+		assert.Positive(t, request.DurationMs)
+	}
+}
+
+func TestAnalyzer_DebugTraceIsCapped(t *testing.T) {
+	analyzer, pageURL, traceLimits := newTraceTestAnalyzer(t)
+	analyzer.SetDebugTrace(2)
+
+	result, err := analyzer.AnalyzeURLWithOptions(context.Background(), pageURL, models.AnalysisOptions{Debug: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, *<-traceLimits)
+
+	require.NotNil(t, result.DebugTrace)
+	require.Len(t, result.DebugTrace.Requests, 2)
+	assert.Equal(t, pageURL, result.DebugTrace.Requests[0].URL)
+	assert.Equal(t, 2, result.DebugTrace.Dropped)
+}
+
+func TestAnalyzer_DebugTraceNeedsAdminFlag(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		analyzer, pageURL, traceLimits := newTraceTestAnalyzer(t)
+
+		result, err := analyzer.AnalyzeURLWithOptions(context.Background(), pageURL, models.AnalysisOptions{Debug: true})
+		require.NoError(t, err)
+		assert.Nil(t, result.DebugTrace)
+		assert.Nil(t, <-traceLimits)
+	})
+
+	t.Run("not requested", func(t *testing.T) {
+		analyzer, pageURL, traceLimits := newTraceTestAnalyzer(t)
+		analyzer.SetDebugTrace(10)
+
+		// A debug analysis leaves nothing behind for the next one
+		_, err := analyzer.AnalyzeURLWithOptions(context.Background(), pageURL, models.AnalysisOptions{Debug: true})
+		require.NoError(t, err)
+		<-traceLimits
+
+		result, err := analyzer.AnalyzeURL(context.Background(), pageURL)
+		require.NoError(t, err)
+		assert.Nil(t, result.DebugTrace)
+		assert.Nil(t, <-traceLimits)
+	})
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"cmp"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/idn"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"golang.org/x/net/publicsuffix"
+)
+
+// externalDomains counts the external links by registrable domain, most
+// linked first, and returns the MaxExternalDomains first and the number of
+// links to the rest
+func externalDomains(links []models.Link) ([]models.DomainCount, int) {
+	counts := make(map[string]int)
+	for _, link := range links {
+		if link.Type != models.LinkTypeExternal {
+			continue
+		}
+		u, err := url.Parse(link.URL)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		counts[registrableDomain(u.Hostname())]++
+	}
+	if len(counts) == 0 {
+		return nil, 0
+	}
+
+	domains := make([]models.DomainCount, 0, len(counts))
+	for domain, n := range counts {
+		domains = append(domains, models.DomainCount{Domain: domain, Count: n})
+	}
+	slices.SortFunc(domains, func(a, b models.DomainCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Domain, b.Domain))
+	})
+
+	other := 0
+	if len(domains) > models.MaxExternalDomains {
+		for _, domain := range domains[models.MaxExternalDomains:] {
+			other += domain.Count
+		}
+		domains = domains[:models.MaxExternalDomains]
+	}
+	return domains, other
+}
+
+// registrableDomain returns the domain host was registered under, by the
+// public suffix list, such as example.co.uk for cdn.example.co.uk. IP
+// addresses, and hosts that are a public suffix or have no dot, are their
+// own domain.
+func registrableDomain(host string) string {
+	host = strings.TrimSuffix(idn.ASCIIHost(host), ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
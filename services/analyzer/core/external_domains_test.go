@@ -0,0 +1,81 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func external(urls ...string) []models.Link {
+	var links []models.Link
+	for _, u := range urls {
+		links = append(links, models.Link{URL: u, Type: models.LinkTypeExternal})
+	}
+	return links
+}
+
+func TestExternalDomains_GroupsSubdomains(t *testing.T) {
+	links := append(external(
+		"https://cdn.example.co.uk/app.js",
+		"https://www.example.co.uk/",
+		"https://EXAMPLE.co.uk./about",
+		"https://static.other.com/a.css",
+		"https://other.com:8443/",
+		"https://user.github.io/",
+		"https://someone.github.io/",
+	), models.Link{URL: "https://example.com/", Type: models.LinkTypeInternal})
+
+	domains, other := externalDomains(links)
+
+	// github.io is a public suffix, so its sites are told apart
+	assert.Equal(t, []models.DomainCount{
+		{Domain: "example.co.uk", Count: 3},
+		{Domain: "other.com", Count: 2},
+		{Domain: "someone.github.io", Count: 1},
+		{Domain: "user.github.io", Count: 1},
+	}, domains)
+	assert.Zero(t, other)
+}
+
+func TestExternalDomains_IPAddressesAndBareHosts(t *testing.T) {
+	domains, _ := externalDomains(external(
+		"http://192.168.1.10/admin",
+		"http://192.168.1.10:8080/",
+		"http://10.0.0.1/",
+		"http://[2001:db8::1]/",
+		"http://intranet/",
+		"http://co.uk/",
+		"mailto:someone@example.com",
+	))
+
+	assert.Equal(t, []models.DomainCount{
+		{Domain: "192.168.1.10", Count: 2},
+		{Domain: "10.0.0.1", Count: 1},
+		{Domain: "2001:db8::1", Count: 1},
+		{Domain: "co.uk", Count: 1},
+		{Domain: "intranet", Count: 1},
+	}, domains)
+}
+
+func TestExternalDomains_Other(t *testing.T) {
+	var urls []string
+	for i := range models.MaxExternalDomains + 3 {
+		urls = append(urls, fmt.Sprintf("https://site%02d.example/", i))
+	}
+	urls = append(urls, "https://a.popular.example/", "https://b.popular.example/")
+
+	domains, other := externalDomains(external(urls...))
+
+	assert.Len(t, domains, models.MaxExternalDomains)
+	assert.Equal(t, models.DomainCount{Domain: "popular.example", Count: 2}, domains[0])
+	assert.Equal(t, "site00.example", domains[1].Domain)
+	assert.Equal(t, 4, other, "the links to the 4 domains left out")
+}
+
+func TestExternalDomains_None(t *testing.T) {
+	domains, other := externalDomains([]models.Link{{URL: "https://example.com/", Type: models.LinkTypeInternal}})
+	assert.Nil(t, domains)
+	assert.Zero(t, other)
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/budget"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// FailureCause classifies the error of a failed analysis as one of
+// models.FailureCauses. A lookup that times out is a DNS failure; a page too
+// big for the outbound budget is too large. An analysis its callers gave up
+// on is canceled.
+func FailureCause(err error) string {
+	var dnsErr *net.DNSError
+	var statusErr *models.HTTPStatusError
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return models.FailureCanceled
+	case errors.As(err, &dnsErr):
+		return models.FailureDNS
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return models.FailureTimeout
+	case errors.As(err, &statusErr):
+		if statusErr.StatusCode >= 500 {
+			return models.FailureHTTP5xx
+		}
+		return models.FailureHTTP4xx
+	case errors.Is(err, ErrParse):
+		return models.FailureParse
+	case errors.Is(err, budget.ErrExhausted):
+		return models.FailureTooLarge
+	case isConnectionError(err):
+		return models.FailureConnection
+	}
+	return models.FailureOther
+}
+
+// isConnectionError reports whether err is a failure to connect to the
+// page's server or to keep the connection up: refused, reset, or a failed
+// TLS handshake
+func isConnectionError(err error) bool {
+	var opErr *net.OpError
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+
+	return errors.As(err, &opErr) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &certErr) ||
+		errors.As(err, &recordErr) ||
+		errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr)
+}
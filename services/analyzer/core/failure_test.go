@@ -0,0 +1,94 @@
+package core
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/budget"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fetchFailure wraps err the way a failed page fetch reaches the analyzer
+func fetchFailure(err error) error {
+	return fmt.Errorf("failed to fetch URL: request failed: %w", &url.Error{Op: "Get", URL: "https://example.com", Err: err})
+}
+
+func TestFailureCause(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		cause string
+	}{
+		{name: "unknown host", err: fetchFailure(&net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}), cause: models.FailureDNS},
+		{name: "lookup timeout", err: fetchFailure(&net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true}), cause: models.FailureDNS},
+		{name: "analysis deadline", err: fmt.Errorf("failed to fetch URL: %w", context.DeadlineExceeded), cause: models.FailureTimeout},
+		{name: "read timeout", err: fetchFailure(os.ErrDeadlineExceeded), cause: models.FailureTimeout},
+		{name: "connection refused", err: fetchFailure(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), cause: models.FailureConnection},
+		{name: "connection reset", err: fetchFailure(syscall.ECONNRESET), cause: models.FailureConnection},
+		{name: "connection closed early", err: fetchFailure(io.ErrUnexpectedEOF), cause: models.FailureConnection},
+		{name: "untrusted certificate", err: fetchFailure(x509.UnknownAuthorityError{}), cause: models.FailureConnection},
+		{name: "not found", err: &models.HTTPStatusError{StatusCode: 404}, cause: models.FailureHTTP4xx},
+		{name: "forbidden, wrapped", err: fmt.Errorf("frame: %w", &models.HTTPStatusError{StatusCode: 403}), cause: models.FailureHTTP4xx},
+		{name: "unavailable", err: &models.HTTPStatusError{StatusCode: 503}, cause: models.FailureHTTP5xx},
+		{name: "parse", err: fmt.Errorf("%w: %w", ErrParse, errors.New("unexpected EOF")), cause: models.FailureParse},
+		{name: "budget exhausted", err: fetchFailure(budget.ErrExhausted), cause: models.FailureTooLarge},
+		{name: "caller gone", err: fetchFailure(context.Canceled), cause: models.FailureCanceled},
+		{name: "not HTML", err: &models.UnsupportedContentTypeError{ContentType: "application/pdf"}, cause: models.FailureOther},
+		{name: "redirect loop", err: fetchFailure(httpclient.ErrRedirectLoop), cause: models.FailureOther},
+		{name: "anything else", err: errors.New("boom"), cause: models.FailureOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.cause, FailureCause(tt.err))
+		})
+	}
+}
+
+func TestFailureCause_RefusedConnection(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	log := newTestLogger()
+	_, err = NewHTTPFetcher(httpclient.New(2*time.Second, log)).Fetch(context.Background(), "http://"+addr+"/")
+	require.Error(t, err)
+	assert.Equal(t, models.FailureConnection, FailureCause(err))
+}
+
+// hostRecorder is a FailureTracker keeping every failure it is told about
+type hostRecorder struct {
+	failures []string
+}
+
+func (r *hostRecorder) RecordHostFailure(host, cause string) {
+	r.failures = append(r.failures, host+" "+cause)
+}
+
+func TestAnalyzer_RecordsFailingHost(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	analyzer := newTestAnalyzer(t, nil, newStatusLinkChecker())
+	recorder := &hostRecorder{}
+	analyzer.SetFailureTracker(recorder)
+
+	_, err = analyzer.AnalyzeURL(context.Background(), "http://"+addr+"/page")
+	require.Error(t, err)
+	assert.Equal(t, []string{addr + " " + models.FailureConnection}, recorder.failures)
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// HTTPFetcher is the default FetcherStrategy: a plain GET of the page
+type HTTPFetcher struct {
+	httpClient interfaces.HTTPClient
+	// timeout bounds each fetch, see SetTimeout
+	timeout time.Duration
+}
+
+func NewHTTPFetcher(httpClient interfaces.HTTPClient) *HTTPFetcher {
+	return &HTTPFetcher{httpClient: httpClient}
+}
+
+// SetTimeout bounds each fetch, body read included, by a deadline derived
+// from its context. Zero, the default, leaves the context's deadline alone.
+func (f *HTTPFetcher) SetTimeout(timeout time.Duration) {
+	f.timeout = max(timeout, 0)
+}
+
+// Fetch returns the page body, treating HTTP error statuses as failures
+func (f *HTTPFetcher) Fetch(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	ctx, cancel := f.deadline(ctx)
+	defer cancel()
+	return checkResponse(f.httpClient.Get(ctx, url))
+}
+
+// FetchConditional revalidates a previous fetch when the HTTP client supports
+// it. A 304 Not Modified is returned as a response with an empty body; other
+// clients, or zero validators, fall back to Fetch.
+func (f *HTTPFetcher) FetchConditional(ctx context.Context, url string, validators models.Validators) (*models.HTTPResponse, error) {
+	client, ok := f.httpClient.(interfaces.ConditionalHTTPClient)
+	if !ok || validators.IsZero() {
+		return f.Fetch(ctx, url)
+	}
+	ctx, cancel := f.deadline(ctx)
+	defer cancel()
+	return checkResponse(client.GetConditional(ctx, url, validators))
+}
+
+// deadline derives the context of one fetch from ctx. The client reads the
+// whole body before returning, so the deadline may end with the call.
+func (f *HTTPFetcher) deadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if f.timeout == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, f.timeout)
+}
+
+func checkResponse(response *models.HTTPResponse, err error) (*models.HTTPResponse, error) {
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+
+	if response.StatusCode >= 400 {
+		return nil, &models.HTTPStatusError{StatusCode: response.StatusCode, Challenges: challenges(response.StatusCode, response.Headers)}
+	}
+
+	return response, nil
+}
+
+// challenges returns the authentication challenges of a 401 or 407 response
+func challenges(statusCode int, headers http.Header) []string {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return headers.Values("WWW-Authenticate")
+	case http.StatusProxyAuthRequired:
+		return headers.Values("Proxy-Authenticate")
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/findings"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// pageFindings gathers the findings of the selected rules, in their order
+func pageFindings(selected []Rule, result *models.AnalysisResult) []models.Finding {
+	var list []models.Finding
+	for _, rule := range selected {
+		list = append(list, rule.Findings(result)...)
+	}
+	return list
+}
+
+// evaluateTitle judges the title. Results without a title report, from
+// analyzers predating it, are only checked for a missing title.
+func evaluateTitle(result *models.AnalysisResult) []models.Finding {
+	titles := result.Titles
+	if titles == nil {
+		titles = &models.TitleReport{}
+	}
+	evidence := models.FindingEvidence{Selectors: []string{"title"}}
+
+	var list []models.Finding
+	empty := strings.TrimSpace(result.Title) == ""
+	switch {
+	case empty && titles.Count == 0:
+		list = append(list, findings.New(findings.TitleMissing, "The page has no title", evidence))
+	case empty:
+		list = append(list, findings.New(findings.TitleEmpty, "The page's title element is empty", evidence))
+	case titles.MinLength > 0 && titles.Length < titles.MinLength:
+		list = append(list, findings.New(findings.TitleTooShort,
+			fmt.Sprintf("The title is %d characters long, shorter than %d", titles.Length, titles.MinLength),
+			models.FindingEvidence{Selectors: evidence.Selectors, Values: []string{result.Title}}))
+	case titles.MaxLength > 0 && titles.Length > titles.MaxLength:
+		list = append(list, findings.New(findings.TitleTooLong,
+			fmt.Sprintf("The title is %d characters long, longer than %d", titles.Length, titles.MaxLength),
+			models.FindingEvidence{Selectors: evidence.Selectors, Values: []string{result.Title}}))
+	}
+	if titles.Count > 1 {
+		list = append(list, findings.New(findings.TitleMultiple,
+			fmt.Sprintf("The page has %d title elements; the first is its title", titles.Count),
+			models.FindingEvidence{Selectors: evidence.Selectors, Count: titles.Count}))
+	}
+	return list
+}
+
+func evaluateHeadings(result *models.AnalysisResult) []models.Finding {
+	switch h1 := result.Headings.H1; {
+	case h1 == 0:
+		return []models.Finding{findings.New(findings.HeadingsMissingH1, "The page has no h1 heading",
+			models.FindingEvidence{Selectors: []string{"h1"}})}
+	case h1 > 1:
+		return []models.Finding{findings.New(findings.HeadingsMultipleH1, fmt.Sprintf("The page has %d h1 headings", h1),
+			models.FindingEvidence{Selectors: []string{"h1"}, Count: h1})}
+	default:
+		return nil
+	}
+}
+
+func evaluateLinks(result *models.AnalysisResult) []models.Finding {
+	return append(evaluateBrokenLinks(result), evaluateDataURIs(result)...)
+}
+
+func evaluateBrokenLinks(result *models.AnalysisResult) []models.Finding {
+	n := result.Links.Inaccessible
+	if n == 0 {
+		return nil
+	}
+	return []models.Finding{findings.New(findings.LinksBroken, fmt.Sprintf("%d of the %d links could not be reached", n, result.Links.Total),
+		models.FindingEvidence{Selectors: []string{"a[href]"}, Count: n})}
+}
+
+func evaluateDataURIs(result *models.AnalysisResult) []models.Finding {
+	summary := result.Links.DataURIs
+	if summary == nil || summary.Large == 0 {
+		return nil
+	}
+	return []models.Finding{findings.New(findings.LinksLargeDataURI,
+		fmt.Sprintf("%d data: URI links are %d bytes or larger, the largest %d bytes", summary.Large, summary.LargeBytes, summary.LargestBytes),
+		models.FindingEvidence{Selectors: []string{`a[href^="data:"]`}, Count: summary.Large})}
+}
+
+func evaluateNoopener(result *models.AnalysisResult) []models.Finding {
+	if result.LinkFindings == nil {
+		return nil
+	}
+	var list []models.Finding
+	for _, finding := range result.LinkFindings.Findings {
+		if finding.Kind != models.LinkMissingNoopener {
+			continue
+		}
+		list = append(list, findings.New(findings.LinksMissingNoopener,
+			fmt.Sprintf(`%d links open a new tab without rel="noopener"`, finding.Count),
+			models.FindingEvidence{URLs: finding.URLs, Selectors: []string{`a[target="_blank"]`}, Count: finding.Count}))
+	}
+	return list
+}
+
+// linkTextFindings are the IDs of the link text finding kinds
+var linkTextFindings = map[string]string{
+	models.LinkTextEmpty:     findings.LinkTextEmpty,
+	models.LinkTextGeneric:   findings.LinkTextGeneric,
+	models.LinkTextAmbiguous: findings.LinkTextAmbiguous,
+}
+
+func evaluateLinkText(result *models.AnalysisResult) []models.Finding {
+	if result.Accessibility == nil {
+		return nil
+	}
+	var list []models.Finding
+	for _, finding := range result.Accessibility.LinkText {
+		id, ok := linkTextFindings[finding.Kind]
+		if !ok {
+			continue
+		}
+		var message string
+		switch finding.Kind {
+		case models.LinkTextEmpty:
+			message = fmt.Sprintf("%d links have no text, alt text or aria-label", finding.Count)
+		case models.LinkTextGeneric:
+			message = fmt.Sprintf("%d links have text that says nothing about where they lead", finding.Count)
+		default:
+			message = fmt.Sprintf("%d links share their text with links to other URLs", finding.Count)
+		}
+		list = append(list, findings.New(id, message, models.FindingEvidence{
+			URLs: finding.URLs, Selectors: []string{"a[href]"}, Count: finding.Count, Values: finding.Texts,
+		}))
+	}
+	return list
+}
+
+func evaluateAnchors(result *models.AnalysisResult) []models.Finding {
+	if result.Anchors == nil {
+		return nil
+	}
+	var list []models.Finding
+	if duplicates := result.Anchors.DuplicateIDs; len(duplicates) > 0 {
+		ids := make([]string, len(duplicates))
+		for i, duplicate := range duplicates {
+			ids[i] = duplicate.ID
+		}
+		list = append(list, findings.New(findings.AnchorsDuplicateID,
+			fmt.Sprintf("%d ids are shared by more than one element", len(duplicates)),
+			models.FindingEvidence{Selectors: []string{"[id]"}, Count: len(duplicates), Values: ids}))
+	}
+	if dangling := result.Anchors.DanglingAnchors; len(dangling) > 0 {
+		list = append(list, findings.New(findings.AnchorsDangling,
+			fmt.Sprintf("%d in-page links point at no element of the page", len(dangling)),
+			models.FindingEvidence{Selectors: []string{`a[href*="#"]`}, Count: len(dangling), Values: dangling}))
+	}
+	return list
+}
+
+func evaluateLoginForm(result *models.AnalysisResult) []models.Finding {
+	if !result.HasLoginForm {
+		return nil
+	}
+	pageURL := result.URL
+	if result.FinalURL != "" {
+		pageURL = result.FinalURL
+	}
+	evidence := models.FindingEvidence{URLs: []string{pageURL}, Selectors: []string{`form input[type="password"]`}}
+
+	if u, err := url.Parse(pageURL); err == nil && u.Scheme == "http" {
+		return []models.Finding{findings.New(findings.LoginFormInsecure,
+			"The page has a login form but is served over plain HTTP, so passwords are sent unencrypted", evidence)}
+	}
+	return []models.Finding{findings.New(findings.LoginFormPresent, "The page has a login form", evidence)}
+}
+
+func evaluateContent(result *models.AnalysisResult) []models.Finding {
+	if result.Content == nil {
+		return nil
+	}
+	var list []models.Finding
+	for _, finding := range result.Content.Findings {
+		if finding.Kind != models.ContentLanguageMismatch {
+			continue
+		}
+		list = append(list, findings.New(findings.ContentLanguageMismatch, upperFirst(finding.Detail), models.FindingEvidence{
+			Selectors: []string{"html[lang]"}, Values: []string{result.Content.DeclaredLanguage, result.Content.Language},
+		}))
+	}
+	return list
+}
+
+func evaluateRobots(result *models.AnalysisResult) []models.Finding {
+	robots := result.Robots
+	if robots == nil {
+		return nil
+	}
+	var list []models.Finding
+	if !robots.Indexable {
+		list = append(list, findings.New(findings.RobotsNoIndex, "Search engines are told not to index the page",
+			robotsEvidence(robots, func(d models.RobotsDirectives) bool { return d.NoIndex })))
+	}
+	if !robots.Followable {
+		list = append(list, findings.New(findings.RobotsNoFollow, "Search engines are told not to follow the links of the page",
+			robotsEvidence(robots, func(d models.RobotsDirectives) bool { return d.NoFollow })))
+	}
+	for _, conflict := range robots.Conflicts {
+		crawler := "all crawlers"
+		if conflict.UserAgent != "" {
+			crawler = conflict.UserAgent
+		}
+		list = append(list, findings.New(findings.RobotsConflict,
+			fmt.Sprintf("For %s, the robots meta tags say %s but the X-Robots-Tag header says %s", crawler, conflict.Meta, conflict.Header),
+			models.FindingEvidence{Selectors: []string{`meta[name="robots"]`}, Values: []string{conflict.Meta, conflict.Header}}))
+	}
+	return list
+}
+
+// robotsEvidence lists the directives that match, as sent, and where they
+// come from
+func robotsEvidence(robots *models.RobotsReport, match func(models.RobotsDirectives) bool) models.FindingEvidence {
+	var evidence models.FindingEvidence
+	for _, directives := range robots.Directives {
+		if !match(directives) {
+			continue
+		}
+		evidence.Values = append(evidence.Values, directives.Values...)
+		if directives.Source == models.RobotsSourceMeta {
+			name := "robots"
+			if directives.UserAgent != "" {
+				name = directives.UserAgent
+			}
+			evidence.Selectors = append(evidence.Selectors, fmt.Sprintf(`meta[name=%q]`, name))
+		}
+	}
+	return evidence
+}
+
+// hreflangFindings are the IDs of the hreflang finding kinds
+var hreflangFindings = map[string]string{
+	models.HreflangInvalidCode:     findings.HreflangInvalidCode,
+	models.HreflangUnreachable:     findings.HreflangUnreachable,
+	models.HreflangMissingXDefault: findings.HreflangMissingXDefault,
+	models.HreflangNotReciprocal:   findings.HreflangNotReciprocal,
+}
+
+func evaluateHreflang(result *models.AnalysisResult) []models.Finding {
+	if result.Hreflang == nil {
+		return nil
+	}
+	var list []models.Finding
+	for _, finding := range result.Hreflang.Findings {
+		id, ok := hreflangFindings[finding.Kind]
+		if !ok {
+			continue
+		}
+		definition, _ := findings.Lookup(id)
+		message := definition.Description
+		if finding.Detail != "" {
+			message += ": " + finding.Detail
+		}
+		evidence := models.FindingEvidence{Selectors: []string{`link[rel="alternate"][hreflang]`}}
+		if finding.URL != "" {
+			evidence.URLs = []string{finding.URL}
+		}
+		if finding.Lang != "" {
+			evidence.Values = []string{finding.Lang}
+		}
+		list = append(list, findings.New(id, message, evidence))
+	}
+	return list
+}
+
+// ampFindings are the IDs of the AMP finding kinds
+var ampFindings = map[string]string{
+	models.AMPMissingCanonical:     findings.AMPMissingCanonical,
+	models.AMPUnreachableAMPHTML:   findings.AMPUnreachableAMPHTML,
+	models.AMPUnreachableCanonical: findings.AMPUnreachableCanonical,
+}
+
+func evaluateAMP(result *models.AnalysisResult) []models.Finding {
+	if result.AMP == nil {
+		return nil
+	}
+	var list []models.Finding
+	for _, finding := range result.AMP.Findings {
+		id, ok := ampFindings[finding.Kind]
+		if !ok {
+			continue
+		}
+		definition, _ := findings.Lookup(id)
+		message := definition.Description
+		if finding.Detail != "" {
+			message += ": " + finding.Detail
+		}
+		selector := `link[rel="canonical"]`
+		if finding.Kind == models.AMPUnreachableAMPHTML {
+			selector = `link[rel="amphtml"]`
+		}
+		evidence := models.FindingEvidence{Selectors: []string{selector}}
+		if finding.URL != "" {
+			evidence.URLs = []string{finding.URL}
+		}
+		list = append(list, findings.New(id, message, evidence))
+	}
+	return list
+}
+
+// paginationFindings are the IDs of the pagination finding kinds
+var paginationFindings = map[string]string{
+	models.PaginationUnreachableNext: findings.PaginationUnreachableNext,
+	models.PaginationUnreachablePrev: findings.PaginationUnreachablePrev,
+	models.PaginationSelfReference:   findings.PaginationSelfReference,
+	models.PaginationChainLoop:       findings.PaginationChainLoop,
+	models.PaginationChainBroken:     findings.PaginationChainBroken,
+}
+
+func evaluatePagination(result *models.AnalysisResult) []models.Finding {
+	if result.Pagination == nil {
+		return nil
+	}
+	var list []models.Finding
+	for _, finding := range result.Pagination.Findings {
+		id, ok := paginationFindings[finding.Kind]
+		if !ok {
+			continue
+		}
+		definition, _ := findings.Lookup(id)
+		message := definition.Description
+		if finding.Detail != "" {
+			message += ": " + finding.Detail
+		}
+		selector := `link[rel="next"]`
+		if finding.Kind == models.PaginationUnreachablePrev || finding.Kind == models.PaginationSelfReference && finding.URL != result.Pagination.NextURL {
+			selector = `link[rel="prev"]`
+		}
+		evidence := models.FindingEvidence{Selectors: []string{selector}}
+		if finding.URL != "" {
+			evidence.URLs = []string{finding.URL}
+		}
+		list = append(list, findings.New(id, message, evidence))
+	}
+	return list
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
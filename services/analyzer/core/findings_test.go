@@ -0,0 +1,220 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/findings"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// troubledResult has every problem a result section can report
+func troubledResult() *models.AnalysisResult {
+	return &models.AnalysisResult{
+		URL:      "http://example.com/login",
+		Titles:   &models.TitleReport{Count: 2, MinLength: 10, MaxLength: 60},
+		Headings: models.HeadingCount{H1: 3},
+		Links: models.LinkSummary{Total: 10, Inaccessible: 2, DataURIs: &models.DataURISummary{
+			MediaTypes: map[string]int{"image/png": 1}, Large: 1, LargeBytes: 32768, LargestBytes: 40000,
+		}},
+		HasLoginForm: true,
+		LinkFindings: &models.LinkFindings{NewTab: 1, Findings: []models.LinkFinding{
+			{Kind: models.LinkMissingNoopener, Count: 1, URLs: []string{"https://other.example/"}},
+		}},
+		Accessibility: &models.AccessibilityReport{LinkText: []models.LinkFinding{
+			{Kind: models.LinkTextEmpty, Count: 1, URLs: []string{"http://example.com/icon"}},
+			{Kind: models.LinkTextGeneric, Count: 2, URLs: []string{"http://example.com/a"}, Texts: []string{"click here"}},
+			{Kind: models.LinkTextAmbiguous, Count: 2, URLs: []string{"http://example.com/b", "http://example.com/c"}, Texts: []string{"more"}},
+		}},
+		Content: &models.ContentReport{
+			TextStats:        models.TextStats{Language: "de", Confidence: 0.9},
+			DeclaredLanguage: "en",
+			Findings: []models.ContentFinding{
+				{Kind: models.ContentLanguageMismatch, Detail: `the page declares "en" but its text reads as de`},
+			},
+		},
+		Robots: &models.RobotsReport{
+			Directives: []models.RobotsDirectives{
+				{Source: models.RobotsSourceMeta, NoIndex: true, NoFollow: true, Values: []string{"none"}},
+				{Source: models.RobotsSourceHeader, Values: []string{"index"}},
+			},
+			Conflicts: []models.RobotsConflict{{Directive: "index", Meta: "noindex", Header: "index"}},
+		},
+		Anchors: &models.AnchorReport{
+			DuplicateIDs:    []models.DuplicateID{{ID: "main", Count: 2}},
+			DanglingAnchors: []string{"pricing"},
+		},
+		Hreflang: &models.HreflangReport{Findings: []models.HreflangFinding{
+			{Kind: models.HreflangInvalidCode, Lang: "english", URL: "http://example.com/en"},
+			{Kind: models.HreflangUnreachable, Lang: "de", URL: "http://example.com/de", Detail: "status 404"},
+			{Kind: models.HreflangMissingXDefault},
+			{Kind: models.HreflangNotReciprocal, Lang: "fr", URL: "http://example.com/fr"},
+		}},
+		AMP: &models.AMPReport{IsAMP: true, Findings: []models.AMPFinding{
+			{Kind: models.AMPMissingCanonical},
+			{Kind: models.AMPUnreachableAMPHTML, URL: "http://example.com/amp"},
+			{Kind: models.AMPUnreachableCanonical, URL: "http://example.com/"},
+		}},
+		Pagination: &models.PaginationReport{Position: models.PaginationMiddle, NextURL: "http://example.com/?page=3",
+			PrevURL: "http://example.com/?page=1", Findings: []models.PaginationFinding{
+				{Kind: models.PaginationUnreachableNext, URL: "http://example.com/?page=3", Detail: "status 404"},
+				{Kind: models.PaginationUnreachablePrev, URL: "http://example.com/?page=1"},
+				{Kind: models.PaginationSelfReference, URL: "http://example.com/?page=2"},
+				{Kind: models.PaginationChainLoop, URL: "http://example.com/?page=2"},
+				{Kind: models.PaginationChainBroken, URL: "http://example.com/?page=4"},
+			}},
+	}
+}
+
+// everyRule is the default set of rules
+var everyRule = (&Analyzer{}).selectRules(models.RuleSelection{})
+
+func findingIDs(list []models.Finding) []string {
+	var ids []string
+	for _, finding := range list {
+		ids = append(ids, finding.ID)
+	}
+	return ids
+}
+
+// TestPageFindings_EveryDefinitionIsEmitted guards the registry both ways:
+// every finding the evaluators emit is registered, or findings.New panics,
+// and every registered finding is emitted by some evaluator
+func TestPageFindings_EveryDefinitionIsEmitted(t *testing.T) {
+	secure := &models.AnalysisResult{URL: "https://example.com/login", Title: "Login", HasLoginForm: true}
+	untitled := &models.AnalysisResult{URL: "https://example.com/"}
+	short := &models.AnalysisResult{URL: "https://example.com/", Title: "Login",
+		Titles: &models.TitleReport{Count: 1, Length: 5, MinLength: 10, MaxLength: 60}}
+	long := &models.AnalysisResult{URL: "https://example.com/", Title: strings.Repeat("Login ", 12),
+		Titles: &models.TitleReport{Count: 1, Length: 72, MinLength: 10, MaxLength: 60}}
+
+	var emitted []string
+	for _, result := range []*models.AnalysisResult{troubledResult(), secure, untitled, short, long} {
+		emitted = append(emitted, findingIDs(pageFindings(everyRule, result))...)
+	}
+	// The auth finding stands in for the rules of a page that asked for
+	// authentication
+	locked := &models.AnalysisResult{URL: "https://example.com/", RequiresAuth: &models.AuthRequirement{StatusCode: 401}}
+	emitted = append(emitted, findingIDs(evaluateAuth(locked))...)
+
+	for _, definition := range findings.Definitions() {
+		assert.Contains(t, emitted, definition.ID)
+	}
+}
+
+func TestPageFindings(t *testing.T) {
+	list := pageFindings(everyRule, troubledResult())
+
+	assert.Equal(t, []string{
+		findings.TitleEmpty, findings.TitleMultiple,
+		findings.HeadingsMultipleH1,
+		findings.LinksBroken, findings.LinksLargeDataURI, findings.LinksMissingNoopener,
+		findings.LinkTextEmpty, findings.LinkTextGeneric, findings.LinkTextAmbiguous,
+		findings.AnchorsDuplicateID, findings.AnchorsDangling,
+		findings.LoginFormInsecure,
+		findings.ContentLanguageMismatch,
+		findings.RobotsNoIndex, findings.RobotsNoFollow, findings.RobotsConflict,
+		findings.HreflangInvalidCode, findings.HreflangUnreachable, findings.HreflangMissingXDefault, findings.HreflangNotReciprocal,
+		findings.AMPMissingCanonical, findings.AMPUnreachableAMPHTML, findings.AMPUnreachableCanonical,
+		findings.PaginationUnreachableNext, findings.PaginationUnreachablePrev, findings.PaginationSelfReference,
+		findings.PaginationChainLoop, findings.PaginationChainBroken,
+	}, findingIDs(list))
+
+	byID := make(map[string]models.Finding)
+	for _, finding := range list {
+		byID[finding.ID] = finding
+	}
+
+	multiple := byID[findings.HeadingsMultipleH1]
+	assert.Equal(t, "The page has 3 h1 headings", multiple.Message)
+	assert.Equal(t, models.FindingEvidence{Selectors: []string{"h1"}, Count: 3}, multiple.Evidence)
+	assert.Equal(t, models.CategorySEO, multiple.Category)
+	assert.Equal(t, models.SeverityInfo, multiple.Severity)
+
+	generic := byID[findings.LinkTextGeneric]
+	assert.Equal(t, models.CategoryAccessibility, generic.Category)
+	assert.Equal(t, []string{"click here"}, generic.Evidence.Values)
+	assert.Equal(t, 2, generic.Evidence.Count)
+
+	noopener := byID[findings.LinksMissingNoopener]
+	assert.Equal(t, models.CategorySecurity, noopener.Category)
+	assert.Equal(t, []string{"https://other.example/"}, noopener.Evidence.URLs)
+
+	duplicate := byID[findings.AnchorsDuplicateID]
+	assert.Equal(t, "1 ids are shared by more than one element", duplicate.Message)
+	assert.Equal(t, []string{"main"}, duplicate.Evidence.Values)
+
+	dangling := byID[findings.AnchorsDangling]
+	assert.Equal(t, []string{"pricing"}, dangling.Evidence.Values)
+	assert.Equal(t, 1, dangling.Evidence.Count)
+
+	mismatch := byID[findings.ContentLanguageMismatch]
+	assert.Equal(t, `The page declares "en" but its text reads as de`, mismatch.Message)
+	assert.Equal(t, []string{"en", "de"}, mismatch.Evidence.Values)
+
+	noindex := byID[findings.RobotsNoIndex]
+	assert.Equal(t, []string{"none"}, noindex.Evidence.Values)
+	assert.Equal(t, []string{`meta[name="robots"]`}, noindex.Evidence.Selectors)
+
+	unreachable := byID[findings.HreflangUnreachable]
+	assert.Equal(t, "An hreflang alternate could not be reached: status 404", unreachable.Message)
+	assert.Equal(t, []string{"http://example.com/de"}, unreachable.Evidence.URLs)
+	assert.Equal(t, []string{"de"}, unreachable.Evidence.Values)
+
+	next := byID[findings.PaginationUnreachableNext]
+	assert.Equal(t, "The next page of the series could not be reached: status 404", next.Message)
+	assert.Equal(t, []string{`link[rel="next"]`}, next.Evidence.Selectors)
+	self := byID[findings.PaginationSelfReference]
+	assert.Equal(t, []string{`link[rel="prev"]`}, self.Evidence.Selectors, "the page is not its next page, so it is the previous one")
+}
+
+func TestPageFindings_CleanPage(t *testing.T) {
+	result := &models.AnalysisResult{
+		URL:      "https://example.com",
+		Title:    "Example",
+		Headings: models.HeadingCount{H1: 1, H2: 3},
+		Links:    models.LinkSummary{Total: 4, Internal: 4},
+		Robots:   &models.RobotsReport{Indexable: true, Followable: true},
+		Content:  &models.ContentReport{TextStats: models.TextStats{Words: 300, Language: "en"}},
+	}
+
+	assert.Empty(t, pageFindings(everyRule, result))
+}
+
+func TestPageFindings_MissingH1(t *testing.T) {
+	list := pageFindings(everyRule, &models.AnalysisResult{URL: "https://example.com", Title: "Example"})
+
+	require.Len(t, list, 1)
+	assert.Equal(t, findings.HeadingsMissingH1, list[0].ID)
+	assert.Equal(t, models.SeverityWarning, list[0].Severity)
+}
+
+func TestAnalyzer_AnalyzeURL_Findings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<html><head><title></title></head><body>
+			<h1>One</h1><h1>Two</h1>
+			<a href="https://other.example/" target="_blank">Other site</a>
+		</body></html>`)
+	}))
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+
+	result, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	got := findingIDs(result.Findings)
+	assert.Contains(t, got, findings.TitleEmpty)
+	assert.Contains(t, got, findings.HeadingsMultipleH1)
+	assert.Contains(t, got, findings.LinksMissingNoopener)
+
+	require.NotNil(t, result.FindingSummary)
+	assert.Equal(t, len(result.Findings), result.FindingSummary.Total)
+	assert.Equal(t, 1, result.FindingSummary.ByCategory[models.CategorySecurity])
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"maps"
+	"net/url"
+	"slices"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/idn"
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultMaxFrames caps the frame documents followed per analysis
+const DefaultMaxFrames = 10
+
+// frameFetchConcurrency caps the frame documents fetched at once
+const frameFetchConcurrency = 4
+
+// errFrameLimit is reported for frames beyond the analyzer's frame limit
+const errFrameLimit = "not followed, frame limit reached"
+
+// SetMaxFrames caps the frame documents followed per analysis
+func (a *Analyzer) SetMaxFrames(n int) {
+	if n > 0 {
+		a.maxFrames = n
+	}
+}
+
+// listFrames reports the frames of a page without following them
+func listFrames(sources []string) []models.Frame {
+	frames := make([]models.Frame, len(sources))
+	for i, src := range sources {
+		frames[i] = models.Frame{URL: src}
+	}
+	return frames
+}
+
+// followFrames fetches and parses the frame documents of page, up to the
+// frame limit, and returns a copy of page with their headings and links
+// merged in. Frames of frames are not followed. A frame that cannot be
+// fetched or parsed is reported with its error and contributes nothing.
+func (a *Analyzer) followFrames(ctx context.Context, pageURL string, fetcher interfaces.FetcherStrategy, page *models.ParsedHTML) (*models.ParsedHTML, []models.Frame) {
+	frames := listFrames(page.Frames)
+	documents := make([]*models.ParsedHTML, len(frames))
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(frameFetchConcurrency)
+	for i := range frames {
+		if i >= a.maxFrames {
+			frames[i].Error = errFrameLimit
+			continue
+		}
+		g.Go(func() error {
+			documents[i], frames[i].Error = a.fetchFrame(gctx, fetcher, frames[i].URL)
+			return nil
+		})
+	}
+	g.Wait()
+
+	merged := *page
+	merged.Headings = maps.Clone(page.Headings)
+	if merged.Headings == nil {
+		merged.Headings = make(map[string][]string)
+	}
+	merged.Links = slices.Clone(page.Links)
+	merged.SkippedLinks = maps.Clone(page.SkippedLinks)
+	merged.DataURIs = cloneDataURIs(page.DataURIs)
+
+	pageHost := hostOf(pageURL)
+	for i, document := range documents {
+		if document == nil {
+			continue
+		}
+		for level, texts := range document.Headings {
+			merged.Headings[level] = slices.Concat(merged.Headings[level], texts)
+		}
+		for _, link := range document.Links {
+			// Frame links were resolved against the frame; classify them
+			// against the page like its own links
+			link.Type = models.LinkTypeExternal
+			if hostOf(link.URL) == pageHost {
+				link.Type = models.LinkTypeInternal
+			}
+			merged.Links = append(merged.Links, link)
+		}
+		for reason, n := range document.SkippedLinks {
+			if merged.SkippedLinks == nil {
+				merged.SkippedLinks = make(map[string]int)
+			}
+			merged.SkippedLinks[reason] += n
+		}
+		merged.DataURIs = mergeDataURIs(merged.DataURIs, document.DataURIs)
+		merged.HasLoginForm = merged.HasLoginForm || document.HasLoginForm
+
+		headings := a.countHeadings(document.Headings)
+		frames[i].Followed = true
+		frames[i].Headings = &headings
+		frames[i].Links = len(document.Links)
+	}
+
+	return &merged, frames
+}
+
+// fetchFrame fetches and parses one frame document, returning the error as
+// text for the frame's entry
+func (a *Analyzer) fetchFrame(ctx context.Context, fetcher interfaces.FetcherStrategy, frameURL string) (*models.ParsedHTML, string) {
+	response, err := fetcher.Fetch(ctx, frameURL)
+	if err == nil {
+		var document *models.ParsedHTML
+		if document, err = a.htmlParser.ParseHTML(ctx, response.Body, frameURL); err == nil {
+			return document, ""
+		}
+	}
+
+	a.logger.Warn("Failed to follow frame", "frame_url", logger.RedactURL(frameURL), "error", err)
+	return nil, err.Error()
+}
+
+// hostOf returns the host of rawURL in lower-case ASCII form, so that
+// spellings of the same host compare equal
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return idn.ASCIIHost(u.Host)
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/cache"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// frameDocuments are the frames of testdata/frameset.html served under /site/
+var frameDocuments = map[string]string{
+	"/site/nav.html": `<html><body><h2>Menu</h2>
+<a href="#menu">Menu</a><a href="/content/main.html">Home</a><a href="https://other.example/">Partner</a></body></html>`,
+	"/content/main.html": `<html><body><h1>Welcome</h1><h2>News</h2>
+<a href="about.html">About</a><a href="mailto:news@example.com">Write to us</a><a href="#top">Top</a>
+<form action="/login"><input name="user"><input type="password"></form></body></html>`,
+}
+
+// newFramesetServer serves the frameset fixture at /site/index.html and its
+// frame documents; the banner frame is on another host and cannot be fetched
+func newFramesetServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	page, err := os.ReadFile("testdata/frameset.html")
+	require.NoError(t, err)
+
+	var frameFetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.URL.Path == "/site/index.html" {
+			w.Write(page)
+			return
+		}
+		document, ok := frameDocuments[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		frameFetches.Add(1)
+		io.WriteString(w, document)
+	}))
+	t.Cleanup(server.Close)
+	return server, &frameFetches
+}
+
+func TestAnalyzer_Frames_ListedWithoutFollowing(t *testing.T) {
+	server, frameFetches := newFramesetServer(t)
+	analyzer := newTestAnalyzer(t, nil, staticLinkChecker{})
+
+	result, err := analyzer.AnalyzeURL(context.Background(), server.URL+"/site/index.html")
+	require.NoError(t, err)
+
+	assert.True(t, result.HasFrames)
+	assert.Equal(t, []models.Frame{
+		{URL: server.URL + "/site/nav.html"},
+		{URL: server.URL + "/content/main.html"},
+		{URL: "https://ads.example.net/banner.html"},
+	}, result.Frames)
+	assert.Zero(t, frameFetches.Load())
+
+	// A frameset page has no content of its own; <noframes> is raw text
+	assert.Equal(t, models.HeadingCount{}, result.Headings)
+	assert.Zero(t, result.Links.Total)
+}
+
+func TestAnalyzer_Frames_FollowedAndMerged(t *testing.T) {
+	server, frameFetches := newFramesetServer(t)
+	analyzer := newTestAnalyzer(t, nil, staticLinkChecker{})
+
+	result, err := analyzer.AnalyzeURLWithOptions(context.Background(), server.URL+"/site/index.html", models.AnalysisOptions{FollowFrames: true})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), frameFetches.Load())
+
+	require.Len(t, result.Frames, 3)
+	assert.Equal(t, models.Frame{
+		URL: server.URL + "/site/nav.html", Followed: true, Headings: &models.HeadingCount{H2: 1}, Links: 2,
+	}, result.Frames[0])
+	assert.Equal(t, models.Frame{
+		URL: server.URL + "/content/main.html", Followed: true, Headings: &models.HeadingCount{H1: 1, H2: 1}, Links: 1,
+	}, result.Frames[1])
+	assert.False(t, result.Frames[2].Followed)
+	assert.NotEmpty(t, result.Frames[2].Error)
+
+	// Page and frame content together, frame links classified against the page
+	assert.Equal(t, models.HeadingCount{H1: 1, H2: 2}, result.Headings)
+	assert.Equal(t, models.LinkSummary{
+		Internal: 2, External: 1, Total: 3, Unique: 3,
+		Skipped:         map[string]int{models.LinkSkipFragmentOnly: 2, models.LinkSkipUnsupportedScheme: 1},
+		ExternalDomains: []models.DomainCount{{Domain: "other.example", Count: 1}},
+	}, result.Links)
+	assert.True(t, result.HasLoginForm, "login form inside a frame")
+}
+
+func TestAnalyzer_Frames_FrameLimit(t *testing.T) {
+	server, frameFetches := newFramesetServer(t)
+	analyzer := newTestAnalyzer(t, nil, staticLinkChecker{})
+	analyzer.SetMaxFrames(1)
+
+	result, err := analyzer.AnalyzeURLWithOptions(context.Background(), server.URL+"/site/index.html", models.AnalysisOptions{FollowFrames: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(1), frameFetches.Load())
+	require.Len(t, result.Frames, 3)
+	assert.True(t, result.Frames[0].Followed)
+	for _, frame := range result.Frames[1:] {
+		assert.False(t, frame.Followed)
+		assert.Equal(t, errFrameLimit, frame.Error)
+	}
+	assert.Equal(t, models.HeadingCount{H2: 1}, result.Headings)
+}
+
+func TestAnalyzer_Frames_MergedCountsAreNotCached(t *testing.T) {
+	server, _ := newFramesetServer(t)
+	analyzer := newTestAnalyzer(t, nil, staticLinkChecker{})
+	analyzer.SetResultCache(cache.NewMemory(10), time.Minute, false)
+	analyzer.SetMaxFrames(2)
+	pageURL := server.URL + "/site/index.html"
+
+	followed, err := analyzer.AnalyzeURLWithOptions(context.Background(), pageURL, models.AnalysisOptions{FollowFrames: true})
+	require.NoError(t, err)
+	plain, err := analyzer.AnalyzeURL(context.Background(), pageURL)
+	require.NoError(t, err)
+	again, err := analyzer.AnalyzeURLWithOptions(context.Background(), pageURL, models.AnalysisOptions{FollowFrames: true})
+	require.NoError(t, err)
+
+	assert.Zero(t, plain.Links.Total, "frame links must not leak into the page's own summary")
+	assert.Equal(t, followed.Links, again.Links, "a revalidated page must still follow its frames")
+	assert.Equal(t, followed.Headings, again.Headings)
+}
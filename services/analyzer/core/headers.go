@@ -0,0 +1,45 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+)
+
+// redactedHeaders maps the response headers whose values are not returned
+// to what is returned in their place
+var redactedHeaders = map[string]func(string) string{
+	"Set-Cookie":  cookieName,
+	"Set-Cookie2": cookieName,
+}
+
+// responseHeaders copies the headers of a page response for the result:
+// names canonical, every value kept in order, and the values of
+// redactedHeaders replaced
+func responseHeaders(headers http.Header) map[string][]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	copied := make(map[string][]string, len(headers))
+	for name, values := range headers {
+		canonical := http.CanonicalHeaderKey(name)
+		redact := redactedHeaders[canonical]
+		for _, value := range values {
+			if redact != nil {
+				value = redact(value)
+			}
+			copied[canonical] = append(copied[canonical], value)
+		}
+	}
+	return copied
+}
+
+// cookieName is the name of the cookie a Set-Cookie value sets, without its
+// value and attributes, or "" when the value sets none
+func cookieName(value string) string {
+	pair, _, _ := strings.Cut(value, ";")
+	name, _, ok := strings.Cut(pair, "=")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(name)
+}
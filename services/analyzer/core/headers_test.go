@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/cache"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseHeaders(t *testing.T) {
+	headers := http.Header{
+		"Content-Type": {"text/html; charset=utf-8"},
+		"Set-Cookie": {
+			"session=abc123; Path=/; HttpOnly; Secure",
+			" theme = dark",
+			"flag",
+			"empty=",
+		},
+		"set-cookie2": {"legacy=1; Version=1"},
+		"Vary":        {"Accept-Encoding", "Accept-Language"},
+		"x-served-by": {"cache-a", "cache-b"},
+		"X-Served-By": {"cache-c"},
+	}
+
+	got := responseHeaders(headers)
+	assert.Equal(t, []string{"text/html; charset=utf-8"}, got["Content-Type"])
+	assert.Equal(t, []string{"session", "theme", "", "empty"}, got["Set-Cookie"])
+	assert.Equal(t, []string{"legacy"}, got["Set-Cookie2"])
+	assert.Equal(t, []string{"Accept-Encoding", "Accept-Language"}, got["Vary"])
+	assert.ElementsMatch(t, []string{"cache-a", "cache-b", "cache-c"}, got["X-Served-By"])
+	assert.NotContains(t, got, "set-cookie2")
+	assert.NotContains(t, got, "x-served-by")
+	assert.Len(t, got, 5)
+
+	// The response keeps its values
+	assert.Equal(t, "session=abc123; Path=/; HttpOnly; Secure", headers["Set-Cookie"][0])
+
+	assert.Nil(t, responseHeaders(nil))
+}
+
+func TestAnalyzer_AnalyzeURLWithOptions_IncludeHeaders(t *testing.T) {
+	var full int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "redirect", Value: "secret"})
+		http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full++
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cr3t", HttpOnly: true})
+		http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark"})
+		w.Header().Add("Link", "</a.css>; rel=preload")
+		w.Header().Add("Link", "</b.js>; rel=preload")
+		io.WriteString(w, "<!DOCTYPE html><html><head><title>Page</title></head><body></body></html>")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+	analyzer.SetResultCache(cache.NewMemory(10), time.Minute, false)
+
+	// Headers are left out unless asked for
+	result, err := analyzer.AnalyzeURL(context.Background(), server.URL+"/old")
+	require.NoError(t, err)
+	assert.Zero(t, result.StatusCode)
+	assert.Nil(t, result.ResponseHeaders)
+
+	// The cached analysis is not revalidated: a 304 carries neither the
+	// status nor the headers of the page
+	result, err = analyzer.AnalyzeURLWithOptions(context.Background(), server.URL+"/old", models.AnalysisOptions{IncludeHeaders: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, full)
+	assert.Equal(t, server.URL+"/page", result.FinalURL)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	assert.Equal(t, []string{"session", "theme"}, result.ResponseHeaders["Set-Cookie"], "the final response's cookies, by name")
+	assert.Equal(t, []string{"</a.css>; rel=preload", "</b.js>; rel=preload"}, result.ResponseHeaders["Link"])
+	assert.Equal(t, []string{`"v1"`}, result.ResponseHeaders["Etag"])
+
+	// Nor are the headers cached for the analyses that did not ask for them
+	result, err = analyzer.AnalyzeURL(context.Background(), server.URL+"/old")
+	require.NoError(t, err)
+	assert.Nil(t, result.ResponseHeaders)
+}
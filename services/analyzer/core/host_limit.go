@@ -0,0 +1,63 @@
+package core
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/idn"
+	"github.com/RuvinSL/webpage-analyzer/pkg/keyedsem"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// SetHostLimit lets at most maxPerHost analyses run at once against the same
+// target host, service-wide; further analyses wait up to wait for a turn and
+// then fail with a models.TargetBusyError. Zero maxPerHost leaves hosts
+// unlimited.
+func (a *Analyzer) SetHostLimit(maxPerHost int, wait time.Duration) {
+	if maxPerHost <= 0 {
+		a.hosts = nil
+		return
+	}
+	a.hosts = keyedsem.New(maxPerHost)
+	a.hostWait = wait
+}
+
+// admit waits for a turn on the host of pageURL. The returned release ends
+// the turn.
+func (a *Analyzer) admit(ctx context.Context, pageURL string) (release func(), err error) {
+	if a.hosts == nil {
+		return func() {}, nil
+	}
+
+	host := targetHost(pageURL)
+	waitCtx, cancel := context.WithTimeout(ctx, a.hostWait)
+	defer cancel()
+
+	release, err = a.hosts.Acquire(waitCtx, host)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		a.logger.Warn("Target host busy, analysis turned away",
+			"url", logger.RedactURL(pageURL),
+			"host", host,
+			"wait", a.hostWait,
+		)
+		return nil, &models.TargetBusyError{Host: host, RetryAfter: a.hostWait}
+	}
+	return release, nil
+}
+
+// targetHost normalizes the host of a page URL so that spellings of the same
+// origin server share a limit: lower case and in ASCII form, without port or
+// trailing dot
+func targetHost(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Hostname() == "" {
+		return pageURL
+	}
+	return strings.TrimSuffix(idn.ASCIIHost(u.Hostname()), ".")
+}
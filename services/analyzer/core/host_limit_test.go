@@ -0,0 +1,142 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowOrigin serves /slow only once release is closed, reporting each
+// arrival on arrived, and every other page straight away. peak is the most
+// requests it ever had in flight.
+type slowOrigin struct {
+	*httptest.Server
+	arrived chan string
+	release chan struct{}
+	peak    atomic.Int64
+}
+
+func newSlowOrigin(t *testing.T) *slowOrigin {
+	t.Helper()
+	origin := &slowOrigin{arrived: make(chan string, 10), release: make(chan struct{})}
+	var inFlight atomic.Int64
+	origin.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			peak := origin.peak.Load()
+			if now <= peak || origin.peak.CompareAndSwap(peak, now) {
+				break
+			}
+		}
+
+		origin.arrived <- r.URL.Path
+		if r.URL.Path == "/slow" {
+			<-origin.release
+		}
+		io.WriteString(w, `<!DOCTYPE html><html><head><title>Page</title></head><body></body></html>`)
+	}))
+	t.Cleanup(origin.Close)
+	return origin
+}
+
+func TestAnalyzer_HostLimit_TurnsAwayWhenBusy(t *testing.T) {
+	origin := newSlowOrigin(t)
+	analyzer := newTestAnalyzer(t, nil, newStatusLinkChecker())
+	analyzer.SetHostLimit(1, 50*time.Millisecond)
+
+	slowDone := make(chan error, 1)
+	go func() {
+		_, err := analyzer.AnalyzeURL(context.Background(), origin.URL+"/slow")
+		slowDone <- err
+	}()
+	require.Equal(t, "/slow", <-origin.arrived)
+
+	// A different page on the same host has to wait for the slow one
+	_, err := analyzer.AnalyzeURL(context.Background(), origin.URL+"/other")
+	var busy *models.TargetBusyError
+	require.True(t, errors.As(err, &busy), "got %v", err)
+	assert.Equal(t, "127.0.0.1", busy.Host)
+	assert.Equal(t, 50*time.Millisecond, busy.RetryAfter)
+
+	close(origin.release)
+	require.NoError(t, <-slowDone)
+
+	// The host is free again
+	_, err = analyzer.AnalyzeURL(context.Background(), origin.URL+"/other")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), origin.peak.Load())
+}
+
+func TestAnalyzer_HostLimit_WaitsForTurn(t *testing.T) {
+	origin := newSlowOrigin(t)
+	analyzer := newTestAnalyzer(t, nil, newStatusLinkChecker())
+	analyzer.SetHostLimit(1, 5*time.Second)
+
+	done := make(chan error, 2)
+	go func() {
+		_, err := analyzer.AnalyzeURL(context.Background(), origin.URL+"/slow")
+		done <- err
+	}()
+	require.Equal(t, "/slow", <-origin.arrived)
+	go func() {
+		_, err := analyzer.AnalyzeURL(context.Background(), origin.URL+"/other")
+		done <- err
+	}()
+
+	select {
+	case path := <-origin.arrived:
+		t.Fatalf("%s fetched while the host was busy", path)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(origin.release)
+	require.NoError(t, <-done)
+	require.NoError(t, <-done)
+	assert.Equal(t, "/other", <-origin.arrived)
+	assert.Equal(t, int64(1), origin.peak.Load())
+}
+
+func TestAnalyzer_HostLimit_OtherHostsUnaffected(t *testing.T) {
+	origin := newSlowOrigin(t)
+	analyzer := newTestAnalyzer(t, nil, newStatusLinkChecker())
+	analyzer.SetHostLimit(1, 50*time.Millisecond)
+
+	slowDone := make(chan error, 1)
+	go func() {
+		_, err := analyzer.AnalyzeURL(context.Background(), origin.URL+"/slow")
+		slowDone <- err
+	}()
+	require.Equal(t, "/slow", <-origin.arrived)
+
+	// Same server, but a different host name
+	other := strings.Replace(origin.URL, "127.0.0.1", "localhost", 1) + "/other"
+	_, err := analyzer.AnalyzeURL(context.Background(), other)
+	require.NoError(t, err)
+
+	close(origin.release)
+	require.NoError(t, <-slowDone)
+}
+
+func TestTargetHost(t *testing.T) {
+	tests := map[string]string{
+		"https://Example.COM/page":        "example.com",
+		"https://example.com:8443/a?b=c":  "example.com",
+		"http://example.com./":            "example.com",
+		"http://[2001:db8::1]:8080/index": "2001:db8::1",
+		"not a url":                       "not a url",
+	}
+	for raw, want := range tests {
+		assert.Equal(t, want, targetHost(raw), raw)
+	}
+}
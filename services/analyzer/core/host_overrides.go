@@ -0,0 +1,84 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/idn"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// ErrHostOverrideDenied is wrapped by the error of an analysis whose host
+// overrides the analyzer does not accept
+var ErrHostOverrideDenied = errors.New("host override not allowed")
+
+// SetHostOverrides lets analyses override the addresses of the hosts
+// matching patterns, each a host name or *.domain for the hosts under
+// domain, in Unicode or ASCII form. Overrides to private, loopback and
+// link-local addresses are accepted only with allowPrivate. No patterns, the
+// default, accepts none.
+func (a *Analyzer) SetHostOverrides(patterns []string, allowPrivate bool) {
+	a.overrideHosts = nil
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+			pattern = "*." + idn.ASCIIHost(domain)
+		} else {
+			pattern = idn.ASCIIHost(pattern)
+		}
+		if pattern != "" {
+			a.overrideHosts = append(a.overrideHosts, pattern)
+		}
+	}
+	a.overridePrivate = allowPrivate
+}
+
+// checkHostOverrides checks overrides against the analyzer's policy. A
+// browser resolves names itself, so overrides cannot apply to rendered
+// pages.
+func (a *Analyzer) checkHostOverrides(overrides map[string]string, render bool) error {
+	if len(overrides) == 0 {
+		return nil
+	}
+	if err := models.ValidateHostOverrides(overrides); err != nil {
+		return err
+	}
+	if render {
+		return fmt.Errorf("%w: host_overrides do not apply to rendered pages", ErrHostOverrideDenied)
+	}
+	for _, host := range slices.Sorted(maps.Keys(overrides)) {
+		if !a.overrideAllowed(host) {
+			return fmt.Errorf("%w: %s is not a host this analyzer overrides", ErrHostOverrideDenied, host)
+		}
+		if ip := net.ParseIP(overrides[host]); isPrivateAddress(ip) && !a.overridePrivate {
+			return fmt.Errorf("%w: %s is a private address", ErrHostOverrideDenied, ip)
+		}
+	}
+	return nil
+}
+
+// overrideAllowed reports whether host matches one of the patterns of
+// SetHostOverrides
+func (a *Analyzer) overrideAllowed(host string) bool {
+	host = idn.ASCIIHost(host)
+	for _, pattern := range a.overrideHosts {
+		if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// isPrivateAddress reports whether ip is an address of a private network,
+// the host itself or a link, rather than of a public host
+func isPrivateAddress(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzer_CheckHostOverrides(t *testing.T) {
+	tests := []struct {
+		name         string
+		patterns     []string
+		allowPrivate bool
+		overrides    map[string]string
+		render       bool
+		wantErr      string
+	}{
+		{name: "none requested", overrides: nil},
+		{name: "exact host", patterns: []string{"staging.example.com"}, overrides: map[string]string{"Staging.Example.com": "203.0.113.7"}},
+		{name: "host under a domain", patterns: []string{" *.Example.com "}, overrides: map[string]string{"staging.example.com": "203.0.113.7"}},
+		{name: "unicode pattern", patterns: []string{"bücher.example"}, overrides: map[string]string{"xn--bcher-kva.example": "203.0.113.7"}},
+		{name: "ASCII pattern", patterns: []string{"*.XN--BCHER-KVA.example"}, overrides: map[string]string{"shop.Bücher.example": "203.0.113.7"}},
+		{name: "private with ALLOW_PRIVATE_TARGETS", patterns: []string{"staging.example.com"}, allowPrivate: true, overrides: map[string]string{"staging.example.com": "10.0.0.5"}},
+		{
+			name:      "nothing allowed",
+			overrides: map[string]string{"staging.example.com": "203.0.113.7"},
+			wantErr:   "host override not allowed: staging.example.com is not a host this analyzer overrides",
+		},
+		{
+			name:      "the domain itself",
+			patterns:  []string{"*.example.com"},
+			overrides: map[string]string{"example.com": "203.0.113.7"},
+			wantErr:   "host override not allowed: example.com is not a host this analyzer overrides",
+		},
+		{
+			name:      "another host",
+			patterns:  []string{"staging.example.com"},
+			overrides: map[string]string{"staging.example.com": "203.0.113.7", "www.example.org": "203.0.113.8"},
+			wantErr:   "host override not allowed: www.example.org is not a host this analyzer overrides",
+		},
+		{
+			name:      "private address",
+			patterns:  []string{"staging.example.com"},
+			overrides: map[string]string{"staging.example.com": "192.168.1.10"},
+			wantErr:   "host override not allowed: 192.168.1.10 is a private address",
+		},
+		{
+			name:      "loopback address",
+			patterns:  []string{"staging.example.com"},
+			overrides: map[string]string{"staging.example.com": "::1"},
+			wantErr:   "host override not allowed: ::1 is a private address",
+		},
+		{
+			name:      "rendered page",
+			patterns:  []string{"staging.example.com"},
+			overrides: map[string]string{"staging.example.com": "203.0.113.7"},
+			render:    true,
+			wantErr:   "host override not allowed: host_overrides do not apply to rendered pages",
+		},
+		{
+			name:      "not an address",
+			patterns:  []string{"staging.example.com"},
+			overrides: map[string]string{"staging.example.com": "staging.internal"},
+			wantErr:   `host_overrides["staging.example.com"]: "staging.internal" is not an IP address`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := &Analyzer{}
+			analyzer.SetHostOverrides(tt.patterns, tt.allowPrivate)
+			err := analyzer.checkHostOverrides(tt.overrides, tt.render)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeURL_HostOverrides(t *testing.T) {
+	var mu sync.Mutex
+	var hosts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts = append(hosts, r.Host)
+		mu.Unlock()
+		if r.URL.Path != "/" {
+			io.WriteString(w, "ok")
+			return
+		}
+		io.WriteString(w, `<!DOCTYPE html><html><head><title>Staging</title></head><body><h1>Staging</h1><a href="/about">About</a></body></html>`)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	// staging.example.test resolves nowhere; the override points it at the
+	// test server
+	log := newTestLogger()
+	client := httpclient.New(5*time.Second, log)
+	analyzer := newTestAnalyzer(t, client, budgetLinkChecker{client: client})
+	pageURL := "http://staging.example.test:" + u.Port() + "/"
+	opts := models.AnalysisOptions{HostOverrides: map[string]string{"staging.example.test": "127.0.0.1"}}
+
+	_, err = analyzer.AnalyzeURLWithOptions(context.Background(), pageURL, opts)
+	assert.ErrorIs(t, err, ErrHostOverrideDenied)
+	assert.Empty(t, hosts, "a refused override fetches nothing")
+
+	analyzer.SetHostOverrides([]string{"staging.example.test"}, true)
+	result, err := analyzer.AnalyzeURLWithOptions(context.Background(), pageURL, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "Staging", result.Title)
+	assert.Equal(t, 1, result.Links.Internal)
+	assert.Zero(t, result.Links.Inaccessible, "the links are checked through the override too")
+	assert.Equal(t, []string{"staging.example.test:" + u.Port(), "staging.example.test:" + u.Port()}, hosts)
+}
@@ -0,0 +1,232 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/crosspage"
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"golang.org/x/sync/errgroup"
+)
+
+// xDefault is the hreflang value for the fallback alternate
+const xDefault = "x-default"
+
+// maxReciprocalChecks caps the alternates fetched to check for a link back
+const maxReciprocalChecks = 20
+
+// checkHreflang validates the hreflang alternates of a page and checks that
+// they can be reached. With reciprocal set, each alternate is also fetched to
+// check that it lists the page in turn.
+func (a *Analyzer) checkHreflang(ctx context.Context, pageURL, finalURL string, fetcher interfaces.FetcherStrategy, alternates []models.HreflangLink, reciprocal bool) *models.HreflangReport {
+	report := &models.HreflangReport{
+		Alternates: slices.Clone(alternates),
+		Findings:   hreflangCodeFindings(alternates),
+	}
+
+	statuses, err := a.linkChecker.CheckLinks(ctx, hreflangLinks(pageURL, alternates))
+	if err != nil {
+		a.logger.Warn("Failed to check some hreflang alternates", "error", err)
+	}
+	report.Findings = append(report.Findings, unreachableFindings(alternates, statuses)...)
+
+	if reciprocal {
+		reachable := slices.DeleteFunc(slices.Clone(alternates), func(l models.HreflangLink) bool {
+			return slices.ContainsFunc(report.Findings, func(f models.HreflangFinding) bool {
+				return f.Kind == models.HreflangUnreachable && f.URL == l.URL
+			})
+		})
+		report.Findings = append(report.Findings, a.reciprocalFindings(ctx, pageAddresses(pageURL, finalURL), fetcher, reachable)...)
+	}
+	return report
+}
+
+// pageAddresses returns the normalized addresses an alternate may use to
+// point back at the page
+func pageAddresses(pageURL, finalURL string) []string {
+	addresses := []string{crosspage.NormalizeURL(pageURL)}
+	if finalURL != "" {
+		addresses = append(addresses, crosspage.NormalizeURL(finalURL))
+	}
+	return addresses
+}
+
+// ValidHreflang reports whether code is x-default or a BCP 47 language tag
+// of the shape hreflang uses: a 2-3 letter language, then optionally a
+// 4 letter script, a 2 letter or 3 digit region, and variants, in that order.
+// Case is not significant.
+func ValidHreflang(code string) bool {
+	if strings.EqualFold(code, xDefault) {
+		return true
+	}
+
+	subtags := strings.Split(code, "-")
+	if !isAlpha(subtags[0]) || len(subtags[0]) < 2 || len(subtags[0]) > 3 {
+		return false
+	}
+	rest := subtags[1:]
+	if len(rest) > 0 && len(rest[0]) == 4 && isAlpha(rest[0]) {
+		rest = rest[1:] // script
+	}
+	if len(rest) > 0 && ((len(rest[0]) == 2 && isAlpha(rest[0])) || (len(rest[0]) == 3 && isDigits(rest[0]))) {
+		rest = rest[1:] // region
+	}
+	for _, variant := range rest {
+		if !isVariant(variant) {
+			return false
+		}
+	}
+	return true
+}
+
+// isVariant matches 5-8 alphanumerics, or a digit followed by 3 alphanumerics
+func isVariant(subtag string) bool {
+	switch {
+	case len(subtag) >= 5 && len(subtag) <= 8:
+		return isAlnum(subtag)
+	case len(subtag) == 4:
+		return isDigits(subtag[:1]) && isAlnum(subtag)
+	}
+	return false
+}
+
+func isAlpha(s string) bool {
+	return s != "" && !strings.ContainsFunc(s, func(r rune) bool { return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') })
+}
+
+func isDigits(s string) bool {
+	return s != "" && !strings.ContainsFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+}
+
+func isAlnum(s string) bool {
+	return s != "" && !strings.ContainsFunc(s, func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9')
+	})
+}
+
+// hreflangCodeFindings reports invalid codes and a missing x-default
+func hreflangCodeFindings(alternates []models.HreflangLink) []models.HreflangFinding {
+	var findings []models.HreflangFinding
+	hasDefault := false
+	for _, alternate := range alternates {
+		if strings.EqualFold(alternate.Lang, xDefault) {
+			hasDefault = true
+		}
+		if !ValidHreflang(alternate.Lang) {
+			findings = append(findings, models.HreflangFinding{
+				Kind: models.HreflangInvalidCode,
+				Lang: alternate.Lang,
+				URL:  alternate.URL,
+			})
+		}
+	}
+	if !hasDefault {
+		findings = append(findings, models.HreflangFinding{Kind: models.HreflangMissingXDefault})
+	}
+	return findings
+}
+
+// hreflangLinks turns alternates into links for the link checker, one per
+// distinct URL
+func hreflangLinks(pageURL string, alternates []models.HreflangLink) []models.Link {
+	pageHost := hostOf(pageURL)
+	var links []models.Link
+	for _, alternate := range alternates {
+		if slices.ContainsFunc(links, func(l models.Link) bool { return l.URL == alternate.URL }) {
+			continue
+		}
+		link := models.Link{URL: alternate.URL, Text: alternate.Lang, Type: models.LinkTypeExternal}
+		if hostOf(alternate.URL) == pageHost {
+			link.Type = models.LinkTypeInternal
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// unreachableFindings reports the alternates whose check failed. Alternates
+// skipped for lack of budget were not checked and are not reported.
+func unreachableFindings(alternates []models.HreflangLink, statuses []models.LinkStatus) []models.HreflangFinding {
+	var findings []models.HreflangFinding
+	for _, alternate := range alternates {
+		i := slices.IndexFunc(statuses, func(s models.LinkStatus) bool { return s.Link.URL == alternate.URL })
+		if i < 0 || statuses[i].Accessible || statuses[i].Skipped {
+			continue
+		}
+		findings = append(findings, models.HreflangFinding{
+			Kind:   models.HreflangUnreachable,
+			Lang:   alternate.Lang,
+			URL:    alternate.URL,
+			Detail: statuses[i].Error,
+		})
+	}
+	return findings
+}
+
+// reciprocalFindings fetches each alternate other than the page itself and
+// reports those that do not list the page among their own alternates. Up to
+// maxReciprocalChecks alternates are checked; one that cannot be fetched is
+// reported as unreachable.
+func (a *Analyzer) reciprocalFindings(ctx context.Context, page []string, fetcher interfaces.FetcherStrategy, alternates []models.HreflangLink) []models.HreflangFinding {
+	var targets []models.HreflangLink
+	for _, alternate := range alternates {
+		if len(targets) == maxReciprocalChecks {
+			break
+		}
+		normalized := crosspage.NormalizeURL(alternate.URL)
+		if slices.Contains(page, normalized) || slices.ContainsFunc(targets, func(t models.HreflangLink) bool { return crosspage.NormalizeURL(t.URL) == normalized }) {
+			continue
+		}
+		targets = append(targets, alternate)
+	}
+
+	results := make([]*models.HreflangFinding, len(targets))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(frameFetchConcurrency)
+	for i, target := range targets {
+		g.Go(func() error {
+			results[i] = a.checkReciprocal(gctx, page, fetcher, target)
+			return nil
+		})
+	}
+	g.Wait()
+
+	var findings []models.HreflangFinding
+	for _, finding := range results {
+		if finding != nil {
+			findings = append(findings, *finding)
+		}
+	}
+	return findings
+}
+
+// checkReciprocal returns a finding unless target links back to one of the
+// page's addresses
+func (a *Analyzer) checkReciprocal(ctx context.Context, page []string, fetcher interfaces.FetcherStrategy, target models.HreflangLink) *models.HreflangFinding {
+	response, err := fetcher.Fetch(ctx, target.URL)
+	var parsed *models.ParsedHTML
+	if err == nil {
+		parsed, err = a.htmlParser.ParseHTML(ctx, response.Body, target.URL)
+	}
+	if err != nil {
+		a.logger.Warn("Failed to fetch hreflang alternate", "url", logger.RedactURL(target.URL), "error", err)
+		return &models.HreflangFinding{Kind: models.HreflangUnreachable, Lang: target.Lang, URL: target.URL, Detail: err.Error()}
+	}
+
+	linksBack := slices.ContainsFunc(parsed.Hreflangs, func(l models.HreflangLink) bool {
+		return slices.Contains(page, crosspage.NormalizeURL(l.URL))
+	})
+	if linksBack {
+		return nil
+	}
+	return &models.HreflangFinding{
+		Kind:   models.HreflangNotReciprocal,
+		Lang:   target.Lang,
+		URL:    target.URL,
+		Detail: fmt.Sprintf("no hreflang alternate of %s points back to the page", logger.RedactURL(target.URL)),
+	}
+}
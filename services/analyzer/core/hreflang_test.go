@@ -0,0 +1,156 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidHreflang(t *testing.T) {
+	tests := map[string]bool{
+		"en":          true,
+		"EN-gb":       true,
+		"de-DE":       true,
+		"zh-Hant-TW":  true,
+		"es-419":      true,
+		"sr-Latn":     true,
+		"yue":         true,
+		"sl-rozaj":    true,
+		"de-CH-1996":  true,
+		"x-default":   true,
+		"X-Default":   true,
+		"":            false,
+		"english":     false,
+		"e":           false,
+		"en_GB":       false,
+		"en-":         false,
+		"en-GBR":      false,
+		"en-12":       false,
+		"en-GB-x":     false,
+		"123":         false,
+		"en-GB extra": false,
+		"fr-CA-ABCD":  false,
+	}
+	for code, want := range tests {
+		assert.Equal(t, want, ValidHreflang(code), code)
+	}
+}
+
+// statusLinkChecker fetches each link, reporting 4xx and 5xx answers as
+// inaccessible and the redirects followed, like the link checker service
+type statusLinkChecker struct {
+	client interfaces.HTTPClient
+}
+
+func (c statusLinkChecker) CheckLinks(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+	statuses := make([]models.LinkStatus, len(links))
+	for i, link := range links {
+		statuses[i] = c.CheckLink(ctx, link)
+	}
+	return statuses, nil
+}
+
+func (c statusLinkChecker) CheckLink(ctx context.Context, link models.Link) models.LinkStatus {
+	resp, err := c.client.Get(ctx, link.URL)
+	if err != nil {
+		return models.LinkStatus{Link: link, Error: err.Error()}
+	}
+	status := models.LinkStatus{Link: link, StatusCode: resp.StatusCode, Accessible: resp.StatusCode < 400}
+	if !status.Accessible {
+		status.Error = fmt.Sprintf("HTTP %d", resp.StatusCode)
+	}
+	if resp.Redirects > 0 {
+		status.Redirects = resp.Redirects
+		status.FinalURL = resp.FinalURL
+	}
+	return status
+}
+
+// newHreflangServer serves a page with alternates in English, German and
+// French, an invalid code and no x-default. The English page links back with
+// a relative URL, the German one does not, and the French one is missing.
+func newHreflangServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	documents := map[string]string{
+		"/": `<!DOCTYPE html><html><head><title>Home</title>
+<link rel="alternate" hreflang="en" href="/en">
+<link rel="alternate" hreflang="de-DE" href="de">
+<link rel="alternate" hreflang="fr" href="/missing">
+<link rel="alternate" hreflang="english" href="/en">
+</head><body><a href="/en">English</a></body></html>`,
+		"/en": `<html><head><link rel="alternate" hreflang="x-default" href="/"></head></html>`,
+		"/de": `<html><head><link rel="alternate" hreflang="en" href="/en"></head></html>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		document, ok := documents[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, document)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newStatusLinkChecker returns a statusLinkChecker over a client of its own
+func newStatusLinkChecker() statusLinkChecker {
+	return statusLinkChecker{client: httpclient.New(5*time.Second, newTestLogger())}
+}
+
+func TestAnalyzer_Hreflang_Findings(t *testing.T) {
+	server := newHreflangServer(t)
+
+	result, err := newTestAnalyzer(t, nil, newStatusLinkChecker()).AnalyzeURL(context.Background(), server.URL+"/")
+	require.NoError(t, err)
+
+	require.NotNil(t, result.Hreflang)
+	assert.Equal(t, []models.HreflangLink{
+		{Lang: "en", URL: server.URL + "/en"},
+		{Lang: "de-DE", URL: server.URL + "/de"},
+		{Lang: "fr", URL: server.URL + "/missing"},
+		{Lang: "english", URL: server.URL + "/en"},
+	}, result.Hreflang.Alternates)
+	assert.Equal(t, []models.HreflangFinding{
+		{Kind: models.HreflangInvalidCode, Lang: "english", URL: server.URL + "/en"},
+		{Kind: models.HreflangMissingXDefault},
+		{Kind: models.HreflangUnreachable, Lang: "fr", URL: server.URL + "/missing", Detail: "HTTP 404"},
+	}, result.Hreflang.Findings)
+
+	// Alternates are checked apart from the page's own links
+	assert.Equal(t, models.LinkSummary{Internal: 1, Total: 1, Unique: 1}, result.Links)
+}
+
+func TestAnalyzer_Hreflang_Reciprocal(t *testing.T) {
+	server := newHreflangServer(t)
+
+	result, err := newTestAnalyzer(t, nil, newStatusLinkChecker()).AnalyzeURLWithOptions(context.Background(), server.URL, models.AnalysisOptions{CheckHreflangReciprocal: true})
+	require.NoError(t, err)
+
+	require.NotNil(t, result.Hreflang)
+	findings := result.Hreflang.Findings
+	require.Len(t, findings, 4)
+	assert.Equal(t, models.HreflangNotReciprocal, findings[3].Kind)
+	assert.Equal(t, server.URL+"/de", findings[3].URL)
+}
+
+func TestAnalyzer_Hreflang_NoneDeclared(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<!DOCTYPE html><html><head><title>Plain</title></head><body></body></html>`)
+	}))
+	defer server.Close()
+
+	result, err := newTestAnalyzer(t, nil, newStatusLinkChecker()).AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Nil(t, result.Hreflang)
+}
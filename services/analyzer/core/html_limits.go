@@ -0,0 +1,245 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"slices"
+
+	"golang.org/x/net/html"
+)
+
+// Parser limits applied unless SetLimits overrides them
+const (
+	DefaultMaxDepth      = 512
+	DefaultMaxLinks      = 10000
+	DefaultMaxTextLength = 512
+	DefaultMaxAnchors    = 10000
+	DefaultMaxHeadings   = 1000
+	// DefaultLargeDataURIBytes is 32KiB
+	DefaultLargeDataURIBytes = 32 << 10
+)
+
+// ParserLimits bound what one document can cost the parser. Zero fields take
+// the defaults.
+type ParserLimits struct {
+	// MaxDepth is the deepest element nesting examined
+	MaxDepth int
+	// MaxLinks caps the links extracted from a document
+	MaxLinks int
+	// MaxTextLength caps, in characters, the title, heading and link texts
+	MaxTextLength int
+	// MaxAnchors caps the distinct ids, the distinct <a name>s and the
+	// distinct fragments of same-page links kept per document
+	MaxAnchors int
+	// MaxHeadings caps the headings kept per level, h1 to h6; those beyond
+	// it are not counted
+	MaxHeadings int
+	// LargeDataURIBytes is the size from which a data: URI link counts as
+	// large, weighing on the page
+	LargeDataURIBytes int
+}
+
+func (l ParserLimits) withDefaults() ParserLimits {
+	if l.MaxDepth < 1 {
+		l.MaxDepth = DefaultMaxDepth
+	}
+	if l.MaxLinks < 1 {
+		l.MaxLinks = DefaultMaxLinks
+	}
+	if l.MaxTextLength < 1 {
+		l.MaxTextLength = DefaultMaxTextLength
+	}
+	if l.MaxAnchors < 1 {
+		l.MaxAnchors = DefaultMaxAnchors
+	}
+	if l.MaxHeadings < 1 {
+		l.MaxHeadings = DefaultMaxHeadings
+	}
+	if l.LargeDataURIBytes < 1 {
+		l.LargeDataURIBytes = DefaultLargeDataURIBytes
+	}
+	return l
+}
+
+// voidElements never have content, so they do not add to the nesting
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "keygen": true, "link": true, "meta": true, "param": true, "source": true,
+	"track": true, "wbr": true,
+}
+
+// rawTextElements hold unparsed text, which must not be read as markup once
+// their tags are dropped
+var rawTextElements = map[string]bool{
+	"iframe": true, "noembed": true, "noframes": true, "noscript": true, "plaintext": true,
+	"script": true, "style": true, "textarea": true, "title": true, "xmp": true,
+}
+
+// impliedEnd lists, for the elements whose start closes open siblings, the
+// elements they close, e.g. an unclosed <li> by the next <li>
+var impliedEnd = map[string][]string{
+	"p":        {"p"},
+	"li":       {"li"},
+	"dt":       {"dt", "dd"},
+	"dd":       {"dt", "dd"},
+	"option":   {"option"},
+	"optgroup": {"optgroup", "option"},
+	"tr":       {"tr", "td", "th"},
+	"td":       {"td", "th"},
+	"th":       {"td", "th"},
+	"thead":    {"thead", "tbody", "tfoot", "tr", "td", "th"},
+	"tbody":    {"thead", "tbody", "tfoot", "tr", "td", "th"},
+	"tfoot":    {"thead", "tbody", "tfoot", "tr", "td", "th"},
+	"rb":       {"rb", "rt", "rp"},
+	"rt":       {"rb", "rt", "rp"},
+	"rp":       {"rb", "rt", "rp"},
+}
+
+type openElement struct {
+	name    string
+	dropped bool
+}
+
+// nesting estimates the parser's stack of open elements from the token
+// stream, without building a tree
+type nesting struct {
+	open []openElement
+}
+
+// start records a start tag and reports whether it opens an element
+func (n *nesting) start(name string) bool {
+	if voidElements[name] {
+		return false
+	}
+	closes := impliedEnd[name]
+	for len(n.open) > 0 && slices.Contains(closes, n.open[len(n.open)-1].name) {
+		n.open = n.open[:len(n.open)-1]
+	}
+	return true
+}
+
+// end closes the innermost open element called name, and everything opened
+// after it. It returns the closed element, or ok false for a stray end tag.
+func (n *nesting) end(name string) (closed openElement, ok bool) {
+	for i := len(n.open) - 1; i >= 0; i-- {
+		if n.open[i].name == name {
+			closed = n.open[i]
+			n.open = n.open[:i]
+			return closed, true
+		}
+	}
+	return openElement{}, false
+}
+
+// capNesting drops the tags of elements nested deeper than maxDepth,
+// keeping their text and void elements in place. html.Parse takes time
+// quadratic in the nesting depth, so a pathological document is flattened
+// before it is parsed. Documents within the limit are returned unchanged.
+// Once ctx is done it stops, returning what it has; the caller checks ctx.
+func capNesting(ctx context.Context, content []byte, maxDepth int) ([]byte, int) {
+	if maxNesting(ctx, content) <= maxDepth {
+		return content, 0
+	}
+
+	var out bytes.Buffer
+	out.Grow(len(content))
+	var stack nesting
+	dropped := 0
+	skipRawText := false
+
+	z := html.NewTokenizer(&contextReader{ctx: ctx, r: bytes.NewReader(content)})
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() == io.EOF {
+				break
+			}
+			// Keep whatever the tokenizer could not make sense of
+			out.Write(z.Raw())
+			break
+		}
+
+		raw := z.Raw()
+		switch tt {
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			if !stack.start(string(name)) {
+				break
+			}
+			element := openElement{name: string(name), dropped: len(stack.open) >= maxDepth}
+			stack.open = append(stack.open, element)
+			if element.dropped {
+				dropped++
+				skipRawText = rawTextElements[element.name]
+				continue
+			}
+		case html.TextToken:
+			if skipRawText {
+				continue
+			}
+		case html.EndTagToken:
+			skipRawText = false
+			name, _ := z.TagName()
+			if closed, ok := stack.end(string(name)); ok && closed.dropped {
+				continue
+			}
+		}
+		out.Write(raw)
+	}
+	return out.Bytes(), dropped
+}
+
+// maxNesting returns the deepest element nesting of content, or of the part
+// read before ctx was done
+func maxNesting(ctx context.Context, content []byte) int {
+	var stack nesting
+	deepest := 0
+
+	z := html.NewTokenizer(&contextReader{ctx: ctx, r: bytes.NewReader(content)})
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return deepest
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			if stack.start(string(name)) {
+				stack.open = append(stack.open, openElement{name: string(name)})
+				deepest = max(deepest, len(stack.open))
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			stack.end(string(name))
+		}
+	}
+}
+
+// walk visits root and its descendants in document order without
+// recursion. Children are skipped when visit returns false, and below
+// maxDepth levels under root; walk returns how many nodes had children cut
+// off by the depth limit.
+func walk(root *html.Node, maxDepth int, visit func(*html.Node) bool) int {
+	cut := 0
+	node, depth := root, 0
+	for {
+		descend := visit(node) && node.FirstChild != nil
+		if descend && depth >= maxDepth {
+			cut++
+			descend = false
+		}
+		if descend {
+			node = node.FirstChild
+			depth++
+			continue
+		}
+
+		for node != root && node.NextSibling == nil {
+			node = node.Parent
+			depth--
+		}
+		if node == root {
+			return cut
+		}
+		node = node.NextSibling
+	}
+}
@@ -0,0 +1,189 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+)
+
+// nested returns text wrapped in depth elements named tag
+func nested(tag string, depth int, text string) string {
+	return strings.Repeat("<"+tag+">", depth) + text + strings.Repeat("</"+tag+">", depth)
+}
+
+func TestCapNesting(t *testing.T) {
+	shallow := []byte(`<html><body><ul><li>one<li>two<li>three</ul><p>a<p>b</body></html>`)
+	out, dropped := capNesting(context.Background(), shallow, 5)
+	assert.Equal(t, 0, dropped)
+	assert.Equal(t, shallow, out, "documents within the limit are not rewritten")
+
+	deep := []byte(nested("div", 20, `<a href="/deep">deep</a><br>text`))
+	out, dropped = capNesting(context.Background(), deep, 5)
+	assert.Equal(t, 16, dropped, "15 divs and the link")
+	assert.Equal(t, nested("div", 5, `deep<br>text`), string(out))
+
+	// The text of a dropped raw text element is dropped with it rather than
+	// read as markup
+	script := []byte(nested("div", 3, `<script>if (a<b) { x = "<a href='/x'>" }</script>after`))
+	out, dropped = capNesting(context.Background(), script, 3)
+	assert.Equal(t, 1, dropped)
+	assert.Equal(t, nested("div", 3, "after"), string(out))
+}
+
+func TestMaxNesting(t *testing.T) {
+	tests := map[string]int{
+		``:                                       0,
+		`text`:                                   0,
+		`<div><span></span><span></span></div>`:  2,
+		`<ul><li>a<li>b<li>c</ul>`:               2,
+		`<table><tr><td>a<td>b<tr><td>c</table>`: 3,
+		`<dl><dt>a<dd>b<dt>c</dl>`:               2,
+		`<p>a<p>b<p>c`:                           1,
+		`<div><img><br><input></div>`:            1,
+		`</div></div><div>`:                      1,
+		nested("b", 40, "x"):                     40,
+	}
+	for doc, want := range tests {
+		assert.Equal(t, want, maxNesting(context.Background(), []byte(doc)), doc)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<div id="a"><p id="b"><span id="c"></span></p><p id="d"></p></div><div id="e"></div>`))
+	require.NoError(t, err)
+
+	ids := func(maxDepth int) ([]string, int) {
+		var seen []string
+		cut := walk(doc, maxDepth, func(n *html.Node) bool {
+			for _, attr := range n.Attr {
+				if attr.Key == "id" {
+					seen = append(seen, attr.Val)
+				}
+			}
+			return true
+		})
+		return seen, cut
+	}
+
+	// html > body > div > p > span
+	seen, cut := ids(10)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, seen, "document order")
+	assert.Equal(t, 0, cut)
+
+	seen, cut = ids(4)
+	assert.Equal(t, []string{"a", "b", "d", "e"}, seen)
+	assert.Equal(t, 1, cut)
+
+	// Returning false skips the children but not the siblings
+	var visited []string
+	walk(doc, 10, func(n *html.Node) bool {
+		if n.Type == html.ElementNode {
+			visited = append(visited, n.Data)
+		}
+		return n.Data != "div"
+	})
+	assert.Equal(t, []string{"html", "head", "body", "div", "div"}, visited)
+}
+
+func TestHTMLParserParseHTML_Limits(t *testing.T) {
+	parser := NewHTMLParser(nil)
+	parser.SetLimits(ParserLimits{MaxDepth: 8, MaxLinks: 2, MaxTextLength: 10})
+
+	var links strings.Builder
+	for i := range 5 {
+		fmt.Fprintf(&links, `<a href="/%d">link %d</a>`, i, i)
+	}
+	page := `<html><head><title>Limits</title></head><body>` +
+		`<h1>  Überschrift mit viel Text  </h1><h2>short</h2>` + links.String() +
+		nested("div", 20, `<a href="/deep">deep</a><h3>deep heading</h3>`) +
+		`</body></html>`
+
+	result, err := parser.ParseHTML(context.Background(), []byte(page), "https://example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Limits", result.Title)
+	assert.Equal(t, []string{"Überschri…"}, result.Headings["h1"])
+	assert.Equal(t, []string{"short"}, result.Headings["h2"])
+	assert.Empty(t, result.Headings["h3"], "tags beyond the depth are dropped, only their text is kept")
+	require.Len(t, result.Links, 2)
+	assert.Equal(t, "https://example.com/1", result.Links[1].URL)
+
+	require.NotNil(t, result.Truncation)
+	assert.Equal(t, 1, result.Truncation.TruncatedTexts)
+	assert.Equal(t, 3, result.Truncation.DroppedLinks, "links beyond the depth are flattened, not counted")
+	assert.Positive(t, result.Truncation.DeepElements)
+}
+
+func TestHTMLParserParseHTML_WithinLimits(t *testing.T) {
+	result, err := NewHTMLParser(nil).ParseHTML(context.Background(), []byte(`<h1>Title</h1><a href="/a">a</a>`), "https://example.com")
+	require.NoError(t, err)
+	assert.Nil(t, result.Truncation)
+}
+
+func TestHTMLParserParseHTML_DeepNestingIsFast(t *testing.T) {
+	// html.Parse alone needs seconds for this document
+	page := []byte(nested("div", 20000, `<a href="/bottom">bottom</a>`) + `<a href="/top">top</a>`)
+
+	start := time.Now()
+	result, err := NewHTMLParser(nil).ParseHTML(context.Background(), page, "https://example.com")
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	assert.Equal(t, []models.Link{{URL: "https://example.com/top", Text: "top", Type: models.LinkTypeInternal}}, result.Links)
+	require.NotNil(t, result.Truncation)
+	// The flattened divs and link, plus the subtree cut where the elements
+	// the parser adds push the tree past the limit
+	assert.Equal(t, 20000-DefaultMaxDepth+1+1, result.Truncation.DeepElements)
+}
+
+func TestHTMLParserSetLimits_Defaults(t *testing.T) {
+	parser := NewHTMLParser(nil)
+	parser.SetLimits(ParserLimits{MaxLinks: 7})
+	assert.Equal(t, ParserLimits{MaxDepth: DefaultMaxDepth, MaxLinks: 7, MaxTextLength: DefaultMaxTextLength, MaxAnchors: DefaultMaxAnchors,
+		MaxHeadings: DefaultMaxHeadings, LargeDataURIBytes: DefaultLargeDataURIBytes}, parser.limits)
+}
+
+func TestHTMLParserParseHTML_PathologicalDocumentIsBounded(t *testing.T) {
+	const links, headings = 50000, 20000
+	longText := strings.Repeat("x", 4*DefaultMaxTextLength)
+	var page strings.Builder
+	page.WriteString(`<html><head><title>Huge</title></head><body>`)
+	for i := range headings {
+		fmt.Fprintf(&page, `<h2>%d %s</h2>`, i, longText)
+	}
+	for i := range links {
+		fmt.Fprintf(&page, `<a href="/%d">%s</a>`, i, longText)
+	}
+	page.WriteString(`</body></html>`)
+
+	parser := NewHTMLParser(nil)
+	parser.SetLimits(ParserLimits{MaxLinks: 1000, MaxHeadings: 100})
+	result, err := parser.ParseHTML(context.Background(), []byte(page.String()), "https://example.com")
+	require.NoError(t, err)
+
+	assert.Len(t, result.Links, 1000)
+	assert.Len(t, result.Headings["h2"], 100)
+	require.NotNil(t, result.Truncation)
+	assert.Equal(t, links-1000, result.Truncation.DroppedLinks)
+	assert.Equal(t, headings-100, result.Truncation.DroppedHeadings)
+	assert.Equal(t, 1000+100, result.Truncation.TruncatedTexts, "only the texts kept are cut")
+
+	// What is kept is bounded by the limits, not by the size of the page
+	kept := 0
+	for _, link := range result.Links {
+		kept += len(link.Text) + len(link.URL)
+	}
+	for _, text := range result.Headings["h2"] {
+		kept += len(text)
+	}
+	assert.Less(t, kept, (1000+100)*(DefaultMaxTextLength*utf8.UTFMax+64))
+	assert.Less(t, kept, page.Len()/50)
+}
@@ -7,251 +7,487 @@ import (
 	"fmt"
 	"io"
 	"net/url"
-	"regexp"
+	"slices"
 	"strings"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/bufpool"
+	"github.com/RuvinSL/webpage-analyzer/pkg/idn"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/safe"
 	"golang.org/x/net/html"
 )
 
+// noDoctype is the version reported for documents without a DOCTYPE
+const noDoctype = "Unknown/No DOCTYPE"
+
+var html5Doctype = safe.MustCompile(`<!doctype\s+html\s*>`)
+
+// versionScanWindow is how much of a document DetectHTMLVersion reads. A
+// DOCTYPE only counts before any content, after at most whitespace,
+// comments and an XML declaration, so it is found in the window unless
+// those run longer.
+const versionScanWindow = 4 << 10
+
+// cancelCheckInterval is how many nodes the traversal visits between checks
+// of its context
+const cancelCheckInterval = 1024
+
 type HTMLParser struct {
 	logger interfaces.Logger
+	limits ParserLimits
 }
 
 // NewHTMLParser creates a new HTML parser
 func NewHTMLParser(logger interfaces.Logger) *HTMLParser {
 	return &HTMLParser{
 		logger: logger,
+		limits: ParserLimits{}.withDefaults(),
 	}
 }
 
-func (p *HTMLParser) ParseHTML(ctx context.Context, content []byte, baseURL string) (*models.ParsedHTML, error) {
-	var reader io.Reader = bytes.NewReader(content)
-
-	// Detect gzip by magic bytes
-	if len(content) >= 2 && content[0] == 0x1f && content[1] == 0x8b {
-		gz, err := gzip.NewReader(reader)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
-		}
-		defer gz.Close()
-		reader = gz
-	}
+// SetLimits bounds the nesting depth examined, the links and headings
+// extracted and the length of the title, heading and link texts kept per
+// document. Zero fields keep the defaults.
+func (p *HTMLParser) SetLimits(limits ParserLimits) {
+	p.limits = limits.withDefaults()
+}
 
-	doc, err := html.Parse(reader)
+// ParseHTML builds the DOM once and reads everything the analysis needs from
+// it: DOCTYPE and HTML version, title, headings, links, login forms, the
+// element ids in-page links point at and the statistics of the visible text. It gives up with ctx's error once ctx is
+// done, partway through the tokenizing or the traversal of a large document.
+func (p *HTMLParser) ParseHTML(ctx context.Context, content []byte, baseURL string) (*models.ParsedHTML, error) {
+	doc, flattened, err := parseDocument(ctx, content, p.limits.MaxDepth)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		return nil, err
 	}
 
 	base, err := url.Parse(baseURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid base URL: %w", err)
 	}
+	asciiHost(base)
 
 	result := &models.ParsedHTML{
+		Doctype:  findDoctype(doc),
 		Headings: make(map[string][]string),
 		Links:    []models.Link{},
 	}
+	result.HTMLVersion = p.HTMLVersion(result.Doctype)
 
-	p.traverse(doc, base, result)
+	if p.logger != nil {
+		p.logger.Debug("Found DOCTYPE", "doctype", result.Doctype, "html_version", result.HTMLVersion)
+	}
+
+	truncation := models.ParseTruncation{DeepElements: flattened}
+	if err := p.traverse(ctx, doc, base, result, &truncation); err != nil {
+		return nil, err
+	}
+	if truncation != (models.ParseTruncation{}) {
+		result.Truncation = &truncation
+	}
 
 	return result, nil
 }
 
+// DetectHTMLVersion returns the HTML version of content. It is kept for
+// callers that need only the version; ParseHTML reports it as well. Only the
+// first versionScanWindow bytes are parsed, so the cost does not grow with
+// the page.
 func (p *HTMLParser) DetectHTMLVersion(content []byte) string {
+	doc, err := html.Parse(bytes.NewReader(decodePrefix(content, versionScanWindow)))
+	if err != nil {
+		return noDoctype
+	}
+	return p.HTMLVersion(findDoctype(doc))
+}
 
-	// Check if content is gzip compressed
-	if len(content) > 2 && content[0] == 0x1f && content[1] == 0x8b {
-		reader, err := gzip.NewReader(bytes.NewReader(content))
-		if err == nil {
-			defer reader.Close()
-			decompressed, err := io.ReadAll(reader)
-			if err == nil {
-				content = decompressed
-			}
-		}
+// HTMLVersion maps a DOCTYPE declaration, as reported by ParseHTML, to an
+// HTML version name
+func (p *HTMLParser) HTMLVersion(doctype string) string {
+	if doctype == "" {
+		return noDoctype
 	}
 
-	htmlStr := string(content)
+	doctypeLower := strings.ToLower(doctype)
 
-	htmlStr = strings.TrimPrefix(htmlStr, "\xef\xbb\xbf")
+	// HTML5 - just <!DOCTYPE html>
+	if html5Doctype.MatchString(doctypeLower) {
+		return "HTML5"
+	}
 
-	htmlStr = strings.TrimSpace(htmlStr)
+	// XHTML 1.1
+	if strings.Contains(doctypeLower, "xhtml 1.1") {
+		return "XHTML 1.1"
+	}
 
-	lines := strings.Split(htmlStr, "\n")
-	if len(lines) > 0 {
-		if p.logger != nil {
-			p.logger.Debug("First line of HTML", "line", lines[0])
+	// XHTML 1.0 variants
+	if strings.Contains(doctypeLower, "xhtml 1.0") {
+		if strings.Contains(doctypeLower, "strict") {
+			return "XHTML 1.0 Strict"
+		} else if strings.Contains(doctypeLower, "transitional") {
+			return "XHTML 1.0 Transitional"
+		} else if strings.Contains(doctypeLower, "frameset") {
+			return "XHTML 1.0 Frameset"
 		}
+		return "XHTML 1.0"
 	}
 
-	htmlLower := strings.ToLower(htmlStr)
+	// HTML 4.01 variants
+	if strings.Contains(doctypeLower, "html 4.01") {
+		if strings.Contains(doctypeLower, "strict") {
+			return "HTML 4.01 Strict"
+		} else if strings.Contains(doctypeLower, "transitional") {
+			return "HTML 4.01 Transitional"
+		} else if strings.Contains(doctypeLower, "frameset") {
+			return "HTML 4.01 Frameset"
+		}
+		return "HTML 4.01"
+	}
 
-	if strings.HasPrefix(htmlLower, "<!doctype") || strings.HasPrefix(htmlLower, "<!DOCTYPE") {
+	// HTML 3.2
+	if strings.Contains(doctypeLower, "html 3.2") {
+		return "HTML 3.2"
+	}
 
-		doctypeEnd := strings.Index(htmlStr, ">")
-		if doctypeEnd > 0 {
-			doctype := htmlStr[:doctypeEnd+1]
+	// HTML 2.0
+	if strings.Contains(doctypeLower, "html 2.0") {
+		return "HTML 2.0"
+	}
 
-			if p.logger != nil {
-				p.logger.Debug("Found DOCTYPE", "doctype", doctype)
-			}
-			doctypeLower := strings.ToLower(doctype)
+	// Found DOCTYPE but couldn't identify version
+	return "Unknown DOCTYPE"
+}
 
-			// HTML5 - just <!DOCTYPE html>
-			if regexp.MustCompile(`<!doctype\s+html\s*>`).MatchString(doctypeLower) {
-				return "HTML5"
-			}
+// ExtractTitle returns the title of content. It is kept for callers that
+// need only the title; ParseHTML reports it as well.
+func (p *HTMLParser) ExtractTitle(content []byte) string {
+	doc, _, err := parseDocument(context.Background(), content, p.limits.MaxDepth)
+	if err != nil {
+		return ""
+	}
 
-			// XHTML 1.1
-			if strings.Contains(doctypeLower, "xhtml 1.1") {
-				return "XHTML 1.1"
-			}
+	var title string
+	found := false
+	walk(doc, p.limits.MaxDepth, func(n *html.Node) bool {
+		if !found && n.Type == html.ElementNode && n.Data == "title" && n.Namespace == "" {
+			title, _ = p.extractText(n)
+			found = true
+		}
+		return !found
+	})
 
-			// XHTML 1.0 variants
-			if strings.Contains(doctypeLower, "xhtml 1.0") {
-				if strings.Contains(doctypeLower, "strict") {
-					return "XHTML 1.0 Strict"
-				} else if strings.Contains(doctypeLower, "transitional") {
-					return "XHTML 1.0 Transitional"
-				} else if strings.Contains(doctypeLower, "frameset") {
-					return "XHTML 1.0 Frameset"
-				}
-				return "XHTML 1.0"
-			}
+	return title
+}
 
-			// HTML 4.01 variants
-			if strings.Contains(doctypeLower, "html 4.01") {
-				if strings.Contains(doctypeLower, "strict") {
-					return "HTML 4.01 Strict"
-				} else if strings.Contains(doctypeLower, "transitional") {
-					return "HTML 4.01 Transitional"
-				} else if strings.Contains(doctypeLower, "frameset") {
-					return "HTML 4.01 Frameset"
-				}
-				return "HTML 4.01"
+// traverse reads the analysis fields from the DOM in document order,
+// keeping within the parser limits and counting what they cut in truncation.
+// It checks ctx every cancelCheckInterval nodes and stops with its error.
+func (p *HTMLParser) traverse(ctx context.Context, doc *html.Node, baseURL *url.URL, result *models.ParsedHTML, truncation *models.ParseTruncation) error {
+	var text visibleText
+	anchors := newAnchorCollector(p.limits.MaxAnchors)
+	var visited int
+	var err error
+	truncation.DeepElements += walk(doc, p.limits.MaxDepth, func(node *html.Node) bool {
+		if err != nil {
+			return false
+		}
+		if visited++; visited%cancelCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return false
 			}
+		}
+		if node.Type == html.ElementNode {
+			p.visit(node, baseURL, result, truncation)
+			anchors.visit(node, baseURL)
+		}
+		text.visit(node)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	result.Text = text.result()
+	result.Anchors = anchors.result(truncation)
+	return nil
+}
 
-			// HTML 3.2
-			if strings.Contains(doctypeLower, "html 3.2") {
-				return "HTML 3.2"
+func (p *HTMLParser) visit(node *html.Node, baseURL *url.URL, result *models.ParsedHTML, truncation *models.ParseTruncation) {
+	switch node.Data {
+	case "title":
+		// Browsers take the first title of the document, wherever it is;
+		// the title of an inline SVG is a tooltip
+		if node.Namespace != "" {
+			break
+		}
+		result.TitleCount++
+		if result.TitleCount == 1 && node.FirstChild != nil && node.FirstChild.Type == html.TextNode {
+			var truncated bool
+			result.Title, truncated = p.extractText(node)
+			if truncated {
+				truncation.TruncatedTexts++
 			}
-
-			// HTML 2.0
-			if strings.Contains(doctypeLower, "html 2.0") {
-				return "HTML 2.0"
+		}
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		if len(result.Headings[node.Data]) >= p.limits.MaxHeadings {
+			truncation.DroppedHeadings++
+			break
+		}
+		text, truncated := p.extractText(node)
+		if truncated {
+			truncation.TruncatedTexts++
+		}
+		if text != "" {
+			result.Headings[node.Data] = append(result.Headings[node.Data], text)
+		}
+	case "a":
+		target, skipped := p.linkTarget(node, baseURL)
+		switch {
+		case skipped == models.LinkSkipDataURI:
+			p.addDataURI(result, attribute(node, "href"))
+		case skipped != "":
+			countSkipped(result, skipped)
+		case len(result.Links) >= p.limits.MaxLinks:
+			truncation.DroppedLinks++
+		default:
+			text, truncated := p.linkText(node)
+			if truncated {
+				truncation.TruncatedTexts++
+			}
+			rel, browsingContext := anchorAttributes(node)
+			result.Links = append(result.Links, models.Link{
+				URL:    target.String(),
+				Text:   text,
+				Type:   p.determineLinkType(target, baseURL),
+				Rel:    rel,
+				Target: browsingContext,
+			})
+		}
+	case "html":
+		if isAMPDocument(node) {
+			result.IsAMP = true
+		}
+		result.Lang = strings.TrimSpace(attribute(node, "lang"))
+	case "frame", "iframe":
+		if src := frameSource(node, baseURL); src != "" && !slices.Contains(result.Frames, src) {
+			result.Frames = append(result.Frames, src)
+		}
+	case "link":
+		rel, href, hreflang := linkAttributes(node)
+		switch {
+		case href == "":
+		case hasRel(rel, "canonical") && result.CanonicalURL == "":
+			result.CanonicalURL = resolveHref(href, baseURL)
+		case hasRel(rel, "amphtml") && result.AMPHTMLURL == "":
+			result.AMPHTMLURL = resolveHref(href, baseURL)
+		case hasRel(rel, "next") && result.NextURL == "":
+			result.NextURL = resolveHref(href, baseURL)
+		case (hasRel(rel, "prev") || hasRel(rel, "previous")) && result.PrevURL == "":
+			result.PrevURL = resolveHref(href, baseURL)
+		case hasRel(rel, "alternate") && hreflang != "":
+			if alternate := resolveHref(href, baseURL); alternate != "" {
+				result.Hreflangs = append(result.Hreflangs, models.HreflangLink{Lang: hreflang, URL: alternate})
 			}
-
-			// Found DOCTYPE but couldn't identify version
-			return "Unknown DOCTYPE"
+		}
+	case "meta":
+		switch name := strings.ToLower(strings.TrimSpace(attribute(node, "name"))); name {
+		case "robots", "googlebot":
+			content := strings.TrimSpace(attribute(node, "content"))
+			result.RobotsMeta = append(result.RobotsMeta, models.RobotsMeta{Name: name, Content: content})
+		case "generator":
+			if result.Generator == "" {
+				result.Generator = strings.TrimSpace(attribute(node, "content"))
+			}
+		}
+	case "form":
+		if p.isLoginForm(node) {
+			result.HasLoginForm = true
 		}
 	}
+}
 
-	// Check if there's any DOCTYPE anywhere in the first 1000 chars
-	first1000 := htmlLower
-	if len(first1000) > 1000 {
-		first1000 = first1000[:1000]
+// parseDocument builds the DOM of content, decompressing gzip bodies first.
+// Elements nested deeper than maxDepth are flattened before parsing; their
+// number is returned. The tokenizer stops with ctx's error once ctx is done.
+func parseDocument(ctx context.Context, content []byte, maxDepth int) (*html.Node, int, error) {
+	content, err := decodeBody(content)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	if strings.Contains(first1000, "<!doctype") {
-		if p.logger != nil {
-			p.logger.Debug("DOCTYPE found but not at beginning", "position", strings.Index(first1000, "<!doctype"))
+	content, flattened := capNesting(ctx, content, maxDepth)
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	doc, err := html.Parse(&contextReader{ctx: ctx, r: bytes.NewReader(content)})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, 0, ctxErr
 		}
-		return "DOCTYPE not at beginning"
+		return nil, 0, fmt.Errorf("failed to parse HTML: %w", err)
 	}
+	return doc, flattened, nil
+}
 
-	// No DOCTYPE found
-	return "Unknown/No DOCTYPE"
+// contextReader reads from r until ctx is done, then fails with ctx's error.
+// The tokenizer reads a few kilobytes at a time, so a parse notices soon.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
 }
 
-func (p *HTMLParser) ExtractTitle(content []byte) string {
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
 
-	//fmt.Println("LOG: ExtractTitle =", content)
+// decodeBody returns content decompressed when it is gzip, detected by its
+// magic bytes, and unchanged otherwise
+func decodeBody(content []byte) ([]byte, error) {
+	if len(content) < 2 || content[0] != 0x1f || content[1] != 0x8b {
+		return content, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer gz.Close()
+	decoded, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress HTML: %w", err)
+	}
+	return decoded, nil
+}
 
-	doc, err := html.Parse(bytes.NewReader(content))
+// decodePrefix returns up to the first n bytes of content, decompressing
+// only as much of a gzip body as that takes. A broken gzip stream has no
+// prefix.
+func decodePrefix(content []byte, n int) []byte {
+	if len(content) < 2 || content[0] != 0x1f || content[1] != 0x8b {
+		return content[:min(len(content), n)]
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(content))
 	if err != nil {
-		return ""
+		return nil
 	}
+	defer gz.Close()
+	prefix, err := io.ReadAll(io.LimitReader(gz, int64(n)))
+	if err != nil {
+		return nil
+	}
+	return prefix
+}
 
-	var title string
-	var findTitle func(*html.Node)
-	findTitle = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "title" && n.FirstChild != nil {
-			title = strings.TrimSpace(n.FirstChild.Data)
-			return
+// findDoctype returns the document's DOCTYPE declaration serialized back to
+// text, e.g. <!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "...">.
+// The parser only keeps a DOCTYPE that precedes all content, as a direct
+// child of the document node.
+func findDoctype(doc *html.Node) string {
+	for node := doc.FirstChild; node != nil; node = node.NextSibling {
+		if node.Type != html.DoctypeNode {
+			continue
 		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			findTitle(c)
+		var b strings.Builder
+		if err := html.Render(&b, node); err != nil {
+			return ""
 		}
+		return b.String()
 	}
-	findTitle(doc)
-
-	return title
+	return ""
 }
 
-func (p *HTMLParser) traverse(node *html.Node, baseURL *url.URL, result *models.ParsedHTML) {
-	if node.Type == html.ElementNode {
-		switch node.Data {
-		case "title":
-			if node.FirstChild != nil && node.FirstChild.Type == html.TextNode {
-				result.Title = strings.TrimSpace(node.FirstChild.Data)
-				//fmt.Printf("LOG: Found title: '%s'\n", result.Title)
-			}
-		case "h1", "h2", "h3", "h4", "h5", "h6":
-			text := p.extractText(node)
-			if text != "" {
-				result.Headings[node.Data] = append(result.Headings[node.Data], text)
-				//fmt.Printf("LOG: Found %s: '%s'\n", node.Data, text)
-			}
-		case "a":
-			if link := p.extractLink(node, baseURL); link != nil {
-				result.Links = append(result.Links, *link)
-				//fmt.Printf("LOG: Added %s link: '%s' -> %s\n", link.Type, link.Text, link.URL)
-			}
-		case "form":
-			if p.isLoginForm(node) {
-				result.HasLoginForm = true
-				//fmt.Println("LOG: Found login form")
+// extractText returns the normalized text below node, collected in a pooled
+// buffer that is copied out before it goes back to the pool, and whether it
+// was cut at the length limit. The content of script, style and template
+// elements is not part of it.
+func (p *HTMLParser) extractText(node *html.Node) (string, bool) {
+	collector := textCollector{buf: bufpool.Get(), max: p.limits.MaxTextLength}
+	defer bufpool.Put(collector.buf)
+
+	walk(node, p.limits.MaxDepth, func(n *html.Node) bool {
+		switch n.Type {
+		case html.TextNode:
+			return collector.write(n.Data)
+		case html.ElementNode:
+			if n != node && (n.Data == "script" || n.Data == "style" || n.Data == "template") {
+				return false
 			}
 		}
-	}
+		return !collector.truncated
+	})
+	return collector.text()
+}
 
-	for child := node.FirstChild; child != nil; child = child.NextSibling {
-		p.traverse(child, baseURL, result)
+// linkText returns the accessible name of an <a>, as a screen reader
+// announces it: its aria-label, or else its text with the aria-label of the
+// elements inside it and the alt text of its images in their place. An
+// icon-only link with a label is thereby not mistaken for an empty one.
+func (p *HTMLParser) linkText(node *html.Node) (string, bool) {
+	collector := textCollector{buf: bufpool.Get(), max: p.limits.MaxTextLength}
+	defer bufpool.Put(collector.buf)
+
+	if label := attribute(node, "aria-label"); strings.TrimSpace(label) != "" {
+		collector.write(label)
+		return collector.text()
 	}
-}
 
-func (p *HTMLParser) extractText(node *html.Node) string {
-	var text strings.Builder
-	var extract func(*html.Node)
-	extract = func(n *html.Node) {
-		if n.Type == html.TextNode {
-			text.WriteString(n.Data)
+	walk(node, p.limits.MaxDepth, func(n *html.Node) bool {
+		switch {
+		case n.Type == html.TextNode:
+			return collector.write(n.Data)
+		case n.Type != html.ElementNode || n == node:
+		case n.Data == "script" || n.Data == "style" || n.Data == "template":
+			return false
+		case strings.TrimSpace(attribute(n, "aria-label")) != "":
+			// Spaces keep the label apart from the text around it
+			collector.write(" " + attribute(n, "aria-label") + " ")
+			return false
+		case n.Data == "img":
+			collector.write(" " + attribute(n, "alt") + " ")
 		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			extract(c)
+		return !collector.truncated
+	})
+	return collector.text()
+}
+
+// attribute returns the value of node's attribute key, or "" without one
+func attribute(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
 		}
 	}
-	extract(node)
-	return strings.TrimSpace(text.String())
+	return ""
 }
 
-func (p *HTMLParser) extractLink(node *html.Node, baseURL *url.URL) *models.Link {
-
-	//fmt.Println("LOG: extractLink =", node)
+// unsupportedSchemes are link schemes with nothing to check
+var unsupportedSchemes = []string{"javascript:", "mailto:"}
 
+// linkTarget returns the absolute URL an <a> links to, or the reason it is
+// skipped as not a link worth checking
+func (p *HTMLParser) linkTarget(node *html.Node, baseURL *url.URL) (*url.URL, string) {
 	var href string
 	for _, attr := range node.Attr {
 		if attr.Key == "href" {
-			href = attr.Val
+			href = strings.TrimSpace(attr.Val)
 			break
 		}
 	}
 
-	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "javascript:") ||
-		strings.HasPrefix(href, "mailto:") {
-		return nil
+	switch {
+	case href == "":
+		return nil, models.LinkSkipEmptyHref
+	case strings.HasPrefix(href, "#"):
+		return nil, models.LinkSkipFragmentOnly
+	case slices.ContainsFunc(unsupportedSchemes, func(scheme string) bool { return hasScheme(href, scheme) }):
+		return nil, models.LinkSkipUnsupportedScheme
+	case hasScheme(href, dataScheme):
+		return nil, models.LinkSkipDataURI
+	case hasScheme(href, blobScheme):
+		return nil, models.LinkSkipBlob
 	}
 
 	linkURL, err := url.Parse(href)
@@ -259,22 +495,111 @@ func (p *HTMLParser) extractLink(node *html.Node, baseURL *url.URL) *models.Link
 		if p.logger != nil {
 			p.logger.Debug("Failed to parse link URL", "href", href, "error", err)
 		}
-		return nil
+		return nil, models.LinkSkipParseError
+	}
+
+	return resolve(baseURL, linkURL), ""
+}
+
+// frameSource returns the absolute src of a frame or iframe, or "" when it
+// has no fetchable document
+func frameSource(node *html.Node, baseURL *url.URL) string {
+	var src string
+	for _, attr := range node.Attr {
+		if attr.Key == "src" {
+			src = strings.TrimSpace(attr.Val)
+			break
+		}
 	}
 
-	absoluteURL := baseURL.ResolveReference(linkURL)
+	source, err := url.Parse(src)
+	if src == "" || err != nil {
+		return ""
+	}
+	resolved := resolve(baseURL, source)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return ""
+	}
+	return resolved.String()
+}
 
-	link := &models.Link{
-		URL:  absoluteURL.String(),
-		Text: p.extractText(node),
-		Type: p.determineLinkType(absoluteURL, baseURL),
+// linkAttributes returns the rel, href and hreflang of a <link>, trimmed
+func linkAttributes(node *html.Node) (rel, href, hreflang string) {
+	for _, attr := range node.Attr {
+		switch attr.Key {
+		case "rel":
+			rel = attr.Val
+		case "href":
+			href = strings.TrimSpace(attr.Val)
+		case "hreflang":
+			hreflang = strings.TrimSpace(attr.Val)
+		}
 	}
+	return rel, href, hreflang
+}
 
-	return link
+// isAMPDocument reports whether an <html> element marks an AMP page, with
+// either the amp or the ⚡ attribute
+func isAMPDocument(node *html.Node) bool {
+	return slices.ContainsFunc(node.Attr, func(attr html.Attribute) bool {
+		return attr.Key == "amp" || attr.Key == "⚡"
+	})
 }
 
+// anchorAttributes returns the rel keywords of an <a>, lowercased and each
+// once, and its trimmed target
+func anchorAttributes(node *html.Node) (rel []string, target string) {
+	for _, attr := range node.Attr {
+		switch attr.Key {
+		case "rel":
+			for _, keyword := range strings.Fields(attr.Val) {
+				if keyword = strings.ToLower(keyword); !slices.Contains(rel, keyword) {
+					rel = append(rel, keyword)
+				}
+			}
+		case "target":
+			target = strings.TrimSpace(attr.Val)
+		}
+	}
+	return rel, target
+}
+
+// hasRel reports whether the space-separated rel list contains want
+func hasRel(rel, want string) bool {
+	return slices.ContainsFunc(strings.Fields(rel), func(r string) bool { return strings.EqualFold(r, want) })
+}
+
+// resolveHref makes href absolute against baseURL, or returns "" when it
+// cannot be parsed
+func resolveHref(href string, baseURL *url.URL) string {
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	return resolve(baseURL, ref).String()
+}
+
+// resolve makes ref absolute against baseURL, with the host of an
+// internationalized domain name in ASCII form so that the links to a host
+// share one spelling however the page writes it
+func resolve(baseURL, ref *url.URL) *url.URL {
+	resolved := baseURL.ResolveReference(ref)
+	asciiHost(resolved)
+	return resolved
+}
+
+// asciiHost puts the host of u in ASCII form if it is an internationalized
+// domain name; other hosts are left as written
+func asciiHost(u *url.URL) {
+	if idn.IsIDN(u.Host) {
+		u.Host = idn.ASCIIHost(u.Host)
+	}
+}
+
+// determineLinkType tells internal links, on the page's host in any case
+// or form, from external ones
 func (p *HTMLParser) determineLinkType(linkURL, baseURL *url.URL) models.LinkType {
-	if linkURL.Host == "" || linkURL.Host == baseURL.Host {
+	if linkURL.Host == "" || idn.ASCIIHost(linkURL.Host) == idn.ASCIIHost(baseURL.Host) {
 		return models.LinkTypeInternal
 	}
 	return models.LinkTypeExternal
@@ -300,8 +625,7 @@ func (p *HTMLParser) isLoginForm(node *html.Node) bool {
 		}
 	}
 
-	var checkInputs func(*html.Node)
-	checkInputs = func(n *html.Node) {
+	walk(node, p.limits.MaxDepth, func(n *html.Node) bool {
 		if n.Type == html.ElementNode && n.Data == "input" {
 			inputType := ""
 			inputName := ""
@@ -327,13 +651,8 @@ func (p *HTMLParser) isLoginForm(node *html.Node) bool {
 				}
 			}
 		}
-
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			checkInputs(c)
-		}
-	}
-
-	checkInputs(node)
+		return true
+	})
 
 	// A login form typically has both username and password fields
 	return hasPasswordInput && (hasUsernameInput || formAction != "")
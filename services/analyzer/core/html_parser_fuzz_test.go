@@ -0,0 +1,139 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// Limits small enough for the fuzzer to reach them
+var fuzzLimits = ParserLimits{MaxDepth: 32, MaxLinks: 20, MaxTextLength: 40}
+
+// addFuzzSeeds seeds f with the testdata pages, gzipped and plain, and with
+// hand-made pathological documents
+func addFuzzSeeds(f *testing.F) {
+	f.Helper()
+
+	pages, err := filepath.Glob("testdata/*.html")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, name := range pages {
+		page, err := os.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(page)
+
+		var gz bytes.Buffer
+		w := gzip.NewWriter(&gz)
+		w.Write(page)
+		w.Close()
+		f.Add(gz.Bytes())
+	}
+
+	for _, seed := range []string{
+		"",
+		"\x1f\x8b",
+		"<!DOCTYPE html>",
+		"<!doctype html PUBLIC \"-//W3C//DTD XHTML 1.0 Strict//EN\">",
+		nested("div", 200, `<a href="/deep">deep</a>`),
+		nested("b", 200, "<h1>bold</h1>"),
+		strings.Repeat("<table><tr><td>", 50),
+		strings.Repeat("<a href=/x>", 100),
+		strings.Repeat("<h2>"+strings.Repeat("é", 30), 10),
+		"<title>\u200b a \t\n b\u00a0</title><h1> 👩\u200d👩\u200d👧 <b>שלום</b>\ufeff</h1>",
+		`<form action="/login"><input type="password"><form><input name="user">`,
+		`<svg><title><a href="/svg">x</a></title></svg><math><mtext><form><input type=password>`,
+		`<template><h1>hidden</h1><a href="/t">t</a></template>`,
+		"<a href=\"\x00\xff\">\xc3\x28</a><h1>\xed\xa0\x80</h1>",
+	} {
+		f.Add([]byte(seed))
+	}
+}
+
+func FuzzHTMLParser_ParseHTML(f *testing.F) {
+	addFuzzSeeds(f)
+	parser := NewHTMLParser(nil)
+	parser.SetLimits(fuzzLimits)
+
+	f.Fuzz(func(t *testing.T, content []byte) {
+		result, err := parser.ParseHTML(context.Background(), content, "https://example.com/dir/page")
+		if err != nil {
+			if len(content) >= 2 && content[0] == 0x1f && content[1] == 0x8b {
+				return // a broken gzip stream
+			}
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if result.HTMLVersion == "" {
+			t.Error("empty HTML version")
+		}
+		if len(result.Links) > fuzzLimits.MaxLinks {
+			t.Errorf("%d links, limit %d", len(result.Links), fuzzLimits.MaxLinks)
+		}
+		for _, link := range result.Links {
+			if link.URL == "" {
+				t.Error("link without URL")
+			}
+		}
+		texts := []string{result.Title}
+		for _, headings := range result.Headings {
+			texts = append(texts, headings...)
+		}
+		for _, link := range result.Links {
+			texts = append(texts, link.Text)
+		}
+		for _, text := range texts {
+			checkText(t, text, fuzzLimits.MaxTextLength)
+		}
+		if tr := result.Truncation; tr != nil && tr.DeepElements == 0 && tr.DroppedLinks == 0 && tr.TruncatedTexts == 0 && tr.DroppedAnchors == 0 {
+			t.Error("empty truncation reported")
+		}
+	})
+}
+
+func FuzzHTMLParser_DetectHTMLVersion(f *testing.F) {
+	addFuzzSeeds(f)
+	parser := NewHTMLParser(nil)
+	parser.SetLimits(fuzzLimits)
+
+	known := []string{
+		noDoctype, "Unknown DOCTYPE", "HTML5", "XHTML 1.1",
+		"XHTML 1.0", "XHTML 1.0 Strict", "XHTML 1.0 Transitional", "XHTML 1.0 Frameset",
+		"HTML 4.01", "HTML 4.01 Strict", "HTML 4.01 Transitional", "HTML 4.01 Frameset",
+		"HTML 3.2", "HTML 2.0",
+	}
+
+	f.Fuzz(func(t *testing.T, content []byte) {
+		if version := parser.DetectHTMLVersion(content); !slices.Contains(known, version) {
+			t.Errorf("unknown version %q", version)
+		}
+	})
+}
+
+func FuzzHTMLParser_isLoginForm(f *testing.F) {
+	addFuzzSeeds(f)
+	parser := NewHTMLParser(nil)
+	parser.SetLimits(fuzzLimits)
+
+	f.Fuzz(func(t *testing.T, content []byte) {
+		doc, _, err := parseDocument(context.Background(), content, fuzzLimits.MaxDepth)
+		if err != nil {
+			return
+		}
+		walk(doc, fuzzLimits.MaxDepth, func(n *html.Node) bool {
+			if n.Type == html.ElementNode && n.Data == "form" && parser.isLoginForm(n) != parser.isLoginForm(n) {
+				t.Error("isLoginForm is not deterministic")
+			}
+			return true
+		})
+	})
+}
@@ -1,10 +1,19 @@
 package core
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
 	"github.com/RuvinSL/webpage-analyzer/pkg/mocks"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
 	"github.com/golang/mock/gomock"
@@ -67,6 +76,26 @@ func TestHTMLParserDetectHTMLVersion(t *testing.T) {
 			content:  ``,
 			expected: "Unknown/No DOCTYPE",
 		},
+		{
+			name:     "after comments and an XML declaration",
+			content:  "<?xml version=\"1.0\"?>\n<!-- generated\n by hand -->\n<!DOCTYPE html>",
+			expected: "HTML5",
+		},
+		{
+			name:     "after content",
+			content:  `<p>Hello</p><!DOCTYPE html>`,
+			expected: "Unknown/No DOCTYPE",
+		},
+		{
+			name:     "past the scan window",
+			content:  "<!--" + strings.Repeat(" ", versionScanWindow) + "--><!DOCTYPE html>",
+			expected: "Unknown/No DOCTYPE",
+		},
+		{
+			name:     "unterminated comment",
+			content:  `<!-- <!DOCTYPE html>`,
+			expected: "Unknown/No DOCTYPE",
+		},
 	}
 
 	for _, tt := range tests {
@@ -351,6 +380,566 @@ func TestHTMLParserParseHTML(t *testing.T) {
 	}
 }
 
+func TestHTMLParserParseHTML_Doctype(t *testing.T) {
+	parser := NewHTMLParser(nil)
+
+	tests := []struct {
+		name     string
+		content  string
+		doctype  string
+		expected string
+	}{
+		{
+			name:     "HTML5",
+			content:  `<!DOCTYPE html><html><head><title>T</title></head></html>`,
+			doctype:  "<!DOCTYPE html>",
+			expected: "HTML5",
+		},
+		{
+			name:     "HTML5 lowercase",
+			content:  `<!doctype HTML><html></html>`,
+			doctype:  "<!DOCTYPE html>",
+			expected: "HTML5",
+		},
+		{
+			name:     "XHTML 1.0 Strict",
+			content:  `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd"><html></html>`,
+			doctype:  `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd">`,
+			expected: "XHTML 1.0 Strict",
+		},
+		{
+			name:     "HTML 4.01 Transitional",
+			content:  `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN" "http://www.w3.org/TR/html4/loose.dtd"><html></html>`,
+			doctype:  `<!DOCTYPE html PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN" "http://www.w3.org/TR/html4/loose.dtd">`,
+			expected: "HTML 4.01 Transitional",
+		},
+		{
+			name:     "HTML 3.2",
+			content:  `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 3.2 Final//EN"><html></html>`,
+			doctype:  `<!DOCTYPE html PUBLIC "-//W3C//DTD HTML 3.2 Final//EN">`,
+			expected: "HTML 3.2",
+		},
+		{
+			name:     "no DOCTYPE",
+			content:  `<html><head></head><body></body></html>`,
+			doctype:  "",
+			expected: "Unknown/No DOCTYPE",
+		},
+		{
+			// Browsers ignore a DOCTYPE after content, and so does the parser
+			name:     "DOCTYPE after content",
+			content:  `hello<!DOCTYPE html><html></html>`,
+			doctype:  "",
+			expected: "Unknown/No DOCTYPE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parser.ParseHTML(context.Background(), []byte(tt.content), "https://example.com")
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.doctype, parsed.Doctype)
+			assert.Equal(t, tt.expected, parsed.HTMLVersion)
+		})
+	}
+}
+
+func TestHTMLParserParseHTML_CanonicalURL(t *testing.T) {
+	parser := NewHTMLParser(nil)
+
+	tests := []struct {
+		name     string
+		head     string
+		expected string
+	}{
+		{name: "absolute", head: `<link rel="canonical" href="https://example.com/page">`, expected: "https://example.com/page"},
+		{name: "relative", head: `<link rel="canonical" href="/page?x=1">`, expected: "https://example.com/page?x=1"},
+		{name: "rel list and case", head: `<link rel="alternate CANONICAL" href="/page">`, expected: "https://example.com/page"},
+		{name: "first one wins", head: `<link rel="canonical" href="/first"><link rel="canonical" href="/second">`, expected: "https://example.com/first"},
+		{name: "other link", head: `<link rel="stylesheet" href="/style.css">`, expected: ""},
+		{name: "empty href", head: `<link rel="canonical" href=" ">`, expected: ""},
+		{name: "none", head: ``, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := `<!DOCTYPE html><html><head>` + tt.head + `</head><body></body></html>`
+			parsed, err := parser.ParseHTML(context.Background(), []byte(content), "https://example.com/docs/")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, parsed.CanonicalURL)
+		})
+	}
+}
+
+func TestHTMLParserParseHTML_Hreflang(t *testing.T) {
+	parser := NewHTMLParser(nil)
+
+	content := `<!DOCTYPE html><html><head>
+<link rel="alternate" hreflang="en-GB" href="https://example.com/uk/">
+<link rel="alternate" hreflang="de" href="../de/page">
+<link rel="ALTERNATE" hreflang="english" href="/en">
+<link rel="alternate" hreflang="x-default" href="/">
+<link rel="alternate" type="application/rss+xml" href="/feed.xml">
+<link rel="alternate" hreflang="fr" href=" ">
+<link rel="canonical" hreflang="es" href="/es/">
+</head><body></body></html>`
+
+	parsed, err := parser.ParseHTML(context.Background(), []byte(content), "https://example.com/docs/intro")
+	require.NoError(t, err)
+
+	assert.Equal(t, []models.HreflangLink{
+		{Lang: "en-GB", URL: "https://example.com/uk/"},
+		{Lang: "de", URL: "https://example.com/de/page"},
+		{Lang: "english", URL: "https://example.com/en"},
+		{Lang: "x-default", URL: "https://example.com/"},
+	}, parsed.Hreflangs)
+	assert.Equal(t, "https://example.com/es/", parsed.CanonicalURL)
+}
+
+func TestHTMLParserParseHTML_AMP(t *testing.T) {
+	parser := NewHTMLParser(nil)
+
+	tests := []struct {
+		name    string
+		html    string
+		head    string
+		isAMP   bool
+		ampHTML string
+	}{
+		{name: "lightning attribute", html: `<html ⚡ lang="en">`, isAMP: true},
+		{name: "amp attribute", html: `<html amp lang="en">`, isAMP: true},
+		{name: "amp attribute with a value", html: `<html AMP="">`, isAMP: true},
+		{name: "amp for email is not a page", html: `<html ⚡4email>`},
+		{name: "plain page", html: `<html lang="en">`},
+		{name: "amphtml link", html: `<html>`, head: `<link rel="amphtml" href="/amp/page">`, ampHTML: "https://example.com/amp/page"},
+		{name: "first amphtml wins", html: `<html>`, head: `<link rel="AMPHTML" href="/a"><link rel="amphtml" href="/b">`, ampHTML: "https://example.com/a"},
+		{name: "empty amphtml href", html: `<html>`, head: `<link rel="amphtml" href="">`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := `<!DOCTYPE html>` + tt.html + `<head>` + tt.head + `<link rel="canonical" href="/page"></head><body></body></html>`
+			parsed, err := parser.ParseHTML(context.Background(), []byte(content), "https://example.com/docs/")
+			require.NoError(t, err)
+			assert.Equal(t, tt.isAMP, parsed.IsAMP)
+			assert.Equal(t, tt.ampHTML, parsed.AMPHTMLURL)
+			assert.Equal(t, "https://example.com/page", parsed.CanonicalURL)
+		})
+	}
+}
+
+func TestHTMLParserParseHTML_Pagination(t *testing.T) {
+	parser := NewHTMLParser(nil)
+
+	tests := []struct {
+		name string
+		head string
+		next string
+		prev string
+	}{
+		{name: "first page", head: `<link rel="next" href="?page=2">`, next: "https://example.com/docs/?page=2"},
+		{
+			name: "middle page",
+			head: `<link rel="prev" href="?page=1"><link rel="next" href="?page=3">`,
+			next: "https://example.com/docs/?page=3",
+			prev: "https://example.com/docs/?page=1",
+		},
+		{name: "last page", head: `<link rel="Previous" href="/docs/?page=2">`, prev: "https://example.com/docs/?page=2"},
+		{name: "first next wins", head: `<link rel="next" href="/a"><link rel="NEXT" href="/b">`, next: "https://example.com/a"},
+		{name: "empty next href", head: `<link rel="next" href="">`},
+		{name: "not paginated", head: `<link rel="canonical" href="/docs/">`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := `<!DOCTYPE html><html><head>` + tt.head + `</head><body></body></html>`
+			parsed, err := parser.ParseHTML(context.Background(), []byte(content), "https://example.com/docs/")
+			require.NoError(t, err)
+			assert.Equal(t, tt.next, parsed.NextURL)
+			assert.Equal(t, tt.prev, parsed.PrevURL)
+		})
+	}
+}
+
+func TestHTMLParserParseHTML_Frameset(t *testing.T) {
+	parser := NewHTMLParser(nil)
+
+	page, err := os.ReadFile("testdata/frameset.html")
+	require.NoError(t, err)
+
+	parsed, err := parser.ParseHTML(context.Background(), page, "https://example.com/site/index.html")
+	require.NoError(t, err)
+
+	assert.Equal(t, "HTML 4.01 Frameset", parsed.HTMLVersion)
+	assert.Equal(t, "Classic Frames", parsed.Title)
+	// Nested framesets are walked, duplicates and frames without a
+	// fetchable document are dropped
+	assert.Equal(t, []string{
+		"https://example.com/site/nav.html",
+		"https://example.com/content/main.html",
+		"https://ads.example.net/banner.html",
+	}, parsed.Frames)
+	assert.Empty(t, parsed.Headings)
+}
+
+func TestHTMLParserParseHTML_MultipleTitles(t *testing.T) {
+	parser := NewHTMLParser(nil)
+
+	page, err := os.ReadFile("testdata/multiple_titles.html")
+	require.NoError(t, err)
+
+	parsed, err := parser.ParseHTML(context.Background(), page, "https://example.com/")
+	require.NoError(t, err)
+
+	// The first title in document order is the page's; the SVG title is a
+	// tooltip and not counted
+	assert.Equal(t, "Spring Sale | Example Shop", parsed.Title)
+	assert.Equal(t, 3, parsed.TitleCount)
+	assert.Equal(t, []string{"Spring Sale"}, parsed.Headings["h1"])
+}
+
+func TestHTMLParserParseHTML_Titles(t *testing.T) {
+	parser := NewHTMLParser(nil)
+
+	tests := []struct {
+		name      string
+		content   string
+		wantTitle string
+		wantCount int
+	}{
+		{name: "none", content: `<html><head></head><body></body></html>`},
+		{name: "empty", content: `<html><head><title></title></head></html>`, wantCount: 1},
+		{
+			name:      "empty first title is still the title",
+			content:   `<html><head><title> </title><title>Second</title></head></html>`,
+			wantCount: 2,
+		},
+		{
+			name:      "only in the body",
+			content:   `<html><body><h1>Hi</h1><title>Body title</title></body></html>`,
+			wantTitle: "Body title",
+			wantCount: 1,
+		},
+		{
+			name:      "only in an SVG",
+			content:   `<html><body><svg><title>Tooltip</title></svg></body></html>`,
+			wantCount: 0,
+		},
+		{
+			name:      "no html or head elements",
+			content:   `<title>Bare</title><p>Text</p>`,
+			wantTitle: "Bare",
+			wantCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parser.ParseHTML(context.Background(), []byte(tt.content), "https://example.com/")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTitle, parsed.Title)
+			assert.Equal(t, tt.wantCount, parsed.TitleCount)
+			assert.Equal(t, tt.wantTitle, parser.ExtractTitle([]byte(tt.content)), "ExtractTitle agrees")
+		})
+	}
+}
+
+func TestHTMLParserParseHTML_Iframes(t *testing.T) {
+	parser := NewHTMLParser(nil)
+
+	content := `<!DOCTYPE html><html><body><h1>Host</h1>
+<iframe src="/embed/video"></iframe>
+<iframe srcdoc="<p>inline</p>"></iframe>
+<iframe src="data:text/html,hello"></iframe>
+</body></html>`
+	parsed, err := parser.ParseHTML(context.Background(), []byte(content), "https://example.com/")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"https://example.com/embed/video"}, parsed.Frames)
+	assert.Equal(t, []string{"Host"}, parsed.Headings["h1"])
+}
+
+func TestHTMLParserParseHTML_SkippedLinks(t *testing.T) {
+	parser := NewHTMLParser(nil)
+
+	page, err := os.ReadFile("testdata/skipped_links.html")
+	require.NoError(t, err)
+
+	parsed, err := parser.ParseHTML(context.Background(), page, "https://example.com/site/")
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]int{
+		models.LinkSkipEmptyHref:         3,
+		models.LinkSkipFragmentOnly:      2,
+		models.LinkSkipUnsupportedScheme: 3,
+		models.LinkSkipParseError:        2,
+		models.LinkSkipDataURI:           1,
+		models.LinkSkipBlob:              1,
+	}, parsed.SkippedLinks)
+	assert.Equal(t, []models.Link{
+		{URL: "https://example.com/about", Text: "About", Type: models.LinkTypeInternal},
+		{URL: "https://example.com/site/contact.html#form", Text: "Contact", Type: models.LinkTypeInternal},
+		{URL: "https://other.example/", Text: "Partner", Type: models.LinkTypeExternal},
+	}, parsed.Links)
+}
+
+func TestHTMLParserParseHTML_Anchors(t *testing.T) {
+	parser := NewHTMLParser(nil)
+
+	page, err := os.ReadFile("testdata/anchors.html")
+	require.NoError(t, err)
+
+	parsed, err := parser.ParseHTML(context.Background(), page, "https://example.com/docs/anchors.html")
+	require.NoError(t, err)
+
+	// ids match case-sensitively, percent-encoded fragments match decoded,
+	// <a name> and "top" are targets too, and links to another page or
+	// query are not in-page links
+	assert.Equal(t, &models.AnchorReport{
+		DuplicateIDs:    []models.DuplicateID{{ID: "main", Count: 2}, {ID: "card", Count: 3}},
+		DanglingAnchors: []string{"Pricing", "faq", "contact"},
+	}, parsed.Anchors)
+	assert.Nil(t, parsed.Truncation)
+}
+
+func TestHTMLParserParseHTML_NoAnchorProblems(t *testing.T) {
+	parsed, err := NewHTMLParser(nil).ParseHTML(context.Background(),
+		[]byte(`<a href="#a">a</a><a href="https://example.com/#b">b</a><p id="a"></p><p id="b"></p>`), "https://example.com")
+	require.NoError(t, err)
+	assert.Nil(t, parsed.Anchors)
+}
+
+func TestHTMLParserParseHTML_AnchorLimit(t *testing.T) {
+	parser := NewHTMLParser(nil)
+	parser.SetLimits(ParserLimits{MaxAnchors: 2})
+
+	page := `<a href="#missing">m</a><p id="a"></p><p id="a"></p><p id="b"></p><p id="c"></p><p id="d"></p>`
+	parsed, err := parser.ParseHTML(context.Background(), []byte(page), "https://example.com")
+	require.NoError(t, err)
+
+	// With ids dropped, "missing" may name one of them and is not reported
+	assert.Equal(t, &models.AnchorReport{DuplicateIDs: []models.DuplicateID{{ID: "a", Count: 2}}}, parsed.Anchors)
+	require.NotNil(t, parsed.Truncation)
+	assert.Equal(t, 2, parsed.Truncation.DroppedAnchors)
+}
+
+func TestHTMLParserParseHTML_LinkAttributes(t *testing.T) {
+	content := `<a href="/plain">Plain</a>
+<a href="https://ads.example/" rel="sponsored  nofollow">Ad</a>
+<a href="https://forum.example/" REL="UGC NoFollow ugc" TARGET="_BLANK">Post</a>
+<a href="/new" target=" _blank " rel="
+	noopener
+	noreferrer">New tab</a>
+<a href="/frame" target="content" rel="">Framed</a>`
+
+	parsed, err := NewHTMLParser(nil).ParseHTML(context.Background(), []byte(content), "https://example.com")
+	require.NoError(t, err)
+
+	require.Len(t, parsed.Links, 5)
+	for i, want := range []struct {
+		rel    []string
+		target string
+	}{
+		{nil, ""},
+		{[]string{"sponsored", "nofollow"}, ""},
+		{[]string{"ugc", "nofollow"}, "_BLANK"},
+		{[]string{"noopener", "noreferrer"}, "_blank"},
+		{nil, "content"},
+	} {
+		assert.Equal(t, want.rel, parsed.Links[i].Rel, parsed.Links[i].URL)
+		assert.Equal(t, want.target, parsed.Links[i].Target, parsed.Links[i].URL)
+	}
+}
+
+func TestHTMLParserParseHTML_IDNHosts(t *testing.T) {
+	content := `<a href="/katalog">Relative</a>
+<a href="https://bücher.example/neu">Unicode</a>
+<a href="https://xn--bcher-kva.example/alt">ASCII</a>
+<a href="https://BÜCHER.example:8443/shop">Upper case, port</a>
+<a href="https://straße.example/">Other host</a>
+<a href="https://example.com/">Plain host</a>`
+
+	for _, base := range []string{"https://bücher.example/", "https://xn--bcher-kva.example/"} {
+		t.Run(base, func(t *testing.T) {
+			parsed, err := NewHTMLParser(nil).ParseHTML(context.Background(), []byte(content), base)
+			require.NoError(t, err)
+
+			assert.Equal(t, []models.Link{
+				{URL: "https://xn--bcher-kva.example/katalog", Text: "Relative", Type: models.LinkTypeInternal},
+				{URL: "https://xn--bcher-kva.example/neu", Text: "Unicode", Type: models.LinkTypeInternal},
+				{URL: "https://xn--bcher-kva.example/alt", Text: "ASCII", Type: models.LinkTypeInternal},
+				{URL: "https://xn--bcher-kva.example:8443/shop", Text: "Upper case, port", Type: models.LinkTypeExternal},
+				{URL: "https://xn--strae-oqa.example/", Text: "Other host", Type: models.LinkTypeExternal},
+				{URL: "https://example.com/", Text: "Plain host", Type: models.LinkTypeExternal},
+			}, parsed.Links)
+		})
+	}
+}
+
+func TestHTMLParserParseHTML_LinkText(t *testing.T) {
+	content := `<a href="/logo"><img src="/logo.png" alt="Example home"></a>
+<a href="/close" aria-label="Close dialog">×</a>
+<a href="/cart"><svg aria-label="Cart"><title>ignored</title></svg> (3)</a>
+<a href="/search"><i class="icon-search"></i></a>
+<a href="/next">Next <script>track()</script><style>a{}</style>page</a>
+<a href="/spacer"><img src="/spacer.gif"></a>`
+
+	parsed, err := NewHTMLParser(nil).ParseHTML(context.Background(), []byte(content), "https://example.com")
+	require.NoError(t, err)
+
+	var texts []string
+	for _, link := range parsed.Links {
+		texts = append(texts, link.Text)
+	}
+	assert.Equal(t, []string{"Example home", "Close dialog", "Cart (3)", "", "Next page", ""}, texts)
+}
+
+func TestHTMLParserParseHTML_RobotsMeta(t *testing.T) {
+	content := `<head>
+<meta name="ROBOTS" content=" noindex, follow ">
+<meta name="googlebot" content="nosnippet">
+<meta name="bingbot" content="noindex">
+<meta name="description" content="noindex">
+</head>`
+
+	parsed, err := NewHTMLParser(nil).ParseHTML(context.Background(), []byte(content), "https://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []models.RobotsMeta{
+		{Name: "robots", Content: "noindex, follow"},
+		{Name: "googlebot", Content: "nosnippet"},
+	}, parsed.RobotsMeta)
+}
+
+func TestHTMLParserParseHTML_Generator(t *testing.T) {
+	content := `<head>
+<meta name="Generator" content=" Hugo 0.120.4 ">
+<meta name="generator" content="Other">
+</head>`
+
+	parsed, err := NewHTMLParser(nil).ParseHTML(context.Background(), []byte(content), "https://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "Hugo 0.120.4", parsed.Generator, "the first one counts")
+}
+
+func TestHTMLParserParseHTML_NoSkippedLinks(t *testing.T) {
+	parsed, err := NewHTMLParser(nil).ParseHTML(context.Background(), []byte(`<a href="/a">a</a>`), "https://example.com")
+	require.NoError(t, err)
+	assert.Nil(t, parsed.SkippedLinks)
+}
+
+func TestHTMLParser_WrappersMatchParseHTML(t *testing.T) {
+	parser := NewHTMLParser(nil)
+
+	page, err := os.ReadFile("testdata/representative.html")
+	require.NoError(t, err)
+
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	_, err = gz.Write(page)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	for name, content := range map[string][]byte{
+		"representative":  page,
+		"gzip":            gzipped.Bytes(),
+		"XHTML 1.1":       []byte(`<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN" "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd"><title>X</title>`),
+		"legacy compat":   []byte(`<!DOCTYPE html SYSTEM "about:legacy-compat"><title>L</title>`),
+		"no DOCTYPE":      []byte(`<html></html>`),
+		"leading comment": []byte("<?xml version=\"1.0\"?><!-- a -->\n<!doctype   HTML\n>"),
+		"multiline":       []byte("<!DOCTYPE html PUBLIC\n  \"-//W3C//DTD HTML 4.01 Frameset//EN\"\n  \"http://www.w3.org/TR/html4/frameset.dtd\">"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			parsed, err := parser.ParseHTML(context.Background(), content, "https://example.com")
+			require.NoError(t, err)
+
+			assert.Equal(t, parsed.HTMLVersion, parser.DetectHTMLVersion(content))
+			assert.Equal(t, parsed.Title, parser.ExtractTitle(content))
+		})
+	}
+}
+
+// largeDocument repeats the sections of the representative fixture until
+// the page is at least size bytes
+func largeDocument(tb testing.TB, size int) []byte {
+	page, err := os.ReadFile("testdata/representative.html")
+	require.NoError(tb, err)
+
+	head, rest, _ := strings.Cut(string(page), "<main>")
+	sections, tail, _ := strings.Cut(rest, "</main>")
+
+	var b strings.Builder
+	b.WriteString(head + "<main>")
+	for b.Len() < size {
+		b.WriteString(sections)
+	}
+	b.WriteString("</main>" + tail)
+	return []byte(b.String())
+}
+
+// BenchmarkHTMLParser_LargeDocument compares reading version, title and
+// content with one parse against the three separate passes analyses used to make
+func BenchmarkHTMLParser_LargeDocument(b *testing.B) {
+	parser := NewHTMLParser(nil)
+	content := largeDocument(b, 5<<20)
+	ctx := context.Background()
+
+	b.Run("separate passes", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(content)))
+		for i := 0; i < b.N; i++ {
+			parser.DetectHTMLVersion(content)
+			parser.ExtractTitle(content)
+			if _, err := parser.ParseHTML(ctx, content, "https://docs.example.com/"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("single pass", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(content)))
+		for i := 0; i < b.N; i++ {
+			if _, err := parser.ParseHTML(ctx, content, "https://docs.example.com/"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkHTMLParser_DetectHTMLVersion shows that the version costs the
+// same whatever the size of the page
+func BenchmarkHTMLParser_DetectHTMLVersion(b *testing.B) {
+	parser := NewHTMLParser(nil)
+	for _, size := range []int{16 << 10, 5 << 20} {
+		content := largeDocument(b, size)
+		b.Run(strconv.Itoa(size>>10)+"KiB", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if parser.DetectHTMLVersion(content) != "HTML5" {
+					b.Fatal("version not detected")
+				}
+			}
+		})
+	}
+}
+
+func TestHTMLParser_DetectHTMLVersionReadsAWindow(t *testing.T) {
+	if testing.Short() {
+		t.Skip("measures allocations with a benchmark")
+	}
+	parser := NewHTMLParser(nil)
+	content := largeDocument(t, 5<<20)
+
+	result := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			parser.DetectHTMLVersion(content)
+		}
+	})
+	assert.Less(t, result.AllocedBytesPerOp(), int64(64<<10), "only the window is parsed, the body is not copied")
+}
+
 func TestHTMLParserisLoginForm(t *testing.T) {
 	//parser := &HTMLParser{}
 
@@ -432,3 +1021,81 @@ func TestHTMLParserisLoginForm(t *testing.T) {
 		})
 	}
 }
+
+// BenchmarkGetAndParse_Concurrent fetches and parses the representative
+// fixture from many goroutines, the analyzer's hot path under load
+func BenchmarkGetAndParse_Concurrent(b *testing.B) {
+	page, err := os.ReadFile("testdata/representative.html")
+	require.NoError(b, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+		w.Write(page)
+	}))
+	defer server.Close()
+
+	log := newTestLogger()
+	client := httpclient.New(10*time.Second, log)
+	parser := NewHTMLParser(log)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(page)))
+	b.SetParallelism(8)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := context.Background()
+		for pb.Next() {
+			response, err := client.Get(ctx, server.URL)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			if _, err := parser.ParseHTML(ctx, response.Body, server.URL); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func TestHTMLParser_ParseHTMLStopsWhenCancelled(t *testing.T) {
+	parser := NewHTMLParser(nil)
+	content := largeDocument(t, 20<<20)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := parser.ParseHTML(cancelled, content, "https://example.com")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Cancelled mid-parse, it gives up well before a full parse would end
+	ctx, cancel := context.WithCancel(context.Background())
+	var cancelledAt time.Time
+	time.AfterFunc(20*time.Millisecond, func() {
+		cancelledAt = time.Now()
+		cancel()
+	})
+	start := time.Now()
+	result, err := parser.ParseHTML(ctx, content, "https://example.com")
+	stopped := time.Now()
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, result)
+	assert.Less(t, stopped.Sub(cancelledAt), 100*time.Millisecond, "returned %s after the cancel, %s into the parse",
+		stopped.Sub(cancelledAt), stopped.Sub(start))
+}
+
+func TestHTMLParser_TraverseStopsWhenCancelled(t *testing.T) {
+	parser := NewHTMLParser(nil)
+	doc, _, err := parseDocument(context.Background(), largeDocument(t, 1<<20), DefaultMaxDepth)
+	require.NoError(t, err)
+	base, _ := url.Parse("https://example.com")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := &models.ParsedHTML{Headings: make(map[string][]string)}
+	err = parser.traverse(ctx, doc, base, result, &models.ParseTruncation{})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, len(result.Links), cancelCheckInterval, "the traversal stopped at its first check")
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/idn"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// displayURL is the DisplayURL of a result for the page at pageURL: the URL
+// with its host in Unicode form, or "" when the host is not an
+// internationalized one
+func displayURL(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil || !idn.IsIDN(u.Hostname()) {
+		return ""
+	}
+	return idn.UnicodeURL(pageURL)
+}
+
+// idnHosts lists the internationalized hosts of the page and its links in
+// both forms, each once, sorted by the ASCII form
+func idnHosts(pageURL, finalURL string, links []models.Link) []models.IDNHost {
+	seen := make(map[string]bool)
+	var hosts []models.IDNHost
+	add := func(rawURL string) {
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Hostname() == "" || !idn.IsIDN(u.Hostname()) {
+			return
+		}
+		ascii := idn.ASCIIHost(u.Hostname())
+		if seen[ascii] {
+			return
+		}
+		seen[ascii] = true
+		hosts = append(hosts, models.IDNHost{Unicode: idn.UnicodeHost(ascii), ASCII: ascii})
+	}
+
+	add(pageURL)
+	add(finalURL)
+	for _, link := range links {
+		add(link.URL)
+	}
+	slices.SortFunc(hosts, func(a, b models.IDNHost) int { return cmp.Compare(a.ASCII, b.ASCII) })
+	return hosts
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const idnTestPage = `<!DOCTYPE html><html><head><title>Bücher</title></head><body>
+<a href="/katalog">Katalog</a>
+<a href="https://bücher.example/neu">Neu</a>
+<a href="https://xn--bcher-kva.example/neu">Neu</a>
+<a href="https://straße.example/">Straße</a>
+<a href="https://example.com/">Example</a>
+</body></html>`
+
+func TestAnalyzer_IDNHosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		display string
+	}{
+		{name: "unicode", url: "https://bücher.example/", display: "https://bücher.example/"},
+		{name: "ascii", url: "https://xn--bcher-kva.example/", display: "https://bücher.example/"},
+		{name: "mixed case", url: "https://BÜCHER.example/katalog?q=1", display: "https://bücher.example/katalog?q=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer, _ := newCoalescingTestAnalyzer(t, &countingHTTPClient{body: []byte(idnTestPage)})
+
+			result, err := analyzer.AnalyzeURL(context.Background(), tt.url)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.url, result.URL, "the URL is echoed as requested")
+			assert.Equal(t, tt.display, result.DisplayURL)
+			assert.Equal(t, []models.IDNHost{
+				{Unicode: "bücher.example", ASCII: "xn--bcher-kva.example"},
+				{Unicode: "straße.example", ASCII: "xn--strae-oqa.example"},
+			}, result.IDNHosts)
+			assert.Equal(t, 3, result.Links.Internal, "both forms of the page's host are internal")
+			assert.Equal(t, 2, result.Links.External)
+		})
+	}
+}
+
+func TestAnalyzer_IDNHosts_PlainHost(t *testing.T) {
+	analyzer, _ := newCoalescingTestAnalyzer(t, &countingHTTPClient{body: []byte(`<a href="/a">a</a>`)})
+
+	result, err := analyzer.AnalyzeURL(context.Background(), "https://example.com/")
+	require.NoError(t, err)
+	assert.Empty(t, result.DisplayURL)
+	assert.Nil(t, result.IDNHosts)
+}
+
+func TestAnalyzer_IDNHosts_CoalescesForms(t *testing.T) {
+	httpClient := &countingHTTPClient{delay: 100 * time.Millisecond, body: []byte(idnTestPage)}
+	analyzer, mockMetrics := newCoalescingTestAnalyzer(t, httpClient)
+	mockMetrics.EXPECT().RecordCoalescedAnalysis().MinTimes(1)
+
+	urls := []string{"https://bücher.example/", "https://xn--bcher-kva.example/"}
+	results := make([]*models.AnalysisResult, len(urls))
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			results[i], err = analyzer.AnalyzeURL(context.Background(), url)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&httpClient.calls), "both forms are one page")
+	for i, result := range results {
+		require.NotNil(t, result)
+		assert.Equal(t, urls[i], result.URL)
+		assert.Equal(t, "https://bücher.example/", result.DisplayURL)
+	}
+}
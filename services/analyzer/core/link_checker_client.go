@@ -4,35 +4,75 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/budget"
+	"github.com/RuvinSL/webpage-analyzer/pkg/contextkeys"
+	"github.com/RuvinSL/webpage-analyzer/pkg/faults"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/internalhttp"
+	"github.com/RuvinSL/webpage-analyzer/pkg/linkcheck"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/trace"
+	"github.com/RuvinSL/webpage-analyzer/pkg/traffic"
 )
 
+// ErrStreamCutOff is returned by StreamLinks, together with the statuses
+// received so far, when the stream ends before every link was reported
+var ErrStreamCutOff = errors.New("link status stream cut off")
+
 type LinkCheckerClient struct {
 	baseURL    string
 	httpClient *http.Client
-	logger     interfaces.Logger
+	// transport is the HTTP/1.1 transport, used unless h2c is enabled
+	transport *http.Transport
+	h2c       bool
+	faults    *faults.Injector
+	logger    interfaces.Logger
 }
 
 func NewLinkCheckerClient(baseURL string, timeout time.Duration, logger interfaces.Logger) *LinkCheckerClient {
+	transport := &http.Transport{
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     60 * time.Second,
+	}
 	return &LinkCheckerClient{
 		baseURL: baseURL,
 		httpClient: &http.Client{
-			Timeout: timeout,
-			Transport: &http.Transport{
-				MaxIdleConns:        10,
-				MaxIdleConnsPerHost: 10,
-				IdleConnTimeout:     60 * time.Second,
-			},
+			Timeout:   timeout,
+			Transport: transport,
 		},
-		logger: logger,
+		transport: transport,
+		logger:    logger,
 	}
 }
 
+// SetH2C makes the calls to an http:// link checker use HTTP/2 over
+// cleartext, which the link checker has to serve as well. Concurrent
+// analyses then share one connection instead of opening one each.
+func (c *LinkCheckerClient) SetH2C(enabled bool) {
+	c.h2c = enabled
+	c.setTransport()
+}
+
+// SetFaults injects the faults of injector's link-checker rules into the
+// calls
+func (c *LinkCheckerClient) SetFaults(injector *faults.Injector) {
+	c.faults = injector
+	c.setTransport()
+}
+
+func (c *LinkCheckerClient) setTransport() {
+	c.httpClient.Transport = c.faults.Transport(faults.LinkChecker, internalhttp.Transport(c.baseURL, c.h2c, c.transport))
+}
+
 func (c *LinkCheckerClient) CheckLinks(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
 	if len(links) == 0 {
 		return []models.LinkStatus{}, nil
@@ -40,28 +80,9 @@ func (c *LinkCheckerClient) CheckLinks(ctx context.Context, links []models.Link)
 
 	c.logger.Debug("Checking links via link checker service", "count", len(links))
 
-	// Prepare request body
-	requestBody := struct {
-		Links []models.Link `json:"links"`
-	}{
-		Links: links,
-	}
-
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/check", bytes.NewReader(jsonData))
+	req, err := c.newBatchRequest(ctx, "/check", links)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	// Add request ID from context if available
-	if requestID, ok := ctx.Value("request_id").(string); ok {
-		req.Header.Set("X-Request-ID", requestID)
+		return nil, err
 	}
 
 	// Send request
@@ -90,17 +111,146 @@ func (c *LinkCheckerClient) CheckLinks(ctx context.Context, links []models.Link)
 	// Parse response
 	var result struct {
 		LinkStatuses []models.LinkStatus `json:"link_statuses"`
+		BudgetUsed   *models.BudgetUsage `json:"budget_used"`
+		Trace        *models.DebugTrace  `json:"trace"`
+		// BytesDownloaded is absent from services predating it, counting
+		// nothing
+		BytesDownloaded int64 `json:"bytes_downloaded"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("failed to parse link checker response: %w", err)
 	}
 
+	// The service spent part of this analysis' budget on our behalf
+	if b := budget.FromContext(ctx); b != nil && result.BudgetUsed != nil {
+		b.Merge(*result.BudgetUsed)
+	}
+	if t := trace.FromContext(ctx); t != nil && result.Trace != nil {
+		t.Merge(*result.Trace, "link-checker")
+	}
+	traffic.FromContext(ctx).Add(result.BytesDownloaded)
+
 	return result.LinkStatuses, nil
 }
 
+// StreamLinks checks links through the streaming endpoint and passes each
+// status to onStatus as it arrives, in completion order. onStatus may be nil.
+// When ctx or the client timeout ends the stream before every link has been
+// reported, the statuses received so far are returned along with an error
+// wrapping ErrStreamCutOff, so callers can still use the partial results.
+// A budget in ctx is granted to the service, but the stream does not report
+// what was spent or downloaded, nor trace the requests made.
+func (c *LinkCheckerClient) StreamLinks(ctx context.Context, links []models.Link, onStatus func(models.LinkStatus)) ([]models.LinkStatus, error) {
+	if len(links) == 0 {
+		return []models.LinkStatus{}, nil
+	}
+
+	c.logger.Debug("Streaming link checks from link checker service", "count", len(links))
+
+	req, err := c.newBatchRequest(ctx, "/check/stream", links)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.logger.Error("Failed to call link checker service", "error", err, "duration", time.Since(start))
+		return nil, fmt.Errorf("link checker service error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResp models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errorResp); err != nil {
+			return nil, fmt.Errorf("link checker service returned status %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("%s", errorResp.Error)
+	}
+
+	statuses := make([]models.LinkStatus, 0, len(links))
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var status models.LinkStatus
+		err := decoder.Decode(&status)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			c.logger.Warn("Link status stream cut off, using partial results",
+				"received", len(statuses),
+				"expected", len(links),
+				"duration", time.Since(start),
+				"error", err,
+			)
+			return statuses, fmt.Errorf("%w after %d of %d statuses: %w", ErrStreamCutOff, len(statuses), len(links), err)
+		}
+
+		statuses = append(statuses, status)
+		if onStatus != nil {
+			onStatus(status)
+		}
+	}
+
+	if len(statuses) < len(links) {
+		return statuses, fmt.Errorf("%w: service sent %d of %d statuses", ErrStreamCutOff, len(statuses), len(links))
+	}
+
+	c.logger.Debug("Link status stream completed", "count", len(statuses), "duration", time.Since(start))
+	return statuses, nil
+}
+
+// newBatchRequest builds a POST of links to the given link checker path,
+// granting the service whatever is left of the budget in ctx, asking it
+// to trace as many requests as the trace in ctx still keeps and passing on
+// the link check options and host overrides in ctx
+func (c *LinkCheckerClient) newBatchRequest(ctx context.Context, path string, links []models.Link) (*http.Request, error) {
+	requestBody := struct {
+		Links         []models.Link            `json:"links"`
+		Budget        *models.BudgetLimits     `json:"budget,omitempty"`
+		TraceLimit    *int                     `json:"trace_limit,omitempty"`
+		Options       *models.LinkCheckOptions `json:"options,omitempty"`
+		HostOverrides map[string]string        `json:"host_overrides,omitempty"`
+	}{
+		Links:         links,
+		HostOverrides: httpclient.HostOverridesFromContext(ctx),
+	}
+	if opts, ok := linkcheck.FromContext(ctx); ok {
+		requestBody.Options = &opts
+	}
+	if b := budget.FromContext(ctx); b != nil {
+		remaining := b.Remaining()
+		requestBody.Budget = &remaining
+	}
+	if t := trace.FromContext(ctx); t != nil {
+		remaining := t.Remaining()
+		requestBody.TraceLimit = &remaining
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Add request ID from context if available
+	if requestID := contextkeys.RequestIDFrom(ctx); requestID != "" {
+		req.Header.Set(contextkeys.RequestIDHeader, requestID)
+	}
+	return req, nil
+}
+
 // CheckLink checks a single link
 func (c *LinkCheckerClient) CheckLink(ctx context.Context, link models.Link) models.LinkStatus {
-	c.logger.Debug("Checking single link via link checker service", "url", link.URL)
+	c.logger.Debug("Checking single link via link checker service", "url", logger.RedactURL(link.URL))
 
 	requestBody := struct {
 		Link models.Link `json:"link"`
@@ -0,0 +1,263 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/budget"
+	"github.com/RuvinSL/webpage-analyzer/pkg/contextkeys"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
+	"github.com/RuvinSL/webpage-analyzer/pkg/linkcheck"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStreamServer answers /check/stream with one status per link, each sent
+// after a tick. With stallAfter >= 0 it goes quiet after that many statuses
+// until the client gives up.
+func newStreamServer(t *testing.T, tick <-chan struct{}, stallAfter int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/check/stream", r.URL.Path)
+
+		var req struct {
+			Links []models.Link `json:"links"`
+		}
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&req)) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		rc := http.NewResponseController(w)
+		rc.Flush()
+
+		encoder := json.NewEncoder(w)
+		for i, link := range req.Links {
+			if i == stallAfter {
+				<-r.Context().Done()
+				return
+			}
+			select {
+			case <-tick:
+			case <-r.Context().Done():
+				return
+			}
+			encoder.Encode(models.LinkStatus{Link: link, Accessible: true, StatusCode: http.StatusOK})
+			rc.Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestLinkCheckerClient(baseURL string, timeout time.Duration) *LinkCheckerClient {
+	log := newTestLogger()
+	return NewLinkCheckerClient(baseURL, timeout, log)
+}
+
+func streamLinks(n int) []models.Link {
+	links := make([]models.Link, n)
+	for i := range links {
+		links[i] = models.Link{URL: "https://example.com/" + string(rune('a'+i)), Type: models.LinkTypeExternal}
+	}
+	return links
+}
+
+func TestLinkCheckerClient_StreamLinks_Complete(t *testing.T) {
+	tick := make(chan struct{})
+	close(tick)
+	server := newStreamServer(t, tick, -1)
+	client := newTestLinkCheckerClient(server.URL, 5*time.Second)
+
+	links := streamLinks(3)
+	var seen []string
+	statuses, err := client.StreamLinks(context.Background(), links, func(status models.LinkStatus) {
+		seen = append(seen, status.Link.URL)
+	})
+
+	require.NoError(t, err)
+	assert.Len(t, statuses, 3)
+	assert.Equal(t, []string{links[0].URL, links[1].URL, links[2].URL}, seen)
+}
+
+func TestLinkCheckerClient_StreamLinks_CancelKeepsPartialResults(t *testing.T) {
+	tick := make(chan struct{})
+	server := newStreamServer(t, tick, -1)
+	client := newTestLinkCheckerClient(server.URL, 5*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	links := streamLinks(5)
+	received := make(chan models.LinkStatus, len(links))
+	done := make(chan struct{})
+	var statuses []models.LinkStatus
+	var err error
+	go func() {
+		defer close(done)
+		statuses, err = client.StreamLinks(ctx, links, func(status models.LinkStatus) {
+			received <- status
+		})
+	}()
+
+	// Let two statuses through, then give up on the rest
+	for range 2 {
+		tick <- struct{}{}
+		<-received
+	}
+	cancel()
+	<-done
+
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrStreamCutOff))
+	assert.True(t, errors.Is(err, context.Canceled))
+	require.Len(t, statuses, 2)
+	assert.Equal(t, links[0].URL, statuses[0].Link.URL)
+	assert.Equal(t, links[1].URL, statuses[1].Link.URL)
+}
+
+func TestLinkCheckerClient_StreamLinks_ClientTimeoutKeepsPartialResults(t *testing.T) {
+	tick := make(chan struct{})
+	close(tick)
+	server := newStreamServer(t, tick, 2)
+	client := newTestLinkCheckerClient(server.URL, 200*time.Millisecond)
+
+	statuses, err := client.StreamLinks(context.Background(), streamLinks(4), nil)
+
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrStreamCutOff))
+	assert.Len(t, statuses, 2)
+}
+
+func TestLinkCheckerClient_StreamLinks_ShortStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		json.NewEncoder(w).Encode(models.LinkStatus{Link: models.Link{URL: "https://example.com/a"}, Accessible: true})
+	}))
+	defer server.Close()
+	client := newTestLinkCheckerClient(server.URL, 5*time.Second)
+
+	statuses, err := client.StreamLinks(context.Background(), streamLinks(2), nil)
+
+	assert.ErrorIs(t, err, ErrStreamCutOff)
+	assert.Len(t, statuses, 1)
+}
+
+func TestLinkCheckerClient_StreamLinks_RejectedBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(models.ErrorResponse{Error: "Too many links", StatusCode: http.StatusRequestEntityTooLarge})
+	}))
+	defer server.Close()
+	client := newTestLinkCheckerClient(server.URL, 5*time.Second)
+
+	statuses, err := client.StreamLinks(context.Background(), streamLinks(2), nil)
+
+	require.EqualError(t, err, "Too many links")
+	assert.Nil(t, statuses)
+}
+
+func TestLinkCheckerClient_CheckLinks_SharesBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Links  []models.Link        `json:"links"`
+			Budget *models.BudgetLimits `json:"budget"`
+		}
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&req)) || !assert.NotNil(t, req.Budget) {
+			return
+		}
+		// The page fetch already spent 1 request and 100 bytes
+		assert.Equal(t, models.BudgetLimits{MaxRequests: 2, MaxBytes: 900}, *req.Budget)
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"link_statuses": []models.LinkStatus{
+				{Link: req.Links[0], Accessible: true, StatusCode: http.StatusOK},
+				{Link: req.Links[1], Accessible: true, StatusCode: http.StatusOK},
+				{Link: req.Links[2], Skipped: true, Error: budget.SkippedError},
+			},
+			"budget_used": models.BudgetUsage{Requests: 2, Bytes: 300, Exhausted: true},
+		})
+	}))
+	defer server.Close()
+
+	spend := budget.New(3, 1000)
+	require.True(t, spend.TryRequest())
+	spend.AddBytes(100)
+
+	client := newTestLinkCheckerClient(server.URL, 5*time.Second)
+	statuses, err := client.CheckLinks(budget.WithBudget(context.Background(), spend), streamLinks(3))
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	assert.True(t, statuses[2].Skipped)
+
+	usage := spend.Usage()
+	assert.Equal(t, int64(3), usage.Requests)
+	assert.Equal(t, int64(400), usage.Bytes)
+	assert.True(t, usage.Exhausted)
+}
+
+func TestLinkCheckerClient_CheckLinks_ForwardsRequestID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "req-42", r.Header.Get(contextkeys.RequestIDHeader))
+		json.NewEncoder(w).Encode(map[string]any{"link_statuses": []models.LinkStatus{}})
+	}))
+	defer server.Close()
+
+	client := newTestLinkCheckerClient(server.URL, 5*time.Second)
+	_, err := client.CheckLinks(contextkeys.WithRequestID(context.Background(), "req-42"), streamLinks(1))
+	require.NoError(t, err)
+}
+
+func TestLinkCheckerClient_CheckLinks_ForwardsOptions(t *testing.T) {
+	options := make(chan *models.LinkCheckOptions, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Options *models.LinkCheckOptions `json:"options"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		options <- req.Options
+		json.NewEncoder(w).Encode(map[string]any{"link_statuses": []models.LinkStatus{}})
+	}))
+	defer server.Close()
+
+	client := newTestLinkCheckerClient(server.URL, 5*time.Second)
+	_, err := client.CheckLinks(context.Background(), streamLinks(1))
+	require.NoError(t, err)
+	assert.Nil(t, <-options)
+
+	want := models.LinkCheckOptions{Method: models.LinkCheckHead, Retries: 1, AcceptableStatusCodes: []int{403}}
+	_, err = client.CheckLinks(linkcheck.WithOptions(context.Background(), want), streamLinks(1))
+	require.NoError(t, err)
+	assert.Equal(t, &want, <-options)
+}
+
+func TestLinkCheckerClient_CheckLinks_ForwardsHostOverrides(t *testing.T) {
+	overrides := make(chan map[string]string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			HostOverrides map[string]string `json:"host_overrides"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		overrides <- req.HostOverrides
+		json.NewEncoder(w).Encode(map[string]any{"link_statuses": []models.LinkStatus{}})
+	}))
+	defer server.Close()
+
+	client := newTestLinkCheckerClient(server.URL, 5*time.Second)
+	_, err := client.CheckLinks(context.Background(), streamLinks(1))
+	require.NoError(t, err)
+	assert.Nil(t, <-overrides)
+
+	ctx := httpclient.WithHostOverrides(context.Background(), map[string]string{"Staging.example.com": "10.0.0.5"})
+	_, err = client.CheckLinks(ctx, streamLinks(1))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"staging.example.com": "10.0.0.5"}, <-overrides)
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// maxFindingURLs caps the URLs listed per link finding
+const maxFindingURLs = 20
+
+// linkFindings counts the external links marked nofollow, sponsored or ugc
+// and the links opening in a new tab, and flags those that do so without
+// rel="noopener" or "noreferrer". It returns nil when there is nothing to
+// report.
+func linkFindings(links []models.Link) *models.LinkFindings {
+	var findings models.LinkFindings
+	missingNoopener := models.LinkFinding{Kind: models.LinkMissingNoopener}
+
+	for _, link := range links {
+		if link.Type == models.LinkTypeExternal {
+			if slices.Contains(link.Rel, "nofollow") {
+				findings.NofollowExternal++
+			}
+			if slices.Contains(link.Rel, "sponsored") {
+				findings.SponsoredExternal++
+			}
+			if slices.Contains(link.Rel, "ugc") {
+				findings.UGCExternal++
+			}
+		}
+
+		if !strings.EqualFold(link.Target, "_blank") {
+			continue
+		}
+		findings.NewTab++
+		if slices.Contains(link.Rel, "noopener") || slices.Contains(link.Rel, "noreferrer") {
+			continue
+		}
+		missingNoopener.Count++
+		if len(missingNoopener.URLs) < maxFindingURLs && !slices.Contains(missingNoopener.URLs, link.URL) {
+			missingNoopener.URLs = append(missingNoopener.URLs, link.URL)
+		}
+	}
+
+	if missingNoopener.Count > 0 {
+		findings.Findings = append(findings.Findings, missingNoopener)
+	}
+	if findings.NofollowExternal == 0 && findings.SponsoredExternal == 0 && findings.UGCExternal == 0 && findings.NewTab == 0 {
+		return nil
+	}
+	return &findings
+}
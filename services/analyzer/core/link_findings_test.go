@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkFindings(t *testing.T) {
+	external := func(url string, target string, rel ...string) models.Link {
+		return models.Link{URL: url, Type: models.LinkTypeExternal, Target: target, Rel: rel}
+	}
+	internal := func(url string, target string, rel ...string) models.Link {
+		return models.Link{URL: url, Type: models.LinkTypeInternal, Target: target, Rel: rel}
+	}
+
+	findings := linkFindings([]models.Link{
+		external("https://ads.example/", "", "sponsored", "nofollow"),
+		external("https://forum.example/post", "", "ugc"),
+		external("https://partner.example/", "_blank", "noopener"),
+		external("https://news.example/", "_BLANK"),
+		external("https://news.example/", "_blank", "nofollow"),
+		internal("https://example.com/terms", "_blank", "noreferrer"),
+		internal("https://example.com/help", "_blank", "external"),
+		internal("https://example.com/login", "", "nofollow"),
+		internal("https://example.com/frame", "content"),
+	})
+
+	assert.Equal(t, &models.LinkFindings{
+		NofollowExternal:  2,
+		SponsoredExternal: 1,
+		UGCExternal:       1,
+		NewTab:            5,
+		Findings: []models.LinkFinding{{
+			Kind:  models.LinkMissingNoopener,
+			Count: 3,
+			URLs:  []string{"https://news.example/", "https://example.com/help"},
+		}},
+	}, findings)
+}
+
+func TestLinkFindings_URLsAreCapped(t *testing.T) {
+	var links []models.Link
+	for i := range maxFindingURLs + 5 {
+		links = append(links, models.Link{URL: fmt.Sprintf("https://example.com/%d", i), Target: "_blank"})
+	}
+
+	findings := linkFindings(links)
+	require.NotNil(t, findings)
+	require.Len(t, findings.Findings, 1)
+	assert.Equal(t, maxFindingURLs+5, findings.Findings[0].Count)
+	assert.Len(t, findings.Findings[0].URLs, maxFindingURLs)
+}
+
+func TestLinkFindings_NothingToReport(t *testing.T) {
+	assert.Nil(t, linkFindings(nil))
+	assert.Nil(t, linkFindings([]models.Link{
+		{URL: "https://example.com/", Type: models.LinkTypeInternal, Rel: []string{"nofollow"}},
+		{URL: "https://other.example/", Type: models.LinkTypeExternal, Rel: []string{"me"}, Target: "_self"},
+	}))
+}
+
+func TestAnalyzer_LinkFindings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<html><head><title>Links</title></head><body>
+<a href="/about" target="_blank">About</a>
+<a href="https://ads.example/" rel="sponsored nofollow" target="_blank">Ad</a>
+</body></html>`)
+	}))
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, nil, staticLinkChecker{})
+
+	result, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	assert.Equal(t, &models.LinkFindings{
+		NofollowExternal:  1,
+		SponsoredExternal: 1,
+		NewTab:            2,
+		Findings: []models.LinkFinding{
+			{Kind: models.LinkMissingNoopener, Count: 2, URLs: []string{server.URL + "/about", "https://ads.example/"}},
+		},
+	}, result.LinkFindings)
+}
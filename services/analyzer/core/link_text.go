@@ -0,0 +1,85 @@
+package core
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// SetGenericLinkTexts sets the link texts reported as generic, such as
+// "click here"; case, spacing and surrounding punctuation do not matter.
+// None are reported until it is called.
+func (a *Analyzer) SetGenericLinkTexts(texts []string) {
+	a.genericLinkTexts = make(map[string]bool, len(texts))
+	for _, text := range texts {
+		if normalized := normalizeLinkText(text); normalized != "" {
+			a.genericLinkTexts[normalized] = true
+		}
+	}
+}
+
+// normalizeLinkText folds a link text for comparison: lowercased, without
+// the punctuation and symbols around it, such as the » or … of "Read more »"
+func normalizeLinkText(text string) string {
+	return strings.ToLower(strings.TrimFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}))
+}
+
+// accessibilityReport flags the links with an empty text, with a generic
+// one, and with a text shared with links to other URLs. A generic text is
+// only reported as generic, although it usually leads to several URLs too.
+// It returns nil when there is nothing to report.
+func accessibilityReport(links []models.Link, generic map[string]bool) *models.AccessibilityReport {
+	empty := models.LinkFinding{Kind: models.LinkTextEmpty}
+	genericText := models.LinkFinding{Kind: models.LinkTextGeneric}
+	ambiguous := models.LinkFinding{Kind: models.LinkTextAmbiguous}
+
+	// The URLs each text leads to, to find the texts leading to several
+	targets := make(map[string][]string)
+	for _, link := range links {
+		text := normalizeLinkText(link.Text)
+		if text != "" && !generic[text] && !slices.Contains(targets[text], link.URL) {
+			targets[text] = append(targets[text], link.URL)
+		}
+	}
+
+	for _, link := range links {
+		text := normalizeLinkText(link.Text)
+		switch {
+		case text == "":
+			addFinding(&empty, link.URL, "")
+		case generic[text]:
+			addFinding(&genericText, link.URL, link.Text)
+		case len(targets[text]) > 1:
+			addFinding(&ambiguous, link.URL, link.Text)
+		}
+	}
+
+	var report models.AccessibilityReport
+	for _, finding := range []models.LinkFinding{empty, genericText, ambiguous} {
+		if finding.Count > 0 {
+			report.LinkText = append(report.LinkText, finding)
+		}
+	}
+	if report.LinkText == nil {
+		return nil
+	}
+	return &report
+}
+
+// addFinding counts a link in finding and lists its URL and text, each once
+// and up to the cap
+func addFinding(finding *models.LinkFinding, url, text string) {
+	finding.Count++
+	if len(finding.URLs) < maxFindingURLs && !slices.Contains(finding.URLs, url) {
+		finding.URLs = append(finding.URLs, url)
+	}
+	if text != "" && len(finding.Texts) < maxFindingURLs && !slices.ContainsFunc(finding.Texts, func(listed string) bool {
+		return normalizeLinkText(listed) == normalizeLinkText(text)
+	}) {
+		finding.Texts = append(finding.Texts, text)
+	}
+}
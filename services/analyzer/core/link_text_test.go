@@ -0,0 +1,102 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessibilityReport(t *testing.T) {
+	analyzer := &Analyzer{}
+	analyzer.SetGenericLinkTexts([]string{"Click here", " read more ", "…"})
+
+	link := func(url, text string) models.Link {
+		return models.Link{URL: url, Text: text, Type: models.LinkTypeInternal}
+	}
+
+	report := accessibilityReport([]models.Link{
+		link("https://example.com/a", "Pricing"),
+		link("https://example.com/icon", ""),
+		link("https://example.com/icon", "  "),
+		link("https://example.com/posts/1", "Read more »"),
+		link("https://example.com/posts/2", "READ MORE"),
+		link("https://example.com/signup", "click here!"),
+		link("https://example.com/docs/v1", "Documentation"),
+		link("https://example.com/docs/v2", "documentation."),
+		link("https://example.com/docs/v2", "Documentation"),
+		link("https://example.com/a", "pricing"),
+	}, analyzer.genericLinkTexts)
+
+	assert.Equal(t, &models.AccessibilityReport{
+		LinkText: []models.LinkFinding{
+			{Kind: models.LinkTextEmpty, Count: 2, URLs: []string{"https://example.com/icon"}},
+			{
+				Kind:  models.LinkTextGeneric,
+				Count: 3,
+				URLs:  []string{"https://example.com/posts/1", "https://example.com/posts/2", "https://example.com/signup"},
+				Texts: []string{"Read more »", "click here!"},
+			},
+			{
+				Kind:  models.LinkTextAmbiguous,
+				Count: 3,
+				URLs:  []string{"https://example.com/docs/v1", "https://example.com/docs/v2"},
+				Texts: []string{"Documentation"},
+			},
+		},
+	}, report)
+}
+
+func TestAccessibilityReport_URLsAreCapped(t *testing.T) {
+	var links []models.Link
+	for i := range maxFindingURLs + 5 {
+		links = append(links, models.Link{URL: fmt.Sprintf("https://example.com/%d", i), Text: "Next"})
+	}
+
+	report := accessibilityReport(links, nil)
+	require.NotNil(t, report)
+	require.Len(t, report.LinkText, 1)
+	assert.Equal(t, models.LinkTextAmbiguous, report.LinkText[0].Kind)
+	assert.Equal(t, maxFindingURLs+5, report.LinkText[0].Count)
+	assert.Len(t, report.LinkText[0].URLs, maxFindingURLs)
+	assert.Equal(t, []string{"Next"}, report.LinkText[0].Texts)
+}
+
+func TestAccessibilityReport_NothingToReport(t *testing.T) {
+	assert.Nil(t, accessibilityReport(nil, nil))
+	assert.Nil(t, accessibilityReport([]models.Link{
+		{URL: "https://example.com/", Text: "Home"},
+		{URL: "https://example.com/", Text: "home"},
+		{URL: "https://example.com/about", Text: "About us"},
+	}, map[string]bool{"click here": true}))
+}
+
+func TestAnalyzer_AccessibilityReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<html><head><title>Links</title></head><body>
+<a href="/about">About</a>
+<a href="/post"><img src="/arrow.svg"></a>
+<a href="/post/1">Click here</a>
+</body></html>`)
+	}))
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, nil, staticLinkChecker{})
+	analyzer.SetGenericLinkTexts([]string{"click here"})
+
+	result, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	assert.Equal(t, &models.AccessibilityReport{
+		LinkText: []models.LinkFinding{
+			{Kind: models.LinkTextEmpty, Count: 1, URLs: []string{server.URL + "/post"}},
+			{Kind: models.LinkTextGeneric, Count: 1, URLs: []string{server.URL + "/post/1"}, Texts: []string{"Click here"}},
+		},
+	}, result.Accessibility)
+}
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"context"
+	"slices"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/crosspage"
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// DefaultPaginationHops caps the next links followed per analysis
+const DefaultPaginationHops = 10
+
+// SetPaginationHops caps the next links followed when a request asks for
+// the series of the page to be counted
+func (a *Analyzer) SetPaginationHops(n int) {
+	if n > 0 {
+		a.paginationHops = n
+	}
+}
+
+// paginationPosition is where parsed sits in its series, or "" when it
+// declares none
+func paginationPosition(parsed *models.ParsedHTML) string {
+	switch {
+	case parsed.NextURL != "" && parsed.PrevURL != "":
+		return models.PaginationMiddle
+	case parsed.NextURL != "":
+		return models.PaginationFirst
+	case parsed.PrevURL != "":
+		return models.PaginationLast
+	}
+	return ""
+}
+
+// checkPagination places a page in the series it declares and checks that
+// its next and previous pages can be reached. With follow set, the chain
+// of next links is followed from a reachable next page to count the pages
+// of the series.
+func (a *Analyzer) checkPagination(ctx context.Context, pageURL, finalURL string, fetcher interfaces.FetcherStrategy, parsed *models.ParsedHTML, follow bool) *models.PaginationReport {
+	report := &models.PaginationReport{
+		Position: paginationPosition(parsed),
+		NextURL:  parsed.NextURL,
+		PrevURL:  parsed.PrevURL,
+	}
+
+	page := pageAddresses(pageURL, finalURL)
+	pageHost := hostOf(pageURL)
+	var links []models.Link
+	kinds := map[string]string{} // URL to the finding kind if it is unreachable
+	for _, target := range []struct{ url, kind, text string }{
+		{parsed.NextURL, models.PaginationUnreachableNext, "next"},
+		{parsed.PrevURL, models.PaginationUnreachablePrev, "prev"},
+	} {
+		switch {
+		case target.url == "":
+		case slices.Contains(page, crosspage.NormalizeURL(target.url)):
+			report.Findings = append(report.Findings, models.PaginationFinding{Kind: models.PaginationSelfReference, URL: target.url})
+		case kinds[target.url] == "":
+			kinds[target.url] = target.kind
+			link := models.Link{URL: target.url, Text: target.text, Type: models.LinkTypeExternal}
+			if hostOf(target.url) == pageHost {
+				link.Type = models.LinkTypeInternal
+			}
+			links = append(links, link)
+		}
+	}
+	if len(links) == 0 {
+		return report
+	}
+
+	statuses, err := a.linkChecker.CheckLinks(ctx, links)
+	if err != nil {
+		a.logger.Warn("Failed to check the next and previous pages", "error", err)
+	}
+	// Pages skipped for lack of budget were not checked and are not
+	// reported
+	nextReachable := false
+	for _, status := range statuses {
+		kind, ok := kinds[status.Link.URL]
+		switch {
+		case !ok || status.Skipped:
+		case status.Accessible:
+			nextReachable = nextReachable || status.Link.URL == parsed.NextURL
+		default:
+			report.Findings = append(report.Findings, models.PaginationFinding{Kind: kind, URL: status.Link.URL, Detail: status.Error})
+		}
+	}
+
+	if follow && nextReachable {
+		report.SeriesLength, report.SeriesTruncated = a.followPagination(ctx, page, fetcher, parsed.NextURL, report)
+	}
+	return report
+}
+
+// followPagination fetches the pages of the chain of next links starting at
+// next, up to the analyzer's hop limit, and returns how many pages the
+// series has from the page on and whether the limit cut the count short. A
+// loop back into the chain and a page that cannot be fetched end the chain
+// and are reported.
+func (a *Analyzer) followPagination(ctx context.Context, page []string, fetcher interfaces.FetcherStrategy, next string, report *models.PaginationReport) (length int, truncated bool) {
+	visited := slices.Clone(page)
+	length = 1
+	for hops := 0; next != ""; hops++ {
+		if hops == a.paginationHops {
+			return length, true
+		}
+		normalized := crosspage.NormalizeURL(next)
+		if slices.Contains(visited, normalized) {
+			report.Findings = append(report.Findings, models.PaginationFinding{Kind: models.PaginationChainLoop, URL: next})
+			return length, false
+		}
+		visited = append(visited, normalized)
+
+		response, err := fetcher.Fetch(ctx, next)
+		var parsed *models.ParsedHTML
+		if err == nil {
+			parsed, err = a.htmlParser.ParseHTML(ctx, response.Body, next)
+		}
+		if ctx.Err() != nil {
+			return length, true
+		}
+		if err != nil {
+			a.logger.Warn("Failed to fetch the next page of the series", "url", logger.RedactURL(next), "error", err)
+			report.Findings = append(report.Findings, models.PaginationFinding{Kind: models.PaginationChainBroken, URL: next, Detail: err.Error()})
+			return length, false
+		}
+		if final := crosspage.NormalizeURL(response.FinalURL); response.FinalURL != "" && final != normalized {
+			if slices.Contains(visited, final) {
+				report.Findings = append(report.Findings, models.PaginationFinding{Kind: models.PaginationChainLoop, URL: next})
+				return length, false
+			}
+			visited = append(visited, final)
+		}
+		length++
+		next = parsed.NextURL
+	}
+	return length, false
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPaginationServer serves a series of three pages, a page whose next
+// page is missing, a page that is its own next page, two pages whose next
+// links loop, a chain whose second page links to a missing page and a
+// series of twenty pages
+func newPaginationServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	page := func(head string) string {
+		return `<!DOCTYPE html><html><head><title>Page</title>` + head + `</head><body></body></html>`
+	}
+	documents := map[string]string{
+		"/series/1": page(`<link rel="next" href="/series/2">`),
+		"/series/2": page(`<link rel="prev" href="/series/1"><link rel="next" href="/series/3">`),
+		"/series/3": page(`<link rel="previous" href="/series/2">`),
+		"/broken":   page(`<link rel="next" href="/missing">`),
+		"/self":     page(`<link rel="prev" href="/series/1"><link rel="next" href="/self">`),
+		"/loop/1":   page(`<link rel="next" href="/loop/2">`),
+		"/loop/2":   page(`<link rel="prev" href="/loop/1"><link rel="next" href="/loop/1">`),
+		"/chain/1":  page(`<link rel="next" href="/chain/2">`),
+		"/chain/2":  page(`<link rel="prev" href="/chain/1"><link rel="next" href="/gone">`),
+		"/plain":    page(``),
+	}
+	for i := 1; i < 20; i++ {
+		documents[fmt.Sprintf("/long/%d", i)] = page(fmt.Sprintf(`<link rel="next" href="/long/%d">`, i+1))
+	}
+	documents["/long/20"] = page(`<link rel="prev" href="/long/19">`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		document, ok := documents[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, document)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_Pagination(t *testing.T) {
+	server := newPaginationServer(t)
+
+	tests := []struct {
+		path     string
+		expected *models.PaginationReport
+	}{
+		{
+			path:     "/series/1",
+			expected: &models.PaginationReport{Position: models.PaginationFirst, NextURL: server.URL + "/series/2"},
+		},
+		{
+			path: "/series/2",
+			expected: &models.PaginationReport{
+				Position: models.PaginationMiddle,
+				NextURL:  server.URL + "/series/3",
+				PrevURL:  server.URL + "/series/1",
+			},
+		},
+		{
+			path:     "/series/3",
+			expected: &models.PaginationReport{Position: models.PaginationLast, PrevURL: server.URL + "/series/2"},
+		},
+		{
+			path: "/broken",
+			expected: &models.PaginationReport{
+				Position: models.PaginationFirst,
+				NextURL:  server.URL + "/missing",
+				Findings: []models.PaginationFinding{{Kind: models.PaginationUnreachableNext, URL: server.URL + "/missing", Detail: "HTTP 404"}},
+			},
+		},
+		{
+			path: "/self",
+			expected: &models.PaginationReport{
+				Position: models.PaginationMiddle,
+				NextURL:  server.URL + "/self",
+				PrevURL:  server.URL + "/series/1",
+				Findings: []models.PaginationFinding{{Kind: models.PaginationSelfReference, URL: server.URL + "/self"}},
+			},
+		},
+		{path: "/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result, err := newTestAnalyzer(t, nil, newStatusLinkChecker()).AnalyzeURL(context.Background(), server.URL+tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Pagination)
+			// The next and previous pages are checked apart from the page's
+			// own links
+			assert.Zero(t, result.Links.Total)
+		})
+	}
+}
+
+func TestAnalyzer_Pagination_Follow(t *testing.T) {
+	server := newPaginationServer(t)
+
+	tests := []struct {
+		path      string
+		length    int
+		truncated bool
+		findings  []models.PaginationFinding
+	}{
+		{path: "/series/1", length: 3},
+		{path: "/series/2", length: 2},
+		{path: "/series/3"},
+		{path: "/broken"},
+		{
+			path:     "/loop/1",
+			length:   2,
+			findings: []models.PaginationFinding{{Kind: models.PaginationChainLoop, URL: server.URL + "/loop/1"}},
+		},
+		{
+			path:   "/chain/1",
+			length: 2,
+			findings: []models.PaginationFinding{{
+				Kind:   models.PaginationChainBroken,
+				URL:    server.URL + "/gone",
+				Detail: (&models.HTTPStatusError{StatusCode: http.StatusNotFound}).Error(),
+			}},
+		},
+		{path: "/long/1", length: 4, truncated: true},
+		{path: "/long/18", length: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			analyzer := newTestAnalyzer(t, nil, newStatusLinkChecker())
+			analyzer.SetPaginationHops(3)
+			result, err := analyzer.AnalyzeURLWithOptions(context.Background(), server.URL+tt.path, models.AnalysisOptions{FollowPagination: true})
+			require.NoError(t, err)
+			require.NotNil(t, result.Pagination)
+			assert.Equal(t, tt.length, result.Pagination.SeriesLength)
+			assert.Equal(t, tt.truncated, result.Pagination.SeriesTruncated)
+			var findings []models.PaginationFinding
+			for _, finding := range result.Pagination.Findings {
+				if !strings.HasPrefix(finding.Kind, "unreachable_") {
+					findings = append(findings, finding)
+				}
+			}
+			assert.Equal(t, tt.findings, findings)
+		})
+	}
+}
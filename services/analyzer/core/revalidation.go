@@ -0,0 +1,115 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// conditionalFetcher is implemented by fetchers that can revalidate a
+// previous fetch; HTTPFetcher is one, the headless renderer is not
+type conditionalFetcher interface {
+	FetchConditional(ctx context.Context, url string, validators models.Validators) (*models.HTTPResponse, error)
+}
+
+// revalidationEntry is what the result cache holds per URL: the validators of
+// the last full fetch, its parse, and the result built from it
+type revalidationEntry struct {
+	Validators models.Validators      `json:"validators"`
+	Parsed     *models.ParsedHTML     `json:"parsed"`
+	Result     *models.AnalysisResult `json:"result"`
+}
+
+// SetResultCache keeps the validators and parse of every fetched page in
+// cache for ttl, so the next analysis of the same URL sends a conditional
+// request. On 304 Not Modified the cached parse is reused and the links are
+// checked again, unless recheckLinks is false, in which case the cached link
+// summary is reused as well.
+func (a *Analyzer) SetResultCache(cache interfaces.Cache, ttl time.Duration, recheckLinks bool) {
+	a.cache = cache
+	a.cacheTTL = ttl
+	a.recheckLinks = recheckLinks
+}
+
+// revalidationKey is the cache key of url; each language variant of a page
+// is cached apart
+func (a *Analyzer) revalidationKey(url, language string) string {
+	key := "revalidate:" + a.coalesceKey(url)
+	if language != models.DefaultAcceptLanguage {
+		key += "|lang=" + language
+	}
+	return key
+}
+
+// fetch retrieves the page, revalidating cached when there is one and
+// revalidate is set. It returns the entry to reuse when the server answered
+// 304 Not Modified.
+func (a *Analyzer) fetch(ctx context.Context, url, language string, fetcher interfaces.FetcherStrategy, revalidate bool) (*models.HTTPResponse, *revalidationEntry, error) {
+	conditional, ok := fetcher.(conditionalFetcher)
+	if !ok || a.cache == nil || !revalidate {
+		response, err := fetcher.Fetch(ctx, url)
+		return response, nil, err
+	}
+
+	cached := a.loadEntry(ctx, url, language)
+	if cached == nil {
+		a.metrics.RecordCacheLookup(false)
+		response, err := fetcher.Fetch(ctx, url)
+		return response, nil, err
+	}
+
+	response, err := conditional.FetchConditional(ctx, url, cached.Validators)
+	if err != nil || response.StatusCode != http.StatusNotModified {
+		a.metrics.RecordCacheLookup(false)
+		return response, nil, err
+	}
+
+	a.metrics.RecordCacheLookup(true)
+	a.logger.Info("Page not modified, reusing cached parse", "url", logger.RedactURL(url))
+	return response, cached, nil
+}
+
+func (a *Analyzer) loadEntry(ctx context.Context, url, language string) *revalidationEntry {
+	data, err := a.cache.Get(ctx, a.revalidationKey(url, language))
+	if err != nil {
+		return nil
+	}
+
+	var entry revalidationEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Parsed == nil || entry.Result == nil {
+		a.logger.Warn("Discarding unreadable cache entry", "url", logger.RedactURL(url), "error", err)
+		return nil
+	}
+	return &entry
+}
+
+// storeEntry caches the parse and result of a fetch that carried validators.
+// Screenshots, timings and custom checks are specific to one analysis and
+// are not kept.
+func (a *Analyzer) storeEntry(ctx context.Context, url, language string, validators models.Validators, parsed *models.ParsedHTML, result *models.AnalysisResult) {
+	if a.cache == nil || validators.IsZero() {
+		return
+	}
+
+	kept := cloneResult(result)
+	kept.Screenshot = ""
+	kept.Timings = nil
+	kept.Checks = nil
+	kept.ChecksFailed = false
+	kept.StatusCode = 0
+	kept.ResponseHeaders = nil
+
+	data, err := json.Marshal(revalidationEntry{Validators: validators, Parsed: parsed, Result: kept})
+	if err != nil {
+		a.logger.Warn("Failed to encode cache entry", "url", logger.RedactURL(url), "error", err)
+		return
+	}
+	if err := a.cache.Set(ctx, a.revalidationKey(url, language), data, int(a.cacheTTL.Seconds())); err != nil {
+		a.logger.Warn("Failed to cache analysis for revalidation", "url", logger.RedactURL(url), "error", err)
+	}
+}
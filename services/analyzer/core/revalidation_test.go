@@ -0,0 +1,168 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/cache"
+	"github.com/RuvinSL/webpage-analyzer/pkg/cacheability"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const revalidationPage = `<!DOCTYPE html>
+<html><head><title>Cached</title></head>
+<body><h1>Heading</h1><a href="/ok">OK</a><a href="https://broken.example/">Broken</a></body></html>`
+
+// etagServer serves revalidationPage with an ETag and honors If-None-Match,
+// counting full responses and 304s separately
+type etagServer struct {
+	*httptest.Server
+	full        atomic.Int32
+	notModified atomic.Int32
+	bodyBytes   atomic.Int64
+}
+
+func newETagServer(t *testing.T) *etagServer {
+	t.Helper()
+	s := &etagServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"page-v1"`)
+		if r.Header.Get("If-None-Match") == `"page-v1"` {
+			s.notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		s.full.Add(1)
+		n, _ := io.WriteString(w, revalidationPage)
+		s.bodyBytes.Add(int64(n))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// brokenLinkChecker reports links on broken.example as inaccessible and
+// counts its calls
+type brokenLinkChecker struct {
+	calls atomic.Int32
+}
+
+func (c *brokenLinkChecker) CheckLinks(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+	c.calls.Add(1)
+	statuses := make([]models.LinkStatus, len(links))
+	for i, link := range links {
+		statuses[i] = c.CheckLink(ctx, link)
+	}
+	return statuses, nil
+}
+
+func (c *brokenLinkChecker) CheckLink(ctx context.Context, link models.Link) models.LinkStatus {
+	accessible := link.URL != "https://broken.example/"
+	return models.LinkStatus{Link: link, Accessible: accessible}
+}
+
+func TestAnalyzer_AnalyzeURL_RevalidatesWithETag(t *testing.T) {
+	tests := []struct {
+		name           string
+		recheckLinks   bool
+		wantLinkChecks int32
+	}{
+		{name: "links rechecked", recheckLinks: true, wantLinkChecks: 2},
+		{name: "links reused", recheckLinks: false, wantLinkChecks: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newETagServer(t)
+			linkChecker := &brokenLinkChecker{}
+
+			analyzer := newTestAnalyzer(t, nil, linkChecker)
+			analyzer.SetResultCache(cache.NewMemory(10), time.Minute, tt.recheckLinks)
+
+			first, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+			require.NoError(t, err)
+			second, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+			require.NoError(t, err)
+
+			// The body went over the wire once; the repeat was a bodiless 304
+			assert.Equal(t, int32(1), server.full.Load())
+			assert.Equal(t, int32(1), server.notModified.Load())
+			assert.Equal(t, int64(len(revalidationPage)), server.bodyBytes.Load())
+			assert.Equal(t, tt.wantLinkChecks, linkChecker.calls.Load())
+
+			assert.Equal(t, "Cached", second.Title)
+			assert.Equal(t, "HTML5", second.HTMLVersion)
+			assert.Equal(t, first.Headings, second.Headings)
+			assert.Equal(t, first.Links, second.Links)
+			assert.Equal(t, 1, second.Links.Inaccessible)
+			assert.Zero(t, second.Timings.ParseMs, "a 304 must not be parsed")
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeURL_NoCacheWithoutValidators(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		assert.Empty(t, r.Header.Get("If-None-Match"))
+		assert.Empty(t, r.Header.Get("If-Modified-Since"))
+		io.WriteString(w, revalidationPage)
+	}))
+	defer server.Close()
+
+	store := cache.NewMemory(10)
+	analyzer := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+	analyzer.SetResultCache(store, time.Minute, true)
+
+	for range 2 {
+		_, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(2), requests.Load())
+	assert.Equal(t, 0, store.Len())
+}
+
+func TestAnalyzer_AnalyzeURL_CacheabilityAfterRevalidation(t *testing.T) {
+	lastModified := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "private, max-age=300")
+		w.Header().Set("Vary", "Cookie")
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			// A 304 need not repeat the validator it confirms
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", lastModified)
+		io.WriteString(w, revalidationPage)
+	}))
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, nil, staticLinkChecker{})
+	analyzer.SetResultCache(cache.NewMemory(10), time.Minute, false)
+
+	expected := &models.Cacheability{
+		Cacheable:       true,
+		MaxAgeSeconds:   300,
+		FreshnessSource: cacheability.SourceMaxAge,
+		Private:         true,
+		LastModified:    lastModified,
+		Vary:            []string{"Cookie"},
+		Notes:           []string{"Cache-Control: private keeps the page out of shared caches such as CDNs"},
+	}
+
+	first, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, expected, first.Cacheability)
+
+	second, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Zero(t, second.Timings.ParseMs, "the repeat was revalidated")
+	assert.Equal(t, expected, second.Cacheability)
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/cache"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzer_AnalyzeURL_Robots(t *testing.T) {
+	// The header is only sent with the full page, as a 304 may leave it out
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("X-Robots-Tag", "noindex")
+		io.WriteString(w, `<html><head><meta name="robots" content="index, follow"></head><body></body></html>`)
+	}))
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+	analyzer.SetResultCache(cache.NewMemory(10), time.Minute, true)
+
+	expected := &models.RobotsReport{
+		Followable: true,
+		Directives: []models.RobotsDirectives{
+			{Source: models.RobotsSourceMeta, Values: []string{"index, follow"}},
+			{Source: models.RobotsSourceHeader, NoIndex: true, Values: []string{"noindex"}},
+		},
+		Conflicts: []models.RobotsConflict{{Directive: "index", Meta: "index", Header: "noindex"}},
+	}
+
+	first, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, expected, first.Robots)
+
+	revalidated, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Zero(t, revalidated.Timings.ParseMs, "the page was not modified")
+	assert.Equal(t, expected, revalidated.Robots)
+}
+
+func TestAnalyzer_AnalyzeURL_NoRobotsDirectives(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, revalidationPage)
+	}))
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+
+	result, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Nil(t, result.Robots)
+}
@@ -0,0 +1,209 @@
+package core
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/rules"
+)
+
+// Rule is one check of an analysis, enabled or disabled per request by its
+// name in pkg/rules. A disabled rule does none of its work, outbound
+// requests included, and leaves its sections of the result out.
+type Rule interface {
+	Name() string
+	// Apply checks the page and fills in the rule's sections of result.
+	// The rules of an analysis run concurrently, so each writes only its
+	// own fields.
+	Apply(ctx context.Context, page *pageRun, result *models.AnalysisResult)
+	// Findings evaluates the sections of result the rule filled in
+	Findings(result *models.AnalysisResult) []models.Finding
+}
+
+// pageRun is what the rules of one analysis share: the fetched and parsed
+// page, and the link statuses, which outlive the links rule
+type pageRun struct {
+	analyzer *Analyzer
+	url      string
+	fetcher  interfaces.FetcherStrategy
+	opts     models.AnalysisOptions
+	response *models.HTTPResponse
+	// parsed is the page's own parse and page has the content of its
+	// frames merged in, when they were followed
+	parsed *models.ParsedHTML
+	page   *models.ParsedHTML
+	// cached is the revalidated entry on 304 Not Modified, and reuseLinks
+	// says whether its link summary stands
+	cached     *revalidationEntry
+	reuseLinks bool
+	timings    *models.Timings
+
+	// linkStatuses are set by the links rule
+	linkStatuses []models.LinkStatus
+}
+
+// funcRule is a Rule made of functions; either may be nil
+type funcRule struct {
+	name     string
+	apply    func(ctx context.Context, page *pageRun, result *models.AnalysisResult)
+	findings func(result *models.AnalysisResult) []models.Finding
+}
+
+func (r funcRule) Name() string { return r.name }
+
+func (r funcRule) Apply(ctx context.Context, page *pageRun, result *models.AnalysisResult) {
+	if r.apply != nil {
+		r.apply(ctx, page, result)
+	}
+}
+
+func (r funcRule) Findings(result *models.AnalysisResult) []models.Finding {
+	if r.findings == nil {
+		return nil
+	}
+	return r.findings(result)
+}
+
+// pageRules are the rules by name; every name of pkg/rules has one
+var pageRules = func() map[string]Rule {
+	byName := make(map[string]Rule)
+	for _, rule := range []Rule{
+		// The title is always reported, the rule describes and judges it
+		funcRule{name: rules.Title, apply: applyTitle, findings: evaluateTitle},
+		funcRule{name: rules.Headings, apply: applyHeadings, findings: evaluateHeadings},
+		funcRule{name: rules.Links, apply: applyLinks, findings: evaluateLinks},
+		funcRule{name: rules.LinkAttributes, apply: applyLinkAttributes, findings: evaluateNoopener},
+		funcRule{name: rules.LinkText, apply: applyLinkText, findings: evaluateLinkText},
+		funcRule{name: rules.Anchors, apply: applyAnchors, findings: evaluateAnchors},
+		funcRule{name: rules.LoginForm, apply: applyLoginForm, findings: evaluateLoginForm},
+		funcRule{name: rules.Content, apply: applyContent, findings: evaluateContent},
+		funcRule{name: rules.Robots, apply: applyRobots, findings: evaluateRobots},
+		funcRule{name: rules.Cacheability, apply: applyCacheability},
+		funcRule{name: rules.Technology, apply: applyTechnology},
+		funcRule{name: rules.Hreflang, apply: applyHreflang, findings: evaluateHreflang},
+		funcRule{name: rules.AMP, apply: applyAMP, findings: evaluateAMP},
+		funcRule{name: rules.Pagination, apply: applyPagination, findings: evaluatePagination},
+	} {
+		byName[rule.Name()] = rule
+	}
+	return byName
+}()
+
+// SetDisabledRules turns the named rules off unless a request includes them.
+// The names must be those of pkg/rules.
+func (a *Analyzer) SetDisabledRules(names []string) {
+	a.disabledRules = slices.Clone(names)
+}
+
+// selectRules returns the rules that run for selection, in the order of
+// pkg/rules
+func (a *Analyzer) selectRules(selection models.RuleSelection) []Rule {
+	var selected []Rule
+	for _, name := range rules.Select(a.disabledRules, selection) {
+		selected = append(selected, pageRules[name])
+	}
+	return selected
+}
+
+// ruleNames returns the names of selected
+func ruleNames(selected []Rule) []string {
+	names := make([]string, len(selected))
+	for i, rule := range selected {
+		names[i] = rule.Name()
+	}
+	return names
+}
+
+func applyHeadings(ctx context.Context, page *pageRun, result *models.AnalysisResult) {
+	result.Headings = page.analyzer.countHeadings(page.page.Headings)
+}
+
+// applyLinks checks the links of the page, unless the cached summary of an
+// unchanged page stands
+func applyLinks(ctx context.Context, page *pageRun, result *models.AnalysisResult) {
+	result.URLNormalization = page.analyzer.urlNormalization()
+	if page.reuseLinks {
+		result.Links = page.cached.Result.Links
+		return
+	}
+
+	a := page.analyzer
+	stageStart := time.Now()
+	statuses, err := a.checkDistinctLinks(ctx, page.page.Links)
+	page.timings.LinkCheckMs = a.recordStage(models.StageLinkCheck, stageStart)
+	if err != nil {
+		a.logger.Warn("Failed to check some links", "error", err)
+		// Continue with partial results
+	}
+	page.linkStatuses = statuses
+
+	result.Links = a.summarizeLinks(page.page.Links, statuses)
+	result.Links.Skipped = maps.Clone(page.page.SkippedLinks)
+	result.Links.DataURIs = cloneDataURIs(page.page.DataURIs)
+	if page.opts.ReportRedirectedLinks {
+		result.RedirectedLinks = a.redirectedLinks(page.page.Links, statuses)
+	}
+}
+
+func applyLinkAttributes(ctx context.Context, page *pageRun, result *models.AnalysisResult) {
+	result.LinkFindings = linkFindings(page.page.Links)
+}
+
+func applyLinkText(ctx context.Context, page *pageRun, result *models.AnalysisResult) {
+	result.Accessibility = accessibilityReport(page.page.Links, page.analyzer.genericLinkTexts)
+}
+
+// applyAnchors reports the anchors of the page's own parse; ids are only
+// unique within one document, so frames are not merged in
+func applyAnchors(ctx context.Context, page *pageRun, result *models.AnalysisResult) {
+	result.Anchors = page.parsed.Anchors
+}
+
+func applyLoginForm(ctx context.Context, page *pageRun, result *models.AnalysisResult) {
+	result.HasLoginForm = page.page.HasLoginForm
+}
+
+func applyContent(ctx context.Context, page *pageRun, result *models.AnalysisResult) {
+	result.Content = contentReport(page.parsed)
+}
+
+func applyRobots(ctx context.Context, page *pageRun, result *models.AnalysisResult) {
+	result.Robots = pageRobots(page.response, page.parsed, page.cached)
+}
+
+func applyCacheability(ctx context.Context, page *pageRun, result *models.AnalysisResult) {
+	result.Cacheability = pageCacheability(page.response, page.cached)
+}
+
+func applyTechnology(ctx context.Context, page *pageRun, result *models.AnalysisResult) {
+	result.Technology = pageTechnology(page.response, page.parsed, page.cached)
+}
+
+// applyHreflang checks the alternates apart from the page's links so that
+// they do not change its link summary
+func applyHreflang(ctx context.Context, page *pageRun, result *models.AnalysisResult) {
+	if len(page.parsed.Hreflangs) == 0 {
+		return
+	}
+	result.Hreflang = page.analyzer.checkHreflang(ctx, page.url, page.response.FinalURL, page.fetcher,
+		page.parsed.Hreflangs, page.opts.CheckHreflangReciprocal)
+}
+
+func applyAMP(ctx context.Context, page *pageRun, result *models.AnalysisResult) {
+	if !page.parsed.IsAMP && page.parsed.AMPHTMLURL == "" {
+		return
+	}
+	result.AMP = page.analyzer.checkAMP(ctx, page.url, page.response.FinalURL, page.parsed)
+}
+
+func applyPagination(ctx context.Context, page *pageRun, result *models.AnalysisResult) {
+	if paginationPosition(page.parsed) == "" {
+		return
+	}
+	result.Pagination = page.analyzer.checkPagination(ctx, page.url, page.response.FinalURL, page.fetcher,
+		page.parsed, page.opts.FollowPagination)
+}
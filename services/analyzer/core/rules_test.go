@@ -0,0 +1,192 @@
+package core
+
+import (
+	"context"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/cache"
+	"github.com/RuvinSL/webpage-analyzer/pkg/findings"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageRules_CoverEveryRule(t *testing.T) {
+	assert.ElementsMatch(t, rules.Names(), slices.Collect(maps.Keys(pageRules)))
+	for name, rule := range pageRules {
+		assert.Equal(t, name, rule.Name())
+	}
+}
+
+// rulesPage has a broken link, an hreflang alternate and an AMP variant,
+// so each of the fetching rules has work to do, and something for each of
+// the others
+const rulesPage = `<!DOCTYPE html>
+<html lang="en"><head><title></title>
+<link rel="alternate" hreflang="de" href="/de">
+<link rel="amphtml" href="/amp">
+<meta name="robots" content="noindex">
+</head>
+<body><h1>One</h1><h1>Two</h1>
+<a href="/ok">OK</a><a href="https://broken.example/">Broken</a>
+<form><input type="text" name="username"><input type="password"></form></body></html>`
+
+// newRulesServer serves rulesPage and counts the requests for anything else
+func newRulesServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var others atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			others.Add(1)
+		}
+		io.WriteString(w, rulesPage)
+	}))
+	t.Cleanup(server.Close)
+	return server, &others
+}
+
+// countingLinkChecker checks links through client, so that the requests
+// reach the test server, and counts its calls
+type countingLinkChecker struct {
+	statusLinkChecker
+	calls atomic.Int32
+}
+
+func (c *countingLinkChecker) CheckLinks(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+	c.calls.Add(1)
+	return c.statusLinkChecker.CheckLinks(ctx, links)
+}
+
+func newRulesAnalyzer(t *testing.T) (*Analyzer, *countingLinkChecker) {
+	t.Helper()
+	log := newTestLogger()
+	client := httpclient.New(5*time.Second, log)
+	checker := &countingLinkChecker{statusLinkChecker: statusLinkChecker{client: client}}
+	return newTestAnalyzer(t, client, checker), checker
+}
+
+func TestAnalyzer_Rules_DefaultsRunEveryRule(t *testing.T) {
+	server, others := newRulesServer(t)
+	analyzer, checker := newRulesAnalyzer(t)
+
+	result, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	assert.Equal(t, rules.Names(), result.Rules)
+	assert.Equal(t, 2, result.Headings.H1)
+	assert.True(t, result.HasLoginForm)
+	assert.NotNil(t, result.Hreflang)
+	assert.NotNil(t, result.AMP)
+	assert.NotNil(t, result.Content)
+	assert.NotNil(t, result.Robots)
+	assert.NotNil(t, result.Cacheability)
+	assert.Equal(t, int32(3), checker.calls.Load(), "the page's links, the hreflang alternate and the AMP variant")
+	assert.Positive(t, others.Load())
+}
+
+func TestAnalyzer_Rules_DisabledRulesSkipTheirWork(t *testing.T) {
+	server, others := newRulesServer(t)
+	analyzer, checker := newRulesAnalyzer(t)
+
+	result, err := analyzer.AnalyzeURLWithOptions(context.Background(), server.URL, models.AnalysisOptions{
+		Rules: models.RuleSelection{Include: []string{rules.Headings, rules.Title}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{rules.Title, rules.Headings}, result.Rules)
+	assert.Zero(t, checker.calls.Load(), "no link was checked")
+	assert.Zero(t, others.Load(), "nothing but the page was fetched")
+
+	// The page facts are always reported
+	assert.Equal(t, "HTML5", result.HTMLVersion)
+	assert.Equal(t, 2, result.Links.Total)
+	assert.Zero(t, result.Links.Inaccessible)
+
+	// The sections of the disabled rules are left out
+	assert.False(t, result.HasLoginForm)
+	assert.Nil(t, result.Hreflang)
+	assert.Nil(t, result.AMP)
+	assert.Nil(t, result.LinkFindings)
+	assert.Nil(t, result.Accessibility)
+	assert.Nil(t, result.Content)
+	assert.Nil(t, result.Robots)
+	assert.Nil(t, result.Cacheability)
+
+	assert.Equal(t, []string{findings.TitleEmpty, findings.HeadingsMultipleH1}, findingIDs(result.Findings))
+	assert.Equal(t, 2, result.FindingSummary.Total)
+}
+
+func TestAnalyzer_Rules_Exclude(t *testing.T) {
+	server, others := newRulesServer(t)
+	analyzer, checker := newRulesAnalyzer(t)
+
+	result, err := analyzer.AnalyzeURLWithOptions(context.Background(), server.URL, models.AnalysisOptions{
+		Rules: models.RuleSelection{Exclude: []string{rules.Hreflang, rules.AMP}},
+	})
+	require.NoError(t, err)
+
+	assert.NotContains(t, result.Rules, rules.Hreflang)
+	assert.NotContains(t, result.Rules, rules.AMP)
+	assert.Equal(t, int32(1), checker.calls.Load(), "only the page's links were checked")
+	assert.Equal(t, int32(1), others.Load(), "/ok was checked, the alternate and AMP variant were not fetched")
+	assert.Equal(t, 1, result.Links.Inaccessible)
+	assert.Contains(t, findingIDs(result.Findings), findings.LinksBroken)
+	assert.Nil(t, result.Hreflang)
+	assert.Nil(t, result.AMP)
+}
+
+func TestAnalyzer_Rules_DisabledByDefault(t *testing.T) {
+	server, _ := newRulesServer(t)
+	analyzer, checker := newRulesAnalyzer(t)
+	analyzer.SetDisabledRules([]string{rules.Links, rules.Hreflang, rules.AMP})
+
+	result, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.NotContains(t, result.Rules, rules.Links)
+	assert.Zero(t, checker.calls.Load())
+
+	// Including a rule runs it whatever the defaults
+	result, err = analyzer.AnalyzeURLWithOptions(context.Background(), server.URL, models.AnalysisOptions{
+		Rules: models.RuleSelection{Include: []string{rules.Links}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{rules.Links}, result.Rules)
+	assert.Equal(t, 1, result.Links.Inaccessible)
+	assert.Equal(t, int32(1), checker.calls.Load())
+}
+
+func TestAnalyzer_Rules_UnknownRule(t *testing.T) {
+	analyzer, _ := newRulesAnalyzer(t)
+
+	_, err := analyzer.AnalyzeURLWithOptions(context.Background(), "https://example.com", models.AnalysisOptions{
+		Rules: models.RuleSelection{Exclude: []string{"spelling"}},
+	})
+	assert.ErrorIs(t, err, rules.ErrUnknown)
+}
+
+func TestAnalyzer_Rules_CachedSummaryWithoutLinkCheckIsNotReused(t *testing.T) {
+	server := newETagServer(t)
+	linkChecker := &brokenLinkChecker{}
+	analyzer := newTestAnalyzer(t, nil, linkChecker)
+	analyzer.SetResultCache(cache.NewMemory(10), time.Minute, false)
+
+	_, err := analyzer.AnalyzeURLWithOptions(context.Background(), server.URL, models.AnalysisOptions{
+		Rules: models.RuleSelection{Exclude: []string{rules.Links}},
+	})
+	require.NoError(t, err)
+	result, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(1), server.notModified.Load())
+	assert.Equal(t, int32(1), linkChecker.calls.Load(), "the links were never checked, so they are now")
+	assert.Equal(t, 1, result.Links.Inaccessible)
+}
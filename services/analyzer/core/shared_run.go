@@ -0,0 +1,57 @@
+package core
+
+import "context"
+
+// sharedRun counts the callers waiting on one coalesced analysis, so the
+// analysis is cancelled once none is left to receive it
+type sharedRun struct {
+	waiters int
+	// cancel stops the analysis in flight, once it has started
+	cancel context.CancelFunc
+}
+
+// joinRun counts a caller in on the analysis of key
+func (a *Analyzer) joinRun(key string) *sharedRun {
+	a.runsMu.Lock()
+	defer a.runsMu.Unlock()
+	if a.runs == nil {
+		a.runs = make(map[string]*sharedRun)
+	}
+	run, ok := a.runs[key]
+	if !ok {
+		run = &sharedRun{}
+		a.runs[key] = run
+	}
+	run.waiters++
+	return run
+}
+
+// startRun hands the analysis of key its cancel. An analysis whose callers
+// all left before it started is cancelled at once.
+func (a *Analyzer) startRun(key string, cancel context.CancelFunc) {
+	a.runsMu.Lock()
+	defer a.runsMu.Unlock()
+	run, ok := a.runs[key]
+	if !ok {
+		cancel()
+		return
+	}
+	run.cancel = cancel
+}
+
+// leaveRun counts a caller out of run. The last caller to leave cancels the
+// analysis when it gave up on it, rather than received its result.
+func (a *Analyzer) leaveRun(key string, run *sharedRun, gaveUp bool) {
+	a.runsMu.Lock()
+	defer a.runsMu.Unlock()
+	run.waiters--
+	if run.waiters > 0 {
+		return
+	}
+	if a.runs[key] == run {
+		delete(a.runs, key)
+	}
+	if gaveUp && run.cancel != nil {
+		run.cancel()
+	}
+}
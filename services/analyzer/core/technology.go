@@ -0,0 +1,137 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// maxHintRunes caps the length of each technology hint; the values are the
+// site's to choose
+const maxHintRunes = 100
+
+// knownProducts are the products recognized in technology hints: the
+// lowercase name they are written with and the name they are reported by
+var knownProducts = []struct{ key, name string }{
+	{"wordpress", "WordPress"},
+	{"drupal", "Drupal"},
+	{"joomla", "Joomla"},
+	{"typo3", "TYPO3"},
+	{"ghost", "Ghost"},
+	{"hugo", "Hugo"},
+	{"jekyll", "Jekyll"},
+	{"gatsby", "Gatsby"},
+	{"next.js", "Next.js"},
+	{"nuxt", "Nuxt"},
+	{"docusaurus", "Docusaurus"},
+	{"wix.com", "Wix"},
+	{"squarespace", "Squarespace"},
+	{"shopify", "Shopify"},
+	{"webflow", "Webflow"},
+	{"nginx", "nginx"},
+	{"openresty", "OpenResty"},
+	{"apache", "Apache"},
+	{"microsoft-iis", "IIS"},
+	{"litespeed", "LiteSpeed"},
+	{"caddy", "Caddy"},
+	{"cloudflare", "Cloudflare"},
+	{"php", "PHP"},
+	{"asp.net", "ASP.NET"},
+	{"express", "Express"},
+	{"servlet", "Java Servlet"},
+}
+
+// pageTechnology gathers the technology hints of the page's response and
+// meta tags, or nil when there are none. A 304 need not repeat the Server
+// and X-Powered-By headers, so without them the cached page's are used.
+func pageTechnology(response *models.HTTPResponse, parsed *models.ParsedHTML, cached *revalidationEntry) *models.TechnologyHints {
+	hints := &models.TechnologyHints{
+		Protocol:  response.Protocol,
+		Server:    truncateHint(response.Headers.Get("Server")),
+		PoweredBy: truncateHint(response.Headers.Get("X-Powered-By")),
+		Generator: truncateHint(parsed.Generator),
+	}
+	if cached != nil && cached.Result.Technology != nil {
+		if hints.Server == "" {
+			hints.Server = cached.Result.Technology.Server
+		}
+		if hints.PoweredBy == "" {
+			hints.PoweredBy = cached.Result.Technology.PoweredBy
+		}
+	}
+	if hints.Protocol == "" && hints.Server == "" && hints.PoweredBy == "" && hints.Generator == "" {
+		return nil
+	}
+	hints.Products = detectProducts(hints.Server, hints.PoweredBy, hints.Generator)
+	return hints
+}
+
+// truncateHint trims value and cuts it to maxHintRunes characters
+func truncateHint(value string) string {
+	value = strings.TrimSpace(strings.ToValidUTF8(value, ""))
+	runes := 0
+	for i := range value {
+		if runes == maxHintRunes {
+			return strings.TrimSpace(value[:i])
+		}
+		runes++
+	}
+	return value
+}
+
+// detectProducts lists the known products the values name, once each, in
+// the order met. A product written with a version is reported by its major
+// version, such as "WordPress 6.x".
+func detectProducts(values ...string) []string {
+	var found []string
+	seen := make(map[string]bool)
+	for _, value := range values {
+		lower := strings.ToLower(value)
+		for _, product := range knownProducts {
+			at := wordIndex(lower, product.key)
+			if at < 0 || seen[product.name] {
+				continue
+			}
+			seen[product.name] = true
+			name := product.name
+			if major := majorVersion(lower[at+len(product.key):]); major != "" {
+				name += " " + major + ".x"
+			}
+			found = append(found, name)
+		}
+	}
+	return found
+}
+
+// wordIndex returns where word starts in s as a whole word, not within a
+// longer one, or -1
+func wordIndex(s, word string) int {
+	for offset := 0; ; {
+		i := strings.Index(s[offset:], word)
+		if i < 0 {
+			return -1
+		}
+		start, end := offset+i, offset+i+len(word)
+		if !isWordByte(s, start-1) && !isWordByte(s, end) {
+			return start
+		}
+		offset = start + 1
+	}
+}
+
+// isWordByte reports whether lowercase s has a letter at i
+func isWordByte(s string, i int) bool {
+	return i >= 0 && i < len(s) && s[i] >= 'a' && s[i] <= 'z'
+}
+
+// majorVersion returns the major version written right after a product
+// name, as in "/1.25.3", " 6.4.2" or " v4.3", or "" when there is none
+func majorVersion(rest string) string {
+	rest = strings.TrimLeft(rest, " /")
+	rest = strings.TrimPrefix(rest, "v")
+	end := 0
+	for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+		end++
+	}
+	return rest[:end]
+}
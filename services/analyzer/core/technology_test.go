@@ -0,0 +1,142 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/cache"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageTechnology(t *testing.T) {
+	tests := []struct {
+		name      string
+		headers   http.Header
+		generator string
+		want      *models.TechnologyHints
+	}{
+		{
+			name:    "headers only",
+			headers: http.Header{"Server": {"nginx/1.25.3"}, "X-Powered-By": {"PHP/8.2.12"}},
+			want: &models.TechnologyHints{
+				Protocol: "HTTP/1.1", Server: "nginx/1.25.3", PoweredBy: "PHP/8.2.12",
+				Products: []string{"nginx 1.x", "PHP 8.x"},
+			},
+		},
+		{
+			name:      "meta only",
+			generator: "WordPress 6.4.2",
+			want: &models.TechnologyHints{
+				Protocol: "HTTP/1.1", Generator: "WordPress 6.4.2",
+				Products: []string{"WordPress 6.x"},
+			},
+		},
+		{
+			name:      "combined",
+			headers:   http.Header{"Server": {"Apache"}, "X-Powered-By": {"PHP/7.4.33"}},
+			generator: "Drupal 10 (https://www.drupal.org)",
+			want: &models.TechnologyHints{
+				Protocol: "HTTP/1.1", Server: "Apache", PoweredBy: "PHP/7.4.33", Generator: "Drupal 10 (https://www.drupal.org)",
+				Products: []string{"Apache", "PHP 7.x", "Drupal 10.x"},
+			},
+		},
+		{
+			name:      "nothing known",
+			headers:   http.Header{"Server": {"  in-house  "}},
+			generator: "Hand-written",
+			want:      &models.TechnologyHints{Protocol: "HTTP/1.1", Server: "in-house", Generator: "Hand-written"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := &models.HTTPResponse{StatusCode: http.StatusOK, Headers: tt.headers, Protocol: "HTTP/1.1"}
+			assert.Equal(t, tt.want, pageTechnology(response, &models.ParsedHTML{Generator: tt.generator}, nil))
+		})
+	}
+
+	// Without a protocol, headers or generator there is nothing to report
+	assert.Nil(t, pageTechnology(&models.HTTPResponse{StatusCode: http.StatusOK}, &models.ParsedHTML{}, nil))
+}
+
+func TestTechnologyHints_AbsentValuesOmitted(t *testing.T) {
+	response := &models.HTTPResponse{StatusCode: http.StatusOK, Headers: http.Header{"Server": {"cloudflare"}}}
+	data, err := json.Marshal(pageTechnology(response, &models.ParsedHTML{}, nil))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"server":"cloudflare","products":["Cloudflare"]}`, string(data))
+
+	data, err = json.Marshal(models.AnalysisResult{})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "technology")
+}
+
+func TestTruncateHint(t *testing.T) {
+	assert.Equal(t, "nginx", truncateHint(" nginx "))
+	assert.Equal(t, strings.Repeat("a", maxHintRunes), truncateHint(strings.Repeat("a", maxHintRunes+20)))
+	// Characters are counted, not bytes
+	assert.Equal(t, strings.Repeat("é", maxHintRunes), truncateHint(strings.Repeat("é", maxHintRunes+1)))
+	assert.Equal(t, "ok", truncateHint("ok\xff"))
+}
+
+func TestDetectProducts(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   []string
+	}{
+		{[]string{"Microsoft-IIS/10.0", "ASP.NET"}, []string{"IIS 10.x", "ASP.NET"}},
+		{[]string{"", "Express", "Jekyll v4.3.2"}, []string{"Express", "Jekyll 4.x"}},
+		{[]string{"", "", "Wix.com Website Builder"}, []string{"Wix"}},
+		{[]string{"openresty/1.21.4.3", "PHP/8.1", "WordPress 6.3; PHP"}, []string{"OpenResty 1.x", "PHP 8.x", "WordPress 6.x"}},
+		// Names within longer words are not products
+		{[]string{"", "", "ExpressionEngine"}, nil},
+		{[]string{"", "", "Hugo 0.120.4"}, []string{"Hugo 0.x"}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, detectProducts(tt.values...), "%q", tt.values)
+	}
+}
+
+func TestAnalyzer_AnalyzeURL_Technology(t *testing.T) {
+	// The headers are only sent with the full page, as a 304 may leave
+	// them out
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Server", "nginx/1.25.3")
+		w.Header().Set("X-Powered-By", "PHP/8.2.12")
+		io.WriteString(w, `<html><head><meta name="generator" content="WordPress 6.4.2"><meta name="generator" content="Elementor 3.18"></head><body></body></html>`)
+	}))
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+	analyzer.SetResultCache(cache.NewMemory(10), time.Minute, true)
+
+	expected := &models.TechnologyHints{
+		Protocol:  "HTTP/1.1",
+		Server:    "nginx/1.25.3",
+		PoweredBy: "PHP/8.2.12",
+		Generator: "WordPress 6.4.2",
+		Products:  []string{"nginx 1.x", "PHP 8.x", "WordPress 6.x"},
+	}
+
+	first, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, expected, first.Technology)
+
+	revalidated, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Zero(t, revalidated.Timings.ParseMs, "the page was not modified")
+	assert.Equal(t, expected, revalidated.Technology)
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+)
+
+// ellipsis ends text cut at the length limit
+const ellipsis = "…"
+
+// invisible reports the formatting characters dropped from extracted text.
+// The zero-width joiner and non-joiner are kept, as emoji sequences and
+// scripts such as Persian and Devanagari depend on them, and so are the
+// directional marks right-to-left text relies on.
+func invisible(r rune) bool {
+	switch r {
+	case '\u200b', // zero-width space
+		'\u2060', // word joiner
+		'\ufeff', // byte order mark
+		'\u00ad': // soft hyphen
+		return true
+	}
+	return false
+}
+
+// textCollector builds display text from a run of text nodes: whitespace
+// runs collapse to one space, leading and trailing whitespace and invisible
+// characters are dropped, and at most max characters are kept. The work
+// stops with the text, so a huge node costs no more than a short one.
+type textCollector struct {
+	buf   *bytes.Buffer
+	max   int
+	runes int
+	// cut is where the text ends when it has to make room for the ellipsis
+	cut       int
+	space     bool
+	truncated bool
+}
+
+// write adds s and reports whether there is room for more
+func (c *textCollector) write(s string) bool {
+	if c.truncated {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case invisible(r):
+			continue
+		case unicode.IsSpace(r):
+			c.space = c.runes > 0
+			continue
+		}
+		if c.space {
+			if !c.add(' ') {
+				return false
+			}
+			c.space = false
+		}
+		if !c.add(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *textCollector) add(r rune) bool {
+	if c.runes == c.max {
+		c.truncated = true
+		return false
+	}
+	if c.runes == c.max-1 {
+		c.cut = c.buf.Len()
+	}
+	c.buf.WriteRune(r)
+	c.runes++
+	return true
+}
+
+// text returns the collected text, ending in an ellipsis when it was cut,
+// and whether it was
+func (c *textCollector) text() (string, bool) {
+	if !c.truncated {
+		return c.buf.String(), false
+	}
+	kept := strings.TrimRightFunc(string(c.buf.Bytes()[:c.cut]), unicode.IsSpace)
+	return kept + ellipsis, true
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkText fails t unless text is normalized and within max characters
+func checkText(t *testing.T, text string, max int) {
+	t.Helper()
+	switch {
+	case !utf8.ValidString(text):
+		t.Errorf("invalid UTF-8 in %q", text)
+	case utf8.RuneCountInString(text) > max:
+		t.Errorf("%q has %d characters, limit %d", text, utf8.RuneCountInString(text), max)
+	case text != strings.TrimSpace(text):
+		t.Errorf("%q is not trimmed", text)
+	case strings.Contains(text, "  "):
+		t.Errorf("%q has a whitespace run", text)
+	case strings.ContainsFunc(text, invisible):
+		t.Errorf("%q has invisible characters", text)
+	}
+}
+
+func TestTextCollector(t *testing.T) {
+	tests := []struct {
+		name      string
+		parts     []string
+		max       int
+		expected  string
+		truncated bool
+	}{
+		{name: "plain", parts: []string{"Hello"}, max: 10, expected: "Hello"},
+		{name: "whitespace runs", parts: []string{"  Hello \t\n  world \r\n"}, max: 20, expected: "Hello world"},
+		{name: "across nodes", parts: []string{"\n  Hello ", " ", "  world", "!"}, max: 20, expected: "Hello world!"},
+		{name: "no-break spaces", parts: []string{"a\u00a0\u00a0b"}, max: 10, expected: "a b"},
+		{name: "zero-width characters", parts: []string{"\ufeffzero\u200bwidth\u2060soft\u00adhyphen"}, max: 30, expected: "zerowidthsofthyphen"},
+		{name: "only whitespace", parts: []string{" \u200b\t", "\n"}, max: 10, expected: ""},
+		{name: "exactly max", parts: []string{"abcde"}, max: 5, expected: "abcde"},
+		{name: "cut", parts: []string{"abcdef"}, max: 5, expected: "abcd…", truncated: true},
+		{name: "cut after a space", parts: []string{"abc defgh"}, max: 5, expected: "abc…", truncated: true},
+		{name: "cut multibyte", parts: []string{"Grüße aus Köln"}, max: 4, expected: "Grü…", truncated: true},
+		{name: "cut emoji", parts: []string{"😀😃😄😁😆"}, max: 3, expected: "😀😃…", truncated: true},
+		{name: "emoji sequences keep their joiners", parts: []string{"👩\u200d👩\u200d👧 family"}, max: 20, expected: "👩\u200d👩\u200d👧 family"},
+		{name: "Persian keeps its non-joiner", parts: []string{"می\u200cخواهم"}, max: 20, expected: "می\u200cخواهم"},
+		{name: "RTL", parts: []string{"مرحبا بالعالم"}, max: 20, expected: "مرحبا بالعالم"},
+		{name: "RTL with directional marks", parts: []string{"\u200fשלום עולם\u200f"}, max: 20, expected: "\u200fשלום עולם\u200f"},
+		{name: "cut RTL", parts: []string{"שלום עולם"}, max: 5, expected: "שלום…", truncated: true},
+		{name: "invalid UTF-8", parts: []string{"a\xffb"}, max: 10, expected: "a�b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := textCollector{buf: &bytes.Buffer{}, max: tt.max}
+			for _, part := range tt.parts {
+				collector.write(part)
+			}
+
+			text, truncated := collector.text()
+			assert.Equal(t, tt.expected, text)
+			assert.Equal(t, tt.truncated, truncated)
+			checkText(t, text, tt.max)
+		})
+	}
+}
+
+func TestTextCollector_StopsAtTheLimit(t *testing.T) {
+	collector := textCollector{buf: &bytes.Buffer{}, max: 3}
+	assert.True(t, collector.write("ab"))
+	assert.False(t, collector.write("cd"))
+	assert.False(t, collector.write("ef"))
+	assert.Equal(t, "abc", collector.buf.String(), "nothing is collected past the limit")
+}
+
+func TestHTMLParserParseHTML_TextNormalization(t *testing.T) {
+	page := `<html><head><title>
+			Normalized
+			Title&#8203;
+		</title></head><body>
+		<h1>  Hello <em>big</em>
+			<a href="/wide">wide</a> <span>world</span><script>var hidden = 1</script></h1>
+		<h2><svg viewBox="0 0 24 24"><style>.icon { fill: red }</style>` + strings.Repeat(`<path d="M0 0h24v24H0z"/>`, 5000) + `</svg> Icon heading</h2>
+		<h3 dir="rtl">مرحبا بالعالم</h3>
+		<a href="/next">
+			Next&nbsp;&nbsp;<span aria-hidden="true">→</span>
+		</a>
+		</body></html>`
+
+	result, err := NewHTMLParser(nil).ParseHTML(context.Background(), []byte(page), "https://example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Normalized Title", result.Title)
+	assert.Equal(t, []string{"Hello big wide world"}, result.Headings["h1"])
+	assert.Equal(t, []string{"Icon heading"}, result.Headings["h2"])
+	assert.Equal(t, []string{"مرحبا بالعالم"}, result.Headings["h3"], "RTL text passes through unchanged")
+	require.Len(t, result.Links, 2)
+	assert.Equal(t, "wide", result.Links[0].Text)
+	assert.Equal(t, "Next →", result.Links[1].Text)
+	assert.Nil(t, result.Truncation)
+}
+
+func TestHTMLParserParseHTML_TextLength(t *testing.T) {
+	parser := NewHTMLParser(nil)
+	parser.SetLimits(ParserLimits{MaxTextLength: 8})
+
+	page := `<title>A rather long page title</title>` +
+		`<h1>Emoji 😀😃😄😁😆</h1><h2>short</h2>` +
+		`<a href="/a">` + strings.Repeat("<b>word</b> ", 10000) + `</a>`
+
+	result, err := parser.ParseHTML(context.Background(), []byte(page), "https://example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, "A rathe…", result.Title)
+	assert.Equal(t, []string{"Emoji 😀…"}, result.Headings["h1"])
+	assert.Equal(t, []string{"short"}, result.Headings["h2"])
+	require.Len(t, result.Links, 1)
+	assert.Equal(t, "word wo…", result.Links[0].Text)
+
+	require.NotNil(t, result.Truncation)
+	assert.Equal(t, 3, result.Truncation.TruncatedTexts)
+
+	assert.Equal(t, result.Title, parser.ExtractTitle([]byte(page)))
+}
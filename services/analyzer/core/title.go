@@ -0,0 +1,36 @@
+package core
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// Title lengths, in characters, judged right unless SetTitleLength
+// overrides them; search results cut titles much longer than the maximum
+const (
+	DefaultTitleMinLength = 10
+	DefaultTitleMaxLength = 60
+)
+
+// SetTitleLength sets the range of title lengths, in characters, that is
+// not reported as too short or too long. Zero keeps a bound's default.
+func (a *Analyzer) SetTitleLength(minLength, maxLength int) {
+	if minLength > 0 {
+		a.titleMinLength = minLength
+	}
+	if maxLength > 0 {
+		a.titleMaxLength = maxLength
+	}
+}
+
+func applyTitle(ctx context.Context, page *pageRun, result *models.AnalysisResult) {
+	a := page.analyzer
+	result.Titles = &models.TitleReport{
+		Count:     page.parsed.TitleCount,
+		Length:    utf8.RuneCountInString(result.Title),
+		MinLength: a.titleMinLength,
+		MaxLength: a.titleMaxLength,
+	}
+}
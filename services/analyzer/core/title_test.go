@@ -0,0 +1,98 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/findings"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// titleFindings returns the IDs of the findings of a page with title among
+// count title elements
+func titleFindings(a *Analyzer, title string, count int) []string {
+	result := &models.AnalysisResult{Title: title}
+	applyTitle(context.Background(), &pageRun{analyzer: a, parsed: &models.ParsedHTML{TitleCount: count}}, result)
+	return findingIDs(evaluateTitle(result))
+}
+
+func TestEvaluateTitle_LengthBoundaries(t *testing.T) {
+	a := &Analyzer{titleMinLength: DefaultTitleMinLength, titleMaxLength: DefaultTitleMaxLength}
+
+	tests := []struct {
+		name  string
+		title string
+		want  []string
+	}{
+		{name: "one below the minimum", title: strings.Repeat("a", 9), want: []string{findings.TitleTooShort}},
+		{name: "at the minimum", title: strings.Repeat("a", 10)},
+		{name: "at the maximum", title: strings.Repeat("a", 60)},
+		{name: "one above the maximum", title: strings.Repeat("a", 61), want: []string{findings.TitleTooLong}},
+		// Characters are counted, not bytes
+		{name: "multibyte at the maximum", title: strings.Repeat("é", 60)},
+		{name: "multibyte at the minimum", title: strings.Repeat("日", 10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, titleFindings(a, tt.title, 1))
+		})
+	}
+}
+
+func TestEvaluateTitle(t *testing.T) {
+	a := &Analyzer{titleMinLength: DefaultTitleMinLength, titleMaxLength: DefaultTitleMaxLength}
+
+	assert.Equal(t, []string{findings.TitleMissing}, titleFindings(a, "", 0))
+	assert.Equal(t, []string{findings.TitleEmpty}, titleFindings(a, "", 1))
+	assert.Equal(t, []string{findings.TitleEmpty, findings.TitleMultiple}, titleFindings(a, "", 2))
+	assert.Equal(t, []string{findings.TitleTooShort, findings.TitleMultiple}, titleFindings(a, "Home", 2))
+	assert.Empty(t, titleFindings(a, "Example Domain", 1))
+
+	// A result from before the title report only has its title judged
+	assert.Equal(t, []string{findings.TitleMissing}, findingIDs(evaluateTitle(&models.AnalysisResult{})))
+	assert.Empty(t, evaluateTitle(&models.AnalysisResult{Title: "Home"}))
+}
+
+func TestAnalyzer_SetTitleLength(t *testing.T) {
+	a := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+
+	assert.Equal(t, []string{findings.TitleTooShort}, titleFindings(a, "Home", 1))
+
+	a.SetTitleLength(3, 5)
+	assert.Empty(t, titleFindings(a, "Home", 1))
+	assert.Equal(t, []string{findings.TitleTooLong}, titleFindings(a, "Homepage", 1))
+
+	// Zero keeps the bound
+	a.SetTitleLength(0, 0)
+	assert.Equal(t, 3, a.titleMinLength)
+	assert.Equal(t, 5, a.titleMaxLength)
+}
+
+func TestAnalyzer_AnalyzeURL_MultipleTitles(t *testing.T) {
+	page, err := os.ReadFile("testdata/multiple_titles.html")
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(page)
+	}))
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+
+	result, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Spring Sale | Example Shop", result.Title)
+	assert.Equal(t, &models.TitleReport{Count: 3, Length: 26, MinLength: 10, MaxLength: 60}, result.Titles)
+
+	got := findingIDs(result.Findings)
+	assert.Contains(t, got, findings.TitleMultiple)
+	assert.NotContains(t, got, findings.TitleTooShort)
+	assert.NotContains(t, got, findings.TitleTooLong)
+}
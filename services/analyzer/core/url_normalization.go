@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/urlutil"
+)
+
+// SetURLNormalization sets which spellings of a URL are the same one, by
+// default those of urlutil.DefaultPolicy: the links of a page are checked
+// and counted once per URL, and the analyses of one page are shared and
+// revalidated together. Results echo the policy.
+func (a *Analyzer) SetURLNormalization(normalizer *urlutil.Normalizer) {
+	a.urls = normalizer
+}
+
+// urlNormalization is the policy results echo
+func (a *Analyzer) urlNormalization() *models.URLNormalization {
+	policy := a.urls.Policy()
+	return &policy
+}
+
+// checkDistinctLinks checks links, each URL once under the analyzer's URL
+// normalization, and returns the status of every link: the spellings of a
+// URL share the status of the first, with their own Link
+func (a *Analyzer) checkDistinctLinks(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+	keys := make([]string, len(links))
+	first := make(map[string]bool, len(links))
+	var distinct []models.Link
+	for i, link := range links {
+		keys[i] = a.urls.Normalize(link.URL)
+		if !first[keys[i]] {
+			first[keys[i]] = true
+			distinct = append(distinct, link)
+		}
+	}
+	if len(distinct) == len(links) {
+		return a.linkChecker.CheckLinks(ctx, links)
+	}
+
+	statuses, err := a.linkChecker.CheckLinks(ctx, distinct)
+	byKey := make(map[string]models.LinkStatus, len(statuses))
+	for _, status := range statuses {
+		byKey[a.urls.Normalize(status.Link.URL)] = status
+	}
+	// Links the checker reported nothing for stay missing
+	all := make([]models.LinkStatus, 0, len(links))
+	for i, link := range links {
+		if status, ok := byKey[keys[i]]; ok {
+			status.Link = link
+			all = append(all, status)
+		}
+	}
+	return all, err
+}
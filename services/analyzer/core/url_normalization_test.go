@@ -0,0 +1,132 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/urlutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzer_URLNormalization_Links(t *testing.T) {
+	var mu sync.Mutex
+	var checked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			io.WriteString(w, `<!DOCTYPE html><html><head><title>Home</title></head><body>
+<a href="/pricing">Pricing</a>
+<a href="/pricing/">Pricing</a>
+<a href="/pricing?utm_source=nav">Pricing</a>
+<a href="/Pricing">Pricing</a>
+<a href="/missing">Missing</a>
+<a href="/missing?gclid=1">Missing</a>
+</body></html>`)
+			return
+		case "/pricing", "/pricing/":
+			io.WriteString(w, "ok")
+		default:
+			http.NotFound(w, r)
+		}
+		mu.Lock()
+		checked = append(checked, r.URL.RequestURI())
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		policy       models.URLNormalization
+		checked      []string
+		unique       int
+		inaccessible int
+	}{
+		{
+			name:         "default",
+			policy:       urlutil.DefaultPolicy(),
+			checked:      []string{"/Pricing", "/missing", "/pricing", "/pricing/"},
+			unique:       4,
+			inaccessible: 3,
+		},
+		{
+			name:         "folded",
+			policy:       models.URLNormalization{FoldTrailingSlash: true, StripParams: []string{"utm_*", "gclid"}, Case: models.URLCaseFull},
+			checked:      []string{"/missing", "/pricing"},
+			unique:       2,
+			inaccessible: 2,
+		},
+		{
+			name:         "nothing stripped",
+			policy:       models.URLNormalization{},
+			checked:      []string{"/Pricing", "/missing", "/missing?gclid=1", "/pricing", "/pricing/", "/pricing?utm_source=nav"},
+			unique:       6,
+			inaccessible: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			checked = nil
+			mu.Unlock()
+			normalizer, err := urlutil.New(tt.policy)
+			require.NoError(t, err)
+			analyzer := newTestAnalyzer(t, nil, newStatusLinkChecker())
+			analyzer.SetURLNormalization(normalizer)
+
+			result, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+			require.NoError(t, err)
+
+			mu.Lock()
+			assert.ElementsMatch(t, tt.checked, checked, "each URL is checked once")
+			mu.Unlock()
+			assert.Equal(t, 6, result.Links.Total)
+			assert.Equal(t, tt.unique, result.Links.Unique)
+			assert.Equal(t, tt.inaccessible, result.Links.Inaccessible, "every spelling shares the status of its URL")
+			assert.Equal(t, normalizer.Policy(), *result.URLNormalization)
+		})
+	}
+}
+
+func TestAnalyzer_URLNormalization_CoalescesSpellings(t *testing.T) {
+	httpClient := &countingHTTPClient{
+		delay: 100 * time.Millisecond,
+		body:  []byte("<!DOCTYPE html><html><head><title>Shared</title></head><body></body></html>"),
+	}
+	analyzer, mockMetrics := newCoalescingTestAnalyzer(t, httpClient)
+	mockMetrics.EXPECT().RecordCoalescedAnalysis().MinTimes(1)
+	normalizer, err := urlutil.New(models.URLNormalization{FoldTrailingSlash: true, StripParams: urlutil.DefaultStripParams})
+	require.NoError(t, err)
+	analyzer.SetURLNormalization(normalizer)
+
+	urls := []string{"https://example.com/pricing", "https://example.com/pricing/?utm_source=mail"}
+	results := make([]*models.AnalysisResult, len(urls))
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			results[i], err = analyzer.AnalyzeURL(context.Background(), url)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&httpClient.calls))
+	for i, result := range results {
+		require.NotNil(t, result)
+		assert.Equal(t, urls[i], result.URL, "each caller gets the result for its own URL")
+		hash, err := result.Hash()
+		require.NoError(t, err)
+		assert.Equal(t, hash, result.ResultHash)
+	}
+}
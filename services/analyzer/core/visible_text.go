@@ -0,0 +1,129 @@
+package core
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/langdetect"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"golang.org/x/net/html"
+)
+
+// languageSample is how much of the visible text, in bytes, the language is
+// detected from; the counts cover all of it
+const languageSample = 16 << 10
+
+// visibleText accumulates the statistics of a page's visible text as the
+// parser's traversal passes its nodes
+type visibleText struct {
+	stats  models.TextStats
+	sample strings.Builder
+	// hidden is the outermost hidden element the traversal is in, if any
+	hidden *html.Node
+	inWord bool
+}
+
+// visit takes in node, which the traversal reaches in document order
+func (v *visibleText) visit(node *html.Node) {
+	if v.hidden != nil && !isWithin(node, v.hidden) {
+		v.hidden = nil
+	}
+	if v.hidden != nil {
+		return
+	}
+	switch node.Type {
+	case html.ElementNode:
+		switch node.Data {
+		case "head", "script", "style", "noscript", "template":
+			// Elements holding no visible text
+			v.hidden = node
+		}
+	case html.TextNode:
+		v.write(node.Data)
+	}
+}
+
+// Character classes of visible text
+const (
+	runeSpace = iota
+	runeWordPart
+	runeIdeograph
+	runeJoiner
+	runeOther
+)
+
+// write counts the words and characters of one text node; a node boundary
+// ends a word
+func (v *visibleText) write(text string) {
+	v.inWord = false
+	for _, r := range text {
+		switch classify(r) {
+		case runeSpace:
+			v.inWord = false
+			continue
+		case runeIdeograph:
+			v.stats.Words++
+			v.inWord = false
+		case runeWordPart:
+			if !v.inWord {
+				v.stats.Words++
+				v.inWord = true
+			}
+		case runeJoiner:
+			// Inside a word, as in don't and well-known
+		default:
+			v.inWord = false
+		}
+		v.stats.Characters++
+	}
+
+	if v.sample.Len() < languageSample && strings.TrimSpace(text) != "" {
+		v.sample.WriteString(text[:min(len(text), languageSample-v.sample.Len())])
+		v.sample.WriteByte(' ')
+	}
+}
+
+// classify returns the class of r, without the Unicode tables for ASCII,
+// which most pages are mostly written in
+func classify(r rune) int {
+	if r < utf8.RuneSelf {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			return runeWordPart
+		case r == ' ', '\t' <= r && r <= '\r':
+			return runeSpace
+		case r == '\'' || r == '-':
+			return runeJoiner
+		}
+		return runeOther
+	}
+	switch {
+	case unicode.IsSpace(r) || invisible(r):
+		return runeSpace
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana):
+		return runeIdeograph
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+		return runeWordPart
+	case r == '’':
+		return runeJoiner
+	}
+	return runeOther
+}
+
+// result returns the statistics with the detected language
+func (v *visibleText) result() models.TextStats {
+	stats := v.stats
+	stats.Language, stats.Confidence = langdetect.Detect(v.sample.String())
+	return stats
+}
+
+// isWithin reports whether node is ancestor itself or one of its descendants
+func isWithin(node, ancestor *html.Node) bool {
+	for ; node != nil; node = node.Parent {
+		if node == ancestor {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+)
+
+func TestHTMLParserParseHTML_VisibleText(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		words      int
+		characters int
+	}{
+		{
+			name:       "hidden elements are skipped",
+			content:    `<head><title>Not counted</title><style>p{}</style></head><body><script>var x = 1;</script><noscript>Enable JavaScript</noscript><template><p>Later</p></template><p>Two words</p></body>`,
+			words:      2,
+			characters: 8,
+		},
+		{
+			name:       "text after a hidden element counts",
+			content:    `<p>One</p><script>skip()</script><p>two <b>three</b></p><style>a{}</style>four`,
+			words:      4,
+			characters: 15,
+		},
+		{
+			name:       "apostrophes and hyphens join words",
+			content:    `<p>Don't use well-known words — or 35 of them.</p>`,
+			words:      8,
+			characters: 35,
+		},
+		{
+			name:       "each Japanese character is a word",
+			content:    `<p>東京は晴れ</p>`,
+			words:      5,
+			characters: 5,
+		},
+		{
+			name:       "invisible characters are left out",
+			content:    "<p>zero​width ­soft</p>",
+			words:      3,
+			characters: 13,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := NewHTMLParser(nil).ParseHTML(context.Background(), []byte(tt.content), "https://example.com")
+			require.NoError(t, err)
+			assert.Equal(t, tt.words, parsed.Text.Words)
+			assert.Equal(t, tt.characters, parsed.Text.Characters)
+		})
+	}
+}
+
+func TestHTMLParserParseHTML_Language(t *testing.T) {
+	content := `<html lang=" de-DE "><body>
+<p>Die Stadtbibliothek hat in dieser Woche einen neuen Lesesaal eröffnet. Besucher können Bücher ausleihen
+und an kostenlosen Kursen über die Geschichte der Stadt teilnehmen.</p>
+<script>document.write("This script is written in English and must not be read")</script>
+</body></html>`
+
+	parsed, err := NewHTMLParser(nil).ParseHTML(context.Background(), []byte(content), "https://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "de-DE", parsed.Lang)
+	assert.Equal(t, "de", parsed.Text.Language)
+	assert.Greater(t, parsed.Text.Confidence, mismatchConfidence)
+}
+
+func TestContentReport(t *testing.T) {
+	german := models.TextStats{Words: 120, Characters: 700, Language: "de", Confidence: 0.9}
+
+	tests := []struct {
+		name     string
+		lang     string
+		text     models.TextStats
+		findings []models.ContentFinding
+	}{
+		{name: "declared language matches", lang: "de", text: german},
+		{name: "region subtag is ignored", lang: "DE-at", text: german},
+		{name: "no declared language", text: german},
+		{
+			name: "mismatch",
+			lang: "en-US",
+			text: german,
+			findings: []models.ContentFinding{
+				{Kind: models.ContentLanguageMismatch, Detail: `the page declares "en-US" but its text reads as de`},
+			},
+		},
+		{name: "uncertain detection", lang: "en", text: models.TextStats{Words: 12, Language: "de", Confidence: 0.3}},
+		{name: "nothing detected", lang: "en", text: models.TextStats{Words: 2}},
+		{name: "declared language without a profile", lang: "pl", text: models.TextStats{Words: 80, Language: "en", Confidence: 0.8}},
+		{name: "no other candidate in the script", lang: "en", text: models.TextStats{Words: 80, Language: "ru", Confidence: 0.9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := contentReport(&models.ParsedHTML{Lang: tt.lang, Text: tt.text})
+			assert.Equal(t, tt.text, report.TextStats)
+			assert.Equal(t, tt.lang, report.DeclaredLanguage)
+			assert.Equal(t, tt.findings, report.Findings)
+		})
+	}
+}
+
+func TestContentReport_UnprofiledLanguages(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		text string
+	}{
+		{
+			// Reads as Russian, the only Cyrillic profile
+			name: "Ukrainian",
+			lang: "uk",
+			text: "Музей працює допізна щоп'ятниці, а екскурсії починаються щогодини від головного входу. " +
+				"Діти до дванадцяти років відвідують його безкоштовно, а групам надається знижка.",
+		},
+		{
+			name: "Polish",
+			lang: "pl",
+			text: "Muzeum jest otwarte do późna w piątki, a wycieczki z przewodnikiem zaczynają się co godzinę " +
+				"przy głównym wejściu. Dzieci do lat dwunastu wchodzą za darmo.",
+		},
+		{
+			name: "Swedish",
+			lang: "sv",
+			text: "Museet har öppet sent på fredagar och guidade visningar börjar varje timme vid huvudentrén. " +
+				"Barn under tolv år går in gratis och grupper får rabatt om de anmäler sig i förväg.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := `<html lang="` + tt.lang + `"><body><p>` + tt.text + `</p></body></html>`
+			parsed, err := NewHTMLParser(nil).ParseHTML(context.Background(), []byte(content), "https://example.com")
+			require.NoError(t, err)
+
+			report := contentReport(parsed)
+			assert.Empty(t, report.Findings, "detected %s at %.2f", parsed.Text.Language, parsed.Text.Confidence)
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeURL_Content(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<html lang="en"><body><p>Die Stadtbibliothek hat in dieser Woche einen neuen Lesesaal
+eröffnet. Besucher können Bücher ausleihen und an kostenlosen Kursen über die Geschichte der Stadt teilnehmen.</p></body></html>`)
+	}))
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+
+	result, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	require.NotNil(t, result.Content)
+	assert.Equal(t, 24, result.Content.Words)
+	assert.Equal(t, "de", result.Content.Language)
+	assert.Equal(t, "en", result.Content.DeclaredLanguage)
+	require.Len(t, result.Content.Findings, 1)
+	assert.Equal(t, models.ContentLanguageMismatch, result.Content.Findings[0].Kind)
+}
+
+// BenchmarkHTMLParser_VisibleText measures the visible text pass alone over
+// the DOM of a large document; ParseHTML runs it within its one traversal
+func BenchmarkHTMLParser_VisibleText(b *testing.B) {
+	content := largeDocument(b, 5<<20)
+	doc, _, err := parseDocument(context.Background(), content, DefaultMaxDepth)
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(content)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var text visibleText
+		walk(doc, DefaultMaxDepth, func(node *html.Node) bool {
+			text.visit(node)
+			return true
+		})
+		text.result()
+	}
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/rules"
+)
+
+// warmupURL is where the built-in page claims to be. The .invalid domain
+// never resolves, so checking its links costs the link checker one failed
+// lookup and reaches no one.
+const warmupURL = "https://warmup.invalid/"
+
+// warmupPage is the built-in page of a warm-up: enough text for the language
+// detection, and a link so that the link checker is called
+const warmupPage = `<!DOCTYPE html>
+<html lang="en"><head><title>Warm-up</title>
+<meta name="robots" content="index, follow">
+<link rel="canonical" href="/">
+</head><body>
+<h1>Warm-up</h1>
+<p>This page is analyzed once when the service starts, so that the first
+request does not pay for connections, caches and tables that are only set
+up when they are first used.</p>
+<form><input type="text" name="username"><input type="password"></form>
+<a href="/about">About this page</a>
+</body></html>`
+
+// ErrWarmupLinkCheck is returned by a warm-up whose links the link checker
+// did not report
+var ErrWarmupLinkCheck = errors.New("the link checker did not report the warm-up links")
+
+// warmup is the outcome of the first WarmUp
+type warmup struct {
+	took time.Duration
+	err  error
+}
+
+// fixtureFetcher answers every fetch with one page
+type fixtureFetcher struct {
+	page string
+}
+
+func (f fixtureFetcher) Fetch(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	return &models.HTTPResponse{
+		StatusCode: http.StatusOK,
+		Body:       []byte(f.page),
+		Headers:    http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+		FinalURL:   url,
+	}, nil
+}
+
+// WarmUp runs one analysis with every rule so that the first request does
+// not pay for what the process sets up lazily: connections to the link
+// checker and the pages' hosts, the language profiles and the like. It
+// analyzes url, or the built-in page when url is empty, and is counted like
+// any other analysis. Only the first call does the work; later ones report
+// its outcome.
+func (a *Analyzer) WarmUp(ctx context.Context, url string) (time.Duration, error) {
+	a.warmupOnce.Do(func() {
+		start := time.Now()
+		err := a.warmUp(ctx, url)
+		a.warmup = warmup{took: time.Since(start), err: err}
+	})
+	return a.warmup.took, a.warmup.err
+}
+
+func (a *Analyzer) warmUp(ctx context.Context, url string) error {
+	selected := a.selectRules(models.RuleSelection{Include: rules.Names()})
+
+	var result *models.AnalysisResult
+	var err error
+	if url == "" {
+		result, err = a.analyze(ctx, warmupURL, fixtureFetcher{page: warmupPage}, models.AnalysisOptions{}, selected)
+	} else {
+		a.logger.Info("Warming up", "url", logger.RedactURL(url))
+		result, err = a.run(ctx, url, a.fetcher, models.AnalysisOptions{}, selected)
+	}
+	if err != nil {
+		return fmt.Errorf("warm-up analysis failed: %w", err)
+	}
+
+	for _, warning := range result.Warnings {
+		if warning.Code == models.WarningLinksUnchecked && warning.Context["not_reported"] != "0" {
+			return ErrWarmupLinkCheck
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/rules"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unreachableLinkChecker fails like a link checker service that is down
+type unreachableLinkChecker struct{}
+
+func (unreachableLinkChecker) CheckLinks(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (unreachableLinkChecker) CheckLink(ctx context.Context, link models.Link) models.LinkStatus {
+	return models.LinkStatus{Link: link, Error: "connection refused"}
+}
+
+func TestAnalyzer_WarmUp_BuiltInPageRunsOnce(t *testing.T) {
+	linkChecker := &brokenLinkChecker{}
+	analyzer := newTestAnalyzer(t, nil, linkChecker)
+	// The warm-up exercises every rule, whatever the defaults
+	analyzer.SetDisabledRules([]string{rules.Links})
+
+	took, err := analyzer.WarmUp(context.Background(), "")
+	require.NoError(t, err)
+	assert.Positive(t, took)
+	assert.Equal(t, int32(1), linkChecker.calls.Load(), "the link checker was called")
+
+	again, err := analyzer.WarmUp(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, took, again, "the first outcome is reported")
+	assert.Equal(t, int32(1), linkChecker.calls.Load(), "the warm-up ran once")
+}
+
+func TestAnalyzer_WarmUp_URL(t *testing.T) {
+	var fetches int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		io.WriteString(w, warmupPage)
+	}))
+	defer server.Close()
+	analyzer := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+
+	_, err := analyzer.WarmUp(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, 1, fetches)
+}
+
+func TestAnalyzer_WarmUp_Failures(t *testing.T) {
+	_, err := newTestAnalyzer(t, nil, unreachableLinkChecker{}).WarmUp(context.Background(), "")
+	assert.ErrorIs(t, err, ErrWarmupLinkCheck)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err = newTestAnalyzer(t, nil, &brokenLinkChecker{}).WarmUp(context.Background(), server.URL)
+	assert.ErrorContains(t, err, "warm-up analysis failed")
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/safe"
+)
+
+// warnings collects the soft issues of one analysis in the order they are met
+type warnings []models.Warning
+
+// add records a warning; context is given as key, value pairs
+func (w *warnings) add(code, message string, context ...string) {
+	warning := models.Warning{Code: code, Message: message}
+	if len(context) > 0 {
+		warning.Context = make(map[string]string, len(context)/2)
+		for i := 0; i+1 < len(context); i += 2 {
+			warning.Context[context[i]] = context[i+1]
+		}
+	}
+	*w = append(*w, warning)
+}
+
+// bodyWarningCodes are the warnings about the fetched body and its parse; a
+// page that has not changed since keeps them along with its cached parse
+var bodyWarningCodes = []string{models.WarningTruncatedBody, models.WarningCharsetFallback, models.WarningCollectionTruncated}
+
+// reuse adds the body warnings of a cached result
+func (w *warnings) reuse(cached []models.Warning) {
+	for _, warning := range cached {
+		if slices.Contains(bodyWarningCodes, warning.Code) {
+			*w = append(*w, warning)
+		}
+	}
+}
+
+// checkRedirect warns when the page was fetched from elsewhere than url
+func (w *warnings) checkRedirect(url string, response *models.HTTPResponse) {
+	if response.FinalURL == "" || response.FinalURL == url {
+		return
+	}
+	context := []string{"final_url", response.FinalURL}
+	if response.Redirects > 0 {
+		context = append(context, "redirects", strconv.Itoa(response.Redirects))
+	}
+	w.add(models.WarningRedirected, fmt.Sprintf("The URL redirected, %s was analyzed instead", response.FinalURL), context...)
+}
+
+// checkBody warns about a body that was cut short or may have been decoded
+// wrongly
+func (w *warnings) checkBody(response *models.HTTPResponse) {
+	if response.Truncated {
+		w.add(models.WarningTruncatedBody,
+			fmt.Sprintf("The page is too large, only its first %d bytes were analyzed", len(response.Body)),
+			"analyzed_bytes", strconv.Itoa(len(response.Body)))
+	}
+
+	// Pages are read as UTF-8, which is right for plain ASCII whatever the
+	// declared charset
+	if isASCII(response.Body) {
+		return
+	}
+	declared := declaredCharset(response.Headers.Get("Content-Type"), response.Body)
+	switch {
+	case declared != "" && declared != "utf-8" && declared != "utf8":
+		w.add(models.WarningCharsetFallback,
+			fmt.Sprintf("The page is in %s, which is not supported; it was read as UTF-8 and some text may be garbled", declared),
+			"charset", declared, "read_as", "utf-8")
+	case !utf8.Valid(response.Body) && declared == "":
+		w.add(models.WarningCharsetFallback,
+			"The page does not declare its charset and is not valid UTF-8; it was read as UTF-8 and some text may be garbled",
+			"read_as", "utf-8")
+	case !utf8.Valid(response.Body):
+		w.add(models.WarningCharsetFallback,
+			"The page claims to be UTF-8 but is not valid UTF-8; some text may be garbled",
+			"charset", declared, "read_as", "utf-8")
+	}
+}
+
+// checkTruncation warns that the parser left part of the page out to stay
+// within its limits, naming what and how much
+func (w *warnings) checkTruncation(t *models.ParseTruncation) {
+	var parts, context []string
+	for _, cut := range []struct {
+		count       int
+		key, phrase string
+	}{
+		{t.DroppedLinks, "dropped_links", "%d links were not collected"},
+		{t.DroppedHeadings, "dropped_headings", "%d headings were not collected"},
+		{t.TruncatedTexts, "truncated_texts", "%d texts were shortened"},
+		{t.DroppedAnchors, "dropped_anchors", "%d anchors were not collected"},
+		{t.DeepElements, "deep_elements", "%d deeply nested elements were flattened"},
+	} {
+		if cut.count > 0 {
+			parts = append(parts, fmt.Sprintf(cut.phrase, cut.count))
+			context = append(context, cut.key, strconv.Itoa(cut.count))
+		}
+	}
+	if len(parts) == 0 {
+		return
+	}
+	w.add(models.WarningCollectionTruncated,
+		fmt.Sprintf("The page exceeds the parser limits and was analyzed in part: %s", strings.Join(parts, ", ")),
+		context...)
+}
+
+// checkLinks warns when some of links were not checked: left out for lack of
+// budget, not reached in time, or missing from the statuses altogether
+// because the link checker failed
+func (w *warnings) checkLinks(links []models.Link, statuses []models.LinkStatus) {
+	checked := make(map[string]models.LinkStatus, len(statuses))
+	for _, status := range statuses {
+		checked[status.Link.URL] = status
+	}
+
+	var skipped, timedOut, missing int
+	for _, link := range links {
+		status, ok := checked[link.URL]
+		switch {
+		case !ok:
+			missing++
+		case status.Skipped:
+			skipped++
+		case status.Error == models.LinkNotCheckedError:
+			timedOut++
+		}
+	}
+
+	unchecked := skipped + timedOut + missing
+	if unchecked == 0 {
+		return
+	}
+	w.add(models.WarningLinksUnchecked,
+		fmt.Sprintf("%d of %d links were not checked, the link summary is incomplete", unchecked, len(links)),
+		"unchecked", strconv.Itoa(unchecked),
+		"budget_exhausted", strconv.Itoa(skipped),
+		"timed_out", strconv.Itoa(timedOut),
+		"not_reported", strconv.Itoa(missing),
+	)
+}
+
+// metaCharset finds the charset of <meta charset> and of
+// <meta http-equiv="Content-Type" content="...; charset=...">
+var metaCharset = safe.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_.:-]+)`).Window(charsetSniffLen)
+
+// charsetSniffLen is how much of a body is searched for a <meta> charset, as
+// browsers do
+const charsetSniffLen = 1024
+
+// declaredCharset returns the charset a page declares, lower case: the
+// Content-Type header's, or else the first <meta> one. It is empty when the
+// page declares none.
+func declaredCharset(contentType string, body []byte) string {
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		return strings.ToLower(params["charset"])
+	}
+	if m := metaCharset.FindSubmatch(body); m != nil {
+		return strings.ToLower(string(m[1]))
+	}
+	return ""
+}
+
+func isASCII(body []byte) bool {
+	return !bytes.ContainsFunc(body, func(r rune) bool { return r >= utf8.RuneSelf })
+}
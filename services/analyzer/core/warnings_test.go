@@ -0,0 +1,171 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/cache"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarnings_CheckBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        []models.Warning
+	}{
+		{
+			name:        "ASCII in any charset",
+			contentType: "text/html; charset=iso-8859-1",
+			body:        "<p>plain</p>",
+		},
+		{
+			name: "valid UTF-8 without a declaration",
+			body: "<p>café</p>",
+		},
+		{
+			name: "UTF-8 declared in a meta tag",
+			body: `<meta charset="UTF-8"><p>caf` + "é</p>",
+		},
+		{
+			name:        "other charset in the header",
+			contentType: "text/html; charset=ISO-8859-1",
+			body:        "<p>caf\xe9</p>",
+			want: []models.Warning{{
+				Code:    models.WarningCharsetFallback,
+				Message: "The page is in iso-8859-1, which is not supported; it was read as UTF-8 and some text may be garbled",
+				Context: map[string]string{"charset": "iso-8859-1", "read_as": "utf-8"},
+			}},
+		},
+		{
+			name: "other charset in http-equiv",
+			body: `<meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS"><p>` + "\x82\xa0</p>",
+			want: []models.Warning{{
+				Code:    models.WarningCharsetFallback,
+				Message: "The page is in shift_jis, which is not supported; it was read as UTF-8 and some text may be garbled",
+				Context: map[string]string{"charset": "shift_jis", "read_as": "utf-8"},
+			}},
+		},
+		{
+			name: "undeclared and not UTF-8",
+			body: "<p>caf\xe9</p>",
+			want: []models.Warning{{
+				Code:    models.WarningCharsetFallback,
+				Message: "The page does not declare its charset and is not valid UTF-8; it was read as UTF-8 and some text may be garbled",
+				Context: map[string]string{"read_as": "utf-8"},
+			}},
+		},
+		{
+			name:        "declared UTF-8 but is not",
+			contentType: "text/html; charset=utf-8",
+			body:        "<p>caf\xe9</p>",
+			want: []models.Warning{{
+				Code:    models.WarningCharsetFallback,
+				Message: "The page claims to be UTF-8 but is not valid UTF-8; some text may be garbled",
+				Context: map[string]string{"charset": "utf-8", "read_as": "utf-8"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got warnings
+			got.checkBody(&models.HTTPResponse{
+				Body:    []byte(tt.body),
+				Headers: http.Header{"Content-Type": {tt.contentType}},
+			})
+			assert.Equal(t, tt.want, []models.Warning(got))
+		})
+	}
+}
+
+func TestWarnings_CheckLinks_TimedOut(t *testing.T) {
+	links := []models.Link{{URL: "https://example.com/a"}, {URL: "https://example.com/b"}}
+
+	var got warnings
+	got.checkLinks(links, []models.LinkStatus{
+		{Link: links[0], Accessible: true},
+		{Link: links[1], Error: models.LinkNotCheckedError},
+	})
+
+	require.Len(t, got, 1)
+	assert.Equal(t, models.WarningLinksUnchecked, got[0].Code)
+	assert.Equal(t, "1", got[0].Context["timed_out"])
+}
+
+func TestWarnings_CheckTruncation(t *testing.T) {
+	var got warnings
+	got.checkTruncation(&models.ParseTruncation{DroppedLinks: 40, DroppedHeadings: 3, TruncatedTexts: 1})
+
+	assert.Equal(t, []models.Warning{{
+		Code:    models.WarningCollectionTruncated,
+		Message: "The page exceeds the parser limits and was analyzed in part: 40 links were not collected, 3 headings were not collected, 1 texts were shortened",
+		Context: map[string]string{"dropped_links": "40", "dropped_headings": "3", "truncated_texts": "1"},
+	}}, []models.Warning(got))
+}
+
+func TestAnalyzer_AnalyzeURL_CollectionTruncated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"many"`)
+		if r.Header.Get("If-None-Match") == `"many"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		io.WriteString(w, "<!DOCTYPE html><html><head><title>Many</title></head><body>")
+		for range 5 {
+			io.WriteString(w, "<h2>Section</h2>")
+		}
+		io.WriteString(w, "</body></html>")
+	}))
+	defer server.Close()
+
+	parser := NewHTMLParser(newTestLogger())
+	parser.SetLimits(ParserLimits{MaxHeadings: 2})
+	analyzer := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+	analyzer.htmlParser = parser
+	analyzer.SetResultCache(cache.NewMemory(10), time.Minute, true)
+
+	first, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	second, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, first.Headings.H2)
+	require.Len(t, first.Warnings, 1)
+	assert.Equal(t, models.WarningCollectionTruncated, first.Warnings[0].Code)
+	assert.Equal(t, "3", first.Warnings[0].Context["dropped_headings"])
+	assert.Equal(t, first.Warnings, second.Warnings, "the cached parse keeps its warning")
+}
+
+func TestAnalyzer_AnalyzeURL_BodyWarningsSurviveRevalidation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"latin-1"`)
+		if r.Header.Get("If-None-Match") == `"latin-1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+		io.WriteString(w, "<!DOCTYPE html><html><head><title>Caf\xe9</title></head><body></body></html>")
+	}))
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, nil, &brokenLinkChecker{})
+	analyzer.SetResultCache(cache.NewMemory(10), time.Minute, true)
+
+	first, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+	second, err := analyzer.AnalyzeURL(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	// The 304 has no body to look at, the cached parse's warning still holds
+	require.Len(t, first.Warnings, 1)
+	assert.Equal(t, models.WarningCharsetFallback, first.Warnings[0].Code)
+	assert.Equal(t, first.Warnings, second.Warnings)
+}
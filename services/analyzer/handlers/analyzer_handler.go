@@ -1,18 +1,29 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/budget"
+	"github.com/RuvinSL/webpage-analyzer/pkg/contextkeys"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/rules"
+	"github.com/RuvinSL/webpage-analyzer/services/analyzer/core"
 )
 
 // AnalyzerHandler handles analyzer service requests
 type AnalyzerHandler struct {
 	analyzer interfaces.Analyzer
 	logger   interfaces.Logger // *slog.Logger
+	// maxResultBytes is the size above which results are sent without
+	// their per-link details; zero sends them whatever their size
+	maxResultBytes int
 }
 
 // func NewAnalyzerHandler(analyzer interfaces.Analyzer, logger *slog.Logger) *AnalyzerHandler { // slog.Logger showing errors so I added interfaces.Logger - Ruvin
@@ -23,6 +34,12 @@ func NewAnalyzerHandler(analyzer interfaces.Analyzer, logger interfaces.Logger)
 	}
 }
 
+// SetMaxResultBytes sets the size of serialized result above which the
+// per-link details are left out; zero sends results whatever their size
+func (h *AnalyzerHandler) SetMaxResultBytes(n int) {
+	h.maxResultBytes = n
+}
+
 func (h *AnalyzerHandler) Analyze(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -40,24 +57,81 @@ func (h *AnalyzerHandler) Analyze(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	requestID := r.Header.Get("X-Request-ID")
+	if err := models.ValidateAcceptLanguage(req.AcceptLanguage); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := rules.ValidateSelection(req.Rules); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := models.ValidateChecks(req.Checks); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := models.ValidateLinkCheckOptions(req.LinkCheck); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := models.ValidateHostOverrides(req.HostOverrides); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The request ID goes on to the link checker with the analysis
+	requestID := r.Header.Get(contextkeys.RequestIDHeader)
+	ctx = contextkeys.WithRequestID(ctx, requestID)
 	h.logger.Info("Processing analysis request",
-		"url", req.URL,
+		"url", logger.RedactURL(req.URL),
+		"render", req.Render,
 		"request_id", requestID,
 	)
 
-	result, err := h.analyzer.AnalyzeURL(ctx, req.URL)
+	result, err := h.analyzer.AnalyzeURLWithOptions(ctx, req.URL, req.AnalysisOptions)
 	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			// Nobody is left to answer; the status is for the request log
+			h.logger.Info("Client closed request",
+				"url", logger.RedactURL(req.URL),
+				"status", models.StatusClientClosedRequest,
+				"request_id", requestID,
+			)
+			w.WriteHeader(models.StatusClientClosedRequest)
+			return
+		}
+
 		h.logger.Error("Analysis failed",
-			"url", req.URL,
+			"url", logger.RedactURL(req.URL),
 			"error", err,
 			"request_id", requestID,
 		)
 
+		var unsupported *models.UnsupportedContentTypeError
+		if errors.As(err, &unsupported) {
+			h.sendErrorResponse(w, unsupported.Response())
+			return
+		}
+		var busy *models.TargetBusyError
+		if errors.As(err, &busy) {
+			h.sendErrorResponse(w, busy.Response())
+			return
+		}
+
 		errorMessage := "Failed to analyze URL"
 		statusCode := http.StatusInternalServerError
 
-		if err.Error() == "context deadline exceeded" {
+		if errors.Is(err, core.ErrRenderingDisabled) {
+			errorMessage = "JavaScript rendering is not enabled on this server"
+			statusCode = http.StatusBadRequest
+		} else if errors.Is(err, core.ErrHostOverrideDenied) {
+			errorMessage = err.Error()
+			statusCode = http.StatusForbidden
+		} else if errors.Is(err, budget.ErrExhausted) {
+			errorMessage = "Outbound request budget exhausted while fetching the page"
+			statusCode = http.StatusUnprocessableEntity
+		} else if errors.Is(err, context.DeadlineExceeded) {
 			errorMessage = "Analysis timeout"
 			statusCode = http.StatusGatewayTimeout
 		} else if contains(err.Error(), "HTTP error") {
@@ -69,33 +143,116 @@ func (h *AnalyzerHandler) Analyze(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Log success
-	h.logger.Info("Analysis completed successfully",
-		"url", req.URL,
-		"title", result.Title,
-		"links_found", result.Links.Total,
-		"request_id", requestID,
-	)
+	// A result breaking the models invariants points at a bug; clients get
+	// an error rather than the bad data
+	if err := result.Validate(req.URL); err != nil {
+		h.logger.Error("Analysis produced an invalid result",
+			"url", logger.RedactURL(req.URL),
+			"error", err,
+			"request_id", requestID,
+		)
+		h.sendErrorCode(w, "Analysis produced an invalid result", http.StatusInternalServerError, models.ErrorCodeInvalidResult)
+		return
+	}
+
+	// Log success; a page that asked for authentication is answered with
+	// its result like any other, unless StrictAuth made it an HTTP error
+	if auth := result.RequiresAuth; auth != nil {
+		h.logger.Info("Analysis completed, the page requires authentication",
+			"url", logger.RedactURL(req.URL),
+			"status", auth.StatusCode,
+			"scheme", auth.Scheme,
+			"realm", auth.Realm,
+			"request_id", requestID,
+		)
+	} else {
+		h.logger.Info("Analysis completed successfully",
+			"url", logger.RedactURL(req.URL),
+			"title", result.Title,
+			"links_found", result.Links.Total,
+			"request_id", requestID,
+		)
+	}
+
+	body, err := h.encodeResult(result, requestID)
+	if err != nil {
+		h.logger.Error("Failed to encode response", "error", err, "request_id", requestID)
+		h.sendError(w, "Failed to encode result", http.StatusInternalServerError)
+		return
+	}
 
 	// Send response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// encodeResult serializes result, leaving out its per-link details when it
+// is larger than maxResultBytes, and sending only its summary when it is
+// still larger without them
+func (h *AnalyzerHandler) encodeResult(result *models.AnalysisResult, requestID string) ([]byte, error) {
+	body, err := json.Marshal(result)
+	if err != nil || h.maxResultBytes <= 0 || len(body) <= h.maxResultBytes {
+		return append(body, '\n'), err
+	}
 
-	if err := json.NewEncoder(w).Encode(result); err != nil {
-		h.logger.Error("Failed to encode response", "error", err)
+	size := len(body)
+	body, err = json.Marshal(trimLinkDetails(result, size, h.maxResultBytes))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) <= h.maxResultBytes {
+		h.logger.Warn("Result too large, per-link details left out",
+			"url", logger.RedactURL(result.URL),
+			"result_bytes", size,
+			"trimmed_bytes", len(body),
+			"max_result_bytes", h.maxResultBytes,
+			"request_id", requestID,
+		)
+		return append(body, '\n'), nil
+	}
+
+	// What is left is not link details, so only the summary fits
+	trimmed := len(body)
+	body, err = json.Marshal(summarize(result, size, h.maxResultBytes))
+	if err != nil {
+		return nil, err
 	}
+	h.logger.Warn("Result too large even without per-link details, only its summary sent",
+		"url", logger.RedactURL(result.URL),
+		"result_bytes", size,
+		"trimmed_bytes", trimmed,
+		"summary_bytes", len(body),
+		"max_result_bytes", h.maxResultBytes,
+		"over_limit", len(body) > h.maxResultBytes,
+		"request_id", requestID,
+	)
+	return append(body, '\n'), nil
 }
 
 // sendError sends an error response
 func (h *AnalyzerHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
-	response := models.ErrorResponse{
+	h.sendErrorCode(w, message, statusCode, "")
+}
+
+// sendErrorCode sends an error response carrying a models.ErrorCode* code
+func (h *AnalyzerHandler) sendErrorCode(w http.ResponseWriter, message string, statusCode int, code string) {
+	h.sendErrorResponse(w, models.ErrorResponse{
 		Error:      message,
 		StatusCode: statusCode,
+		Code:       code,
 		Timestamp:  time.Now(),
-	}
+	})
+}
 
+// sendErrorResponse sends response with its status code, and Retry-After
+// when it says when to retry
+func (h *AnalyzerHandler) sendErrorResponse(w http.ResponseWriter, response models.ErrorResponse) {
+	if response.RetryAfterSeconds > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(response.RetryAfterSeconds))
+	}
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+	w.WriteHeader(response.StatusCode)
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		h.logger.Error("Failed to encode error response", "error", err)
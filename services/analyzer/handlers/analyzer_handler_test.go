@@ -5,14 +5,21 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/contextkeys"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/mocks"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/services/analyzer/core"
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -79,6 +86,12 @@ func (t *TestLogger) Reset() {
 // MockAnalyzer implements the Analyzer interface for testing
 type MockAnalyzer struct {
 	AnalyzeURLFunc func(ctx context.Context, url string) (*models.AnalysisResult, error)
+	LastOptions    models.AnalysisOptions
+}
+
+func (m *MockAnalyzer) AnalyzeURLWithOptions(ctx context.Context, url string, opts models.AnalysisOptions) (*models.AnalysisResult, error) {
+	m.LastOptions = opts
+	return m.AnalyzeURL(ctx, url)
 }
 
 func (m *MockAnalyzer) AnalyzeURL(ctx context.Context, url string) (*models.AnalysisResult, error) {
@@ -117,6 +130,7 @@ func TestAnalyzerHandler_Analyze_Success(t *testing.T) {
 			Inaccessible: 0,
 		},
 		HasLoginForm: false,
+		AnalyzedAt:   time.Now(),
 	}
 
 	analyzer := &MockAnalyzer{
@@ -164,6 +178,113 @@ func TestAnalyzerHandler_Analyze_Success(t *testing.T) {
 	assert.Empty(t, logger.ErrorCalls)
 }
 
+// manyLinksResult is the result of a page with n internal links that all
+// redirect
+func manyLinksResult(n int) *models.AnalysisResult {
+	result := &models.AnalysisResult{
+		URL:        "https://example.com",
+		Title:      "Many links",
+		Links:      models.LinkSummary{Total: n, Internal: n, RedirectedLinks: n},
+		AnalyzedAt: time.Now(),
+	}
+	for i := range n {
+		result.RedirectedLinks = append(result.RedirectedLinks, models.RedirectedLink{
+			URL:       fmt.Sprintf("https://example.com/old/%d", i),
+			FinalURL:  fmt.Sprintf("https://example.com/new/%d", i),
+			Redirects: 1,
+		})
+	}
+	return result
+}
+
+func TestAnalyzerHandler_Analyze_MaxResultBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		links    int
+		maxBytes int
+		trimmed  bool
+	}{
+		{name: "within the limit", links: 10, maxBytes: 64 << 10},
+		{name: "no limit", links: 20000},
+		{name: "over the limit", links: 20000, maxBytes: 64 << 10, trimmed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &TestLogger{}
+			handler := NewAnalyzerHandler(&MockAnalyzer{
+				AnalyzeURLFunc: func(ctx context.Context, url string) (*models.AnalysisResult, error) {
+					return manyLinksResult(tt.links), nil
+				},
+			}, logger)
+			handler.SetMaxResultBytes(tt.maxBytes)
+
+			w := httptest.NewRecorder()
+			handler.Analyze(w, httptest.NewRequest("POST", "/analyze", strings.NewReader(`{"url":"https://example.com"}`)))
+
+			require.Equal(t, http.StatusOK, w.Code)
+			size := w.Body.Len()
+			var result models.AnalysisResult
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+			assert.Equal(t, tt.links, result.Links.Total)
+			assert.Equal(t, tt.links, result.Links.RedirectedLinks)
+			if !tt.trimmed {
+				assert.Len(t, result.RedirectedLinks, tt.links)
+				assert.Empty(t, result.Warnings)
+				assert.Empty(t, logger.WarnCalls)
+				return
+			}
+			assert.LessOrEqual(t, size, tt.maxBytes)
+			assert.Empty(t, result.RedirectedLinks)
+			require.Len(t, result.Warnings, 1)
+			assert.Equal(t, models.WarningResultTrimmed, result.Warnings[0].Code)
+			require.Len(t, logger.WarnCalls, 1)
+			assert.Equal(t, "Result too large, per-link details left out", logger.WarnCalls[0].Message)
+		})
+	}
+}
+
+func TestAnalyzerHandler_Analyze_MaxResultBytesSummary(t *testing.T) {
+	// Most of the size is response headers, not link details
+	result := manyLinksResult(100)
+	result.StatusCode = http.StatusOK
+	result.ResponseHeaders = map[string][]string{}
+	for i := range 2000 {
+		result.ResponseHeaders[fmt.Sprintf("X-Header-%d", i)] = []string{strings.Repeat("v", 40)}
+	}
+	result.ResultHash = "abc"
+
+	logger := &TestLogger{}
+	handler := NewAnalyzerHandler(&MockAnalyzer{
+		AnalyzeURLFunc: func(ctx context.Context, url string) (*models.AnalysisResult, error) {
+			return result, nil
+		},
+	}, logger)
+	handler.SetMaxResultBytes(16 << 10)
+
+	w := httptest.NewRecorder()
+	handler.Analyze(w, httptest.NewRequest("POST", "/analyze", strings.NewReader(`{"url":"https://example.com"}`)))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.LessOrEqual(t, w.Body.Len(), 16<<10)
+	var got models.AnalysisResult
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, 100, got.Links.Total)
+	assert.Equal(t, http.StatusOK, got.StatusCode)
+	assert.Equal(t, "abc", got.ResultHash)
+	assert.Empty(t, got.ResponseHeaders)
+	assert.Empty(t, got.RedirectedLinks)
+	require.Len(t, got.Warnings, 1)
+	assert.Equal(t, models.WarningResultSummarized, got.Warnings[0].Code)
+
+	require.Len(t, logger.WarnCalls, 1)
+	assert.Equal(t, "Result too large even without per-link details, only its summary sent", logger.WarnCalls[0].Message)
+	args := logger.WarnCalls[0].Args
+	i := slices.Index(args, any("over_limit"))
+	require.GreaterOrEqual(t, i, 0)
+	assert.Equal(t, false, args[i+1], "the summary fits")
+}
+
 func TestAnalyzerHandler_Analyze_InvalidJSON(t *testing.T) {
 	logger := &TestLogger{}
 	analyzer := &MockAnalyzer{}
@@ -276,7 +397,7 @@ func TestAnalyzerHandler_Analyze_ContextTimeout(t *testing.T) {
 
 	analyzer := &MockAnalyzer{
 		AnalyzeURLFunc: func(ctx context.Context, url string) (*models.AnalysisResult, error) {
-			return nil, errors.New("context deadline exceeded")
+			return nil, context.DeadlineExceeded
 		},
 	}
 
@@ -306,6 +427,29 @@ func TestAnalyzerHandler_Analyze_ContextTimeout(t *testing.T) {
 	assert.Equal(t, http.StatusGatewayTimeout, errorResp.StatusCode)
 }
 
+func TestAnalyzerHandler_Analyze_ClientClosedRequest(t *testing.T) {
+	logger := &TestLogger{}
+	ctx, cancel := context.WithCancel(context.Background())
+	analyzer := &MockAnalyzer{
+		AnalyzeURLFunc: func(ctx context.Context, url string) (*models.AnalysisResult, error) {
+			cancel()
+			return nil, fmt.Errorf("failed to fetch URL: %w", ctx.Err())
+		},
+	}
+	handler := NewAnalyzerHandler(analyzer, logger)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/analyze", strings.NewReader(`{"url":"https://example.com"}`))
+	handler.Analyze(w, req.WithContext(ctx))
+
+	assert.Equal(t, models.StatusClientClosedRequest, w.Code)
+	assert.Zero(t, w.Body.Len())
+	assert.Empty(t, logger.ErrorCalls, "a client going away is not an error")
+	last := logger.InfoCalls[len(logger.InfoCalls)-1]
+	assert.Equal(t, "Client closed request", last.Message)
+	assert.Contains(t, last.Args, models.StatusClientClosedRequest)
+}
+
 func TestAnalyzerHandler_Analyze_HTTPError(t *testing.T) {
 	logger := &TestLogger{}
 
@@ -341,13 +485,350 @@ func TestAnalyzerHandler_Analyze_HTTPError(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, errorResp.StatusCode)
 }
 
+// newAuthAnalyzer is a real analyzer for pages that ask for authentication,
+// which never get as far as their links
+func newAuthAnalyzer(t *testing.T) *core.Analyzer {
+	ctrl := gomock.NewController(t)
+	logger := &TestLogger{}
+	metrics := mocks.NewMockMetricsCollector(ctrl)
+	metrics.EXPECT().AddAnalysesInFlight(gomock.Any()).AnyTimes()
+	metrics.EXPECT().RecordAnalysis(gomock.Any(), gomock.Any()).AnyTimes()
+	metrics.EXPECT().RecordAnalysisFailure(gomock.Any()).AnyTimes()
+	metrics.EXPECT().RecordStage(gomock.Any(), gomock.Any()).AnyTimes()
+	metrics.EXPECT().RecordDownloadedBytes(gomock.Any()).AnyTimes()
+	return core.NewAnalyzer(httpclient.New(5*time.Second, logger), core.NewHTMLParser(logger),
+		mocks.NewMockLinkChecker(ctrl), logger, metrics)
+}
+
+func TestAnalyzerHandler_Analyze_RequiresAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     string
+		challenges []string
+		want       models.AuthRequirement
+	}{
+		{
+			name:       "basic",
+			status:     http.StatusUnauthorized,
+			header:     "WWW-Authenticate",
+			challenges: []string{`Basic realm="Staging"`},
+			want: models.AuthRequirement{StatusCode: 401, Scheme: "Basic", Realm: "Staging",
+				Challenges: []models.AuthChallenge{{Scheme: "Basic", Realm: "Staging"}}},
+		},
+		{
+			name:       "bearer and basic",
+			status:     http.StatusUnauthorized,
+			header:     "WWW-Authenticate",
+			challenges: []string{`Bearer realm="api", error="invalid_token", Basic realm="legacy"`},
+			want: models.AuthRequirement{StatusCode: 401, Scheme: "Bearer", Realm: "api",
+				Challenges: []models.AuthChallenge{{Scheme: "Bearer", Realm: "api"}, {Scheme: "Basic", Realm: "legacy"}}},
+		},
+		{
+			name:       "digest",
+			status:     http.StatusUnauthorized,
+			header:     "WWW-Authenticate",
+			challenges: []string{`Digest realm="admin@example.com", qop="auth", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093"`},
+			want: models.AuthRequirement{StatusCode: 401, Scheme: "Digest", Realm: "admin@example.com",
+				Challenges: []models.AuthChallenge{{Scheme: "Digest", Realm: "admin@example.com"}}},
+		},
+		{
+			name:       "proxy",
+			status:     http.StatusProxyAuthRequired,
+			header:     "Proxy-Authenticate",
+			challenges: []string{`Basic realm="proxy"`},
+			want: models.AuthRequirement{StatusCode: 407, Proxy: true, Scheme: "Basic", Realm: "proxy",
+				Challenges: []models.AuthChallenge{{Scheme: "Basic", Realm: "proxy"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for _, challenge := range tt.challenges {
+					w.Header().Add(tt.header, challenge)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer target.Close()
+
+			handler := NewAnalyzerHandler(newAuthAnalyzer(t), &TestLogger{})
+			req := httptest.NewRequest("POST", "/analyze", strings.NewReader(`{"url":"`+target.URL+`/admin"}`))
+			w := httptest.NewRecorder()
+
+			handler.Analyze(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			var result models.AnalysisResult
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+			require.NotNil(t, result.RequiresAuth)
+			assert.Equal(t, tt.want, *result.RequiresAuth)
+			require.Len(t, result.Findings, 1)
+			assert.Equal(t, "AUTH_REQUIRED", result.Findings[0].ID)
+		})
+	}
+}
+
+func TestAnalyzerHandler_Analyze_StrictAuth(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Staging"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer target.Close()
+
+	logger := &TestLogger{}
+	handler := NewAnalyzerHandler(newAuthAnalyzer(t), logger)
+	req := httptest.NewRequest("POST", "/analyze", strings.NewReader(`{"url":"`+target.URL+`","strict_auth":true}`))
+	w := httptest.NewRecorder()
+
+	handler.Analyze(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var errorResp models.ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResp))
+	assert.Equal(t, "HTTP error: status code 401", errorResp.Error)
+	require.Len(t, logger.ErrorCalls, 1)
+	assert.Equal(t, "Analysis failed", logger.ErrorCalls[0].Message)
+}
+
+func TestAnalyzerHandler_Analyze_InvalidResult(t *testing.T) {
+	logger := &TestLogger{}
+
+	// A zero-value result, as an upstream bug might produce
+	analyzer := &MockAnalyzer{
+		AnalyzeURLFunc: func(ctx context.Context, url string) (*models.AnalysisResult, error) {
+			return &models.AnalysisResult{}, nil
+		},
+	}
+
+	handler := NewAnalyzerHandler(analyzer, logger)
+
+	req := httptest.NewRequest("POST", "/analyze", strings.NewReader(`{"url":"https://example.com"}`))
+	req.Header.Set("X-Request-ID", "test-789")
+	w := httptest.NewRecorder()
+
+	handler.Analyze(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	var errorResp models.ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResp))
+	assert.Equal(t, models.ErrorCodeInvalidResult, errorResp.Code)
+	assert.Equal(t, "Analysis produced an invalid result", errorResp.Error)
+
+	require.Len(t, logger.ErrorCalls, 1)
+	assert.Equal(t, "Analysis produced an invalid result", logger.ErrorCalls[0].Message)
+	assert.Contains(t, logger.ErrorCalls[0].Args, "test-789")
+	assert.Len(t, logger.InfoCalls, 1, "no success is logged")
+}
+
+func TestAnalyzerHandler_Analyze_UnsupportedContentType(t *testing.T) {
+	analyzer := &MockAnalyzer{
+		AnalyzeURLFunc: func(ctx context.Context, url string) (*models.AnalysisResult, error) {
+			return nil, &models.UnsupportedContentTypeError{ContentType: "application/pdf", Bytes: 48213}
+		},
+	}
+	handler := NewAnalyzerHandler(analyzer, &TestLogger{})
+
+	req := httptest.NewRequest("POST", "/analyze", strings.NewReader(`{"url":"https://example.com/report.pdf"}`))
+	w := httptest.NewRecorder()
+
+	handler.Analyze(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var errorResp models.ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResp))
+	assert.Equal(t, models.ErrorCodeUnsupportedContentType, errorResp.Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, errorResp.StatusCode)
+	assert.Equal(t, "application/pdf", errorResp.ContentType)
+	assert.Equal(t, int64(48213), errorResp.ContentBytes)
+	assert.Contains(t, errorResp.Error, "not HTML")
+}
+
+func TestAnalyzerHandler_Analyze_TargetBusy(t *testing.T) {
+	analyzer := &MockAnalyzer{
+		AnalyzeURLFunc: func(ctx context.Context, url string) (*models.AnalysisResult, error) {
+			return nil, &models.TargetBusyError{Host: "slow.example", RetryAfter: 2500 * time.Millisecond}
+		},
+	}
+	handler := NewAnalyzerHandler(analyzer, &TestLogger{})
+
+	req := httptest.NewRequest("POST", "/analyze", strings.NewReader(`{"url":"https://slow.example/page"}`))
+	w := httptest.NewRecorder()
+
+	handler.Analyze(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "3", w.Header().Get("Retry-After"))
+
+	var errorResp models.ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResp))
+	assert.Equal(t, models.ErrorCodeTargetBusy, errorResp.Code)
+	assert.Equal(t, "slow.example", errorResp.Host)
+	assert.Equal(t, 3, errorResp.RetryAfterSeconds)
+	assert.Contains(t, errorResp.Error, "retry later")
+}
+
+func TestAnalyzerHandler_Analyze_RenderOption(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		analyzeErr     error
+		expectedStatus int
+		expectedRender bool
+	}{
+		{"render requested", `{"url":"https://example.com","render":true}`, nil, http.StatusOK, true},
+		{"render omitted", `{"url":"https://example.com"}`, nil, http.StatusOK, false},
+		{"rendering disabled", `{"url":"https://example.com","render":true}`, core.ErrRenderingDisabled, http.StatusBadRequest, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := &MockAnalyzer{
+				AnalyzeURLFunc: func(ctx context.Context, url string) (*models.AnalysisResult, error) {
+					if tt.analyzeErr != nil {
+						return nil, tt.analyzeErr
+					}
+					return &models.AnalysisResult{URL: url, AnalyzedAt: time.Now()}, nil
+				},
+			}
+			handler := NewAnalyzerHandler(analyzer, &TestLogger{})
+
+			req := httptest.NewRequest("POST", "/analyze", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.Analyze(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedRender, analyzer.LastOptions.Render)
+		})
+	}
+}
+
+func TestAnalyzerHandler_Analyze_Rules(t *testing.T) {
+	analyzer := &MockAnalyzer{
+		AnalyzeURLFunc: func(ctx context.Context, url string) (*models.AnalysisResult, error) {
+			return &models.AnalysisResult{URL: url, AnalyzedAt: time.Now()}, nil
+		},
+	}
+	handler := NewAnalyzerHandler(analyzer, &TestLogger{})
+
+	w := httptest.NewRecorder()
+	handler.Analyze(w, httptest.NewRequest("POST", "/analyze",
+		strings.NewReader(`{"url":"https://example.com","rules":{"include":["links","headings"],"exclude":["amp"]}}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.RuleSelection{Include: []string{"links", "headings"}, Exclude: []string{"amp"}}, analyzer.LastOptions.Rules)
+
+	analyzer.LastOptions = models.AnalysisOptions{}
+	w = httptest.NewRecorder()
+	handler.Analyze(w, httptest.NewRequest("POST", "/analyze",
+		strings.NewReader(`{"url":"https://example.com","rules":{"include":["links","spelling"]}}`)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var errorResp models.ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResp))
+	assert.Equal(t, `rules.include: unknown rule "spelling"`, errorResp.Error)
+	assert.Zero(t, analyzer.LastOptions, "the analysis never started")
+}
+
+func TestAnalyzerHandler_Analyze_Checks(t *testing.T) {
+	analyzer := &MockAnalyzer{
+		AnalyzeURLFunc: func(ctx context.Context, url string) (*models.AnalysisResult, error) {
+			return &models.AnalysisResult{URL: url, AnalyzedAt: time.Now()}, nil
+		},
+	}
+	handler := NewAnalyzerHandler(analyzer, &TestLogger{})
+
+	w := httptest.NewRecorder()
+	handler.Analyze(w, httptest.NewRequest("POST", "/analyze",
+		strings.NewReader(`{"url":"https://example.com","checks":[{"name":"analytics","type":"regex","pattern":"gtag\\(","optional":true}]}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []models.CustomCheck{{Name: "analytics", Type: models.CheckRegex, Pattern: `gtag\(`, Optional: true}}, analyzer.LastOptions.Checks)
+
+	analyzer.LastOptions = models.AnalysisOptions{}
+	w = httptest.NewRecorder()
+	handler.Analyze(w, httptest.NewRequest("POST", "/analyze",
+		strings.NewReader(`{"url":"https://example.com","checks":[{"type":"contains","pattern":"gtag"}]}`)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var errorResp models.ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResp))
+	assert.Equal(t, "checks[0].name is required", errorResp.Error)
+	assert.Zero(t, analyzer.LastOptions, "the analysis never started")
+}
+
+func TestAnalyzerHandler_Analyze_LinkCheckOptions(t *testing.T) {
+	analyzer := &MockAnalyzer{
+		AnalyzeURLFunc: func(ctx context.Context, url string) (*models.AnalysisResult, error) {
+			return &models.AnalysisResult{URL: url, AnalyzedAt: time.Now()}, nil
+		},
+	}
+	handler := NewAnalyzerHandler(analyzer, &TestLogger{})
+
+	w := httptest.NewRecorder()
+	handler.Analyze(w, httptest.NewRequest("POST", "/analyze",
+		strings.NewReader(`{"url":"https://example.com","link_check":{"per_link_timeout_ms":1500,"method":"head_then_get","acceptable_status_codes":[403]}}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, &models.LinkCheckOptions{PerLinkTimeoutMs: 1500, Method: models.LinkCheckHeadThenGet, AcceptableStatusCodes: []int{403}}, analyzer.LastOptions.LinkCheck)
+
+	analyzer.LastOptions = models.AnalysisOptions{}
+	w = httptest.NewRecorder()
+	handler.Analyze(w, httptest.NewRequest("POST", "/analyze",
+		strings.NewReader(`{"url":"https://example.com","link_check":{"max_redirects":20}}`)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var errorResp models.ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResp))
+	assert.Equal(t, "link_check.max_redirects must be between 0 and 10", errorResp.Error)
+	assert.Zero(t, analyzer.LastOptions, "the analysis never started")
+}
+
+func TestAnalyzerHandler_Analyze_HostOverrides(t *testing.T) {
+	var analyzeErr error
+	analyzer := &MockAnalyzer{
+		AnalyzeURLFunc: func(ctx context.Context, url string) (*models.AnalysisResult, error) {
+			if analyzeErr != nil {
+				return nil, analyzeErr
+			}
+			return &models.AnalysisResult{URL: url, AnalyzedAt: time.Now()}, nil
+		},
+	}
+	handler := NewAnalyzerHandler(analyzer, &TestLogger{})
+	body := `{"url":"https://staging.example.com","host_overrides":{"staging.example.com":"203.0.113.7"}}`
+
+	w := httptest.NewRecorder()
+	handler.Analyze(w, httptest.NewRequest("POST", "/analyze", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]string{"staging.example.com": "203.0.113.7"}, analyzer.LastOptions.HostOverrides)
+
+	// Overrides the analyzer does not accept are forbidden
+	analyzeErr = fmt.Errorf("%w: staging.example.com is not a host this analyzer overrides", core.ErrHostOverrideDenied)
+	w = httptest.NewRecorder()
+	handler.Analyze(w, httptest.NewRequest("POST", "/analyze", strings.NewReader(body)))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var errorResp models.ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResp))
+	assert.Equal(t, "host override not allowed: staging.example.com is not a host this analyzer overrides", errorResp.Error)
+
+	analyzer.LastOptions = models.AnalysisOptions{}
+	w = httptest.NewRecorder()
+	handler.Analyze(w, httptest.NewRequest("POST", "/analyze",
+		strings.NewReader(`{"url":"https://staging.example.com","host_overrides":{"staging.example.com":"staging.internal"}}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResp))
+	assert.Equal(t, `host_overrides["staging.example.com"]: "staging.internal" is not an IP address`, errorResp.Error)
+	assert.Zero(t, analyzer.LastOptions, "the analysis never started")
+}
+
 func TestAnalyzerHandler_Analyze_WithoutRequestID(t *testing.T) {
 	logger := &TestLogger{}
 
 	expectedResult := &models.AnalysisResult{
-		URL:   "https://example.com",
-		Title: "Test",
-		Links: models.LinkSummary{Total: 5},
+		URL:        "https://example.com",
+		Title:      "Test",
+		Links:      models.LinkSummary{Internal: 5, Total: 5},
+		AnalyzedAt: time.Now(),
 	}
 
 	analyzer := &MockAnalyzer{
@@ -485,9 +966,10 @@ func TestAnalyzerHandler_Integration(t *testing.T) {
 						Inaccessible: 1,
 					},
 					HasLoginForm: true,
+					AnalyzedAt:   time.Now(),
 				}, nil
 			case "https://timeout.com":
-				return nil, errors.New("context deadline exceeded")
+				return nil, context.DeadlineExceeded
 			case "https://notfound.com":
 				return nil, errors.New("HTTP error: 404 Not Found")
 			default:
@@ -568,9 +1050,10 @@ func BenchmarkAnalyzerHandler_Analyze(b *testing.B) {
 	analyzer := &MockAnalyzer{
 		AnalyzeURLFunc: func(ctx context.Context, url string) (*models.AnalysisResult, error) {
 			return &models.AnalysisResult{
-				URL:   url,
-				Title: "Benchmark Test",
-				Links: models.LinkSummary{Total: 10},
+				URL:        url,
+				Title:      "Benchmark Test",
+				Links:      models.LinkSummary{Internal: 10, Total: 10},
+				AnalyzedAt: time.Now(),
 			}, nil
 		},
 	}
@@ -593,3 +1076,23 @@ func BenchmarkAnalyzerHandler_Analyze(b *testing.B) {
 		}
 	}
 }
+
+func TestAnalyzerHandler_Analyze_PassesRequestIDOn(t *testing.T) {
+	var got string
+	analyzer := &MockAnalyzer{
+		AnalyzeURLFunc: func(ctx context.Context, url string) (*models.AnalysisResult, error) {
+			got = contextkeys.RequestIDFrom(ctx)
+			return &models.AnalysisResult{URL: url, AnalyzedAt: time.Now()}, nil
+		},
+	}
+	handler := NewAnalyzerHandler(analyzer, &TestLogger{})
+
+	req := httptest.NewRequest("POST", "/analyze", strings.NewReader(`{"url":"https://example.com"}`))
+	req.Header.Set(contextkeys.RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+
+	handler.Analyze(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "req-42", got)
+}
@@ -7,7 +7,9 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/healthprobe"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/version"
 )
 
 type HealthChecker interface {
@@ -20,7 +22,9 @@ type HealthHandler struct {
 	startTime         time.Time
 }
 
-// NewHealthHandler creates a new health handler
+// NewHealthHandler reports the link checker's health from linkCheckerClient,
+// either the client itself or a healthprobe.Prober answering from its latest
+// check
 func NewHealthHandler(serviceName string, linkCheckerClient HealthChecker) *HealthHandler {
 	return &HealthHandler{
 		serviceName:       serviceName,
@@ -53,11 +57,14 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	response := models.HealthStatus{
 		Status:    status,
 		Service:   h.serviceName,
-		Version:   "1.0.0",
+		Version:   version.Get().Version,
 		Uptime:    formatDuration(time.Since(h.startTime)),
 		Checks:    checks,
 		Timestamp: time.Now(),
 	}
+	if prober, ok := h.linkCheckerClient.(*healthprobe.Prober); ok {
+		response.Probes = map[string]models.ProbeResult{"link_checker_service": prober.Result()}
+	}
 
 	statusCode := http.StatusOK
 	if status != "healthy" {
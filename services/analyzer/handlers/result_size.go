@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// trimLinkDetails returns a copy of result without its per-link details,
+// which grow with the number of links on the page: the slowest links, the
+// examples of broken link groups, the redirected links, the URLs and texts
+// of link findings and findings, and the dangling anchors. The counts stay,
+// and a WarningResultTrimmed warning says what happened. size is that of the
+// full result and maxBytes the size it exceeded. result is left unchanged.
+func trimLinkDetails(result *models.AnalysisResult, size, maxBytes int) *models.AnalysisResult {
+	trimmed := *result
+
+	trimmed.Links.SlowestLinks = nil
+	if groups := result.Links.BrokenGroups; groups != nil {
+		trimmed.Links.BrokenGroups = make([]models.BrokenLinkGroup, len(groups))
+		for i, group := range groups {
+			group.Examples = nil
+			trimmed.Links.BrokenGroups[i] = group
+		}
+	}
+	trimmed.RedirectedLinks = nil
+
+	if f := result.LinkFindings; f != nil {
+		linkFindings := *f
+		linkFindings.Findings = make([]models.LinkFinding, len(f.Findings))
+		for i, finding := range f.Findings {
+			finding.URLs = nil
+			finding.Texts = nil
+			linkFindings.Findings[i] = finding
+		}
+		trimmed.LinkFindings = &linkFindings
+	}
+
+	if result.Findings != nil {
+		trimmed.Findings = make([]models.Finding, len(result.Findings))
+		for i, finding := range result.Findings {
+			if len(finding.Evidence.URLs) > 0 {
+				if finding.Evidence.Count == 0 {
+					finding.Evidence.Count = len(finding.Evidence.URLs)
+				}
+				finding.Evidence.URLs = nil
+			}
+			trimmed.Findings[i] = finding
+		}
+	}
+
+	if a := result.Anchors; a != nil && len(a.DanglingAnchors) > 0 {
+		anchors := *a
+		anchors.DanglingAnchors = nil
+		trimmed.Anchors = &anchors
+	}
+
+	trimmed.Warnings = append(slices.Clip(result.Warnings), models.Warning{
+		Code:    models.WarningResultTrimmed,
+		Message: fmt.Sprintf("The result is too large to send in full (%d bytes), the details of individual links were left out", size),
+		Context: map[string]string{
+			"result_bytes":     strconv.Itoa(size),
+			"max_result_bytes": strconv.Itoa(maxBytes),
+		},
+	})
+	return &trimmed
+}
+
+// summarize returns the summary of result, for a result too large to send
+// even without its per-link details: what the page is, the counts of its
+// headings, links and findings, the timings and the result hash, but none
+// of the sections. A WarningResultSummarized warning says what happened.
+// size is that of the full result and maxBytes the size it exceeded.
+func summarize(result *models.AnalysisResult, size, maxBytes int) *models.AnalysisResult {
+	links := result.Links
+	return &models.AnalysisResult{
+		URL:          result.URL,
+		HTMLVersion:  result.HTMLVersion,
+		Title:        result.Title,
+		Headings:     result.Headings,
+		HasLoginForm: result.HasLoginForm,
+		AnalyzedAt:   result.AnalyzedAt,
+		Links: models.LinkSummary{
+			Internal:        links.Internal,
+			External:        links.External,
+			Inaccessible:    links.Inaccessible,
+			Total:           links.Total,
+			RedirectedLinks: links.RedirectedLinks,
+			Unique:          links.Unique,
+			DurationP50Ms:   links.DurationP50Ms,
+			DurationP95Ms:   links.DurationP95Ms,
+		},
+		Timings:        result.Timings,
+		FinalURL:       result.FinalURL,
+		StatusCode:     result.StatusCode,
+		AcceptLanguage: result.AcceptLanguage,
+		HasFrames:      result.HasFrames,
+		FindingSummary: result.FindingSummary,
+		Rules:          result.Rules,
+		ChecksFailed:   result.ChecksFailed,
+		ResultHash:     result.ResultHash,
+		Warnings: append(slices.Clip(result.Warnings), models.Warning{
+			Code:    models.WarningResultSummarized,
+			Message: fmt.Sprintf("The result is too large to send (%d bytes), even without the details of individual links; only its summary was sent", size),
+			Context: map[string]string{
+				"result_bytes":     strconv.Itoa(size),
+				"max_result_bytes": strconv.Itoa(maxBytes),
+			},
+		}),
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrimLinkDetails(t *testing.T) {
+	result := &models.AnalysisResult{
+		URL: "https://example.com",
+		Links: models.LinkSummary{
+			Total:        3,
+			Internal:     3,
+			Inaccessible: 2,
+			SlowestLinks: []models.SlowLink{{URL: "https://example.com/slow"}},
+			BrokenGroups: []models.BrokenLinkGroup{{Pattern: "/old/*", Count: 2, Examples: []string{"https://example.com/old/a"}}},
+		},
+		RedirectedLinks: []models.RedirectedLink{{URL: "https://example.com/a", FinalURL: "https://example.com/b", Redirects: 1}},
+		LinkFindings: &models.LinkFindings{NewTab: 1, Findings: []models.LinkFinding{
+			{Kind: models.LinkMissingNoopener, Count: 1, URLs: []string{"https://example.org"}, Texts: []string{"Elsewhere"}},
+		}},
+		Anchors: &models.AnchorReport{
+			DuplicateIDs:    []models.DuplicateID{{ID: "top", Count: 2}},
+			DanglingAnchors: []string{"missing"},
+		},
+		Findings: []models.Finding{
+			{ID: "LINKS_BROKEN", Evidence: models.FindingEvidence{URLs: []string{"https://example.com/old/a", "https://example.com/old/b"}}},
+			{ID: "HEADINGS_MISSING_H1", Evidence: models.FindingEvidence{Count: 0}},
+		},
+		Warnings: []models.Warning{{Code: models.WarningRedirected}},
+	}
+
+	trimmed := trimLinkDetails(result, 2000, 1000)
+
+	assert.Nil(t, trimmed.Links.SlowestLinks)
+	assert.Equal(t, []models.BrokenLinkGroup{{Pattern: "/old/*", Count: 2}}, trimmed.Links.BrokenGroups)
+	assert.Equal(t, 2, trimmed.Links.Inaccessible, "counts are kept")
+	assert.Nil(t, trimmed.RedirectedLinks)
+	assert.Equal(t, []models.LinkFinding{{Kind: models.LinkMissingNoopener, Count: 1, URLs: nil}}, trimmed.LinkFindings.Findings)
+	assert.Equal(t, 1, trimmed.LinkFindings.NewTab)
+	assert.Equal(t, &models.AnchorReport{DuplicateIDs: []models.DuplicateID{{ID: "top", Count: 2}}}, trimmed.Anchors)
+	assert.Equal(t, models.FindingEvidence{Count: 2}, trimmed.Findings[0].Evidence, "the URLs are counted")
+	assert.Equal(t, models.FindingEvidence{}, trimmed.Findings[1].Evidence)
+
+	require.Len(t, trimmed.Warnings, 2)
+	assert.Equal(t, models.WarningResultTrimmed, trimmed.Warnings[1].Code)
+	assert.Equal(t, map[string]string{"result_bytes": "2000", "max_result_bytes": "1000"}, trimmed.Warnings[1].Context)
+
+	// The result itself is untouched
+	assert.Len(t, result.Links.SlowestLinks, 1)
+	assert.Len(t, result.Links.BrokenGroups[0].Examples, 1)
+	assert.Len(t, result.RedirectedLinks, 1)
+	assert.Len(t, result.LinkFindings.Findings[0].URLs, 1)
+	assert.Len(t, result.Anchors.DanglingAnchors, 1)
+	assert.Len(t, result.Findings[0].Evidence.URLs, 2)
+	assert.Len(t, result.Warnings, 1)
+}
+
+func TestSummarize(t *testing.T) {
+	result := &models.AnalysisResult{
+		URL:         "https://example.com",
+		HTMLVersion: "HTML5",
+		Title:       "Example",
+		Headings:    models.HeadingCount{H1: 1},
+		Links: models.LinkSummary{
+			Total:           3,
+			Internal:        3,
+			Inaccessible:    2,
+			RedirectedLinks: 1,
+			SlowestLinks:    []models.SlowLink{{URL: "https://example.com/slow"}},
+			ExternalDomains: []models.DomainCount{{Domain: "example.org", Count: 1}},
+		},
+		ResponseHeaders: map[string][]string{"Set-Cookie": {"session"}},
+		Frames:          []models.Frame{{URL: "https://example.com/frame"}},
+		Findings:        []models.Finding{{ID: "LINKS_BROKEN"}},
+		FindingSummary:  &models.FindingSummary{Total: 1},
+		ResultHash:      "abc",
+		Warnings:        []models.Warning{{Code: models.WarningRedirected}},
+	}
+
+	summary := summarize(result, 2000, 1000)
+
+	assert.Equal(t, &models.AnalysisResult{
+		URL:            "https://example.com",
+		HTMLVersion:    "HTML5",
+		Title:          "Example",
+		Headings:       models.HeadingCount{H1: 1},
+		Links:          models.LinkSummary{Total: 3, Internal: 3, Inaccessible: 2, RedirectedLinks: 1},
+		FindingSummary: &models.FindingSummary{Total: 1},
+		ResultHash:     "abc",
+		Warnings: []models.Warning{
+			{Code: models.WarningRedirected},
+			{
+				Code:    models.WarningResultSummarized,
+				Message: "The result is too large to send (2000 bytes), even without the details of individual links; only its summary was sent",
+				Context: map[string]string{"result_bytes": "2000", "max_result_bytes": "1000"},
+			},
+		},
+	}, summary)
+	assert.Len(t, result.Warnings, 1, "the result itself is untouched")
+}
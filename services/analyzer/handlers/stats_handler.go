@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/stats"
+)
+
+// StatsHandler serves a JSON snapshot of the analyzer's load
+type StatsHandler struct {
+	serviceName string
+	collector   *stats.Collector
+	startTime   time.Time
+}
+
+// NewStatsHandler reports the figures kept by collector
+func NewStatsHandler(serviceName string, collector *stats.Collector) *StatsHandler {
+	return &StatsHandler{
+		serviceName: serviceName,
+		collector:   collector,
+		startTime:   time.Now(),
+	}
+}
+
+// Stats handles GET /stats
+func (h *StatsHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	response := models.ServiceStats{
+		Service:       h.serviceName,
+		UptimeSeconds: int64(time.Since(h.startTime) / time.Second),
+		WindowSeconds: int64(stats.Span / time.Second),
+		Analyses:      h.collector.Analyses(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
@@ -4,105 +4,337 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/admin"
+	pkganalyzer "github.com/RuvinSL/webpage-analyzer/pkg/analyzer"
+	"github.com/RuvinSL/webpage-analyzer/pkg/cache"
+	"github.com/RuvinSL/webpage-analyzer/pkg/config"
+	"github.com/RuvinSL/webpage-analyzer/pkg/faults"
+	"github.com/RuvinSL/webpage-analyzer/pkg/healthprobe"
 	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/internalhttp"
 	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
 	"github.com/RuvinSL/webpage-analyzer/pkg/metrics"
+	"github.com/RuvinSL/webpage-analyzer/pkg/middleware"
+	"github.com/RuvinSL/webpage-analyzer/pkg/readiness"
+	"github.com/RuvinSL/webpage-analyzer/pkg/servertls"
+	"github.com/RuvinSL/webpage-analyzer/pkg/stats"
+	"github.com/RuvinSL/webpage-analyzer/pkg/urlutil"
+	"github.com/RuvinSL/webpage-analyzer/pkg/version"
 	"github.com/RuvinSL/webpage-analyzer/services/analyzer/core"
 	"github.com/RuvinSL/webpage-analyzer/services/analyzer/handlers"
+	"github.com/RuvinSL/webpage-analyzer/services/analyzer/render"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 const (
-	defaultPort = "8081"
 	serviceName = "analyzer"
 )
 
-// createLogger creates a logger with optional file output
-func createLogger() interfaces.Logger {
-	// Check if file logging is enabled via environment variable
-	if getEnv("LOG_TO_FILE", "true") == "true" {
-		logDir := getEnv("LOG_DIR", "./logs")
-		return logger.NewWithFiles(serviceName, getLogLevel(), logDir)
+// createLogger creates a logger with optional file output. The level is a
+// slog.Leveler so a *slog.LevelVar can be adjusted after startup
+func createLogger(cfg *config.Common, level slog.Leveler) interfaces.Logger {
+	if cfg.LogToFile {
+		return logger.NewWithFiles(serviceName, level, cfg.LogDir)
 	}
 
-	// Default: stdout only (your current behavior)
-	return logger.New(serviceName, getLogLevel())
+	return logger.New(serviceName, level)
+}
+
+// loadConfigOrExit reports configuration errors through a bootstrap logger
+// and exits, so a bad value never silently falls back to a default
+func loadConfigOrExit[T any](load func() (T, error)) T {
+	cfg, err := load()
+	if err != nil {
+		logger.New(serviceName, slog.LevelInfo).Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	return cfg
+}
+
+// sourceIPOrExit checks that OUTBOUND_SOURCE_IP is an address of the host,
+// which the configuration alone cannot tell, and logs how outbound
+// connections are made
+func sourceIPOrExit(cfg config.DNS, log interfaces.Logger) net.IP {
+	ip, err := httpclient.ParseSourceIP(cfg.OutboundSourceIP)
+	if err != nil {
+		log.Error("Invalid configuration", "error", fmt.Errorf("OUTBOUND_SOURCE_IP: %w", err))
+		os.Exit(1)
+	}
+	source := "system"
+	if ip != nil {
+		source = ip.String()
+	}
+	log.Info("Outbound HTTP client configured", "ip_family", cfg.IPFamily, "source_ip", source)
+	return ip
+}
+
+// faultsOrExit returns the injector of FAULT_INJECT, nil when it is not set,
+// and warns loudly when it is
+func faultsOrExit(cfg *config.Common, log interfaces.Logger) *faults.Injector {
+	injector, err := faults.New(cfg.FaultInject, cfg.FaultInjectSeed)
+	if err != nil {
+		log.Error("Invalid configuration", "error", fmt.Errorf("FAULT_INJECT: %w", err))
+		os.Exit(1)
+	}
+	if injector != nil {
+		log.Warn("FAULT INJECTION ENABLED: latency and errors are injected on purpose, never run this in production",
+			"rules", injector.Rules(), "seed", injector.Seed())
+	}
+	return injector
 }
 
 func main() {
 
+	cfg := loadConfigOrExit(config.LoadAnalyzer)
+
 	//log := logger.New(serviceName, getLogLevel())
-	log := createLogger()
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(cfg.SlogLevel())
+	log := createLogger(&cfg.Common, logLevel)
+	log.Info("Loaded configuration", config.Fields(cfg)...)
+	faultInjector := faultsOrExit(&cfg.Common, log)
 
 	metricsCollector := metrics.NewPrometheusCollector(serviceName)
 	prometheus.MustRegister(metricsCollector.GetCollectors()...)
+	statsCollector := stats.NewCollector(metricsCollector)
+	prometheus.MustRegister(statsCollector.SLOCollectors(serviceName, stats.SLO{
+		Objective: cfg.SLOObjective,
+		Latency:   cfg.SLOLatency,
+	})...)
+
+	// The analysis is built like the in-process library's, with the link
+	// checks handed to the link checker service
+	sourceIP := sourceIPOrExit(cfg.DNS, log)
+	var resolver httpclient.Resolver
+	if endpoint := cfg.DoHEndpoint(); endpoint != "" {
+		resolver = httpclient.NewDoHResolver(endpoint, cfg.DNSResolverTimeout, log)
+		log.Info("Resolving host names with DNS over HTTPS", "endpoint", endpoint)
+	}
+	linkCheckerClient := core.NewLinkCheckerClient(cfg.LinkCheckerURL, cfg.LinkCheckerTimeout, log)
+	linkCheckerClient.SetH2C(cfg.InternalH2C)
+	linkCheckerClient.SetFaults(faultInjector)
+
+	library := pkganalyzer.New(
+		pkganalyzer.WithServiceLogger(log),
+		pkganalyzer.WithMetrics(statsCollector),
+		pkganalyzer.WithFetchTimeout(cfg.FetchTimeout),
+		pkganalyzer.WithIPFamily(cfg.IPFamily),
+		pkganalyzer.WithSourceIP(sourceIP),
+		pkganalyzer.WithResolver(resolver),
+		pkganalyzer.WithParserLimits(core.ParserLimits{
+			MaxDepth:          cfg.ParserMaxDepth,
+			MaxLinks:          cfg.ParserMaxLinks,
+			MaxTextLength:     cfg.ParserMaxTextLength,
+			MaxAnchors:        cfg.ParserMaxAnchors,
+			MaxHeadings:       cfg.ParserMaxHeadings,
+			LargeDataURIBytes: cfg.LargeDataURIBytes,
+		}),
+		pkganalyzer.WithAnalysisTimeout(cfg.MaxAnalysisTimeout),
+		pkganalyzer.WithBudget(int64(cfg.MaxRequestsPerAnalysis), int64(cfg.MaxBytesPerAnalysis)),
+		pkganalyzer.WithGenericLinkTexts(cfg.GenericLinkTexts),
+		pkganalyzer.WithTitleLength(cfg.TitleMinLength, cfg.TitleMaxLength),
+		pkganalyzer.WithDisabledRules(cfg.DisabledRules...),
+		pkganalyzer.WithLinkChecker(linkCheckerClient),
+	)
+	defer library.Close()
+
+	// The service-only settings
+	analyzer := library.Core()
+	analyzer.SetMaxFrames(cfg.MaxFramesPerAnalysis)
+	analyzer.SetPaginationHops(cfg.MaxPaginationHops)
+	analyzer.SetHostLimit(cfg.MaxAnalysesPerHost, cfg.HostWaitTimeout)
+	analyzer.SetHostOverrides(cfg.HostOverrideHosts, cfg.AllowPrivateTargets)
+	urls, err := urlutil.New(cfg.URLNormalization.Policy())
+	if err != nil {
+		log.Error("Invalid URL normalization", "error", err)
+		os.Exit(1)
+	}
+	analyzer.SetURLNormalization(urls)
+	analyzer.SetFailureTracker(statsCollector)
+	if cfg.DebugTraceEnabled {
+		analyzer.SetDebugTrace(cfg.DebugTraceMaxEntries)
+	}
 
-	// Configuration
-	port := getEnv("PORT", defaultPort)
-	linkCheckerURL := getEnv("LINK_CHECKER_SERVICE_URL", "http://localhost:8082")
-
-	// Initialize dependencies
-	httpClient := httpclient.New(30*time.Second, log)
-	htmlParser := core.NewHTMLParser(log)
-	linkCheckerClient := core.NewLinkCheckerClient(linkCheckerURL, 30*time.Second, log)
+	// Headless rendering is heavy, so it only exists when explicitly enabled.
+	// Screenshots come from the same browser.
+	if cfg.RenderEnabled {
+		renderer := render.NewChromeFetcher(render.Options{
+			MaxConcurrent:      cfg.RenderMaxConcurrent,
+			Timeout:            cfg.RenderTimeout,
+			WaitSelector:       cfg.RenderWaitSelector,
+			ExecPath:           cfg.ChromePath,
+			MaxScreenshotBytes: cfg.ScreenshotMaxBytes,
+		}, log)
+		defer renderer.Close()
+		analyzer.SetRenderer(renderer, cfg.RenderByDefault)
+		analyzer.SetScreenshotter(renderer)
+	}
 
-	// Initialize analyzer with dependency injection
-	analyzer := core.NewAnalyzer(httpClient, htmlParser, linkCheckerClient, log, metricsCollector)
+	// Repeat analyses revalidate the page with its ETag/Last-Modified and
+	// reuse the cached parse on 304 Not Modified. Replicas share the cache
+	// through Redis when it is configured.
+	if cfg.ResultCacheEnabled {
+		var resultCache interfaces.Cache = cache.NewMemory(cfg.ResultCacheMaxEntries)
+		if cfg.RedisURL != "" {
+			shared, err := cache.NewRedis(cache.RedisOptions{
+				URL:             cfg.RedisURL,
+				KeyPrefix:       cfg.RedisKeyPrefix,
+				Timeout:         cfg.RedisTimeout,
+				BreakerFailures: cfg.RedisBreakerFailures,
+				BreakerCooldown: cfg.RedisBreakerCooldown,
+			}, log)
+			if err != nil {
+				log.Error("Failed to configure Redis", "error", err)
+				os.Exit(1)
+			}
+			defer shared.Close()
+			resultCache = shared
+		}
+		analyzer.SetResultCache(resultCache, cfg.ResultCacheTTL, !cfg.RevalidateSkipLinkCheck)
+	}
 
 	// Initialize handlers
 	analyzerHandler := handlers.NewAnalyzerHandler(analyzer, log)
-	healthHandler := handlers.NewHealthHandler(serviceName, linkCheckerClient)
+	analyzerHandler.SetMaxResultBytes(cfg.ResultMaxBytes)
+	// /health answers from a background check of the link checker
+	linkCheckerProbe := healthprobe.New("link_checker_service", linkCheckerClient, cfg.HealthCheckInterval, cfg.HealthCheckTimeout, log)
+	probeCtx, stopProbes := context.WithCancel(context.Background())
+	defer stopProbes()
+	go linkCheckerProbe.Run(probeCtx)
+	healthHandler := handlers.NewHealthHandler(serviceName, linkCheckerProbe)
+	statsHandler := handlers.NewStatsHandler(serviceName, statsCollector)
+	readinessGate := readiness.NewGate(serviceName, log,
+		readiness.Dependency{Name: "link_checker_service", Checker: linkCheckerClient},
+	)
 
 	// Setup routes
 	router := mux.NewRouter()
 
 	// Middleware
-	router.Use(loggingMiddleware(log))
-	router.Use(metricsMiddleware(metricsCollector))
-	router.Use(recoveryMiddleware(log))
+	router.Use(middleware.RequestID)
+	router.Use(middleware.Logging(log))
+	router.Use(middleware.Metrics(metricsCollector))
+	router.Use(middleware.Recovery(log))
 
 	// Routes
-	router.HandleFunc("/analyze", analyzerHandler.Analyze).Methods("POST")
+	router.Handle("/analyze", faultInjector.Middleware(http.HandlerFunc(analyzerHandler.Analyze))).Methods("POST")
 	router.HandleFunc("/health", healthHandler.Health).Methods("GET")
-	router.Handle("/metrics", promhttp.Handler())
+	router.HandleFunc("/health/ready", readinessGate.Ready).Methods("GET")
+	router.HandleFunc("/version", version.Handler(serviceName)).Methods("GET")
+	router.HandleFunc("/stats", statsHandler.Stats).Methods("GET")
+	router.Handle("/metrics", metrics.Handler())
+
+	// Admin routes, guarded by ADMIN_TOKEN
+	logLevelHandler := admin.NewLogLevelHandler(logLevel, log)
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(admin.RequireToken(cfg.AdminToken))
+	adminRouter.HandleFunc("/loglevel", logLevelHandler.Get).Methods("GET")
+	adminRouter.HandleFunc("/loglevel", logLevelHandler.Set).Methods("PUT")
+
+	// pprof, only when DEBUG_ENDPOINTS is set
+	debugServer := admin.MountDebug(router, admin.DebugOptions{
+		Enabled: cfg.DebugEndpoints,
+		Addr:    cfg.DebugAddr,
+		Token:   cfg.AdminToken,
+	})
 
 	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%s", port),
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
 		Handler:      router,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 60 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	if cfg.InternalH2C {
+		// The callers inside the deployment speak HTTP/2 without TLS
+		srv.Handler = internalhttp.H2CHandler(router)
+	}
+
+	// HTTPS is served directly when a certificate is configured, and a
+	// renewed certificate is picked up without a restart
+	var certs *servertls.CertReloader
+	if cfg.TLSEnabled() {
+		var err error
+		certs, err = servertls.NewCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile, log)
+		if err != nil {
+			log.Error("Failed to load TLS certificate", "error", err)
+			os.Exit(1)
+		}
+		srv.TLSConfig = certs.TLSConfig()
+		go certs.Watch(context.Background(), cfg.TLSReloadInterval)
+	}
+	redirectServer := servertls.RedirectServer(cfg.TLSRedirectAddr, cfg.Port)
 
 	// Start server
 	go func() {
 		//log.Info("Starting Analyzer Service", "port", port)
 		log.Info("Starting Analyzer Service",
 			"service", serviceName,
-			"port", port,
-			"log_level", getLogLevel().String(),
-			"log_to_file", getEnv("LOG_TO_FILE", "false"),
-			"log_dir", getEnv("LOG_DIR", "./logs"),
-			"version", getEnv("APP_VERSION", "dev"),
+			"port", cfg.Port,
+			"log_level", cfg.LogLevel,
+			"log_to_file", cfg.LogToFile,
+			"log_dir", cfg.LogDir,
+			"tls", cfg.TLSEnabled(),
+			"version", version.Get().Version,
+			"commit", version.Get().Commit,
 		)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := servertls.ListenAndServe(srv); err != nil && err != http.ErrServerClosed {
 			log.Error("Failed to start server", "error", err)
 			os.Exit(1)
 		}
 	}()
 
+	if redirectServer != nil {
+		go func() {
+			log.Info("Redirecting HTTP to HTTPS", "addr", cfg.TLSRedirectAddr)
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("HTTP redirect server failed", "error", err)
+			}
+		}()
+	}
+	if debugServer != nil {
+		go func() {
+			log.Info("Serving debug endpoints", "addr", cfg.DebugAddr)
+			if err := debugServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("Debug server failed", "error", err)
+			}
+		}()
+	}
+
+	// The warm-up runs once the link checker is reachable
+	go func() {
+		waitForReadiness(readinessGate, &cfg.Common, log)
+		if err := warmUp(context.Background(), analyzer, cfg, statsCollector, log); err != nil {
+			log.Error("Startup warm-up failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	// Reload the runtime-tunable settings on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reloadConfig(cfg, logLevel, log)
+			if certs != nil {
+				certs.Reload()
+			}
+		}
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
+	signal.Stop(hup)
 
 	log.Info("Shutting down server...")
 
@@ -112,85 +344,78 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Error("Server forced to shutdown", "error", err)
 	}
+	if redirectServer != nil {
+		redirectServer.Shutdown(ctx)
+	}
+	if debugServer != nil {
+		debugServer.Shutdown(ctx)
+	}
 
 	log.Info("Server exited")
 }
 
-func loggingMiddleware(log interfaces.Logger) mux.MiddlewareFunc {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			start := time.Now()
-
-			// Wrap response writer to capture status code
-			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-
-			next.ServeHTTP(wrapped, r)
-
-			log.Info("Request completed",
-				"method", r.Method,
-				"path", r.URL.Path,
-				"status", wrapped.statusCode,
-				"duration", time.Since(start),
-				"remote_addr", r.RemoteAddr,
-			)
-		})
+// waitForReadiness blocks until the critical dependencies are reachable and
+// flips the readiness gate, exiting the process in fail-fast mode
+func waitForReadiness(gate *readiness.Gate, cfg *config.Common, log interfaces.Logger) {
+	mode := readiness.Mode(cfg.StartupMode)
+	if err := gate.Wait(context.Background(), cfg.StartupMaxWait, mode); err != nil {
+		log.Error("Startup dependencies unavailable", "error", err, "max_wait", cfg.StartupMaxWait)
+		os.Exit(1)
 	}
 }
 
-func metricsMiddleware(collector metrics.Collector) mux.MiddlewareFunc {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			start := time.Now()
-
-			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-			next.ServeHTTP(wrapped, r)
-
-			duration := time.Since(start).Seconds()
-			collector.RecordRequest(r.Method, r.URL.Path, wrapped.statusCode, duration)
-		})
-	}
+// warmer runs the analyzer's warm-up, see core.Analyzer.WarmUp
+type warmer interface {
+	WarmUp(ctx context.Context, url string) (time.Duration, error)
 }
 
-func recoveryMiddleware(log interfaces.Logger) mux.MiddlewareFunc {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			defer func() {
-				if err := recover(); err != nil {
-					log.Error("Panic recovered", "error", err, "path", r.URL.Path)
-					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-				}
-			}()
-			next.ServeHTTP(w, r)
-		})
+// warmUp runs the warm-up when it is enabled and records how long it took.
+// A failure is only logged unless STRICT_WARMUP is set; then it is returned
+// so that the service stops.
+func warmUp(ctx context.Context, analyzer warmer, cfg *config.Analyzer, collector *stats.Collector, log interfaces.Logger) error {
+	if !cfg.WarmupEnabled {
+		return nil
 	}
-}
 
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
+	ctx, cancel := context.WithTimeout(ctx, cfg.WarmupTimeout)
+	defer cancel()
+	took, err := analyzer.WarmUp(ctx, cfg.WarmupURL)
+	collector.RecordWarmup(took, err)
+	if err != nil {
+		if cfg.StrictWarmup {
+			return fmt.Errorf("warm-up failed after %s: %w", took, err)
+		}
+		log.Warn("Warm-up failed, serving anyway", "error", err, "duration", took)
+		return nil
+	}
+	log.Info("Warm-up complete", "duration", took)
+	return nil
 }
 
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
-}
+// reloadConfig re-reads the configuration on SIGHUP and applies the log level.
+// Environment variables are fixed for the life of the process, so in practice
+// new values arrive through CONFIG_FILE; every other change is reported and
+// ignored until restart.
+func reloadConfig(cfg *config.Analyzer, level *slog.LevelVar, log interfaces.Logger) {
+	next, err := config.LoadAnalyzer()
+	if err != nil {
+		log.Error("Configuration reload failed, keeping current values", "error", err)
+		return
+	}
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+	var ignored []string
+	for _, key := range config.Changed(cfg, next) {
+		switch key {
+		case "LOG_LEVEL":
+			level.Set(next.SlogLevel())
+			cfg.LogLevel = next.LogLevel
+		default:
+			ignored = append(ignored, key)
+		}
 	}
-	return defaultValue
-}
 
-func getLogLevel() slog.Level {
-	switch os.Getenv("LOG_LEVEL") {
-	case "debug":
-		return slog.LevelDebug
-	case "warn":
-		return slog.LevelWarn
-	case "error":
-		return slog.LevelError
-	default:
-		return slog.LevelInfo
+	if len(ignored) > 0 {
+		log.Warn("Configuration changes ignored until restart", "fields", ignored)
 	}
+	log.Info("Configuration reloaded", "log_level", cfg.LogLevel)
 }
@@ -0,0 +1,335 @@
+// Package render fetches pages through a headless Chrome so that content
+// built by JavaScript is visible to the HTML parser.
+package render
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// networkIdle is the lifecycle event Chrome fires once the page has had no
+// network activity for 500ms
+const networkIdle = "networkIdle"
+
+// waitShare is the part of the render timeout spent waiting for the page to
+// settle; the rest is kept for capturing the DOM
+const waitShare = 0.8
+
+// Screenshots are taken at a fixed viewport and scaled down to a thumbnail
+const (
+	viewportWidth  = 1280
+	viewportHeight = 800
+	thumbnailScale = 0.5
+)
+
+// DefaultMaxScreenshotBytes caps screenshots when Options leaves it unset
+const DefaultMaxScreenshotBytes = 1 << 20
+
+// documentScript returns the rendered DOM including its DOCTYPE, which
+// outerHTML alone drops and the parser needs for version detection
+const documentScript = `(document.doctype ? new XMLSerializer().serializeToString(document.doctype) : "") + document.documentElement.outerHTML`
+
+// ErrBrowserClosed is returned by Fetch after Close
+var ErrBrowserClosed = errors.New("headless browser is closed")
+
+// Options configures the headless browser and its resource limits
+type Options struct {
+	// MaxConcurrent caps the number of pages rendered at once; further
+	// requests wait for a free slot until their context is done
+	MaxConcurrent int
+	// Timeout bounds a single render, from navigation to DOM capture
+	Timeout time.Duration
+	// WaitSelector, when set, is awaited instead of network idle
+	WaitSelector string
+	// ExecPath overrides the Chrome binary lookup
+	ExecPath string
+	// MaxScreenshotBytes caps the size of a captured PNG
+	MaxScreenshotBytes int
+}
+
+// ChromeFetcher is a FetcherStrategy that renders pages in headless Chrome.
+// One browser process is shared and each render gets its own tab.
+type ChromeFetcher struct {
+	opts   Options
+	slots  chan struct{}
+	logger interfaces.Logger
+
+	allocCancel   context.CancelFunc
+	browserCtx    context.Context
+	browserCancel context.CancelFunc
+
+	startOnce sync.Once
+	startErr  error
+}
+
+func NewChromeFetcher(opts Options, logger interfaces.Logger) *ChromeFetcher {
+	if opts.MaxConcurrent < 1 {
+		opts.MaxConcurrent = 1
+	}
+	if opts.MaxScreenshotBytes < 1 {
+		opts.MaxScreenshotBytes = DefaultMaxScreenshotBytes
+	}
+
+	allocOpts := chromedp.DefaultExecAllocatorOptions[:]
+	if opts.ExecPath != "" {
+		allocOpts = append(allocOpts, chromedp.ExecPath(opts.ExecPath))
+	}
+
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), allocOpts...)
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx)
+
+	return &ChromeFetcher{
+		opts:          opts,
+		slots:         make(chan struct{}, opts.MaxConcurrent),
+		logger:        logger,
+		allocCancel:   allocCancel,
+		browserCtx:    browserCtx,
+		browserCancel: browserCancel,
+	}
+}
+
+// Fetch renders the page at url and returns the resulting DOM as the body
+func (f *ChromeFetcher) Fetch(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	start := time.Now()
+
+	var html, finalURL string
+	capture := chromedp.Tasks{chromedp.Evaluate(documentScript, &html), chromedp.Location(&finalURL)}
+	statusCode, err := f.render(ctx, url, nil, capture)
+	if err != nil {
+		return nil, err
+	}
+
+	f.logger.Debug("Rendered page",
+		"url", logger.RedactURL(url),
+		"status_code", statusCode,
+		"bytes", len(html),
+		"duration", time.Since(start),
+	)
+
+	return &models.HTTPResponse{
+		StatusCode: statusCode,
+		Body:       []byte(html),
+		FinalURL:   finalURL,
+	}, nil
+}
+
+// CaptureScreenshot renders the page at url in a fixed viewport and returns
+// a scaled-down PNG of it
+func (f *ChromeFetcher) CaptureScreenshot(ctx context.Context, url string) ([]byte, error) {
+	var png []byte
+	capture := chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		png, err = page.CaptureScreenshot().
+			WithFormat(page.CaptureScreenshotFormatPng).
+			WithClip(&page.Viewport{Width: viewportWidth, Height: viewportHeight, Scale: thumbnailScale}).
+			Do(ctx)
+		return err
+	})
+
+	setup := []chromedp.Action{chromedp.EmulateViewport(viewportWidth, viewportHeight)}
+	if _, err := f.render(ctx, url, setup, capture); err != nil {
+		return nil, err
+	}
+
+	if len(png) > f.opts.MaxScreenshotBytes {
+		return nil, fmt.Errorf("screenshot is %d bytes, over the %d byte cap", len(png), f.opts.MaxScreenshotBytes)
+	}
+	return png, nil
+}
+
+// render loads url in a new tab, waits for it to settle and runs capture.
+// It returns the status code of the main document.
+func (f *ChromeFetcher) render(ctx context.Context, url string, setup []chromedp.Action, capture chromedp.Action) (int, error) {
+	if err := f.acquire(ctx); err != nil {
+		return 0, fmt.Errorf("waiting for a render slot: %w", err)
+	}
+	defer f.release()
+
+	if err := f.start(); err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+
+	tabCtx, cancelTab := chromedp.NewContext(f.browserCtx)
+	defer cancelTab()
+	tabCtx, cancelTimeout := context.WithTimeout(tabCtx, f.opts.Timeout)
+	defer cancelTimeout()
+
+	// The tab lives under the browser context, so tie it to the caller too
+	stop := context.AfterFunc(ctx, cancelTab)
+	defer stop()
+
+	idle := f.listenNetworkIdle(tabCtx)
+
+	setup = append([]chromedp.Action{page.SetLifecycleEventsEnabled(true)}, setup...)
+	if header := httpclient.HeadersFromContext(ctx); len(header) > 0 {
+		// The browser asks for the same variant of the page as a plain fetch
+		extra := make(network.Headers, len(header))
+		for name := range header {
+			extra[name] = header.Get(name)
+		}
+		setup = append(setup, network.SetExtraHTTPHeaders(extra))
+	}
+	if err := chromedp.Run(tabCtx, setup...); err != nil {
+		return 0, fmt.Errorf("failed to open browser tab: %w", err)
+	}
+
+	response, err := chromedp.RunResponse(tabCtx, chromedp.Navigate(url))
+	if err != nil {
+		return 0, fmt.Errorf("failed to render URL: %w", err)
+	}
+
+	statusCode := 200
+	if response != nil && response.Status != 0 {
+		statusCode = int(response.Status)
+	}
+	if statusCode >= 400 {
+		return statusCode, &models.HTTPStatusError{StatusCode: statusCode, Challenges: challenges(statusCode, response)}
+	}
+
+	deadline := start.Add(time.Duration(float64(f.opts.Timeout) * waitShare))
+	if err := chromedp.Run(tabCtx, f.waitReady(url, idle, deadline), capture); err != nil {
+		return statusCode, fmt.Errorf("failed to render URL: %w", err)
+	}
+
+	return statusCode, nil
+}
+
+// Close shuts down the browser; in-flight renders fail
+func (f *ChromeFetcher) Close() {
+	f.browserCancel()
+	f.allocCancel()
+}
+
+func (f *ChromeFetcher) acquire(ctx context.Context) error {
+	select {
+	case f.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (f *ChromeFetcher) release() {
+	<-f.slots
+}
+
+// start launches the browser on first use so that an analyzer with rendering
+// enabled but unused never spawns Chrome
+func (f *ChromeFetcher) start() error {
+	f.startOnce.Do(func() {
+		if err := chromedp.Run(f.browserCtx); err != nil {
+			f.startErr = fmt.Errorf("failed to start headless browser: %w", err)
+			f.logger.Error("Failed to start headless browser", "error", err)
+		}
+	})
+	if f.startErr != nil {
+		return f.startErr
+	}
+	if f.browserCtx.Err() != nil {
+		return ErrBrowserClosed
+	}
+	return nil
+}
+
+// listenNetworkIdle signals once the navigated document reaches network idle.
+// Events for the previous document (about:blank) are discarded when the new
+// document starts loading.
+func (f *ChromeFetcher) listenNetworkIdle(ctx context.Context) <-chan struct{} {
+	idle := make(chan struct{}, 1)
+	var mu sync.Mutex
+	var loader string
+
+	chromedp.ListenTarget(ctx, func(ev any) {
+		event, ok := ev.(*page.EventLifecycleEvent)
+		if !ok {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch event.Name {
+		case "init":
+			loader = string(event.LoaderID)
+			select {
+			case <-idle:
+			default:
+			}
+		case networkIdle:
+			if string(event.LoaderID) == loader {
+				select {
+				case idle <- struct{}{}:
+				default:
+				}
+			}
+		}
+	})
+
+	return idle
+}
+
+// waitReady waits for the configured selector, or for network idle. If the
+// page has not settled by deadline the DOM is captured as it is.
+func (f *ChromeFetcher) waitReady(url string, idle <-chan struct{}, deadline time.Time) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		waitCtx, cancel := context.WithDeadline(ctx, deadline)
+		defer cancel()
+
+		var err error
+		if f.opts.WaitSelector != "" {
+			err = chromedp.WaitReady(f.opts.WaitSelector, chromedp.ByQuery).Do(waitCtx)
+		} else {
+			select {
+			case <-idle:
+			case <-waitCtx.Done():
+				err = waitCtx.Err()
+			}
+		}
+
+		if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			f.logger.Debug("Page did not settle before the render timeout, capturing current DOM",
+				"url", logger.RedactURL(url),
+				"wait_selector", f.opts.WaitSelector,
+			)
+			return nil
+		}
+		return err
+	})
+}
+
+// challenges returns the authentication challenges of a 401 or 407
+// response. Chrome joins the values of a repeated header with newlines.
+func challenges(statusCode int, response *network.Response) []string {
+	var name string
+	switch {
+	case response == nil:
+		return nil
+	case statusCode == http.StatusUnauthorized:
+		name = "WWW-Authenticate"
+	case statusCode == http.StatusProxyAuthRequired:
+		name = "Proxy-Authenticate"
+	default:
+		return nil
+	}
+	for key, value := range response.Headers {
+		if values, ok := value.(string); ok && strings.EqualFold(key, name) {
+			return strings.Split(values, "\n")
+		}
+	}
+	return nil
+}
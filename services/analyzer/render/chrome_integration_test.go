@@ -0,0 +1,142 @@
+//go:build integration
+
+// Run with: go test -tags integration ./services/analyzer/render/
+// Requires a Chrome or Chromium binary on PATH, or CHROME_PATH.
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// spaPage builds its content in JavaScript after a delay, like a
+// single-page app; a plain HTTP fetch only sees the empty shell
+const spaPage = `<!DOCTYPE html>
+<html><head><title>SPA</title></head>
+<body><div id="app"></div>
+<script>
+setTimeout(function () {
+	document.getElementById("app").innerHTML =
+		'<h1>Rendered</h1><h2>Section</h2><a href="/about">About</a><a href="https://example.com">Out</a>';
+	document.body.setAttribute("data-ready", "true");
+}, 200);
+</script>
+</body></html>`
+
+func chromePath(t *testing.T) string {
+	if path := os.Getenv("CHROME_PATH"); path != "" {
+		return path
+	}
+	for _, name := range []string{"google-chrome", "chromium", "chromium-browser", "headless-shell"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	t.Skip("no Chrome binary found, set CHROME_PATH")
+	return ""
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(spaPage))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestIntegrationChromeFetcher_RendersJavaScript(t *testing.T) {
+	server := newTestServer(t)
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"network idle", Options{MaxConcurrent: 1, Timeout: 20 * time.Second}},
+		{"wait selector", Options{MaxConcurrent: 1, Timeout: 20 * time.Second, WaitSelector: "body[data-ready]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.ExecPath = chromePath(t)
+			fetcher := NewChromeFetcher(tt.opts, testLogger())
+			defer fetcher.Close()
+
+			response, err := fetcher.Fetch(context.Background(), server.URL)
+			require.NoError(t, err)
+
+			body := string(response.Body)
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			assert.True(t, strings.HasPrefix(body, "<!DOCTYPE html>"))
+			assert.Contains(t, body, "<h1>Rendered</h1>")
+			assert.Contains(t, body, `href="/about"`)
+		})
+	}
+}
+
+func TestIntegrationChromeFetcher_HTTPError(t *testing.T) {
+	server := newTestServer(t)
+	fetcher := NewChromeFetcher(Options{MaxConcurrent: 1, Timeout: 20 * time.Second, ExecPath: chromePath(t)}, testLogger())
+	defer fetcher.Close()
+
+	_, err := fetcher.Fetch(context.Background(), server.URL+"/missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP error: status code 404")
+}
+
+func TestIntegrationChromeFetcher_Concurrent(t *testing.T) {
+	server := newTestServer(t)
+	fetcher := NewChromeFetcher(Options{MaxConcurrent: 2, Timeout: 20 * time.Second, ExecPath: chromePath(t)}, testLogger())
+	defer fetcher.Close()
+
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			_, err := fetcher.Fetch(context.Background(), server.URL)
+			errs <- err
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, <-errs)
+	}
+}
+
+func TestIntegrationChromeFetcher_CaptureScreenshot(t *testing.T) {
+	server := newTestServer(t)
+	fetcher := NewChromeFetcher(Options{MaxConcurrent: 1, Timeout: 20 * time.Second, ExecPath: chromePath(t)}, testLogger())
+	defer fetcher.Close()
+
+	data, err := fetcher.CaptureScreenshot(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, viewportWidth*thumbnailScale, float64(img.Bounds().Dx()))
+	assert.Equal(t, viewportHeight*thumbnailScale, float64(img.Bounds().Dy()))
+}
+
+func TestIntegrationChromeFetcher_ScreenshotSizeCap(t *testing.T) {
+	server := newTestServer(t)
+	fetcher := NewChromeFetcher(Options{MaxConcurrent: 1, Timeout: 20 * time.Second, ExecPath: chromePath(t), MaxScreenshotBytes: 10}, testLogger())
+	defer fetcher.Close()
+
+	_, err := fetcher.CaptureScreenshot(context.Background(), server.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "byte cap")
+}
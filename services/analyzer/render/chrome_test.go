@@ -0,0 +1,73 @@
+package render
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLogger() interfaces.Logger {
+	return logger.NewAdapter(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestNewChromeFetcher_DoesNotStartBrowser(t *testing.T) {
+	fetcher := NewChromeFetcher(Options{MaxConcurrent: 0, Timeout: time.Second}, testLogger())
+	defer fetcher.Close()
+
+	// The browser is only launched by the first Fetch
+	assert.NoError(t, fetcher.browserCtx.Err())
+	assert.Equal(t, 1, cap(fetcher.slots))
+}
+
+func TestChromeFetcher_LimitsConcurrentRenders(t *testing.T) {
+	fetcher := NewChromeFetcher(Options{MaxConcurrent: 2, Timeout: time.Second}, testLogger())
+	defer fetcher.Close()
+
+	ctx := context.Background()
+	require.NoError(t, fetcher.acquire(ctx))
+	require.NoError(t, fetcher.acquire(ctx))
+
+	// A third render waits and gives up with its context
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, fetcher.acquire(waitCtx), context.DeadlineExceeded)
+
+	// Releasing a slot admits the next render
+	fetcher.release()
+	assert.NoError(t, fetcher.acquire(ctx))
+}
+
+func TestChromeFetcher_FetchWaitsForSlot(t *testing.T) {
+	fetcher := NewChromeFetcher(Options{MaxConcurrent: 1, Timeout: time.Second}, testLogger())
+	defer fetcher.Close()
+
+	require.NoError(t, fetcher.acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := fetcher.Fetch(ctx, "https://example.com")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "waiting for a render slot")
+}
+
+func TestChallenges(t *testing.T) {
+	response := &network.Response{Headers: network.Headers{
+		"www-authenticate":   "Negotiate\nBasic realm=\"intranet\"",
+		"proxy-authenticate": "Basic realm=\"proxy\"",
+	}}
+	assert.Equal(t, []string{"Negotiate", `Basic realm="intranet"`}, challenges(http.StatusUnauthorized, response))
+	assert.Equal(t, []string{`Basic realm="proxy"`}, challenges(http.StatusProxyAuthRequired, response))
+	assert.Nil(t, challenges(http.StatusForbidden, response))
+	assert.Nil(t, challenges(http.StatusUnauthorized, nil))
+	assert.Nil(t, challenges(http.StatusUnauthorized, &network.Response{}))
+}
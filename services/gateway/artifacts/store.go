@@ -0,0 +1,173 @@
+// Package artifacts keeps short-lived binary artifacts, such as page
+// screenshots, in memory and serves them over HTTP until they expire.
+package artifacts
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/httperror"
+	"github.com/gorilla/mux"
+)
+
+// ErrTooLarge is returned by Put for artifacts over the size cap
+var ErrTooLarge = errors.New("artifact exceeds size cap")
+
+// Artifact is a stored blob and its metadata
+type Artifact struct {
+	ContentType string
+	Data        []byte
+	ExpiresAt   time.Time
+}
+
+// Store is an in-memory artifact store with a per-artifact TTL. When full,
+// the artifact closest to expiry is evicted to make room.
+type Store struct {
+	mu       sync.Mutex
+	items    map[string]Artifact
+	ttl      time.Duration
+	maxItems int
+	maxBytes int
+
+	now func() time.Time
+}
+
+func NewStore(ttl time.Duration, maxItems, maxBytes int) *Store {
+	if maxItems < 1 {
+		maxItems = 1
+	}
+	return &Store{
+		items:    make(map[string]Artifact),
+		ttl:      ttl,
+		maxItems: maxItems,
+		maxBytes: maxBytes,
+		now:      time.Now,
+	}
+}
+
+// Put stores data and returns its id
+func (s *Store) Put(contentType string, data []byte) (string, error) {
+	if len(data) > s.maxBytes {
+		return "", fmt.Errorf("%w: %d bytes, cap is %d", ErrTooLarge, len(data), s.maxBytes)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.evictLocked(now)
+	for len(s.items) >= s.maxItems {
+		s.evictOldestLocked()
+	}
+
+	s.items[id] = Artifact{
+		ContentType: contentType,
+		Data:        data,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+	return id, nil
+}
+
+// Get returns the artifact with the given id unless it has expired
+func (s *Store) Get(id string) (Artifact, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	artifact, ok := s.items[id]
+	if !ok {
+		return Artifact{}, false
+	}
+	if !s.now().Before(artifact.ExpiresAt) {
+		delete(s.items, id)
+		return Artifact{}, false
+	}
+	return artifact, true
+}
+
+// Len returns the number of stored artifacts, including expired ones not yet evicted
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+// Evict removes expired artifacts and returns how many were removed
+func (s *Store) Evict() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.evictLocked(s.now())
+}
+
+// Run evicts expired artifacts every interval until ctx is done
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.Evict()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Store) evictLocked(now time.Time) int {
+	evicted := 0
+	for id, artifact := range s.items {
+		if !now.Before(artifact.ExpiresAt) {
+			delete(s.items, id)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+func (s *Store) evictOldestLocked() {
+	var oldestID string
+	var oldest time.Time
+	for id, artifact := range s.items {
+		if oldestID == "" || artifact.ExpiresAt.Before(oldest) {
+			oldestID, oldest = id, artifact.ExpiresAt
+		}
+	}
+	delete(s.items, oldestID)
+}
+
+// Handler serves GET {prefix}/artifacts/{id}
+func (s *Store) Handler(w http.ResponseWriter, r *http.Request) {
+	artifact, ok := s.Get(mux.Vars(r)["id"])
+	if !ok {
+		httperror.Write(w, r, httperror.New("Artifact not found or expired", http.StatusNotFound))
+		return
+	}
+
+	maxAge := int(artifact.ExpiresAt.Sub(s.now()).Seconds())
+	w.Header().Set("Content-Type", artifact.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(artifact.Data)))
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(artifact.Data)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate artifact id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
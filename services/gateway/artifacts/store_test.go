@@ -0,0 +1,134 @@
+package artifacts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStore returns a store whose clock only moves when advance is called
+func newTestStore(ttl time.Duration, maxItems int) (*Store, func(time.Duration)) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewStore(ttl, maxItems, 1024)
+	store.now = func() time.Time { return now }
+	return store, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestStore_PutAndGet(t *testing.T) {
+	store, _ := newTestStore(time.Minute, 10)
+
+	id, err := store.Put("image/png", []byte("png"))
+	require.NoError(t, err)
+	assert.Len(t, id, 32)
+
+	artifact, ok := store.Get(id)
+	require.True(t, ok)
+	assert.Equal(t, "image/png", artifact.ContentType)
+	assert.Equal(t, []byte("png"), artifact.Data)
+
+	_, ok = store.Get("unknown")
+	assert.False(t, ok)
+}
+
+func TestStore_TTLExpiry(t *testing.T) {
+	store, advance := newTestStore(time.Minute, 10)
+
+	id, err := store.Put("image/png", []byte("png"))
+	require.NoError(t, err)
+
+	advance(59 * time.Second)
+	_, ok := store.Get(id)
+	assert.True(t, ok)
+
+	advance(time.Second)
+	_, ok = store.Get(id)
+	assert.False(t, ok)
+	assert.Equal(t, 0, store.Len())
+}
+
+func TestStore_EvictRemovesOnlyExpired(t *testing.T) {
+	store, advance := newTestStore(time.Minute, 10)
+
+	old, _ := store.Put("image/png", []byte("old"))
+	advance(30 * time.Second)
+	fresh, _ := store.Put("image/png", []byte("fresh"))
+	advance(30 * time.Second)
+
+	assert.Equal(t, 1, store.Evict())
+	assert.Equal(t, 1, store.Len())
+
+	_, ok := store.Get(old)
+	assert.False(t, ok)
+	_, ok = store.Get(fresh)
+	assert.True(t, ok)
+}
+
+func TestStore_FullEvictsClosestToExpiry(t *testing.T) {
+	store, advance := newTestStore(time.Minute, 2)
+
+	first, _ := store.Put("image/png", []byte("1"))
+	advance(time.Second)
+	second, _ := store.Put("image/png", []byte("2"))
+	advance(time.Second)
+	third, _ := store.Put("image/png", []byte("3"))
+
+	assert.Equal(t, 2, store.Len())
+	_, ok := store.Get(first)
+	assert.False(t, ok)
+	_, ok = store.Get(second)
+	assert.True(t, ok)
+	_, ok = store.Get(third)
+	assert.True(t, ok)
+}
+
+func TestStore_SizeCap(t *testing.T) {
+	store, _ := newTestStore(time.Minute, 10)
+
+	_, err := store.Put("image/png", make([]byte, 1025))
+	assert.ErrorIs(t, err, ErrTooLarge)
+	assert.Equal(t, 0, store.Len())
+}
+
+func TestStore_Handler(t *testing.T) {
+	store, advance := newTestStore(time.Minute, 10)
+	id, err := store.Put("image/png", []byte("png-bytes"))
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/artifacts/{id}", store.Handler).Methods("GET")
+
+	t.Run("found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/artifacts/"+id, nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.Equal(t, "private, max-age=60", w.Header().Get("Cache-Control"))
+		assert.Equal(t, "png-bytes", w.Body.String())
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/artifacts/nope", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		var body models.ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+		assert.Equal(t, "Artifact not found or expired", body.Error)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		advance(time.Minute)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/artifacts/"+id, nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -6,53 +6,191 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/contextkeys"
+	"github.com/RuvinSL/webpage-analyzer/pkg/faults"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/internalhttp"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
 )
 
 type AnalyzerClient interface {
 	Analyze(ctx context.Context, url string) (*models.AnalysisResult, error)
+	AnalyzeWithOptions(ctx context.Context, url string, opts models.AnalysisOptions) (*models.AnalysisResult, error)
 	CheckHealth(ctx context.Context) error
 }
 
+// Analyses are retried at most maxAnalyzeRetries times, after a jittered
+// backoff starting at analyzeRetryBackoff and doubling
+const (
+	maxAnalyzeRetries   = 2
+	analyzeRetryBackoff = 200 * time.Millisecond
+)
+
+// AnalyzerError is an error response of the analyzer service. Response is
+// the ErrorResponse it sent, if any; errors it reports in a known form, such
+// as models.UnsupportedContentTypeError, are unwrapped from it.
+type AnalyzerError struct {
+	StatusCode int
+	Response   models.ErrorResponse
+	// body is the redacted response body when it is not an ErrorResponse
+	body string
+	err  error
+}
+
+func (e *AnalyzerError) Error() string {
+	switch {
+	case e.err != nil:
+		return fmt.Sprintf("analyzer service error (status %d): %v", e.StatusCode, e.err)
+	case e.Response.Error != "":
+		return fmt.Sprintf("analyzer service error (status %d): %s", e.StatusCode, e.Response.Error)
+	}
+	return fmt.Sprintf("analyzer service returned status %d: %s", e.StatusCode, e.body)
+}
+
+func (e *AnalyzerError) Unwrap() error {
+	return e.err
+}
+
 type HTTPAnalyzerClient struct {
 	baseURL    string
 	httpClient *http.Client
-	logger     interfaces.Logger
+	// transport is the HTTP/1.1 transport, used unless h2c is enabled
+	transport *http.Transport
+	h2c       bool
+	faults    *faults.Injector
+	logger    interfaces.Logger
+	metrics   interfaces.MetricsCollector
+
+	maxRetries   int
+	retryBackoff time.Duration
+
+	// logResultContent logs page titles and whole URLs, see
+	// SetLogResultContent
+	logResultContent bool
 }
 
-func NewAnalyzerClient(baseURL string, timeout time.Duration, logger interfaces.Logger) AnalyzerClient {
+func NewAnalyzerClient(baseURL string, timeout time.Duration, logger interfaces.Logger) *HTTPAnalyzerClient {
+	transport := &http.Transport{
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     30 * time.Second,
+	}
 	return &HTTPAnalyzerClient{
 		baseURL: baseURL,
 		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        10,
-				MaxIdleConnsPerHost: 10,
-				IdleConnTimeout:     30 * time.Second,
-			},
+			Timeout:   60 * time.Second,
+			Transport: transport,
 		},
-		logger: logger,
+		transport:    transport,
+		logger:       logger,
+		maxRetries:   maxAnalyzeRetries,
+		retryBackoff: analyzeRetryBackoff,
 	}
 }
 
+// SetLogResultContent logs the titles and whole URLs of the pages analyzed,
+// redacted, at debug level. Otherwise, the default, only their hosts are
+// logged: page content can be sensitive.
+func (c *HTTPAnalyzerClient) SetLogResultContent(enabled bool) {
+	c.logResultContent = enabled
+}
+
+// SetMetrics counts the retried analyzer calls in metrics
+func (c *HTTPAnalyzerClient) SetMetrics(metrics interfaces.MetricsCollector) {
+	c.metrics = metrics
+}
+
+// SetMaxRetries sets how many times a failed call may be retried; zero
+// makes every call once
+func (c *HTTPAnalyzerClient) SetMaxRetries(n int) {
+	c.maxRetries = n
+}
+
+// SetH2C makes the calls to an http:// analyzer use HTTP/2 over cleartext,
+// which the analyzer has to serve as well
+func (c *HTTPAnalyzerClient) SetH2C(enabled bool) {
+	c.h2c = enabled
+	c.setTransport()
+}
+
+// SetFaults injects the faults of injector's analyzer rules into the calls
+func (c *HTTPAnalyzerClient) SetFaults(injector *faults.Injector) {
+	c.faults = injector
+	c.setTransport()
+}
+
+func (c *HTTPAnalyzerClient) setTransport() {
+	c.httpClient.Transport = c.faults.Transport(faults.Analyzer, internalhttp.Transport(c.baseURL, c.h2c, c.transport))
+}
+
 func (c *HTTPAnalyzerClient) Analyze(ctx context.Context, url string) (*models.AnalysisResult, error) {
-	// Enhanced logging with request details
-	requestID, _ := ctx.Value("request_id").(string)
-	c.logger.Info("Starting analyzer service call",
-		"url", url,
+	return c.AnalyzeWithOptions(ctx, url, models.AnalysisOptions{})
+}
+
+// AnalyzeWithOptions forwards the per-request options to the analyzer
+// service. An analysis only reads the page, so a call lost to a connection
+// error or answered with 502, 503 or 504 is retried, as long as the retry
+// fits in ctx's deadline; errors the analyzer reports are not.
+func (c *HTTPAnalyzerClient) AnalyzeWithOptions(ctx context.Context, url string, opts models.AnalysisOptions) (*models.AnalysisResult, error) {
+	requestID := contextkeys.RequestIDFrom(ctx)
+	backoff := c.retryBackoff
+
+	for attempt := 0; ; attempt++ {
+		result, retryReason, err := c.analyze(ctx, url, opts)
+		if err == nil || retryReason == "" || attempt == c.maxRetries {
+			return result, err
+		}
+
+		// The jitter keeps gateways that lost the analyzer together from
+		// coming back at the same moment
+		wait := rand.N(backoff) + backoff/2
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return nil, err
+		}
+		c.logger.Warn("Retrying analyzer service call",
+			"url", logger.RedactPageURL(url, c.logResultContent),
+			"attempt", attempt+1,
+			"reason", retryReason,
+			"retry_in", wait,
+			"error", err,
+			"request_id", requestID)
+		if c.metrics != nil {
+			c.metrics.RecordUpstreamRetry("analyzer", retryReason)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// analyze makes one call to the analyzer. On failure it also returns the
+// reason to retry the call, "connection" or the HTTP status, or "" when
+// retrying would not help.
+func (c *HTTPAnalyzerClient) analyze(ctx context.Context, url string, opts models.AnalysisOptions) (*models.AnalysisResult, string, error) {
+	requestID := contextkeys.RequestIDFrom(ctx)
+	c.logger.Debug("Starting analyzer service call",
+		"url", logger.RedactPageURL(url, c.logResultContent),
 		"analyzer_endpoint", c.baseURL,
 		"request_id", requestID)
 
 	// Prepare request
-	reqBody := models.AnalysisRequest{URL: url}
+	reqBody := models.AnalysisRequest{URL: url, AnalysisOptions: opts}
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		c.logger.Error("Failed to marshal analysis request", "error", err, "url", url)
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		c.logger.Error("Failed to marshal analysis request", "error", err, "url", logger.RedactPageURL(url, c.logResultContent))
+		return nil, "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Create HTTP request
@@ -60,14 +198,14 @@ func (c *HTTPAnalyzerClient) Analyze(ctx context.Context, url string) (*models.A
 	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonData))
 	if err != nil {
 		c.logger.Error("Failed to create HTTP request", "error", err, "endpoint", endpoint)
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 
 	if requestID != "" {
-		req.Header.Set("X-Request-ID", requestID)
+		req.Header.Set(contextkeys.RequestIDHeader, requestID)
 	}
 
 	// Send request with detailed logging
@@ -86,7 +224,7 @@ func (c *HTTPAnalyzerClient) Analyze(ctx context.Context, url string) (*models.A
 			"duration", duration,
 			"endpoint", endpoint,
 			"request_id", requestID)
-		return nil, fmt.Errorf("analyzer service error: %w", err)
+		return nil, connectionRetry(ctx), fmt.Errorf("analyzer service error: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -105,24 +243,37 @@ func (c *HTTPAnalyzerClient) Analyze(ctx context.Context, url string) (*models.A
 			"error", err,
 			"status_code", resp.StatusCode,
 			"request_id", requestID)
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, connectionRetry(ctx), fmt.Errorf("failed to read response: %w", err)
 	}
 
 	// Enhanced error handling
 	if resp.StatusCode != http.StatusOK {
 		c.logger.Error("Analyzer service returned error",
 			"status_code", resp.StatusCode,
-			"response_body", string(responseBody),
+			"response_body", logger.RedactBody(responseBody),
 			"request_id", requestID)
 
 		// Try to parse structured error response
-		var errorResp models.ErrorResponse
-		if err := json.Unmarshal(responseBody, &errorResp); err == nil && errorResp.Error != "" {
-			return nil, fmt.Errorf("analyzer service error (status %d): %s", resp.StatusCode, errorResp.Error)
+		analyzerErr := &AnalyzerError{StatusCode: resp.StatusCode}
+		if err := json.Unmarshal(responseBody, &analyzerErr.Response); err == nil && analyzerErr.Response.Error != "" {
+			errorResp := analyzerErr.Response
+			switch errorResp.Code {
+			case models.ErrorCodeInvalidResult:
+				analyzerErr.err = models.ErrInvalidResult
+			case models.ErrorCodeUnsupportedContentType:
+				analyzerErr.err = &models.UnsupportedContentTypeError{ContentType: errorResp.ContentType, Bytes: errorResp.ContentBytes}
+			case models.ErrorCodeTargetBusy:
+				analyzerErr.err = &models.TargetBusyError{Host: errorResp.Host, RetryAfter: time.Duration(errorResp.RetryAfterSeconds) * time.Second}
+			default:
+				return nil, statusRetry(resp.StatusCode), analyzerErr
+			}
+			return nil, "", analyzerErr
 		}
 
 		// Fallback to generic error with response body
-		return nil, fmt.Errorf("analyzer service returned status %d: %s", resp.StatusCode, string(responseBody))
+		analyzerErr.Response = models.ErrorResponse{}
+		analyzerErr.body = logger.RedactBody(responseBody)
+		return nil, statusRetry(resp.StatusCode), analyzerErr
 	}
 
 	// Parse response with enhanced error handling
@@ -130,67 +281,98 @@ func (c *HTTPAnalyzerClient) Analyze(ctx context.Context, url string) (*models.A
 	if err := json.Unmarshal(responseBody, &result); err != nil {
 		c.logger.Error("Failed to parse analyzer response",
 			"error", err,
-			"response_body", string(responseBody),
+			"response_body", logger.RedactBody(responseBody),
 			"request_id", requestID)
-		return nil, fmt.Errorf("failed to parse analyzer response: %w", err)
+		return nil, "", fmt.Errorf("failed to parse analyzer response: %w", err)
 	}
 
-	// Log successful response details - using only basic fields
-	c.logger.Info("Analyzer service call completed successfully",
-		"url", url,
-		"result_url", result.URL,
-		"title", result.Title,
-		"html_version", result.HTMLVersion,
-		"has_login_form", result.HasLoginForm,
+	// Never pass on a result breaking the models invariants
+	if err := result.Validate(url); err != nil {
+		c.logger.Error("Analyzer returned an invalid result",
+			"url", logger.RedactPageURL(url, c.logResultContent),
+			"error", err,
+			"request_id", requestID)
+		return nil, "", err
+	}
+
+	// Counts only at info; what the page says is left to debug. This line
+	// is the audit record of what each caller's analyses downloaded.
+	downloaded := totalBytes(result.Traffic)
+	caller := contextkeys.CallerFrom(ctx)
+	c.logger.Info("Analyzer service call completed",
+		"request_id", requestID,
+		"caller", caller,
 		"duration", duration,
-		"request_id", requestID)
+		"status_code", resp.StatusCode,
+		"total_links", result.Links.Total,
+		"inaccessible_links", result.Links.Inaccessible,
+		"total_headings", totalHeadings(result.Headings),
+		"total_bytes", downloaded)
+	if c.metrics != nil && caller != "" && downloaded > 0 {
+		c.metrics.RecordCallerBytes(caller, downloaded)
+	}
+	c.logResultDetails(url, &result, requestID)
+
+	return &result, "", nil
+}
 
-	// Log detailed analysis results
-	c.logAnalysisDetails(&result, requestID)
+// connectionRetry is the retry reason of a call that failed on the
+// connection, unless it failed because ctx ended
+func connectionRetry(ctx context.Context) string {
+	if ctx.Err() != nil {
+		return ""
+	}
+	return "connection"
+}
 
-	return &result, nil
+// statusRetry is the retry reason of an analyzer response with status code:
+// the gateway statuses, which mean the analyzer could not be reached or was
+// briefly unavailable, but never 4xx or 500
+func statusRetry(code int) string {
+	switch code {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return strconv.Itoa(code)
+	}
+	return ""
 }
 
-// logAnalysisDetails logs the detailed analysis results
-func (c *HTTPAnalyzerClient) logAnalysisDetails(result *models.AnalysisResult, requestID string) {
-	// Log basic details
-	c.logger.Debug("Analysis result summary",
-		"url", result.URL,
-		"title", result.Title,
+// logResultDetails logs the result of an analysis in one debug entry, with
+// its title and whole URLs only when result content is logged
+func (c *HTTPAnalyzerClient) logResultDetails(url string, result *models.AnalysisResult, requestID string) {
+	args := []any{
+		"request_id", requestID,
+		"url", logger.RedactPageURL(url, c.logResultContent),
+		"final_url", logger.RedactPageURL(result.FinalURL, c.logResultContent),
 		"html_version", result.HTMLVersion,
 		"has_login_form", result.HasLoginForm,
-		"request_id", requestID)
-
-	// Log heading counts (assuming HeadingCount has H1, H2, etc. fields)
-	c.logger.Debug("Heading analysis",
 		"h1_count", result.Headings.H1,
 		"h2_count", result.Headings.H2,
 		"h3_count", result.Headings.H3,
 		"h4_count", result.Headings.H4,
 		"h5_count", result.Headings.H5,
 		"h6_count", result.Headings.H6,
-		"total_headings", result.Headings.H1+result.Headings.H2+result.Headings.H3+result.Headings.H4+result.Headings.H5+result.Headings.H6,
-		"request_id", requestID)
-
-	// Log link summary (assuming LinkSummary has Total, Internal, External, Inaccessible fields)
-	c.logger.Debug("Link analysis summary",
-		"total_links", result.Links.Total,
 		"internal_links", result.Links.Internal,
 		"external_links", result.Links.External,
 		"inaccessible_links", result.Links.Inaccessible,
-		"request_id", requestID)
+	}
+	if c.logResultContent {
+		args = append(args, "title", result.Title)
+	}
+	c.logger.Debug("Analysis result details", args...)
+}
 
-	// Special attention to inaccessible links for your debugging
-	if result.Links.Inaccessible > 0 {
-		c.logger.Warn("Found inaccessible links",
-			"inaccessible_count", result.Links.Inaccessible,
-			"total_links", result.Links.Total,
-			"request_id", requestID)
-	} else {
-		c.logger.Debug("All links are accessible",
-			"total_links", result.Links.Total,
-			"request_id", requestID)
+// totalHeadings is the number of headings of every level
+func totalHeadings(h models.HeadingCount) int {
+	return h.H1 + h.H2 + h.H3 + h.H4 + h.H5 + h.H6
+}
+
+// totalBytes is what an analysis downloaded, zero from analyzers that do
+// not report it
+func totalBytes(t *models.Traffic) int64 {
+	if t == nil {
+		return 0
 	}
+	return t.Bytes
 }
 
 func (c *HTTPAnalyzerClient) CheckHealth(ctx context.Context) error {
@@ -226,8 +408,8 @@ func (c *HTTPAnalyzerClient) CheckHealth(ctx context.Context) error {
 		body, _ := io.ReadAll(resp.Body)
 		c.logger.Warn("Analyzer service health check failed",
 			"status_code", resp.StatusCode,
-			"response_body", string(body))
-		return fmt.Errorf("unhealthy status: %d - %s", resp.StatusCode, string(body))
+			"response_body", logger.RedactBody(body))
+		return fmt.Errorf("unhealthy status: %d - %s", resp.StatusCode, logger.RedactBody(body))
 	}
 
 	c.logger.Debug("Analyzer service health check passed")
@@ -1,13 +1,21 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/contextkeys"
+	"github.com/RuvinSL/webpage-analyzer/pkg/faults"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
 	"github.com/RuvinSL/webpage-analyzer/pkg/mocks"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
 	"github.com/golang/mock/gomock"
@@ -92,12 +100,9 @@ func TestNewAnalyzerClient(t *testing.T) {
 
 	assert.NotNil(t, client)
 
-	// Test that it returns the correct type
-	httpClient, ok := client.(*HTTPAnalyzerClient)
-	assert.True(t, ok)
-	assert.Equal(t, baseURL, httpClient.baseURL)
-	assert.NotNil(t, httpClient.httpClient)
-	assert.Equal(t, mockLogger, httpClient.logger)
+	assert.Equal(t, baseURL, client.baseURL)
+	assert.NotNil(t, client.httpClient)
+	assert.Equal(t, mockLogger, client.logger)
 }
 
 func TestHTTPAnalyzerClient_Analyze_Success(t *testing.T) {
@@ -126,6 +131,7 @@ func TestHTTPAnalyzerClient_Analyze_Success(t *testing.T) {
 			Inaccessible: 0,
 		},
 		HasLoginForm: false,
+		AnalyzedAt:   time.Now(),
 	}
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -161,6 +167,30 @@ func TestHTTPAnalyzerClient_Analyze_Success(t *testing.T) {
 	assert.Equal(t, expectedResult.Links, result.Links)
 }
 
+func TestHTTPAnalyzerClient_AnalyzeWithOptions_ForwardsRender(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var raw map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&raw))
+		json.NewEncoder(w).Encode(models.AnalysisResult{URL: "https://example.com", AnalyzedAt: time.Now()})
+	}))
+	defer server.Close()
+
+	client := NewAnalyzerClient(server.URL, 30*time.Second, setupMockLogger(ctrl))
+
+	_, err := client.AnalyzeWithOptions(context.Background(), "https://example.com", models.AnalysisOptions{Render: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"url": "https://example.com", "render": true}, raw)
+
+	// The plain call keeps the original request body
+	_, err = client.Analyze(context.Background(), "https://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"url": "https://example.com"}, raw)
+}
+
 func TestHTTPAnalyzerClient_Analyze_WithRequestID(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -173,10 +203,11 @@ func TestHTTPAnalyzerClient_Analyze_WithRequestID(t *testing.T) {
 		assert.Equal(t, requestID, r.Header.Get("X-Request-ID"))
 
 		result := &models.AnalysisResult{
-			URL:      "https://example.com",
-			Title:    "Test",
-			Headings: models.HeadingCount{},
-			Links:    models.LinkSummary{},
+			URL:        "https://example.com",
+			Title:      "Test",
+			Headings:   models.HeadingCount{},
+			Links:      models.LinkSummary{},
+			AnalyzedAt: time.Now(),
 		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(result)
@@ -184,12 +215,34 @@ func TestHTTPAnalyzerClient_Analyze_WithRequestID(t *testing.T) {
 	defer server.Close()
 
 	client := NewAnalyzerClient(server.URL, 30*time.Second, mockLogger)
-	ctx := context.WithValue(context.Background(), "request_id", requestID)
+	ctx := contextkeys.WithRequestID(context.Background(), requestID)
 
 	_, err := client.Analyze(ctx, "https://example.com")
 	require.NoError(t, err)
 }
 
+func TestHTTPAnalyzerClient_Analyze_CountsCallerBytes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(models.AnalysisResult{
+			URL:        "https://example.com",
+			Traffic:    &models.Traffic{Bytes: 4096},
+			AnalyzedAt: time.Now(),
+		})
+	}))
+	defer server.Close()
+
+	metrics := mocks.NewMockMetricsCollector(ctrl)
+	metrics.EXPECT().RecordCallerBytes("key-0123456789ab", int64(4096)).Times(1)
+	client := NewAnalyzerClient(server.URL, 30*time.Second, setupMockLogger(ctrl))
+	client.SetMetrics(metrics)
+
+	ctx := contextkeys.WithCaller(context.Background(), "key-0123456789ab")
+	_, err := client.Analyze(ctx, "https://example.com")
+	require.NoError(t, err)
+}
+
 func TestHTTPAnalyzerClient_Analyze_ServerError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -216,6 +269,47 @@ func TestHTTPAnalyzerClient_Analyze_ServerError(t *testing.T) {
 	assert.Contains(t, err.Error(), "Internal server error")
 }
 
+func TestHTTPAnalyzerClient_Analyze_InvalidResult(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "result for another URL",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(models.AnalysisResult{URL: "https://example.org", AnalyzedAt: time.Now()})
+			},
+		},
+		{
+			name: "rejected by the analyzer",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(models.ErrorResponse{
+					Error:      "Analysis produced an invalid result",
+					StatusCode: http.StatusInternalServerError,
+					Code:       models.ErrorCodeInvalidResult,
+				})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			client := NewAnalyzerClient(server.URL, 30*time.Second, setupMockLogger(ctrl))
+			result, err := client.Analyze(context.Background(), "https://example.com")
+
+			assert.ErrorIs(t, err, models.ErrInvalidResult)
+			assert.Nil(t, result)
+		})
+	}
+}
+
 func TestHTTPAnalyzerClient_Analyze_ServerError_InvalidJSON(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -238,6 +332,40 @@ func TestHTTPAnalyzerClient_Analyze_ServerError_InvalidJSON(t *testing.T) {
 	assert.Contains(t, err.Error(), "analyzer service returned status 500")
 }
 
+func TestHTTPAnalyzerClient_Analyze_ReturnsAnalyzerError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.AnalysisRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if req.URL == "https://example.com/plain" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("no"))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(models.ErrorResponse{Error: "HTTP error: status code 404", StatusCode: 400})
+	}))
+	defer server.Close()
+
+	client := NewAnalyzerClient(server.URL, 30*time.Second, setupMockLogger(ctrl))
+	_, err := client.Analyze(context.Background(), "https://example.com/missing")
+
+	var analyzerErr *AnalyzerError
+	require.ErrorAs(t, err, &analyzerErr)
+	assert.Equal(t, http.StatusBadRequest, analyzerErr.StatusCode)
+	assert.Equal(t, "HTTP error: status code 404", analyzerErr.Response.Error)
+	assert.Equal(t, "analyzer service error (status 400): HTTP error: status code 404", err.Error())
+
+	_, err = client.Analyze(context.Background(), "https://example.com/plain")
+
+	require.ErrorAs(t, err, &analyzerErr)
+	assert.Equal(t, http.StatusBadRequest, analyzerErr.StatusCode)
+	assert.Empty(t, analyzerErr.Response.Error)
+	assert.Equal(t, "analyzer service returned status 400: no", err.Error())
+}
+
 func TestHTTPAnalyzerClient_Analyze_NetworkError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -411,7 +539,7 @@ func BenchmarkHTTPAnalyzerClient_Analyze(b *testing.B) {
 }
 
 func createTestContextWithRequestID(requestID string) context.Context {
-	return context.WithValue(context.Background(), "request_id", requestID)
+	return contextkeys.WithRequestID(context.Background(), requestID)
 }
 
 func TestHTTPAnalyzerClient_Analyze_ErrorScenarios(t *testing.T) {
@@ -470,3 +598,251 @@ func TestHTTPAnalyzerClient_Analyze_ErrorScenarios(t *testing.T) {
 		})
 	}
 }
+
+// flakyAnalyzer answers the first failures calls with status and the rest
+// with a valid result, counting the calls it gets
+func flakyAnalyzer(t *testing.T, failures int, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	calls := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.AnalysisRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		if int(calls.Add(1)) <= failures {
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(models.ErrorResponse{Error: http.StatusText(status), StatusCode: status})
+			return
+		}
+		json.NewEncoder(w).Encode(models.AnalysisResult{
+			URL:         req.URL,
+			HTMLVersion: "HTML5",
+			AnalyzedAt:  time.Now(),
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+func newRetryingClient(ctrl *gomock.Controller, baseURL string) *HTTPAnalyzerClient {
+	client := NewAnalyzerClient(baseURL, 30*time.Second, setupMockLogger(ctrl))
+	client.retryBackoff = time.Millisecond
+	return client
+}
+
+func TestHTTPAnalyzerClient_Analyze_RetriesTransientFailures(t *testing.T) {
+	for _, status := range []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		t.Run(strconv.Itoa(status), func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			server, calls := flakyAnalyzer(t, 1, status)
+
+			metrics := mocks.NewMockMetricsCollector(ctrl)
+			metrics.EXPECT().RecordUpstreamRetry("analyzer", strconv.Itoa(status)).Times(1)
+			client := newRetryingClient(ctrl, server.URL)
+			client.SetMetrics(metrics)
+
+			result, err := client.Analyze(context.Background(), "https://example.com")
+
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com", result.URL)
+			assert.Equal(t, int32(2), calls.Load())
+		})
+	}
+}
+
+func TestHTTPAnalyzerClient_Analyze_GivesUpAfterTwoRetries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	server, calls := flakyAnalyzer(t, 10, http.StatusServiceUnavailable)
+	client := newRetryingClient(ctrl, server.URL)
+
+	_, err := client.Analyze(context.Background(), "https://example.com")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 503")
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestHTTPAnalyzerClient_Analyze_DoesNotRetryOtherErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusTooManyRequests, http.StatusInternalServerError} {
+		t.Run(strconv.Itoa(status), func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			server, calls := flakyAnalyzer(t, 1, status)
+			client := newRetryingClient(ctrl, server.URL)
+
+			_, err := client.Analyze(context.Background(), "https://example.com")
+
+			require.Error(t, err)
+			assert.Equal(t, int32(1), calls.Load())
+		})
+	}
+}
+
+func TestHTTPAnalyzerClient_Analyze_InjectedFaults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	server, calls := flakyAnalyzer(t, 0, http.StatusOK)
+	injector, err := faults.New("analyzer:error=503", 1)
+	require.NoError(t, err)
+
+	metrics := mocks.NewMockMetricsCollector(ctrl)
+	metrics.EXPECT().RecordUpstreamRetry("analyzer", "503").Times(2)
+	client := newRetryingClient(ctrl, server.URL)
+	client.SetMetrics(metrics)
+	client.SetFaults(injector)
+	// The faults outlast a change of transport
+	client.SetH2C(true)
+
+	_, err = client.Analyze(context.Background(), "https://example.com")
+
+	var analyzerErr *AnalyzerError
+	require.ErrorAs(t, err, &analyzerErr)
+	assert.Equal(t, http.StatusServiceUnavailable, analyzerErr.StatusCode)
+	assert.Equal(t, faults.ErrorCode, analyzerErr.Response.Code)
+	assert.Zero(t, calls.Load(), "injected errors never reach the analyzer")
+}
+
+func TestHTTPAnalyzerClient_Analyze_RetriesConnectionErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	calls := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.AnalysisRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		// The first connection is dropped before any answer
+		if calls.Add(1) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			conn.Close()
+			return
+		}
+		json.NewEncoder(w).Encode(models.AnalysisResult{URL: req.URL, HTMLVersion: "HTML5", AnalyzedAt: time.Now()})
+	}))
+	defer server.Close()
+
+	metrics := mocks.NewMockMetricsCollector(ctrl)
+	metrics.EXPECT().RecordUpstreamRetry("analyzer", "connection").Times(1)
+	client := newRetryingClient(ctrl, server.URL)
+	client.SetMetrics(metrics)
+
+	result, err := client.Analyze(context.Background(), "https://example.com")
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", result.URL)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestHTTPAnalyzerClient_Analyze_RetryMustFitTheDeadline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	server, calls := flakyAnalyzer(t, 1, http.StatusServiceUnavailable)
+	client := NewAnalyzerClient(server.URL, 30*time.Second, setupMockLogger(ctrl))
+	client.retryBackoff = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := client.Analyze(ctx, "https://example.com")
+
+	require.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestHTTPAnalyzerClient_CheckHealth_DoesNotRetry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	calls := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client := newRetryingClient(ctrl, server.URL)
+
+	require.Error(t, client.CheckHealth(context.Background()))
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+// logRecords decodes the JSON log lines in buf
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestHTTPAnalyzerClient_Analyze_LogsResultsByLevel(t *testing.T) {
+	const pageURL = "https://example.com/account/settings?token=abc"
+	result := models.AnalysisResult{
+		URL:         pageURL,
+		FinalURL:    "https://example.com/account/settings",
+		Title:       "Jane Doe's settings",
+		HTMLVersion: "HTML5",
+		Headings:    models.HeadingCount{H1: 1, H2: 2},
+		Links:       models.LinkSummary{Total: 3, Internal: 2, External: 1, Inaccessible: 1},
+		AnalyzedAt:  time.Now(),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(result)
+	}))
+	defer server.Close()
+
+	for _, logContent := range []bool{false, true} {
+		t.Run("log_result_content="+strconv.FormatBool(logContent), func(t *testing.T) {
+			var buf bytes.Buffer
+			log := logger.NewAdapter(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+			client := NewAnalyzerClient(server.URL, 5*time.Second, log)
+			client.SetLogResultContent(logContent)
+
+			ctx := contextkeys.WithRequestID(context.Background(), "req-1")
+			_, err := client.Analyze(ctx, pageURL)
+			require.NoError(t, err)
+
+			var info, details []map[string]any
+			for _, record := range logRecords(t, &buf) {
+				switch {
+				case record["level"] == "INFO":
+					info = append(info, record)
+				case record["msg"] == "Analysis result details":
+					details = append(details, record)
+				}
+			}
+
+			// One info line, with counts only
+			require.Len(t, info, 1)
+			assert.Equal(t, "Analyzer service call completed", info[0]["msg"])
+			assert.Equal(t, "req-1", info[0]["request_id"])
+			assert.EqualValues(t, http.StatusOK, info[0]["status_code"])
+			assert.EqualValues(t, 3, info[0]["total_links"])
+			assert.EqualValues(t, 1, info[0]["inaccessible_links"])
+			assert.EqualValues(t, 3, info[0]["total_headings"])
+			assert.Contains(t, info[0], "duration")
+			for _, field := range []string{"url", "result_url", "final_url", "title", "html_version"} {
+				assert.NotContains(t, info[0], field)
+			}
+
+			// The rest of the result in one debug entry
+			require.Len(t, details, 1)
+			assert.Equal(t, "DEBUG", details[0]["level"])
+			assert.Equal(t, "HTML5", details[0]["html_version"])
+			assert.EqualValues(t, 2, details[0]["h2_count"])
+			assert.EqualValues(t, 1, details[0]["external_links"])
+			if logContent {
+				assert.Equal(t, "Jane Doe's settings", details[0]["title"])
+				assert.Equal(t, "https://example.com/account/settings?token=[REDACTED]", details[0]["url"])
+				assert.Equal(t, "https://example.com/account/settings", details[0]["final_url"])
+			} else {
+				assert.NotContains(t, details[0], "title")
+				assert.Equal(t, "example.com", details[0]["url"])
+				assert.Equal(t, "example.com", details[0]["final_url"])
+			}
+
+			assert.NotContains(t, buf.String(), "token=abc")
+			if !logContent {
+				assert.NotContains(t, buf.String(), "Jane Doe")
+				assert.NotContains(t, buf.String(), "/account/settings")
+			}
+		})
+	}
+}
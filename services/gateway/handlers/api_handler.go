@@ -1,18 +1,48 @@
 package handlers
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/crosspage"
+	"github.com/RuvinSL/webpage-analyzer/pkg/fieldset"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httperror"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/rules"
+	"github.com/RuvinSL/webpage-analyzer/pkg/storage"
+	"github.com/RuvinSL/webpage-analyzer/pkg/urlutil"
+	"github.com/RuvinSL/webpage-analyzer/services/gateway/artifacts"
+	"github.com/RuvinSL/webpage-analyzer/services/gateway/translate"
 )
 
+// Route prefixes of the API versions, used to build artifact URLs
+const (
+	apiV1Prefix = "/api/v1"
+	apiV2Prefix = "/api/v2"
+)
+
+// screenshotDataPrefix marks an inline screenshot as sent by the analyzer
+const screenshotDataPrefix = "data:image/png;base64,"
+
 type APIHandler struct {
 	analyzerClient AnalyzerClient
 	logger         interfaces.Logger
 	metrics        interfaces.MetricsCollector
+	artifacts      *artifacts.Store
+	results        storage.Store
+	mirror         *Mirror
+	// urls decides which spellings of a URL the batch findings compare
+	// equal
+	urls *urlutil.Normalizer
 }
 
 func NewAPIHandler(analyzerClient AnalyzerClient, logger interfaces.Logger, metrics interfaces.MetricsCollector) *APIHandler {
@@ -20,118 +50,396 @@ func NewAPIHandler(analyzerClient AnalyzerClient, logger interfaces.Logger, metr
 		analyzerClient: analyzerClient,
 		logger:         logger,
 		metrics:        metrics,
+		urls:           urlutil.Default(),
 	}
 }
 
+// SetArtifactStore moves screenshots into store and returns their artifact
+// URLs instead of inline data URIs
+func (h *APIHandler) SetArtifactStore(store *artifacts.Store) {
+	h.artifacts = store
+}
+
+// SetResultStore saves every successful analysis result to store
+func (h *APIHandler) SetResultStore(store storage.Store) {
+	h.results = store
+}
+
+// SetMirror sends a share of the single analyses to a second analyzer as
+// well, see Mirror
+func (h *APIHandler) SetMirror(mirror *Mirror) {
+	h.mirror = mirror
+}
+
+// SetURLNormalization compares the pages of a batch under normalizer, by
+// default under urlutil.DefaultPolicy; it should match the analyzer's
+func (h *APIHandler) SetURLNormalization(normalizer *urlutil.Normalizer) {
+	h.urls = normalizer
+}
+
+// AnalyzeURL serves POST /api/v1/analyze with the legacy response shape
 func (h *APIHandler) AnalyzeURL(w http.ResponseWriter, r *http.Request) {
+	result, ok := h.analyze(w, r, apiV1Prefix)
+	if !ok {
+		return
+	}
+
+	h.sendJSON(w, translate.ToV1(result))
+}
+
+// AnalyzeURLV2 serves POST /api/v2/analyze. The optional query parameters
+// of resultView shape the result.
+func (h *APIHandler) AnalyzeURLV2(w http.ResponseWriter, r *http.Request) {
+	view, err := parseResultView(r)
+	if err != nil {
+		h.sendError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, ok := h.analyze(w, r, apiV2Prefix)
+	if !ok {
+		return
+	}
+
+	v2 := translate.ToV2(result)
+	view.apply(&v2)
+	h.sendView(w, r, view, v2)
+}
+
+// BatchAnalyze serves POST /api/v1/batch-analyze with the legacy response shape
+func (h *APIHandler) BatchAnalyze(w http.ResponseWriter, r *http.Request) {
+	batch, ok := h.batch(w, r, apiV1Prefix)
+	if !ok {
+		return
+	}
+
+	h.sendJSON(w, translate.BatchToV1(batch))
+}
+
+// BatchAnalyzeV2 serves POST /api/v2/batch-analyze. The optional query
+// parameters of resultView shape the result of every item.
+func (h *APIHandler) BatchAnalyzeV2(w http.ResponseWriter, r *http.Request) {
+	view, err := parseResultView(r)
+	if err != nil {
+		h.sendError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	batch, ok := h.batch(w, r, apiV2Prefix)
+	if !ok {
+		return
+	}
+
+	response := translate.BatchToV2(batch)
+	for _, item := range response.Items {
+		if item.Result != nil {
+			view.apply(item.Result)
+		}
+	}
+	h.sendView(w, r, view, response, "items", fieldset.Each, "result")
+}
+
+// analyze parses and validates a single analysis request and runs it. On
+// failure the error response has already been written.
+func (h *APIHandler) analyze(w http.ResponseWriter, r *http.Request, apiPrefix string) (*models.AnalysisResult, bool) {
 	ctx := r.Context()
 
 	// Parse request
 	var req models.AnalysisRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Error("Failed to parse request", "error", err)
-		h.sendError(w, "Invalid request format", http.StatusBadRequest)
-		return
+		h.sendError(w, r, "Invalid request format", http.StatusBadRequest)
+		return nil, false
 	}
 
 	// Validate URL
 	if req.URL == "" {
-		h.sendError(w, "URL is required", http.StatusBadRequest)
-		return
+		h.sendError(w, r, "URL is required", http.StatusBadRequest)
+		return nil, false
 	}
 
-	// Call analyzer service
-	h.logger.Info("Processing analysis request", "url", req.URL)
+	if err := models.ValidateAcceptLanguage(req.AcceptLanguage); err != nil {
+		h.sendError(w, r, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
 
-	result, err := h.analyzerClient.Analyze(ctx, req.URL)
-	if err != nil {
-		h.logger.Error("Analysis failed", "url", req.URL, "error", err)
+	if err := rules.ValidateSelection(req.Rules); err != nil {
+		h.sendError(w, r, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
 
-		if err.Error() == "context deadline exceeded" {
-			h.sendError(w, "Analysis timeout", http.StatusGatewayTimeout)
-		} else {
-			h.sendError(w, "Analysis failed: "+err.Error(), http.StatusInternalServerError)
-		}
-		return
+	if err := models.ValidateChecks(req.Checks); err != nil {
+		h.sendError(w, r, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if err := models.ValidateLinkCheckOptions(req.LinkCheck); err != nil {
+		h.sendError(w, r, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if err := models.ValidateHostOverrides(req.HostOverrides); err != nil {
+		h.sendError(w, r, err.Error(), http.StatusBadRequest)
+		return nil, false
 	}
 
-	// Send response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	// Call analyzer service
+	h.logger.Info("Processing analysis request", "url", logger.RedactURL(req.URL))
 
-	if err := json.NewEncoder(w).Encode(result); err != nil {
-		h.logger.Error("Failed to encode response", "error", err)
+	mirrored := h.mirror.Send(ctx, req.URL, req.AnalysisOptions)
+	result, err := h.analyzerClient.AnalyzeWithOptions(ctx, req.URL, req.AnalysisOptions)
+	if err != nil {
+		mirrored(nil)
+	}
+	if err != nil && errors.Is(err, context.Canceled) {
+		// The analyzer stops the analysis too; the status is for the
+		// request log
+		h.logger.Info("Client closed request", "url", logger.RedactURL(req.URL), "status", models.StatusClientClosedRequest)
+		w.WriteHeader(models.StatusClientClosedRequest)
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Error("Analysis failed", "url", logger.RedactURL(req.URL), "error", err)
+		h.sendErrorResponse(w, r, analysisError(err))
+		return nil, false
 	}
+
+	h.storeScreenshot(result, apiPrefix)
+	mirrored(result)
+	h.saveResult(ctx, result)
+	return result, true
 }
 
-func (h *APIHandler) BatchAnalyze(w http.ResponseWriter, r *http.Request) {
+// batch parses and validates a batch request and analyzes every URL. On
+// failure the error response has already been written.
+func (h *APIHandler) batch(w http.ResponseWriter, r *http.Request, apiPrefix string) (translate.Batch, bool) {
 	ctx := r.Context()
 
 	// Parse request
-	var req models.BatchAnalysisRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to parse batch request", "error", err)
-		h.sendError(w, "Invalid request format", http.StatusBadRequest)
-		return
+	req, ok := h.decodeBatch(w, r)
+	if !ok {
+		return translate.Batch{}, false
 	}
 
 	// Validate URLs
 	if len(req.URLs) == 0 {
-		h.sendError(w, "At least one URL is required", http.StatusBadRequest)
-		return
+		h.sendError(w, r, "At least one URL is required", http.StatusBadRequest)
+		return translate.Batch{}, false
 	}
 
-	if len(req.URLs) > 100 {
-		h.sendError(w, "Maximum 100 URLs allowed per batch", http.StatusBadRequest)
-		return
+	if len(req.URLs) > maxBatchURLs {
+		h.sendError(w, r, fmt.Sprintf("Maximum %d URLs allowed per batch", maxBatchURLs), http.StatusBadRequest)
+		return translate.Batch{}, false
+	}
+
+	if err := models.ValidateAcceptLanguage(req.AcceptLanguage); err != nil {
+		h.sendError(w, r, err.Error(), http.StatusBadRequest)
+		return translate.Batch{}, false
+	}
+
+	if err := rules.ValidateSelection(req.Rules); err != nil {
+		h.sendError(w, r, err.Error(), http.StatusBadRequest)
+		return translate.Batch{}, false
+	}
+
+	if err := models.ValidateChecks(req.Checks); err != nil {
+		h.sendError(w, r, err.Error(), http.StatusBadRequest)
+		return translate.Batch{}, false
+	}
+	if err := models.ValidateLinkCheckOptions(req.LinkCheck); err != nil {
+		h.sendError(w, r, err.Error(), http.StatusBadRequest)
+		return translate.Batch{}, false
+	}
+	if err := models.ValidateHostOverrides(req.HostOverrides); err != nil {
+		h.sendError(w, r, err.Error(), http.StatusBadRequest)
+		return translate.Batch{}, false
 	}
 
-	// Process URLs concurrently - Ruvin
 	start := time.Now()
-	results := make([]models.AnalysisResult, 0, len(req.URLs))
-	errors := make([]models.ErrorResponse, 0)
+	batch := translate.Batch{Items: make([]translate.BatchItem, 0, len(req.URLs))}
 
 	for _, url := range req.URLs {
-		result, err := h.analyzerClient.Analyze(ctx, url)
+		item := translate.BatchItem{URL: url}
+		result, err := h.analyzerClient.AnalyzeWithOptions(ctx, url, req.AnalysisOptions)
+		if errors.Is(err, context.Canceled) {
+			// The rest of the batch is not analyzed for nobody
+			h.logger.Info("Client closed request", "url_count", len(req.URLs), "analyzed_count", len(batch.Items),
+				"status", models.StatusClientClosedRequest)
+			w.WriteHeader(models.StatusClientClosedRequest)
+			return translate.Batch{}, false
+		}
 		if err != nil {
-			errors = append(errors, models.ErrorResponse{
-				Error:     err.Error(),
-				Details:   "Failed to analyze: " + url,
-				Timestamp: time.Now(),
-			})
+			response := analysisError(err)
+			item.Error = &response
 		} else {
-			results = append(results, *result)
+			h.storeScreenshot(result, apiPrefix)
+			h.saveResult(ctx, result)
+			item.Result = result
 		}
+		batch.Items = append(batch.Items, item)
+	}
+
+	results := make([]*models.AnalysisResult, len(batch.Items))
+	for i, item := range batch.Items {
+		results[i] = item.Result
+	}
+	batch.Findings = crosspage.Find(results, h.urls)
+
+	batch.TotalTime = time.Since(start)
+	return batch, true
+}
+
+// decodeBatch reads a batch request: JSON, or a text/csv or text/plain list
+// of URLs analyzed with the default options. On failure the error response
+// has already been written.
+func (h *APIHandler) decodeBatch(w http.ResponseWriter, r *http.Request) (models.BatchAnalysisRequest, bool) {
+	var req models.BatchAnalysisRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != mediaTypeCSV && mediaType != mediaTypePlain {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Error("Failed to parse batch request", "error", err)
+			h.sendError(w, r, "Invalid request format", http.StatusBadRequest)
+			return req, false
+		}
+		return req, true
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxBatchListBytes)
+	var err error
+	if mediaType == mediaTypeCSV {
+		req.URLs, err = parseCSVList(body)
+	} else {
+		req.URLs, err = parsePlainList(body)
+	}
+
+	var tooLarge *http.MaxBytesError
+	var invalid *batchListError
+	switch {
+	case err == nil:
+		return req, true
+	case errors.As(err, &tooLarge):
+		h.sendError(w, r, fmt.Sprintf("URL list larger than %d KiB", maxBatchListBytes>>10), http.StatusRequestEntityTooLarge)
+	case errors.As(err, &invalid), errors.Is(err, errBatchListTooLong):
+		h.sendError(w, r, err.Error(), http.StatusBadRequest)
+	default:
+		h.logger.Error("Failed to read batch URL list", "error", err)
+		h.sendError(w, r, "Invalid request format", http.StatusBadRequest)
+	}
+	return req, false
+}
+
+// storeScreenshot replaces an inline screenshot with a link to it in the
+// artifact store. If it cannot be stored the screenshot is dropped, never
+// the analysis.
+func (h *APIHandler) storeScreenshot(result *models.AnalysisResult, apiPrefix string) {
+	if h.artifacts == nil || !strings.HasPrefix(result.Screenshot, screenshotDataPrefix) {
+		return
 	}
 
-	// Build response
-	response := models.BatchAnalysisResult{
-		Results:   results,
-		Errors:    errors,
-		TotalTime: time.Since(start),
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(result.Screenshot, screenshotDataPrefix))
+	var id string
+	if err == nil {
+		id, err = h.artifacts.Put("image/png", data)
+	}
+	if err != nil {
+		h.logger.Warn("Failed to store screenshot, omitting it", "error", err)
+		result.Screenshot = ""
+		return
 	}
 
-	// Send response
+	result.Screenshot = apiPrefix + "/artifacts/" + id
+}
+
+// saveResult keeps result in the result store, if any. A failed save is
+// logged and never fails the analysis.
+func (h *APIHandler) saveResult(ctx context.Context, result *models.AnalysisResult) {
+	if h.results == nil {
+		return
+	}
+	if _, err := h.results.SaveResult(ctx, result); err != nil {
+		h.logger.Warn("Failed to save analysis result", "url", logger.RedactURL(result.URL), "error", err)
+	}
+}
+
+// sendJSON writes a 200 response with the given body
+func (h *APIHandler) sendJSON(w http.ResponseWriter, body any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		h.logger.Error("Failed to encode batch response", "error", err)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.logger.Error("Failed to encode response", "error", err)
+	}
+}
+
+// sendView writes body with the fields view selects of the results at the
+// path at
+func (h *APIHandler) sendView(w http.ResponseWriter, r *http.Request, view resultView, body any, at ...string) {
+	projected, err := view.project(body, at...)
+	if err != nil {
+		h.logger.Error("Failed to select response fields", "error", err)
+		h.sendError(w, r, "Failed to encode response", http.StatusInternalServerError)
+		return
 	}
+	h.sendJSON(w, projected)
 }
 
 // sendError sends an error response
-func (h *APIHandler) sendError(w http.ResponseWriter, message string, statusCode int) {
-	response := models.ErrorResponse{
-		Error:      message,
-		StatusCode: statusCode,
-		Timestamp:  time.Now(),
+func (h *APIHandler) sendError(w http.ResponseWriter, r *http.Request, message string, statusCode int) {
+	h.sendErrorResponse(w, r, errorResponse(message, statusCode, ""))
+}
+
+// analysisError is the error response for a failed analysis. The analyzer's
+// 4xx responses are passed on with its status and message, its other
+// failures are answered with 502 and timeouts, its own or the gateway's,
+// with 504.
+func analysisError(err error) models.ErrorResponse {
+	if response, ok := passThroughError(err); ok {
+		return response
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+	var analyzerErr *AnalyzerError
+	upstream := errors.As(err, &analyzerErr)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded),
+		upstream && analyzerErr.StatusCode == http.StatusGatewayTimeout:
+		return errorResponse("Analysis timeout", http.StatusGatewayTimeout, "")
+	case errors.Is(err, models.ErrInvalidResult):
+		return errorResponse("Analysis produced an invalid result", http.StatusInternalServerError, models.ErrorCodeInvalidResult)
+	case upstream && analyzerErr.StatusCode >= 400 && analyzerErr.StatusCode < 500:
+		message := analyzerErr.Response.Error
+		if message == "" {
+			message = http.StatusText(analyzerErr.StatusCode)
+		}
+		return errorResponse(message, analyzerErr.StatusCode, analyzerErr.Response.Code)
+	}
+	return errorResponse("Analysis failed: "+err.Error(), http.StatusBadGateway, "")
+}
+
+// errorResponse is an error response carrying a models.ErrorCode* code, if
+// any
+func errorResponse(message string, statusCode int, code string) models.ErrorResponse {
+	response := httperror.New(message, statusCode)
+	response.Code = code
+	return response
+}
+
+// passThroughError returns the analyzer's own response for the errors it
+// reports to the caller as they are, such as a page that is not HTML or a
+// busy target host
+func passThroughError(err error) (models.ErrorResponse, bool) {
+	var unsupported *models.UnsupportedContentTypeError
+	if errors.As(err, &unsupported) {
+		return unsupported.Response(), true
+	}
+	var busy *models.TargetBusyError
+	if errors.As(err, &busy) {
+		return busy.Response(), true
+	}
+	return models.ErrorResponse{}, false
+}
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+// sendErrorResponse sends response with its status code, and Retry-After
+// when it says when to retry, as HTML to browsers
+func (h *APIHandler) sendErrorResponse(w http.ResponseWriter, r *http.Request, response models.ErrorResponse) {
+	if err := httperror.Write(w, r, response); err != nil {
 		h.logger.Error("Failed to encode error response", "error", err)
 	}
 }
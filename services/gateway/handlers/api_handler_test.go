@@ -0,0 +1,790 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/findings"
+	pkgmiddleware "github.com/RuvinSL/webpage-analyzer/pkg/middleware"
+	"github.com/RuvinSL/webpage-analyzer/pkg/mocks"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/storage"
+	"github.com/RuvinSL/webpage-analyzer/services/gateway/artifacts"
+	"github.com/RuvinSL/webpage-analyzer/services/gateway/middleware"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const brokenURL = "https://broken.example"
+
+// invalidURL makes the fake analyzer answer with a zero-value result
+const invalidURL = "https://invalid.example"
+
+// pdfURL makes the fake analyzer answer that the page is a PDF
+const pdfURL = "https://example.com/report.pdf"
+
+// busyURL makes the fake analyzer answer that its host is busy
+const busyURL = "https://slow.example/page"
+
+// movedURL makes the fake analyzer warn that the page redirected
+const movedURL = "https://example.com/moved"
+
+// findingsURL makes the fake analyzer report a finding of each severity
+const findingsURL = "https://example.com/findings"
+
+var testPNG = []byte("\x89PNG\r\n\x1a\nthumbnail")
+
+var testTimings = &models.Timings{FetchMs: 120.5, HTMLVersionDetectionMs: 0.01, ParseMs: 2.25, LinkCheckMs: 800, TotalMs: 923}
+
+// newContractServer wires both API versions the way gateway main.go does,
+// backed by a fake analyzer that fails for brokenURL, returns an invalid
+// result for invalidURL, rejects pdfURL as not HTML and busyURL for its busy
+// host, warns about movedURL and reports findings for findingsURL
+func newContractServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+
+	analyzer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.AnalysisRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		if req.URL == brokenURL {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(models.ErrorResponse{Error: "Failed to analyze URL", StatusCode: 500})
+			return
+		}
+		if req.URL == invalidURL {
+			json.NewEncoder(w).Encode(models.AnalysisResult{})
+			return
+		}
+		if req.URL == pdfURL {
+			unsupported := &models.UnsupportedContentTypeError{ContentType: "application/pdf", Bytes: 2048}
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(unsupported.Response())
+			return
+		}
+		if req.URL == busyURL {
+			busy := &models.TargetBusyError{Host: "slow.example", RetryAfter: 5 * time.Second}
+			w.Header().Set("Retry-After", "5")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(busy.Response())
+			return
+		}
+
+		result := models.AnalysisResult{
+			URL:         req.URL,
+			HTMLVersion: "HTML5",
+			Title:       "Example Domain",
+			Headings:    models.HeadingCount{H1: 1},
+			Links:       models.LinkSummary{Internal: 1, External: 1, Inaccessible: 1, Total: 2},
+			AnalyzedAt:  time.Now(),
+			Timings:     testTimings,
+		}
+		if req.Screenshot {
+			result.Screenshot = screenshotDataPrefix + base64.StdEncoding.EncodeToString(testPNG)
+		}
+		if req.ReportRedirectedLinks {
+			for _, path := range []string{"/a", "/b", "/c"} {
+				result.RedirectedLinks = append(result.RedirectedLinks, models.RedirectedLink{
+					URL: req.URL + path, FinalURL: req.URL + path + "/", Redirects: 1,
+				})
+			}
+		}
+		if req.URL == movedURL {
+			result.Warnings = []models.Warning{{
+				Code:    models.WarningRedirected,
+				Message: "The URL redirected, https://example.com/new was analyzed instead",
+				Context: map[string]string{"final_url": "https://example.com/new"},
+			}}
+		}
+		if req.URL == findingsURL {
+			result.Findings = []models.Finding{
+				findings.New(findings.HeadingsMultipleH1, "The page has 2 h1 headings", models.FindingEvidence{Count: 2}),
+				findings.New(findings.RobotsNoIndex, "Search engines are told not to index the page", models.FindingEvidence{}),
+				findings.New(findings.TitleMissing, "The page has no title", models.FindingEvidence{}),
+			}
+			result.FindingSummary = findings.Summarize(result.Findings)
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	t.Cleanup(analyzer.Close)
+
+	client := NewAnalyzerClient(analyzer.URL, 5*time.Second, setupMockLogger(ctrl))
+	apiHandler := NewAPIHandler(client, setupMockLogger(ctrl), mocks.NewMockMetricsCollector(ctrl))
+	store := artifacts.NewStore(time.Minute, 10, 1<<20)
+	apiHandler.SetArtifactStore(store)
+	results := storage.NewMemory(100)
+	apiHandler.SetResultStore(results)
+	resultsHandler := NewResultsHandler(results, setupMockLogger(ctrl))
+
+	limiter := middleware.NewLimiter("test", 10, 10, time.Second)
+
+	router := mux.NewRouter()
+	router.Use(pkgmiddleware.RequestID)
+	apiV1 := router.PathPrefix("/api/v1").Subrouter()
+	apiV1.Use(middleware.Deprecation(time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC), "/api/v2"))
+	apiV1.Handle("/analyze", limiter.Limit(http.HandlerFunc(apiHandler.AnalyzeURL))).Methods("POST")
+	apiV1.Handle("/batch-analyze", limiter.Limit(http.HandlerFunc(apiHandler.BatchAnalyze))).Methods("POST")
+	apiV1.HandleFunc("/artifacts/{id}", store.Handler).Methods("GET")
+
+	apiV2 := router.PathPrefix("/api/v2").Subrouter()
+	apiV2.Handle("/analyze", limiter.Limit(http.HandlerFunc(apiHandler.AnalyzeURLV2))).Methods("POST")
+	apiV2.Handle("/batch-analyze", limiter.Limit(http.HandlerFunc(apiHandler.BatchAnalyzeV2))).Methods("POST")
+	apiV2.HandleFunc("/artifacts/{id}", store.Handler).Methods("GET")
+	apiV2.HandleFunc("/results", resultsHandler.List).Methods("GET")
+	apiV2.HandleFunc("/results/{id}", resultsHandler.Get).Methods("GET")
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// get fetches path and decodes the JSON response into a generic map
+func get(t *testing.T, server *httptest.Server, path string) (*http.Response, map[string]any) {
+	t.Helper()
+
+	resp, err := http.Get(server.URL + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var decoded map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+	return resp, decoded
+}
+
+// post sends body to path and decodes the JSON response into a generic map
+func post(t *testing.T, server *httptest.Server, path, body string) (*http.Response, map[string]any) {
+	t.Helper()
+
+	resp, err := http.Post(server.URL+path, "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var decoded map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+	return resp, decoded
+}
+
+func keys(m map[string]any) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+func TestContractV1_Analyze(t *testing.T) {
+	server := newContractServer(t)
+
+	resp, body := post(t, server, "/api/v1/analyze", `{"url":"https://example.com"}`)
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.ElementsMatch(t, []string{
+		"url", "html_version", "title", "headings", "links", "has_login_form", "analyzed_at", "timings",
+	}, keys(body))
+	assert.Equal(t, "https://example.com", body["url"])
+
+	assert.Equal(t, "true", resp.Header.Get("Deprecation"))
+	assert.Equal(t, "Wed, 30 Jun 2027 00:00:00 GMT", resp.Header.Get("Sunset"))
+	assert.Equal(t, `</api/v2>; rel="successor-version"`, resp.Header.Get("Link"))
+}
+
+func TestContractV1_BatchAnalyze(t *testing.T) {
+	server := newContractServer(t)
+
+	resp, body := post(t, server, "/api/v1/batch-analyze",
+		`{"urls":["https://example.com","`+brokenURL+`","https://example.org"]}`)
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.ElementsMatch(t, []string{"results", "errors", "total_time", "cross_page_findings"}, keys(body))
+	assert.Len(t, body["results"], 2)
+	assertSharedTitleFinding(t, body["cross_page_findings"])
+
+	errors := body["errors"].([]any)
+	require.Len(t, errors, 1)
+	failure := errors[0].(map[string]any)
+	assert.Equal(t, "Failed to analyze: "+brokenURL, failure["details"])
+	assert.Equal(t, float64(http.StatusBadGateway), failure["status_code"])
+
+	// total_time stays in nanoseconds for v1 clients
+	assert.IsType(t, float64(0), body["total_time"])
+	assert.Equal(t, "true", resp.Header.Get("Deprecation"))
+}
+
+// assertSharedTitleFinding checks the findings of a contract batch, whose
+// two successful pages share the fake analyzer's title
+func assertSharedTitleFinding(t *testing.T, findings any) {
+	t.Helper()
+	assert.Equal(t, map[string]any{
+		"duplicate_titles": []any{map[string]any{
+			"title": "Example Domain",
+			"urls":  []any{"https://example.com", "https://example.org"},
+		}},
+	}, findings)
+}
+
+func TestContractV2_Analyze(t *testing.T) {
+	server := newContractServer(t)
+
+	resp, body := post(t, server, "/api/v2/analyze", `{"url":"https://example.com"}`)
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.ElementsMatch(t, []string{
+		"url", "html_version", "title", "headings", "links", "has_login_form", "analyzed_at", "timings", "warnings",
+	}, keys(body))
+	assert.Equal(t, []any{"1 of 2 links are inaccessible"}, body["warnings"])
+
+	assert.Empty(t, resp.Header.Get("Deprecation"))
+	assert.Empty(t, resp.Header.Get("Sunset"))
+}
+
+func TestContractV2_BatchAnalyze(t *testing.T) {
+	server := newContractServer(t)
+
+	resp, body := post(t, server, "/api/v2/batch-analyze",
+		`{"urls":["https://example.com","`+brokenURL+`","https://example.org"]}`)
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.ElementsMatch(t, []string{"items", "succeeded", "failed", "total_time_ms", "cross_page_findings"}, keys(body))
+	assert.Equal(t, float64(2), body["succeeded"])
+	assert.Equal(t, float64(1), body["failed"])
+	assertSharedTitleFinding(t, body["cross_page_findings"])
+
+	// Items keep the request order and carry their own URL
+	items := body["items"].([]any)
+	require.Len(t, items, 3)
+	for i, want := range []struct{ url, status string }{
+		{"https://example.com", "succeeded"},
+		{brokenURL, "failed"},
+		{"https://example.org", "succeeded"},
+	} {
+		item := items[i].(map[string]any)
+		assert.Equal(t, want.url, item["url"])
+		assert.Equal(t, want.status, item["status"])
+	}
+
+	failure := items[1].(map[string]any)["error"].(map[string]any)
+	assert.Equal(t, float64(http.StatusBadGateway), failure["status_code"])
+	assert.NotContains(t, failure, "details")
+}
+
+func TestContractV2_SavedResults(t *testing.T) {
+	server := newContractServer(t)
+
+	post(t, server, "/api/v1/analyze", `{"url":"https://example.com"}`)
+	post(t, server, "/api/v2/batch-analyze", `{"urls":["https://example.org","`+brokenURL+`"]}`)
+
+	resp, body := get(t, server, "/api/v2/results")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	saved := body["results"].([]any)
+	require.Len(t, saved, 2, "failed analyses are not saved")
+
+	newest := saved[0].(map[string]any)
+	assert.ElementsMatch(t, []string{"id", "saved_at", "result"}, keys(newest))
+	assert.Equal(t, "https://example.org", newest["result"].(map[string]any)["url"])
+	assert.Contains(t, newest["result"], "warnings", "saved results are served in the v2 shape")
+
+	resp, body = get(t, server, "/api/v2/results?url=https://example.com&limit=5")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, body["results"], 1)
+	id := body["results"].([]any)[0].(map[string]any)["id"].(string)
+
+	resp, body = get(t, server, "/api/v2/results/"+id)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, id, body["id"])
+	assert.Equal(t, "https://example.com", body["result"].(map[string]any)["url"])
+
+	resp, body = get(t, server, "/api/v2/results/missing")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "Result not found", body["error"])
+
+	resp, _ = get(t, server, "/api/v2/results?limit=none")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestContract_ValidationErrorsMatchAcrossVersions(t *testing.T) {
+	server := newContractServer(t)
+
+	tests := []struct {
+		path    string
+		body    string
+		message string
+	}{
+		{"/analyze", `{"url":""}`, "URL is required"},
+		{"/analyze", `not json`, "Invalid request format"},
+		{"/analyze", `{"url":"https://example.com","accept_language":"en_US"}`, `accept_language: "en_US" is not a language tag`},
+		{"/analyze", `{"url":"https://example.com","rules":{"include":["spelling"]}}`, `rules.include: unknown rule "spelling"`},
+		{"/analyze", `{"url":"https://example.com","checks":[{"name":"pixel","type":"xpath","pattern":"//img"}]}`, `checks[0].type: "xpath" is not one of contains, not_contains, regex, css_selector_exists`},
+		{"/batch-analyze", `{"urls":[]}`, "At least one URL is required"},
+		{"/batch-analyze", `{"urls":["https://example.com"],"checks":[{"name":"pixel","type":"contains"}]}`, "checks[0].pattern is required"},
+		{"/analyze", `{"url":"https://example.com","link_check":{"method":"post"}}`, `link_check.method: "post" is not one of get, head, head_then_get`},
+		{"/batch-analyze", `{"urls":["https://example.com"],"link_check":{"retries":5}}`, "link_check.retries must be between 0 and 3"},
+		{"/analyze", `{"url":"https://example.com","host_overrides":{"example.com":"example.net"}}`, `host_overrides["example.com"]: "example.net" is not an IP address`},
+		{"/batch-analyze", `{"urls":["https://example.com"],"host_overrides":{"https://example.com":"10.0.0.5"}}`, `host_overrides: "https://example.com" is not a host name`},
+		{"/batch-analyze", `{"urls":["https://example.com"],"rules":{"exclude":["Links"]}}`, `rules.exclude: unknown rule "Links"`},
+		{"/batch-analyze", `{"urls":["https://example.com"],"accept_language":"en;q=2"}`, `accept_language: "q=2" is not a weight between q=0 and q=1`},
+		{"/batch-analyze", `{"urls":[` + strings.Repeat(`"https://example.com",`, 100) + `"https://example.com"]}`, "Maximum 100 URLs allowed per batch"},
+	}
+
+	for _, tt := range tests {
+		for _, prefix := range []string{"/api/v1", "/api/v2"} {
+			t.Run(prefix+tt.path+" "+tt.message, func(t *testing.T) {
+				resp, body := post(t, server, prefix+tt.path, tt.body)
+
+				assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+				assert.Equal(t, tt.message, body["error"])
+			})
+		}
+	}
+}
+
+func TestContract_UpstreamStatusMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		upstream int
+		body     string
+		status   int
+		message  string
+		code     string
+	}{
+		{"bad request", http.StatusBadRequest, `{"error":"HTTP error: status code 404","status_code":400}`,
+			http.StatusBadRequest, "HTTP error: status code 404", ""},
+		{"unprocessable", http.StatusUnprocessableEntity, `{"error":"Outbound request budget exhausted while fetching the page","status_code":422}`,
+			http.StatusUnprocessableEntity, "Outbound request budget exhausted while fetching the page", ""},
+		{"coded 4xx", http.StatusConflict, `{"error":"Already running","status_code":409,"code":"already_running"}`,
+			http.StatusConflict, "Already running", "already_running"},
+		{"4xx without a body", http.StatusNotFound, ``,
+			http.StatusNotFound, "Not Found", ""},
+		{"internal error", http.StatusInternalServerError, `{"error":"Failed to analyze URL","status_code":500}`,
+			http.StatusBadGateway, "Analysis failed: analyzer service error (status 500): Failed to analyze URL", ""},
+		{"unavailable", http.StatusServiceUnavailable, `overloaded`,
+			http.StatusBadGateway, "Analysis failed: analyzer service returned status 503: overloaded", ""},
+		{"timeout", http.StatusGatewayTimeout, `{"error":"Analysis timeout","status_code":504}`,
+			http.StatusGatewayTimeout, "Analysis timeout", ""},
+	}
+
+	analyzer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.AnalysisRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		index, err := strconv.Atoi(strings.TrimPrefix(req.URL, "https://example.com/"))
+		require.NoError(t, err)
+		w.WriteHeader(tests[index].upstream)
+		io.WriteString(w, tests[index].body)
+	}))
+	t.Cleanup(analyzer.Close)
+
+	ctrl := gomock.NewController(t)
+	client := NewAnalyzerClient(analyzer.URL, 5*time.Second, setupMockLogger(ctrl))
+	client.retryBackoff = time.Millisecond
+	apiHandler := NewAPIHandler(client, setupMockLogger(ctrl), mocks.NewMockMetricsCollector(ctrl))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/analyze", apiHandler.AnalyzeURL).Methods("POST")
+	router.HandleFunc("/api/v2/analyze", apiHandler.AnalyzeURLV2).Methods("POST")
+	router.HandleFunc("/api/v2/batch-analyze", apiHandler.BatchAnalyzeV2).Methods("POST")
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	urls := make([]string, len(tests))
+	for i, tt := range tests {
+		urls[i] = `"https://example.com/` + strconv.Itoa(i) + `"`
+		for _, prefix := range []string{"/api/v1", "/api/v2"} {
+			t.Run(prefix+" "+tt.name, func(t *testing.T) {
+				resp, body := post(t, server, prefix+"/analyze", `{"url":`+urls[i]+`}`)
+
+				assert.Equal(t, tt.status, resp.StatusCode)
+				assert.Equal(t, tt.message, body["error"])
+				if tt.code != "" {
+					assert.Equal(t, tt.code, body["code"])
+				}
+			})
+		}
+	}
+
+	// A batch reports each failure with the status a single analysis gets
+	resp, body := post(t, server, "/api/v2/batch-analyze", `{"urls":[`+strings.Join(urls, ",")+`]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	items := body["items"].([]any)
+	require.Len(t, items, len(tests))
+	for i, tt := range tests {
+		failure := items[i].(map[string]any)["error"].(map[string]any)
+		assert.Equal(t, float64(tt.status), failure["status_code"], tt.name)
+		assert.Equal(t, tt.message, failure["error"], tt.name)
+	}
+}
+
+func TestContract_InvalidResultIsNotPassedOn(t *testing.T) {
+	server := newContractServer(t)
+
+	for _, prefix := range []string{"/api/v1", "/api/v2"} {
+		t.Run(prefix, func(t *testing.T) {
+			resp, body := post(t, server, prefix+"/analyze", `{"url":"`+invalidURL+`"}`)
+
+			assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+			assert.Equal(t, models.ErrorCodeInvalidResult, body["code"])
+			assert.Equal(t, "Analysis produced an invalid result", body["error"])
+		})
+	}
+
+	resp, body := post(t, server, "/api/v2/batch-analyze", `{"urls":["https://example.com","`+invalidURL+`"]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, float64(1), body["failed"])
+	failure := body["items"].([]any)[1].(map[string]any)["error"].(map[string]any)
+	assert.Equal(t, models.ErrorCodeInvalidResult, failure["code"])
+}
+
+func TestContract_UnsupportedContentTypePassesThrough(t *testing.T) {
+	server := newContractServer(t)
+
+	for _, prefix := range []string{"/api/v1", "/api/v2"} {
+		t.Run(prefix, func(t *testing.T) {
+			resp, body := post(t, server, prefix+"/analyze", `{"url":"`+pdfURL+`"}`)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+			assert.Equal(t, models.ErrorCodeUnsupportedContentType, body["code"])
+			assert.Equal(t, "application/pdf", body["content_type"])
+			assert.Equal(t, float64(2048), body["content_bytes"])
+		})
+	}
+
+	resp, body := post(t, server, "/api/v2/batch-analyze", `{"urls":["https://example.com","`+pdfURL+`"]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, float64(1), body["failed"])
+	failure := body["items"].([]any)[1].(map[string]any)["error"].(map[string]any)
+	assert.Equal(t, models.ErrorCodeUnsupportedContentType, failure["code"])
+	assert.Equal(t, float64(http.StatusUnprocessableEntity), failure["status_code"])
+	assert.Equal(t, "application/pdf", failure["content_type"])
+}
+
+func TestContract_TargetBusyPassesThrough(t *testing.T) {
+	server := newContractServer(t)
+
+	for _, prefix := range []string{"/api/v1", "/api/v2"} {
+		t.Run(prefix, func(t *testing.T) {
+			resp, body := post(t, server, prefix+"/analyze", `{"url":"`+busyURL+`"}`)
+
+			assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+			assert.Equal(t, "5", resp.Header.Get("Retry-After"))
+			assert.Equal(t, models.ErrorCodeTargetBusy, body["code"])
+			assert.Equal(t, "slow.example", body["host"])
+			assert.Equal(t, float64(5), body["retry_after_seconds"])
+		})
+	}
+
+	resp, body := post(t, server, "/api/v2/batch-analyze", `{"urls":["https://example.com","`+busyURL+`"]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, float64(1), body["failed"])
+	failure := body["items"].([]any)[1].(map[string]any)["error"].(map[string]any)
+	assert.Equal(t, models.ErrorCodeTargetBusy, failure["code"])
+	assert.Equal(t, float64(http.StatusTooManyRequests), failure["status_code"])
+}
+
+// Browsers get the error as a page, API clients as JSON, with the same code
+// and request ID
+func TestContract_ErrorRepresentationFollowsAccept(t *testing.T) {
+	server := newContractServer(t)
+
+	send := func(accept string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/api/v2/analyze", strings.NewReader(`{"url":"`+busyURL+`"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", accept)
+		req.Header.Set("X-Request-ID", "req-"+strconv.Itoa(len(accept)))
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	api := send("application/json")
+	assert.Equal(t, http.StatusTooManyRequests, api.StatusCode)
+	assert.Equal(t, "application/json", api.Header.Get("Content-Type"))
+	var body models.ErrorResponse
+	require.NoError(t, json.NewDecoder(api.Body).Decode(&body))
+	assert.Equal(t, models.ErrorCodeTargetBusy, body.Code)
+	assert.Equal(t, api.Header.Get("X-Request-ID"), body.RequestID)
+
+	browser := send("text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	assert.Equal(t, http.StatusTooManyRequests, browser.StatusCode)
+	assert.Equal(t, "5", browser.Header.Get("Retry-After"))
+	assert.Equal(t, "text/html; charset=utf-8", browser.Header.Get("Content-Type"))
+	page, err := io.ReadAll(browser.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(page), "<code>"+models.ErrorCodeTargetBusy+"</code>")
+	assert.Contains(t, string(page), "<code>"+browser.Header.Get("X-Request-ID")+"</code>")
+
+	// The gateway's own errors, such as a saved result that is not there,
+	// follow the same rule
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v2/results/missing", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "text/html")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+}
+
+func TestContract_TimingsPassThrough(t *testing.T) {
+	server := newContractServer(t)
+
+	want := map[string]any{
+		"fetch_ms":                  120.5,
+		"html_version_detection_ms": 0.01,
+		"parse_ms":                  2.25,
+		"link_check_ms":             float64(800),
+		"total_ms":                  float64(923),
+	}
+
+	for _, prefix := range []string{"/api/v1", "/api/v2"} {
+		t.Run(prefix, func(t *testing.T) {
+			_, body := post(t, server, prefix+"/analyze", `{"url":"https://example.com"}`)
+			assert.Equal(t, want, body["timings"])
+		})
+	}
+
+	_, body := post(t, server, "/api/v2/batch-analyze", `{"urls":["https://example.com"]}`)
+	item := body["items"].([]any)[0].(map[string]any)
+	assert.Equal(t, want, item["result"].(map[string]any)["timings"])
+}
+
+func TestContract_AnalysisWarningsPassThrough(t *testing.T) {
+	server := newContractServer(t)
+
+	want := []any{map[string]any{
+		"code":    models.WarningRedirected,
+		"message": "The URL redirected, https://example.com/new was analyzed instead",
+		"context": map[string]any{"final_url": "https://example.com/new"},
+	}}
+
+	_, body := post(t, server, "/api/v1/analyze", `{"url":"`+movedURL+`"}`)
+	assert.Equal(t, want, body["warnings"])
+
+	// v2 keeps "warnings" for the ones the gateway derives
+	_, body = post(t, server, "/api/v2/analyze", `{"url":"`+movedURL+`"}`)
+	assert.Equal(t, want, body["analysis_warnings"])
+	assert.Equal(t, []any{"1 of 2 links are inaccessible"}, body["warnings"])
+
+	_, body = post(t, server, "/api/v2/batch-analyze", `{"urls":["https://example.com","`+movedURL+`"]}`)
+	items := body["items"].([]any)
+	assert.NotContains(t, items[0].(map[string]any)["result"], "analysis_warnings")
+	assert.Equal(t, want, items[1].(map[string]any)["result"].(map[string]any)["analysis_warnings"])
+}
+
+func TestContractV2_MinSeverityFiltersFindings(t *testing.T) {
+	server := newContractServer(t)
+	ids := func(result any) []string {
+		var ids []string
+		for _, finding := range result.(map[string]any)["findings"].([]any) {
+			ids = append(ids, finding.(map[string]any)["id"].(string))
+		}
+		return ids
+	}
+
+	_, body := post(t, server, "/api/v2/analyze", `{"url":"`+findingsURL+`"}`)
+	assert.Equal(t, []string{findings.HeadingsMultipleH1, findings.RobotsNoIndex, findings.TitleMissing}, ids(body))
+
+	_, body = post(t, server, "/api/v2/analyze?min_severity=warning", `{"url":"`+findingsURL+`"}`)
+	assert.Equal(t, []string{findings.RobotsNoIndex, findings.TitleMissing}, ids(body))
+	assert.Equal(t, float64(3), body["finding_summary"].(map[string]any)["total"], "the summary counts every finding")
+
+	_, body = post(t, server, "/api/v2/batch-analyze?min_severity=error", `{"urls":["`+findingsURL+`","`+brokenURL+`"]}`)
+	items := body["items"].([]any)
+	assert.Equal(t, []string{findings.TitleMissing}, ids(items[0].(map[string]any)["result"]))
+
+	_, body = get(t, server, "/api/v2/results?min_severity=error")
+	saved := body["results"].([]any)[0].(map[string]any)
+	assert.Equal(t, []string{findings.TitleMissing}, ids(saved["result"]))
+
+	_, body = get(t, server, "/api/v2/results/"+saved["id"].(string)+"?min_severity=warning")
+	assert.Equal(t, []string{findings.RobotsNoIndex, findings.TitleMissing}, ids(body["result"]))
+
+	for _, path := range []string{"/api/v2/analyze", "/api/v2/batch-analyze", "/api/v2/results", "/api/v2/results/" + saved["id"].(string)} {
+		var resp *http.Response
+		if strings.HasPrefix(path, "/api/v2/results") {
+			resp, body = get(t, server, path+"?min_severity=critical")
+		} else {
+			resp, body = post(t, server, path+"?min_severity=critical", `{"url":"`+findingsURL+`","urls":["`+findingsURL+`"]}`)
+		}
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, path)
+		assert.Equal(t, "min_severity must be info, warning or error", body["error"], path)
+	}
+}
+
+func TestContract_ScreenshotServedAsArtifact(t *testing.T) {
+	server := newContractServer(t)
+
+	for _, prefix := range []string{"/api/v1", "/api/v2"} {
+		t.Run(prefix, func(t *testing.T) {
+			resp, body := post(t, server, prefix+"/analyze", `{"url":"https://example.com","screenshot":true}`)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			link, _ := body["screenshot"].(string)
+			require.True(t, strings.HasPrefix(link, prefix+"/artifacts/"), link)
+
+			artifact, err := http.Get(server.URL + link)
+			require.NoError(t, err)
+			defer artifact.Body.Close()
+			data, err := io.ReadAll(artifact.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, http.StatusOK, artifact.StatusCode)
+			assert.Equal(t, "image/png", artifact.Header.Get("Content-Type"))
+			assert.Equal(t, testPNG, data)
+		})
+	}
+}
+
+func TestContract_ScreenshotIsOptIn(t *testing.T) {
+	server := newContractServer(t)
+
+	_, body := post(t, server, "/api/v2/analyze", `{"url":"https://example.com"}`)
+
+	assert.NotContains(t, body, "screenshot")
+}
+
+func TestAPIHandler_StoreScreenshot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	inline := screenshotDataPrefix + base64.StdEncoding.EncodeToString(testPNG)
+
+	tests := []struct {
+		name     string
+		store    *artifacts.Store
+		input    string
+		expected func(t *testing.T, got string)
+	}{
+		{
+			name:  "no store keeps the data URI",
+			input: inline,
+			expected: func(t *testing.T, got string) {
+				assert.Equal(t, inline, got)
+			},
+		},
+		{
+			name:  "stored as artifact",
+			store: artifacts.NewStore(time.Minute, 10, 1<<20),
+			input: inline,
+			expected: func(t *testing.T, got string) {
+				assert.True(t, strings.HasPrefix(got, "/api/v1/artifacts/"))
+			},
+		},
+		{
+			name:  "over the size cap is dropped",
+			store: artifacts.NewStore(time.Minute, 10, 4),
+			input: inline,
+			expected: func(t *testing.T, got string) {
+				assert.Empty(t, got)
+			},
+		},
+		{
+			name:  "corrupt data is dropped",
+			store: artifacts.NewStore(time.Minute, 10, 1<<20),
+			input: screenshotDataPrefix + "not base64!",
+			expected: func(t *testing.T, got string) {
+				assert.Empty(t, got)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAPIHandler(nil, setupMockLogger(ctrl), nil)
+			if tt.store != nil {
+				handler.SetArtifactStore(tt.store)
+			}
+
+			result := &models.AnalysisResult{URL: "https://example.com", Screenshot: tt.input}
+			handler.storeScreenshot(result, apiV1Prefix)
+
+			tt.expected(t, result.Screenshot)
+		})
+	}
+}
+
+func TestAPIHandler_AnalyzeTimesOutAndCancelsTheAnalyzerCall(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	cancelled := make(chan struct{})
+	analyzer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Never answers; the gateway has to give up and hang up. The
+		// server only notices once the body has been read.
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+		close(cancelled)
+	}))
+	t.Cleanup(analyzer.Close)
+
+	client := NewAnalyzerClient(analyzer.URL, 5*time.Second, setupMockLogger(ctrl))
+	apiHandler := NewAPIHandler(client, setupMockLogger(ctrl), mocks.NewMockMetricsCollector(ctrl))
+	limiter := middleware.NewLimiter("test", 10, 10, time.Second)
+
+	router := mux.NewRouter()
+	router.Handle("/api/v2/analyze", middleware.Timeout(100*time.Millisecond)(limiter.Limit(http.HandlerFunc(apiHandler.AnalyzeURLV2)))).Methods("POST")
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	start := time.Now()
+	resp, body := post(t, server, "/api/v2/analyze", `{"url":"https://example.com"}`)
+
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	assert.Equal(t, "Request timed out", body["error"])
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the analyzer call was not cancelled")
+	}
+}
+
+func TestAPIHandler_ClientClosedRequest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	var calls atomic.Int32
+	analyzer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	t.Cleanup(analyzer.Close)
+
+	client := NewAnalyzerClient(analyzer.URL, 5*time.Second, setupMockLogger(ctrl))
+	apiHandler := NewAPIHandler(client, setupMockLogger(ctrl), mocks.NewMockMetricsCollector(ctrl))
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		path    string
+		body    string
+	}{
+		{name: "analyze", handler: apiHandler.AnalyzeURLV2, path: "/api/v2/analyze", body: `{"url":"https://example.com"}`},
+		{name: "batch", handler: apiHandler.BatchAnalyzeV2, path: "/api/v2/batch-analyze",
+			body: `{"urls":["https://example.com/a","https://example.com/b","https://example.com/c"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.Store(0)
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(20*time.Millisecond, cancel)
+
+			w := httptest.NewRecorder()
+			tt.handler(w, httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body)).WithContext(ctx))
+
+			assert.Equal(t, models.StatusClientClosedRequest, w.Code)
+			assert.Zero(t, w.Body.Len())
+			assert.Equal(t, int32(1), calls.Load(), "nothing was analyzed after the client left")
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Media types of the batch bodies that list URLs rather than hold a JSON
+// request, for users with a spreadsheet of URLs
+const (
+	mediaTypeCSV   = "text/csv"
+	mediaTypePlain = "text/plain"
+)
+
+// Limits of a batch. A URL list body is read up to maxBatchListBytes and
+// maxBatchListLines, blank lines and a header row included; its URLs are
+// then capped at maxBatchURLs as those of JSON requests are.
+const (
+	maxBatchURLs      = 100
+	maxBatchListBytes = 256 << 10
+	maxBatchListLines = 1000
+	// maxLineErrors caps the invalid lines named in one error
+	maxLineErrors = 10
+)
+
+// errBatchListTooLong is returned for a list of more than maxBatchListLines
+var errBatchListTooLong = fmt.Errorf("URL list longer than %d lines", maxBatchListLines)
+
+// lineError is the problem of one line of a URL list
+type lineError struct {
+	line    int
+	problem string
+}
+
+// batchListError lists the invalid lines of a URL list
+type batchListError struct {
+	lines []lineError
+}
+
+func (e *batchListError) Error() string {
+	var parts []string
+	for _, line := range e.lines[:min(len(e.lines), maxLineErrors)] {
+		parts = append(parts, fmt.Sprintf("line %d: %s", line.line, line.problem))
+	}
+	if more := len(e.lines) - maxLineErrors; more > 0 {
+		parts = append(parts, fmt.Sprintf("and %d more invalid lines", more))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (e *batchListError) add(line int, problem string) {
+	e.lines = append(e.lines, lineError{line: line, problem: problem})
+}
+
+// err returns e, or nil when no line is invalid
+func (e *batchListError) err() error {
+	if len(e.lines) == 0 {
+		return nil
+	}
+	return e
+}
+
+// parseCSVList returns the URLs of a CSV body, one per record in its first
+// column. A first row whose first field could not be a URL, such as "url"
+// or "Page", is taken for a header and skipped. Blank lines are skipped;
+// quoted fields, CRLF line endings and a UTF-8 byte order mark are
+// accepted.
+func parseCSVList(body io.Reader) ([]string, error) {
+	reader := csv.NewReader(skipBOM(body))
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	var urls []string
+	invalid := &batchListError{}
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			invalid.add(parseErr.Line, parseErr.Err.Error())
+			return nil, invalid
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		if line > maxBatchListLines {
+			return nil, errBatchListTooLong
+		}
+		value := strings.TrimSpace(record[0])
+		if first && isHeader(value) {
+			continue
+		}
+		if problem := urlProblem(value); problem != "" {
+			invalid.add(line, problem)
+			continue
+		}
+		urls = append(urls, value)
+	}
+	return urls, invalid.err()
+}
+
+// parsePlainList returns the URLs of a text body, one per line. Blank
+// lines are skipped; CRLF line endings and a UTF-8 byte order mark are
+// accepted.
+func parsePlainList(body io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(skipBOM(body))
+	scanner.Buffer(make([]byte, 0, 4096), maxBatchListBytes)
+
+	var urls []string
+	invalid := &batchListError{}
+	line := 0
+	for scanner.Scan() {
+		line++
+		if line > maxBatchListLines {
+			return nil, errBatchListTooLong
+		}
+		value := strings.TrimSpace(scanner.Text())
+		if value == "" {
+			continue
+		}
+		if problem := urlProblem(value); problem != "" {
+			invalid.add(line, problem)
+			continue
+		}
+		urls = append(urls, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return urls, invalid.err()
+}
+
+// skipBOM drops the UTF-8 byte order mark spreadsheet programs start their
+// exports with
+func skipBOM(body io.Reader) io.Reader {
+	reader := bufio.NewReader(body)
+	if prefix, err := reader.Peek(3); err == nil && bytes.Equal(prefix, []byte("\xef\xbb\xbf")) {
+		reader.Discard(3)
+	}
+	return reader
+}
+
+// isHeader reports whether the first field of a CSV row is a column name
+// rather than a URL: URLs and host names have a dot or a slash
+func isHeader(value string) bool {
+	return !strings.ContainsAny(value, "./")
+}
+
+// urlProblem says what keeps value from being analyzed, or "" when it is an
+// absolute http or https URL
+func urlProblem(value string) string {
+	if value == "" {
+		return "the URL is empty"
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Sprintf("%q is not an http or https URL", value)
+	}
+	return ""
+}
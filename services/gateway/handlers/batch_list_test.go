@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCSVList(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "header, BOM and CRLF",
+			body: "\xef\xbb\xbfurl,notes\r\nhttps://example.com,home\r\nhttps://example.org/about,\r\n",
+			want: []string{"https://example.com", "https://example.org/about"},
+		},
+		{
+			name: "no header",
+			body: "https://example.com\nhttps://example.org\n",
+			want: []string{"https://example.com", "https://example.org"},
+		},
+		{
+			name: "quoted fields and blank lines",
+			body: "\"Page URL\",\"Owner\"\n\n\" https://example.com/a?x=1,2 \",\"Smith, J\"\n\n\n\"https://example.com/b\"\n",
+			want: []string{"https://example.com/a?x=1,2", "https://example.com/b"},
+		},
+		{
+			name:    "invalid lines are numbered",
+			body:    "url\nhttps://example.com\nexample.com\n\nftp://example.com/file\n,orphan\n",
+			wantErr: `line 3: "example.com" is not an http or https URL; line 5: "ftp://example.com/file" is not an http or https URL; line 6: the URL is empty`,
+		},
+		{
+			name:    "unterminated quote",
+			body:    "https://example.com\n\"https://example.org\n",
+			wantErr: `line 2: extraneous or missing " in quoted-field`,
+		},
+		{
+			name:    "too many lines",
+			body:    strings.Repeat("\n", maxBatchListLines) + "https://example.com\n",
+			wantErr: "URL list longer than 1000 lines",
+		},
+		{name: "empty", body: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, err := parseCSVList(strings.NewReader(tt.body))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, urls)
+		})
+	}
+}
+
+func TestParsePlainList(t *testing.T) {
+	urls, err := parsePlainList(strings.NewReader("\xef\xbb\xbfhttps://example.com\r\n\r\n  https://example.org/about  \r\nHTTPS://EXAMPLE.NET\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com", "https://example.org/about", "HTTPS://EXAMPLE.NET"}, urls)
+
+	// Every line is a URL, the first included
+	_, err = parsePlainList(strings.NewReader("url\nhttps://example.com\nnot a url\n"))
+	assert.EqualError(t, err, `line 1: "url" is not an http or https URL; line 3: "not a url" is not an http or https URL`)
+
+	_, err = parsePlainList(strings.NewReader(strings.Repeat("https://example.com\n", maxBatchListLines+1)))
+	assert.ErrorIs(t, err, errBatchListTooLong)
+}
+
+func TestBatchListError_CapsTheLinesNamed(t *testing.T) {
+	invalid := &batchListError{}
+	for line := 1; line <= maxLineErrors+3; line++ {
+		invalid.add(line, "bad")
+	}
+	message := invalid.Error()
+	assert.True(t, strings.HasPrefix(message, "line 1: bad; line 2: bad"))
+	assert.True(t, strings.HasSuffix(message, "line 10: bad; and 3 more invalid lines"))
+}
+
+// postList sends body to path as contentType and decodes the JSON response
+// into a generic map
+func postList(t *testing.T, server *httptest.Server, path, contentType, body string) (*http.Response, map[string]any) {
+	t.Helper()
+
+	resp, err := http.Post(server.URL+path, contentType, strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var decoded map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+	return resp, decoded
+}
+
+func TestBatchAnalyze_URLLists(t *testing.T) {
+	server := newContractServer(t)
+
+	resp, body := postList(t, server, "/api/v2/batch-analyze", "text/csv; charset=utf-8",
+		"\xef\xbb\xbfURL,Section\r\n\"https://example.com\",Home\r\n\r\n"+brokenURL+",Support\r\n")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	items := body["items"].([]any)
+	require.Len(t, items, 2)
+	assert.Equal(t, "https://example.com", items[0].(map[string]any)["url"])
+	assert.Equal(t, brokenURL, items[1].(map[string]any)["url"])
+	assert.Contains(t, items[1], "error")
+
+	resp, body = postList(t, server, "/api/v1/batch-analyze", "text/plain",
+		"https://example.com\n\nhttps://example.org\n")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, body["results"], 2)
+	assertSharedTitleFinding(t, body["cross_page_findings"])
+}
+
+func TestBatchAnalyze_URLListErrors(t *testing.T) {
+	server := newContractServer(t)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		message     string
+	}{
+		{"invalid lines", "text/csv", "url\nhttps://example.com\nexample\n", http.StatusBadRequest,
+			`line 3: "example" is not an http or https URL`},
+		{"only a header", "text/csv", "url\r\n", http.StatusBadRequest, "At least one URL is required"},
+		{"too many URLs", "text/plain", strings.Repeat("https://example.com\n", maxBatchURLs+1), http.StatusBadRequest,
+			"Maximum 100 URLs allowed per batch"},
+		{"too large", "text/plain", strings.Repeat("https://example.com/"+strings.Repeat("a", 1000)+"\n", 300),
+			http.StatusRequestEntityTooLarge, "URL list larger than 256 KiB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := postList(t, server, "/api/v2/batch-analyze", tt.contentType, tt.body)
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.Equal(t, tt.message, body["error"])
+		})
+	}
+}
@@ -7,23 +7,34 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/healthprobe"
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/version"
 )
 
 type HealthHandler struct {
-	serviceName    string
-	analyzerClient AnalyzerClient
-	startTime      time.Time
+	serviceName string
+	analyzer    interfaces.HealthChecker
+	startTime   time.Time
+	admission   func() models.AdmissionStats
 }
 
-func NewHealthHandler(serviceName string, analyzerClient AnalyzerClient) *HealthHandler {
+// NewHealthHandler reports the analyzer's health from analyzer, either the
+// client itself or a healthprobe.Prober answering from its latest check
+func NewHealthHandler(serviceName string, analyzer interfaces.HealthChecker) *HealthHandler {
 	return &HealthHandler{
-		serviceName:    serviceName,
-		analyzerClient: analyzerClient,
-		startTime:      time.Now(),
+		serviceName: serviceName,
+		analyzer:    analyzer,
+		startTime:   time.Now(),
 	}
 }
 
+// SetAdmission reports the admission limiter state from stats in /health
+func (h *HealthHandler) SetAdmission(stats func() models.AdmissionStats) {
+	h.admission = stats
+}
+
 func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
@@ -32,7 +43,7 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	checks := make(map[string]string)
 
 	// Check analyzer service
-	if err := h.analyzerClient.CheckHealth(ctx); err != nil {
+	if err := h.analyzer.CheckHealth(ctx); err != nil {
 		checks["analyzer_service"] = "unhealthy: " + err.Error()
 	} else {
 		checks["analyzer_service"] = "healthy"
@@ -51,11 +62,18 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	response := models.HealthStatus{
 		Status:    status,
 		Service:   h.serviceName,
-		Version:   getVersion(),
+		Version:   version.Get().Version,
 		Uptime:    formatDuration(time.Since(h.startTime)),
 		Checks:    checks,
 		Timestamp: time.Now(),
 	}
+	if prober, ok := h.analyzer.(*healthprobe.Prober); ok {
+		response.Probes = map[string]models.ProbeResult{"analyzer_service": prober.Result()}
+	}
+	if h.admission != nil {
+		stats := h.admission()
+		response.Admission = &stats
+	}
 
 	statusCode := http.StatusOK
 	if status != "healthy" {
@@ -68,10 +86,6 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-func getVersion() string {
-	return "1.0.0"
-}
-
 func formatDuration(d time.Duration) string {
 	days := int(d.Hours() / 24)
 	hours := int(d.Hours()) % 24
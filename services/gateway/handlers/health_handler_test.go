@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/healthprobe"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler_ReportsAdmission(t *testing.T) {
+	analyzer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer analyzer.Close()
+
+	client := NewAnalyzerClient(analyzer.URL, 5*time.Second, setupMockLogger(gomock.NewController(t)))
+	handler := NewHealthHandler("gateway", client)
+
+	get := func() models.HealthStatus {
+		w := httptest.NewRecorder()
+		handler.Health(w, httptest.NewRequest("GET", "/health", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var health models.HealthStatus
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&health))
+		return health
+	}
+
+	assert.Nil(t, get().Admission)
+
+	stats := models.AdmissionStats{InFlight: 3, Queued: 1, Rejected: 7, MaxInFlight: 4, MaxQueued: 8}
+	handler.SetAdmission(func() models.AdmissionStats { return stats })
+	assert.Equal(t, &stats, get().Admission)
+}
+
+func TestHealthHandler_AnswersFromTheProbe(t *testing.T) {
+	var calls atomic.Int32
+	var failing atomic.Bool
+	analyzer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer analyzer.Close()
+
+	client := NewAnalyzerClient(analyzer.URL, 5*time.Second, setupMockLogger(gomock.NewController(t)))
+	probe := healthprobe.New("analyzer_service", client, time.Minute, time.Second, setupMockLogger(gomock.NewController(t)))
+	handler := NewHealthHandler("gateway", probe)
+
+	get := func() (int, models.HealthStatus) {
+		w := httptest.NewRecorder()
+		handler.Health(w, httptest.NewRequest("GET", "/health", nil))
+		var health models.HealthStatus
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&health))
+		return w.Code, health
+	}
+
+	probe.Check(context.Background())
+	for range 3 {
+		code, health := get()
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "healthy", health.Checks["analyzer_service"])
+		assert.False(t, health.Probes["analyzer_service"].CheckedAt.IsZero())
+	}
+	assert.Equal(t, int32(1), calls.Load(), "/health must not call the analyzer")
+
+	failing.Store(true)
+	probe.Check(context.Background())
+	code, health := get()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "degraded", health.Status)
+	assert.Contains(t, health.Checks["analyzer_service"], "unhealthy")
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// Outcomes of a mirrored analysis, as recorded by RecordMirror
+const (
+	mirrorSuccess  = "success"
+	mirrorMismatch = "mismatch"
+	mirrorFailure  = "failure"
+	// mirrorDropped is an analysis not mirrored because the mirror calls
+	// were at their cap
+	mirrorDropped = "dropped"
+)
+
+// Mirror sends a share of the analyses to a second analyzer as well, such as
+// a canary of a new analyzer version, without the caller waiting for it.
+// Each mirrored call has its own timeout and is not retried; its result is
+// discarded or, with SetDiff, compared to the primary's.
+type Mirror struct {
+	client  AnalyzerClient
+	percent float64
+	timeout time.Duration
+	diff    bool
+	// slots holds a token for each mirrored call running
+	slots   chan struct{}
+	logger  interfaces.Logger
+	metrics interfaces.MetricsCollector
+	// sample returns a number in [0, 100), compared to percent
+	sample func() float64
+	wg     sync.WaitGroup
+}
+
+// NewMirror returns a Mirror sending percent of the analyses to client, with
+// at most maxConcurrent calls running at a time, each bounded by timeout.
+// client should not retry.
+func NewMirror(client AnalyzerClient, percent float64, timeout time.Duration, maxConcurrent int, logger interfaces.Logger, metrics interfaces.MetricsCollector) *Mirror {
+	return &Mirror{
+		client:  client,
+		percent: percent,
+		timeout: timeout,
+		slots:   make(chan struct{}, maxConcurrent),
+		logger:  logger,
+		metrics: metrics,
+		sample:  func() float64 { return rand.Float64() * 100 },
+	}
+}
+
+// SetDiff compares each mirrored result to the primary's, logging and
+// counting those that differ
+func (m *Mirror) SetDiff(enabled bool) {
+	m.diff = enabled
+}
+
+// Send starts mirroring the analysis of url with opts, unless it is not
+// sampled or the mirror calls are at their cap, and returns at once. The
+// primary result, nil when the primary analysis failed, must then be passed
+// to the returned function once nothing changes it any more; the function
+// never blocks. A nil Mirror mirrors nothing.
+func (m *Mirror) Send(ctx context.Context, url string, opts models.AnalysisOptions) func(primary *models.AnalysisResult) {
+	ignore := func(*models.AnalysisResult) {}
+	if m == nil || m.sample() >= m.percent {
+		return ignore
+	}
+	select {
+	case m.slots <- struct{}{}:
+	default:
+		m.logger.Debug("Mirror calls at their cap, not mirroring analysis", "url", logger.RedactURL(url))
+		m.metrics.RecordMirror(mirrorDropped)
+		return ignore
+	}
+
+	primaries := make(chan *models.AnalysisResult, 1)
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer func() { <-m.slots }()
+		m.mirror(ctx, url, opts, primaries)
+	}()
+
+	var once sync.Once
+	return func(primary *models.AnalysisResult) {
+		once.Do(func() { primaries <- primary })
+	}
+}
+
+// mirror makes the mirrored call and records its outcome. The call outlives
+// the request, whose context only carries its values.
+func (m *Mirror) mirror(ctx context.Context, url string, opts models.AnalysisOptions, primaries <-chan *models.AnalysisResult) {
+	mirrorCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.timeout)
+	defer cancel()
+
+	result, err := m.client.AnalyzeWithOptions(mirrorCtx, url, opts)
+	if err != nil {
+		m.logger.Warn("Mirrored analysis failed", "url", logger.RedactURL(url), "error", err)
+		m.metrics.RecordMirror(mirrorFailure)
+		return
+	}
+	if !m.diff {
+		m.metrics.RecordMirror(mirrorSuccess)
+		return
+	}
+
+	// The handler passes the primary result before it returns, so once
+	// the request is done it is either waiting or was never coming
+	var primary *models.AnalysisResult
+	select {
+	case primary = <-primaries:
+	case <-ctx.Done():
+		select {
+		case primary = <-primaries:
+		default:
+		}
+	}
+	if primary == nil {
+		m.metrics.RecordMirror(mirrorSuccess)
+		return
+	}
+
+	fields, err := resultDiff(primary, result)
+	if err != nil {
+		m.logger.Warn("Failed to compare mirrored analysis", "url", logger.RedactURL(url), "error", err)
+		m.metrics.RecordMirror(mirrorSuccess)
+		return
+	}
+	if len(fields) > 0 {
+		m.logger.Warn("Mirrored analysis differs from the primary", "url", logger.RedactURL(url), "fields", fields)
+		m.metrics.RecordMirror(mirrorMismatch)
+		return
+	}
+	m.metrics.RecordMirror(mirrorSuccess)
+}
+
+// wait waits for the mirrored calls running to finish
+func (m *Mirror) wait() {
+	m.wg.Wait()
+}
+
+// resultDiff returns the top-level JSON fields, sorted, in which the
+// canonical forms of a and b differ; what changes from one run to the next,
+// such as the timings, is left out of those
+func resultDiff(a, b *models.AnalysisResult) ([]string, error) {
+	fieldsA, err := canonicalFields(a)
+	if err != nil {
+		return nil, err
+	}
+	fieldsB, err := canonicalFields(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var differ []string
+	for name, value := range fieldsA {
+		if other, ok := fieldsB[name]; !ok || !bytes.Equal(value, other) {
+			differ = append(differ, name)
+		}
+	}
+	for name := range fieldsB {
+		if _, ok := fieldsA[name]; !ok {
+			differ = append(differ, name)
+		}
+	}
+	slices.Sort(differ)
+	return differ, nil
+}
+
+func canonicalFields(result *models.AnalysisResult) (map[string]json.RawMessage, error) {
+	data, err := result.CanonicalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/mocks"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeAnalyzer serves result, with the requested URL and a fresh
+// AnalyzedAt and Timings, after release is closed if it is not nil. calls
+// counts the requests.
+func newFakeAnalyzer(t *testing.T, result models.AnalysisResult, release <-chan struct{}, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req models.AnalysisRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if release != nil {
+			<-release
+		}
+
+		result.URL = req.URL
+		result.AnalyzedAt = time.Now()
+		result.Timings = &models.Timings{TotalMs: float64(calls.Load())}
+		json.NewEncoder(w).Encode(result)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newMirroredServer serves POST /api/v1/analyze from primary, mirrored to
+// mirror
+func newMirroredServer(t *testing.T, ctrl *gomock.Controller, primary, mirror *httptest.Server, metrics *mocks.MockMetricsCollector, maxConcurrent int, diff bool) (*httptest.Server, *Mirror) {
+	t.Helper()
+
+	mirrorClient := NewAnalyzerClient(mirror.URL, 5*time.Second, setupMockLogger(ctrl))
+	mirrorClient.SetMaxRetries(0)
+	m := NewMirror(mirrorClient, 100, 2*time.Second, maxConcurrent, setupMockLogger(ctrl), metrics)
+	m.SetDiff(diff)
+
+	apiHandler := NewAPIHandler(NewAnalyzerClient(primary.URL, 5*time.Second, setupMockLogger(ctrl)), setupMockLogger(ctrl), metrics)
+	apiHandler.SetMirror(m)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/analyze", apiHandler.AnalyzeURL).Methods("POST")
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, m
+}
+
+var mirrorTestResult = models.AnalysisResult{
+	HTMLVersion: "HTML5",
+	Title:       "Example Domain",
+	Headings:    models.HeadingCount{H1: 1},
+	Links:       models.LinkSummary{Internal: 2, Total: 2},
+}
+
+func TestMirror_Mismatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	metrics := mocks.NewMockMetricsCollector(ctrl)
+	metrics.EXPECT().RecordMirror(mirrorMismatch)
+
+	changed := mirrorTestResult
+	changed.Title = "Example Domain (canary)"
+	changed.Links.Inaccessible = 1
+
+	var primaryCalls, mirrorCalls atomic.Int32
+	primary := newFakeAnalyzer(t, mirrorTestResult, nil, &primaryCalls)
+	mirror := newFakeAnalyzer(t, changed, nil, &mirrorCalls)
+	server, m := newMirroredServer(t, ctrl, primary, mirror, metrics, 10, true)
+
+	resp, body := post(t, server, "/api/v1/analyze", `{"url":"https://example.com"}`)
+	m.wait()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Example Domain", body["title"], "the primary result is served")
+	assert.Equal(t, int32(1), primaryCalls.Load())
+	assert.Equal(t, int32(1), mirrorCalls.Load())
+}
+
+func TestMirror_SameResult(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	metrics := mocks.NewMockMetricsCollector(ctrl)
+	// The timings and AnalyzedAt differ from run to run and are not
+	// compared
+	metrics.EXPECT().RecordMirror(mirrorSuccess)
+
+	var primaryCalls, mirrorCalls atomic.Int32
+	primary := newFakeAnalyzer(t, mirrorTestResult, nil, &primaryCalls)
+	mirror := newFakeAnalyzer(t, mirrorTestResult, nil, &mirrorCalls)
+	server, m := newMirroredServer(t, ctrl, primary, mirror, metrics, 10, true)
+
+	resp, _ := post(t, server, "/api/v1/analyze", `{"url":"https://example.com"}`)
+	m.wait()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestMirror_WithoutDiffDiscardsTheResult(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	metrics := mocks.NewMockMetricsCollector(ctrl)
+	metrics.EXPECT().RecordMirror(mirrorSuccess)
+
+	changed := mirrorTestResult
+	changed.Title = "Something else"
+
+	var primaryCalls, mirrorCalls atomic.Int32
+	primary := newFakeAnalyzer(t, mirrorTestResult, nil, &primaryCalls)
+	mirror := newFakeAnalyzer(t, changed, nil, &mirrorCalls)
+	server, m := newMirroredServer(t, ctrl, primary, mirror, metrics, 10, false)
+
+	post(t, server, "/api/v1/analyze", `{"url":"https://example.com"}`)
+	m.wait()
+}
+
+func TestMirror_AddsNoLatency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	metrics := mocks.NewMockMetricsCollector(ctrl)
+	metrics.EXPECT().RecordMirror(mirrorSuccess)
+
+	release := make(chan struct{})
+	var primaryCalls, mirrorCalls atomic.Int32
+	primary := newFakeAnalyzer(t, mirrorTestResult, nil, &primaryCalls)
+	mirror := newFakeAnalyzer(t, mirrorTestResult, release, &mirrorCalls)
+	server, m := newMirroredServer(t, ctrl, primary, mirror, metrics, 10, true)
+
+	// The mirror answers only after the primary response has been sent
+	resp, body := post(t, server, "/api/v1/analyze", `{"url":"https://example.com"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Example Domain", body["title"])
+
+	close(release)
+	m.wait()
+}
+
+func TestMirror_CapsConcurrentCalls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	metrics := mocks.NewMockMetricsCollector(ctrl)
+	gomock.InOrder(
+		metrics.EXPECT().RecordMirror(mirrorDropped).Times(2),
+		metrics.EXPECT().RecordMirror(mirrorSuccess),
+	)
+
+	release := make(chan struct{})
+	var primaryCalls, mirrorCalls atomic.Int32
+	primary := newFakeAnalyzer(t, mirrorTestResult, nil, &primaryCalls)
+	mirror := newFakeAnalyzer(t, mirrorTestResult, release, &mirrorCalls)
+	server, m := newMirroredServer(t, ctrl, primary, mirror, metrics, 1, false)
+
+	for range 3 {
+		resp, _ := post(t, server, "/api/v1/analyze", `{"url":"https://example.com"}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	close(release)
+	m.wait()
+
+	assert.Equal(t, int32(3), primaryCalls.Load())
+	assert.Equal(t, int32(1), mirrorCalls.Load())
+}
+
+func TestMirror_FailureIsNotRetried(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	metrics := mocks.NewMockMetricsCollector(ctrl)
+	metrics.EXPECT().RecordMirror(mirrorFailure)
+
+	var primaryCalls, mirrorCalls atomic.Int32
+	primary := newFakeAnalyzer(t, mirrorTestResult, nil, &primaryCalls)
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorCalls.Add(1)
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(mirror.Close)
+	server, m := newMirroredServer(t, ctrl, primary, mirror, metrics, 10, true)
+
+	resp, _ := post(t, server, "/api/v1/analyze", `{"url":"https://example.com"}`)
+	m.wait()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(1), mirrorCalls.Load())
+}
+
+func TestMirror_Sampling(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	metrics := mocks.NewMockMetricsCollector(ctrl)
+	metrics.EXPECT().RecordMirror(mirrorSuccess).Times(2)
+
+	var mirrorCalls atomic.Int32
+	mirror := newFakeAnalyzer(t, mirrorTestResult, nil, &mirrorCalls)
+	client := NewAnalyzerClient(mirror.URL, 5*time.Second, setupMockLogger(ctrl))
+	m := NewMirror(client, 25, time.Second, 10, setupMockLogger(ctrl), metrics)
+
+	samples := []float64{10, 25, 99.5, 24.9}
+	m.sample = func() float64 {
+		sample := samples[0]
+		samples = samples[1:]
+		return sample
+	}
+	for range 4 {
+		m.Send(t.Context(), "https://example.com", models.AnalysisOptions{})(nil)
+	}
+	m.wait()
+
+	assert.Equal(t, int32(2), mirrorCalls.Load())
+}
+
+func TestResultDiff(t *testing.T) {
+	a := mirrorTestResult
+	a.AnalyzedAt = time.Now()
+	a.Timings = &models.Timings{TotalMs: 100}
+
+	b := mirrorTestResult
+	b.Timings = &models.Timings{TotalMs: 900}
+	fields, err := resultDiff(&a, &b)
+	require.NoError(t, err)
+	assert.Empty(t, fields)
+
+	b.Title = "Changed"
+	b.Headings.H2 = 3
+	b.Warnings = []models.Warning{{Code: models.WarningRedirected, Message: "redirected"}}
+	fields, err = resultDiff(&a, &b)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"headings", "title", "warnings"}, fields)
+}
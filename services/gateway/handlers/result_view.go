@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/fieldset"
+	"github.com/RuvinSL/webpage-analyzer/pkg/findings"
+	"github.com/RuvinSL/webpage-analyzer/services/gateway/translate"
+)
+
+// minSeverityError answers a min_severity query parameter that is not a
+// severity
+const minSeverityError = "min_severity must be info, warning or error"
+
+// resultView is how the v2 results of a response are shaped by its query
+// parameters: min_severity keeps only the findings of that severity or
+// above, fields selects the fields of each result, such as
+// fields=title,headings,links.total, and links_offset and links_limit page
+// its redirected links
+type resultView struct {
+	minSeverity string
+	fields      *fieldset.Set
+	paged       bool
+	linksOffset int
+	linksLimit  int
+}
+
+// parseResultView reads the view query parameters of r. Its error is the
+// message of a 400 response.
+func parseResultView(r *http.Request) (resultView, error) {
+	query := r.URL.Query()
+	view := resultView{minSeverity: query.Get("min_severity")}
+	if view.minSeverity != "" && !findings.ValidSeverity(view.minSeverity) {
+		return resultView{}, errors.New(minSeverityError)
+	}
+
+	fields, err := fieldset.Parse(reflect.TypeFor[translate.AnalysisResultV2](), query.Get("fields"))
+	if err != nil {
+		return resultView{}, errors.New("fields: " + err.Error())
+	}
+	view.fields = fields
+
+	if raw := query.Get("links_offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return resultView{}, errors.New("links_offset must be a non-negative integer")
+		}
+		view.paged, view.linksOffset = true, offset
+	}
+	if raw := query.Get("links_limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return resultView{}, errors.New("links_limit must be a positive integer")
+		}
+		view.paged, view.linksLimit = true, limit
+	}
+	return view, nil
+}
+
+// apply filters the findings of result and pages its redirected links
+func (v resultView) apply(result *translate.AnalysisResultV2) {
+	result.KeepFindings(v.minSeverity)
+	if v.paged {
+		result.PageRedirectedLinks(v.linksOffset, v.linksLimit)
+	}
+}
+
+// project selects the fields of the results at the path at of body, see
+// fieldset.Set.Project
+func (v resultView) project(body any, at ...string) (any, error) {
+	return v.fields.Project(body, at...)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContractV2_Fields(t *testing.T) {
+	server := newContractServer(t)
+
+	resp, body := post(t, server, "/api/v2/analyze?fields=title,headings,links.total", `{"url":"https://example.com"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]any{
+		"title":    "Example Domain",
+		"headings": map[string]any{"h1": float64(1), "h2": float64(0), "h3": float64(0), "h4": float64(0), "h5": float64(0), "h6": float64(0)},
+		"links":    map[string]any{"total": float64(2)},
+	}, body)
+
+	// Each batch item keeps its URL and status, its result the fields
+	resp, body = post(t, server, "/api/v2/batch-analyze?fields=title", `{"urls":["https://example.com","`+brokenURL+`"]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	items := body["items"].([]any)
+	require.Len(t, items, 2)
+	assert.Equal(t, map[string]any{"url": "https://example.com", "status": "succeeded", "result": map[string]any{"title": "Example Domain"}}, items[0])
+	assert.Contains(t, items[1], "error")
+	assert.Equal(t, float64(1), body["succeeded"])
+
+	// The saved results are shaped the same way
+	resp, body = get(t, server, "/api/v2/results?fields=url,links.internal")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	results := body["results"].([]any)
+	require.NotEmpty(t, results)
+	saved := results[0].(map[string]any)
+	assert.Contains(t, saved, "id")
+	assert.Equal(t, map[string]any{"url": "https://example.com", "links": map[string]any{"internal": float64(1)}}, saved["result"])
+
+	resp, body = get(t, server, "/api/v2/results/"+saved["id"].(string)+"?fields=title")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]any{"title": "Example Domain"}, body["result"])
+}
+
+func TestContractV2_RedirectedLinksPage(t *testing.T) {
+	server := newContractServer(t)
+	request := `{"url":"https://example.com","report_redirected_links":true}`
+
+	resp, body := post(t, server, "/api/v2/analyze?links_offset=1&links_limit=1&fields=redirected_links.url,redirected_links_page", request)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]any{
+		"redirected_links":      []any{map[string]any{"url": "https://example.com/b"}},
+		"redirected_links_page": map[string]any{"offset": float64(1), "limit": float64(1), "total": float64(3)},
+	}, body)
+
+	// Past the end the page is empty
+	_, body = post(t, server, "/api/v2/analyze?links_offset=5", request)
+	assert.NotContains(t, body, "redirected_links")
+	assert.Equal(t, map[string]any{"offset": float64(5), "total": float64(3)}, body["redirected_links_page"])
+
+	// Without paging every link is there
+	_, body = post(t, server, "/api/v2/analyze", request)
+	assert.Len(t, body["redirected_links"], 3)
+	assert.NotContains(t, body, "redirected_links_page")
+
+	// Without the links there is nothing to page
+	_, body = post(t, server, "/api/v2/analyze?links_limit=2", `{"url":"https://example.com"}`)
+	assert.NotContains(t, body, "redirected_links_page")
+}
+
+func TestContractV2_ViewErrors(t *testing.T) {
+	server := newContractServer(t)
+
+	tests := []struct {
+		query   string
+		message string
+	}{
+		{"fields=title,links.totl", `fields: unknown field "links.totl"`},
+		{"fields=Title", `fields: unknown field "Title"`},
+		{"fields=analyzed_at.year", `fields: unknown field "analyzed_at.year"`},
+		{"links_offset=-1", "links_offset must be a non-negative integer"},
+		{"links_limit=0", "links_limit must be a positive integer"},
+		{"links_limit=all", "links_limit must be a positive integer"},
+		{"min_severity=fatal", minSeverityError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			for _, path := range []string{"/api/v2/analyze", "/api/v2/batch-analyze"} {
+				resp, body := post(t, server, path+"?"+tt.query, `{"url":"https://example.com","urls":["https://example.com"]}`)
+				assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+				assert.Equal(t, tt.message, body["error"])
+			}
+			resp, body := get(t, server, "/api/v2/results?"+tt.query)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Equal(t, tt.message, body["error"])
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/fieldset"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httperror"
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/storage"
+	"github.com/RuvinSL/webpage-analyzer/services/gateway/translate"
+	"github.com/gorilla/mux"
+)
+
+// ResultsHandler serves the saved analysis results
+type ResultsHandler struct {
+	store  storage.Store
+	logger interfaces.Logger
+}
+
+func NewResultsHandler(store storage.Store, logger interfaces.Logger) *ResultsHandler {
+	return &ResultsHandler{store: store, logger: logger}
+}
+
+// List serves GET /api/v2/results, newest first. The optional url query
+// parameter keeps only the results for that URL, limit caps their number and
+// those of resultView shape each of them.
+func (h *ResultsHandler) List(w http.ResponseWriter, r *http.Request) {
+	view, err := parseResultView(r)
+	if err != nil {
+		h.sendError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := storage.ResultFilter{URL: query.Get("url")}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			h.sendError(w, r, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	records, err := h.store.ListResults(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("Failed to list saved results", "error", err)
+		h.sendError(w, r, "Failed to list saved results", http.StatusInternalServerError)
+		return
+	}
+
+	results := make([]translate.StoredResultV2, len(records))
+	for i, record := range records {
+		results[i] = translate.StoredToV2(record)
+		view.apply(&results[i].Result)
+	}
+	h.sendView(w, r, view, translate.ResultListV2{Results: results}, "results", fieldset.Each, "result")
+}
+
+// Get serves GET /api/v2/results/{id}, shaped by the query parameters of
+// resultView as List's are
+func (h *ResultsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	view, err := parseResultView(r)
+	if err != nil {
+		h.sendError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	record, err := h.store.GetResult(r.Context(), mux.Vars(r)["id"])
+	if errors.Is(err, storage.ErrNotFound) {
+		h.sendError(w, r, "Result not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get saved result", "error", err)
+		h.sendError(w, r, "Failed to get saved result", http.StatusInternalServerError)
+		return
+	}
+
+	stored := translate.StoredToV2(record)
+	view.apply(&stored.Result)
+	h.sendView(w, r, view, stored, "result")
+}
+
+// sendJSON writes a 200 response with the given body
+func (h *ResultsHandler) sendJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.logger.Error("Failed to encode response", "error", err)
+	}
+}
+
+// sendView writes body with the fields view selects of the results at the
+// path at
+func (h *ResultsHandler) sendView(w http.ResponseWriter, r *http.Request, view resultView, body any, at ...string) {
+	projected, err := view.project(body, at...)
+	if err != nil {
+		h.logger.Error("Failed to select response fields", "error", err)
+		h.sendError(w, r, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	h.sendJSON(w, projected)
+}
+
+// sendError sends an error response
+func (h *ResultsHandler) sendError(w http.ResponseWriter, r *http.Request, message string, statusCode int) {
+	if err := httperror.Write(w, r, httperror.New(message, statusCode)); err != nil {
+		h.logger.Error("Failed to encode error response", "error", err)
+	}
+}
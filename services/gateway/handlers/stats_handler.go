@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// statsTimeout bounds the wait for each downstream /stats
+const statsTimeout = 2 * time.Second
+
+// StatsHandler serves the gateway's load together with the /stats of the
+// services behind it
+type StatsHandler struct {
+	serviceName string
+	downstream  map[string]string
+	httpClient  *http.Client
+	startTime   time.Time
+	admission   func() models.AdmissionStats
+}
+
+// NewStatsHandler aggregates the /stats of downstream, base URLs by service
+// name
+func NewStatsHandler(serviceName string, downstream map[string]string) *StatsHandler {
+	return &StatsHandler{
+		serviceName: serviceName,
+		downstream:  downstream,
+		httpClient:  &http.Client{Timeout: statsTimeout},
+		startTime:   time.Now(),
+	}
+}
+
+// SetAdmission reports the admission limiter state from stats
+func (h *StatsHandler) SetAdmission(stats func() models.AdmissionStats) {
+	h.admission = stats
+}
+
+// Stats handles GET /stats. A downstream service that cannot be read is
+// reported with its error rather than failing the response.
+func (h *StatsHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	response := models.ServiceStats{
+		Service:       h.serviceName,
+		UptimeSeconds: int64(time.Since(h.startTime) / time.Second),
+		Downstream:    make(map[string]*models.ServiceStats, len(h.downstream)),
+	}
+	if h.admission != nil {
+		stats := h.admission()
+		response.Admission = &stats
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, baseURL := range h.downstream {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats, err := h.fetch(r.Context(), baseURL)
+			if err != nil {
+				stats = &models.ServiceStats{Service: name, Error: err.Error()}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			response.Downstream[name] = stats
+		}()
+	}
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *StatsHandler) fetch(ctx context.Context, baseURL string) (*models.ServiceStats, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/stats", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("stats returned status %d", resp.StatusCode)
+	}
+
+	var stats models.ServiceStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("failed to decode stats: %w", err)
+	}
+	return &stats, nil
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsHandler_AggregatesDownstream(t *testing.T) {
+	analyzer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/stats", r.URL.Path)
+		json.NewEncoder(w).Encode(models.ServiceStats{
+			Service:  "analyzer",
+			Analyses: &models.AnalysisStats{InFlight: 2, CacheHits: 3, CacheMisses: 1, CacheHitRate: 0.75},
+		})
+	}))
+	defer analyzer.Close()
+	linkChecker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer linkChecker.Close()
+
+	handler := NewStatsHandler("gateway", map[string]string{
+		"analyzer":     analyzer.URL,
+		"link-checker": linkChecker.URL,
+	})
+	admission := models.AdmissionStats{InFlight: 1, MaxInFlight: 4}
+	handler.SetAdmission(func() models.AdmissionStats { return admission })
+
+	w := httptest.NewRecorder()
+	handler.Stats(w, httptest.NewRequest("GET", "/stats", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	var stats models.ServiceStats
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&stats))
+	assert.Equal(t, "gateway", stats.Service)
+	assert.Equal(t, &admission, stats.Admission)
+	require.Len(t, stats.Downstream, 2)
+	assert.Equal(t, int64(2), stats.Downstream["analyzer"].Analyses.InFlight)
+	assert.Equal(t, 0.75, stats.Downstream["analyzer"].Analyses.CacheHitRate)
+	assert.Equal(t, "link-checker", stats.Downstream["link-checker"].Service)
+	assert.Equal(t, "stats returned status 503", stats.Downstream["link-checker"].Error)
+}
@@ -10,53 +10,135 @@ import (
 	"syscall"
 	"time"
 
-	"net/http/pprof"
-
+	"github.com/RuvinSL/webpage-analyzer/pkg/admin"
+	"github.com/RuvinSL/webpage-analyzer/pkg/config"
+	"github.com/RuvinSL/webpage-analyzer/pkg/faults"
+	"github.com/RuvinSL/webpage-analyzer/pkg/healthprobe"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
 	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
 	"github.com/RuvinSL/webpage-analyzer/pkg/metrics"
+	"github.com/RuvinSL/webpage-analyzer/pkg/middleware"
+	"github.com/RuvinSL/webpage-analyzer/pkg/readiness"
+	"github.com/RuvinSL/webpage-analyzer/pkg/servertls"
+	"github.com/RuvinSL/webpage-analyzer/pkg/storage"
+	"github.com/RuvinSL/webpage-analyzer/pkg/storage/postgres"
+	"github.com/RuvinSL/webpage-analyzer/pkg/urlutil"
+	"github.com/RuvinSL/webpage-analyzer/pkg/version"
+	"github.com/RuvinSL/webpage-analyzer/services/gateway/artifacts"
 	"github.com/RuvinSL/webpage-analyzer/services/gateway/handlers"
-	"github.com/RuvinSL/webpage-analyzer/services/gateway/middleware"
+	gatewayMiddleware "github.com/RuvinSL/webpage-analyzer/services/gateway/middleware"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 const (
-	defaultPort = "8080"
 	serviceName = "gateway"
 )
 
-// createLogger creates a logger with optional file output
-func createLogger() interfaces.Logger {
-	// Check if file logging is enabled via environment variable
-	if getEnv("LOG_TO_FILE", "true") == "true" {
-		logDir := getEnv("LOG_DIR", "./logs")
-		return logger.NewWithFiles(serviceName, getLogLevel(), logDir)
+// apiV1Sunset is advertised on every /api/v1 response; v1 is removed after it
+var apiV1Sunset = time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC)
+
+// createLogger creates a logger with optional file output. The level is a
+// slog.Leveler so a *slog.LevelVar can be adjusted after startup
+func createLogger(cfg *config.Common, level slog.Leveler) interfaces.Logger {
+	if cfg.LogToFile {
+		return logger.NewWithFiles(serviceName, level, cfg.LogDir)
 	}
 
-	// Default: stdout only (your current behavior)
-	return logger.New(serviceName, getLogLevel())
+	return logger.New(serviceName, level)
+}
+
+// loadConfigOrExit reports configuration errors through a bootstrap logger
+// and exits, so a bad value never silently falls back to a default
+func loadConfigOrExit[T any](load func() (T, error)) T {
+	cfg, err := load()
+	if err != nil {
+		logger.New(serviceName, slog.LevelInfo).Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	return cfg
+}
+
+// faultsOrExit returns the injector of FAULT_INJECT, nil when it is not set,
+// and warns loudly when it is
+func faultsOrExit(cfg *config.Common, log interfaces.Logger) *faults.Injector {
+	injector, err := faults.New(cfg.FaultInject, cfg.FaultInjectSeed)
+	if err != nil {
+		log.Error("Invalid configuration", "error", fmt.Errorf("FAULT_INJECT: %w", err))
+		os.Exit(1)
+	}
+	if injector != nil {
+		log.Warn("FAULT INJECTION ENABLED: latency and errors are injected on purpose, never run this in production",
+			"rules", injector.Rules(), "seed", injector.Seed())
+	}
+	return injector
 }
 
 func main() {
 	// Initialize structured logger
+	cfg := loadConfigOrExit(config.LoadGateway)
+
 	//log := logger.New(serviceName, getLogLevel()) // Modified by Ruvin
-	log := createLogger()
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(cfg.SlogLevel())
+	log := createLogger(&cfg.Common, logLevel)
+	log.Info("Loaded configuration", config.Fields(cfg)...)
+	faultInjector := faultsOrExit(&cfg.Common, log)
 
 	// Initialize metrics
 	metricsCollector := metrics.NewPrometheusCollector(serviceName)
 	prometheus.MustRegister(metricsCollector.GetCollectors()...)
 
-	// Configuration
-	port := getEnv("PORT", defaultPort)
-	analyzerURL := getEnv("ANALYZER_SERVICE_URL", "http://localhost:8081")
-
 	// Initialize handlers
-	analyzerClient := handlers.NewAnalyzerClient(analyzerURL, 30*time.Second, log)
+	analyzerClient := handlers.NewAnalyzerClient(cfg.AnalyzerURL, cfg.AnalyzerTimeout, log)
+	analyzerClient.SetH2C(cfg.InternalH2C)
+	analyzerClient.SetFaults(faultInjector)
+	analyzerClient.SetMetrics(metricsCollector)
+	analyzerClient.SetLogResultContent(cfg.LogResultContent)
 	apiHandler := handlers.NewAPIHandler(analyzerClient, log, metricsCollector)
+	urls, err := urlutil.New(cfg.URLNormalization.Policy())
+	if err != nil {
+		log.Error("Invalid URL normalization", "error", err)
+		os.Exit(1)
+	}
+	apiHandler.SetURLNormalization(urls)
+	if cfg.MirrorAnalyzerURL != "" {
+		apiHandler.SetMirror(newMirror(cfg, log, metricsCollector))
+	}
+	artifactStore := artifacts.NewStore(cfg.ArtifactTTL, cfg.ArtifactMaxItems, cfg.ArtifactMaxBytes)
+	apiHandler.SetArtifactStore(artifactStore)
+	resultStore, err := openResultStore(cfg, log)
+	if err != nil {
+		log.Error("Failed to open result storage", "backend", cfg.StorageBackend, "error", err)
+		os.Exit(1)
+	}
+	defer resultStore.Close()
+	apiHandler.SetResultStore(resultStore)
+	resultsHandler := handlers.NewResultsHandler(resultStore, log)
+	// Background work stops when main returns
+	background, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	go artifactStore.Run(background, time.Minute)
 	webHandler := handlers.NewWebHandler(log)
-	healthHandler := handlers.NewHealthHandler(serviceName, analyzerClient)
+	// /health answers from a background check of the analyzer
+	analyzerProbe := healthprobe.New("analyzer_service", analyzerClient, cfg.HealthCheckInterval, cfg.HealthCheckTimeout, log)
+	go analyzerProbe.Run(background)
+	healthHandler := handlers.NewHealthHandler(serviceName, analyzerProbe)
+
+	// Admission control keeps a traffic spike from piling onto the analyzer
+	limiter := gatewayMiddleware.NewLimiter(serviceName, cfg.MaxConcurrentAnalyses, cfg.AnalysisQueueSize, cfg.AnalysisQueueTimeout)
+	prometheus.MustRegister(limiter.Collectors()...)
+	healthHandler.SetAdmission(limiter.Stats)
+	statsHandler := handlers.NewStatsHandler(serviceName, map[string]string{
+		"analyzer_service":     cfg.AnalyzerURL,
+		"link_checker_service": cfg.LinkCheckerURL,
+	})
+	statsHandler.SetAdmission(limiter.Stats)
+	dependencies := []readiness.Dependency{{Name: "analyzer_service", Checker: analyzerClient}}
+	if checker, ok := resultStore.(interfaces.HealthChecker); ok {
+		dependencies = append(dependencies, readiness.Dependency{Name: "result_storage", Checker: checker})
+	}
+	readinessGate := readiness.NewGate(serviceName, log, dependencies...)
 
 	// Setup routes
 	router := mux.NewRouter()
@@ -66,60 +148,153 @@ func main() {
 	router.Use(middleware.Logging(log))
 	router.Use(middleware.Metrics(metricsCollector))
 	router.Use(middleware.Recovery(log))
-	router.Use(middleware.CORS())
+	if cfg.Compression {
+		router.Use(middleware.Compress(middleware.CompressOptions{MinSize: cfg.CompressionMinSize}))
+	}
+	// Inside the compression, so bodies are logged as the handlers see them
+	router.Use(middleware.LogBodies(log, middleware.BodyLogOptions{
+		Always:   cfg.DebugLogBodies,
+		Token:    cfg.AdminToken,
+		MaxBytes: cfg.DebugLogBodiesMaxBytes,
+	}))
+	if cfg.DebugLogBodies {
+		log.Warn("Logging every request and response body at debug level", "max_bytes", cfg.DebugLogBodiesMaxBytes)
+	}
+	router.Use(middleware.CORS(middleware.CORSOptions{
+		AllowedOrigins:   cfg.CORSAllowedOrigins,
+		AllowedMethods:   cfg.CORSAllowedMethods,
+		AllowedHeaders:   cfg.CORSAllowedHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
+	}))
+	router.Use(gatewayMiddleware.AppVersion)
+	router.Use(gatewayMiddleware.Caller)
+	router.Use(gatewayMiddleware.SecurityHeaders(securityHeadersOptions(cfg)))
+
+	// Per-route deadlines; the time spent queueing for admission counts
+	analyzeTimeout := gatewayMiddleware.Timeout(cfg.RouteTimeoutAnalyze)
+	batchTimeout := gatewayMiddleware.Timeout(cfg.RouteTimeoutBatch)
+	healthTimeout := gatewayMiddleware.Timeout(cfg.RouteTimeoutHealth)
 
-	// API routes
-	api := router.PathPrefix("/api/v1").Subrouter()
-	api.HandleFunc("/analyze", apiHandler.AnalyzeURL).Methods("POST", "OPTIONS")
-	api.HandleFunc("/batch-analyze", apiHandler.BatchAnalyze).Methods("POST", "OPTIONS")
+	// API routes. v1 keeps the legacy response shapes until its sunset date.
+	apiV1 := router.PathPrefix("/api/v1").Subrouter()
+	apiV1.Use(gatewayMiddleware.Deprecation(apiV1Sunset, "/api/v2"))
+	apiV1.Use(faultInjector.Middleware)
+	apiV1.Handle("/analyze", analyzeTimeout(limiter.Limit(http.HandlerFunc(apiHandler.AnalyzeURL)))).Methods("POST", "OPTIONS")
+	apiV1.Handle("/batch-analyze", batchTimeout(limiter.Limit(http.HandlerFunc(apiHandler.BatchAnalyze)))).Methods("POST", "OPTIONS")
+	apiV1.HandleFunc("/artifacts/{id}", artifactStore.Handler).Methods("GET")
+
+	apiV2 := router.PathPrefix("/api/v2").Subrouter()
+	apiV2.Use(faultInjector.Middleware)
+	apiV2.Handle("/analyze", analyzeTimeout(limiter.Limit(http.HandlerFunc(apiHandler.AnalyzeURLV2)))).Methods("POST", "OPTIONS")
+	apiV2.Handle("/batch-analyze", batchTimeout(limiter.Limit(http.HandlerFunc(apiHandler.BatchAnalyzeV2)))).Methods("POST", "OPTIONS")
+	apiV2.HandleFunc("/artifacts/{id}", artifactStore.Handler).Methods("GET")
+	apiV2.HandleFunc("/results", resultsHandler.List).Methods("GET")
+	apiV2.HandleFunc("/results/{id}", resultsHandler.Get).Methods("GET")
 
 	// Web UI routes
 	router.HandleFunc("/", webHandler.HomePage).Methods("GET")
 	router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("./web/static"))))
 
 	// Health and monitoring routes
-	router.HandleFunc("/health", healthHandler.Health).Methods("GET")
-	router.Handle("/metrics", promhttp.Handler())
-
-	// pprof routes for profiling
-	router.HandleFunc("/debug/pprof/", pprof.Index)
-	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-	router.HandleFunc("/debug/pprof/profile", pprof.Profile)
-	router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-	router.HandleFunc("/debug/pprof/trace", pprof.Trace)
-	router.Handle("/debug/pprof/heap", pprof.Handler("heap"))
-	router.Handle("/debug/pprof/goroutine", pprof.Handler("goroutine"))
-	router.Handle("/debug/pprof/block", pprof.Handler("block"))
+	router.Handle("/health", healthTimeout(http.HandlerFunc(healthHandler.Health))).Methods("GET")
+	router.Handle("/health/ready", healthTimeout(http.HandlerFunc(readinessGate.Ready))).Methods("GET")
+	router.HandleFunc("/version", version.Handler(serviceName)).Methods("GET")
+	router.HandleFunc("/stats", statsHandler.Stats).Methods("GET")
+	router.Handle("/metrics", metrics.Handler())
+
+	// Admin routes, guarded by ADMIN_TOKEN
+	logLevelHandler := admin.NewLogLevelHandler(logLevel, log)
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(admin.RequireToken(cfg.AdminToken))
+	adminRouter.HandleFunc("/loglevel", logLevelHandler.Get).Methods("GET")
+	adminRouter.HandleFunc("/loglevel", logLevelHandler.Set).Methods("PUT")
+
+	// pprof, only when DEBUG_ENDPOINTS is set
+	debugServer := admin.MountDebug(router, admin.DebugOptions{
+		Enabled: cfg.DebugEndpoints,
+		Addr:    cfg.DebugAddr,
+		Token:   cfg.AdminToken,
+	})
 
 	// Create server
 	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%s", port),
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
 		Handler:      router,
 		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 60 * time.Second,
+		WriteTimeout: writeTimeout(cfg),
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// HTTPS is served directly when a certificate is configured, and a
+	// renewed certificate is picked up without a restart
+	var certs *servertls.CertReloader
+	if cfg.TLSEnabled() {
+		var err error
+		certs, err = servertls.NewCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile, log)
+		if err != nil {
+			log.Error("Failed to load TLS certificate", "error", err)
+			os.Exit(1)
+		}
+		srv.TLSConfig = certs.TLSConfig()
+		go certs.Watch(context.Background(), cfg.TLSReloadInterval)
+	}
+	redirectServer := servertls.RedirectServer(cfg.TLSRedirectAddr, cfg.Port)
+
 	go func() {
 		//	log.Info("Starting API Gateway", "port", port)
-		log.Info("Starting Analyzer Service",
+		log.Info("Starting API Gateway",
 			"service", serviceName,
-			"port", port,
-			"log_level", getLogLevel().String(),
-			"log_to_file", getEnv("LOG_TO_FILE", "false"),
-			"log_dir", getEnv("LOG_DIR", "./logs"),
-			"version", getEnv("APP_VERSION", "dev"),
+			"port", cfg.Port,
+			"log_level", cfg.LogLevel,
+			"log_to_file", cfg.LogToFile,
+			"log_dir", cfg.LogDir,
+			"tls", cfg.TLSEnabled(),
+			"version", version.Get().Version,
+			"commit", version.Get().Commit,
 		)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := servertls.ListenAndServe(srv); err != nil && err != http.ErrServerClosed {
 			log.Error("Failed to start server", "error", err)
 			os.Exit(1)
 		}
 	}()
 
+	if redirectServer != nil {
+		go func() {
+			log.Info("Redirecting HTTP to HTTPS", "addr", cfg.TLSRedirectAddr)
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("HTTP redirect server failed", "error", err)
+			}
+		}()
+	}
+	if debugServer != nil {
+		go func() {
+			log.Info("Serving debug endpoints", "addr", cfg.DebugAddr)
+			if err := debugServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("Debug server failed", "error", err)
+			}
+		}()
+	}
+
+	go waitForReadiness(readinessGate, &cfg.Common, log)
+
 	// Wait for interrupt signal
+	// Reload the runtime-tunable settings on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reloadConfig(cfg, logLevel, log)
+			if certs != nil {
+				certs.Reload()
+			}
+		}
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
+	signal.Stop(hup)
 
 	log.Info("Shutting down server...")
 
@@ -129,26 +304,124 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Error("Server forced to shutdown", "error", err)
 	}
+	if redirectServer != nil {
+		redirectServer.Shutdown(ctx)
+	}
+	if debugServer != nil {
+		debugServer.Shutdown(ctx)
+	}
 
 	log.Info("Server exited")
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// newMirror returns the mirror of the single analyses to
+// MIRROR_ANALYZER_URL. Its calls are made once: a retry would only add
+// load for a result nobody waits for.
+func newMirror(cfg *config.Gateway, log interfaces.Logger, metrics interfaces.MetricsCollector) *handlers.Mirror {
+	client := handlers.NewAnalyzerClient(cfg.MirrorAnalyzerURL, cfg.MirrorTimeout, log)
+	client.SetH2C(cfg.InternalH2C)
+	client.SetMaxRetries(0)
+	client.SetLogResultContent(cfg.LogResultContent)
+
+	mirror := handlers.NewMirror(client, cfg.MirrorPercent, cfg.MirrorTimeout, cfg.MirrorMaxConcurrent, log, metrics)
+	mirror.SetDiff(cfg.MirrorDiff)
+	log.Info("Mirroring analyses",
+		"mirror_analyzer_url", cfg.MirrorAnalyzerURL,
+		"percent", cfg.MirrorPercent,
+		"max_concurrent", cfg.MirrorMaxConcurrent,
+		"diff", cfg.MirrorDiff,
+	)
+	return mirror
+}
+
+// securityHeadersOptions sorts the routes into UI pages and API routes, the
+// latter being everything that answers JSON, metrics or artifacts
+func securityHeadersOptions(cfg *config.Gateway) gatewayMiddleware.SecurityHeadersOptions {
+	opts := gatewayMiddleware.SecurityHeadersOptions{
+		ContentSecurityPolicy: cfg.SecurityCSP,
+		FrameOptions:          cfg.SecurityFrameOptions,
+		ReferrerPolicy:        cfg.SecurityReferrerPolicy,
+		APIPaths:              []string{"/api/", "/admin/", "/health", "/health/", "/version", "/stats", "/metrics"},
+		ExcludedPaths:         cfg.SecurityHeadersExclude,
+	}
+	if cfg.SecurityHSTS {
+		opts.HSTSMaxAge = cfg.SecurityHSTSMaxAge
+	}
+	return opts
+}
+
+// openResultStore opens the configured result storage. The postgres schema
+// is migrated first when POSTGRES_MIGRATE is set.
+func openResultStore(cfg *config.Gateway, log interfaces.Logger) (storage.Store, error) {
+	if cfg.StorageBackend != storage.BackendPostgres {
+		return storage.NewMemory(cfg.StorageMaxResults), nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.StartupMaxWait)
+	defer cancel()
+
+	store, err := postgres.Open(ctx, postgres.Options{
+		DSN:             cfg.PostgresDSN,
+		MaxConns:        int32(cfg.PostgresMaxConns),
+		MinConns:        int32(cfg.PostgresMinConns),
+		MaxConnLifetime: cfg.PostgresMaxConnLifetime,
+		QueryTimeout:    cfg.StorageQueryTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.PostgresMigrate {
+		applied, err := store.Migrate(ctx)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		log.Info("Storage migrations applied", "migrations", applied)
+	}
+	return store, nil
+}
+
+// waitForReadiness blocks until the critical dependencies are reachable and
+// flips the readiness gate, exiting the process in fail-fast mode
+func waitForReadiness(gate *readiness.Gate, cfg *config.Common, log interfaces.Logger) {
+	mode := readiness.Mode(cfg.StartupMode)
+	if err := gate.Wait(context.Background(), cfg.StartupMaxWait, mode); err != nil {
+		log.Error("Startup dependencies unavailable", "error", err, "max_wait", cfg.StartupMaxWait)
+		os.Exit(1)
 	}
-	return defaultValue
 }
 
-func getLogLevel() slog.Level {
-	switch os.Getenv("LOG_LEVEL") {
-	case "debug":
-		return slog.LevelDebug
-	case "warn":
-		return slog.LevelWarn
-	case "error":
-		return slog.LevelError
-	default:
-		return slog.LevelInfo
+// reloadConfig re-reads the configuration on SIGHUP and applies the log level.
+// Environment variables are fixed for the life of the process, so in practice
+// new values arrive through CONFIG_FILE; every other change is reported and
+// ignored until restart.
+func reloadConfig(cfg *config.Gateway, level *slog.LevelVar, log interfaces.Logger) {
+	next, err := config.LoadGateway()
+	if err != nil {
+		log.Error("Configuration reload failed, keeping current values", "error", err)
+		return
 	}
+
+	var ignored []string
+	for _, key := range config.Changed(cfg, next) {
+		switch key {
+		case "LOG_LEVEL":
+			level.Set(next.SlogLevel())
+			cfg.LogLevel = next.LogLevel
+		default:
+			ignored = append(ignored, key)
+		}
+	}
+
+	if len(ignored) > 0 {
+		log.Warn("Configuration changes ignored until restart", "fields", ignored)
+	}
+	log.Info("Configuration reloaded", "log_level", cfg.LogLevel)
+}
+
+// writeTimeout outlasts the longest route deadline, so the server does not
+// cut off a response, such as a route's 504, that is still within it
+func writeTimeout(cfg *config.Gateway) time.Duration {
+	return max(cfg.RouteTimeoutAnalyze, cfg.RouteTimeoutBatch, cfg.RouteTimeoutHealth) + 10*time.Second
 }
@@ -1,142 +1,68 @@
 package main
 
 import (
+	"bytes"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
+	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/config"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	gatewayMiddleware "github.com/RuvinSL/webpage-analyzer/services/gateway/middleware"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-// Test utility functions
-func TestGetEnv(t *testing.T) {
-	tests := []struct {
-		name         string
-		key          string
-		defaultValue string
-		envValue     string
-		expected     string
-	}{
-		{
-			name:         "returns environment value when set",
-			key:          "TEST_GATEWAY_KEY",
-			defaultValue: "default",
-			envValue:     "env_value",
-			expected:     "env_value",
-		},
-		{
-			name:         "returns default value when env not set",
-			key:          "UNSET_GATEWAY_KEY",
-			defaultValue: "default",
-			envValue:     "",
-			expected:     "default",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Clean up environment
-			os.Unsetenv(tt.key)
-
-			if tt.envValue != "" {
-				os.Setenv(tt.key, tt.envValue)
-				defer os.Unsetenv(tt.key)
-			}
-
-			result := getEnv(tt.key, tt.defaultValue)
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
-func TestGetLogLevel(t *testing.T) {
-	tests := []struct {
-		name     string
-		envValue string
-		expected slog.Level
-	}{
-		{
-			name:     "returns debug level",
-			envValue: "debug",
-			expected: slog.LevelDebug,
-		},
-		{
-			name:     "returns warn level",
-			envValue: "warn",
-			expected: slog.LevelWarn,
-		},
-		{
-			name:     "returns error level",
-			envValue: "error",
-			expected: slog.LevelError,
-		},
-		{
-			name:     "returns info level as default",
-			envValue: "",
-			expected: slog.LevelInfo,
-		},
-		{
-			name:     "returns info level for invalid value",
-			envValue: "invalid",
-			expected: slog.LevelInfo,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			os.Unsetenv("LOG_LEVEL")
-
-			if tt.envValue != "" {
-				os.Setenv("LOG_LEVEL", tt.envValue)
-				defer os.Unsetenv("LOG_LEVEL")
-			}
-
-			result := getLogLevel()
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
 func TestCreateLogger(t *testing.T) {
 	t.Run("creates logger successfully", func(t *testing.T) {
 		// Test with file logging disabled to avoid file creation
-		os.Setenv("LOG_TO_FILE", "false")
-		defer os.Unsetenv("LOG_TO_FILE")
+		cfg := config.DefaultGateway()
+		cfg.LogToFile = false
 
-		logger := createLogger()
+		logger := createLogger(&cfg.Common, cfg.SlogLevel())
 		assert.NotNil(t, logger)
 	})
 }
 
 func TestServerConfiguration(t *testing.T) {
 	t.Run("uses default configuration values", func(t *testing.T) {
-		// Clean environment
-		os.Unsetenv("PORT")
-		os.Unsetenv("ANALYZER_SERVICE_URL")
+		t.Setenv("PORT", "")
+		t.Setenv("ANALYZER_SERVICE_URL", "")
 
-		port := getEnv("PORT", defaultPort)
-		analyzerURL := getEnv("ANALYZER_SERVICE_URL", "http://localhost:8081")
+		cfg, err := config.LoadGateway()
+		require.NoError(t, err)
 
-		assert.Equal(t, defaultPort, port)
-		assert.Equal(t, "http://localhost:8081", analyzerURL)
+		assert.Equal(t, 8080, cfg.Port)
+		assert.Equal(t, "http://localhost:8081", cfg.AnalyzerURL)
+		assert.Equal(t, 30*time.Second, cfg.AnalyzerTimeout)
 	})
 
 	t.Run("uses custom configuration values", func(t *testing.T) {
-		os.Setenv("PORT", "9090")
-		os.Setenv("ANALYZER_SERVICE_URL", "http://custom-analyzer:8081")
-		defer func() {
-			os.Unsetenv("PORT")
-			os.Unsetenv("ANALYZER_SERVICE_URL")
-		}()
-
-		port := getEnv("PORT", defaultPort)
-		analyzerURL := getEnv("ANALYZER_SERVICE_URL", "http://localhost:8081")
-
-		assert.Equal(t, "9090", port)
-		assert.Equal(t, "http://custom-analyzer:8081", analyzerURL)
+		t.Setenv("PORT", "9090")
+		t.Setenv("ANALYZER_SERVICE_URL", "http://custom-analyzer:8081")
+
+		cfg, err := config.LoadGateway()
+		require.NoError(t, err)
+
+		assert.Equal(t, 9090, cfg.Port)
+		assert.Equal(t, "http://custom-analyzer:8081", cfg.AnalyzerURL)
+	})
+
+	t.Run("rejects invalid values instead of defaulting", func(t *testing.T) {
+		t.Setenv("PORT", "not-a-port")
+
+		_, err := config.LoadGateway()
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects malformed analyzer URL", func(t *testing.T) {
+		t.Setenv("ANALYZER_SERVICE_URL", "analyzer:8081")
+
+		_, err := config.LoadGateway()
+		assert.Error(t, err)
 	})
 }
 
@@ -161,120 +87,91 @@ func TestBasicRouterSetup(t *testing.T) {
 	})
 }
 
+func TestSecurityHeadersOptions_RouteClasses(t *testing.T) {
+	cfg := config.DefaultGateway()
+	cfg.SecurityHSTS = true
+
+	router := mux.NewRouter()
+	router.Use(gatewayMiddleware.SecurityHeaders(securityHeadersOptions(cfg)))
+	router.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := map[string]string{
+		"/":                      config.DefaultContentSecurityPolicy,
+		"/static/css/style.css":  config.DefaultContentSecurityPolicy,
+		"/api/v1/analyze":        gatewayMiddleware.APIContentSecurityPolicy,
+		"/api/v2/artifacts/abc":  gatewayMiddleware.APIContentSecurityPolicy,
+		"/health":                gatewayMiddleware.APIContentSecurityPolicy,
+		"/health/ready":          gatewayMiddleware.APIContentSecurityPolicy,
+		"/metrics":               gatewayMiddleware.APIContentSecurityPolicy,
+		"/admin/loglevel":        gatewayMiddleware.APIContentSecurityPolicy,
+		"/debug/pprof/goroutine": "",
+	}
+	for path, want := range tests {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+
+		assert.Equal(t, want, recorder.Header().Get("Content-Security-Policy"), path)
+		if want == "" {
+			assert.Empty(t, recorder.Header().Get("Strict-Transport-Security"), path)
+		} else {
+			assert.Equal(t, "max-age=31536000", recorder.Header().Get("Strict-Transport-Security"), path)
+		}
+	}
+}
+
 func TestConstants(t *testing.T) {
 	t.Run("service constants are correct", func(t *testing.T) {
-		assert.Equal(t, "8080", defaultPort)
 		assert.Equal(t, "gateway", serviceName)
 	})
 }
 
-func TestEnvironmentVariables(t *testing.T) {
-	t.Run("all environment variables work correctly", func(t *testing.T) {
-		envTests := map[string]struct {
-			key          string
-			testValue    string
-			defaultValue string
-		}{
-			"PORT": {
-				key:          "PORT",
-				testValue:    "8888",
-				defaultValue: defaultPort,
-			},
-			"ANALYZER_SERVICE_URL": {
-				key:          "ANALYZER_SERVICE_URL",
-				testValue:    "http://test:8081",
-				defaultValue: "http://localhost:8081",
-			},
-			"LOG_LEVEL": {
-				key:          "LOG_LEVEL",
-				testValue:    "debug",
-				defaultValue: "info",
-			},
-			"LOG_TO_FILE": {
-				key:          "LOG_TO_FILE",
-				testValue:    "false",
-				defaultValue: "true",
-			},
-			"APP_VERSION": {
-				key:          "APP_VERSION",
-				testValue:    "v1.0.0",
-				defaultValue: "dev",
-			},
-		}
-
-		for name, test := range envTests {
-			t.Run(name, func(t *testing.T) {
-				// Clean environment
-				os.Unsetenv(test.key)
+func TestReloadConfig(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "info")
+	cfg, err := config.LoadGateway()
+	require.NoError(t, err)
 
-				// Test default value
-				defaultResult := getEnv(test.key, test.defaultValue)
-				assert.Equal(t, test.defaultValue, defaultResult)
+	var buf bytes.Buffer
+	level := new(slog.LevelVar)
+	level.Set(cfg.SlogLevel())
+	log := logger.NewAdapter(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: level})))
 
-				// Test custom value
-				os.Setenv(test.key, test.testValue)
-				customResult := getEnv(test.key, test.defaultValue)
-				assert.Equal(t, test.testValue, customResult)
+	log.Debug("before reload")
+	assert.NotContains(t, buf.String(), "before reload")
 
-				// Clean up
-				os.Unsetenv(test.key)
-			})
-		}
-	})
-}
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("PORT", "9999")
+	reloadConfig(cfg, level, log)
 
-// Benchmark tests
-func BenchmarkGetEnv(b *testing.B) {
-	os.Setenv("BENCH_TEST", "value")
-	defer os.Unsetenv("BENCH_TEST")
+	assert.Equal(t, slog.LevelDebug, level.Level())
+	assert.Equal(t, "debug", cfg.LogLevel)
+	log.Debug("after reload")
+	assert.Contains(t, buf.String(), "after reload")
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		getEnv("BENCH_TEST", "default")
-	}
+	// Non-reloadable fields are reported and left untouched
+	assert.Contains(t, buf.String(), "Configuration changes ignored until restart")
+	assert.Contains(t, buf.String(), "PORT")
+	assert.Equal(t, 8080, cfg.Port)
 }
 
-func BenchmarkGetLogLevel(b *testing.B) {
-	os.Setenv("LOG_LEVEL", "debug")
-	defer os.Unsetenv("LOG_LEVEL")
+func TestReloadConfig_InvalidKeepsCurrent(t *testing.T) {
+	cfg, err := config.LoadGateway()
+	require.NoError(t, err)
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		getLogLevel()
-	}
-}
+	var buf bytes.Buffer
+	level := new(slog.LevelVar)
+	log := logger.NewAdapter(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: level})))
 
-// Integration test
-func TestIntegration(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping integration test in short mode")
-	}
+	t.Setenv("LOG_LEVEL", "verbose")
+	reloadConfig(cfg, level, log)
 
-	t.Run("full environment setup", func(t *testing.T) {
-		// Set multiple environment variables
-		envVars := map[string]string{
-			"PORT":                 "8888",
-			"ANALYZER_SERVICE_URL": "http://test-analyzer:8081",
-			"LOG_LEVEL":            "debug",
-			"LOG_TO_FILE":          "false",
-			"APP_VERSION":          "test-v1.0.0",
-		}
-
-		// Set all environment variables
-		for key, value := range envVars {
-			os.Setenv(key, value)
-		}
+	assert.Equal(t, slog.LevelInfo, level.Level())
+	assert.Equal(t, "info", cfg.LogLevel)
+	assert.Contains(t, buf.String(), "Configuration reload failed")
+}
 
-		// Test all values
-		assert.Equal(t, "8888", getEnv("PORT", defaultPort))
-		assert.Equal(t, "http://test-analyzer:8081", getEnv("ANALYZER_SERVICE_URL", "http://localhost:8081"))
-		assert.Equal(t, slog.LevelDebug, getLogLevel())
-		assert.Equal(t, "false", getEnv("LOG_TO_FILE", "true"))
-		assert.Equal(t, "test-v1.0.0", getEnv("APP_VERSION", "dev"))
+func TestWriteTimeout_OutlastsRouteTimeouts(t *testing.T) {
+	cfg := config.DefaultGateway()
+	cfg.RouteTimeoutBatch = 10 * time.Minute
 
-		// Clean up
-		for key := range envVars {
-			os.Unsetenv(key)
-		}
-	})
+	assert.Greater(t, writeTimeout(cfg), cfg.RouteTimeoutBatch)
 }
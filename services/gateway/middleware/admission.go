@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/httperror"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	errQueueFull    = errors.New("admission queue is full")
+	errQueueTimeout = errors.New("timed out waiting in the admission queue")
+)
+
+// Limiter is admission control for expensive routes: at most maxInFlight
+// requests run at once, up to maxQueued more wait for a slot for at most
+// queueTimeout, and everything beyond that is turned away with 503 so load
+// never piles onto the analyzer.
+type Limiter struct {
+	slots        chan struct{}
+	maxQueued    int
+	queueTimeout time.Duration
+
+	queued   atomic.Int64
+	rejected atomic.Int64
+
+	rejectedTotal *prometheus.CounterVec
+	collectors    []prometheus.Collector
+}
+
+func NewLimiter(serviceName string, maxInFlight, maxQueued int, queueTimeout time.Duration) *Limiter {
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+	if maxQueued < 0 {
+		maxQueued = 0
+	}
+
+	l := &Limiter{
+		slots:        make(chan struct{}, maxInFlight),
+		maxQueued:    maxQueued,
+		queueTimeout: queueTimeout,
+	}
+
+	labels := prometheus.Labels{"service": serviceName}
+	l.rejectedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "admission_rejected_total",
+			Help:        "Requests turned away by admission control",
+			ConstLabels: labels,
+		},
+		[]string{"reason"},
+	)
+	l.collectors = []prometheus.Collector{
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name:        "admission_in_flight",
+				Help:        "Requests currently admitted and running",
+				ConstLabels: labels,
+			},
+			func() float64 { return float64(len(l.slots)) },
+		),
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name:        "admission_queued",
+				Help:        "Requests waiting for an admission slot",
+				ConstLabels: labels,
+			},
+			func() float64 { return float64(l.queued.Load()) },
+		),
+		l.rejectedTotal,
+	}
+	return l
+}
+
+// Collectors returns the limiter's Prometheus collectors for registration
+func (l *Limiter) Collectors() []prometheus.Collector {
+	return l.collectors
+}
+
+// Stats returns the current limiter state
+func (l *Limiter) Stats() models.AdmissionStats {
+	return models.AdmissionStats{
+		InFlight:    len(l.slots),
+		Queued:      int(l.queued.Load()),
+		Rejected:    l.rejected.Load(),
+		MaxInFlight: cap(l.slots),
+		MaxQueued:   l.maxQueued,
+	}
+}
+
+// Limit admits requests to next under the limiter
+func (l *Limiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := l.acquire(r); err != nil {
+			if r.Context().Err() != nil {
+				// The caller is gone, so there is nobody to answer
+				return
+			}
+			l.reject(w, r, err)
+			return
+		}
+		defer l.release()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *Limiter) acquire(r *http.Request) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if l.queued.Add(1) > int64(l.maxQueued) {
+		l.queued.Add(-1)
+		return errQueueFull
+	}
+	defer l.queued.Add(-1)
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return errQueueTimeout
+	case <-r.Context().Done():
+		return r.Context().Err()
+	}
+}
+
+func (l *Limiter) release() {
+	<-l.slots
+}
+
+func (l *Limiter) reject(w http.ResponseWriter, r *http.Request, err error) {
+	reason := "queue_full"
+	if errors.Is(err, errQueueTimeout) {
+		reason = "queue_timeout"
+	}
+	l.rejected.Add(1)
+	l.rejectedTotal.WithLabelValues(reason).Inc()
+
+	// A slot frees up within roughly one queue wait, so suggest that
+	response := httperror.New("Server is busy, retry later", http.StatusServiceUnavailable)
+	response.Details = err.Error()
+	response.RetryAfterSeconds = max(1, int(math.Ceil(l.queueTimeout.Seconds())))
+	httperror.Write(w, r, response)
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowAnalyzer stands in for the analyze handler: it holds every request
+// until release is closed and records the peak concurrency it saw
+type slowAnalyzer struct {
+	release chan struct{}
+	active  atomic.Int32
+	peak    atomic.Int32
+}
+
+func (s *slowAnalyzer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := s.active.Add(1)
+	defer s.active.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	<-s.release
+	w.WriteHeader(http.StatusOK)
+}
+
+func waitForStats(t *testing.T, limiter *Limiter, inFlight, queued int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		stats := limiter.Stats()
+		return stats.InFlight == inFlight && stats.Queued == queued
+	}, 2*time.Second, time.Millisecond, "limiter never reached %d in flight, %d queued: %+v", inFlight, queued, limiter.Stats())
+}
+
+func TestLimiter_CapAndQueueBoundary(t *testing.T) {
+	const maxInFlight, maxQueued, overflow = 3, 2, 5
+
+	analyzer := &slowAnalyzer{release: make(chan struct{})}
+	limiter := NewLimiter("test", maxInFlight, maxQueued, 10*time.Second)
+	server := httptest.NewServer(limiter.Limit(analyzer))
+	defer server.Close()
+
+	var wg sync.WaitGroup
+	admitted := make(chan int, maxInFlight+maxQueued)
+	send := func() {
+		defer wg.Done()
+		resp, err := http.Post(server.URL, "application/json", nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		resp.Body.Close()
+		admitted <- resp.StatusCode
+	}
+
+	// Fill every slot, then the queue
+	for range maxInFlight + maxQueued {
+		wg.Add(1)
+		go send()
+	}
+	waitForStats(t, limiter, maxInFlight, maxQueued)
+
+	// Everything past the boundary is turned away straight away
+	for range overflow {
+		resp, err := http.Post(server.URL, "application/json", nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, "10", resp.Header.Get("Retry-After"))
+		var errorResp models.ErrorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errorResp))
+		resp.Body.Close()
+		assert.Equal(t, "Server is busy, retry later", errorResp.Error)
+		assert.Equal(t, http.StatusServiceUnavailable, errorResp.StatusCode)
+	}
+
+	close(analyzer.release)
+	wg.Wait()
+	close(admitted)
+
+	for status := range admitted {
+		assert.Equal(t, http.StatusOK, status)
+	}
+	assert.Equal(t, int32(maxInFlight), analyzer.peak.Load())
+	assert.Equal(t, models.AdmissionStats{
+		Rejected:    overflow,
+		MaxInFlight: maxInFlight,
+		MaxQueued:   maxQueued,
+	}, limiter.Stats())
+	assert.Equal(t, float64(overflow), testutil.ToFloat64(limiter.rejectedTotal.WithLabelValues("queue_full")))
+}
+
+func TestLimiter_QueueTimeout(t *testing.T) {
+	analyzer := &slowAnalyzer{release: make(chan struct{})}
+	limiter := NewLimiter("test", 1, 1, 50*time.Millisecond)
+	handler := limiter.Limit(analyzer)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v2/analyze", nil))
+	}()
+	waitForStats(t, limiter, 1, 0)
+
+	w := httptest.NewRecorder()
+	start := time.Now()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/v2/analyze", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, float64(1), testutil.ToFloat64(limiter.rejectedTotal.WithLabelValues("queue_timeout")))
+
+	close(analyzer.release)
+	<-done
+}
+
+func TestLimiter_QueuedRequestRunsWhenSlotFrees(t *testing.T) {
+	analyzer := &slowAnalyzer{release: make(chan struct{})}
+	limiter := NewLimiter("test", 1, 1, 5*time.Second)
+	handler := limiter.Limit(analyzer)
+
+	codes := make(chan int, 2)
+	for range 2 {
+		go func() {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/v2/analyze", nil))
+			codes <- w.Code
+		}()
+	}
+	waitForStats(t, limiter, 1, 1)
+
+	close(analyzer.release)
+	assert.Equal(t, http.StatusOK, <-codes)
+	assert.Equal(t, http.StatusOK, <-codes)
+	assert.Equal(t, int32(1), analyzer.peak.Load())
+	assert.Zero(t, limiter.Stats().Rejected)
+}
+
+func TestLimiter_Collectors(t *testing.T) {
+	limiter := NewLimiter("test", 2, 2, time.Second)
+	limiter.rejectedTotal.WithLabelValues("queue_full").Inc()
+
+	registry := prometheus.NewRegistry()
+	for _, c := range limiter.Collectors() {
+		require.NoError(t, registry.Register(c))
+	}
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	var names []string
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	assert.ElementsMatch(t, []string{"admission_in_flight", "admission_queued", "admission_rejected_total"}, names)
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/contextkeys"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaller(t *testing.T) {
+	var caller string
+	handler := Caller(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller = contextkeys.CallerFrom(r.Context())
+	}))
+
+	req := httptest.NewRequest("POST", "/api/v2/analyze", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, AnonymousCaller, caller)
+
+	// The key is hashed, the same key always to the same caller
+	req.Header.Set(APIKeyHeader, "secret-key-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	first := caller
+	assert.Regexp(t, `^key-[0-9a-f]{12}$`, first)
+	assert.NotContains(t, first, "secret")
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, first, caller)
+
+	req.Header.Set(APIKeyHeader, "secret-key-2")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.NotEqual(t, first, caller)
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/version"
+	"github.com/gorilla/mux"
+)
+
+// AppVersion advertises the running build on every response
+func AppVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-App-Version", version.Get().Version)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Deprecation marks every response as deprecated (RFC 8594) and points
+// clients at the successor API
+func Deprecation(sunset time.Time, successor string) mux.MiddlewareFunc {
+	sunsetHeader := sunset.UTC().Format(http.TimeFormat)
+	linkHeader := fmt.Sprintf("<%s>; rel=\"successor-version\"", successor)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Sunset", sunsetHeader)
+			w.Header().Add("Link", linkHeader)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+}
+
+func TestAppVersion_SetsHeader(t *testing.T) {
+	handler := okHandler()
+	middleware := AppVersion(handler)
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	w := httptest.NewRecorder()
+
+	middleware.ServeHTTP(w, req)
+
+	assert.Equal(t, "dev", w.Header().Get("X-App-Version"))
+	assert.Equal(t, "OK", w.Body.String())
+}
+
+func TestDeprecation_SetsHeaders(t *testing.T) {
+	handler := okHandler()
+	sunset := time.Date(2027, 6, 30, 0, 0, 0, 0, time.UTC)
+	middleware := Deprecation(sunset, "/api/v2")(handler)
+
+	req := httptest.NewRequest("POST", "/api/v1/analyze", nil)
+	w := httptest.NewRecorder()
+
+	middleware.ServeHTTP(w, req)
+
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+	assert.Equal(t, "Wed, 30 Jun 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v2>; rel="successor-version"`, w.Header().Get("Link"))
+	assert.Equal(t, "OK", w.Body.String())
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// APIContentSecurityPolicy is sent with JSON and metrics responses, which
+// load nothing and are never framed
+const APIContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+
+// SecurityHeadersOptions configures the security headers of the gateway's
+// responses. Paths are matched exactly, or as a prefix when they end in /.
+type SecurityHeadersOptions struct {
+	// ContentSecurityPolicy, FrameOptions and ReferrerPolicy are sent with
+	// the UI pages; an empty value leaves the header out
+	ContentSecurityPolicy string
+	FrameOptions          string
+	ReferrerPolicy        string
+	// HSTSMaxAge sends Strict-Transport-Security with every response when
+	// positive. Only set it when clients reach the gateway over HTTPS.
+	HSTSMaxAge time.Duration
+	// APIPaths get only nosniff, a locked down policy and HSTS
+	APIPaths []string
+	// ExcludedPaths get no security headers at all
+	ExcludedPaths []string
+}
+
+// SecurityHeaders sets the security headers of each response by the class of
+// its route: UI pages, API and metrics routes, or excluded routes
+func SecurityHeaders(opts SecurityHeadersOptions) mux.MiddlewareFunc {
+	var hsts string
+	if opts.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(opts.HSTSMaxAge/time.Second), 10)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := r.URL.Path
+			if matchPath(opts.ExcludedPaths, path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set("X-Content-Type-Options", "nosniff")
+			if hsts != "" {
+				header.Set("Strict-Transport-Security", hsts)
+			}
+
+			if matchPath(opts.APIPaths, path) {
+				header.Set("Content-Security-Policy", APIContentSecurityPolicy)
+			} else {
+				setIfNotEmpty(header, "Content-Security-Policy", opts.ContentSecurityPolicy)
+				setIfNotEmpty(header, "X-Frame-Options", opts.FrameOptions)
+				setIfNotEmpty(header, "Referrer-Policy", opts.ReferrerPolicy)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// matchPath reports whether path is one of paths, or under one ending in /
+func matchPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+func setIfNotEmpty(header http.Header, key, value string) {
+	if value != "" {
+		header.Set(key, value)
+	}
+}
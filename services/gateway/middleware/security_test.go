@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	const csp = "default-src 'self'"
+	opts := SecurityHeadersOptions{
+		ContentSecurityPolicy: csp,
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "no-referrer",
+		APIPaths:              []string{"/api/", "/metrics"},
+		ExcludedPaths:         []string{"/debug/pprof/"},
+	}
+	uiHeaders := map[string]string{
+		"Content-Security-Policy": csp,
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         "DENY",
+		"Referrer-Policy":         "no-referrer",
+	}
+	apiHeaders := map[string]string{
+		"Content-Security-Policy": APIContentSecurityPolicy,
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         "",
+		"Referrer-Policy":         "",
+	}
+	noHeaders := map[string]string{
+		"Content-Security-Policy": "",
+		"X-Content-Type-Options":  "",
+		"X-Frame-Options":         "",
+		"Referrer-Policy":         "",
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want map[string]string
+	}{
+		{"UI page", "/", uiHeaders},
+		{"static asset", "/static/js/main.js", uiHeaders},
+		{"API route", "/api/v2/analyze", apiHeaders},
+		{"metrics", "/metrics", apiHeaders},
+		{"path merely starting like an exact API path", "/metrics-old", uiHeaders},
+		{"excluded debug route", "/debug/pprof/heap", noHeaders},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			SecurityHeaders(opts)(okHandler()).ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			assert.Equal(t, "OK", w.Body.String())
+			for header, want := range tt.want {
+				assert.Equal(t, want, w.Header().Get(header), header)
+			}
+			assert.Empty(t, w.Header().Get("Strict-Transport-Security"), "HSTS is off unless configured")
+		})
+	}
+}
+
+func TestSecurityHeaders_HSTS(t *testing.T) {
+	opts := SecurityHeadersOptions{
+		HSTSMaxAge:    365 * 24 * time.Hour,
+		APIPaths:      []string{"/api/"},
+		ExcludedPaths: []string{"/debug/pprof/"},
+	}
+
+	tests := map[string]string{
+		"/":                 "max-age=31536000",
+		"/api/v2/analyze":   "max-age=31536000",
+		"/debug/pprof/heap": "",
+	}
+	for path, want := range tests {
+		w := httptest.NewRecorder()
+		SecurityHeaders(opts)(okHandler()).ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, want, w.Header().Get("Strict-Transport-Security"), path)
+	}
+}
+
+func TestSecurityHeaders_EmptyValuesLeaveHeadersOut(t *testing.T) {
+	w := httptest.NewRecorder()
+	SecurityHeaders(SecurityHeadersOptions{})(okHandler()).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	for _, header := range []string{"Content-Security-Policy", "X-Frame-Options", "Referrer-Policy"} {
+		_, ok := w.Header()[http.CanonicalHeaderKey(header)]
+		assert.False(t, ok, header)
+	}
+}
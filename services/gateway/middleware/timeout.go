@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/httperror"
+)
+
+// Timeout bounds the handlers it wraps by timeout. The request context is
+// cancelled when the time is up, which abandons the calls made with it such
+// as the one to the analyzer, and a client that has not been answered yet
+// gets a 504 right away; whatever the handler writes after that is dropped.
+// A timeout of zero or less disables it.
+//
+// Streaming routes must not be wrapped, as the deadline would cut a stream
+// off; requests that accept text/event-stream are passed through untouched
+// as well.
+func Timeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if timeout <= 0 || acceptsEventStream(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{w: w, req: r, header: w.Header().Clone(), ctx: ctx, timeout: timeout}
+			fired := make(chan struct{})
+			stop := context.AfterFunc(ctx, func() {
+				defer close(fired)
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.expired()
+			})
+
+			next.ServeHTTP(tw, r.WithContext(ctx))
+
+			if !stop() {
+				// The deadline passed as the handler returned; the 504
+				// has to be out before the writer goes away
+				<-fired
+			}
+		})
+	}
+}
+
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// timeoutWriter lets the handler and the deadline race for the response
+// without both writing it. The handler gets its own header map, copied out
+// when it writes the header, so the 504 never shares one with it.
+type timeoutWriter struct {
+	w       http.ResponseWriter
+	req     *http.Request
+	header  http.Header
+	ctx     context.Context
+	timeout time.Duration
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeader(code)
+}
+
+func (tw *timeoutWriter) writeHeader(code int) {
+	if tw.expired() || tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	dst := tw.w.Header()
+	for key, values := range tw.header {
+		dst[key] = values
+	}
+	tw.w.WriteHeader(code)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired() {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeader(http.StatusOK)
+	return tw.w.Write(b)
+}
+
+// Flush sends what the handler has written so far, if the underlying writer
+// can. The response has then started and a later deadline cannot replace it.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired() {
+		return
+	}
+	if flusher, ok := tw.w.(http.Flusher); ok {
+		tw.writeHeader(http.StatusOK)
+		flusher.Flush()
+	}
+}
+
+// expired reports whether the client has been answered with 504, sending
+// it first if the deadline has passed before the handler started its
+// response. The handler can see the context end before the deadline callback
+// runs, so its writes check as well. It must be called with mu held.
+func (tw *timeoutWriter) expired() bool {
+	if tw.timedOut || tw.wroteHeader || tw.ctx.Err() != context.DeadlineExceeded {
+		return tw.timedOut
+	}
+	tw.timedOut = true
+
+	response := httperror.New("Request timed out", http.StatusGatewayTimeout)
+	response.Details = "no response within " + tw.timeout.String()
+	httperror.Write(tw.w, tw.req, response)
+	return true
+}
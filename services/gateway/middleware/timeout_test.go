@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout_AnswersSlowHandlerWith504(t *testing.T) {
+	cancelled := make(chan error, 1)
+	handler := Timeout(50 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		cancelled <- r.Context().Err()
+		// Too late; the client has had its 504
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte("late"))
+		assert.ErrorIs(t, err, http.ErrHandlerTimeout)
+	}))
+
+	req := httptest.NewRequest("POST", "/api/v2/analyze", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.ErrorIs(t, <-cancelled, context.DeadlineExceeded)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var body models.ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, "Request timed out", body.Error)
+	assert.Equal(t, http.StatusGatewayTimeout, body.StatusCode)
+	assert.Equal(t, "no response within 50ms", body.Details)
+}
+
+func TestTimeout_FastHandlerIsUntouched(t *testing.T) {
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline := r.Context().Deadline()
+		assert.True(t, hasDeadline)
+		w.Header().Set("X-Test", "yes")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("OK"))
+	}))
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+	w.Header().Set("X-Request-ID", "req-1")
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "OK", w.Body.String())
+	assert.Equal(t, "yes", w.Header().Get("X-Test"))
+	assert.Equal(t, "req-1", w.Header().Get("X-Request-ID"))
+}
+
+func TestTimeout_StartedResponseIsNotReplaced(t *testing.T) {
+	handler := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("partial"))
+		<-r.Context().Done()
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "partial", w.Body.String())
+}
+
+func TestTimeout_SkipsEventStreams(t *testing.T) {
+	handler := Timeout(time.Nanosecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline := r.Context().Deadline()
+		assert.False(t, hasDeadline)
+		w.Write([]byte("data: ok\n\n"))
+	}))
+
+	req := httptest.NewRequest("GET", "/events", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "data: ok\n\n", w.Body.String())
+}
+
+func TestTimeout_ZeroDisables(t *testing.T) {
+	handler := Timeout(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline := r.Context().Deadline()
+		assert.False(t, hasDeadline)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestTimeout_ClientGoneIsNotAnswered(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		<-r.Context().Done()
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.False(t, w.Flushed)
+	assert.Empty(t, w.Body.String())
+}
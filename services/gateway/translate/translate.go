@@ -0,0 +1,426 @@
+package translate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/findings"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/storage"
+)
+
+// Batch item statuses used by the v2 batch response
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// v1 batch errors carry the failing URL only inside Details
+const v1FailedDetailsPrefix = "Failed to analyze: "
+
+// unknownDoctype is what the analyzer reports when no DOCTYPE is present
+const unknownDoctype = "Unknown/No DOCTYPE"
+
+// Batch is the version-neutral outcome of a batch analysis, in request order
+type Batch struct {
+	Items     []BatchItem
+	TotalTime time.Duration
+	// Findings is nil when the pages have nothing in common worth reporting
+	Findings *models.CrossPageFindings
+}
+
+// BatchItem is the outcome for one URL; exactly one of Result and Error is set
+type BatchItem struct {
+	URL    string
+	Result *models.AnalysisResult
+	Error  *models.ErrorResponse
+}
+
+// AnalysisResultV2 is the v2 single analysis response
+type AnalysisResultV2 struct {
+	URL             string                   `json:"url"`
+	HTMLVersion     string                   `json:"html_version"`
+	Title           string                   `json:"title"`
+	Headings        models.HeadingCount      `json:"headings"`
+	Links           models.LinkSummary       `json:"links"`
+	HasLoginForm    bool                     `json:"has_login_form"`
+	AnalyzedAt      time.Time                `json:"analyzed_at,omitzero"`
+	Screenshot      string                   `json:"screenshot,omitempty"`
+	Timings         *models.Timings          `json:"timings,omitempty"`
+	Budget          *models.BudgetUsage      `json:"budget,omitempty"`
+	Traffic         *models.Traffic          `json:"traffic,omitempty"`
+	FinalURL        string                   `json:"final_url,omitempty"`
+	DisplayURL      string                   `json:"display_url,omitempty"`
+	IDNHosts        []models.IDNHost         `json:"idn_hosts,omitempty"`
+	RequiresAuth    *models.AuthRequirement  `json:"requires_auth,omitempty"`
+	StatusCode      int                      `json:"status_code,omitempty"`
+	ResponseHeaders map[string][]string      `json:"response_headers,omitempty"`
+	AcceptLanguage  string                   `json:"accept_language,omitempty"`
+	CanonicalURL    string                   `json:"canonical_url,omitempty"`
+	HasFrames       bool                     `json:"has_frames,omitempty"`
+	Frames          []models.Frame           `json:"frames,omitempty"`
+	Titles          *models.TitleReport      `json:"titles,omitempty"`
+	Hreflang        *models.HreflangReport   `json:"hreflang,omitempty"`
+	AMP             *models.AMPReport        `json:"amp,omitempty"`
+	Pagination      *models.PaginationReport `json:"pagination,omitempty"`
+	Anchors         *models.AnchorReport     `json:"anchors,omitempty"`
+	RedirectedLinks []models.RedirectedLink  `json:"redirected_links,omitempty"`
+	// RedirectedLinksPage is set when a request pages the redirected links,
+	// which then hold only that page
+	RedirectedLinksPage *Page                       `json:"redirected_links_page,omitempty"`
+	LinkFindings        *models.LinkFindings        `json:"link_findings,omitempty"`
+	Accessibility       *models.AccessibilityReport `json:"accessibility,omitempty"`
+	Content             *models.ContentReport       `json:"content,omitempty"`
+	Robots              *models.RobotsReport        `json:"robots,omitempty"`
+	Cacheability        *models.Cacheability        `json:"cacheability,omitempty"`
+	Technology          *models.TechnologyHints     `json:"technology,omitempty"`
+	DebugTrace          *models.DebugTrace          `json:"debug_trace,omitempty"`
+	Warnings            []string                    `json:"warnings,omitempty"`
+	// AnalysisWarnings are the analyzer's own warnings, passed on as they
+	// are; "warnings" already holds the ones derived here
+	AnalysisWarnings []models.Warning `json:"analysis_warnings,omitempty"`
+	Findings         []models.Finding `json:"findings,omitempty"`
+	// FindingSummary counts every finding, including those min_severity
+	// leaves out
+	FindingSummary   *models.FindingSummary   `json:"finding_summary,omitempty"`
+	Rules            []string                 `json:"rules,omitempty"`
+	URLNormalization *models.URLNormalization `json:"url_normalization,omitempty"`
+	Checks           []models.CheckResult     `json:"checks,omitempty"`
+	ChecksFailed     bool                     `json:"checks_failed,omitempty"`
+	ResultHash       string                   `json:"result_hash,omitempty"`
+}
+
+// KeepFindings drops the findings less severe than minSeverity, keeping
+// them all when it is empty
+func (r *AnalysisResultV2) KeepFindings(minSeverity string) {
+	r.Findings = findings.AtLeast(r.Findings, minSeverity)
+}
+
+// PageRedirectedLinks keeps the redirected links from offset on, up to
+// limit of them when it is above zero, and records the page. A result
+// without the redirected links is left as it is.
+func (r *AnalysisResultV2) PageRedirectedLinks(offset, limit int) {
+	if r.RedirectedLinks == nil {
+		return
+	}
+	total := len(r.RedirectedLinks)
+	start, end := min(offset, total), total
+	if limit > 0 {
+		end = min(start+limit, total)
+	}
+	r.RedirectedLinks = r.RedirectedLinks[start:end]
+	r.RedirectedLinksPage = &Page{Offset: offset, Limit: limit, Total: total}
+}
+
+// Page is the part of a list a response holds: the items from Offset on,
+// up to Limit of them when it is set, out of Total
+type Page struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit,omitempty"`
+	Total  int `json:"total"`
+}
+
+// StoredResultV2 is a saved analysis result as served under /api/v2/results
+type StoredResultV2 struct {
+	ID      string           `json:"id"`
+	SavedAt time.Time        `json:"saved_at"`
+	Result  AnalysisResultV2 `json:"result"`
+}
+
+// ResultListV2 is the list of saved results served by /api/v2/results
+type ResultListV2 struct {
+	Results []StoredResultV2 `json:"results"`
+}
+
+// BatchItemV2 keeps each URL next to its own outcome
+type BatchItemV2 struct {
+	URL    string                `json:"url"`
+	Status string                `json:"status"`
+	Result *AnalysisResultV2     `json:"result,omitempty"`
+	Error  *models.ErrorResponse `json:"error,omitempty"`
+}
+
+// BatchResultV2 is the v2 batch response
+type BatchResultV2 struct {
+	Items             []BatchItemV2             `json:"items"`
+	Succeeded         int                       `json:"succeeded"`
+	Failed            int                       `json:"failed"`
+	TotalTimeMs       int64                     `json:"total_time_ms"`
+	CrossPageFindings *models.CrossPageFindings `json:"cross_page_findings,omitempty"`
+}
+
+// ToV1 converts an internal result into the legacy v1 shape
+func ToV1(result *models.AnalysisResult) models.AnalysisResult {
+	return *result
+}
+
+// FromV1 converts a legacy v1 result back into the internal model
+func FromV1(v1 models.AnalysisResult) *models.AnalysisResult {
+	return &v1
+}
+
+// StoredToV2 converts a saved result into the v2 shape
+func StoredToV2(record storage.ResultRecord) StoredResultV2 {
+	return StoredResultV2{ID: record.ID, SavedAt: record.SavedAt, Result: ToV2(record.Result)}
+}
+
+// ToV2 converts an internal result into the v2 shape, deriving warnings
+func ToV2(result *models.AnalysisResult) AnalysisResultV2 {
+	return AnalysisResultV2{
+		URL:              result.URL,
+		HTMLVersion:      result.HTMLVersion,
+		Title:            result.Title,
+		Headings:         result.Headings,
+		Links:            result.Links,
+		HasLoginForm:     result.HasLoginForm,
+		AnalyzedAt:       result.AnalyzedAt,
+		Screenshot:       result.Screenshot,
+		Timings:          result.Timings,
+		Budget:           result.Budget,
+		Traffic:          result.Traffic,
+		FinalURL:         result.FinalURL,
+		DisplayURL:       result.DisplayURL,
+		IDNHosts:         result.IDNHosts,
+		RequiresAuth:     result.RequiresAuth,
+		StatusCode:       result.StatusCode,
+		ResponseHeaders:  result.ResponseHeaders,
+		AcceptLanguage:   result.AcceptLanguage,
+		CanonicalURL:     result.CanonicalURL,
+		HasFrames:        result.HasFrames,
+		Frames:           result.Frames,
+		Titles:           result.Titles,
+		Hreflang:         result.Hreflang,
+		AMP:              result.AMP,
+		Pagination:       result.Pagination,
+		Anchors:          result.Anchors,
+		RedirectedLinks:  result.RedirectedLinks,
+		LinkFindings:     result.LinkFindings,
+		Accessibility:    result.Accessibility,
+		Content:          result.Content,
+		Robots:           result.Robots,
+		Cacheability:     result.Cacheability,
+		Technology:       result.Technology,
+		DebugTrace:       result.DebugTrace,
+		Warnings:         warnings(result),
+		AnalysisWarnings: result.Warnings,
+		Findings:         result.Findings,
+		FindingSummary:   result.FindingSummary,
+		Rules:            result.Rules,
+		URLNormalization: result.URLNormalization,
+		Checks:           result.Checks,
+		ChecksFailed:     result.ChecksFailed,
+		ResultHash:       result.ResultHash,
+	}
+}
+
+// FromV2 converts a v2 result back into the internal model. The derived
+// warnings are not carried over, the analyzer's are.
+func FromV2(v2 AnalysisResultV2) *models.AnalysisResult {
+	return &models.AnalysisResult{
+		URL:              v2.URL,
+		HTMLVersion:      v2.HTMLVersion,
+		Title:            v2.Title,
+		Headings:         v2.Headings,
+		Links:            v2.Links,
+		HasLoginForm:     v2.HasLoginForm,
+		AnalyzedAt:       v2.AnalyzedAt,
+		Screenshot:       v2.Screenshot,
+		Timings:          v2.Timings,
+		Budget:           v2.Budget,
+		Traffic:          v2.Traffic,
+		FinalURL:         v2.FinalURL,
+		DisplayURL:       v2.DisplayURL,
+		IDNHosts:         v2.IDNHosts,
+		RequiresAuth:     v2.RequiresAuth,
+		StatusCode:       v2.StatusCode,
+		ResponseHeaders:  v2.ResponseHeaders,
+		AcceptLanguage:   v2.AcceptLanguage,
+		CanonicalURL:     v2.CanonicalURL,
+		HasFrames:        v2.HasFrames,
+		Frames:           v2.Frames,
+		Titles:           v2.Titles,
+		Hreflang:         v2.Hreflang,
+		AMP:              v2.AMP,
+		Pagination:       v2.Pagination,
+		Anchors:          v2.Anchors,
+		RedirectedLinks:  v2.RedirectedLinks,
+		LinkFindings:     v2.LinkFindings,
+		Accessibility:    v2.Accessibility,
+		Content:          v2.Content,
+		Robots:           v2.Robots,
+		Cacheability:     v2.Cacheability,
+		Technology:       v2.Technology,
+		DebugTrace:       v2.DebugTrace,
+		Warnings:         v2.AnalysisWarnings,
+		Findings:         v2.Findings,
+		FindingSummary:   v2.FindingSummary,
+		Rules:            v2.Rules,
+		URLNormalization: v2.URLNormalization,
+		Checks:           v2.Checks,
+		ChecksFailed:     v2.ChecksFailed,
+		ResultHash:       v2.ResultHash,
+	}
+}
+
+// BatchToV1 splits a batch into the legacy results and errors lists
+func BatchToV1(batch Batch) models.BatchAnalysisResult {
+	response := models.BatchAnalysisResult{
+		Results:           make([]models.AnalysisResult, 0, len(batch.Items)),
+		TotalTime:         batch.TotalTime,
+		CrossPageFindings: batch.Findings,
+	}
+
+	for _, item := range batch.Items {
+		if item.Error != nil {
+			failure := *item.Error
+			failure.Details = v1FailedDetailsPrefix + item.URL
+			response.Errors = append(response.Errors, failure)
+			continue
+		}
+		response.Results = append(response.Results, ToV1(item.Result))
+	}
+
+	return response
+}
+
+// BatchFromV1 rebuilds a batch from the legacy shape. v1 does not preserve
+// the interleaving of successes and failures, so successes come first.
+func BatchFromV1(v1 models.BatchAnalysisResult) Batch {
+	batch := Batch{
+		Items:     make([]BatchItem, 0, len(v1.Results)+len(v1.Errors)),
+		TotalTime: v1.TotalTime,
+		Findings:  v1.CrossPageFindings,
+	}
+
+	for _, result := range v1.Results {
+		batch.Items = append(batch.Items, BatchItem{URL: result.URL, Result: FromV1(result)})
+	}
+	for _, failure := range v1.Errors {
+		failure := failure
+		url := strings.TrimPrefix(failure.Details, v1FailedDetailsPrefix)
+		failure.Details = ""
+		batch.Items = append(batch.Items, BatchItem{URL: url, Error: &failure})
+	}
+
+	return batch
+}
+
+// BatchToV2 converts a batch into the v2 shape, keeping request order
+func BatchToV2(batch Batch) BatchResultV2 {
+	response := BatchResultV2{
+		Items:             make([]BatchItemV2, 0, len(batch.Items)),
+		TotalTimeMs:       batch.TotalTime.Milliseconds(),
+		CrossPageFindings: batch.Findings,
+	}
+
+	for _, item := range batch.Items {
+		entry := BatchItemV2{URL: item.URL}
+		if item.Error != nil {
+			entry.Status = StatusFailed
+			entry.Error = item.Error
+			response.Failed++
+		} else {
+			v2 := ToV2(item.Result)
+			entry.Status = StatusSucceeded
+			entry.Result = &v2
+			response.Succeeded++
+		}
+		response.Items = append(response.Items, entry)
+	}
+
+	return response
+}
+
+// BatchFromV2 rebuilds a batch from the v2 shape
+func BatchFromV2(v2 BatchResultV2) Batch {
+	batch := Batch{
+		Items:     make([]BatchItem, 0, len(v2.Items)),
+		TotalTime: time.Duration(v2.TotalTimeMs) * time.Millisecond,
+		Findings:  v2.CrossPageFindings,
+	}
+
+	for _, entry := range v2.Items {
+		item := BatchItem{URL: entry.URL, Error: entry.Error}
+		if entry.Result != nil {
+			item.Result = FromV2(*entry.Result)
+		}
+		batch.Items = append(batch.Items, item)
+	}
+
+	return batch
+}
+
+// warnings flags results that are technically successful but likely need attention
+func warnings(result *models.AnalysisResult) []string {
+	// A page behind authentication has no content to warn about
+	if result.RequiresAuth != nil {
+		return []string{"page requires authentication, its content was not analyzed"}
+	}
+	var found []string
+	if result.Title == "" {
+		found = append(found, "page has no title")
+	}
+	if result.HTMLVersion == "" || result.HTMLVersion == unknownDoctype {
+		found = append(found, "page has no DOCTYPE declaration")
+	}
+	if result.Links.Inaccessible > 0 {
+		found = append(found, fmt.Sprintf("%d of %d links are inaccessible", result.Links.Inaccessible, result.Links.Total))
+	}
+	if unfollowed := unfollowedFrames(result.Frames); unfollowed > 0 {
+		found = append(found, fmt.Sprintf("%d of %d frames were not analyzed, their content is not counted", unfollowed, len(result.Frames)))
+	}
+	if len(result.RedirectedLinks) > 0 {
+		found = append(found, fmt.Sprintf("%d internal links redirect, update them to their final URL", len(result.RedirectedLinks)))
+	}
+	if result.LinkFindings != nil {
+		for _, finding := range result.LinkFindings.Findings {
+			if finding.Kind == models.LinkMissingNoopener {
+				found = append(found, fmt.Sprintf("%d links open in a new tab without rel=noopener", finding.Count))
+			}
+		}
+	}
+	if result.Accessibility != nil {
+		for _, finding := range result.Accessibility.LinkText {
+			switch finding.Kind {
+			case models.LinkTextEmpty:
+				found = append(found, fmt.Sprintf("%d links have no text a screen reader can announce", finding.Count))
+			case models.LinkTextGeneric:
+				found = append(found, fmt.Sprintf("%d links have generic text such as \"click here\"", finding.Count))
+			case models.LinkTextAmbiguous:
+				found = append(found, fmt.Sprintf("%d links share their text with links to other pages", finding.Count))
+			}
+		}
+	}
+	if result.Hreflang != nil && len(result.Hreflang.Findings) > 0 {
+		found = append(found, fmt.Sprintf("%d hreflang problems found", len(result.Hreflang.Findings)))
+	}
+	if result.AMP != nil && len(result.AMP.Findings) > 0 {
+		found = append(found, fmt.Sprintf("%d AMP problems found", len(result.AMP.Findings)))
+	}
+	if result.Pagination != nil && len(result.Pagination.Findings) > 0 {
+		found = append(found, fmt.Sprintf("%d pagination problems found", len(result.Pagination.Findings)))
+	}
+	if result.Anchors != nil {
+		if n := len(result.Anchors.DuplicateIDs); n > 0 {
+			found = append(found, fmt.Sprintf("%d ids are shared by more than one element", n))
+		}
+		if n := len(result.Anchors.DanglingAnchors); n > 0 {
+			found = append(found, fmt.Sprintf("%d in-page links point at no element", n))
+		}
+	}
+	if result.Budget != nil && result.Budget.SkippedLinks > 0 {
+		found = append(found, fmt.Sprintf("%d of %d links were not checked, the outbound budget ran out", result.Budget.SkippedLinks, result.Links.Total))
+	}
+	return found
+}
+
+func unfollowedFrames(frames []models.Frame) int {
+	unfollowed := 0
+	for _, frame := range frames {
+		if !frame.Followed {
+			unfollowed++
+		}
+	}
+	return unfollowed
+}
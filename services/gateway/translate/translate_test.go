@@ -0,0 +1,303 @@
+package translate
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var analyzedAt = time.Date(2025, 3, 14, 15, 9, 26, 0, time.UTC)
+
+func testResults() map[string]*models.AnalysisResult {
+	return map[string]*models.AnalysisResult{
+		"complete": {
+			URL:          "https://example.com",
+			HTMLVersion:  "HTML5",
+			Title:        "Example Domain",
+			Headings:     models.HeadingCount{H1: 1, H2: 2, H3: 3, H4: 4, H5: 5, H6: 6},
+			Links:        models.LinkSummary{Internal: 3, External: 2, Inaccessible: 0, Total: 5, Unique: 4, RedirectedLinks: 1},
+			HasLoginForm: true,
+			AnalyzedAt:   analyzedAt,
+			Screenshot:   "/api/v2/artifacts/0123456789abcdef",
+			Timings: &models.Timings{
+				FetchMs:                412.5,
+				HTMLVersionDetectionMs: 0.02,
+				ParseMs:                3.1,
+				LinkCheckMs:            1830,
+				TotalMs:                2246.4,
+			},
+			Budget:     &models.BudgetUsage{Requests: 6, Bytes: 48213, MaxRequests: 1000, MaxBytes: 256 << 20},
+			Traffic:    &models.Traffic{Bytes: 61440},
+			FinalURL:   "https://example.com/",
+			DisplayURL: "https://example.com/",
+			IDNHosts:   []models.IDNHost{{Unicode: "bücher.example", ASCII: "xn--bcher-kva.example"}},
+			StatusCode: 200,
+			ResponseHeaders: map[string][]string{
+				"Content-Type": {"text/html"},
+				"Set-Cookie":   {"session", "theme"},
+			},
+			AcceptLanguage: "en-US,en;q=0.9",
+			CanonicalURL:   "https://example.com/",
+			HasFrames:      true,
+			Frames: []models.Frame{
+				{URL: "https://example.com/nav.html", Followed: true, Headings: &models.HeadingCount{H2: 1}, Links: 2},
+			},
+			Titles: &models.TitleReport{Count: 2, Length: 14, MinLength: 10, MaxLength: 60},
+			Hreflang: &models.HreflangReport{
+				Alternates: []models.HreflangLink{
+					{Lang: "en", URL: "https://example.com/"},
+					{Lang: "x-default", URL: "https://example.com/"},
+				},
+			},
+			AMP:          &models.AMPReport{AMPHTMLURL: "https://example.com/amp/", CanonicalURL: "https://example.com/"},
+			LinkFindings: &models.LinkFindings{NofollowExternal: 1, NewTab: 2},
+			Content: &models.ContentReport{
+				TextStats:        models.TextStats{Words: 412, Characters: 2380, Language: "en", Confidence: 0.82},
+				DeclaredLanguage: "en",
+			},
+			Robots: &models.RobotsReport{
+				Indexable:  false,
+				Followable: true,
+				Directives: []models.RobotsDirectives{
+					{Source: models.RobotsSourceMeta, NoIndex: true, Values: []string{"noindex"}},
+				},
+			},
+			Cacheability: &models.Cacheability{
+				Cacheable: true, SharedCacheable: true, MaxAgeSeconds: 60, FreshnessSource: "max-age",
+				Public: true, ETag: `"v1"`, Vary: []string{"Accept-Encoding"},
+			},
+			Technology: &models.TechnologyHints{
+				Protocol: "HTTP/2.0", Server: "nginx", Generator: "WordPress 6.4.2",
+				Products: []string{"nginx", "WordPress 6.x"},
+			},
+			DebugTrace: &models.DebugTrace{
+				Requests: []models.OutboundRequest{
+					{Method: "GET", URL: "https://example.com/", StatusCode: 200, DurationMs: 410.2, Bytes: 48213},
+					{Method: "HEAD", URL: "https://example.com/about", StatusCode: 200, DurationMs: 95.4, Source: "link-checker"},
+				},
+				Dropped: 3,
+			},
+			Warnings: []models.Warning{
+				{Code: models.WarningRedirected, Message: "The URL redirected", Context: map[string]string{"final_url": "https://example.com/"}},
+				{Code: models.WarningTruncatedBody, Message: "The page is too large"},
+			},
+		},
+		"with warnings": {
+			URL:         "https://example.com/legacy",
+			HTMLVersion: unknownDoctype,
+			Links:       models.LinkSummary{Internal: 1, External: 2, Inaccessible: 2, Total: 3, RedirectedLinks: 1},
+			AnalyzedAt:  analyzedAt,
+			Budget:      &models.BudgetUsage{Requests: 3, Bytes: 9000, MaxRequests: 3, MaxBytes: 1 << 20, Exhausted: true, SkippedLinks: 1},
+			HasFrames:   true,
+			Frames:      []models.Frame{{URL: "https://example.com/legacy/main.html"}},
+			RedirectedLinks: []models.RedirectedLink{
+				{URL: "https://example.com/old", FinalURL: "https://example.com/new", Redirects: 2},
+			},
+			Hreflang: &models.HreflangReport{
+				Alternates: []models.HreflangLink{{Lang: "english", URL: "https://example.com/legacy"}},
+				Findings: []models.HreflangFinding{
+					{Kind: models.HreflangInvalidCode, Lang: "english", URL: "https://example.com/legacy"},
+					{Kind: models.HreflangMissingXDefault},
+				},
+			},
+			Anchors: &models.AnchorReport{DanglingAnchors: []string{"pricing", "faq"}},
+			AMP: &models.AMPReport{
+				IsAMP:    true,
+				Findings: []models.AMPFinding{{Kind: models.AMPMissingCanonical}},
+			},
+			Pagination: &models.PaginationReport{
+				Position: models.PaginationFirst,
+				NextURL:  "https://example.com/legacy?page=2",
+				Findings: []models.PaginationFinding{
+					{Kind: models.PaginationUnreachableNext, URL: "https://example.com/legacy?page=2", Detail: "HTTP 404"},
+				},
+			},
+			LinkFindings: &models.LinkFindings{
+				NewTab: 2,
+				Findings: []models.LinkFinding{
+					{Kind: models.LinkMissingNoopener, Count: 2, URLs: []string{"https://example.com/legacy/help"}},
+				},
+			},
+			Accessibility: &models.AccessibilityReport{
+				LinkText: []models.LinkFinding{
+					{Kind: models.LinkTextGeneric, Count: 2, URLs: []string{"https://example.com/legacy/1"}, Texts: []string{"Click here"}},
+				},
+			},
+			Findings: []models.Finding{
+				{ID: "HEADINGS_MISSING_H1", Category: models.CategorySEO, Severity: models.SeverityWarning, Message: "The page has no h1 heading",
+					Evidence: models.FindingEvidence{Selectors: []string{"h1"}}},
+				{ID: "AMP_MISSING_CANONICAL", Category: models.CategorySEO, Severity: models.SeverityError, Message: "The AMP page has no canonical link"},
+			},
+			FindingSummary: &models.FindingSummary{
+				Total:      2,
+				ByCategory: map[string]int{models.CategorySEO: 2},
+				BySeverity: map[string]int{models.SeverityWarning: 1, models.SeverityError: 1},
+			},
+			Rules: []string{"title", "headings", "links", "amp"},
+			URLNormalization: &models.URLNormalization{
+				FoldTrailingSlash: true,
+				StripParams:       []string{"utm_*", "gclid"},
+				Case:              models.URLCaseHost,
+			},
+			Checks: []models.CheckResult{
+				{Name: "analytics", Type: models.CheckRegex, Passed: true, Matches: 2},
+				{Name: "no lorem", Type: models.CheckNotContains, Matches: 1},
+				{Name: "banner", Type: models.CheckRegex, Optional: true, Error: "invalid regular expression: missing closing )"},
+			},
+			ChecksFailed: true,
+			ResultHash:   "9f2b6c1e0d4a7b3c8e5f1a2d6c9b0e3f7a4d8c1b5e2f9a6d3c0b7e4f1a8d5c2b",
+		},
+		"requires auth": {
+			URL:        "https://example.com/admin",
+			AnalyzedAt: analyzedAt,
+			RequiresAuth: &models.AuthRequirement{
+				StatusCode: 401,
+				Scheme:     "Basic",
+				Realm:      "Staging",
+				Challenges: []models.AuthChallenge{{Scheme: "Basic", Realm: "Staging"}},
+			},
+			Findings: []models.Finding{{ID: "AUTH_REQUIRED", Category: models.CategoryContent, Severity: models.SeverityWarning,
+				Message: `The page asks for Basic authentication to "Staging"; its content was not analyzed`}},
+		},
+		"zero value": {},
+	}
+}
+
+func testBatch() Batch {
+	return Batch{
+		Items: []BatchItem{
+			{URL: "https://example.com", Result: testResults()["complete"]},
+			{URL: "https://broken.example", Error: &models.ErrorResponse{
+				Error:      "analyzer service error (status 500): Failed to analyze URL",
+				StatusCode: 502,
+				Timestamp:  analyzedAt,
+			}},
+			{URL: "https://example.com/legacy", Result: testResults()["with warnings"]},
+		},
+		TotalTime: 1500 * time.Millisecond,
+		Findings: &models.CrossPageFindings{
+			CollapsedRedirects: []models.CollapsedRedirect{
+				{FinalURL: "https://example.com/", URLs: []string{"http://example.com", "https://example.com"}},
+			},
+		},
+	}
+}
+
+func TestResultRoundTrip(t *testing.T) {
+	for name, result := range testResults() {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, result, FromV1(ToV1(result)))
+			assert.Equal(t, result, FromV2(ToV2(result)))
+		})
+	}
+}
+
+func TestResultRoundTrip_ThroughJSON(t *testing.T) {
+	for name, result := range testResults() {
+		t.Run(name, func(t *testing.T) {
+			data, err := json.Marshal(ToV1(result))
+			require.NoError(t, err)
+			var v1 models.AnalysisResult
+			require.NoError(t, json.Unmarshal(data, &v1))
+			assert.Equal(t, result, FromV1(v1))
+
+			data, err = json.Marshal(ToV2(result))
+			require.NoError(t, err)
+			var v2 AnalysisResultV2
+			require.NoError(t, json.Unmarshal(data, &v2))
+			assert.Equal(t, result, FromV2(v2))
+		})
+	}
+}
+
+func TestToV2_Warnings(t *testing.T) {
+	results := testResults()
+
+	assert.Empty(t, ToV2(results["complete"]).Warnings)
+	assert.Equal(t, []string{
+		"page has no title",
+		"page has no DOCTYPE declaration",
+		"2 of 3 links are inaccessible",
+		"1 of 1 frames were not analyzed, their content is not counted",
+		"1 internal links redirect, update them to their final URL",
+		"2 links open in a new tab without rel=noopener",
+		`2 links have generic text such as "click here"`,
+		"2 hreflang problems found",
+		"1 AMP problems found",
+		"1 pagination problems found",
+		"2 in-page links point at no element",
+		"1 of 3 links were not checked, the outbound budget ran out",
+	}, ToV2(results["with warnings"]).Warnings)
+	assert.Equal(t, []string{"page requires authentication, its content was not analyzed"}, ToV2(results["requires auth"]).Warnings)
+}
+
+func TestBatchRoundTrip_V2(t *testing.T) {
+	batch := testBatch()
+
+	assert.Equal(t, batch, BatchFromV2(BatchToV2(batch)))
+
+	data, err := json.Marshal(BatchToV2(batch))
+	require.NoError(t, err)
+	var v2 BatchResultV2
+	require.NoError(t, json.Unmarshal(data, &v2))
+	assert.Equal(t, batch, BatchFromV2(v2))
+}
+
+func TestBatchRoundTrip_V1(t *testing.T) {
+	batch := testBatch()
+
+	// v1 lists successes and failures separately, so only the order within
+	// each group survives the round trip
+	want := Batch{
+		Items:     []BatchItem{batch.Items[0], batch.Items[2], batch.Items[1]},
+		TotalTime: batch.TotalTime,
+		Findings:  batch.Findings,
+	}
+	assert.Equal(t, want, BatchFromV1(BatchToV1(batch)))
+
+	data, err := json.Marshal(BatchToV1(batch))
+	require.NoError(t, err)
+	var v1 models.BatchAnalysisResult
+	require.NoError(t, json.Unmarshal(data, &v1))
+	assert.Equal(t, want, BatchFromV1(v1))
+}
+
+func TestBatchToV1_LegacyShape(t *testing.T) {
+	v1 := BatchToV1(testBatch())
+
+	require.Len(t, v1.Results, 2)
+	require.Len(t, v1.Errors, 1)
+	assert.Equal(t, "Failed to analyze: https://broken.example", v1.Errors[0].Details)
+	assert.Equal(t, 1500*time.Millisecond, v1.TotalTime)
+}
+
+func TestBatchToV2_Shape(t *testing.T) {
+	v2 := BatchToV2(testBatch())
+
+	require.Len(t, v2.Items, 3)
+	assert.Equal(t, 2, v2.Succeeded)
+	assert.Equal(t, 1, v2.Failed)
+	assert.Equal(t, int64(1500), v2.TotalTimeMs)
+
+	assert.Equal(t, StatusSucceeded, v2.Items[0].Status)
+	assert.Nil(t, v2.Items[0].Error)
+	assert.Equal(t, StatusFailed, v2.Items[1].Status)
+	assert.Nil(t, v2.Items[1].Result)
+	assert.Equal(t, "https://broken.example", v2.Items[1].URL)
+	assert.NotEmpty(t, v2.Items[2].Result.Warnings)
+}
+
+func TestEmptyBatch(t *testing.T) {
+	empty := Batch{Items: []BatchItem{}}
+
+	assert.Equal(t, empty, BatchFromV1(BatchToV1(empty)))
+	assert.Equal(t, empty, BatchFromV2(BatchToV2(empty)))
+
+	data, err := json.Marshal(BatchToV2(empty))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"items":[],"succeeded":0,"failed":0,"total_time_ms":0}`, string(data))
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// BatchInfo is the bookkeeping of a running CheckLinks or CheckLinksStream
+// call, as served by the debug endpoint. A batch whose context is done but
+// which still has goroutines is winding down; one that stays so is stuck.
+type BatchInfo struct {
+	ID        uint64    `json:"id"`
+	Links     int       `json:"links"`
+	Emitted   int64     `json:"emitted"`
+	Chunk     int64     `json:"chunk"`
+	Chunks    int       `json:"chunks"`
+	StartedAt time.Time `json:"started_at"`
+	// Goroutines counts the workers and the feeder of the current chunk
+	Goroutines int64 `json:"goroutines"`
+	Done       bool  `json:"done"`
+}
+
+// batchState is the bookkeeping of a running batch; the counters are
+// updated as it runs and read by Batches
+type batchState struct {
+	id        uint64
+	ctx       context.Context
+	links     int
+	startedAt time.Time
+
+	emitted    atomic.Int64
+	chunk      atomic.Int64
+	goroutines atomic.Int64
+}
+
+// Batches returns the bookkeeping of the running batches, oldest first
+func (c *ConcurrentLinkChecker) Batches() []BatchInfo {
+	c.batchMu.Lock()
+	batches := make([]BatchInfo, 0, len(c.batches))
+	for _, batch := range c.batches {
+		batches = append(batches, BatchInfo{
+			ID:         batch.id,
+			Links:      batch.links,
+			Emitted:    batch.emitted.Load(),
+			Chunk:      batch.chunk.Load(),
+			Chunks:     (batch.links + batchChunkSize - 1) / batchChunkSize,
+			StartedAt:  batch.startedAt,
+			Goroutines: batch.goroutines.Load(),
+			Done:       batch.ctx.Err() != nil,
+		})
+	}
+	c.batchMu.Unlock()
+
+	slices.SortFunc(batches, func(a, b BatchInfo) int { return cmp.Compare(a.ID, b.ID) })
+	return batches
+}
+
+// trackBatch registers a batch of n links checked under ctx
+func (c *ConcurrentLinkChecker) trackBatch(ctx context.Context, n int) *batchState {
+	c.batchMu.Lock()
+	defer c.batchMu.Unlock()
+	c.nextBatchID++
+	batch := &batchState{id: c.nextBatchID, ctx: ctx, links: n, startedAt: time.Now()}
+	c.batches[batch.id] = batch
+	return batch
+}
+
+// untrackBatch forgets a batch once it has completed
+func (c *ConcurrentLinkChecker) untrackBatch(batch *batchState) {
+	c.batchMu.Lock()
+	defer c.batchMu.Unlock()
+	delete(c.batches, batch.id)
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"go.uber.org/goleak"
+)
+
+// blockingHTTPClient calls onRequest, if set, for every request and answers
+// only once the request's context is done
+type blockingHTTPClient struct {
+	SimpleHTTPClient
+	requests  atomic.Int64
+	onRequest func()
+}
+
+func (c *blockingHTTPClient) Get(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	c.requests.Add(1)
+	if c.onRequest != nil {
+		c.onRequest()
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// loadMetrics keeps the queued and active link check gauges
+type loadMetrics struct {
+	SimpleMetricsCollector
+	queued atomic.Int64
+	active atomic.Int64
+}
+
+func (m *loadMetrics) AddLinkChecksQueued(delta int) { m.queued.Add(int64(delta)) }
+func (m *loadMetrics) AddLinkChecksActive(delta int) { m.active.Add(int64(delta)) }
+
+func numberedLinks(n int, hosts int) []models.Link {
+	links := make([]models.Link, n)
+	for i := range links {
+		links[i] = models.Link{URL: fmt.Sprintf("https://host%d.example.com/%d", i%hosts, i)}
+	}
+	return links
+}
+
+func TestCheckLinks_NoGoroutineLeaks(t *testing.T) {
+	tests := []struct {
+		name      string
+		links     int
+		hostDelay time.Duration
+		block     bool // requests block until the batch is cancelled
+	}{
+		{name: "completed", links: 1200},
+		{name: "completed paced", links: 40, hostDelay: time.Millisecond},
+		{name: "cancelled", links: 1200, block: true},
+		{name: "cancelled paced", links: 40, hostDelay: time.Millisecond, block: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.hostDelay > 0 {
+				ctx = WithHostDelay(ctx, tt.hostDelay)
+			}
+			var client interfaces.HTTPClient = &SimpleHTTPClient{}
+			if tt.block {
+				// The first request cancels the batch, with the workers busy
+				// and links still queued
+				client = &blockingHTTPClient{onRequest: cancel}
+			}
+			metrics := &loadMetrics{}
+			checker := NewConcurrentLinkChecker(client, 8, &SimpleLogger{}, metrics)
+
+			links := numberedLinks(tt.links, 4)
+			results, err := checker.CheckLinks(ctx, links)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(results) != len(links) {
+				t.Fatalf("expected %d results, got %d", len(links), len(results))
+			}
+			for i, status := range results {
+				if status.Link.URL != links[i].URL {
+					t.Fatalf("result %d is for %s, want %s", i, status.Link.URL, links[i].URL)
+				}
+				if tt.block == status.Accessible {
+					t.Fatalf("result %d: got %+v", i, status)
+				}
+			}
+
+			if queued := metrics.queued.Load(); queued != 0 {
+				t.Errorf("expected no queued link checks left, got %d", queued)
+			}
+			if active := metrics.active.Load(); active != 0 {
+				t.Errorf("expected no active link checks left, got %d", active)
+			}
+			if batches := checker.Batches(); len(batches) != 0 {
+				t.Errorf("expected no batches left, got %+v", batches)
+			}
+		})
+	}
+}
+
+func TestBatches(t *testing.T) {
+	const workers = 4
+	client := &blockingHTTPClient{}
+	checker := NewConcurrentLinkChecker(client, workers, &SimpleLogger{}, &SimpleMetricsCollector{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		checker.CheckLinks(ctx, numberedLinks(batchChunkSize+100, 1))
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for client.requests.Load() < workers {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d requests in flight, got %d", workers, client.requests.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	batches := checker.Batches()
+	if len(batches) != 1 {
+		t.Fatalf("expected 1 running batch, got %+v", batches)
+	}
+	batch := batches[0]
+	if batch.Links != batchChunkSize+100 || batch.Chunk != 1 || batch.Chunks != 2 || batch.Emitted != 0 || batch.Done {
+		t.Errorf("unexpected bookkeeping %+v", batch)
+	}
+	if batch.Goroutines < workers {
+		t.Errorf("expected at least %d goroutines, got %d", workers, batch.Goroutines)
+	}
+
+	cancel()
+	<-done
+	if batches := checker.Batches(); len(batches) != 0 {
+		t.Errorf("expected no batches once done, got %+v", batches)
+	}
+}
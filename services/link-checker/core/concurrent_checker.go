@@ -2,24 +2,75 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/budget"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
+	"github.com/RuvinSL/webpage-analyzer/pkg/idn"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/linkcheck"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
 )
 
+// Per-link debug lines are sampled so large batches stay cheap at debug level
+const (
+	debugSampleFirst      = 10
+	debugSampleThereafter = 100
+	debugSampleInterval   = time.Second
+)
+
+// batchChunkSize caps how many links of one CheckLinks call are queued at once
+const batchChunkSize = 500
+
+// defaultLinkTimeout bounds each request of a check, unless SetLinkTimeout
+// or the options of its batch say otherwise
+const defaultLinkTimeout = 5 * time.Second
+
+// retryBackoff is the wait before the first retry of a link; it doubles
+// with every retry
+const retryBackoff = 250 * time.Millisecond
+
 type ConcurrentLinkChecker struct {
 	httpClient     interfaces.HTTPClient
 	workerPoolSize int
 	logger         interfaces.Logger
+	linkLogger     *logger.SampledLogger
 	metrics        interfaces.MetricsCollector
 
+	// Requests to one host are spaced defaultHostDelay apart unless the
+	// context says otherwise, see SetHostDelay
+	defaultHostDelay time.Duration
+	pacer            *hostPacer
+	clock            clock
+
+	// linkTimeout bounds each request of a check unless its batch sets
+	// one, see SetLinkTimeout
+	linkTimeout time.Duration
+	// hedgeDelay is how long the first request of a check runs before a
+	// second is sent, see SetHedgeDelay
+	hedgeDelay time.Duration
+
+	// preconnectHosts is how many hosts each batch connects to before
+	// checking, see SetPreconnectHosts
+	preconnectHosts int
+
+	// batches are the CheckLinks calls running, by ID, see Batches
+	batchMu     sync.Mutex
+	batches     map[uint64]*batchState
+	nextBatchID uint64
+
 	jobQueue    chan linkCheckJob
 	resultQueue chan models.LinkStatus
 	workerWG    sync.WaitGroup
 	stopChan    chan struct{}
+	shrinkChan  chan struct{}
 	started     bool         // fixed - Ruvin
 	mu          sync.RWMutex // fixed - Ruvin
 }
@@ -27,26 +78,59 @@ type ConcurrentLinkChecker struct {
 type linkCheckJob struct {
 	ctx  context.Context
 	link models.Link
+	// turn delivers how long a paced job waits for its host; it is nil
+	// for jobs that pace themselves, if at all
+	turn chan time.Duration
 }
 
 func NewConcurrentLinkChecker(
 	httpClient interfaces.HTTPClient,
 	workerPoolSize int,
-	logger interfaces.Logger,
+	log interfaces.Logger,
 	metrics interfaces.MetricsCollector,
 ) *ConcurrentLinkChecker {
 	return &ConcurrentLinkChecker{
 		httpClient:     httpClient,
 		workerPoolSize: workerPoolSize,
-		logger:         logger,
+		logger:         log,
+		linkLogger:     logger.NewSampledLogger(log, logger.NewSampler(debugSampleFirst, debugSampleThereafter, debugSampleInterval)),
 		metrics:        metrics,
 		jobQueue:       make(chan linkCheckJob, workerPoolSize*2),
 		resultQueue:    make(chan models.LinkStatus, workerPoolSize*2),
 		stopChan:       make(chan struct{}),
+		shrinkChan:     make(chan struct{}),
 		started:        false, // added fixed - Ruvin
+		batches:        make(map[uint64]*batchState),
+		pacer:          newHostPacer(realClock{}),
+		clock:          realClock{},
+		linkTimeout:    defaultLinkTimeout,
 	}
 }
 
+// SetLinkTimeout bounds each request of a check, body read included, by
+// timeout; PerLinkTimeoutMs in the options of a batch overrides it. Zero
+// keeps the default of 5s.
+func (c *ConcurrentLinkChecker) SetLinkTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		c.linkTimeout = timeout
+	}
+}
+
+// SetHedgeDelay has a check whose first request has not completed within
+// delay send a second, once the host's turn comes under SetHostDelay, and use
+// whichever answers first, cancelling the other. Only the first attempt of a
+// link is hedged. Zero, the default, turns hedging off.
+func (c *ConcurrentLinkChecker) SetHedgeDelay(delay time.Duration) {
+	c.hedgeDelay = max(delay, 0)
+}
+
+// SetHostDelay spaces requests to the same host at least delay apart, across
+// all batches; links of other hosts are checked in the meantime. Zero, the
+// default, leaves requests unpaced. WithHostDelay overrides it per request.
+func (c *ConcurrentLinkChecker) SetHostDelay(delay time.Duration) {
+	c.defaultHostDelay = min(max(delay, 0), MaxHostDelay)
+}
+
 // fixed the code and added concurrent start
 func (c *ConcurrentLinkChecker) Start(ctx context.Context) {
 	c.mu.Lock()
@@ -92,136 +176,434 @@ func (c *ConcurrentLinkChecker) Stop() {
 	c.logger.Info("Link checker worker pool stopped")
 }
 
+// Resize changes the number of workers. New workers start immediately, while
+// surplus workers finish their current job before exiting, so in-flight
+// checks are never dropped. Batches already running keep their original size.
+func (c *ConcurrentLinkChecker) Resize(ctx context.Context, size int) {
+	if size < 1 {
+		c.logger.Warn("Ignoring invalid worker pool size", "workers", size)
+		return
+	}
+
+	c.mu.Lock()
+	previous := c.workerPoolSize
+	c.workerPoolSize = size
+	started := c.started
+	if started {
+		for i := previous; i < size; i++ {
+			c.workerWG.Add(1)
+			go c.worker(ctx, i)
+		}
+	}
+	c.mu.Unlock()
+
+	c.logger.Info("Resized link checker worker pool", "previous_workers", previous, "workers", size)
+
+	if !started {
+		return
+	}
+	for i := size; i < previous; i++ {
+		select {
+		case c.shrinkChan <- struct{}{}:
+		case <-c.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// WorkerPoolSize returns the current number of workers
+func (c *ConcurrentLinkChecker) WorkerPoolSize() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.workerPoolSize
+}
+
 // CheckLinks checks multiple links concurrently
 func (c *ConcurrentLinkChecker) CheckLinks(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
 	if len(links) == 0 {
 		return []models.LinkStatus{}, nil
 	}
 
+	resultMap := make(map[string]models.LinkStatus, len(links))
+	c.checkBatch(ctx, links, func(status models.LinkStatus) {
+		resultMap[status.Link.URL] = status
+	})
+
+	// Convert map to slice maintaining order
+	results := make([]models.LinkStatus, 0, len(links))
+	for _, link := range links {
+		results = append(results, resultMap[link.URL])
+	}
+	return results, nil
+}
+
+// CheckLinksStream checks links like CheckLinks but passes each status to
+// emit as soon as it is known, so in completion order rather than input
+// order. emit is called from a single goroutine.
+func (c *ConcurrentLinkChecker) CheckLinksStream(ctx context.Context, links []models.Link, emit func(models.LinkStatus)) error {
+	if len(links) == 0 {
+		return nil
+	}
+	c.checkBatch(ctx, links, emit)
+	return nil
+}
+
+// checkBatch emits exactly one status per link, with links not checked in
+// time reported as timed out
+func (c *ConcurrentLinkChecker) checkBatch(ctx context.Context, links []models.Link, emit func(models.LinkStatus)) {
 	start := time.Now()
+	droppedBefore := c.linkLogger.Sampler().Dropped()
 	c.logger.Info("Starting batch link check", "link_count", len(links))
 
 	checkCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	// Create dedicated channels for this batch to avoid interference
-	batchJobQueue := make(chan linkCheckJob, len(links))
+	batch := c.trackBatch(checkCtx, len(links))
+	defer c.untrackBatch(batch)
+
+	c.preconnect(checkCtx, links)
+
+	processed, failures := 0, 0
+	count := func(status models.LinkStatus) {
+		processed++
+		if !status.Accessible {
+			failures++
+		}
+		batch.emitted.Add(1)
+		emit(status)
+	}
+
+	// Large batches are checked a chunk at a time so the per-batch queues
+	// and worker count stay bounded regardless of the request size
+	for chunk := range slices.Chunk(links, batchChunkSize) {
+		batch.chunk.Add(1)
+		c.checkChunk(checkCtx, batch, chunk, count)
+	}
+
+	duration := time.Since(start)
+	c.logger.Info("Batch link check completed",
+		"link_count", len(links),
+		"processed_count", processed,
+		"failed_count", failures,
+		"duration", duration,
+		"avg_time_per_link", duration/time.Duration(len(links)),
+	)
+
+	// Summarise what sampling hid so the sampled debug entries can be read in context
+	if droppedAfter := c.linkLogger.Sampler().Dropped(); droppedAfter > droppedBefore {
+		c.logger.Debug("Per-link debug logs sampled, see sampled entries",
+			"checked_count", len(links),
+			"failed_count", failures,
+			"suppressed_debug_lines", droppedAfter-droppedBefore,
+		)
+	}
+}
+
+// checkChunk checks links on a dedicated set of workers and emits their
+// statuses as they complete. Links not checked before ctx is done are
+// emitted as timed out once the rest have been collected. The workers and
+// the feeder of the chunk have all exited by the time it returns, however
+// the chunk ended.
+func (c *ConcurrentLinkChecker) checkChunk(ctx context.Context, batch *batchState, links []models.Link, emit func(models.LinkStatus)) {
+	// Cancelled once the chunk is collected, so that its goroutines stop
+	// when the collector stops waiting for them
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Create dedicated channels for this chunk to avoid interference. Paced
+	// links are handed to workers one at a time as their hosts' turns come.
+	delay := c.hostDelay(ctx)
+	queueSize := len(links)
+	if delay > 0 {
+		queueSize = 0
+	}
+	batchJobQueue := make(chan linkCheckJob, queueSize)
+	// A worker sends at most one status per link, so sending never blocks
 	batchResultQueue := make(chan models.LinkStatus, len(links))
 
-	// Start workers for this batch
-	var workerWG sync.WaitGroup
-	for i := 0; i < c.workerPoolSize; i++ {
-		workerWG.Add(1)
-		go func(workerID int) {
-			defer workerWG.Done()
+	var chunkWG sync.WaitGroup
+	spawn := func(run func()) {
+		chunkWG.Add(1)
+		batch.goroutines.Add(1)
+		go func() {
+			defer chunkWG.Done()
+			defer batch.goroutines.Add(-1)
+			run()
+		}()
+	}
+
+	// Start workers for this chunk, never more than there are links. Jobs
+	// still queued once the chunk is cancelled are drained unchecked, and
+	// reported as not checked by the collector.
+	workers := min(c.WorkerPoolSize(), len(links))
+	for range workers {
+		spawn(func() {
 			for job := range batchJobQueue {
-				status := c.CheckLink(job.ctx, job.link)
-				select {
-				case batchResultQueue <- status:
-				case <-checkCtx.Done():
-					return
+				c.metrics.AddLinkChecksQueued(-1)
+				if ctx.Err() != nil {
+					continue
 				}
+				batchResultQueue <- c.checkJob(job)
 			}
-		}(i)
+		})
 	}
 
-	// fixed Submit all jobs
-	go func() {
+	// fixed Submit all jobs. Each is counted as queued before it can reach
+	// a worker, which counts it out again; workers drain the whole queue.
+	spawn(func() {
 		defer close(batchJobQueue)
+		if delay > 0 {
+			c.feedPaced(ctx, links, delay, batchJobQueue)
+			return
+		}
 		for _, link := range links {
+			c.metrics.AddLinkChecksQueued(1)
 			select {
-			case batchJobQueue <- linkCheckJob{ctx: checkCtx, link: link}:
-			case <-checkCtx.Done():
+			case batchJobQueue <- linkCheckJob{ctx: ctx, link: link}:
+			case <-ctx.Done():
+				c.metrics.AddLinkChecksQueued(-1)
 				return
 			}
 		}
-	}()
-
-	// Collect results
-	results := make([]models.LinkStatus, 0, len(links))
-	resultMap := make(map[string]models.LinkStatus)
-
-	// Start result collector
-	go func() {
-		defer close(batchResultQueue)
-		workerWG.Wait() // Wait for all workers to finish before closing result channel
-	}()
+	})
 
 	// Collect all results
+	checked := make(map[string]bool, len(links))
+collect:
 	for i := 0; i < len(links); i++ {
 		select {
 		case status := <-batchResultQueue:
-			resultMap[status.Link.URL] = status
-		case <-checkCtx.Done():
+			checked[status.Link.URL] = true
+			emit(status)
+		case <-ctx.Done():
 			c.logger.Warn("Context cancelled during result collection")
-			break
+			break collect
 		}
 	}
 
-	// Convert map to slice maintaining order
+	// Stop the feeder and the workers, which give up their checks in
+	// flight, and wait for them
+	cancel()
+	chunkWG.Wait()
+
 	for _, link := range links {
-		if status, exists := resultMap[link.URL]; exists {
-			results = append(results, status)
-		} else {
-			// Create timeout result for unchecked links
-			results = append(results, models.LinkStatus{
-				Link:       link,
-				Accessible: false,
-				StatusCode: 0,
-				Error:      "Check timeout or not processed",
-				CheckedAt:  time.Now(),
-			})
+		if checked[link.URL] {
+			continue
 		}
+		// Create timeout result for unchecked links
+		emit(notChecked(link))
 	}
+}
 
-	duration := time.Since(start)
-	c.logger.Info("Batch link check completed",
-		"link_count", len(links),
-		"processed_count", len(results),
-		"duration", duration,
-		"avg_time_per_link", duration/time.Duration(len(links)),
-	)
-
-	return results, nil
+// checkJob checks a job's link once its host's turn has come
+func (c *ConcurrentLinkChecker) checkJob(job linkCheckJob) models.LinkStatus {
+	if job.turn == nil {
+		return c.CheckLink(job.ctx, job.link)
+	}
+	if err := c.sleep(job.ctx, <-job.turn); err != nil {
+		return notChecked(job.link)
+	}
+	return c.check(job.ctx, job.link)
 }
 
+// CheckLink checks a link once its host's turn has come
 func (c *ConcurrentLinkChecker) CheckLink(ctx context.Context, link models.Link) models.LinkStatus {
+	// Waiting for the host's turn is not part of the check
+	if err := c.pace(ctx, link); err != nil {
+		return notChecked(link)
+	}
+	return c.check(ctx, link)
+}
+
+// notChecked is the status of a link that was not checked in time
+func notChecked(link models.Link) models.LinkStatus {
+	return models.LinkStatus{
+		Link:       link,
+		Accessible: false,
+		StatusCode: 0,
+		Error:      models.LinkNotCheckedError,
+		CheckedAt:  time.Now(),
+	}
+}
+
+func (c *ConcurrentLinkChecker) check(ctx context.Context, link models.Link) models.LinkStatus {
 	start := time.Now()
+	status := models.LinkStatus{Link: link}
+
+	// Exactly one observation per check, covering the full duration. Links
+	// skipped for lack of budget were never checked, so they are not metered.
+	c.metrics.AddLinkChecksActive(1)
 	defer func() {
-		duration := time.Since(start).Seconds()
-		c.metrics.RecordLinkCheck(true, duration)
+		c.metrics.AddLinkChecksActive(-1)
+		if !status.Skipped {
+			c.metrics.RecordLinkCheck(status.Accessible, time.Since(start).Seconds())
+		}
 	}()
 
-	c.logger.Debug("Checking link", "url", link.URL, "type", link.Type)
-
-	checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
+	c.linkLogger.Debug("Checking link", "url", logger.RedactURL(link.URL), "type", link.Type)
 
-	// Perform HTTP GET request
-	resp, err := c.httpClient.Get(checkCtx, link.URL)
-
-	status := models.LinkStatus{
-		Link:      link,
-		CheckedAt: time.Now(),
+	// The analyzer never sends data: or blob: links, but another caller
+	// might; they have nothing to fetch
+	if !checkable(link.URL) {
+		status.CheckedAt = time.Now()
+		status.Error = models.LinkUnsupportedSchemeError
+		c.linkLogger.Debug("Link not checked, unsupported scheme", "type", link.Type)
+		return status
 	}
 
-	if err != nil {
+	opts, _ := linkcheck.FromContext(ctx)
+	if opts.MaxRedirects > 0 {
+		ctx = httpclient.WithMaxRedirects(ctx, opts.MaxRedirects)
+	}
+	// The status keeps the link as given; the request goes to the ASCII
+	// form of an internationalized host, which is what resolves
+	resp, err := c.fetch(ctx, idn.ASCIIURL(link.URL), opts)
+	status.CheckedAt = time.Now()
+
+	switch {
+	case errors.Is(err, budget.ErrExhausted):
+		status.Skipped = true
+		status.Error = budget.SkippedError
+		c.linkLogger.Debug("Link check skipped, budget exhausted", "url", logger.RedactURL(link.URL))
+		return status
+	case err != nil:
 		status.Accessible = false
 		status.Error = err.Error()
-		c.logger.Debug("Link check failed", "url", link.URL, "error", err)
-		c.metrics.RecordLinkCheck(false, time.Since(start).Seconds())
-	} else {
-		status.Accessible = resp.StatusCode >= 200 && resp.StatusCode < 400
+		var attemptErr *httpclient.AttemptError
+		if errors.As(err, &attemptErr) {
+			status.DialAttempts = attemptErr.Attempts
+		}
+		c.linkLogger.Debug("Link check failed", "url", logger.RedactURL(link.URL), "error", err)
+	default:
+		status.Accessible = resp.StatusCode >= 200 && resp.StatusCode < 400 ||
+			slices.Contains(opts.AcceptableStatusCodes, resp.StatusCode)
 		status.StatusCode = resp.StatusCode
 		if !status.Accessible {
 			status.Error = fmt.Sprintf("HTTP %d", resp.StatusCode)
 		}
-		c.logger.Debug("Link check completed", "url", link.URL, "status", resp.StatusCode)
+		if resp.Redirects > 0 {
+			status.Redirects = resp.Redirects
+			status.FinalURL = resp.FinalURL
+		}
+		c.linkLogger.Debug("Link check completed", "url", logger.RedactURL(link.URL), "status", resp.StatusCode)
 	}
 
+	status.DurationMs = float64(status.CheckedAt.Sub(start)) / float64(time.Millisecond)
 	return status
 }
 
+// checkable reports whether rawURL is an http or https URL
+func checkable(rawURL string) bool {
+	scheme, _, found := strings.Cut(rawURL, ":")
+	return found && (strings.EqualFold(scheme, "http") || strings.EqualFold(scheme, "https"))
+}
+
+// fetch requests url the way opts say, trying again after a failure that
+// may not last
+func (c *ConcurrentLinkChecker) fetch(ctx context.Context, url string, opts models.LinkCheckOptions) (*models.HTTPResponse, error) {
+	timeout := c.linkTimeout
+	if opts.PerLinkTimeoutMs > 0 {
+		timeout = time.Duration(opts.PerLinkTimeoutMs) * time.Millisecond
+	}
+	for attempt := 0; ; attempt++ {
+		var resp *models.HTTPResponse
+		var err error
+		if attempt == 0 && c.hedgeDelay > 0 {
+			resp, err = c.hedgedRequest(ctx, url, opts.Method, timeout)
+		} else {
+			resp, err = c.request(ctx, url, opts.Method, timeout)
+		}
+		if attempt >= opts.Retries || !retryable(resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+		if c.sleep(ctx, retryBackoff<<attempt) != nil {
+			return resp, err
+		}
+	}
+}
+
+// request makes one attempt at url with method, each request bounded by
+// timeout
+func (c *ConcurrentLinkChecker) request(ctx context.Context, url, method string, timeout time.Duration) (*models.HTTPResponse, error) {
+	do := func(send func(context.Context, string) (*models.HTTPResponse, error)) (*models.HTTPResponse, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return send(ctx, url)
+	}
+
+	switch method {
+	case models.LinkCheckHead:
+		return do(c.httpClient.Head)
+	case models.LinkCheckHeadThenGet:
+		resp, err := do(c.httpClient.Head)
+		if errors.Is(err, budget.ErrExhausted) || err == nil && resp.StatusCode < 400 {
+			return resp, err
+		}
+		return do(c.httpClient.Get)
+	default:
+		return do(c.httpClient.Get)
+	}
+}
+
+// hedgedRequest makes the request of url and, if it has not completed after
+// the hedge delay and the host's turn, a second one. The first to complete
+// is returned and the other cancelled, unless it is a hedge the budget had
+// no request left for.
+func (c *ConcurrentLinkChecker) hedgedRequest(ctx context.Context, url, method string, timeout time.Duration) (*models.HTTPResponse, error) {
+	// The request that loses is cancelled and waited for, so that neither
+	// outlives the check
+	var requests sync.WaitGroup
+	ctx, cancel := context.WithCancel(ctx)
+	defer requests.Wait()
+	defer cancel()
+
+	type outcome struct {
+		resp  *models.HTTPResponse
+		err   error
+		hedge bool
+	}
+	outcomes := make(chan outcome, 2)
+	requests.Add(2)
+	go func() {
+		defer requests.Done()
+		resp, err := c.request(ctx, url, method, timeout)
+		outcomes <- outcome{resp, err, false}
+	}()
+
+	hedged := make(chan struct{})
+	go func() {
+		defer requests.Done()
+		if c.sleep(ctx, c.hedgeDelay) != nil || c.pace(ctx, models.Link{URL: url}) != nil || ctx.Err() != nil {
+			return
+		}
+		close(hedged)
+		resp, err := c.request(ctx, url, method, timeout)
+		outcomes <- outcome{resp, err, true}
+	}()
+
+	first := <-outcomes
+	if first.hedge && errors.Is(first.err, budget.ErrExhausted) {
+		first = <-outcomes
+	}
+	select {
+	case <-hedged:
+		c.metrics.RecordLinkCheckHedge(first.hedge)
+	default:
+	}
+	return first.resp, first.err
+}
+
+// retryable reports whether a check that ended with resp or err is worth
+// another try
+func retryable(resp *models.HTTPResponse, err error) bool {
+	if err != nil {
+		return !errors.Is(err, budget.ErrExhausted)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
 func (c *ConcurrentLinkChecker) worker(ctx context.Context, id int) {
 	defer c.workerWG.Done()
 
@@ -235,6 +617,9 @@ func (c *ConcurrentLinkChecker) worker(ctx context.Context, id int) {
 		case <-c.stopChan:
 			c.logger.Debug("Worker stopping due to stop signal", "worker_id", id)
 			return
+		case <-c.shrinkChan:
+			c.logger.Debug("Worker stopping due to pool resize", "worker_id", id)
+			return
 		case job, ok := <-c.jobQueue:
 			if !ok {
 				c.logger.Debug("Worker stopping, job queue closed", "worker_id", id)
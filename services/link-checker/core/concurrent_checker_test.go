@@ -2,10 +2,27 @@ package core
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/budget"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/linkcheck"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/pkg/mocks"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/golang/mock/gomock"
 )
 
 // Simple test logger
@@ -31,9 +48,22 @@ func (s *SimpleHTTPClient) Head(ctx context.Context, url string) (*models.HTTPRe
 // Simple metrics collector
 type SimpleMetricsCollector struct{}
 
-func (s *SimpleMetricsCollector) RecordLinkCheck(success bool, duration float64) {}
-func (s *SimpleMetricsCollector) RecordAnalysis(success bool, duration float64)  {}
-func (s *SimpleMetricsCollector) RecordRequest(method string, url string, statusCode int, duration float64) {
+func (s *SimpleMetricsCollector) RecordLinkCheck(success bool, duration float64)  {}
+func (s *SimpleMetricsCollector) RecordAnalysis(success bool, duration float64)   {}
+func (s *SimpleMetricsCollector) RecordCoalescedAnalysis()                        {}
+func (s *SimpleMetricsCollector) RecordScreenshot(success bool, duration float64) {}
+func (s *SimpleMetricsCollector) RecordStage(name string, seconds float64)        {}
+func (s *SimpleMetricsCollector) RecordAnalysisFailure(cause string)              {}
+func (s *SimpleMetricsCollector) RecordCacheLookup(hit bool)                      {}
+func (s *SimpleMetricsCollector) RecordUpstreamRetry(upstream, reason string)     {}
+func (s *SimpleMetricsCollector) RecordLinkCheckHedge(won bool)                   {}
+func (s *SimpleMetricsCollector) RecordMirror(outcome string)                     {}
+func (s *SimpleMetricsCollector) RecordDownloadedBytes(bytes int64)               {}
+func (s *SimpleMetricsCollector) RecordCallerBytes(caller string, bytes int64)    {}
+func (s *SimpleMetricsCollector) AddAnalysesInFlight(delta int)                   {}
+func (s *SimpleMetricsCollector) AddLinkChecksActive(delta int)                   {}
+func (s *SimpleMetricsCollector) AddLinkChecksQueued(delta int)                   {}
+func (s *SimpleMetricsCollector) RecordRequest(method string, url string, statusCode int, duration float64, requestID string) {
 }
 
 func TestSimple(t *testing.T) {
@@ -48,3 +78,772 @@ func TestSimple(t *testing.T) {
 		t.Fatal("checker should not be nil")
 	}
 }
+
+func TestResize(t *testing.T) {
+	checker := NewConcurrentLinkChecker(&SimpleHTTPClient{}, 4, &SimpleLogger{}, &SimpleMetricsCollector{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	checker.Start(ctx)
+
+	// Shrinking returns once the surplus workers have exited
+	checker.Resize(ctx, 2)
+	if got := checker.WorkerPoolSize(); got != 2 {
+		t.Fatalf("expected 2 workers after shrink, got %d", got)
+	}
+
+	checker.Resize(ctx, 6)
+	if got := checker.WorkerPoolSize(); got != 6 {
+		t.Fatalf("expected 6 workers after grow, got %d", got)
+	}
+
+	checker.Resize(ctx, 0)
+	if got := checker.WorkerPoolSize(); got != 6 {
+		t.Fatalf("invalid size should be ignored, got %d workers", got)
+	}
+
+	links := []models.Link{{URL: "https://example.com/a"}, {URL: "https://example.com/b"}}
+	results, err := checker.CheckLinks(ctx, links)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != len(links) {
+		t.Fatalf("expected %d results, got %d", len(links), len(results))
+	}
+
+	checker.Stop()
+}
+
+func TestCheckLink_RecordsMetricsOnce(t *testing.T) {
+	tests := []struct {
+		name            string
+		response        *models.HTTPResponse
+		err             error
+		expectedSuccess bool
+	}{
+		{name: "success", response: &models.HTTPResponse{StatusCode: 200}, expectedSuccess: true},
+		{name: "HTTP error", response: &models.HTTPResponse{StatusCode: 404}, expectedSuccess: false},
+		{name: "transport error", err: errors.New("connection refused"), expectedSuccess: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			httpClient := mocks.NewMockHTTPClient(ctrl)
+			httpClient.EXPECT().Get(gomock.Any(), "https://example.com").Return(tt.response, tt.err)
+
+			metrics := mocks.NewMockMetricsCollector(ctrl)
+			metrics.EXPECT().RecordLinkCheck(tt.expectedSuccess, gomock.Any()).Times(1)
+			metrics.EXPECT().AddLinkChecksActive(1).Times(1)
+			metrics.EXPECT().AddLinkChecksActive(-1).Times(1)
+
+			checker := NewConcurrentLinkChecker(httpClient, 1, &SimpleLogger{}, metrics)
+			status := checker.CheckLink(context.Background(), models.Link{URL: "https://example.com"})
+
+			if status.Accessible != tt.expectedSuccess {
+				t.Fatalf("expected accessible=%v, got %v", tt.expectedSuccess, status.Accessible)
+			}
+		})
+	}
+}
+
+func TestCheckLinks_RejectsUnsupportedSchemes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Only the https link is fetched
+	httpClient := mocks.NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Get(gomock.Any(), "HTTPS://example.com/a").Return(&models.HTTPResponse{StatusCode: 200}, nil)
+
+	checker := NewConcurrentLinkChecker(httpClient, 2, &SimpleLogger{}, &SimpleMetricsCollector{})
+	statuses, err := checker.CheckLinks(context.Background(), []models.Link{
+		{URL: "HTTPS://example.com/a"},
+		{URL: "data:text/plain;base64,aGVsbG8="},
+		{URL: "blob:https://example.com/550e8400-e29b-41d4-a716-446655440000"},
+		{URL: "ftp://example.com/file"},
+		{URL: "example.com/no-scheme"},
+	})
+	if err != nil || len(statuses) != 5 {
+		t.Fatalf("expected 5 statuses, got %d (%v)", len(statuses), err)
+	}
+
+	if !statuses[0].Accessible {
+		t.Errorf("expected %s to be accessible", statuses[0].Link.URL)
+	}
+	for _, status := range statuses[1:] {
+		if status.Accessible || status.Skipped || status.StatusCode != 0 || status.Error != models.LinkUnsupportedSchemeError {
+			t.Errorf("expected %s to be rejected, got %+v", status.Link.URL, status)
+		}
+	}
+}
+
+func TestCheckLinks_FetchesIDNHostsInASCIIForm(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	httpClient := mocks.NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Get(gomock.Any(), "https://xn--bcher-kva.example/neu?q=%C3%BC").Return(&models.HTTPResponse{StatusCode: 200}, nil)
+	httpClient.EXPECT().Get(gomock.Any(), "https://xn--bcher-kva.example/alt").Return(&models.HTTPResponse{StatusCode: 200}, nil)
+
+	checker := NewConcurrentLinkChecker(httpClient, 2, &SimpleLogger{}, &SimpleMetricsCollector{})
+	links := []models.Link{
+		{URL: "https://bücher.example/neu?q=%C3%BC"},
+		{URL: "https://xn--bcher-kva.example/alt"},
+	}
+	statuses, err := checker.CheckLinks(context.Background(), links)
+	if err != nil || len(statuses) != 2 {
+		t.Fatalf("expected 2 statuses, got %d (%v)", len(statuses), err)
+	}
+	for i, status := range statuses {
+		if !status.Accessible || status.Link.URL != links[i].URL {
+			t.Errorf("expected %s to be accessible as given, got %+v", links[i].URL, status)
+		}
+	}
+}
+
+// chunkTrackingClient answers instantly and records, for every request,
+// whether all links of the earlier chunks had already completed
+type chunkTrackingClient struct {
+	SimpleHTTPClient
+	mu        sync.Mutex
+	completed int
+	inFlight  int
+	maxFlight int
+	early     []int
+}
+
+func (c *chunkTrackingClient) Get(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	var index int
+	fmt.Sscanf(url, "https://example.com/%d", &index)
+
+	c.mu.Lock()
+	if c.completed < index/batchChunkSize*batchChunkSize {
+		c.early = append(c.early, index)
+	}
+	c.inFlight++
+	c.maxFlight = max(c.maxFlight, c.inFlight)
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.inFlight--
+		c.completed++
+		c.mu.Unlock()
+	}()
+	return &models.HTTPResponse{StatusCode: 200}, nil
+}
+
+func TestCheckLinks_ChunksLargeBatches(t *testing.T) {
+	const workers = 8
+	client := &chunkTrackingClient{}
+	checker := NewConcurrentLinkChecker(client, workers, &SimpleLogger{}, &SimpleMetricsCollector{})
+
+	links := make([]models.Link, 5000)
+	for i := range links {
+		links[i] = models.Link{URL: fmt.Sprintf("https://example.com/%d", i)}
+	}
+
+	results, err := checker.CheckLinks(context.Background(), links)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != len(links) {
+		t.Fatalf("expected %d results, got %d", len(links), len(results))
+	}
+	for i, status := range results {
+		if status.Link.URL != links[i].URL || !status.Accessible {
+			t.Fatalf("result %d: got %+v, want accessible %s", i, status, links[i].URL)
+		}
+	}
+
+	if len(client.early) > 0 {
+		t.Fatalf("%d links were checked before the previous chunk finished, first %d", len(client.early), client.early[0])
+	}
+	if client.maxFlight > workers {
+		t.Fatalf("expected at most %d concurrent checks, got %d", workers, client.maxFlight)
+	}
+}
+
+func TestCheckLinksStream_EmitsEveryLink(t *testing.T) {
+	checker := NewConcurrentLinkChecker(&SimpleHTTPClient{}, 4, &SimpleLogger{}, &SimpleMetricsCollector{})
+
+	links := make([]models.Link, 1200)
+	for i := range links {
+		links[i] = models.Link{URL: fmt.Sprintf("https://example.com/%d", i)}
+	}
+
+	seen := make(map[string]int)
+	err := checker.CheckLinksStream(context.Background(), links, func(status models.LinkStatus) {
+		seen[status.Link.URL]++
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(seen) != len(links) {
+		t.Fatalf("expected %d distinct statuses, got %d", len(links), len(seen))
+	}
+	for url, n := range seen {
+		if n != 1 {
+			t.Fatalf("%s emitted %d times", url, n)
+		}
+	}
+}
+
+func TestCheckLinks_StopsWhenBudgetIsSpent(t *testing.T) {
+	// Every link redirects twice before answering, so each costs 3 requests
+	var hits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/hop/hop"):
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.Redirect(w, r, r.URL.Path+"/hop", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	client := httpclient.New(5*time.Second, &SimpleLogger{})
+	checker := NewConcurrentLinkChecker(client, 4, &SimpleLogger{}, &SimpleMetricsCollector{})
+
+	links := make([]models.Link, 20)
+	for i := range links {
+		links[i] = models.Link{URL: fmt.Sprintf("%s/%d", server.URL, i)}
+	}
+
+	spend := budget.New(10, 1<<20)
+	statuses, err := checker.CheckLinks(budget.WithBudget(context.Background(), spend), links)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := hits.Load(); got != 10 {
+		t.Fatalf("expected the budget to allow exactly 10 requests, server saw %d", got)
+	}
+	skipped := 0
+	for _, status := range statuses {
+		switch {
+		case status.Skipped:
+			if status.Error != budget.SkippedError || status.Accessible {
+				t.Fatalf("unexpected skipped status: %+v", status)
+			}
+			skipped++
+		case !status.Accessible:
+			t.Fatalf("%s failed instead of being skipped: %s", status.Link.URL, status.Error)
+		}
+	}
+	if skipped < len(links)-4 {
+		t.Fatalf("expected at least %d skipped links, got %d", len(links)-4, skipped)
+	}
+	if usage := spend.Usage(); !usage.Exhausted || usage.Requests != 10 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestCheckLinks_ReportsRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/direct", "/final":
+			w.WriteHeader(http.StatusOK)
+		case "/moved":
+			http.Redirect(w, r, "/final", http.StatusMovedPermanently)
+		case "/chain":
+			http.Redirect(w, r, "/chain/2", http.StatusFound)
+		case "/chain/2":
+			http.Redirect(w, r, "/moved", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop/back", http.StatusFound)
+		case "/loop/back":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	client := httpclient.New(5*time.Second, &SimpleLogger{})
+	checker := NewConcurrentLinkChecker(client, 4, &SimpleLogger{}, &SimpleMetricsCollector{})
+
+	links := []models.Link{
+		{URL: server.URL + "/direct"},
+		{URL: server.URL + "/moved"},
+		{URL: server.URL + "/chain"},
+		{URL: server.URL + "/loop"},
+	}
+	statuses, err := checker.CheckLinks(context.Background(), links)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []struct {
+		accessible bool
+		redirects  int
+		finalURL   string
+	}{
+		{accessible: true},
+		{accessible: true, redirects: 1, finalURL: server.URL + "/final"},
+		{accessible: true, redirects: 3, finalURL: server.URL + "/final"},
+		{accessible: false},
+	}
+	for i, status := range statuses {
+		if status.Accessible != want[i].accessible || status.Redirects != want[i].redirects || status.FinalURL != want[i].finalURL {
+			t.Fatalf("%s: unexpected status %+v", links[i].URL, status)
+		}
+	}
+	if !strings.Contains(statuses[3].Error, "redirect loop") {
+		t.Fatalf("expected a redirect loop error, got %q", statuses[3].Error)
+	}
+}
+
+func TestCheckLinks_OptionsApplyToTheirBatchOnly(t *testing.T) {
+	// Every link answers 403 to GET and HEAD alike; the methods are counted
+	// per batch, named by the first path segment
+	var mu sync.Mutex
+	methods := map[string]map[string]int{"head": {}, "default": {}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		batch, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		mu.Lock()
+		methods[batch][r.Method]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client := httpclient.New(5*time.Second, &SimpleLogger{})
+	checker := NewConcurrentLinkChecker(client, 4, &SimpleLogger{}, &SimpleMetricsCollector{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	checker.Start(ctx)
+	defer checker.Stop()
+
+	batchLinks := func(batch string) []models.Link {
+		links := make([]models.Link, 20)
+		for i := range links {
+			links[i] = models.Link{URL: fmt.Sprintf("%s/%s/%d", server.URL, batch, i)}
+		}
+		return links
+	}
+	headCtx := linkcheck.WithOptions(ctx, models.LinkCheckOptions{
+		Method:                models.LinkCheckHead,
+		AcceptableStatusCodes: []int{http.StatusForbidden},
+	})
+
+	var wg sync.WaitGroup
+	var headStatuses, defaultStatuses []models.LinkStatus
+	var headErr, defaultErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		headStatuses, headErr = checker.CheckLinks(headCtx, batchLinks("head"))
+	}()
+	go func() {
+		defer wg.Done()
+		defaultStatuses, defaultErr = checker.CheckLinks(ctx, batchLinks("default"))
+	}()
+	wg.Wait()
+	if headErr != nil || defaultErr != nil {
+		t.Fatalf("unexpected errors: %v, %v", headErr, defaultErr)
+	}
+
+	for _, status := range headStatuses {
+		if !status.Accessible || status.StatusCode != http.StatusForbidden {
+			t.Fatalf("%s: the 403 is acceptable to its batch, got %+v", status.Link.URL, status)
+		}
+	}
+	for _, status := range defaultStatuses {
+		if status.Accessible {
+			t.Fatalf("%s: the 403 is not acceptable to the default batch, got %+v", status.Link.URL, status)
+		}
+	}
+	if want := map[string]int{http.MethodHead: 20}; fmt.Sprint(methods["head"]) != fmt.Sprint(want) {
+		t.Fatalf("the head batch sent %v", methods["head"])
+	}
+	if want := map[string]int{http.MethodGet: 20}; fmt.Sprint(methods["default"]) != fmt.Sprint(want) {
+		t.Fatalf("the default batch sent %v", methods["default"])
+	}
+}
+
+func TestCheckLink_Options(t *testing.T) {
+	var flakyHits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky":
+			// Fails twice, then recovers
+			if flakyHits.Add(1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/moved":
+			http.Redirect(w, r, "/moved/again", http.StatusFound)
+		case "/moved/again":
+			http.Redirect(w, r, "/", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	client := httpclient.New(5*time.Second, &SimpleLogger{})
+	checker := NewConcurrentLinkChecker(client, 1, &SimpleLogger{}, &SimpleMetricsCollector{})
+	check := func(path string, opts models.LinkCheckOptions) models.LinkStatus {
+		ctx := linkcheck.WithOptions(context.Background(), opts)
+		return checker.CheckLink(ctx, models.Link{URL: server.URL + path})
+	}
+
+	if status := check("/flaky", models.LinkCheckOptions{Retries: 1}); status.Accessible {
+		t.Fatalf("one retry is not enough, got %+v", status)
+	}
+	flakyHits.Store(0)
+	if status := check("/flaky", models.LinkCheckOptions{Retries: 2}); !status.Accessible {
+		t.Fatalf("the second retry succeeds, got %+v", status)
+	}
+
+	if status := check("/slow", models.LinkCheckOptions{PerLinkTimeoutMs: 20}); status.Accessible || status.Error == "" {
+		t.Fatalf("the check times out, got %+v", status)
+	}
+
+	if status := check("/no-head", models.LinkCheckOptions{Method: models.LinkCheckHead}); status.Accessible {
+		t.Fatalf("HEAD alone is refused, got %+v", status)
+	}
+	if status := check("/no-head", models.LinkCheckOptions{Method: models.LinkCheckHeadThenGet}); !status.Accessible {
+		t.Fatalf("GET follows the refused HEAD, got %+v", status)
+	}
+
+	if status := check("/moved", models.LinkCheckOptions{MaxRedirects: 1}); status.Accessible || !strings.Contains(status.Error, "stopped after 1 redirects") {
+		t.Fatalf("one redirect is allowed, got %+v", status)
+	}
+	if status := check("/moved", models.LinkCheckOptions{MaxRedirects: 2}); !status.Accessible {
+		t.Fatalf("two redirects are allowed, got %+v", status)
+	}
+}
+
+// hedgeMetrics counts the hedged link checks and those the hedge won
+type hedgeMetrics struct {
+	SimpleMetricsCollector
+	hedged, won atomic.Int32
+}
+
+func (m *hedgeMetrics) RecordLinkCheckHedge(won bool) {
+	m.hedged.Add(1)
+	if won {
+		m.won.Add(1)
+	}
+}
+
+// slowFirstServer answers the first request to each path after a second,
+// or once it is cancelled, and every later one at once. It records when
+// each request arrived and whether the slow one was cancelled.
+type slowFirstServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	arrivals  map[string][]time.Time
+	cancelled atomic.Int32
+}
+
+func newSlowFirstServer(t *testing.T) *slowFirstServer {
+	s := &slowFirstServer{arrivals: make(map[string][]time.Time)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.arrivals[r.URL.Path] = append(s.arrivals[r.URL.Path], time.Now())
+		first := len(s.arrivals[r.URL.Path]) == 1
+		s.mu.Unlock()
+
+		if first {
+			select {
+			case <-r.Context().Done():
+				s.cancelled.Add(1)
+				return
+			case <-time.After(time.Second):
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *slowFirstServer) requests(path string) []time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.arrivals[path]
+}
+
+func TestCheckLink_Hedging(t *testing.T) {
+	t.Run("off by default", func(t *testing.T) {
+		server := newSlowFirstServer(t)
+		metrics := &hedgeMetrics{}
+		checker := NewConcurrentLinkChecker(httpclient.New(5*time.Second, &SimpleLogger{}), 1, &SimpleLogger{}, metrics)
+
+		status := checker.CheckLink(context.Background(), models.Link{URL: server.URL + "/page"})
+		if !status.Accessible || status.DurationMs < 900 {
+			t.Fatalf("the slow answer is waited for, got %+v", status)
+		}
+		if n := len(server.requests("/page")); n != 1 || metrics.hedged.Load() != 0 {
+			t.Fatalf("%d requests and %d hedges, want 1 and 0", n, metrics.hedged.Load())
+		}
+	})
+
+	t.Run("the hedge wins", func(t *testing.T) {
+		server := newSlowFirstServer(t)
+		metrics := &hedgeMetrics{}
+		checker := NewConcurrentLinkChecker(httpclient.New(5*time.Second, &SimpleLogger{}), 1, &SimpleLogger{}, metrics)
+		checker.SetHedgeDelay(50 * time.Millisecond)
+
+		status := checker.CheckLink(context.Background(), models.Link{URL: server.URL + "/page"})
+		if !status.Accessible || status.DurationMs >= 900 {
+			t.Fatalf("the hedge answers, got %+v", status)
+		}
+		arrivals := server.requests("/page")
+		if len(arrivals) != 2 || arrivals[1].Sub(arrivals[0]) < 50*time.Millisecond {
+			t.Fatalf("the hedge is sent after the hedge delay, got %v", arrivals)
+		}
+		if metrics.hedged.Load() != 1 || metrics.won.Load() != 1 {
+			t.Fatalf("%d hedges, %d won, want 1 and 1", metrics.hedged.Load(), metrics.won.Load())
+		}
+		deadline := time.Now().Add(time.Second)
+		for server.cancelled.Load() == 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if server.cancelled.Load() != 1 {
+			t.Fatal("the slow request is cancelled")
+		}
+	})
+
+	t.Run("a fast answer is not hedged", func(t *testing.T) {
+		var hits atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+		}))
+		defer server.Close()
+
+		metrics := &hedgeMetrics{}
+		checker := NewConcurrentLinkChecker(httpclient.New(5*time.Second, &SimpleLogger{}), 1, &SimpleLogger{}, metrics)
+		checker.SetHedgeDelay(50 * time.Millisecond)
+
+		if status := checker.CheckLink(context.Background(), models.Link{URL: server.URL}); !status.Accessible {
+			t.Fatalf("unexpected status %+v", status)
+		}
+		time.Sleep(100 * time.Millisecond)
+		if hits.Load() != 1 || metrics.hedged.Load() != 0 {
+			t.Fatalf("%d requests and %d hedges, want 1 and 0", hits.Load(), metrics.hedged.Load())
+		}
+	})
+
+	t.Run("one hedge per link, retries included", func(t *testing.T) {
+		var hits atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			time.Sleep(100 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		metrics := &hedgeMetrics{}
+		checker := NewConcurrentLinkChecker(httpclient.New(5*time.Second, &SimpleLogger{}), 1, &SimpleLogger{}, metrics)
+		checker.SetHedgeDelay(20 * time.Millisecond)
+		ctx := linkcheck.WithOptions(context.Background(), models.LinkCheckOptions{Retries: 2})
+
+		if status := checker.CheckLink(ctx, models.Link{URL: server.URL}); status.Accessible {
+			t.Fatalf("unexpected status %+v", status)
+		}
+		// Let the cancelled hedge reach the server, if it is going to
+		time.Sleep(150 * time.Millisecond)
+		if hits.Load() != 4 || metrics.hedged.Load() != 1 || metrics.won.Load() != 0 {
+			t.Fatalf("%d requests, %d hedges, %d won; want 4, 1 and 0", hits.Load(), metrics.hedged.Load(), metrics.won.Load())
+		}
+	})
+
+	t.Run("the hedge waits for the host's turn", func(t *testing.T) {
+		server := newSlowFirstServer(t)
+		metrics := &hedgeMetrics{}
+		checker := NewConcurrentLinkChecker(httpclient.New(5*time.Second, &SimpleLogger{}), 1, &SimpleLogger{}, metrics)
+		checker.SetHedgeDelay(20 * time.Millisecond)
+		checker.SetHostDelay(300 * time.Millisecond)
+
+		status := checker.CheckLink(context.Background(), models.Link{URL: server.URL + "/page"})
+		if !status.Accessible {
+			t.Fatalf("unexpected status %+v", status)
+		}
+		arrivals := server.requests("/page")
+		if len(arrivals) != 2 || arrivals[1].Sub(arrivals[0]) < 300*time.Millisecond {
+			t.Fatalf("the hedge keeps the host delay, got %v", arrivals)
+		}
+	})
+}
+
+func TestCheckLink_SetLinkTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The headers come at once, the rest of the body stalls
+		w.Header().Set("Content-Length", "10")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("hello"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	// The client only bounds the wait for headers; the checker's own
+	// deadline covers the body
+	client := httpclient.New(5*time.Second, &SimpleLogger{})
+	checker := NewConcurrentLinkChecker(client, 1, &SimpleLogger{}, &SimpleMetricsCollector{})
+	checker.SetLinkTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+	status := checker.CheckLink(context.Background(), models.Link{URL: server.URL})
+	if status.Accessible || !strings.Contains(status.Error, "failed to read response") {
+		t.Fatalf("the body read times out, got %+v", status)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Fatalf("the check took %s", elapsed)
+	}
+}
+
+func TestCheckLink_ReportsDuration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			time.Sleep(20 * time.Millisecond)
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused := "http://" + listener.Addr().String() + "/"
+	listener.Close()
+
+	client := httpclient.New(5*time.Second, &SimpleLogger{})
+	checker := NewConcurrentLinkChecker(client, 1, &SimpleLogger{}, &SimpleMetricsCollector{})
+	timeoutCtx := linkcheck.WithOptions(context.Background(), models.LinkCheckOptions{PerLinkTimeoutMs: 50})
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		url        string
+		accessible bool
+		min, max   float64
+	}{
+		{name: "success", ctx: context.Background(), url: server.URL + "/ok", accessible: true, min: 20, max: 1000},
+		{name: "HTTP error", ctx: context.Background(), url: server.URL + "/missing", min: 0, max: 1000},
+		{name: "transport error", ctx: context.Background(), url: refused, min: 0, max: 1000},
+		{name: "timeout", ctx: timeoutCtx, url: server.URL + "/slow", min: 50, max: 900},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := checker.CheckLink(tt.ctx, models.Link{URL: tt.url})
+			if status.Accessible != tt.accessible {
+				t.Fatalf("unexpected status %+v", status)
+			}
+			if status.DurationMs <= tt.min || status.DurationMs >= tt.max {
+				t.Fatalf("duration %.2fms is not between %.0fms and %.0fms", status.DurationMs, tt.min, tt.max)
+			}
+		})
+	}
+
+	// A link skipped for lack of budget was never checked
+	spent := budget.New(0, 0)
+	status := checker.CheckLink(budget.WithBudget(context.Background(), spent), models.Link{URL: server.URL + "/ok"})
+	if !status.Skipped || status.DurationMs != 0 {
+		t.Fatalf("expected a skipped link without duration, got %+v", status)
+	}
+}
+
+// dualStackResolver resolves every host to an unroutable IPv6 address ahead
+// of the loopback, as seen from an IPv4-only host
+type dualStackResolver struct{}
+
+func (dualStackResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return []net.IPAddr{{IP: net.ParseIP("100::1")}, {IP: net.ParseIP("127.0.0.1")}}, nil
+}
+
+func TestCheckLinks_ReportsDialAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	_, livePort, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, deadPort, _ := net.SplitHostPort(closed.Addr().String())
+	closed.Close()
+
+	client := httpclient.New(5*time.Second, &SimpleLogger{})
+	client.SetResolver(dualStackResolver{})
+	checker := NewConcurrentLinkChecker(client, 2, &SimpleLogger{}, &SimpleMetricsCollector{})
+
+	links := []models.Link{
+		{URL: "http://dual.example:" + livePort + "/"},
+		{URL: "http://dual.example:" + deadPort + "/"},
+	}
+	statuses, err := checker.CheckLinks(context.Background(), links)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !statuses[0].Accessible || statuses[0].DialAttempts != nil {
+		t.Fatalf("expected the link to be reached over IPv4, got %+v", statuses[0])
+	}
+
+	failed := statuses[1]
+	if failed.Accessible {
+		t.Fatalf("expected the closed port to fail, got %+v", failed)
+	}
+	var ipv4 *models.DialAttempt
+	for i, attempt := range failed.DialAttempts {
+		if attempt.Family == models.AddressFamilyIPv4 {
+			ipv4 = &failed.DialAttempts[i]
+		}
+	}
+	if ipv4 == nil || ipv4.Address != "127.0.0.1:"+deadPort || ipv4.Error == "" {
+		t.Fatalf("expected a failed IPv4 attempt, got %+v", failed.DialAttempts)
+	}
+}
+
+// BenchmarkCheckLinks_DebugLogging compares a 500-link batch at debug level
+// with and without sampling of the per-link debug lines
+func BenchmarkCheckLinks_DebugLogging(b *testing.B) {
+	links := make([]models.Link, 500)
+	for i := range links {
+		links[i] = models.Link{URL: fmt.Sprintf("https://example.com/page/%d", i), Type: models.LinkTypeInternal}
+	}
+
+	newChecker := func() *ConcurrentLinkChecker {
+		log := logger.NewAdapter(slog.New(slog.NewJSONHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug})))
+		return NewConcurrentLinkChecker(&SimpleHTTPClient{}, 10, log, &SimpleMetricsCollector{})
+	}
+
+	b.Run("sampled", func(b *testing.B) {
+		checker := newChecker()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			checker.CheckLinks(context.Background(), links)
+		}
+	})
+
+	b.Run("unsampled", func(b *testing.B) {
+		checker := newChecker()
+		// A sampler that never drops reproduces the previous behaviour
+		checker.linkLogger = logger.NewSampledLogger(checker.logger, logger.NewSampler(len(links)*3, 1, debugSampleInterval))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			checker.CheckLinks(context.Background(), links)
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package core
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/idn"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// MaxHostDelay bounds the politeness delay, configured or requested
+const MaxHostDelay = 10 * time.Second
+
+// maxPacedHosts is how many hosts the pacer remembers before it forgets
+// those whose last request is older than MaxHostDelay
+const maxPacedHosts = 1024
+
+// clock is the time source of the pacing, replaced in tests
+type clock interface {
+	Now() time.Time
+	// Sleep waits for d, or until ctx is done
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// hostPacer spaces out the requests to each host. It remembers when each
+// host was last requested, for every batch of the checker, so concurrent
+// batches share one pace per host. It is safe for concurrent use.
+type hostPacer struct {
+	clock clock
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newHostPacer(clock clock) *hostPacer {
+	return &hostPacer{clock: clock, last: make(map[string]time.Time)}
+}
+
+// readyAt returns when host may next be requested with delay between
+// requests
+func (p *hostPacer) readyAt(host string, delay time.Duration) time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	last, ok := p.last[host]
+	if !ok {
+		return time.Time{}
+	}
+	return last.Add(delay)
+}
+
+// reserve claims the next request to host and returns how long to wait
+// before making it
+func (p *hostPacer) reserve(host string, delay time.Duration) time.Duration {
+	now := p.clock.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.last) >= maxPacedHosts {
+		for h, last := range p.last {
+			if now.Sub(last) > MaxHostDelay {
+				delete(p.last, h)
+			}
+		}
+	}
+
+	start := now
+	if last, ok := p.last[host]; ok && last.Add(delay).After(now) {
+		start = last.Add(delay)
+	}
+	p.last[host] = start
+	return start.Sub(now)
+}
+
+// hostKey is the host a link's requests are paced under: lower case and in
+// ASCII form, without port or trailing dot
+func hostKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return rawURL
+	}
+	return strings.TrimSuffix(idn.ASCIIHost(u.Hostname()), ".")
+}
+
+type hostDelayKey struct{}
+
+// WithHostDelay returns a context whose link checks use delay between
+// requests to the same host instead of the checker's own
+func WithHostDelay(ctx context.Context, delay time.Duration) context.Context {
+	return context.WithValue(ctx, hostDelayKey{}, min(max(delay, 0), MaxHostDelay))
+}
+
+// hostDelay returns the delay between requests to one host for checks made
+// under ctx
+func (c *ConcurrentLinkChecker) hostDelay(ctx context.Context) time.Duration {
+	if delay, ok := ctx.Value(hostDelayKey{}).(time.Duration); ok {
+		return delay
+	}
+	return c.defaultHostDelay
+}
+
+// pace waits for the turn of link's host
+func (c *ConcurrentLinkChecker) pace(ctx context.Context, link models.Link) error {
+	delay := c.hostDelay(ctx)
+	if delay <= 0 {
+		return nil
+	}
+	return c.sleep(ctx, c.pacer.reserve(hostKey(link.URL), delay))
+}
+
+// sleep waits for d, if positive, or until ctx is done
+func (c *ConcurrentLinkChecker) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	return c.clock.Sleep(ctx, d)
+}
+
+// feedPaced submits links to queue, handing out first the links whose host's
+// turn has come so that workers check other hosts while one waits. Only when
+// no host is ready does a worker take a link and wait for its turn. Links of
+// one host keep their order. queue must be unbuffered: a link's turn is
+// reserved once a worker has taken it, and sent on the job's turn channel.
+func (c *ConcurrentLinkChecker) feedPaced(ctx context.Context, links []models.Link, delay time.Duration, queue chan<- linkCheckJob) {
+	var hosts []string
+	pending := make(map[string][]models.Link)
+	for _, link := range links {
+		host := hostKey(link.URL)
+		if _, ok := pending[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		pending[host] = append(pending[host], link)
+	}
+
+	// Every link counts as queued until a worker takes it
+	remaining := len(links)
+	c.metrics.AddLinkChecksQueued(remaining)
+	defer func() { c.metrics.AddLinkChecksQueued(-remaining) }()
+
+	for len(hosts) > 0 {
+		// The host that is ready first, preferring hosts seen earlier
+		next, readyAt := 0, c.pacer.readyAt(hosts[0], delay)
+		for i := 1; i < len(hosts) && readyAt.After(c.clock.Now()); i++ {
+			if at := c.pacer.readyAt(hosts[i], delay); at.Before(readyAt) {
+				next, readyAt = i, at
+			}
+		}
+
+		host := hosts[next]
+		job := linkCheckJob{ctx: ctx, link: pending[host][0], turn: make(chan time.Duration, 1)}
+		select {
+		case queue <- job:
+		case <-ctx.Done():
+			return
+		}
+		job.turn <- c.pacer.reserve(host, delay)
+		remaining--
+		if pending[host] = pending[host][1:]; len(pending[host]) == 0 {
+			hosts = append(hosts[:next], hosts[next+1:]...)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+// fakeClock only moves when slept on, so pacing waits take no real time
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+	return nil
+}
+
+// pacedRequest is a request seen by pacingClient, at fake clock time
+type pacedRequest struct {
+	url string
+	at  time.Time
+}
+
+// pacingClient records when each URL was requested
+type pacingClient struct {
+	SimpleHTTPClient
+	clock *fakeClock
+
+	mu       sync.Mutex
+	requests map[string][]pacedRequest
+}
+
+func (c *pacingClient) Get(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	host := hostKey(url)
+	c.requests[host] = append(c.requests[host], pacedRequest{url: url, at: c.clock.Now()})
+	return &models.HTTPResponse{StatusCode: 200}, nil
+}
+
+func newPacedChecker(workers int, delay time.Duration) (*ConcurrentLinkChecker, *pacingClient, *fakeClock) {
+	clock := newFakeClock()
+	client := &pacingClient{clock: clock, requests: make(map[string][]pacedRequest)}
+	checker := NewConcurrentLinkChecker(client, workers, &SimpleLogger{}, &SimpleMetricsCollector{})
+	checker.clock = clock
+	checker.pacer = newHostPacer(clock)
+	checker.SetHostDelay(delay)
+	return checker, client, clock
+}
+
+// pacedLinks lists perHost links on each of hosts, grouped by host
+func pacedLinks(hosts []string, perHost int) []models.Link {
+	var links []models.Link
+	for _, host := range hosts {
+		for i := range perHost {
+			links = append(links, models.Link{URL: fmt.Sprintf("https://%s/%d", host, i)})
+		}
+	}
+	return links
+}
+
+func TestCheckLinks_PacesRequestsPerHost(t *testing.T) {
+	const delay = 500 * time.Millisecond
+	// One worker keeps the fake clock in step with the requests
+	checker, client, clock := newPacedChecker(1, delay)
+	hosts := []string{"a.example", "b.example", "c.example"}
+	links := pacedLinks(hosts, 4)
+	start := clock.Now()
+
+	results, err := checker.CheckLinks(context.Background(), links)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Results keep the input order
+	if len(results) != len(links) {
+		t.Fatalf("expected %d results, got %d", len(links), len(results))
+	}
+	for i, status := range results {
+		if status.Link.URL != links[i].URL || !status.Accessible {
+			t.Fatalf("result %d: got %+v, want accessible %s", i, status, links[i].URL)
+		}
+	}
+
+	for _, host := range hosts {
+		requests := client.requests[host]
+		if len(requests) != 4 {
+			t.Fatalf("%s: expected 4 requests, got %d", host, len(requests))
+		}
+		for i, request := range requests {
+			// Links of one host are checked in their order
+			if want := fmt.Sprintf("https://%s/%d", host, i); request.url != want {
+				t.Fatalf("%s: request %d was %s, want %s", host, i, request.url, want)
+			}
+			if i == 0 {
+				continue
+			}
+			if gap := request.at.Sub(requests[i-1].at); gap < delay {
+				t.Fatalf("%s: requests %d and %d only %s apart", host, i-1, i, gap)
+			}
+		}
+	}
+
+	// The hosts were interleaved: one host's four requests take three
+	// delays, where checking host after host would take nine
+	if elapsed := clock.Now().Sub(start); elapsed > 4*delay {
+		t.Fatalf("checking took %s, hosts were not interleaved", elapsed)
+	}
+}
+
+func TestCheckLinks_HostDelayOverride(t *testing.T) {
+	tests := []struct {
+		name     string
+		override time.Duration
+		wantGap  time.Duration
+	}{
+		{name: "longer", override: 2 * time.Second, wantGap: 2 * time.Second},
+		{name: "disabled", override: 0, wantGap: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, client, _ := newPacedChecker(1, 500*time.Millisecond)
+			ctx := WithHostDelay(context.Background(), tt.override)
+
+			if _, err := checker.CheckLinks(ctx, pacedLinks([]string{"a.example"}, 3)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			requests := client.requests["a.example"]
+			if len(requests) != 3 {
+				t.Fatalf("expected 3 requests, got %d", len(requests))
+			}
+			for i := 1; i < len(requests); i++ {
+				if gap := requests[i].at.Sub(requests[i-1].at); gap < tt.wantGap || (tt.wantGap == 0 && gap != 0) {
+					t.Fatalf("requests %d and %d %s apart, want %s", i-1, i, gap, tt.wantGap)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckLink_SharesPaceAcrossCalls(t *testing.T) {
+	const delay = time.Second
+	checker, client, _ := newPacedChecker(1, delay)
+
+	for range 3 {
+		checker.CheckLink(context.Background(), models.Link{URL: "https://A.example:8443/page"})
+	}
+
+	requests := client.requests["a.example"]
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(requests))
+	}
+	if gap := requests[2].at.Sub(requests[0].at); gap != 2*delay {
+		t.Fatalf("three requests spanned %s, want %s", gap, 2*delay)
+	}
+}
+
+func TestHostPacer_ForgetsIdleHosts(t *testing.T) {
+	clock := newFakeClock()
+	pacer := newHostPacer(clock)
+
+	for i := range maxPacedHosts {
+		pacer.reserve(fmt.Sprintf("host-%d.example", i), time.Second)
+	}
+	clock.Sleep(context.Background(), MaxHostDelay+time.Second)
+	pacer.reserve("fresh.example", time.Second)
+
+	pacer.mu.Lock()
+	defer pacer.mu.Unlock()
+	if len(pacer.last) != 1 {
+		t.Fatalf("expected only the fresh host to be remembered, got %d hosts", len(pacer.last))
+	}
+}
+
+func TestHostKey(t *testing.T) {
+	tests := map[string]string{
+		"https://Example.COM/page":        "example.com",
+		"https://example.com:8443/a?b=c":  "example.com",
+		"http://example.com./":            "example.com",
+		"https://Bücher.example/neu":      "xn--bcher-kva.example",
+		"https://xn--bcher-kva.example./": "xn--bcher-kva.example",
+		"mailto:someone@example.com":      "mailto:someone@example.com",
+	}
+	for raw, want := range tests {
+		if got := hostKey(raw); got != want {
+			t.Fatalf("hostKey(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
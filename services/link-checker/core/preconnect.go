@@ -0,0 +1,105 @@
+package core
+
+import (
+	"cmp"
+	"context"
+	"net/url"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/idn"
+	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"golang.org/x/sync/errgroup"
+)
+
+// MaxPreconnectHosts bounds how many hosts of a batch are preconnected
+const MaxPreconnectHosts = 64
+
+// preconnectConcurrency is how many hosts are connected to at once
+const preconnectConcurrency = 16
+
+// preconnectTimeout bounds the preconnect phase, so a slow host holds the
+// batch up for no longer than this
+const preconnectTimeout = 2 * time.Second
+
+// SetPreconnectHosts has each batch connect to the n hosts with the most
+// links, several at once, before its links are checked, so that their
+// checks find the lookups and handshakes done. It needs an HTTP client that
+// implements interfaces.PreconnectingHTTPClient. Preconnecting sends no
+// request, so it is neither paced nor charged to a budget. Zero, the
+// default, turns it off.
+func (c *ConcurrentLinkChecker) SetPreconnectHosts(n int) {
+	c.preconnectHosts = min(max(n, 0), MaxPreconnectHosts)
+}
+
+// preconnect connects to the busiest hosts of links and logs how long it
+// took. Failures are left for the checks of those links to report.
+func (c *ConcurrentLinkChecker) preconnect(ctx context.Context, links []models.Link) {
+	client, ok := c.httpClient.(interfaces.PreconnectingHTTPClient)
+	if !ok || c.preconnectHosts == 0 {
+		return
+	}
+	origins := busiestOrigins(links, c.preconnectHosts)
+	if len(origins) == 0 {
+		return
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, preconnectTimeout)
+	defer cancel()
+
+	var connected atomic.Int32
+	g := &errgroup.Group{}
+	g.SetLimit(preconnectConcurrency)
+	for _, origin := range origins {
+		g.Go(func() error {
+			if err := client.Preconnect(ctx, origin); err == nil {
+				connected.Add(1)
+			}
+			return nil
+		})
+	}
+	g.Wait()
+
+	c.logger.Info("Preconnected link hosts",
+		"host_count", len(origins),
+		"connected_count", connected.Load(),
+		"duration", time.Since(start),
+	)
+}
+
+// busiestOrigins returns the scheme and host of the n origins with the
+// most links, the first seen first among equals
+func busiestOrigins(links []models.Link, n int) []string {
+	type origin struct {
+		url   string
+		links int
+	}
+	var origins []*origin
+	byURL := make(map[string]*origin)
+	for _, link := range links {
+		u, err := url.Parse(link.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			continue
+		}
+		key := u.Scheme + "://" + idn.ASCIIHost(u.Host)
+		o, ok := byURL[key]
+		if !ok {
+			o = &origin{url: key}
+			byURL[key] = o
+			origins = append(origins, o)
+		}
+		o.links++
+	}
+
+	slices.SortStableFunc(origins, func(a, b *origin) int {
+		return cmp.Compare(b.links, a.links)
+	})
+	urls := make([]string, 0, min(n, len(origins)))
+	for _, o := range origins[:min(n, len(origins))] {
+		urls = append(urls, o.url)
+	}
+	return urls
+}
@@ -0,0 +1,179 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+)
+
+func TestBusiestOrigins(t *testing.T) {
+	links := []models.Link{
+		{URL: "https://b.example/1"},
+		{URL: "https://a.example/1"},
+		{URL: "https://A.example/2"},
+		{URL: "http://a.example/3"},
+		{URL: "https://b.example:8443/1"},
+		{URL: "https://b.example/2"},
+		{URL: "mailto:someone@example.com"},
+		{URL: "://broken"},
+		{URL: "https://c.example/1"},
+	}
+
+	got := busiestOrigins(links, 3)
+	want := []string{"https://b.example", "https://a.example", "http://a.example"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := busiestOrigins(links, 10); len(got) != 5 {
+		t.Fatalf("expected every origin, got %v", got)
+	}
+	if got := busiestOrigins(nil, 3); len(got) != 0 {
+		t.Fatalf("expected no origins, got %v", got)
+	}
+}
+
+// preconnectingClient is a pacingClient that records its preconnects and
+// whether each request found its origin preconnected
+type preconnectingClient struct {
+	*pacingClient
+
+	mu          sync.Mutex
+	preconnects []string
+	warm        map[string]bool
+}
+
+func (c *preconnectingClient) Preconnect(ctx context.Context, url string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.preconnects = append(c.preconnects, url)
+	return nil
+}
+
+func (c *preconnectingClient) Get(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	c.mu.Lock()
+	c.warm[url] = slices.Contains(c.preconnects, "https://"+hostKey(url))
+	c.mu.Unlock()
+	return c.pacingClient.Get(ctx, url)
+}
+
+func TestCheckLinks_PreconnectsBusiestHosts(t *testing.T) {
+	const delay = time.Second
+	checker, pacing, clock := newPacedChecker(1, delay)
+	client := &preconnectingClient{pacingClient: pacing, warm: make(map[string]bool)}
+	checker.httpClient = client
+	checker.SetPreconnectHosts(2)
+
+	links := append(pacedLinks([]string{"a.example"}, 3), pacedLinks([]string{"b.example", "c.example"}, 2)...)
+	links = append(links, pacedLinks([]string{"d.example"}, 1)...)
+	start := clock.Now()
+	if _, err := checker.CheckLinks(context.Background(), links); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The hosts are preconnected concurrently
+	slices.Sort(client.preconnects)
+	if want := []string{"https://a.example", "https://b.example"}; !slices.Equal(client.preconnects, want) {
+		t.Fatalf("preconnected %v, want %v", client.preconnects, want)
+	}
+	for _, link := range links {
+		want := hostKey(link.URL) == "a.example" || hostKey(link.URL) == "b.example"
+		if client.warm[link.URL] != want {
+			t.Fatalf("%s: preconnected before its check %t, want %t", link.URL, client.warm[link.URL], want)
+		}
+	}
+
+	// Preconnecting is not a request: the first request to each host is
+	// still made at once
+	for host, requests := range pacing.requests {
+		if requests[0].at != start {
+			t.Fatalf("%s: first request waited %s", host, requests[0].at.Sub(start))
+		}
+	}
+}
+
+func TestCheckLinks_PreconnectIsOffByDefault(t *testing.T) {
+	checker, pacing, _ := newPacedChecker(1, 0)
+	client := &preconnectingClient{pacingClient: pacing, warm: make(map[string]bool)}
+	checker.httpClient = client
+
+	if _, err := checker.CheckLinks(context.Background(), pacedLinks([]string{"a.example"}, 2)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.preconnects) != 0 {
+		t.Fatalf("preconnected %v", client.preconnects)
+	}
+}
+
+// newTLSFarm starts hosts TLS servers whose handshakes each take latency,
+// as if the servers were that far away, and returns them with a pool
+// trusting their certificate
+func newTLSFarm(b *testing.B, hosts int, latency time.Duration) ([]*httptest.Server, *x509.CertPool) {
+	b.Helper()
+	roots := x509.NewCertPool()
+	servers := make([]*httptest.Server, hosts)
+	for i := range servers {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		server.Config.ErrorLog = log.New(io.Discard, "", 0)
+		server.TLS = &tls.Config{GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			time.Sleep(latency)
+			return nil, nil
+		}}
+		server.StartTLS()
+		b.Cleanup(server.Close)
+		roots.AddCert(server.Certificate())
+		servers[i] = server
+	}
+	return servers, roots
+}
+
+// BenchmarkCheckLinks_Preconnect checks a batch spread over a farm of TLS
+// hosts with fewer workers than hosts, from a cold client each time
+func BenchmarkCheckLinks_Preconnect(b *testing.B) {
+	const (
+		hosts        = 16
+		linksPerHost = 4
+		workers      = 4
+	)
+	servers, roots := newTLSFarm(b, hosts, 20*time.Millisecond)
+	var links []models.Link
+	for i := range linksPerHost {
+		for _, server := range servers {
+			links = append(links, models.Link{URL: fmt.Sprintf("%s/%d", server.URL, i)})
+		}
+	}
+
+	for _, preconnectHosts := range []int{0, hosts} {
+		b.Run(fmt.Sprintf("preconnect_hosts=%d", preconnectHosts), func(b *testing.B) {
+			for b.Loop() {
+				client := httpclient.New(5*time.Second, &SimpleLogger{})
+				client.SetTLSConfig(&tls.Config{RootCAs: roots})
+				checker := NewConcurrentLinkChecker(client, workers, &SimpleLogger{}, &SimpleMetricsCollector{})
+				checker.SetPreconnectHosts(preconnectHosts)
+
+				statuses, err := checker.CheckLinks(context.Background(), links)
+				if err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+				for _, status := range statuses {
+					if !status.Accessible {
+						b.Fatalf("%s: %s", status.Link.URL, status.Error)
+					}
+				}
+			}
+		})
+	}
+}
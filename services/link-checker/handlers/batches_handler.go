@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+
+	"github.com/RuvinSL/webpage-analyzer/services/link-checker/core"
+)
+
+// BatchesHandler serves the bookkeeping of the running link check batches,
+// to tell a batch that is slow from one whose goroutines never exit
+type BatchesHandler struct {
+	batches func() []core.BatchInfo
+}
+
+// batchesResponse is the body of GET /debug/batches
+type batchesResponse struct {
+	Goroutines int              `json:"goroutines"`
+	Batches    []core.BatchInfo `json:"batches"`
+}
+
+// NewBatchesHandler reports the batches returned by batches
+func NewBatchesHandler(batches func() []core.BatchInfo) *BatchesHandler {
+	return &BatchesHandler{batches: batches}
+}
+
+// Batches handles GET /debug/batches
+func (h *BatchesHandler) Batches(w http.ResponseWriter, r *http.Request) {
+	response := batchesResponse{
+		Goroutines: runtime.NumGoroutine(),
+		Batches:    h.batches(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/metrics"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/services/link-checker/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+// okHTTPClient answers every request with 200
+type okHTTPClient struct{}
+
+func (okHTTPClient) Get(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	return &models.HTTPResponse{StatusCode: http.StatusOK}, nil
+}
+
+func (okHTTPClient) Head(ctx context.Context, url string) (*models.HTTPResponse, error) {
+	return &models.HTTPResponse{StatusCode: http.StatusOK}, nil
+}
+
+func TestLinkHandler_RejectsOversizedBatchesWithoutLeaks(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	checker := core.NewConcurrentLinkChecker(okHTTPClient{}, 4, &TestLogger{}, metrics.Nop{})
+	handler := NewLinkHandler(checker, &TestLogger{})
+	handler.SetMaxLinks(1)
+
+	for _, path := range []string{"/check", "/check/stream"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", path, bytes.NewReader(streamRequestBody(t, "https://example.com/a", "https://example.com/b")))
+		if path == "/check" {
+			handler.CheckLinks(w, req)
+		} else {
+			handler.CheckLinksStream(w, req)
+		}
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, path)
+	}
+
+	// A batch within the limit is checked and leaves nothing behind either
+	w := httptest.NewRecorder()
+	handler.CheckLinks(w, httptest.NewRequest("POST", "/check", bytes.NewReader(streamRequestBody(t, "https://example.com/a"))))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, checker.Batches())
+}
+
+func TestBatchesHandler(t *testing.T) {
+	batches := []core.BatchInfo{{ID: 7, Links: 600, Emitted: 120, Chunk: 1, Chunks: 2, Goroutines: 9}}
+	handler := NewBatchesHandler(func() []core.BatchInfo { return batches })
+
+	w := httptest.NewRecorder()
+	handler.Batches(w, httptest.NewRequest("GET", "/debug/batches", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var response struct {
+		Goroutines int              `json:"goroutines"`
+		Batches    []core.BatchInfo `json:"batches"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Positive(t, response.Goroutines)
+	assert.Equal(t, batches, response.Batches)
+}
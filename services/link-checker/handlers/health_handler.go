@@ -7,11 +7,13 @@ import (
 	"time"
 
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/version"
 )
 
 type HealthHandler struct {
 	serviceName string
 	startTime   time.Time
+	maxLinks    int
 }
 
 func NewHealthHandler(serviceName string) *HealthHandler {
@@ -21,17 +23,26 @@ func NewHealthHandler(serviceName string) *HealthHandler {
 	}
 }
 
+// SetMaxLinks advertises the per-request link limit so callers can size
+// their batches
+func (h *HealthHandler) SetMaxLinks(n int) {
+	h.maxLinks = n
+}
+
 func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 
 	// Build response
 	response := models.HealthStatus{
 		Status:    "healthy",
 		Service:   h.serviceName,
-		Version:   "1.0.0",
+		Version:   version.Get().Version,
 		Uptime:    formatDuration(time.Since(h.startTime)),
 		Checks:    map[string]string{},
 		Timestamp: time.Now(),
 	}
+	if h.maxLinks > 0 {
+		response.Limits = map[string]int{models.LimitMaxLinksPerRequest: h.maxLinks}
+	}
 
 	// Send response
 	w.Header().Set("Content-Type", "application/json")
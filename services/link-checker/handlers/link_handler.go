@@ -1,18 +1,29 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/budget"
+	"github.com/RuvinSL/webpage-analyzer/pkg/contextkeys"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/linkcheck"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/trace"
+	"github.com/RuvinSL/webpage-analyzer/pkg/traffic"
+	"github.com/RuvinSL/webpage-analyzer/services/link-checker/core"
 )
 
 // LinkHandler handles link checking requests
 type LinkHandler struct {
 	linkChecker interfaces.LinkChecker
 	logger      interfaces.Logger
+	maxLinks    int
 }
 
 // NewLinkHandler creates a new link handler
@@ -23,38 +34,32 @@ func NewLinkHandler(linkChecker interfaces.LinkChecker, logger interfaces.Logger
 	}
 }
 
+// SetMaxLinks rejects batches of more than n links; zero means no limit
+func (h *LinkHandler) SetMaxLinks(n int) {
+	h.maxLinks = n
+}
+
 // CheckLinks handles batch link checking
 func (h *LinkHandler) CheckLinks(w http.ResponseWriter, r *http.Request) {
 
-	ctx := r.Context()
-
-	// Parse request
-	var req struct {
-		Links []models.Link `json:"links"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to parse request", "error", err)
-		h.sendError(w, "Invalid request format", http.StatusBadRequest)
-		return
-	}
-
-	// Validate request
-	if len(req.Links) == 0 {
-		h.sendError(w, "No links provided", http.StatusBadRequest)
+	batch, ok := h.decodeLinks(w, r)
+	if !ok {
 		return
 	}
+	links := batch.links
+	ctx := batch.context(r.Context())
 
 	// Extract request ID for logging
-	requestID := r.Header.Get("X-Request-ID")
+	requestID := r.Header.Get(contextkeys.RequestIDHeader)
+	ctx = contextkeys.WithRequestID(ctx, requestID)
 	h.logger.Info("Processing batch link check request",
-		"link_count", len(req.Links),
+		"link_count", len(links),
 		"request_id", requestID,
 	)
 
 	// Check links
 	start := time.Now()
-	statuses, err := h.linkChecker.CheckLinks(ctx, req.Links)
+	statuses, err := h.linkChecker.CheckLinks(ctx, links)
 
 	if err != nil {
 		h.logger.Error("Failed to check links",
@@ -67,8 +72,9 @@ func (h *LinkHandler) CheckLinks(w http.ResponseWriter, r *http.Request) {
 
 	duration := time.Since(start)
 	h.logger.Info("Batch link check completed",
-		"link_count", len(req.Links),
+		"link_count", len(links),
 		"duration", duration,
+		"bytes", batch.meter.Bytes(),
 		"request_id", requestID,
 	)
 
@@ -77,10 +83,23 @@ func (h *LinkHandler) CheckLinks(w http.ResponseWriter, r *http.Request) {
 		LinkStatuses []models.LinkStatus `json:"link_statuses"`
 		CheckedAt    time.Time           `json:"checked_at"`
 		Duration     string              `json:"duration"`
+		BudgetUsed   *models.BudgetUsage `json:"budget_used,omitempty"`
+		Trace        *models.DebugTrace  `json:"trace,omitempty"`
+		// BytesDownloaded is the body bytes the checks read, for cost
+		// accounting
+		BytesDownloaded int64 `json:"bytes_downloaded"`
 	}{
-		LinkStatuses: statuses,
-		CheckedAt:    time.Now(),
-		Duration:     duration.String(),
+		LinkStatuses:    statuses,
+		CheckedAt:       time.Now(),
+		Duration:        duration.String(),
+		BytesDownloaded: batch.meter.Bytes(),
+	}
+	if batch.spend != nil {
+		used := batch.spend.Usage()
+		response.BudgetUsed = &used
+	}
+	if batch.trace != nil {
+		response.Trace = batch.trace.Report()
 	}
 
 	// Send response
@@ -92,6 +111,184 @@ func (h *LinkHandler) CheckLinks(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// CheckLinksStream handles batch link checking with the statuses streamed
+// back as NDJSON, one LinkStatus per line in completion order. Once the
+// stream has started, failures can only end it early, so callers compare
+// the number of lines to the number of links they sent. A budget in the
+// request is honored, but its usage and the bytes downloaded are not
+// reported back, and no trace is kept.
+func (h *LinkHandler) CheckLinksStream(w http.ResponseWriter, r *http.Request) {
+	batch, ok := h.decodeLinks(w, r)
+	if !ok {
+		return
+	}
+	// The stream has nowhere to report a trace
+	batch.trace = nil
+	links := batch.links
+	ctx := batch.context(r.Context())
+
+	requestID := r.Header.Get(contextkeys.RequestIDHeader)
+	ctx = contextkeys.WithRequestID(ctx, requestID)
+	h.logger.Info("Processing streaming link check request",
+		"link_count", len(links),
+		"request_id", requestID,
+	)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// Send the headers straight away so the caller is not left waiting for
+	// the first status
+	rc := http.NewResponseController(w)
+	writeErr := rc.Flush()
+	encoder := json.NewEncoder(w)
+	sent := 0
+	emit := func(status models.LinkStatus) {
+		if writeErr != nil {
+			return
+		}
+		if writeErr = encoder.Encode(status); writeErr == nil {
+			writeErr = rc.Flush()
+		}
+		if writeErr == nil {
+			sent++
+		}
+	}
+
+	start := time.Now()
+	var err error
+	if streamer, ok := h.linkChecker.(interfaces.StreamingLinkChecker); ok {
+		err = streamer.CheckLinksStream(ctx, links, emit)
+	} else {
+		var statuses []models.LinkStatus
+		statuses, err = h.linkChecker.CheckLinks(ctx, links)
+		for _, status := range statuses {
+			emit(status)
+		}
+	}
+
+	switch {
+	case err != nil:
+		h.logger.Error("Failed to check links", "error", err, "request_id", requestID)
+	case writeErr != nil:
+		h.logger.Warn("Link status stream ended early",
+			"error", writeErr,
+			"sent_count", sent,
+			"link_count", len(links),
+			"request_id", requestID,
+		)
+	default:
+		h.logger.Info("Streaming link check completed",
+			"link_count", len(links),
+			"duration", time.Since(start),
+			"request_id", requestID,
+		)
+	}
+}
+
+// batch is a decoded batch request. Its budget and trace are nil unless
+// the caller asked for them.
+type batch struct {
+	links []models.Link
+	spend *budget.Budget
+	trace *trace.Collector
+	// hostDelay overrides the checker's delay between requests to a host
+	hostDelay *time.Duration
+	// options tune the checks of this batch
+	options *models.LinkCheckOptions
+	// hostOverrides are the addresses the links' hosts are dialed at
+	hostOverrides map[string]string
+	// meter counts the bytes the checks download
+	meter *traffic.Meter
+}
+
+// context counts the bytes the link checks made under ctx download and
+// charges them to the batch's budget, records them in its trace, paces them
+// by its host delay, tunes them by its options and connects them to its
+// host overrides, when there are ones
+func (b batch) context(ctx context.Context) context.Context {
+	ctx = traffic.WithMeter(ctx, b.meter)
+	if b.spend != nil {
+		ctx = budget.WithBudget(ctx, b.spend)
+	}
+	if b.trace != nil {
+		ctx = trace.WithCollector(ctx, b.trace)
+	}
+	if b.hostDelay != nil {
+		ctx = core.WithHostDelay(ctx, *b.hostDelay)
+	}
+	if b.options != nil {
+		ctx = linkcheck.WithOptions(ctx, *b.options)
+	}
+	return httpclient.WithHostOverrides(ctx, b.hostOverrides)
+}
+
+// decodeLinks parses and validates a batch request, sending the error
+// response itself when the batch is rejected
+func (h *LinkHandler) decodeLinks(w http.ResponseWriter, r *http.Request) (batch, bool) {
+	var req struct {
+		Links  []models.Link        `json:"links"`
+		Budget *models.BudgetLimits `json:"budget,omitempty"`
+		// TraceLimit is how many outbound requests to list in the trace;
+		// without it no trace is kept
+		TraceLimit *int `json:"trace_limit,omitempty"`
+		// HostDelayMs overrides the delay between requests to one host
+		HostDelayMs *int64 `json:"host_delay_ms,omitempty"`
+		// Options tune the checks of this batch only
+		Options *models.LinkCheckOptions `json:"options,omitempty"`
+		// HostOverrides are those of the analysis, whose policy the
+		// analyzer has already applied
+		HostOverrides map[string]string `json:"host_overrides,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to parse request", "error", err)
+		h.sendError(w, "Invalid request format", http.StatusBadRequest)
+		return batch{}, false
+	}
+
+	// Validate request
+	if len(req.Links) == 0 {
+		h.sendError(w, "No links provided", http.StatusBadRequest)
+		return batch{}, false
+	}
+	if h.maxLinks > 0 && len(req.Links) > h.maxLinks {
+		h.logger.Warn("Rejected oversized batch",
+			"link_count", len(req.Links),
+			"max_links", h.maxLinks,
+			"request_id", r.Header.Get(contextkeys.RequestIDHeader),
+		)
+		h.sendError(w, fmt.Sprintf("Too many links: %d exceeds the limit of %d per request", len(req.Links), h.maxLinks), http.StatusRequestEntityTooLarge)
+		return batch{}, false
+	}
+	if req.HostDelayMs != nil && (*req.HostDelayMs < 0 || *req.HostDelayMs > core.MaxHostDelay.Milliseconds()) {
+		h.sendError(w, fmt.Sprintf("host_delay_ms must be between 0 and %d", core.MaxHostDelay.Milliseconds()), http.StatusBadRequest)
+		return batch{}, false
+	}
+	if err := models.ValidateLinkCheckOptions(req.Options); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return batch{}, false
+	}
+	if err := models.ValidateHostOverrides(req.HostOverrides); err != nil {
+		h.sendError(w, err.Error(), http.StatusBadRequest)
+		return batch{}, false
+	}
+
+	decoded := batch{links: req.Links, options: req.Options, hostOverrides: req.HostOverrides, meter: &traffic.Meter{}}
+	if req.Budget != nil {
+		decoded.spend = budget.FromLimits(*req.Budget)
+	}
+	if req.TraceLimit != nil {
+		decoded.trace = trace.New(*req.TraceLimit)
+	}
+	if req.HostDelayMs != nil {
+		delay := time.Duration(*req.HostDelayMs) * time.Millisecond
+		decoded.hostDelay = &delay
+	}
+	return decoded, true
+}
+
 // CheckSingleLink handles single link checking
 func (h *LinkHandler) CheckSingleLink(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -114,9 +311,10 @@ func (h *LinkHandler) CheckSingleLink(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract request ID for logging
-	requestID := r.Header.Get("X-Request-ID")
+	requestID := r.Header.Get(contextkeys.RequestIDHeader)
+	ctx = contextkeys.WithRequestID(ctx, requestID)
 	h.logger.Info("Processing single link check request",
-		"url", req.Link.URL,
+		"url", logger.RedactURL(req.Link.URL),
 		"request_id", requestID,
 	)
 
@@ -126,7 +324,7 @@ func (h *LinkHandler) CheckSingleLink(w http.ResponseWriter, r *http.Request) {
 	duration := time.Since(start)
 
 	h.logger.Info("Single link check completed",
-		"url", req.Link.URL,
+		"url", logger.RedactURL(req.Link.URL),
 		"accessible", status.Accessible,
 		"status_code", status.StatusCode,
 		"duration", duration,
@@ -5,14 +5,20 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/budget"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/linkcheck"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/trace"
+	"github.com/RuvinSL/webpage-analyzer/pkg/traffic"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -278,6 +284,428 @@ func TestLinkHandler_CheckLinks_EmptyLinks(t *testing.T) {
 	assert.Empty(t, logger.InfoCalls)
 }
 
+func TestLinkHandler_CheckLinks_TooManyLinks(t *testing.T) {
+	tests := []struct {
+		name       string
+		linkCount  int
+		wantStatus int
+	}{
+		{name: "at the limit", linkCount: 3, wantStatus: http.StatusOK},
+		{name: "over the limit", linkCount: 4, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			linkChecker := &MockLinkChecker{
+				CheckLinksFunc: func(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+					called = true
+					return []models.LinkStatus{}, nil
+				},
+			}
+			handler := NewLinkHandler(linkChecker, &TestLogger{})
+			handler.SetMaxLinks(3)
+
+			links := make([]models.Link, tt.linkCount)
+			for i := range links {
+				links[i] = models.Link{URL: "https://example.com", Type: models.LinkTypeExternal}
+			}
+			body, err := json.Marshal(map[string]any{"links": links})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			handler.CheckLinks(w, httptest.NewRequest("POST", "/check", bytes.NewReader(body)))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.True(t, called)
+				return
+			}
+
+			assert.False(t, called, "oversized batches must not reach the checker")
+			var errorResp models.ErrorResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResp))
+			assert.Equal(t, "Too many links: 4 exceeds the limit of 3 per request", errorResp.Error)
+			assert.Equal(t, http.StatusRequestEntityTooLarge, errorResp.StatusCode)
+		})
+	}
+}
+
+func TestLinkHandler_CheckLinks_HostDelay(t *testing.T) {
+	tests := []struct {
+		name       string
+		delayMs    int64
+		wantStatus int
+	}{
+		{name: "disabled", delayMs: 0, wantStatus: http.StatusOK},
+		{name: "at the limit", delayMs: 10000, wantStatus: http.StatusOK},
+		{name: "negative", delayMs: -1, wantStatus: http.StatusBadRequest},
+		{name: "over the limit", delayMs: 10001, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			linkChecker := &MockLinkChecker{
+				CheckLinksFunc: func(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+					called = true
+					return []models.LinkStatus{}, nil
+				},
+			}
+			handler := NewLinkHandler(linkChecker, &TestLogger{})
+
+			body, err := json.Marshal(map[string]any{
+				"links":         []models.Link{{URL: "https://example.com", Type: models.LinkTypeExternal}},
+				"host_delay_ms": tt.delayMs,
+			})
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			handler.CheckLinks(w, httptest.NewRequest("POST", "/check", bytes.NewReader(body)))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantStatus == http.StatusOK, called)
+			if tt.wantStatus != http.StatusOK {
+				var errorResp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResp))
+				assert.Equal(t, "host_delay_ms must be between 0 and 10000", errorResp.Error)
+			}
+		})
+	}
+}
+
+func TestLinkHandler_CheckLinks_Options(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   any
+		wantError string
+	}{
+		{name: "none"},
+		{name: "all set", options: models.LinkCheckOptions{
+			PerLinkTimeoutMs:      2000,
+			Method:                models.LinkCheckHeadThenGet,
+			Retries:               2,
+			MaxRedirects:          3,
+			AcceptableStatusCodes: []int{403},
+		}},
+		{name: "timeout over the limit", options: map[string]any{"per_link_timeout_ms": 30001}, wantError: "link_check.per_link_timeout_ms must be between 0 and 30000"},
+		{name: "unknown method", options: map[string]any{"method": "options"}, wantError: `link_check.method: "options" is not one of get, head, head_then_get`},
+		{name: "too many retries", options: map[string]any{"retries": 4}, wantError: "link_check.retries must be between 0 and 3"},
+		{name: "too many redirects", options: map[string]any{"max_redirects": 11}, wantError: "link_check.max_redirects must be between 0 and 10"},
+		{name: "bad status code", options: map[string]any{"acceptable_status_codes": []int{99}}, wantError: "link_check.acceptable_status_codes[0]: 99 is not an HTTP status code"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *models.LinkCheckOptions
+			linkChecker := &MockLinkChecker{
+				CheckLinksFunc: func(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+					if opts, ok := linkcheck.FromContext(ctx); ok {
+						got = &opts
+					}
+					return []models.LinkStatus{}, nil
+				},
+			}
+			handler := NewLinkHandler(linkChecker, &TestLogger{})
+
+			request := map[string]any{"links": []models.Link{{URL: "https://example.com", Type: models.LinkTypeExternal}}}
+			if tt.options != nil {
+				request["options"] = tt.options
+			}
+			body, err := json.Marshal(request)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			handler.CheckLinks(w, httptest.NewRequest("POST", "/check", bytes.NewReader(body)))
+
+			if tt.wantError != "" {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+				var errorResp models.ErrorResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResp))
+				assert.Equal(t, tt.wantError, errorResp.Error)
+				return
+			}
+			assert.Equal(t, http.StatusOK, w.Code)
+			if tt.options == nil {
+				assert.Nil(t, got)
+			} else {
+				assert.Equal(t, tt.options, *got)
+			}
+		})
+	}
+}
+
+func TestLinkHandler_CheckLinks_HostOverrides(t *testing.T) {
+	var got map[string]string
+	linkChecker := &MockLinkChecker{
+		CheckLinksFunc: func(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+			got = httpclient.HostOverridesFromContext(ctx)
+			return []models.LinkStatus{}, nil
+		},
+	}
+	handler := NewLinkHandler(linkChecker, &TestLogger{})
+
+	w := httptest.NewRecorder()
+	handler.CheckLinks(w, httptest.NewRequest("POST", "/check", strings.NewReader(
+		`{"links":[{"url":"https://staging.example.com/about","type":"internal"}],"host_overrides":{"staging.example.com":"10.0.0.5"}}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]string{"staging.example.com": "10.0.0.5"}, got)
+
+	w = httptest.NewRecorder()
+	handler.CheckLinks(w, httptest.NewRequest("POST", "/check", strings.NewReader(
+		`{"links":[{"url":"https://staging.example.com/about","type":"internal"}],"host_overrides":{"staging.example.com:443":"10.0.0.5"}}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var errorResp models.ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResp))
+	assert.Equal(t, `host_overrides: "staging.example.com:443" is not a host name`, errorResp.Error)
+}
+
+func TestLinkHandler_CheckLinks_HonorsBudget(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     map[string]any
+		wantUsed *models.BudgetUsage
+	}{
+		{
+			name: "without budget",
+			body: map[string]any{"links": []models.Link{{URL: "https://example.com/a"}, {URL: "https://example.com/b"}}},
+		},
+		{
+			name: "with budget",
+			body: map[string]any{
+				"links":  []models.Link{{URL: "https://example.com/a"}, {URL: "https://example.com/b"}},
+				"budget": models.BudgetLimits{MaxRequests: 1, MaxBytes: 1000},
+			},
+			wantUsed: &models.BudgetUsage{Requests: 1, Bytes: 10, MaxRequests: 1, MaxBytes: 1000, Exhausted: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Spends the budget the way the HTTP client does
+			linkChecker := &MockLinkChecker{
+				CheckLinkFunc: func(ctx context.Context, link models.Link) models.LinkStatus {
+					b := budget.FromContext(ctx)
+					if b == nil {
+						return models.LinkStatus{Link: link, Accessible: true, StatusCode: 200}
+					}
+					if !b.TryRequest() {
+						return models.LinkStatus{Link: link, Skipped: true, Error: budget.SkippedError}
+					}
+					b.AddBytes(10)
+					return models.LinkStatus{Link: link, Accessible: true, StatusCode: 200}
+				},
+			}
+			linkChecker.CheckLinksFunc = func(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+				statuses := make([]models.LinkStatus, len(links))
+				for i, link := range links {
+					statuses[i] = linkChecker.CheckLink(ctx, link)
+				}
+				return statuses, nil
+			}
+			handler := NewLinkHandler(linkChecker, &TestLogger{})
+
+			body, err := json.Marshal(tt.body)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			handler.CheckLinks(w, httptest.NewRequest("POST", "/check", bytes.NewReader(body)))
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response struct {
+				LinkStatuses []models.LinkStatus `json:"link_statuses"`
+				BudgetUsed   *models.BudgetUsage `json:"budget_used"`
+			}
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+			require.Len(t, response.LinkStatuses, 2)
+			assert.Equal(t, tt.wantUsed, response.BudgetUsed)
+			assert.Equal(t, tt.wantUsed != nil, response.LinkStatuses[1].Skipped)
+		})
+	}
+}
+
+func TestLinkHandler_CheckLinks_ReturnsTrace(t *testing.T) {
+	// Records the checks the way the HTTP client does
+	linkChecker := &MockLinkChecker{
+		CheckLinksFunc: func(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+			statuses := make([]models.LinkStatus, len(links))
+			for i, link := range links {
+				if collector := trace.FromContext(ctx); collector != nil {
+					collector.Record(models.OutboundRequest{Method: "HEAD", URL: link.URL, StatusCode: 200})
+				}
+				statuses[i] = models.LinkStatus{Link: link, Accessible: true, StatusCode: 200}
+			}
+			return statuses, nil
+		},
+	}
+	handler := NewLinkHandler(linkChecker, &TestLogger{})
+	links := []models.Link{{URL: "https://example.com/a"}, {URL: "https://example.com/b"}, {URL: "https://example.com/c"}}
+
+	tests := []struct {
+		name      string
+		body      map[string]any
+		wantTrace *models.DebugTrace
+	}{
+		{
+			name: "without trace",
+			body: map[string]any{"links": links},
+		},
+		{
+			name: "with trace",
+			body: map[string]any{"links": links, "trace_limit": 2},
+			wantTrace: &models.DebugTrace{
+				Requests: []models.OutboundRequest{
+					{Method: "HEAD", URL: "https://example.com/a", StatusCode: 200},
+					{Method: "HEAD", URL: "https://example.com/b", StatusCode: 200},
+				},
+				Dropped: 1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.body)
+			require.NoError(t, err)
+			w := httptest.NewRecorder()
+			handler.CheckLinks(w, httptest.NewRequest("POST", "/check", bytes.NewReader(body)))
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response struct {
+				Trace *models.DebugTrace `json:"trace"`
+			}
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+			assert.Equal(t, tt.wantTrace, response.Trace)
+		})
+	}
+}
+
+func TestLinkHandler_CheckLinks_ReportsBytesDownloaded(t *testing.T) {
+	// Counts the bytes the way the HTTP client does
+	linkChecker := &MockLinkChecker{
+		CheckLinksFunc: func(ctx context.Context, links []models.Link) ([]models.LinkStatus, error) {
+			statuses := make([]models.LinkStatus, len(links))
+			for i, link := range links {
+				traffic.FromContext(ctx).Add(int64(100 * (i + 1)))
+				statuses[i] = models.LinkStatus{Link: link, Accessible: true, StatusCode: 200}
+			}
+			return statuses, nil
+		},
+	}
+	handler := NewLinkHandler(linkChecker, &TestLogger{})
+
+	body, err := json.Marshal(map[string]any{"links": []models.Link{{URL: "https://example.com/a"}, {URL: "https://example.com/b"}}})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	handler.CheckLinks(w, httptest.NewRequest("POST", "/check", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		BytesDownloaded int64 `json:"bytes_downloaded"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, int64(300), response.BytesDownloaded)
+}
+
+func TestHealthHandler_AdvertisesMaxLinks(t *testing.T) {
+	handler := NewHealthHandler("link-checker")
+	handler.SetMaxLinks(250)
+
+	w := httptest.NewRecorder()
+	handler.Health(w, httptest.NewRequest("GET", "/health", nil))
+
+	var health models.HealthStatus
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&health))
+	assert.Equal(t, 250, health.Limits[models.LimitMaxLinksPerRequest])
+}
+
+// slowStreamingChecker emits one status per release, so a test controls
+// exactly when each line becomes available
+type slowStreamingChecker struct {
+	MockLinkChecker
+	release chan struct{}
+}
+
+func (c *slowStreamingChecker) CheckLinksStream(ctx context.Context, links []models.Link, emit func(models.LinkStatus)) error {
+	for _, link := range links {
+		select {
+		case <-c.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		emit(models.LinkStatus{Link: link, Accessible: true, StatusCode: http.StatusOK})
+	}
+	return nil
+}
+
+func streamRequestBody(t *testing.T, urls ...string) []byte {
+	t.Helper()
+	links := make([]models.Link, len(urls))
+	for i, url := range urls {
+		links[i] = models.Link{URL: url, Type: models.LinkTypeExternal}
+	}
+	body, err := json.Marshal(map[string]any{"links": links})
+	require.NoError(t, err)
+	return body
+}
+
+func TestLinkHandler_CheckLinksStream_FlushesEachStatus(t *testing.T) {
+	checker := &slowStreamingChecker{release: make(chan struct{})}
+	handler := NewLinkHandler(checker, &TestLogger{})
+	server := httptest.NewServer(http.HandlerFunc(handler.CheckLinksStream))
+	defer server.Close()
+
+	urls := []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"}
+	resp, err := http.Post(server.URL, "application/json", bytes.NewReader(streamRequestBody(t, urls...)))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	// Each line must be readable before the checker produces the next one
+	decoder := json.NewDecoder(resp.Body)
+	for _, url := range urls {
+		checker.release <- struct{}{}
+
+		var status models.LinkStatus
+		require.NoError(t, decoder.Decode(&status))
+		assert.Equal(t, url, status.Link.URL)
+		assert.True(t, status.Accessible)
+	}
+
+	var extra models.LinkStatus
+	assert.ErrorIs(t, decoder.Decode(&extra), io.EOF)
+}
+
+func TestLinkHandler_CheckLinksStream_BufferedCheckerFallback(t *testing.T) {
+	handler := NewLinkHandler(&MockLinkChecker{}, &TestLogger{})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/check/stream", bytes.NewReader(streamRequestBody(t, "https://example.com/a", "https://example.com/b")))
+	handler.CheckLinksStream(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	require.Len(t, lines, 2)
+	for _, line := range lines {
+		var status models.LinkStatus
+		require.NoError(t, json.Unmarshal([]byte(line), &status))
+		assert.True(t, status.Accessible)
+	}
+}
+
+func TestLinkHandler_CheckLinksStream_RejectsBeforeStreaming(t *testing.T) {
+	handler := NewLinkHandler(&MockLinkChecker{}, &TestLogger{})
+	handler.SetMaxLinks(1)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/check/stream", bytes.NewReader(streamRequestBody(t, "https://example.com/a", "https://example.com/b")))
+	handler.CheckLinksStream(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+}
+
 func TestLinkHandler_CheckLinks_CheckerError(t *testing.T) {
 	logger := &TestLogger{}
 
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/stats"
+)
+
+// StatsHandler serves a JSON snapshot of the link checker's load
+type StatsHandler struct {
+	serviceName    string
+	collector      *stats.Collector
+	workerPoolSize func() int
+	startTime      time.Time
+}
+
+// NewStatsHandler reports the figures kept by collector, with the worker
+// pool size read from workerPoolSize as it may be resized
+func NewStatsHandler(serviceName string, collector *stats.Collector, workerPoolSize func() int) *StatsHandler {
+	return &StatsHandler{
+		serviceName:    serviceName,
+		collector:      collector,
+		workerPoolSize: workerPoolSize,
+		startTime:      time.Now(),
+	}
+}
+
+// Stats handles GET /stats
+func (h *StatsHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	linkChecks := h.collector.LinkChecks()
+	linkChecks.WorkerPoolSize = h.workerPoolSize()
+	response := models.ServiceStats{
+		Service:       h.serviceName,
+		UptimeSeconds: int64(time.Since(h.startTime) / time.Second),
+		WindowSeconds: int64(stats.Span / time.Second),
+		LinkChecks:    linkChecks,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
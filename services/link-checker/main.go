@@ -4,122 +4,264 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/admin"
+	"github.com/RuvinSL/webpage-analyzer/pkg/config"
+	"github.com/RuvinSL/webpage-analyzer/pkg/faults"
 	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
 	"github.com/RuvinSL/webpage-analyzer/pkg/interfaces"
+	"github.com/RuvinSL/webpage-analyzer/pkg/internalhttp"
 	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
 	"github.com/RuvinSL/webpage-analyzer/pkg/metrics"
+	"github.com/RuvinSL/webpage-analyzer/pkg/middleware"
+	"github.com/RuvinSL/webpage-analyzer/pkg/readiness"
+	"github.com/RuvinSL/webpage-analyzer/pkg/servertls"
+	"github.com/RuvinSL/webpage-analyzer/pkg/stats"
+	"github.com/RuvinSL/webpage-analyzer/pkg/version"
 	"github.com/RuvinSL/webpage-analyzer/services/link-checker/core"
 	"github.com/RuvinSL/webpage-analyzer/services/link-checker/handlers"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 const (
-	defaultPort           = "8082"
-	serviceName           = "link-checker"
-	defaultWorkerPoolSize = 10
-	defaultCheckTimeout   = 5 * time.Second
+	serviceName = "link-checker"
+
+	// goroutineSampleInterval is how often the goroutine gauge is sampled
+	goroutineSampleInterval = 15 * time.Second
 )
 
-// createLogger creates a logger with optional file output
-func createLogger() interfaces.Logger {
-	// Check if file logging is enabled via environment variable
-	// fmt.Printf("=== LOGGER DEBUG ===\n")
-	// fmt.Printf(os.Getenv("LOG_TO_FILE"))
+// createLogger creates a logger with optional file output. The level is a
+// slog.Leveler so a *slog.LevelVar can be adjusted after startup
+func createLogger(cfg *config.Common, level slog.Leveler) interfaces.Logger {
+	if cfg.LogToFile {
+		return logger.NewWithFiles(serviceName, level, cfg.LogDir)
+	}
+
+	return logger.New(serviceName, level)
+}
 
-	if getEnv("LOG_TO_FILE", "true") == "true" {
-		logDir := getEnv("LOG_DIR", "./logs")
-		return logger.NewWithFiles(serviceName, getLogLevel(), logDir)
+// loadConfigOrExit reports configuration errors through a bootstrap logger
+// and exits, so a bad value never silently falls back to a default
+func loadConfigOrExit[T any](load func() (T, error)) T {
+	cfg, err := load()
+	if err != nil {
+		logger.New(serviceName, slog.LevelInfo).Error("Invalid configuration", "error", err)
+		os.Exit(1)
 	}
+	return cfg
+}
 
-	// Default: stdout only (your current behavior)
-	return logger.New(serviceName, getLogLevel())
+// sourceIPOrExit checks that OUTBOUND_SOURCE_IP is an address of the host,
+// which the configuration alone cannot tell, and logs how outbound
+// connections are made
+func sourceIPOrExit(cfg config.DNS, log interfaces.Logger) net.IP {
+	ip, err := httpclient.ParseSourceIP(cfg.OutboundSourceIP)
+	if err != nil {
+		log.Error("Invalid configuration", "error", fmt.Errorf("OUTBOUND_SOURCE_IP: %w", err))
+		os.Exit(1)
+	}
+	source := "system"
+	if ip != nil {
+		source = ip.String()
+	}
+	log.Info("Outbound HTTP client configured", "ip_family", cfg.IPFamily, "source_ip", source)
+	return ip
+}
+
+// faultsOrExit returns the injector of FAULT_INJECT, nil when it is not set,
+// and warns loudly when it is
+func faultsOrExit(cfg *config.Common, log interfaces.Logger) *faults.Injector {
+	injector, err := faults.New(cfg.FaultInject, cfg.FaultInjectSeed)
+	if err != nil {
+		log.Error("Invalid configuration", "error", fmt.Errorf("FAULT_INJECT: %w", err))
+		os.Exit(1)
+	}
+	if injector != nil {
+		log.Warn("FAULT INJECTION ENABLED: latency and errors are injected on purpose, never run this in production",
+			"rules", injector.Rules(), "seed", injector.Seed())
+	}
+	return injector
 }
 
 func main() {
 
 	// Initialize logger
+	cfg := loadConfigOrExit(config.LoadLinkChecker)
+
 	//log := logger.New(serviceName, getLogLevel())
-	log := createLogger()
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(cfg.SlogLevel())
+	log := createLogger(&cfg.Common, logLevel)
+	log.Info("Loaded configuration", config.Fields(cfg)...)
+	faultInjector := faultsOrExit(&cfg.Common, log)
 
 	// Initialize metrics
 	metricsCollector := metrics.NewPrometheusCollector(serviceName)
 	prometheus.MustRegister(metricsCollector.GetCollectors()...)
-
-	// Configuration
-	port := getEnv("PORT", defaultPort)
-	workerPoolSize := getEnvInt("WORKER_POOL_SIZE", defaultWorkerPoolSize)
-	checkTimeout := getEnvDuration("CHECK_TIMEOUT", defaultCheckTimeout)
+	statsCollector := stats.NewCollector(metricsCollector)
 
 	// Initialize dependencies
-	httpClient := httpclient.New(checkTimeout, log)
+	httpClient := httpclient.New(cfg.CheckTimeout, log)
+	httpClient.SetIPFamily(cfg.IPFamily)
+	httpClient.SetSourceIP(sourceIPOrExit(cfg.DNS, log))
+	if endpoint := cfg.DoHEndpoint(); endpoint != "" {
+		httpClient.SetResolver(httpclient.NewDoHResolver(endpoint, cfg.DNSResolverTimeout, log))
+		log.Info("Resolving host names with DNS over HTTPS", "endpoint", endpoint)
+	}
 
 	linkChecker := core.NewConcurrentLinkChecker(
 		httpClient,
-		workerPoolSize,
+		cfg.WorkerPoolSize,
 		log,
-		metricsCollector,
+		statsCollector,
 	)
+	linkChecker.SetHostDelay(cfg.HostDelay)
+	linkChecker.SetLinkTimeout(cfg.CheckTimeout)
+	linkChecker.SetHedgeDelay(cfg.HedgeDelay)
+	linkChecker.SetPreconnectHosts(cfg.PreconnectHosts)
 
 	// Start the worker pool
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	linkChecker.Start(ctx)
+	go metricsCollector.WatchGoroutines(ctx, goroutineSampleInterval)
 
 	// Initialize handlers
 	linkHandler := handlers.NewLinkHandler(linkChecker, log)
+	linkHandler.SetMaxLinks(cfg.MaxLinksPerRequest)
 	healthHandler := handlers.NewHealthHandler(serviceName)
+	healthHandler.SetMaxLinks(cfg.MaxLinksPerRequest)
+	statsHandler := handlers.NewStatsHandler(serviceName, statsCollector, linkChecker.WorkerPoolSize)
+	// The link checker has no critical downstream services, so the gate only
+	// flips once the worker pool and routes are in place
+	readinessGate := readiness.NewGate(serviceName, log)
 
 	// Setup routes
 	router := mux.NewRouter()
 
 	// Middleware
-	router.Use(loggingMiddleware(log))
-	router.Use(metricsMiddleware(metricsCollector))
-	router.Use(recoveryMiddleware(log))
+	router.Use(middleware.RequestID)
+	router.Use(middleware.Logging(log))
+	router.Use(middleware.Metrics(metricsCollector))
+	router.Use(middleware.Recovery(log))
 
 	// Routes
-	router.HandleFunc("/check", linkHandler.CheckLinks).Methods("POST")
-	router.HandleFunc("/check-single", linkHandler.CheckSingleLink).Methods("POST")
+	// Only inbound faults apply: the links checked are not services
+	router.Handle("/check", faultInjector.Middleware(http.HandlerFunc(linkHandler.CheckLinks))).Methods("POST")
+	router.Handle("/check/stream", faultInjector.Middleware(http.HandlerFunc(linkHandler.CheckLinksStream))).Methods("POST")
+	router.Handle("/check-single", faultInjector.Middleware(http.HandlerFunc(linkHandler.CheckSingleLink))).Methods("POST")
 	router.HandleFunc("/health", healthHandler.Health).Methods("GET")
-	router.Handle("/metrics", promhttp.Handler())
+	router.HandleFunc("/health/ready", readinessGate.Ready).Methods("GET")
+	router.HandleFunc("/version", version.Handler(serviceName)).Methods("GET")
+	router.HandleFunc("/stats", statsHandler.Stats).Methods("GET")
+	router.Handle("/metrics", metrics.Handler())
+
+	// Admin routes, guarded by ADMIN_TOKEN
+	logLevelHandler := admin.NewLogLevelHandler(logLevel, log)
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(admin.RequireToken(cfg.AdminToken))
+	adminRouter.HandleFunc("/loglevel", logLevelHandler.Get).Methods("GET")
+	adminRouter.HandleFunc("/loglevel", logLevelHandler.Set).Methods("PUT")
+
+	// pprof and the batch bookkeeping, only when DEBUG_ENDPOINTS is set
+	batchesHandler := handlers.NewBatchesHandler(linkChecker.Batches)
+	debugServer := admin.MountDebug(router, admin.DebugOptions{
+		Enabled:  cfg.DebugEndpoints,
+		Addr:     cfg.DebugAddr,
+		Token:    cfg.AdminToken,
+		Handlers: map[string]http.Handler{"batches": http.HandlerFunc(batchesHandler.Batches)},
+	})
 
 	// Create server
 	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%s", port),
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
 		Handler:      router,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 60 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	if cfg.InternalH2C {
+		// The callers inside the deployment speak HTTP/2 without TLS
+		srv.Handler = internalhttp.H2CHandler(router)
+	}
+
+	// HTTPS is served directly when a certificate is configured, and a
+	// renewed certificate is picked up without a restart
+	var certs *servertls.CertReloader
+	if cfg.TLSEnabled() {
+		var err error
+		certs, err = servertls.NewCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile, log)
+		if err != nil {
+			log.Error("Failed to load TLS certificate", "error", err)
+			os.Exit(1)
+		}
+		srv.TLSConfig = certs.TLSConfig()
+		go certs.Watch(context.Background(), cfg.TLSReloadInterval)
+	}
+	redirectServer := servertls.RedirectServer(cfg.TLSRedirectAddr, cfg.Port)
 
 	// Start server
 	go func() {
 		log.Info("Starting Link Checker Service",
-			"port", port,
-			"worker_pool_size", workerPoolSize,
-			"check_timeout", checkTimeout,
+			"port", cfg.Port,
+			"worker_pool_size", cfg.WorkerPoolSize,
+			"check_timeout", cfg.CheckTimeout,
+			"max_links_per_request", cfg.MaxLinksPerRequest,
+			"tls", cfg.TLSEnabled(),
+			"version", version.Get().Version,
+			"commit", version.Get().Commit,
 		)
 
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := servertls.ListenAndServe(srv); err != nil && err != http.ErrServerClosed {
 			log.Error("Failed to start server", "error", err)
 			os.Exit(1)
 		}
 	}()
 
+	if redirectServer != nil {
+		go func() {
+			log.Info("Redirecting HTTP to HTTPS", "addr", cfg.TLSRedirectAddr)
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("HTTP redirect server failed", "error", err)
+			}
+		}()
+	}
+	if debugServer != nil {
+		go func() {
+			log.Info("Serving debug endpoints", "addr", cfg.DebugAddr)
+			if err := debugServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("Debug server failed", "error", err)
+			}
+		}()
+	}
+
+	go waitForReadiness(readinessGate, &cfg.Common, log)
+
 	// Graceful shutdown
+	// Reload the runtime-tunable settings on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reloadConfig(ctx, cfg, logLevel, linkChecker, log)
+			if certs != nil {
+				certs.Reload()
+			}
+		}
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
+	signal.Stop(hup)
 
 	log.Info("Shutting down server...")
 
@@ -133,6 +275,12 @@ func main() {
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Error("Server forced to shutdown", "error", err)
 	}
+	if redirectServer != nil {
+		redirectServer.Shutdown(shutdownCtx)
+	}
+	if debugServer != nil {
+		debugServer.Shutdown(shutdownCtx)
+	}
 
 	// Wait for workers to finish
 	linkChecker.Stop()
@@ -140,97 +288,43 @@ func main() {
 	log.Info("Server exited")
 }
 
-func loggingMiddleware(log interfaces.Logger) mux.MiddlewareFunc {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			start := time.Now()
-
-			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-			next.ServeHTTP(wrapped, r)
-
-			log.Info("Request completed",
-				"method", r.Method,
-				"path", r.URL.Path,
-				"status", wrapped.statusCode,
-				"duration", time.Since(start),
-				"remote_addr", r.RemoteAddr,
-			)
-		})
-	}
-}
-
-func metricsMiddleware(collector metrics.Collector) mux.MiddlewareFunc {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			start := time.Now()
-
-			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-			next.ServeHTTP(wrapped, r)
-
-			duration := time.Since(start).Seconds()
-			collector.RecordRequest(r.Method, r.URL.Path, wrapped.statusCode, duration)
-		})
-	}
-}
-
-func recoveryMiddleware(log interfaces.Logger) mux.MiddlewareFunc {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			defer func() {
-				if err := recover(); err != nil {
-					log.Error("Panic recovered", "error", err, "path", r.URL.Path)
-					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-				}
-			}()
-			next.ServeHTTP(w, r)
-		})
-	}
-}
-
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
-}
-
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
-}
-
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// waitForReadiness blocks until the critical dependencies are reachable and
+// flips the readiness gate, exiting the process in fail-fast mode
+func waitForReadiness(gate *readiness.Gate, cfg *config.Common, log interfaces.Logger) {
+	mode := readiness.Mode(cfg.StartupMode)
+	if err := gate.Wait(context.Background(), cfg.StartupMaxWait, mode); err != nil {
+		log.Error("Startup dependencies unavailable", "error", err, "max_wait", cfg.StartupMaxWait)
+		os.Exit(1)
 	}
-	return defaultValue
 }
 
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
+// reloadConfig re-reads the configuration on SIGHUP and applies the log level
+// and worker pool size. Environment variables are fixed for the life of the
+// process, so in practice new values arrive through CONFIG_FILE; every other
+// change is reported and ignored until restart.
+func reloadConfig(ctx context.Context, cfg *config.LinkChecker, level *slog.LevelVar, checker *core.ConcurrentLinkChecker, log interfaces.Logger) {
+	next, err := config.LoadLinkChecker()
+	if err != nil {
+		log.Error("Configuration reload failed, keeping current values", "error", err)
+		return
 	}
-	return defaultValue
-}
 
-func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
+	var ignored []string
+	for _, key := range config.Changed(cfg, next) {
+		switch key {
+		case "LOG_LEVEL":
+			level.Set(next.SlogLevel())
+			cfg.LogLevel = next.LogLevel
+		case "WORKER_POOL_SIZE":
+			checker.Resize(ctx, next.WorkerPoolSize)
+			cfg.WorkerPoolSize = next.WorkerPoolSize
+		default:
+			ignored = append(ignored, key)
 		}
 	}
-	return defaultValue
-}
 
-func getLogLevel() slog.Level {
-	switch os.Getenv("LOG_LEVEL") {
-	case "debug":
-		return slog.LevelDebug
-	case "warn":
-		return slog.LevelWarn
-	case "error":
-		return slog.LevelError
-	default:
-		return slog.LevelInfo
+	if len(ignored) > 0 {
+		log.Warn("Configuration changes ignored until restart", "fields", ignored)
 	}
+	log.Info("Configuration reloaded", "log_level", cfg.LogLevel, "worker_pool_size", cfg.WorkerPoolSize)
 }
@@ -1,301 +1,90 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
 	"time"
 
+	"github.com/RuvinSL/webpage-analyzer/pkg/config"
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/pkg/metrics"
+	"github.com/RuvinSL/webpage-analyzer/services/link-checker/core"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-// Test utility functions
-func TestGetEnv(t *testing.T) {
-	tests := []struct {
-		name         string
-		key          string
-		defaultValue string
-		envValue     string
-		expected     string
-	}{
-		{
-			name:         "returns environment value when set",
-			key:          "TEST_LINK_CHECKER_KEY",
-			defaultValue: "default",
-			envValue:     "env_value",
-			expected:     "env_value",
-		},
-		{
-			name:         "returns default value when env not set",
-			key:          "UNSET_LINK_CHECKER_KEY",
-			defaultValue: "default",
-			envValue:     "",
-			expected:     "default",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Clean up environment
-			os.Unsetenv(tt.key)
-
-			if tt.envValue != "" {
-				os.Setenv(tt.key, tt.envValue)
-				defer os.Unsetenv(tt.key)
-			}
-
-			result := getEnv(tt.key, tt.defaultValue)
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
-func TestGetEnvInt(t *testing.T) {
-	tests := []struct {
-		name         string
-		key          string
-		defaultValue int
-		envValue     string
-		expected     int
-	}{
-		{
-			name:         "returns parsed integer value",
-			key:          "TEST_INT_KEY",
-			defaultValue: 10,
-			envValue:     "25",
-			expected:     25,
-		},
-		{
-			name:         "returns default for invalid integer",
-			key:          "TEST_INVALID_INT_KEY",
-			defaultValue: 10,
-			envValue:     "invalid",
-			expected:     10,
-		},
-		{
-			name:         "returns default when env not set",
-			key:          "UNSET_INT_KEY",
-			defaultValue: 15,
-			envValue:     "",
-			expected:     15,
-		},
-		{
-			name:         "handles zero value",
-			key:          "TEST_ZERO_KEY",
-			defaultValue: 10,
-			envValue:     "0",
-			expected:     0,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Clean up environment
-			os.Unsetenv(tt.key)
-
-			if tt.envValue != "" {
-				os.Setenv(tt.key, tt.envValue)
-				defer os.Unsetenv(tt.key)
-			}
-
-			result := getEnvInt(tt.key, tt.defaultValue)
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
-func TestGetEnvDuration(t *testing.T) {
-	tests := []struct {
-		name         string
-		key          string
-		defaultValue time.Duration
-		envValue     string
-		expected     time.Duration
-	}{
-		{
-			name:         "returns parsed duration value",
-			key:          "TEST_DURATION_KEY",
-			defaultValue: 5 * time.Second,
-			envValue:     "10s",
-			expected:     10 * time.Second,
-		},
-		{
-			name:         "returns default for invalid duration",
-			key:          "TEST_INVALID_DURATION_KEY",
-			defaultValue: 5 * time.Second,
-			envValue:     "invalid",
-			expected:     5 * time.Second,
-		},
-		{
-			name:         "returns default when env not set",
-			key:          "UNSET_DURATION_KEY",
-			defaultValue: 3 * time.Second,
-			envValue:     "",
-			expected:     3 * time.Second,
-		},
-		{
-			name:         "handles milliseconds",
-			key:          "TEST_MS_KEY",
-			defaultValue: 5 * time.Second,
-			envValue:     "500ms",
-			expected:     500 * time.Millisecond,
-		},
-		{
-			name:         "handles minutes",
-			key:          "TEST_MIN_KEY",
-			defaultValue: 5 * time.Second,
-			envValue:     "2m",
-			expected:     2 * time.Minute,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Clean up environment
-			os.Unsetenv(tt.key)
-
-			if tt.envValue != "" {
-				os.Setenv(tt.key, tt.envValue)
-				defer os.Unsetenv(tt.key)
-			}
-
-			result := getEnvDuration(tt.key, tt.defaultValue)
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
-func TestGetLogLevel(t *testing.T) {
-	tests := []struct {
-		name     string
-		envValue string
-		expected slog.Level
-	}{
-		{
-			name:     "returns debug level",
-			envValue: "debug",
-			expected: slog.LevelDebug,
-		},
-		{
-			name:     "returns warn level",
-			envValue: "warn",
-			expected: slog.LevelWarn,
-		},
-		{
-			name:     "returns error level",
-			envValue: "error",
-			expected: slog.LevelError,
-		},
-		{
-			name:     "returns info level as default",
-			envValue: "",
-			expected: slog.LevelInfo,
-		},
-		{
-			name:     "returns info level for invalid value",
-			envValue: "invalid",
-			expected: slog.LevelInfo,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			os.Unsetenv("LOG_LEVEL")
-
-			if tt.envValue != "" {
-				os.Setenv("LOG_LEVEL", tt.envValue)
-				defer os.Unsetenv("LOG_LEVEL")
-			}
-
-			result := getLogLevel()
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
 func TestCreateLogger(t *testing.T) {
 	t.Run("creates logger successfully", func(t *testing.T) {
 		// Test with file logging disabled to avoid file creation
-		os.Setenv("LOG_TO_FILE", "false")
-		defer os.Unsetenv("LOG_TO_FILE")
+		cfg := config.DefaultLinkChecker()
+		cfg.LogToFile = false
 
-		logger := createLogger()
+		logger := createLogger(&cfg.Common, cfg.SlogLevel())
 		assert.NotNil(t, logger)
 	})
 }
 
 func TestConstants(t *testing.T) {
 	t.Run("service constants are correct", func(t *testing.T) {
-		assert.Equal(t, "8082", defaultPort)
 		assert.Equal(t, "link-checker", serviceName)
-		assert.Equal(t, 10, defaultWorkerPoolSize)
-		assert.Equal(t, 5*time.Second, defaultCheckTimeout)
 	})
 }
 
 func TestServerConfiguration(t *testing.T) {
 	t.Run("uses default configuration values", func(t *testing.T) {
-		// Clean environment
-		os.Unsetenv("PORT")
-		os.Unsetenv("WORKER_POOL_SIZE")
-		os.Unsetenv("CHECK_TIMEOUT")
-
-		port := getEnv("PORT", defaultPort)
-		workerPoolSize := getEnvInt("WORKER_POOL_SIZE", defaultWorkerPoolSize)
-		checkTimeout := getEnvDuration("CHECK_TIMEOUT", defaultCheckTimeout)
-
-		assert.Equal(t, defaultPort, port)
-		assert.Equal(t, defaultWorkerPoolSize, workerPoolSize)
-		assert.Equal(t, defaultCheckTimeout, checkTimeout)
+		t.Setenv("PORT", "")
+		t.Setenv("WORKER_POOL_SIZE", "")
+		t.Setenv("CHECK_TIMEOUT", "")
+
+		cfg, err := config.LoadLinkChecker()
+		require.NoError(t, err)
+
+		assert.Equal(t, 8082, cfg.Port)
+		assert.Equal(t, 10, cfg.WorkerPoolSize)
+		assert.Equal(t, 5*time.Second, cfg.CheckTimeout)
 	})
 
 	t.Run("uses custom configuration values", func(t *testing.T) {
-		os.Setenv("PORT", "9999")
-		os.Setenv("WORKER_POOL_SIZE", "20")
-		os.Setenv("CHECK_TIMEOUT", "10s")
-		defer func() {
-			os.Unsetenv("PORT")
-			os.Unsetenv("WORKER_POOL_SIZE")
-			os.Unsetenv("CHECK_TIMEOUT")
-		}()
-
-		port := getEnv("PORT", defaultPort)
-		workerPoolSize := getEnvInt("WORKER_POOL_SIZE", defaultWorkerPoolSize)
-		checkTimeout := getEnvDuration("CHECK_TIMEOUT", defaultCheckTimeout)
-
-		assert.Equal(t, "9999", port)
-		assert.Equal(t, 20, workerPoolSize)
-		assert.Equal(t, 10*time.Second, checkTimeout)
+		t.Setenv("PORT", "9999")
+		t.Setenv("WORKER_POOL_SIZE", "20")
+		t.Setenv("CHECK_TIMEOUT", "10s")
+
+		cfg, err := config.LoadLinkChecker()
+		require.NoError(t, err)
+
+		assert.Equal(t, 9999, cfg.Port)
+		assert.Equal(t, 20, cfg.WorkerPoolSize)
+		assert.Equal(t, 10*time.Second, cfg.CheckTimeout)
 	})
-}
 
-func TestResponseWriter(t *testing.T) {
-	t.Run("captures status code correctly", func(t *testing.T) {
-		recorder := httptest.NewRecorder()
-		rw := &responseWriter{ResponseWriter: recorder, statusCode: http.StatusOK}
+	t.Run("rejects invalid values instead of defaulting", func(t *testing.T) {
+		tests := map[string]string{
+			"WORKER_POOL_SIZE": "invalid",
+			"CHECK_TIMEOUT":    "5 seconds",
+			"PORT":             "70000",
+		}
 
-		// Test default status code
-		assert.Equal(t, http.StatusOK, rw.statusCode)
+		for key, value := range tests {
+			t.Run(key, func(t *testing.T) {
+				t.Setenv(key, value)
 
-		// Test WriteHeader
-		rw.WriteHeader(http.StatusNotFound)
-		assert.Equal(t, http.StatusNotFound, rw.statusCode)
-		assert.Equal(t, http.StatusNotFound, recorder.Code)
+				_, err := config.LoadLinkChecker()
+				assert.Error(t, err)
+			})
+		}
 	})
 
-	t.Run("preserves original ResponseWriter functionality", func(t *testing.T) {
-		recorder := httptest.NewRecorder()
-		rw := &responseWriter{ResponseWriter: recorder, statusCode: http.StatusOK}
-
-		// Test Write method
-		data := []byte("test response")
-		n, err := rw.Write(data)
-		assert.NoError(t, err)
-		assert.Equal(t, len(data), n)
-		assert.Equal(t, string(data), recorder.Body.String())
+	t.Run("rejects non-positive worker pool size", func(t *testing.T) {
+		t.Setenv("WORKER_POOL_SIZE", "0")
+
+		_, err := config.LoadLinkChecker()
+		assert.Error(t, err)
 	})
 }
 
@@ -320,198 +109,61 @@ func TestBasicRouterSetup(t *testing.T) {
 	})
 }
 
-func TestEnvironmentVariables(t *testing.T) {
-	t.Run("all environment variables work correctly", func(t *testing.T) {
-		envTests := map[string]struct {
-			key          string
-			testValue    string
-			defaultValue string
-		}{
-			"PORT": {
-				key:          "PORT",
-				testValue:    "8888",
-				defaultValue: defaultPort,
-			},
-			"LOG_LEVEL": {
-				key:          "LOG_LEVEL",
-				testValue:    "debug",
-				defaultValue: "info",
-			},
-			"LOG_TO_FILE": {
-				key:          "LOG_TO_FILE",
-				testValue:    "false",
-				defaultValue: "true",
-			},
-			"LOG_DIR": {
-				key:          "LOG_DIR",
-				testValue:    "./test_logs",
-				defaultValue: "./logs",
-			},
-		}
-
-		for name, test := range envTests {
-			t.Run(name, func(t *testing.T) {
-				// Clean environment
-				os.Unsetenv(test.key)
-
-				// Test default value
-				defaultResult := getEnv(test.key, test.defaultValue)
-				assert.Equal(t, test.defaultValue, defaultResult)
-
-				// Test custom value
-				os.Setenv(test.key, test.testValue)
-				customResult := getEnv(test.key, test.defaultValue)
-				assert.Equal(t, test.testValue, customResult)
-
-				// Clean up
-				os.Unsetenv(test.key)
-			})
-		}
-	})
-
-	t.Run("integer environment variables work correctly", func(t *testing.T) {
-		intTests := map[string]struct {
-			key          string
-			testValue    string
-			expectedInt  int
-			defaultValue int
-		}{
-			"WORKER_POOL_SIZE": {
-				key:          "WORKER_POOL_SIZE",
-				testValue:    "25",
-				expectedInt:  25,
-				defaultValue: defaultWorkerPoolSize,
-			},
-		}
-
-		for name, test := range intTests {
-			t.Run(name, func(t *testing.T) {
-				// Clean environment
-				os.Unsetenv(test.key)
-
-				// Test default value
-				defaultResult := getEnvInt(test.key, test.defaultValue)
-				assert.Equal(t, test.defaultValue, defaultResult)
-
-				// Test custom value
-				os.Setenv(test.key, test.testValue)
-				customResult := getEnvInt(test.key, test.defaultValue)
-				assert.Equal(t, test.expectedInt, customResult)
-
-				// Clean up
-				os.Unsetenv(test.key)
-			})
-		}
-	})
-
-	t.Run("duration environment variables work correctly", func(t *testing.T) {
-		durationTests := map[string]struct {
-			key              string
-			testValue        string
-			expectedDuration time.Duration
-			defaultValue     time.Duration
-		}{
-			"CHECK_TIMEOUT": {
-				key:              "CHECK_TIMEOUT",
-				testValue:        "10s",
-				expectedDuration: 10 * time.Second,
-				defaultValue:     defaultCheckTimeout,
-			},
-		}
-
-		for name, test := range durationTests {
-			t.Run(name, func(t *testing.T) {
-				// Clean environment
-				os.Unsetenv(test.key)
-
-				// Test default value
-				defaultResult := getEnvDuration(test.key, test.defaultValue)
-				assert.Equal(t, test.defaultValue, defaultResult)
-
-				// Test custom value
-				os.Setenv(test.key, test.testValue)
-				customResult := getEnvDuration(test.key, test.defaultValue)
-				assert.Equal(t, test.expectedDuration, customResult)
-
-				// Clean up
-				os.Unsetenv(test.key)
-			})
-		}
-	})
-}
-
-// Benchmark tests
-func BenchmarkGetEnv(b *testing.B) {
-	os.Setenv("BENCH_TEST", "value")
-	defer os.Unsetenv("BENCH_TEST")
-
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		getEnv("BENCH_TEST", "default")
-	}
-}
-
-func BenchmarkGetEnvInt(b *testing.B) {
-	os.Setenv("BENCH_INT_TEST", "123")
-	defer os.Unsetenv("BENCH_INT_TEST")
-
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		getEnvInt("BENCH_INT_TEST", 10)
-	}
+func TestReloadConfig(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "info")
+	cfg, err := config.LoadLinkChecker()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	level := new(slog.LevelVar)
+	level.Set(cfg.SlogLevel())
+	log := logger.NewAdapter(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: level})))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	checker := core.NewConcurrentLinkChecker(httpclient.New(cfg.CheckTimeout, log), cfg.WorkerPoolSize, log, metrics.NewPrometheusCollector("test"))
+	checker.Start(ctx)
+	defer checker.Stop()
+
+	log.Debug("before reload")
+	assert.NotContains(t, buf.String(), "before reload")
+
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("PORT", "9999")
+	t.Setenv("WORKER_POOL_SIZE", "3")
+	reloadConfig(ctx, cfg, level, checker, log)
+
+	assert.Equal(t, slog.LevelDebug, level.Level())
+	assert.Equal(t, "debug", cfg.LogLevel)
+	log.Debug("after reload")
+	assert.Contains(t, buf.String(), "after reload")
+
+	// Non-reloadable fields are reported and left untouched
+	assert.Contains(t, buf.String(), "Configuration changes ignored until restart")
+	assert.Contains(t, buf.String(), "PORT")
+	assert.Equal(t, 8082, cfg.Port)
+	assert.Equal(t, 3, cfg.WorkerPoolSize)
+	assert.Equal(t, 3, checker.WorkerPoolSize())
 }
 
-func BenchmarkGetEnvDuration(b *testing.B) {
-	os.Setenv("BENCH_DURATION_TEST", "5s")
-	defer os.Unsetenv("BENCH_DURATION_TEST")
+func TestReloadConfig_InvalidKeepsCurrent(t *testing.T) {
+	cfg, err := config.LoadLinkChecker()
+	require.NoError(t, err)
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		getEnvDuration("BENCH_DURATION_TEST", 1*time.Second)
-	}
-}
+	var buf bytes.Buffer
+	level := new(slog.LevelVar)
+	log := logger.NewAdapter(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: level})))
 
-func BenchmarkGetLogLevel(b *testing.B) {
-	os.Setenv("LOG_LEVEL", "debug")
-	defer os.Unsetenv("LOG_LEVEL")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	checker := core.NewConcurrentLinkChecker(httpclient.New(cfg.CheckTimeout, log), cfg.WorkerPoolSize, log, metrics.NewPrometheusCollector("test"))
+	checker.Start(ctx)
+	defer checker.Stop()
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		getLogLevel()
-	}
-}
+	t.Setenv("LOG_LEVEL", "verbose")
+	reloadConfig(ctx, cfg, level, checker, log)
 
-// Integration test
-func TestIntegration(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping integration test in short mode")
-	}
-
-	t.Run("full environment setup", func(t *testing.T) {
-		// Set multiple environment variables
-		envVars := map[string]string{
-			"PORT":             "8888",
-			"WORKER_POOL_SIZE": "20",
-			"CHECK_TIMEOUT":    "10s",
-			"LOG_LEVEL":        "debug",
-			"LOG_TO_FILE":      "false",
-		}
-
-		// Set all environment variables
-		for key, value := range envVars {
-			os.Setenv(key, value)
-		}
-
-		// Test all values
-		assert.Equal(t, "8888", getEnv("PORT", defaultPort))
-		assert.Equal(t, 20, getEnvInt("WORKER_POOL_SIZE", defaultWorkerPoolSize))
-		assert.Equal(t, 10*time.Second, getEnvDuration("CHECK_TIMEOUT", defaultCheckTimeout))
-		assert.Equal(t, slog.LevelDebug, getLogLevel())
-		assert.Equal(t, "false", getEnv("LOG_TO_FILE", "true"))
-
-		// Clean up
-		for key := range envVars {
-			os.Unsetenv(key)
-		}
-	})
+	assert.Equal(t, slog.LevelInfo, level.Level())
+	assert.Equal(t, "info", cfg.LogLevel)
+	assert.Contains(t, buf.String(), "Configuration reload failed")
 }
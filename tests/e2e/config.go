@@ -0,0 +1,133 @@
+package e2e
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of the environment's targets and latency ceilings
+const (
+	DefaultStableURL   = "https://example.com/"
+	DefaultNotFoundURL = "https://example.com/webpage-analyzer-e2e-not-found"
+	// The ceilings are generous: the suite catches a broken deployment,
+	// not a slow page
+	DefaultAnalyzeCeiling = 30 * time.Second
+	DefaultBatchCeiling   = 60 * time.Second
+	DefaultHealthCeiling  = 2 * time.Second
+	DefaultReportPath     = "e2e-report.xml"
+)
+
+// Config is what the suite checks and how, from the environment
+type Config struct {
+	// GatewayURL is the gateway of the environment, such as
+	// https://analyzer.staging.example.com
+	GatewayURL string
+	// Insecure skips the verification of the gateway's certificate, for
+	// staging environments with self-signed ones
+	Insecure bool
+	// AuthHeader is sent on the API calls as "Name: value", such as
+	// "Authorization: Bearer <token>", when the gateway asks for one
+	AuthHeader string
+	// StableURL is a page that answers 200 with HTML, NotFoundURL one that
+	// answers 404
+	StableURL   string
+	NotFoundURL string
+	// The latency ceilings of an analysis, a batch and a health check
+	AnalyzeCeiling time.Duration
+	BatchCeiling   time.Duration
+	HealthCeiling  time.Duration
+	// ReportPath is where the JUnit report is written
+	ReportPath string
+}
+
+// ConfigFromEnv reads the Config from GATEWAY_URL, E2E_INSECURE,
+// E2E_AUTH_HEADER, E2E_STABLE_URL, E2E_NOT_FOUND_URL,
+// E2E_ANALYZE_MAX_LATENCY, E2E_BATCH_MAX_LATENCY, E2E_HEALTH_MAX_LATENCY
+// and E2E_REPORT, naming the variables it cannot use
+func ConfigFromEnv() (Config, error) {
+	return configFrom(os.Getenv)
+}
+
+func configFrom(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		GatewayURL:     strings.TrimRight(getenv("GATEWAY_URL"), "/"),
+		AuthHeader:     getenv("E2E_AUTH_HEADER"),
+		StableURL:      getenv("E2E_STABLE_URL"),
+		NotFoundURL:    getenv("E2E_NOT_FOUND_URL"),
+		ReportPath:     getenv("E2E_REPORT"),
+		AnalyzeCeiling: DefaultAnalyzeCeiling,
+		BatchCeiling:   DefaultBatchCeiling,
+		HealthCeiling:  DefaultHealthCeiling,
+	}
+	if cfg.StableURL == "" {
+		cfg.StableURL = DefaultStableURL
+	}
+	if cfg.NotFoundURL == "" {
+		cfg.NotFoundURL = DefaultNotFoundURL
+	}
+	if cfg.ReportPath == "" {
+		cfg.ReportPath = DefaultReportPath
+	}
+
+	var errs []error
+	if cfg.GatewayURL == "" {
+		errs = append(errs, errors.New("GATEWAY_URL: must be set"))
+	} else if u, err := url.Parse(cfg.GatewayURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("GATEWAY_URL: must be an http or https URL, got %q", cfg.GatewayURL))
+	}
+	if value := getenv("E2E_INSECURE"); value != "" {
+		insecure, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("E2E_INSECURE: must be true or false, got %q", value))
+		}
+		cfg.Insecure = insecure
+	}
+	if cfg.AuthHeader != "" {
+		if name, _, ok := strings.Cut(cfg.AuthHeader, ":"); !ok || strings.TrimSpace(name) == "" {
+			errs = append(errs, errors.New(`E2E_AUTH_HEADER: must be "Name: value"`))
+		}
+	}
+	for _, ceiling := range []struct {
+		name string
+		d    *time.Duration
+	}{
+		{"E2E_ANALYZE_MAX_LATENCY", &cfg.AnalyzeCeiling},
+		{"E2E_BATCH_MAX_LATENCY", &cfg.BatchCeiling},
+		{"E2E_HEALTH_MAX_LATENCY", &cfg.HealthCeiling},
+	} {
+		value := getenv(ceiling.name)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("%s: must be a positive duration, got %q", ceiling.name, value))
+			continue
+		}
+		*ceiling.d = d
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// Header returns the name and value of AuthHeader, empty when unset
+func (c Config) Header() (name, value string) {
+	name, value, _ = strings.Cut(c.AuthHeader, ":")
+	return strings.TrimSpace(name), strings.TrimSpace(value)
+}
+
+// HTTPClient returns the client calls to the gateway go through, which
+// does not verify the gateway's certificate when Insecure is set
+func (c Config) HTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,117 @@
+package e2e
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFrom(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		want     Config
+		contains []string
+	}{
+		{
+			name: "defaults",
+			env:  map[string]string{"GATEWAY_URL": "https://staging.example.com/"},
+			want: Config{
+				GatewayURL:     "https://staging.example.com",
+				StableURL:      DefaultStableURL,
+				NotFoundURL:    DefaultNotFoundURL,
+				AnalyzeCeiling: DefaultAnalyzeCeiling,
+				BatchCeiling:   DefaultBatchCeiling,
+				HealthCeiling:  DefaultHealthCeiling,
+				ReportPath:     DefaultReportPath,
+			},
+		},
+		{
+			name: "everything set",
+			env: map[string]string{
+				"GATEWAY_URL":             "https://staging.example.com",
+				"E2E_INSECURE":            "true",
+				"E2E_AUTH_HEADER":         "Authorization: Bearer token",
+				"E2E_STABLE_URL":          "https://www.example.org/",
+				"E2E_NOT_FOUND_URL":       "https://www.example.org/gone",
+				"E2E_ANALYZE_MAX_LATENCY": "10s",
+				"E2E_BATCH_MAX_LATENCY":   "20s",
+				"E2E_HEALTH_MAX_LATENCY":  "500ms",
+				"E2E_REPORT":              "/tmp/report.xml",
+			},
+			want: Config{
+				GatewayURL:     "https://staging.example.com",
+				Insecure:       true,
+				AuthHeader:     "Authorization: Bearer token",
+				StableURL:      "https://www.example.org/",
+				NotFoundURL:    "https://www.example.org/gone",
+				AnalyzeCeiling: 10 * time.Second,
+				BatchCeiling:   20 * time.Second,
+				HealthCeiling:  500 * time.Millisecond,
+				ReportPath:     "/tmp/report.xml",
+			},
+		},
+		{
+			name:     "no gateway",
+			env:      map[string]string{},
+			contains: []string{"GATEWAY_URL: must be set"},
+		},
+		{
+			name: "invalid values",
+			env: map[string]string{
+				"GATEWAY_URL":            "staging.example.com",
+				"E2E_INSECURE":           "maybe",
+				"E2E_AUTH_HEADER":        "token",
+				"E2E_HEALTH_MAX_LATENCY": "0s",
+			},
+			contains: []string{
+				`GATEWAY_URL: must be an http or https URL, got "staging.example.com"`,
+				`E2E_INSECURE: must be true or false, got "maybe"`,
+				`E2E_AUTH_HEADER: must be "Name: value"`,
+				`E2E_HEALTH_MAX_LATENCY: must be a positive duration, got "0s"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := configFrom(func(name string) string { return tt.env[name] })
+			if len(tt.contains) > 0 {
+				require.Error(t, err)
+				for _, s := range tt.contains {
+					assert.Contains(t, err.Error(), s)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cfg)
+		})
+	}
+}
+
+func TestConfig_Header(t *testing.T) {
+	name, value := Config{AuthHeader: "Authorization: Bearer a:b"}.Header()
+	assert.Equal(t, "Authorization", name)
+	assert.Equal(t, "Bearer a:b", value)
+
+	name, value = Config{}.Header()
+	assert.Empty(t, name)
+	assert.Empty(t, value)
+}
+
+func TestConfig_HTTPClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := Config{}.HTTPClient().Get(server.URL)
+	assert.Error(t, err, "the self-signed certificate is verified")
+
+	resp, err := Config{Insecure: true}.HTTPClient().Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,276 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/clients/go/gatewayclient"
+)
+
+var (
+	cfg    Config
+	report = NewReport("e2e")
+)
+
+// timestampSkew is how far the environment's clock may be from the suite's
+const timestampSkew = 10 * time.Minute
+
+func TestMain(m *testing.M) {
+	var err error
+	cfg, err = ConfigFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: %v\n", err)
+		os.Exit(2)
+	}
+	report.SetProperty("gateway_url", cfg.GatewayURL)
+	report.SetProperty("stable_url", cfg.StableURL)
+	report.SetProperty("not_found_url", cfg.NotFoundURL)
+
+	code := m.Run()
+	if err := report.WriteJUnitFile(cfg.ReportPath); err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: writing the report: %v\n", err)
+		code = max(code, 1)
+	}
+	os.Exit(code)
+}
+
+// client is the typed gateway client, without retries so failures and
+// latencies are those of a single call
+func client() *gatewayclient.Client {
+	opts := []gatewayclient.Option{
+		gatewayclient.WithHTTPClient(cfg.HTTPClient()),
+		gatewayclient.WithRetries(0, 0),
+	}
+	if name, value := cfg.Header(); name != "" {
+		opts = append(opts, gatewayclient.WithAuthHeader(name, value))
+	}
+	return gatewayclient.New(cfg.GatewayURL, opts...)
+}
+
+// call sends a request to the gateway as is, for what the typed client
+// cannot send or does not show, and returns the response with its body
+func call(c *Check, method, path, body string, ceiling time.Duration) (*http.Response, []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*ceiling)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, cfg.GatewayURL+path, bytes.NewReader([]byte(body)))
+	if err != nil {
+		c.Fatalf("%s %s: %v", method, path, err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if name, value := cfg.Header(); name != "" {
+		req.Header.Set(name, value)
+	}
+
+	start := time.Now()
+	resp, err := cfg.HTTPClient().Do(req)
+	if err != nil {
+		c.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.Fatalf("%s %s: reading the response: %v", method, path, err)
+	}
+	c.Latency(method+" "+path, time.Since(start), ceiling)
+	return resp, data
+}
+
+func TestHealth(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		want   Object
+	}{
+		{
+			name:   "health",
+			path:   "/health",
+			status: http.StatusOK,
+			want: Object{
+				"status":    "healthy",
+				"service":   "gateway",
+				"checks":    Object{"analyzer_service": "healthy"},
+				"timestamp": Recent(timestampSkew),
+			},
+		},
+		{
+			name:   "ready",
+			path:   "/health/ready",
+			status: http.StatusOK,
+			want:   Object{"status": "ready", "service": "gateway", "timestamp": Recent(timestampSkew)},
+		},
+		{
+			name:   "version",
+			path:   "/version",
+			status: http.StatusOK,
+			want:   Object{"service": "gateway", "version": NonEmptyString(), "commit": NonEmptyString()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := report.Check(t)
+			resp, body := call(c, http.MethodGet, tt.path, "", cfg.HealthCeiling)
+			c.Status(resp, tt.status)
+			c.JSON(tt.path, body, tt.want)
+		})
+	}
+}
+
+func TestAnalyze_StablePage(t *testing.T) {
+	c := report.Check(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*cfg.AnalyzeCeiling)
+	defer cancel()
+
+	start := time.Now()
+	result, err := client().Analyze(ctx, gatewayclient.AnalyzeParams{}, gatewayclient.AnalysisRequest{URL: cfg.StableURL})
+	if err != nil {
+		c.Fatalf("analyzing %s: %v", cfg.StableURL, err)
+	}
+	c.Latency("analysis", time.Since(start), cfg.AnalyzeCeiling)
+
+	count := AtLeast(0)
+	c.Value("result", result, Object{
+		"url":          cfg.StableURL,
+		"html_version": NonEmptyString(),
+		"title":        NonEmptyString(),
+		"headings":     Object{"h1": count, "h2": count, "h3": count, "h4": count, "h5": count, "h6": count},
+		"links": Object{
+			"internal":     count,
+			"external":     count,
+			"inaccessible": count,
+			"total":        count,
+		},
+		"has_login_form": Any(),
+		"analyzed_at":    Recent(timestampSkew),
+		"timings":        Object{"fetch_ms": count, "total_ms": count},
+		"findings":       ArrayOf(0, Object{"id": NonEmptyString(), "severity": OneOf("info", "warning", "error"), "message": NonEmptyString()}),
+		"result_hash":    Pattern(`^[0-9a-f]{64}$`),
+	})
+	if links := result.Links; links.Total != links.Internal+links.External {
+		c.Errorf("links.total %d is not internal %d + external %d", links.Total, links.Internal, links.External)
+	}
+	if result.Links.Inaccessible > result.Links.Total {
+		c.Errorf("links.inaccessible %d is more than links.total %d", result.Links.Inaccessible, result.Links.Total)
+	}
+}
+
+func TestAnalyze_NotFoundPage(t *testing.T) {
+	c := report.Check(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*cfg.AnalyzeCeiling)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client().Analyze(ctx, gatewayclient.AnalyzeParams{}, gatewayclient.AnalysisRequest{URL: cfg.NotFoundURL})
+	c.Latency("analysis", time.Since(start), cfg.AnalyzeCeiling)
+
+	var apiErr *gatewayclient.APIError
+	if !errors.As(err, &apiErr) {
+		c.Fatalf("analyzing %s: want a gateway error, got %v", cfg.NotFoundURL, err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest {
+		c.Errorf("want status %d, got %d", http.StatusBadRequest, apiErr.StatusCode)
+	}
+	if apiErr.RequestID == "" {
+		c.Errorf("the response has no X-Request-ID")
+	}
+	c.Value("error", apiErr.Response, Object{
+		"error":       "HTTP error: status code 404",
+		"status_code": http.StatusBadRequest,
+		"timestamp":   Recent(timestampSkew),
+	})
+}
+
+func TestAnalyze_InvalidRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status Matcher
+		error  any
+	}{
+		{
+			name: "invalid URL",
+			body: `{"url":"not a url"}`,
+			// The gateway passes the URL on, and the analyzer fails it as
+			// it fails any page it cannot fetch
+			status: OneOf(http.StatusBadRequest, http.StatusBadGateway),
+			error:  NonEmptyString(),
+		},
+		{
+			name:   "no URL",
+			body:   `{"url":""}`,
+			status: OneOf(http.StatusBadRequest),
+			error:  "URL is required",
+		},
+		{
+			name:   "malformed JSON",
+			body:   `{"url":`,
+			status: OneOf(http.StatusBadRequest),
+			error:  "Invalid request format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := report.Check(t)
+			resp, body := call(c, http.MethodPost, "/api/v2/analyze", tt.body, cfg.AnalyzeCeiling)
+			if err := tt.status.Match(float64(resp.StatusCode)); err != nil {
+				c.Errorf("status: %v", err)
+			}
+			if resp.Header.Get("X-Request-ID") == "" {
+				c.Errorf("the response has no X-Request-ID")
+			}
+			c.JSON("error", body, Object{
+				"error":       tt.error,
+				"status_code": float64(resp.StatusCode),
+				"request_id":  resp.Header.Get("X-Request-ID"),
+				"timestamp":   Recent(timestampSkew),
+			})
+		})
+	}
+}
+
+func TestBatchAnalyze(t *testing.T) {
+	c := report.Check(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*cfg.BatchCeiling)
+	defer cancel()
+
+	urls := []string{cfg.StableURL, cfg.NotFoundURL}
+	start := time.Now()
+	batch, err := client().BatchAnalyze(ctx, gatewayclient.BatchAnalyzeParams{}, gatewayclient.BatchAnalysisRequest{URLs: urls})
+	if err != nil {
+		c.Fatalf("analyzing the batch: %v", err)
+	}
+	c.Latency("batch", time.Since(start), cfg.BatchCeiling)
+
+	c.Value("batch", batch, Object{
+		"items": []any{
+			Object{
+				"url":    cfg.StableURL,
+				"status": "succeeded",
+				"result": Object{"url": cfg.StableURL, "title": NonEmptyString(), "analyzed_at": Recent(timestampSkew)},
+				"error":  Absent(),
+			},
+			Object{
+				"url":    cfg.NotFoundURL,
+				"status": "failed",
+				"result": Absent(),
+				"error":  Object{"error": "HTTP error: status code 404", "status_code": http.StatusBadRequest},
+			},
+		},
+		"succeeded":     1,
+		"failed":        1,
+		"total_time_ms": AtLeast(0),
+	})
+}
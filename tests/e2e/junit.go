@@ -0,0 +1,248 @@
+package e2e
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TB is the part of *testing.T a Check reports to
+type TB interface {
+	Helper()
+	Name() string
+	Errorf(format string, args ...any)
+	FailNow()
+	Failed() bool
+	Skipped() bool
+	Cleanup(func())
+}
+
+// Report collects the outcome of each check of a run and writes it as a
+// JUnit XML report, which CI systems show per test case
+type Report struct {
+	name    string
+	started time.Time
+
+	mu         sync.Mutex
+	properties [][2]string
+	cases      []testCase
+}
+
+type testCase struct {
+	name     string
+	duration time.Duration
+	failures []string
+	failed   bool
+	skipped  bool
+}
+
+// NewReport returns an empty report of the suite called name
+func NewReport(name string) *Report {
+	return &Report{name: name, started: time.Now()}
+}
+
+// SetProperty records a property of the run, such as the environment
+// checked
+func (r *Report) SetProperty(name, value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.properties = append(r.properties, [2]string{name, value})
+}
+
+// Check starts the check of t, which is recorded in the report once t is
+// done
+func (r *Report) Check(t TB) *Check {
+	c := &Check{t: t, started: time.Now()}
+	t.Cleanup(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.cases = append(r.cases, testCase{
+			name:     t.Name(),
+			duration: time.Since(c.started),
+			failures: c.failures,
+			failed:   t.Failed(),
+			skipped:  t.Skipped(),
+		})
+	})
+	return c
+}
+
+// Check is the check of one test case: its failures go both to the test
+// and to the report
+type Check struct {
+	t        TB
+	started  time.Time
+	failures []string
+}
+
+// Errorf records a failure and carries on
+func (c *Check) Errorf(format string, args ...any) {
+	c.t.Helper()
+	message := fmt.Sprintf(format, args...)
+	c.failures = append(c.failures, message)
+	c.t.Errorf("%s", message)
+}
+
+// Fatalf records a failure and stops the test
+func (c *Check) Fatalf(format string, args ...any) {
+	c.t.Helper()
+	c.Errorf(format, args...)
+	c.t.FailNow()
+}
+
+// Status checks the status code of resp, reporting whether it is want
+func (c *Check) Status(resp *http.Response, want int) bool {
+	c.t.Helper()
+	if resp.StatusCode != want {
+		c.Errorf("%s %s: want status %d, got %d", resp.Request.Method, resp.Request.URL.Path, want, resp.StatusCode)
+		return false
+	}
+	return true
+}
+
+// Latency checks that what took elapsed stayed under ceiling
+func (c *Check) Latency(what string, elapsed, ceiling time.Duration) {
+	c.t.Helper()
+	if elapsed > ceiling {
+		c.Errorf("%s took %s, over the ceiling of %s", what, elapsed.Round(time.Millisecond), ceiling)
+	}
+}
+
+// JSON matches the JSON document body against want, recording a failure
+// per mismatch
+func (c *Check) JSON(what string, body []byte, want any) bool {
+	c.t.Helper()
+	mismatches := MatchJSON(body, want)
+	for _, mismatch := range mismatches {
+		c.Errorf("%s: %s", what, mismatch)
+	}
+	return len(mismatches) == 0
+}
+
+// Value matches a value that was decoded, or encoded, from JSON against
+// want, as JSON does
+func (c *Check) Value(what string, got, want any) bool {
+	c.t.Helper()
+	mismatches := Match(normalize(got), want)
+	for _, mismatch := range mismatches {
+		c.Errorf("%s: %s", what, mismatch)
+	}
+	return len(mismatches) == 0
+}
+
+// The JUnit XML elements, as read by Jenkins, GitLab and GitHub reporters
+type (
+	junitSuites struct {
+		XMLName  xml.Name     `xml:"testsuites"`
+		Tests    int          `xml:"tests,attr"`
+		Failures int          `xml:"failures,attr"`
+		Skipped  int          `xml:"skipped,attr"`
+		Time     string       `xml:"time,attr"`
+		Suites   []junitSuite `xml:"testsuite"`
+	}
+	junitSuite struct {
+		Name       string          `xml:"name,attr"`
+		Tests      int             `xml:"tests,attr"`
+		Failures   int             `xml:"failures,attr"`
+		Skipped    int             `xml:"skipped,attr"`
+		Time       string          `xml:"time,attr"`
+		Timestamp  string          `xml:"timestamp,attr"`
+		Properties []junitProperty `xml:"properties>property,omitempty"`
+		Cases      []junitCase     `xml:"testcase"`
+	}
+	junitProperty struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	}
+	junitCase struct {
+		Name      string        `xml:"name,attr"`
+		ClassName string        `xml:"classname,attr"`
+		Time      string        `xml:"time,attr"`
+		Failure   *junitFailure `xml:"failure,omitempty"`
+		Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	}
+	junitFailure struct {
+		Message string `xml:"message,attr"`
+		Text    string `xml:",chardata"`
+	}
+	junitSkipped struct{}
+)
+
+// WriteJUnit writes the report as JUnit XML, the test cases in the order
+// of their names. A test that failed without a recorded failure, through
+// t.Error directly, still counts as failed.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	r.mu.Lock()
+	cases := slices.Clone(r.cases)
+	properties := slices.Clone(r.properties)
+	r.mu.Unlock()
+	slices.SortStableFunc(cases, func(a, b testCase) int { return strings.Compare(a.name, b.name) })
+
+	suite := junitSuite{
+		Name:      r.name,
+		Tests:     len(cases),
+		Time:      seconds(time.Since(r.started)),
+		Timestamp: r.started.UTC().Format(time.RFC3339),
+	}
+	for _, property := range properties {
+		suite.Properties = append(suite.Properties, junitProperty{Name: property[0], Value: property[1]})
+	}
+	for _, c := range cases {
+		className, _, _ := strings.Cut(c.name, "/")
+		junit := junitCase{Name: c.name, ClassName: r.name + "." + className, Time: seconds(c.duration)}
+		switch {
+		case c.failed:
+			suite.Failures++
+			failures := c.failures
+			if len(failures) == 0 {
+				failures = []string{"failed, see the test output"}
+			}
+			junit.Failure = &junitFailure{Message: failures[0], Text: strings.Join(failures, "\n")}
+		case c.skipped:
+			suite.Skipped++
+			junit.Skipped = &junitSkipped{}
+		}
+		suite.Cases = append(suite.Cases, junit)
+	}
+
+	suites := junitSuites{
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitSuite{suite},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteJUnitFile writes the report to the file at path
+func (r *Report) WriteJUnitFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.WriteJUnit(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,79 @@
+package e2e
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTB is a test that runs its cleanups when done
+type fakeTB struct {
+	name     string
+	errors   []string
+	failed   bool
+	skipped  bool
+	cleanups []func()
+}
+
+func (f *fakeTB) Helper()           {}
+func (f *fakeTB) Name() string      { return f.name }
+func (f *fakeTB) FailNow()          { f.failed = true }
+func (f *fakeTB) Failed() bool      { return f.failed }
+func (f *fakeTB) Skipped() bool     { return f.skipped }
+func (f *fakeTB) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.failed = true
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) done() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func TestReport_WriteJUnit(t *testing.T) {
+	report := NewReport("e2e")
+	report.SetProperty("gateway_url", "https://staging.example.com")
+
+	passed := &fakeTB{name: "TestHealth/ready"}
+	report.Check(passed)
+	passed.done()
+
+	failed := &fakeTB{name: "TestAnalyze_StablePage"}
+	check := report.Check(failed)
+	check.Latency("analysis", 3*time.Second, time.Second)
+	check.JSON("result", []byte(`{"title":""}`), Object{"title": NonEmptyString()})
+	failed.done()
+
+	skipped := &fakeTB{name: "TestBatchAnalyze", skipped: true}
+	report.Check(skipped)
+	skipped.done()
+
+	// Failed through the test directly, with nothing recorded
+	silent := &fakeTB{name: "TestHealth/health", failed: true}
+	report.Check(silent)
+	silent.done()
+
+	assert.Equal(t, []string{
+		"analysis took 3s, over the ceiling of 1s",
+		`result: title: want a non-empty string, got ""`,
+	}, failed.errors, "failures go to the test as well")
+
+	var out bytes.Buffer
+	require.NoError(t, report.WriteJUnit(&out))
+	xml := out.String()
+	assert.Contains(t, xml, `<testsuites tests="4" failures="2" skipped="1"`)
+	assert.Contains(t, xml, `<property name="gateway_url" value="https://staging.example.com"></property>`)
+	assert.Contains(t, xml, `<testcase name="TestAnalyze_StablePage" classname="e2e.TestAnalyze_StablePage"`)
+	assert.Contains(t, xml, `<failure message="analysis took 3s, over the ceiling of 1s">analysis took 3s, over the ceiling of 1s&#xA;result: title: want a non-empty string, got &#34;&#34;</failure>`)
+	assert.Contains(t, xml, `<failure message="failed, see the test output">`)
+	assert.Contains(t, xml, `<testcase name="TestHealth/ready" classname="e2e.TestHealth"`)
+	assert.Contains(t, xml, `<skipped></skipped>`)
+	assert.Less(t, bytes.Index(out.Bytes(), []byte("TestAnalyze_StablePage")), bytes.Index(out.Bytes(), []byte("TestHealth/ready")), "cases are in the order of their names")
+}
@@ -0,0 +1,298 @@
+// Package e2e checks a deployed environment against the gateway's contract:
+// the suite in e2e_test.go, built with the e2e tag, sends real requests to
+// GATEWAY_URL and reports in JUnit form. This file is the matching its
+// assertions rely on, tolerant of the fields that legitimately vary from one
+// run to the next, such as timestamps, durations and request IDs.
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Object is the expected shape of a JSON object: each key is matched
+// against its value, a literal or a Matcher, and keys left out may hold
+// anything
+type Object map[string]any
+
+// Matcher matches a value of a decoded JSON document, returning why it does
+// not
+type Matcher interface {
+	Match(got any) error
+	String() string
+}
+
+// Mismatch is a value that does not match, at Path such as
+// "links.slowest_links[0].url"
+type Mismatch struct {
+	Path   string
+	Reason string
+}
+
+func (m Mismatch) String() string {
+	if m.Path == "" {
+		return m.Reason
+	}
+	return m.Path + ": " + m.Reason
+}
+
+// MatchJSON decodes body and matches it against want
+func MatchJSON(body []byte, want any) []Mismatch {
+	var got any
+	if err := json.Unmarshal(body, &got); err != nil {
+		return []Mismatch{{Reason: fmt.Sprintf("not JSON: %v", err)}}
+	}
+	return Match(got, want)
+}
+
+// Match matches got, as decoded from JSON, against want: an Object matches
+// the keys it lists, a slice matches element by element, a Matcher as it
+// decides, and any other value when it is equal once encoded as JSON
+func Match(got, want any) []Mismatch {
+	var mismatches []Mismatch
+	match("", got, want, &mismatches)
+	return mismatches
+}
+
+func match(path string, got, want any, mismatches *[]Mismatch) {
+	fail := func(format string, args ...any) {
+		*mismatches = append(*mismatches, Mismatch{Path: path, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	switch want := want.(type) {
+	case Matcher:
+		if err := want.Match(got); err != nil {
+			fail("%v", err)
+		}
+	case Object:
+		object, ok := got.(map[string]any)
+		if !ok {
+			fail("want an object, got %s", describe(got))
+			return
+		}
+		for _, key := range slices.Sorted(maps.Keys(want)) {
+			value, present := object[key]
+			_, wantAbsent := want[key].(absent)
+			switch {
+			case present:
+				match(join(path, key), value, want[key], mismatches)
+			case !wantAbsent:
+				*mismatches = append(*mismatches, Mismatch{Path: join(path, key), Reason: "missing"})
+			}
+		}
+	case []any:
+		array, ok := got.([]any)
+		if !ok {
+			fail("want an array, got %s", describe(got))
+			return
+		}
+		if len(array) != len(want) {
+			fail("want %d elements, got %d", len(want), len(array))
+			return
+		}
+		for i := range want {
+			match(fmt.Sprintf("%s[%d]", path, i), array[i], want[i], mismatches)
+		}
+	default:
+		if literal := normalize(want); !reflect.DeepEqual(got, literal) {
+			fail("want %s, got %s", describe(literal), describe(got))
+		}
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// normalize turns a Go literal into what decoding its JSON gives, so 200
+// compares equal to the float64 200 of a decoded document
+func normalize(value any) any {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var decoded any
+	if json.Unmarshal(data, &decoded) != nil {
+		return value
+	}
+	return decoded
+}
+
+// describe renders a decoded value for a mismatch, shortened when long
+func describe(value any) string {
+	if value == nil {
+		return "null"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	if len(data) > 80 {
+		return string(data[:77]) + "..."
+	}
+	return string(data)
+}
+
+// matcherFunc is a Matcher made of a function and its description
+type matcherFunc struct {
+	match       func(got any) error
+	description string
+}
+
+func (m matcherFunc) Match(got any) error { return m.match(got) }
+func (m matcherFunc) String() string      { return m.description }
+
+func newMatcher(description string, match func(got any) error) Matcher {
+	return matcherFunc{match: match, description: description}
+}
+
+// absent is the Matcher of Absent
+type absent struct{}
+
+func (absent) Match(got any) error { return fmt.Errorf("want absent, got %s", describe(got)) }
+func (absent) String() string      { return "absent" }
+
+// Absent matches a key missing from its object
+func Absent() Matcher { return absent{} }
+
+// Any matches any value, null included; as the value of a key it only asks
+// for the key to be there
+func Any() Matcher {
+	return newMatcher("any value", func(any) error { return nil })
+}
+
+// NonEmptyString matches a string with something in it
+func NonEmptyString() Matcher {
+	return newMatcher("a non-empty string", func(got any) error {
+		if s, ok := got.(string); !ok || s == "" {
+			return fmt.Errorf("want a non-empty string, got %s", describe(got))
+		}
+		return nil
+	})
+}
+
+// Contains matches a string holding substr
+func Contains(substr string) Matcher {
+	return newMatcher(fmt.Sprintf("a string containing %q", substr), func(got any) error {
+		if s, ok := got.(string); !ok || !strings.Contains(s, substr) {
+			return fmt.Errorf("want a string containing %q, got %s", substr, describe(got))
+		}
+		return nil
+	})
+}
+
+// Pattern matches a string matching the regular expression expr
+func Pattern(expr string) Matcher {
+	re := regexp.MustCompile(expr)
+	return newMatcher("a string matching "+expr, func(got any) error {
+		if s, ok := got.(string); !ok || !re.MatchString(s) {
+			return fmt.Errorf("want a string matching %s, got %s", expr, describe(got))
+		}
+		return nil
+	})
+}
+
+// Between matches a number from lo to hi, both included
+func Between(lo, hi float64) Matcher {
+	description := fmt.Sprintf("a number from %s to %s", formatNumber(lo), formatNumber(hi))
+	return newMatcher(description, func(got any) error {
+		if n, ok := got.(float64); !ok || n < lo || n > hi {
+			return fmt.Errorf("want %s, got %s", description, describe(got))
+		}
+		return nil
+	})
+}
+
+// AtLeast matches a number of at least lo
+func AtLeast(lo float64) Matcher {
+	description := "a number of at least " + formatNumber(lo)
+	return newMatcher(description, func(got any) error {
+		if n, ok := got.(float64); !ok || n < lo {
+			return fmt.Errorf("want %s, got %s", description, describe(got))
+		}
+		return nil
+	})
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// OneOf matches a value equal to one of values
+func OneOf(values ...any) Matcher {
+	normalized := make([]any, len(values))
+	descriptions := make([]string, len(values))
+	for i, value := range values {
+		normalized[i] = normalize(value)
+		descriptions[i] = describe(normalized[i])
+	}
+	description := "one of " + strings.Join(descriptions, ", ")
+	return newMatcher(description, func(got any) error {
+		for _, value := range normalized {
+			if reflect.DeepEqual(got, value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("want %s, got %s", description, describe(got))
+	})
+}
+
+// Recent matches an RFC 3339 timestamp no further than within from now,
+// either way, which leaves room for clock skew between the suite and the
+// environment
+func Recent(within time.Duration) Matcher {
+	description := fmt.Sprintf("a timestamp within %s of now", within)
+	return newMatcher(description, func(got any) error {
+		s, ok := got.(string)
+		if !ok {
+			return fmt.Errorf("want %s, got %s", description, describe(got))
+		}
+		at, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return fmt.Errorf("want %s, got %s", description, describe(got))
+		}
+		if skew := time.Since(at).Abs(); skew > within {
+			return fmt.Errorf("want %s, got %s, %s off", description, s, skew.Round(time.Second))
+		}
+		return nil
+	})
+}
+
+// ArrayOf matches an array of min elements or more, each matching each
+func ArrayOf(min int, each any) Matcher {
+	description := fmt.Sprintf("an array of at least %d elements", min)
+	return newMatcher(description, func(got any) error {
+		array, ok := got.([]any)
+		if !ok || len(array) < min {
+			return fmt.Errorf("want %s, got %s", description, describe(got))
+		}
+		var mismatches []string
+		for i, element := range array {
+			for _, mismatch := range Match(element, each) {
+				mismatch.Path = fmt.Sprintf("[%d]", i) + prefixDot(mismatch.Path)
+				mismatches = append(mismatches, mismatch.String())
+			}
+		}
+		if len(mismatches) > 0 {
+			return fmt.Errorf("%s", strings.Join(mismatches, "; "))
+		}
+		return nil
+	})
+}
+
+func prefixDot(path string) string {
+	if path == "" {
+		return ""
+	}
+	return "." + path
+}
@@ -0,0 +1,93 @@
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchJSON(t *testing.T) {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	body := []byte(`{
+		"url": "https://example.com/",
+		"title": "Example Domain",
+		"status_code": 200,
+		"links": {"total": 3, "internal": 2, "external": 1},
+		"analyzed_at": "` + now + `",
+		"result_hash": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		"findings": [{"id": "TITLE_TOO_SHORT", "severity": "warning"}],
+		"tags": ["a", "b"],
+		"extra": {"ignored": true}
+	}`)
+
+	tests := []struct {
+		name string
+		want any
+		// mismatches are the expected ones, as strings
+		mismatches []string
+	}{
+		{
+			name: "matches",
+			want: Object{
+				"url":         "https://example.com/",
+				"title":       NonEmptyString(),
+				"status_code": 200,
+				"links":       Object{"total": AtLeast(1), "internal": Between(0, 3)},
+				"analyzed_at": Recent(time.Minute),
+				"result_hash": Pattern(`^[0-9a-f]{64}$`),
+				"findings":    ArrayOf(1, Object{"id": NonEmptyString(), "severity": OneOf("info", "warning", "error")}),
+				"tags":        []any{"a", Contains("b")},
+				"extra":       Any(),
+				"screenshot":  Absent(),
+			},
+		},
+		{
+			name: "mismatches",
+			want: Object{
+				"url":         "https://example.org/",
+				"status_code": OneOf(400, 502),
+				"links":       Object{"total": Between(4, 10), "unique": AtLeast(0)},
+				"title":       Absent(),
+				"tags":        []any{"a"},
+				"findings":    ArrayOf(1, Object{"severity": "error"}),
+			},
+			mismatches: []string{
+				`findings: [0].severity: want "error", got "warning"`,
+				`links.total: want a number from 4 to 10, got 3`,
+				`links.unique: missing`,
+				`status_code: want one of 400, 502, got 200`,
+				`tags: want 1 elements, got 2`,
+				`title: want absent, got "Example Domain"`,
+				`url: want "https://example.org/", got "https://example.com/"`,
+			},
+		},
+		{
+			name:       "wrong types",
+			want:       Object{"links": []any{}, "tags": Object{}, "title": AtLeast(0), "url": Recent(time.Minute)},
+			mismatches: []string{`links: want an array, got {"external":1,"internal":2,"total":3}`, `tags: want an object, got ["a","b"]`, `title: want a number of at least 0, got "Example Domain"`, `url: want a timestamp within 1m0s of now, got "https://example.com/"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, mismatch := range MatchJSON(body, tt.want) {
+				got = append(got, mismatch.String())
+			}
+			assert.Equal(t, tt.mismatches, got)
+		})
+	}
+}
+
+func TestMatchJSON_NotJSON(t *testing.T) {
+	mismatches := MatchJSON([]byte("<html>"), Object{})
+	assert.Len(t, mismatches, 1)
+	assert.Contains(t, mismatches[0].String(), "not JSON")
+}
+
+func TestRecent(t *testing.T) {
+	assert.NoError(t, Recent(time.Minute).Match(time.Now().Add(30*time.Second).Format(time.RFC3339)), "a clock ahead is tolerated")
+	assert.Error(t, Recent(time.Minute).Match(time.Now().Add(-time.Hour).Format(time.RFC3339)))
+	assert.Error(t, Recent(time.Minute).Match("yesterday"))
+}
@@ -13,11 +13,12 @@ import (
 	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
 	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
 	"github.com/RuvinSL/webpage-analyzer/pkg/metrics"
+	"github.com/RuvinSL/webpage-analyzer/pkg/middleware"
 	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/pkg/testsupport"
 	"github.com/RuvinSL/webpage-analyzer/services/analyzer/core"
 	analyzerHandlers "github.com/RuvinSL/webpage-analyzer/services/analyzer/handlers"
 	"github.com/RuvinSL/webpage-analyzer/services/gateway/handlers"
-	"github.com/RuvinSL/webpage-analyzer/services/gateway/middleware"
 	linkCore "github.com/RuvinSL/webpage-analyzer/services/link-checker/core"
 	linkHandlers "github.com/RuvinSL/webpage-analyzer/services/link-checker/handlers"
 	"github.com/gorilla/mux"
@@ -30,7 +31,8 @@ func TestIntegrationCompleteAnalysisFlow(t *testing.T) {
 		t.Skip("Skipping integration test")
 	}
 
-	// Start all services
+	// Start all services, and the site they analyze
+	site := testsupport.NewServer(t)
 	linkCheckerURL := startLinkCheckerService(t)
 	analyzerURL := startAnalyzerService(t, linkCheckerURL)
 	gatewayURL := startGatewayService(t, analyzerURL)
@@ -39,7 +41,7 @@ func TestIntegrationCompleteAnalysisFlow(t *testing.T) {
 	t.Run("analyze_webpage", func(t *testing.T) {
 		// Prepare request
 		reqBody := models.AnalysisRequest{
-			URL: "https://example.com",
+			URL: site.PageURL(testsupport.PathHTML5),
 		}
 		jsonData, err := json.Marshal(reqBody)
 		require.NoError(t, err)
@@ -62,10 +64,24 @@ func TestIntegrationCompleteAnalysisFlow(t *testing.T) {
 		require.NoError(t, err)
 
 		// Verify result
-		assert.Equal(t, "https://example.com", result.URL)
-		assert.NotEmpty(t, result.HTMLVersion)
-		assert.NotEmpty(t, result.Title)
-		assert.True(t, result.Links.Total >= 0)
+		testsupport.AssertMatches(t, site.Expected(testsupport.PathHTML5), &result)
+	})
+
+	t.Run("analyze_fixtures", func(t *testing.T) {
+		for _, path := range []string{testsupport.PathXHTML, testsupport.PathBrokenLinks, testsupport.PathRedirect, testsupport.PathGzip, testsupport.PathLoginForm} {
+			jsonData, err := json.Marshal(models.AnalysisRequest{URL: site.PageURL(path)})
+			require.NoError(t, err)
+
+			resp, err := http.Post(gatewayURL+"/api/v1/analyze", "application/json", bytes.NewReader(jsonData))
+			require.NoError(t, err)
+			var result models.AnalysisResult
+			err = json.NewDecoder(resp.Body).Decode(&result)
+			resp.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+			testsupport.AssertMatches(t, site.Expected(path), &result)
+		}
 	})
 
 	t.Run("health_checks", func(t *testing.T) {
@@ -97,8 +113,8 @@ func TestIntegrationCompleteAnalysisFlow(t *testing.T) {
 		require.NoError(t, err)
 		defer resp.Body.Close()
 
-		// Should return error
-		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		// The analyzer fails it, which the gateway reports as a bad gateway
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
 
 		var errorResp models.ErrorResponse
 		err = json.NewDecoder(resp.Body).Decode(&errorResp)
@@ -205,6 +221,7 @@ func TestIntegrationConcurrentRequests(t *testing.T) {
 	}
 
 	// Start services
+	site := testsupport.NewServer(t)
 	linkCheckerURL := startLinkCheckerService(t)
 	analyzerURL := startAnalyzerService(t, linkCheckerURL)
 	gatewayURL := startGatewayService(t, analyzerURL)
@@ -217,7 +234,7 @@ func TestIntegrationConcurrentRequests(t *testing.T) {
 		go func(i int) {
 			// Make request
 			reqBody := models.AnalysisRequest{
-				URL: "https://example.com",
+				URL: site.PageURL(testsupport.PathHTML5),
 			}
 			jsonData, _ := json.Marshal(reqBody)
 
@@ -0,0 +1,91 @@
+package integration
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/httpclient"
+	"github.com/RuvinSL/webpage-analyzer/pkg/internalhttp"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/pkg/metrics"
+	"github.com/RuvinSL/webpage-analyzer/pkg/testsupport"
+	"github.com/RuvinSL/webpage-analyzer/services/analyzer/core"
+	linkCore "github.com/RuvinSL/webpage-analyzer/services/link-checker/core"
+	linkHandlers "github.com/RuvinSL/webpage-analyzer/services/link-checker/handlers"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// protoRecorder remembers the protocol of every request it passes on
+type protoRecorder struct {
+	mu     sync.Mutex
+	protos []string
+}
+
+func (p *protoRecorder) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		p.protos = append(p.protos, r.Proto)
+		p.mu.Unlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (p *protoRecorder) seen() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.protos...)
+}
+
+func TestIntegrationInternalH2C(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	// The fixture site stands in for the internet
+	pageProtos := &protoRecorder{}
+	page := httptest.NewServer(pageProtos.wrap(testsupport.Fixtures()))
+	t.Cleanup(page.Close)
+
+	// Link checker serving h2c, as with INTERNAL_H2C set
+	log := logger.New("h2c-test", slog.LevelInfo)
+	metricsCollector := metrics.NewPrometheusCollector("h2c-test")
+	linkChecker := linkCore.NewConcurrentLinkChecker(httpclient.New(5*time.Second, log), 5, log, metricsCollector)
+	linkChecker.Start(context.Background())
+	linkHandler := linkHandlers.NewLinkHandler(linkChecker, log)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/check", linkHandler.CheckLinks).Methods("POST")
+	router.HandleFunc("/check-single", linkHandler.CheckSingleLink).Methods("POST")
+	internalProtos := &protoRecorder{}
+	linkServer := httptest.NewServer(internalhttp.H2CHandler(internalProtos.wrap(router)))
+	t.Cleanup(func() {
+		linkChecker.Stop()
+		linkServer.Close()
+	})
+
+	linkCheckerClient := core.NewLinkCheckerClient(linkServer.URL, 10*time.Second, log)
+	linkCheckerClient.SetH2C(true)
+	analyzer := core.NewAnalyzer(httpclient.New(10*time.Second, log), core.NewHTMLParser(log), linkCheckerClient, log, metricsCollector)
+
+	result, err := analyzer.AnalyzeURL(context.Background(), page.URL+testsupport.PathHTML5)
+	require.NoError(t, err)
+	assert.Equal(t, "Fixture Home", result.Title)
+
+	internal := internalProtos.seen()
+	require.NotEmpty(t, internal)
+	for _, proto := range internal {
+		assert.Equal(t, "HTTP/2.0", proto, "internal call")
+	}
+	external := pageProtos.seen()
+	require.NotEmpty(t, external)
+	for _, proto := range external {
+		assert.Equal(t, "HTTP/1.1", proto, "page fetch")
+	}
+}
@@ -0,0 +1,179 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RuvinSL/webpage-analyzer/pkg/budget"
+	"github.com/RuvinSL/webpage-analyzer/pkg/logger"
+	"github.com/RuvinSL/webpage-analyzer/pkg/metrics"
+	"github.com/RuvinSL/webpage-analyzer/pkg/models"
+	"github.com/RuvinSL/webpage-analyzer/services/analyzer/core"
+	analyzerHandlers "github.com/RuvinSL/webpage-analyzer/services/analyzer/handlers"
+	"github.com/RuvinSL/webpage-analyzer/services/gateway/handlers"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingAnalyzer fails the analysis of each URL with the error it is given
+type failingAnalyzer map[string]error
+
+func (f failingAnalyzer) AnalyzeURL(ctx context.Context, url string) (*models.AnalysisResult, error) {
+	return f.AnalyzeURLWithOptions(ctx, url, models.AnalysisOptions{})
+}
+
+func (f failingAnalyzer) AnalyzeURLWithOptions(_ context.Context, url string, _ models.AnalysisOptions) (*models.AnalysisResult, error) {
+	return nil, f[url]
+}
+
+// TestIntegrationStatusMapping pins the status a gateway client gets for
+// each analyzer failure: the analyzer's 4xx are passed on, its 5xx become
+// 502 and its timeouts 504
+func TestIntegrationStatusMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		upstream int
+		status   int
+		message  string
+		code     string
+	}{
+		{
+			name:     "target answered 404",
+			err:      &models.HTTPStatusError{StatusCode: http.StatusNotFound},
+			upstream: http.StatusBadRequest,
+			status:   http.StatusBadRequest,
+			message:  "HTTP error: status code 404",
+		},
+		{
+			name:     "rendering disabled",
+			err:      fmt.Errorf("render: %w", core.ErrRenderingDisabled),
+			upstream: http.StatusBadRequest,
+			status:   http.StatusBadRequest,
+			message:  "JavaScript rendering is not enabled on this server",
+		},
+		{
+			name:     "budget exhausted",
+			err:      fmt.Errorf("failed to fetch URL: %w", budget.ErrExhausted),
+			upstream: http.StatusUnprocessableEntity,
+			status:   http.StatusUnprocessableEntity,
+			message:  "Outbound request budget exhausted while fetching the page",
+		},
+		{
+			name:     "not HTML",
+			err:      &models.UnsupportedContentTypeError{ContentType: "application/pdf", Bytes: 2048},
+			upstream: http.StatusUnprocessableEntity,
+			status:   http.StatusUnprocessableEntity,
+			message:  "The page is not HTML: unsupported content type application/pdf (2048 bytes)",
+			code:     models.ErrorCodeUnsupportedContentType,
+		},
+		{
+			name:     "target host busy",
+			err:      &models.TargetBusyError{Host: "busy.example", RetryAfter: time.Second},
+			upstream: http.StatusTooManyRequests,
+			status:   http.StatusTooManyRequests,
+			message:  "Target host is busy, retry later: target host busy.example is busy",
+			code:     models.ErrorCodeTargetBusy,
+		},
+		{
+			name:     "fetch timed out",
+			err:      fmt.Errorf("failed to fetch URL: request failed: %w", context.DeadlineExceeded),
+			upstream: http.StatusGatewayTimeout,
+			status:   http.StatusGatewayTimeout,
+			message:  "Analysis timeout",
+		},
+		{
+			name:     "analysis failed",
+			err:      errors.New("parse failure"),
+			upstream: http.StatusInternalServerError,
+			status:   http.StatusBadGateway,
+			message:  "Analysis failed: analyzer service error (status 500): Failed to analyze URL",
+		},
+	}
+
+	failures := failingAnalyzer{}
+	for i, tt := range tests {
+		failures[fmt.Sprintf("https://example.com/%d", i)] = tt.err
+	}
+	analyzerURL, upstream := startFailingAnalyzerService(t, failures)
+	gatewayURL := startStatusGatewayService(t, analyzerURL)
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := fmt.Sprintf("https://example.com/%d", i)
+
+			resp, body := postJSON(t, gatewayURL+"/api/v1/analyze", `{"url":"`+url+`"}`)
+			assert.Equal(t, tt.upstream, upstream[url], "analyzer status")
+			assert.Equal(t, tt.status, resp.StatusCode, "gateway status")
+			assert.Equal(t, tt.message, body.Error)
+			assert.Equal(t, tt.code, body.Code)
+		})
+	}
+}
+
+// startFailingAnalyzerService serves the analyzer handler over analyzer,
+// recording the status of its last answer for each URL
+func startFailingAnalyzerService(t *testing.T, analyzer failingAnalyzer) (string, map[string]int) {
+	log := logger.NewAdapter(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	analyzerHandler := analyzerHandlers.NewAnalyzerHandler(analyzer, log)
+
+	statuses := make(map[string]int)
+	router := mux.NewRouter()
+	router.HandleFunc("/analyze", func(w http.ResponseWriter, r *http.Request) {
+		var req models.AnalysisRequest
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &req)
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+
+		recorder := httptest.NewRecorder()
+		analyzerHandler.Analyze(recorder, r)
+		statuses[req.URL] = recorder.Code
+		for key, values := range recorder.Header() {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(recorder.Code)
+		w.Write(recorder.Body.Bytes())
+	}).Methods("POST")
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server.URL, statuses
+}
+
+// startStatusGatewayService serves the gateway's analyze route over the
+// analyzer at analyzerURL
+func startStatusGatewayService(t *testing.T, analyzerURL string) string {
+	log := logger.NewAdapter(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	analyzerClient := handlers.NewAnalyzerClient(analyzerURL, 30*time.Second, log)
+	apiHandler := handlers.NewAPIHandler(analyzerClient, log, metrics.NewPrometheusCollector("gateway-status-test"))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/analyze", apiHandler.AnalyzeURL).Methods("POST")
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// postJSON posts body to url and decodes the error response
+func postJSON(t *testing.T, url, body string) (*http.Response, models.ErrorResponse) {
+	t.Helper()
+
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var errorResp models.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errorResp))
+	return resp, errorResp
+}
@@ -28,12 +28,16 @@
             error.style.display = 'none';
 
             try {
-                const response = await fetch('/api/v1/analyze', {
+                const response = await fetch('/api/v2/analyze', {
                     method: 'POST',
                     headers: {
                         'Content-Type': 'application/json',
                     },
-                    body: JSON.stringify({ url: url })
+                    body: JSON.stringify({
+                        url: url,
+                        screenshot: document.getElementById('screenshot').checked,
+                        follow_frames: document.getElementById('followFrames').checked
+                    })
                 });
 
                 const data = await response.json();
@@ -53,6 +57,29 @@
 
         function displayResults(data) {
             document.getElementById('results').style.display = 'block';
+
+            // Soft issues of the analysis; the code shows on hover
+            const warnings = data.analysis_warnings || [];
+            const warningsList = document.getElementById('warningsList');
+            warningsList.innerHTML = '';
+            for (const warning of warnings) {
+                const item = document.createElement('li');
+                item.textContent = warning.message;
+                item.title = warning.code;
+                warningsList.appendChild(item);
+            }
+            // A page that asked for authentication has no content to show
+            const auth = data.requires_auth;
+            if (auth) {
+                const item = document.createElement('li');
+                const scheme = auth.scheme ? `${auth.scheme} ` : '';
+                const realm = auth.realm ? ` to "${auth.realm}"` : '';
+                const asker = auth.proxy ? 'A proxy on the way to the page' : 'The page';
+                item.textContent = `${asker} asks for ${scheme}authentication${realm}; its content was not analyzed`;
+                item.title = `HTTP ${auth.status_code}`;
+                warningsList.prepend(item);
+            }
+            document.getElementById('warnings').style.display = warningsList.children.length > 0 ? 'block' : 'none';
             
             // Document info
             document.getElementById('htmlVersion').textContent = data.html_version || 'Unknown';
@@ -61,6 +88,16 @@
                 ? '<span class="badge badge-success">Found</span>' 
                 : '<span class="badge badge-info">Not Found</span>';
 
+            // Screenshot, only present when requested and captured
+            const screenshot = document.getElementById('pageScreenshot');
+            if (data.screenshot) {
+                screenshot.src = data.screenshot;
+                screenshot.style.display = 'block';
+            } else {
+                screenshot.removeAttribute('src');
+                screenshot.style.display = 'none';
+            }
+
             // Headings
             const headingsList = document.getElementById('headingsList');
             headingsList.innerHTML = '';
@@ -76,7 +113,7 @@
             }
 
             if (headingsList.children.length === 0) {
-                headingsList.innerHTML = '<span style="color: #7f8c8d;">No headings found</span>';
+                headingsList.innerHTML = '<span class="no-headings">No headings found</span>';
             }
 
             // Links
@@ -84,6 +121,13 @@
             document.getElementById('internalLinks').textContent = data.links.internal || 0;
             document.getElementById('externalLinks').textContent = data.links.external || 0;
             document.getElementById('inaccessibleLinks').textContent = data.links.inaccessible || 0;
+            document.getElementById('redirectedLinks').textContent = data.links.redirected || 0;
+
+            // Skipped links are not in the total; the reasons show on hover
+            const skipped = Object.entries(data.links.skipped || {});
+            const skippedLinks = document.getElementById('skippedLinks');
+            skippedLinks.textContent = skipped.reduce((sum, [, count]) => sum + count, 0);
+            skippedLinks.title = skipped.map(([reason, count]) => `${reason.replace(/_/g, ' ')}: ${count}`).join('\n');
         }
 
         function showError(message) {